@@ -1069,7 +1069,9 @@ async function updateDatadogNotebook(notebookId, notebookData) {
 
 // Resolve a notebook for recovery: updates its title and header to reflect RESOLVED status
 // Follows incident disposition lifecycle: Active -> Investigating -> Resolved
-async function resolveNotebook(notebookId, monitorId, monitorName, recoveryTimestamp) {
+// resolution ({ time_to_resolve, summary }) comes from the Go orchestrator when it
+// linked the recovery to the original analysis
+async function resolveNotebook(notebookId, monitorId, monitorName, recoveryTimestamp, resolution = null) {
     console.log(`[Recovery] Resolving notebook ${notebookId} for monitor ${monitorId}`);
 
     // Fetch the existing notebook
@@ -1120,6 +1122,12 @@ async function resolveNotebook(notebookId, monitorId, monitorName, recoveryTimes
     });
 
     // Add a resolution cell at the end (before the footer)
+    const timeToResolveRow = resolution?.time_to_resolve
+        ? `| Time to Resolve | ${resolution.time_to_resolve} |\n`
+        : '';
+    const whatChanged = resolution?.summary
+        ? `## What changed between alert and recovery\n\n${resolution.summary}\n\n`
+        : '';
     const resolutionCell = {
         type: "notebook_cells",
         attributes: {
@@ -1131,7 +1139,9 @@ async function resolveNotebook(notebookId, monitorId, monitorName, recoveryTimes
                     `|-------|-------|\n` +
                     `| Monitor | ${monitorName} (ID: ${monitorId}) |\n` +
                     `| Resolution Time | ${recoveryTimestamp} |\n` +
+                    timeToResolveRow +
                     `| Status | **RESOLVED** |\n\n` +
+                    whatChanged +
                     `---\n\n` +
                     `> 🤖 *This resolution was automatically recorded by the webhook agent recovery pipeline*\n`
             }
@@ -2397,7 +2407,7 @@ const server = http.createServer(async (req, res) => {
         let fullPayload = {};
         try {
             const body = await parseBody(req);
-            const { payload, template_id, instructions } = body;
+            const { payload, template_id, instructions, context: rayneContext = {} } = body;
 
             // Support both new format (payload object) and legacy format
             fullPayload = payload || body;
@@ -2660,6 +2670,20 @@ const server = http.createServer(async (req, res) => {
                 datadogContext += `🗄️ [View Database Queries](${datadogUrls.dbmQueries}) | [Service DBM](${datadogUrls.dbm})\n`;
             }
 
+            // Findings Rayne gathered before the analysis (e.g. recent deploys and config changes)
+            let rayneFindingsContext = '';
+            const rayneFindings = Array.isArray(rayneContext.findings) ? rayneContext.findings : [];
+            if (rayneFindings.length > 0) {
+                rayneFindingsContext = `\n## Recent Changes and Pre-gathered Findings\n`;
+                rayneFindingsContext += `Changes to the affected service or host shortly before an alert are common root causes; confirm or rule them out.\n\n`;
+                rayneFindings.forEach((f, i) => {
+                    rayneFindingsContext += `${i + 1}. **[${f.category || 'finding'}]** ${f.summary || ''}\n`;
+                    if (f.details) {
+                        rayneFindingsContext += `   ${String(f.details).substring(0, 500).replace(/\n/g, '\n   ')}\n`;
+                    }
+                });
+            }
+
             // Build comprehensive prompt with full payload context AND live data
             const prompt = `You are an SRE analyzing a Datadog alert. You have been provided with LIVE data from Datadog including recent logs, host information, and events. Use this data to provide evidence-based root cause analysis.
 
@@ -2678,7 +2702,7 @@ ${JSON.stringify(fullPayload, null, 2)}
 - Tags: ${tags?.join(', ') || 'N/A'}
 
 ${datadogContext}
-
+${rayneFindingsContext}
 ${similarRCAContext}
 
 ${incidentTemplate ? `## Output Template\n${JSON.stringify(incidentTemplate, null, 2)}` : ''}
@@ -2722,6 +2746,19 @@ Use these evidence types as applicable (skip if no data):
 |----------|------|----------|
 | {service or host name} | {downstream/upstream/shared-resource} | {what evidence shows this} |
 
+## 🛠️ Remediation Proposals (optional)
+If the evidence clearly supports an automated fix, end with ONE fenced block tagged \`remediation\` holding a JSON array.
+Nothing runs until a human approves it. Allowed types and params:
+- create_downtime: {"monitor_id": ${monitorId || 0}, "scope": "host:...", "duration_minutes": 60}
+- mute_monitor: {"monitor_id": ${monitorId || 0}, "duration_minutes": 60}
+- restart_deployment: {"namespace": "...", "deployment": "..."}
+- scale_deployment: {"namespace": "...", "deployment": "...", "replicas": 3}
+- run_script: {"script": "{registered script name}", "args": []}
+\`\`\`remediation
+[{"type": "restart_deployment", "params": {"namespace": "prod", "deployment": "checkout"}, "reason": "{evidence-based reason}"}]
+\`\`\`
+Omit the block when no action is clearly warranted.
+
 Use code blocks for actual log lines, metric values, and trace data. Use > 💡 for insights.
 Cite specific data from the Datadog context above. Do NOT fabricate evidence.`;
 
@@ -3097,7 +3134,7 @@ Ground your analysis in the live Datadog data provided. Quote specific log entri
     if (url.pathname === '/recover' && req.method === 'POST') {
         try {
             const body = await parseBody(req);
-            const { payload } = body;
+            const { payload, resolution = null } = body;
 
             const fullPayload = payload || body;
             const monitorId = fullPayload.monitor_id || fullPayload.monitorId;
@@ -3123,19 +3160,24 @@ Ground your analysis in the live Datadog data provided. Quote specific log entri
             // Look up the notebook in the registry
             const entry = notebookRegistry.get(String(monitorId));
             let notebookResult = null;
+            let notebookId = null;
 
             if (entry && entry.notebookId) {
                 console.log(`[Recovery] Found notebook ${entry.notebookId} for monitor ${monitorId} (status: ${entry.status})`);
-                notebookResult = await resolveNotebook(entry.notebookId, monitorId, monitorName, recoveryTimestamp);
+                notebookId = entry.notebookId;
             } else {
                 console.log(`[Recovery] No notebook found in registry for monitor ${monitorId}`);
-                // Try to find by explicit notebook_id in the payload (for manual recovery)
+                // Fall back to the notebook_id in the payload (set by the Go orchestrator from
+                // the stored analysis, or by hand for manual recovery)
                 const explicitNotebookId = fullPayload.notebook_id || fullPayload.notebookId;
                 if (explicitNotebookId) {
                     console.log(`[Recovery] Using explicit notebook_id from payload: ${explicitNotebookId}`);
-                    notebookResult = await resolveNotebook(explicitNotebookId, monitorId, monitorName, recoveryTimestamp);
+                    notebookId = explicitNotebookId;
                 }
             }
+            if (notebookId) {
+                notebookResult = await resolveNotebook(notebookId, monitorId, monitorName, recoveryTimestamp, resolution);
+            }
 
             sendJson(res, 200, {
                 success: true,
@@ -3144,8 +3186,8 @@ Ground your analysis in the live Datadog data provided. Quote specific log entri
                 alertStatus,
                 recoveryTimestamp,
                 notebookUpdated: !!notebookResult,
-                notebookId: entry?.notebookId || null,
-                notebookUrl: entry?.notebookId ? `${DD_APP_URL}/notebook/${entry.notebookId}` : null,
+                notebookId: notebookId,
+                notebookUrl: notebookId ? `${DD_APP_URL}/notebook/${notebookId}` : null,
                 registryStatus: entry?.status || 'not_found',
                 timestamp: new Date().toISOString()
             });
@@ -3160,6 +3202,86 @@ Ground your analysis in the live Datadog data provided. Quote specific log entri
         return;
     }
 
+    // Follow-up endpoint - answers a question about a completed analysis.
+    // The Go orchestrator sends the alert plus the investigation so far
+    // (root cause, findings, queries, earlier turns) from its stored AgentContext.
+    if (url.pathname === '/ask' && req.method === 'POST') {
+        try {
+            const body = await parseBody(req);
+            const { payload = {}, question, context = {} } = body;
+
+            if (!question) {
+                sendJson(res, 400, { error: 'question is required' });
+                return;
+            }
+
+            const monitorId = payload.monitor_id || payload.monitorId;
+            const monitorName = payload.monitor_name || payload.monitorName || payload.ALERT_TITLE || 'Unknown Monitor';
+            console.log(`[Ask] Follow-up for monitor ${monitorId}: ${question.substring(0, 100)}`);
+
+            let investigation = `## Alert\n${JSON.stringify(payload, null, 2)}\n`;
+            investigation += `\n## Root Cause (from the original analysis)\n${context.root_cause || 'Not determined'}\n`;
+            if (context.notebook_url) {
+                investigation += `\nIncident notebook: ${context.notebook_url}\n`;
+            }
+            if (context.findings && context.findings.length > 0) {
+                investigation += `\n## Findings\n`;
+                context.findings.forEach((f, i) => {
+                    investigation += `${i + 1}. [${f.source}/${f.category}] ${f.summary}\n${(f.details || '').substring(0, 3000)}\n\n`;
+                });
+            }
+            if (context.hypotheses && context.hypotheses.length > 0) {
+                investigation += `\n## Hypotheses\n${context.hypotheses.map(h => `- ${h}`).join('\n')}\n`;
+            }
+            if (context.queries && context.queries.length > 0) {
+                investigation += `\n## Queries Already Run\n`;
+                context.queries.forEach(q => {
+                    investigation += `- ${q.agent_name}: ${q.query}\n  ${(q.result || '').substring(0, 500)}\n`;
+                });
+            }
+            if (context.conversation && context.conversation.length > 0) {
+                investigation += `\n## Earlier Questions\n`;
+                context.conversation.forEach(turn => {
+                    investigation += `**Q:** ${turn.question}\n**A:** ${turn.answer}\n\n`;
+                });
+            }
+
+            const prompt = `You are continuing a root cause investigation for the Datadog monitor "${monitorName}".
+An on-call engineer has a follow-up question. Use the investigation below, and the dd_lib tools if you need more data, to answer it.
+
+${investigation}
+
+## Question
+${question}
+
+Answer the question directly and concisely. Say which evidence supports the answer, and what to check next if it is still uncertain.`;
+
+            const result = await invokeClaudeCode(prompt, WORK_DIR, {
+                monitor_id: monitorId,
+                monitor_name: monitorName,
+                endpoint: '/ask',
+                model: payload.model || null
+            });
+
+            sendJson(res, 200, {
+                success: true,
+                monitorId,
+                analysis: result,
+                timestamp: new Date().toISOString()
+            });
+
+        } catch (err) {
+            console.error(`[Ask] Error: ${err.message}`);
+            sendJson(res, 500, {
+                error: err.message,
+                error_type: err.errorType || classifyError(err, err.stderr || ''),
+                retries_exhausted: !!err.retriesExhausted,
+                timestamp: new Date().toISOString()
+            });
+        }
+        return;
+    }
+
     // Notebook registry status endpoint - lists all tracked notebooks and their lifecycle status
     if (url.pathname === '/notebooks/registry' && req.method === 'GET') {
         const entries = [];
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents/eval"
+)
+
+// agenteval replays the golden alert fixtures through an agent and prints a
+// scorecard, so prompt and classifier rule changes can be checked offline:
+//
+//	go run ./cmd/agenteval -agent heuristic
+//	go run ./cmd/agenteval -agent claude -rules classifier_rules.yaml -json
+func main() {
+	fixturesPath := flag.String("fixtures", "services/agents/eval/fixtures", "fixture file or directory of *.json fixtures")
+	agentName := flag.String("agent", "heuristic", "agent to evaluate: heuristic or claude (uses CLAUDE_AGENT_URL)")
+	rulesPath := flag.String("rules", "", "classifier rules YAML file (default: built-in rules)")
+	maxIterations := flag.Int("max-iterations", 5, "RLM iterations per case")
+	timeout := flag.Duration("timeout", 2*time.Minute, "timeout per case")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	verbose := flag.Bool("v", false, "show agent and RLM logs")
+	minClassification := flag.Float64("min-classification", 0, "fail below this classification accuracy (0-1)")
+	minCategory := flag.Float64("min-category", 0, "fail below this root-cause category accuracy (0-1)")
+	minCoverage := flag.Float64("min-coverage", 0, "fail below this mean finding coverage (0-1)")
+	flag.Parse()
+
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	fixtures, err := eval.LoadFixtures(*fixturesPath)
+	if err != nil {
+		fatalf("%v", err)
+	}
+
+	classifier := agents.NewRoleClassifier()
+	if *rulesPath != "" {
+		if _, err := classifier.Reload(ctx, agents.NewFileRuleSource(*rulesPath)); err != nil {
+			fatalf("load rules: %v", err)
+		}
+	}
+
+	var factory eval.AgentFactory
+	switch *agentName {
+	case "heuristic":
+		agent := agents.NewHeuristicAgent(classifier)
+		factory = func(agents.AgentRole) agents.Agent { return agent }
+	case "claude":
+		factory = func(role agents.AgentRole) agents.Agent { return agents.NewClaudeAgent(role) }
+	default:
+		fatalf("unknown agent %q (want heuristic or claude)", *agentName)
+	}
+
+	runner := &eval.Runner{Classifier: classifier, MaxIterations: *maxIterations, Timeout: *timeout}
+	report := runner.Run(ctx, factory, fixtures)
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			fatalf("encode report: %v", err)
+		}
+	} else {
+		report.WriteText(os.Stdout)
+	}
+
+	err = report.Check(eval.Thresholds{
+		MinClassificationAccuracy: *minClassification,
+		MinCategoryAccuracy:       *minCategory,
+		MinFindingCoverage:        *minCoverage,
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, "agenteval: "+format+"\n", args...)
+	os.Exit(2)
+}
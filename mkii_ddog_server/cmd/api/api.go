@@ -9,19 +9,28 @@ import (
 	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/websocket"
 	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
 	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+	"github.com/Nokodoko/mkii_ddog_server/services/baselines"
 	"github.com/Nokodoko/mkii_ddog_server/services/catalog"
+	"github.com/Nokodoko/mkii_ddog_server/services/changes"
 	"github.com/Nokodoko/mkii_ddog_server/services/demo"
 	"github.com/Nokodoko/mkii_ddog_server/services/downtimes"
 	"github.com/Nokodoko/mkii_ddog_server/services/events"
 	githubsvc "github.com/Nokodoko/mkii_ddog_server/services/github"
 	"github.com/Nokodoko/mkii_ddog_server/services/hosts"
+	"github.com/Nokodoko/mkii_ddog_server/services/incidents"
 	"github.com/Nokodoko/mkii_ddog_server/services/logs"
 	"github.com/Nokodoko/mkii_ddog_server/services/monitors"
+	"github.com/Nokodoko/mkii_ddog_server/services/noise"
 	"github.com/Nokodoko/mkii_ddog_server/services/pl"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
 	"github.com/Nokodoko/mkii_ddog_server/services/rum"
 	"github.com/Nokodoko/mkii_ddog_server/services/user"
+	"github.com/Nokodoko/mkii_ddog_server/services/watchdog"
 	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
 	"github.com/Nokodoko/mkii_ddog_server/services/webhooks/processors"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/ext"
@@ -39,12 +48,18 @@ func (r *statusRecorder) WriteHeader(code int) {
 	r.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer so http.ResponseController can
+// flush (SSE) and hijack (WebSocket) through the middleware
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
 // corsMiddleware adds CORS headers for cross-origin requests
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-datadog-trace-id, x-datadog-parent-id, x-datadog-sampling-priority, x-datadog-origin, x-datadog-tags")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, x-datadog-trace-id, x-datadog-parent-id, x-datadog-sampling-priority, x-datadog-origin, x-datadog-tags, X-RUM-Synthetic")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -143,8 +158,19 @@ func (d *DDogServer) Run(ctx context.Context) error {
 		log.Printf("Warning: Failed to initialize account manager: %v", err)
 	}
 
+	// Record or replay outgoing Datadog and sidecar calls (HTTP_CASSETTE_MODE).
+	// Every account's keys are scrubbed from recordings; cassettes are written
+	// on shutdown.
+	cassetteConfig := httpclient.CassetteConfigFromEnv()
+	cassetteConfig.Secrets = accountManager.Secrets
+	restoreTransports, err := httpclient.UseCassettes(cassetteConfig)
+	if err != nil {
+		return fmt.Errorf("enable HTTP cassettes: %w", err)
+	}
+	defer restoreTransports()
+
 	// Initialize agent orchestrator with bounded concurrency
-	agentOrchConfig := agents.DefaultOrchestratorConfig()
+	agentOrchConfig := agents.OrchestratorConfigFromEnv()
 	agentOrch := agents.NewAgentOrchestrator(agentOrchConfig)
 
 	// Register default Claude agent for all roles
@@ -157,10 +183,114 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	agentOrch.RegisterAgent(agents.NewClaudeAgent(agents.RoleDatabase))
 	agentOrch.RegisterAgent(agents.NewClaudeAgent(agents.RoleNetwork))
 	agentOrch.RegisterAgent(agents.NewClaudeAgent(agents.RoleLogs))
-	agentOrch.RegisterAgent(agents.NewClaudeAgent(agents.RoleWatchdog))
 
-	// Initialize processor orchestrator with tiered execution
-	procOrch := webhooks.NewProcessorOrchestrator(webhookStorage, agentOrch)
+	// Watchdog stories are parsed, enriched and grouped in-process so they are
+	// analyzed even while the sidecar is down (WATCHDOG_USE_SIDECAR=true reverts)
+	watchdogConfig := watchdog.ConfigFromEnv()
+	watchdogGroups := watchdog.NewGrouper(watchdogConfig.GroupWindow)
+	if watchdogConfig.UseSidecar {
+		agentOrch.RegisterAgent(agents.NewClaudeAgent(agents.RoleWatchdog))
+	} else {
+		agentOrch.RegisterAgent(watchdog.NewAgent(watchdog.NewDatadogEnricher(accountManager), watchdogGroups, watchdogConfig))
+	}
+
+	// Cheap payload-only analysis while the sidecar circuit breaker is open
+	agentOrch.SetFallbackAgent(agents.NewHeuristicAgent(agentOrch.Classifier()))
+
+	// Store completed analyses so engineers can ask follow-up questions
+	analysisStorage := agents.NewAnalysisStorage(d.db)
+	if err := analysisStorage.InitTables(); err != nil {
+		log.Printf("Warning: Failed to initialize agent analysis tables: %v", err)
+	} else {
+		agentOrch.SetAnalysisStore(analysisStorage)
+	}
+
+	// Agent-proposed remediation actions wait for human approval (API or Slack)
+	remediationStorage := remediation.NewStorage(d.db)
+	if err := remediationStorage.InitTables(); err != nil {
+		log.Printf("Warning: Failed to initialize remediation tables: %v", err)
+	}
+	// Operators authenticate privileged endpoints with bearer tokens; audit
+	// logs record the token's operator, never a client-supplied name
+	operators := operator.FromEnv()
+
+	remediationConfig := remediation.ConfigFromEnv()
+	remediationManager := remediation.NewManager(remediationStorage, remediationConfig)
+	remediationManager.RegisterExecutor(remediation.NewDatadogExecutor(accountManager))
+	if k8sExecutor, err := remediation.NewKubernetesExecutorInCluster(remediation.NamespacesFromEnv()); err == nil {
+		remediationManager.RegisterExecutor(k8sExecutor)
+	} else {
+		log.Printf("Kubernetes remediation disabled: %v", err)
+	}
+	if scripts := remediation.ScriptsFromEnv(); len(scripts) > 0 {
+		remediationManager.RegisterExecutor(remediation.NewScriptExecutor(scripts, 0))
+	}
+	slackNotifier := remediation.NewSlackNotifier()
+	if slackNotifier != nil {
+		remediationManager.SetNotifier(slackNotifier)
+	}
+	agentOrch.SetRemediation(remediationManager)
+
+	// Change events (deploys, config changes, GitHub pushes/releases, Datadog
+	// deployment events) are attached to analyses as findings
+	changeStorage := changes.NewStorage(d.db)
+	if err := changeStorage.InitTables(); err != nil {
+		log.Printf("Warning: Failed to initialize change tables: %v", err)
+	}
+	changeTracker := changes.NewTracker(changeStorage, changes.ConfigFromEnv())
+	agentOrch.AddContextSource(changes.NewAgentContextSource(changeTracker))
+	go changes.NewDatadogPoller(changeTracker, accountManager, changes.PollerConfigFromEnv()).Run(ctx)
+
+	// Per-monitor alert baselines flag anomalous alerts ("3x usual rate"),
+	// raising their analysis priority and annotating notifications
+	baselineStorage := baselines.NewStorage(d.db)
+	if err := baselineStorage.InitTables(); err != nil {
+		log.Printf("Warning: Failed to initialize baseline tables: %v", err)
+	}
+	baselineConfig := baselines.ConfigFromEnv()
+	baselineEngine := baselines.NewEngine(webhookStorage, baselineStorage, baselineConfig)
+	agentOrch.SetPriorityAdjuster(baselineEngine)
+	go baselineEngine.Run(ctx)
+
+	// Postmortems assemble webhook history, analyses, remediation and acknowledgements
+	incidentStorage := incidents.NewStorage(d.db)
+	if err := incidentStorage.InitTables(); err != nil {
+		log.Printf("Warning: Failed to initialize incident tables: %v", err)
+	}
+	postmortems := incidents.NewGenerator(webhookStorage, incidentStorage)
+	postmortems.SetAnalysisSource(analysisStorage)
+	postmortems.SetActionSource(remediationManager)
+	postmortems.SetSummarizer(incidents.NewAgentSummarizer(agentOrch))
+	postmortems.SetSummaryStore(incidentStorage)
+	postmortems.SetNotebookPublisher(incidents.NewDatadogNotebookPublisher(accountManager))
+
+	// Noisy-monitor report ranks monitors by flaps, auto-recoveries and auto-downtimes
+	noiseConfig := noise.ConfigFromEnv()
+	noiseReporter := noise.NewReporter(webhookStorage, noise.NewDatadogMonitorSource(accountManager), noiseConfig)
+	noiseReporter.SetAckSource(incidentStorage)
+	noiseReporter.SetActionSource(remediationManager)
+	go noiseReporter.Run(ctx)
+
+	// Load classifier rules (YAML file or Postgres) and keep them hot-reloaded
+	ruleSource := agents.RuleSourceFromEnv(d.db)
+	if ruleStorage, ok := ruleSource.(*agents.RuleStorage); ok {
+		if err := ruleStorage.InitTables(); err != nil {
+			log.Printf("Warning: Failed to initialize classifier rule tables: %v", err)
+		}
+	}
+	if ruleSource != nil {
+		if _, err := agentOrch.Classifier().Reload(ctx, ruleSource); err != nil {
+			log.Printf("Warning: Failed to load classifier rules, using defaults: %v", err)
+		}
+		go agentOrch.Classifier().WatchRules(ctx, ruleSource, agents.RuleReloadIntervalFromEnv())
+	}
+
+	// Initialize processor orchestrator with tiered execution. Tier 2 runs in
+	// the background so long analyses don't hold dispatcher workers; analyses
+	// get their own context so shutdown can drain them before cancelling.
+	analysisCtx, cancelAnalyses := context.WithCancel(context.Background())
+	procOrch := webhooks.NewAsyncProcessorOrchestrator(analysisCtx, webhookStorage, agentOrch, webhooks.AsyncAnalysisTimeoutFromEnv())
+	procOrch.SetAnomalyDetector(baselineEngine)
 
 	// Register fast processors (Tier 1: parallel execution)
 	// Use account-aware processors for multi-account support
@@ -185,6 +315,19 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	rumHandler := rum.NewHandler(rumStorage)
 	demoHandler := demo.NewHandler(webhookStorage, rumStorage)
 	accountHandler := accounts.NewHandler(accountManager)
+	agentHandler := agents.NewHandler(agentOrch, ruleSource)
+	agentHandler.SetAllowedOrigins(websocket.AllowedOriginsFromEnv())
+	agentHandler.SetOperators(operators)
+	remediationHandler := remediation.NewHandler(remediationManager, slackNotifier)
+	remediationHandler.SetOperators(operators)
+	incidentHandler := incidents.NewHandler(postmortems)
+	changeHandler := changes.NewHandler(changeTracker)
+	changeHandler.SetOperators(operators)
+	baselineHandler := baselines.NewHandler(baselineEngine)
+	noiseHandler := noise.NewHandler(noiseReporter)
+	watchdogHandler := watchdog.NewHandler(watchdogGroups)
+	githubHandler.SetChangeRecorder(changeTracker)
+	webhookHandler.SetChangeLookup(changeTracker)
 
 	// Initialize database tables for new services
 	if err := webhookStorage.InitTables(); err != nil {
@@ -193,6 +336,55 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	if err := rumStorage.InitTables(); err != nil {
 		log.Printf("Warning: Failed to initialize RUM tables: %v", err)
 	}
+
+	// Batched RUM events are buffered and written with COPY off the request path
+	rumWriterConfig := rum.WriterConfigFromEnv()
+	rumWriter := rum.NewBatchWriter(rumStorage, rumWriterConfig)
+	rumWriter.Start()
+	rumHandler.SetBatchWriter(rumWriter)
+
+	// Session replay chunks go to RUM_REPLAY_DIR when set, otherwise Postgres
+	replayConfig := rum.ReplayConfigFromEnv()
+	var replayStore rum.ReplayStore = rum.NewPostgresReplayStore(d.db)
+	replayBackend := "postgres"
+	if replayConfig.Dir != "" {
+		fileStore, err := rum.NewFileReplayStore(replayConfig.Dir)
+		if err != nil {
+			log.Printf("Warning: Failed to open replay dir %s, storing replays in Postgres: %v", replayConfig.Dir, err)
+		} else {
+			replayStore, replayBackend = fileStore, replayConfig.Dir
+		}
+	}
+	replayRecorder := rum.NewRecorder(rumStorage, replayStore, replayConfig)
+	rumHandler.SetRecorder(replayRecorder)
+	go replayRecorder.Run(ctx)
+
+	// Visitors are located from local MaxMind-format databases, never a network service
+	rumGeoIP, err := rum.NewGeoIP(rum.GeoIPConfigFromEnv())
+	if err != nil {
+		log.Printf("Warning: Failed to open GeoIP databases, visitors won't be located: %v", err)
+	}
+	rumHandler.SetGeoIP(rumGeoIP)
+
+	// Crawlers, headless browsers and scripts are tagged at session start and
+	// left out of analytics unless include_bots=true
+	rumBots, err := rum.BotRulesFromEnv()
+	if err != nil {
+		log.Printf("Warning: Failed to load RUM_BOT_RULES, using the built-in bot rules: %v", err)
+		rumBots = rum.DefaultBotDetector()
+	}
+	rumHandler.SetBotDetector(rumBots)
+
+	// Consent gates ingestion; IPs are pseudonymised with a rotating salt and
+	// PII is scrubbed from URLs and metadata before storage
+	privacyConfig := rum.PrivacyConfigFromEnv()
+	rumHandler.SetPrivacy(rum.NewPrivacy(rumStorage, privacyConfig))
+	rumHandler.SetOperators(operators)
+
+	// Hourly and daily rollups back long-range analytics; raw rows expire after rollup
+	rollupConfig := rum.RollupConfigFromEnv()
+	rumStorage.SetRollupRange(rollupConfig.AnalyticsRange)
+	go rum.NewRollupJob(rumStorage, rollupConfig).Run(ctx)
 	if err := githubStorage.InitTables(); err != nil {
 		log.Printf("Warning: Failed to initialize GitHub tables: %v", err)
 	}
@@ -229,6 +421,7 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	utils.Endpoint(router, "GET", "/v1/webhooks/events", webhookHandler.GetWebhookEvents)
 	utils.EndpointWithPathParams(router, "GET", "/v1/webhooks/events/{id}", "id", webhookHandler.GetWebhookEvent)
 	utils.EndpointWithPathParams(router, "GET", "/v1/webhooks/monitor/{monitorId}", "monitorId", webhookHandler.GetEventsByMonitor)
+	utils.EndpointWithPathParams(router, "GET", "/v1/webhooks/monitor/{monitorId}/insights", "monitorId", baselineHandler.GetMonitorInsights)
 	utils.Endpoint(router, "POST", "/v1/webhooks/create", webhookHandler.CreateWebhook)
 	utils.Endpoint(router, "POST", "/v1/webhooks/config", webhookHandler.SaveWebhookConfig)
 	utils.Endpoint(router, "GET", "/v1/webhooks/config", webhookHandler.GetWebhookConfigs)
@@ -243,6 +436,13 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	utils.Endpoint(router, "GET", "/v1/webhooks/github/issues", githubHandler.GetIssueEvents)
 	utils.Endpoint(router, "GET", "/v1/webhooks/github/issues/stats", githubHandler.GetIssueStats)
 	utils.EndpointWithPathParams(router, "GET", "/v1/webhooks/github/issues/{id}", "id", githubHandler.GetIssueEvent)
+	// Push and release events are recorded as changes; either URL works
+	utils.Endpoint(router, "POST", "/v1/webhooks/github", githubHandler.ReceiveIssueEvent)
+
+	// Change events (deploys and config changes)
+	utils.Endpoint(router, "POST", "/v1/changes", changeHandler.RecordChange)
+	utils.Endpoint(router, "GET", "/v1/changes", changeHandler.ListChanges)
+	utils.EndpointWithPathParams(router, "GET", "/v1/changes/{id}", "id", changeHandler.GetChange)
 
 	// Accounts (multi-account Datadog management)
 	utils.Endpoint(router, "GET", "/v1/accounts", accountHandler.ListAccounts)
@@ -254,20 +454,67 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	utils.EndpointWithPathParams(router, "POST", "/v1/accounts/{name}/default", "name", accountHandler.SetDefaultAccount)
 	utils.EndpointWithPathParams(router, "POST", "/v1/accounts/{name}/test", "name", accountHandler.TestConnection)
 
-	// Agent orchestrator stats
-	utils.Endpoint(router, "GET", "/v1/agents/stats", func(w http.ResponseWriter, r *http.Request) (int, any) {
-		return http.StatusOK, agentOrch.Stats()
-	})
+	// Agent orchestrator
+	utils.Endpoint(router, "GET", "/v1/agents/stats", agentHandler.GetStats)
+	utils.Endpoint(router, "POST", "/v1/agents/classify", agentHandler.ClassifyAlert)
+	utils.Endpoint(router, "GET", "/v1/agents/classifier/rules", agentHandler.GetClassifierRules)
+	utils.Endpoint(router, "POST", "/v1/agents/classifier/rules", agentHandler.SaveClassifierRule)
+	utils.EndpointWithPathParams(router, "DELETE", "/v1/agents/classifier/rules/{name}", "name", agentHandler.DeleteClassifierRule)
+	utils.Endpoint(router, "POST", "/v1/agents/classifier/reload", agentHandler.ReloadClassifier)
+	utils.Endpoint(router, "GET", "/v1/agents/analyses", agentHandler.ListAnalyses)
+	utils.EndpointWithPathParams(router, "GET", "/v1/agents/analyses/{id}", "id", agentHandler.GetAnalysis)
+	utils.EndpointWithPathParams(router, "POST", "/v1/agents/analyses/{id}/ask", "id", agentHandler.AskAnalysis)
+	// Streams write their own response (SSE or WebSocket), so bypass utils.Endpoint
+	router.HandleFunc("GET /v1/agents/analyses/{id}/stream", agentHandler.StreamAnalysis)
+
+	// Remediation actions (human approval required before execution)
+	utils.Endpoint(router, "GET", "/v1/remediation/actions", remediationHandler.ListActions)
+	utils.Endpoint(router, "POST", "/v1/remediation/actions", remediationHandler.ProposeAction)
+	utils.EndpointWithPathParams(router, "GET", "/v1/remediation/actions/{id}", "id", remediationHandler.GetAction)
+	utils.EndpointWithPathParams(router, "POST", "/v1/remediation/actions/{id}/approve", "id", remediationHandler.ApproveAction)
+	utils.EndpointWithPathParams(router, "POST", "/v1/remediation/actions/{id}/reject", "id", remediationHandler.RejectAction)
+	// Slack signs the raw form body, so bypass utils.Endpoint
+	router.HandleFunc("POST /v1/remediation/slack/interactions", remediationHandler.SlackInteraction)
+
+	// Incidents (alert episodes addressed by any of their webhook event IDs)
+	// Postmortems may be Markdown, so bypass utils.Endpoint
+	router.HandleFunc("GET /v1/incidents/{id}/postmortem", incidentHandler.GetPostmortem)
+	utils.EndpointWithPathParams(router, "POST", "/v1/incidents/{id}/postmortem/summary", "id", incidentHandler.SummarizePostmortem)
+	utils.EndpointWithPathParams(router, "POST", "/v1/incidents/{id}/postmortem/notebook", "id", incidentHandler.PublishPostmortem)
+	utils.EndpointWithPathParams(router, "POST", "/v1/incidents/{id}/ack", "id", incidentHandler.AcknowledgeIncident)
 
 	// RUM (Real User Monitoring)
 	utils.Endpoint(router, "POST", "/v1/rum/init", rumHandler.InitVisitor)
 	utils.Endpoint(router, "POST", "/v1/rum/track", rumHandler.TrackEvent)
+	utils.Endpoint(router, "POST", "/v1/rum/batch", rumHandler.TrackBatch)
+	utils.Endpoint(router, "GET", "/v1/rum/batch/stats", rumHandler.GetBatchStats)
+	utils.Endpoint(router, "POST", "/v1/rum/vitals", rumHandler.TrackVitals)
+	utils.Endpoint(router, "POST", "/v1/rum/errors", rumHandler.TrackError)
+	utils.Endpoint(router, "GET", "/v1/rum/errors", rumHandler.ListErrors)
+	utils.EndpointWithPathParams(router, "GET", "/v1/rum/errors/{fingerprint}", "fingerprint", rumHandler.GetError)
+	utils.Endpoint(router, "POST", "/v1/rum/sourcemaps", rumHandler.UploadSourceMap)
 	utils.Endpoint(router, "POST", "/v1/rum/session/end", rumHandler.EndSession)
 	utils.EndpointWithPathParams(router, "GET", "/v1/rum/visitor/{uuid}", "uuid", rumHandler.GetVisitor)
+	utils.EndpointWithPathParams(router, "DELETE", "/v1/rum/visitor/{uuid}", "uuid", rumHandler.DeleteVisitor)
+	utils.EndpointWithPathParams(router, "GET", "/v1/rum/visitor/{uuid}/export", "uuid", rumHandler.ExportVisitor)
+	utils.EndpointWithPathParams(router, "POST", "/v1/rum/visitor/{uuid}/consent", "uuid", rumHandler.UpdateConsent)
+	utils.Endpoint(router, "GET", "/v1/rum/privacy/audit", rumHandler.GetPrivacyAudit)
 	utils.EndpointWithPathParams(router, "GET", "/v1/rum/session/{sessionId}", "sessionId", rumHandler.GetSession)
+	utils.EndpointWithPathParams(router, "POST", "/v1/rum/session/{sessionId}/replay", "sessionId", rumHandler.UploadReplay)
+	utils.EndpointWithPathParams(router, "GET", "/v1/rum/session/{sessionId}/replay", "sessionId", rumHandler.GetReplay)
 	utils.Endpoint(router, "GET", "/v1/rum/visitors", rumHandler.GetUniqueVisitors)
 	utils.Endpoint(router, "GET", "/v1/rum/analytics", rumHandler.GetAnalytics)
+	utils.Endpoint(router, "GET", "/v1/rum/analytics/vitals", rumHandler.GetVitals)
+	utils.Endpoint(router, "GET", "/v1/rum/analytics/cohorts", rumHandler.GetCohorts)
+	utils.Endpoint(router, "GET", "/v1/rum/analytics/active", rumHandler.GetActiveUsers)
+	utils.Endpoint(router, "GET", "/v1/rum/analytics/rollups", rumHandler.GetRollups)
 	utils.Endpoint(router, "GET", "/v1/rum/sessions", rumHandler.GetRecentSessions)
+	utils.Endpoint(router, "POST", "/v1/rum/funnels", rumHandler.CreateFunnel)
+	utils.Endpoint(router, "GET", "/v1/rum/funnels", rumHandler.ListFunnels)
+	utils.Endpoint(router, "POST", "/v1/rum/funnels/evaluate", rumHandler.EvaluateFunnel)
+	utils.EndpointWithPathParams(router, "GET", "/v1/rum/funnels/{id}", "id", rumHandler.GetFunnel)
+	utils.EndpointWithPathParams(router, "DELETE", "/v1/rum/funnels/{id}", "id", rumHandler.DeleteFunnel)
+	utils.Endpoint(router, "GET", "/v1/rum/paths", rumHandler.GetPaths)
 
 	// Demo data generators
 	utils.Endpoint(router, "POST", "/v1/demo/seed/webhooks", demoHandler.SeedWebhookEvents)
@@ -282,8 +529,13 @@ func (d *DDogServer) Run(ctx context.Context) error {
 	utils.Endpoint(router, "GET", "/v1/monitors/triggered", monitors.GetTriggeredMonitors)
 	utils.Endpoint(router, "GET", "/v1/monitors/ids", monitors.GetMonitorIDs)
 	utils.Endpoint(router, "GET", "/v1/monitors/pages", monitors.GetMonitorPageCount)
+	// Noise reports may be CSV, so bypass utils.Endpoint
+	router.HandleFunc("GET /v1/monitors/noise", noiseHandler.GetNoiseReport)
 	utils.EndpointWithPathParams(router, "GET", "/v1/monitors/{id}", "id", monitors.GetMonitorByID)
 
+	// Watchdog stories
+	utils.Endpoint(router, "GET", "/v1/watchdog/stories", watchdogHandler.ListStories)
+
 	// Logs
 	utils.Endpoint(router, "POST", "/v1/logs/search", logs.SearchLogs)
 	utils.Endpoint(router, "POST", "/v1/logs/search/advanced", logs.SearchLogsAdvanced)
@@ -309,13 +561,38 @@ func (d *DDogServer) Run(ctx context.Context) error {
 		  POST /v1/webhooks/receive, /v1/webhooks/receive/{account}
 		  GET  /v1/webhooks/events, /v1/webhooks/stats
 		  GET  /v1/webhooks/dispatcher/stats
+		  GET  /v1/webhooks/monitor/{id}/insights (alert baseline and anomalies)
 		  POST /v1/webhooks/github/issues (GitHub Issue webhook)
+		  POST /v1/webhooks/github (issues, push and release events)
+		  GET  /v1/changes, /v1/changes/{id}, POST /v1/changes (deploys, config changes; operator token)
 		  GET  /v1/webhooks/github/issues, /v1/webhooks/github/issues/{id}
 		  GET  /v1/webhooks/github/issues/stats
 		  GET  /v1/agents/stats
-		  POST /v1/rum/init, /v1/rum/track
+		  POST /v1/agents/classify (explain alert routing)
+		  GET  /v1/agents/classifier/rules, POST|DELETE /v1/agents/classifier/rules, POST /v1/agents/classifier/reload (operator token)
+		  GET  /v1/agents/analyses, /v1/agents/analyses/{id}/stream (SSE or WebSocket)
+		  GET  /v1/agents/analyses/{id}, POST /v1/agents/analyses/{id}/ask (follow-up questions)
+		  GET  /v1/remediation/actions, /v1/remediation/actions/{id} (with audit log)
+		  POST /v1/remediation/actions, /{id}/approve, /{id}/reject (operator token; no self-approval)
+		  POST /v1/remediation/slack/interactions
+		  GET  /v1/incidents/{id}/postmortem (?format=markdown&summarize=true)
+		  POST /v1/incidents/{id}/postmortem/summary (agent summary, stored)
+		  POST /v1/incidents/{id}/postmortem/notebook, /v1/incidents/{id}/ack
+		  POST /v1/rum/init, /v1/rum/track, /v1/rum/batch (arrays, sendBeacon)
+		  POST /v1/rum/vitals (LCP, INP, CLS, FCP, TTFB, resources, long tasks)
+		  GET  /v1/rum/batch/stats, /v1/rum/analytics/vitals (p50/p75/p95 by page, device, browser)
+		  POST /v1/rum/errors (grouped by fingerprint), /v1/rum/sourcemaps?release=&file=
+		  GET  /v1/rum/errors, /v1/rum/errors/{fingerprint}
+		  POST|GET /v1/rum/session/{id}/replay (rrweb chunks, masked at ingestion)
+		  GET  /v1/rum/funnels/{id}, /v1/rum/paths (by period, device, browser)
+		  GET  /v1/rum/analytics/cohorts (weekly/monthly retention), /v1/rum/analytics/active (DAU/WAU/MAU)
+		  GET  /v1/rum/analytics/rollups (hourly/daily views, sessions, errors, vitals by page, device, country)
 		  GET  /v1/rum/analytics, /v1/rum/visitors
+		  POST /v1/rum/visitor/{uuid}/consent, GET /v1/rum/visitor/{uuid}/export (operator token)
+		  DELETE /v1/rum/visitor/{uuid} (erasure), GET /v1/rum/privacy/audit (operator token)
 		  GET  /v1/monitors, /v1/monitors/triggered, /v1/monitors/{id}
+		  GET  /v1/monitors/noise (noisy-monitor report, ?format=csv)
+		  GET  /v1/watchdog/stories (grouped Watchdog stories)
 		  POST /v1/logs/search
 		  GET  /v1/services
 		  POST /v1/services/definitions
@@ -327,9 +604,37 @@ func (d *DDogServer) Run(ctx context.Context) error {
 		Concurrency Architecture:
 		  Workers:       %d (dispatcher)
 		  Queue Size:    %d (buffered)
-		  Max Agents:    %d (concurrent)
+		  Max Agents:    %d (concurrent, priority queue of %d, +%.0f/min aging)
+		  Collaboration: %d (roles per alert)
+		  Agent Budget:  %d/hour, %d tokens/day, %s monitor cooldown (0 = unlimited)
+		  Circuit:       opens after %d failures for %s (heuristic fallback)
+		  Remediation:   dry_run=%v, approvals expire after %s
+		  Operators:     %d (OPERATOR_TOKENS; 0 = API approvals disabled)
+		  Baselines:     %s window, refreshed every %s (0 = on demand)
+		  RUM Writer:    batches of %d, flushed every %s, queue %d
+		  RUM Replay:    stored in %s, kept %s, mask_all_inputs=%v
+		  RUM GeoIP:     %s
+		  RUM Bots:      %d rules (excluded from analytics unless include_bots=true)
+		  RUM Privacy:   require_consent=%v, IP salt rotated every %s, %d extra scrub fields
+		  RUM Rollups:   every %s, analytics from rollups over %s, raw events kept %s (0 = forever)
+		  Noise Report:  %s window, top %d, every %s (0 = on demand)
+		  Watchdog:      %s story grouping, sidecar=%v
 		  Accounts:      %v (cached by name)
-	`, d.addr, dispatcherConfig.Workers, dispatcherConfig.QueueSize, agentOrchConfig.MaxConcurrent, accountStats["cached_by_name"])
+		  Cassettes:     %q (HTTP_CASSETTE_MODE; record/replay in %s)
+	`, d.addr, dispatcherConfig.Workers, dispatcherConfig.QueueSize, agentOrchConfig.MaxConcurrent, agentOrchConfig.MaxQueued, agentOrchConfig.PriorityAgingPerMinute, agentOrchConfig.CollaborationMaxRoles,
+		agentOrchConfig.Budget.GlobalAnalysesPerHour, agentOrchConfig.Budget.GlobalTokensPerDay, agentOrchConfig.Budget.MonitorCooldown,
+		agentOrchConfig.CircuitThreshold, agentOrchConfig.CircuitCooldown,
+		remediationConfig.DryRun, remediationConfig.ApprovalTTL,
+		operators.Count(),
+		baselineConfig.Window, baselineConfig.RefreshInterval,
+		rumWriterConfig.BatchSize, rumWriterConfig.FlushInterval, rumWriterConfig.QueueSize,
+		replayBackend, replayConfig.Retention, replayConfig.MaskAllInputs,
+		rumGeoIP.Databases(), rumBots.Rules(),
+		privacyConfig.RequireConsent, privacyConfig.SaltRotation, len(privacyConfig.ScrubFields),
+		rollupConfig.Interval, rollupConfig.AnalyticsRange, rollupConfig.EventsRetention,
+		noiseConfig.Window, noiseConfig.Limit, noiseConfig.Interval,
+		watchdogConfig.GroupWindow, watchdogConfig.UseSidecar, accountStats["cached_by_name"],
+		cassetteConfig.Mode, cassetteConfig.Dir)
 
 	// Wrap router with CORS and custom tracing middleware that properly propagates spans
 	// and tags errors for APM visibility
@@ -354,10 +659,19 @@ func (d *DDogServer) Run(ctx context.Context) error {
 			log.Printf("HTTP server shutdown error: %v", err)
 		}
 
+		// Flush buffered RUM events now that no more requests arrive
+		rumWriter.Shutdown(10 * time.Second)
+
 		// Shutdown dispatcher (drains worker pool)
 		if d.dispatcher != nil {
 			d.dispatcher.Shutdown()
 		}
+
+		// Let background analyses finish, then cancel stragglers
+		if !procOrch.WaitForAnalyses(60 * time.Second) {
+			log.Println("Agent analysis drain timeout, cancelling remaining analyses")
+		}
+		cancelAnalyses()
 	}()
 
 	log.Printf("HTTP server starting on %s", d.addr)
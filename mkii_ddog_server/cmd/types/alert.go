@@ -44,6 +44,7 @@ type AlertPayload struct {
 // AlertEvent represents a stored alert event
 type AlertEvent struct {
 	ID          int64        `json:"id"`
+	AccountName string       `json:"account_name,omitempty"`
 	Payload     AlertPayload `json:"payload"`
 	ReceivedAt  time.Time    `json:"received_at"`
 	ProcessedAt *time.Time   `json:"processed_at,omitempty"`
@@ -0,0 +1,543 @@
+package httpclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CassetteMode selects how a Recorder treats outgoing requests
+type CassetteMode string
+
+const (
+	CassetteOff    CassetteMode = ""       // pass requests through untouched
+	CassetteRecord CassetteMode = "record" // call the real server and save each interaction
+	CassetteReplay CassetteMode = "replay" // answer from the cassette; never touch the network
+)
+
+// ErrNoInteraction is returned in replay mode when the cassette has no
+// unused interaction matching the request
+var ErrNoInteraction = errors.New("no recorded interaction matches request")
+
+// Cassette is a recorded sequence of HTTP interactions
+type Cassette struct {
+	Name         string        `json:"name"`
+	RecordedAt   time.Time     `json:"recorded_at"`
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is one request/response pair
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	DurationMS int64            `json:"duration_ms"`
+}
+
+// RecordedRequest is a redacted outgoing request
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is a redacted response
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads a cassette file
+func LoadCassette(path string) (*Cassette, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read cassette: %w", err)
+	}
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("decode cassette %s: %w", path, err)
+	}
+	return &cassette, nil
+}
+
+// Save writes the cassette atomically (temp file in the same directory +
+// rename), so an interrupted write never leaves a truncated cassette
+func (c *Cassette) Save(path string) error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("create cassette dir: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("write cassette: %w", err)
+	}
+	return nil
+}
+
+// Recorder is an http.RoundTripper that records interactions to a cassette
+// file or replays them from it. Everything written to disk is redacted first,
+// and replay matches on the redacted form, so cassettes never hold secrets.
+// Recordings are kept in memory and written once by Close.
+type Recorder struct {
+	mode     CassetteMode
+	path     string
+	inner    http.RoundTripper
+	redactor *Redactor
+
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewRecorder creates a recorder for the cassette at path. Replay mode loads
+// the cassette; record mode starts a new one, replacing the file on Close if
+// anything was recorded. inner is the real transport (http.DefaultTransport
+// when nil).
+func NewRecorder(path string, mode CassetteMode, inner http.RoundTripper, redactor *Redactor) (*Recorder, error) {
+	if inner == nil {
+		inner = http.DefaultTransport
+	}
+	if redactor == nil {
+		redactor = NewRedactor()
+	}
+
+	r := &Recorder{
+		mode:     mode,
+		path:     path,
+		inner:    inner,
+		redactor: redactor,
+		cassette: &Cassette{
+			Name:       strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)),
+			RecordedAt: time.Now().UTC(),
+		},
+	}
+
+	switch mode {
+	case CassetteOff, CassetteRecord:
+	case CassetteReplay:
+		cassette, err := LoadCassette(path)
+		if err != nil {
+			return nil, err
+		}
+		r.cassette = cassette
+		r.used = make([]bool, len(cassette.Interactions))
+	default:
+		return nil, fmt.Errorf("unknown cassette mode %q (want record or replay)", mode)
+	}
+	return r, nil
+}
+
+// Mode returns the recorder's mode
+func (r *Recorder) Mode() CassetteMode {
+	return r.mode
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch r.mode {
+	case CassetteReplay:
+		return r.replay(req)
+	case CassetteRecord:
+		return r.record(req)
+	default:
+		return r.inner.RoundTrip(req)
+	}
+}
+
+// record performs the request and appends the redacted interaction
+func (r *Recorder) record(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := r.inner.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	duration := time.Since(start)
+
+	respBody, err := drainBody(&resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read response body: %w", err)
+	}
+
+	interaction := Interaction{
+		Request:    r.redactor.request(req.Method, req.URL, req.Header, reqBody),
+		Response:   r.redactor.response(resp.StatusCode, resp.Header, respBody),
+		DurationMS: duration.Milliseconds(),
+	}
+
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+	return resp, nil
+}
+
+// Close writes the recorded interactions to the cassette file (record mode
+// only; a recorder that saw no requests leaves an existing file alone).
+// Interactions are scrubbed once more first, so a credential first seen in a
+// later request is also removed from earlier ones.
+func (r *Recorder) Close() error {
+	if r.mode != CassetteRecord {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.cassette.Interactions) == 0 {
+		return nil
+	}
+	for i := range r.cassette.Interactions {
+		r.redactor.rescrub(&r.cassette.Interactions[i])
+	}
+	return r.cassette.Save(r.path)
+}
+
+// replay answers from the first unused matching interaction. An interaction
+// whose body also matches is preferred, so repeated calls to one endpoint
+// with different payloads replay in the right order.
+func (r *Recorder) replay(req *http.Request) (*http.Response, error) {
+	reqBody, err := drainBody(&req.Body)
+	if err != nil {
+		return nil, fmt.Errorf("read request body: %w", err)
+	}
+	want := r.redactor.request(req.Method, req.URL, req.Header, reqBody)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || interaction.Request.Method != want.Method || interaction.Request.URL != want.URL {
+			continue
+		}
+		if interaction.Request.Body == want.Body {
+			match = i
+			break
+		}
+		if match < 0 {
+			match = i
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("%w: %s %s (cassette %s)", ErrNoInteraction, want.Method, want.URL, r.path)
+	}
+	r.used[match] = true
+
+	recorded := r.cassette.Interactions[match].Response
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = make(http.Header)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Unused returns the interactions replay has not served yet
+func (r *Recorder) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var unused []Interaction
+	for i, used := range r.used {
+		if !used {
+			unused = append(unused, r.cassette.Interactions[i])
+		}
+	}
+	return unused
+}
+
+// Interactions returns a copy of the recorded or loaded interactions
+func (r *Recorder) Interactions() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Interaction(nil), r.cassette.Interactions...)
+}
+
+// drainBody reads and restores a body so it can be sent (or read) again
+func drainBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+	data, err := io.ReadAll(*body)
+	(*body).Close()
+	*body = io.NopCloser(bytes.NewReader(data))
+	return data, err
+}
+
+// Redaction placeholder written in place of secrets
+const Redacted = "REDACTED"
+
+// minSecretLen is the shortest literal value scrubbed, to avoid redacting
+// ordinary text
+const minSecretLen = 8
+
+// Redactor scrubs credentials from recorded interactions
+type Redactor struct {
+	headers map[string]bool // canonical header names
+	fields  []string        // lower-cased substrings of JSON field and query parameter names
+	sources []func() []string
+
+	mu     sync.RWMutex
+	values map[string]bool // literal secrets replaced wherever they appear
+}
+
+// NewRedactor redacts Datadog and bearer credentials, cookies, and JSON
+// fields or query parameters that look like keys, tokens or passwords.
+// The DD_API_KEY and DD_APP_KEY values, and every value sent in a redacted
+// header (e.g. a per-account DD-API-KEY), are scrubbed wherever they appear.
+func NewRedactor() *Redactor {
+	r := &Redactor{
+		headers: make(map[string]bool),
+		fields:  []string{"api_key", "apikey", "app_key", "application_key", "password", "secret", "token"},
+		values:  make(map[string]bool),
+	}
+	r.AddHeaders("DD-API-KEY", "DD-APPLICATION-KEY", "Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie", "X-Api-Key", "X-Slack-Signature")
+	r.AddValues(os.Getenv("DD_API_KEY"), os.Getenv("DD_APP_KEY"))
+	return r
+}
+
+// AddHeaders redacts additional headers
+func (r *Redactor) AddHeaders(names ...string) *Redactor {
+	for _, name := range names {
+		r.headers[http.CanonicalHeaderKey(name)] = true
+	}
+	return r
+}
+
+// AddFields redacts JSON fields and query parameters whose names contain any of substrings
+func (r *Redactor) AddFields(substrings ...string) *Redactor {
+	for _, s := range substrings {
+		r.fields = append(r.fields, strings.ToLower(s))
+	}
+	return r
+}
+
+// AddValues redacts literal secret values anywhere in URLs, headers and bodies.
+// Values shorter than 8 characters are ignored to avoid scrubbing ordinary text.
+func (r *Redactor) AddValues(values ...string) *Redactor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, v := range values {
+		if len(v) >= minSecretLen {
+			r.values[v] = true
+		}
+	}
+	return r
+}
+
+// AddSecretSource redacts the values returned by source, asked again for
+// every interaction so credentials added at runtime (e.g. new Datadog
+// accounts) are covered
+func (r *Redactor) AddSecretSource(source func() []string) *Redactor {
+	if source != nil {
+		r.sources = append(r.sources, source)
+	}
+	return r
+}
+
+// learnHeaders adds the values of redacted headers to the literal secrets,
+// so a credential sent in a header is also scrubbed if it is echoed in a
+// URL or body. "Bearer <token>" style values contribute the token too.
+func (r *Redactor) learnHeaders(header http.Header) {
+	var learned []string
+	for name, values := range header {
+		if !r.headers[http.CanonicalHeaderKey(name)] {
+			continue
+		}
+		for _, v := range values {
+			learned = append(learned, v)
+			if _, credential, ok := strings.Cut(v, " "); ok {
+				learned = append(learned, strings.TrimSpace(credential))
+			}
+		}
+	}
+	r.AddValues(learned...)
+}
+
+// secrets returns every literal secret, longest first so a secret that
+// contains another is replaced whole
+func (r *Redactor) secrets() []string {
+	r.mu.RLock()
+	secrets := make([]string, 0, len(r.values))
+	for v := range r.values {
+		secrets = append(secrets, v)
+	}
+	r.mu.RUnlock()
+
+	for _, source := range r.sources {
+		for _, v := range source() {
+			if len(v) >= minSecretLen {
+				secrets = append(secrets, v)
+			}
+		}
+	}
+	sort.Slice(secrets, func(i, j int) bool { return len(secrets[i]) > len(secrets[j]) })
+	return secrets
+}
+
+// request returns the redacted form of a request
+func (r *Redactor) request(method string, u *url.URL, header http.Header, body []byte) RecordedRequest {
+	r.learnHeaders(header)
+	return RecordedRequest{
+		Method:  method,
+		URL:     r.url(u),
+		Headers: r.header(header),
+		Body:    r.body(body),
+	}
+}
+
+// response returns the redacted form of a response
+func (r *Redactor) response(status int, header http.Header, body []byte) RecordedResponse {
+	return RecordedResponse{
+		Status:  status,
+		Headers: r.header(header),
+		Body:    r.body(body),
+	}
+}
+
+// rescrub replaces literal secrets in an already redacted interaction
+func (r *Redactor) rescrub(interaction *Interaction) {
+	interaction.Request.URL = r.scrub(interaction.Request.URL)
+	interaction.Request.Body = r.scrub(interaction.Request.Body)
+	interaction.Response.Body = r.scrub(interaction.Response.Body)
+	for _, header := range []http.Header{interaction.Request.Headers, interaction.Response.Headers} {
+		for _, values := range header {
+			for i, v := range values {
+				values[i] = r.scrub(v)
+			}
+		}
+	}
+}
+
+// url redacts sensitive query parameters and sorts the query so matching
+// doesn't depend on parameter order
+func (r *Redactor) url(u *url.URL) string {
+	clean := *u
+	clean.User = nil
+	query := clean.Query()
+	for name := range query {
+		if r.sensitiveField(name) {
+			query[name] = []string{Redacted}
+		}
+	}
+	clean.RawQuery = query.Encode()
+	return r.scrub(clean.String())
+}
+
+// header redacts sensitive headers; hop-by-hop and length headers are dropped
+func (r *Redactor) header(header http.Header) http.Header {
+	if len(header) == 0 {
+		return nil
+	}
+	clean := make(http.Header, len(header))
+	for name, values := range header {
+		switch name {
+		case "Content-Length", "Connection", "Date", "Keep-Alive", "Transfer-Encoding":
+			continue
+		}
+		if r.headers[http.CanonicalHeaderKey(name)] {
+			clean[name] = []string{Redacted}
+			continue
+		}
+		for _, v := range values {
+			clean[name] = append(clean[name], r.scrub(v))
+		}
+	}
+	return clean
+}
+
+// body redacts sensitive fields in JSON bodies (re-encoded with sorted keys)
+// and literal secrets in any body
+func (r *Redactor) body(body []byte) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var doc any
+	if err := json.Unmarshal(body, &doc); err == nil {
+		if encoded, err := json.Marshal(r.redactJSON(doc)); err == nil {
+			body = encoded
+		}
+	}
+	return r.scrub(string(body))
+}
+
+// redactJSON replaces sensitive fields throughout a decoded JSON document
+func (r *Redactor) redactJSON(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, field := range v {
+			if _, isString := field.(string); isString && r.sensitiveField(k) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = r.redactJSON(field)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = r.redactJSON(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// sensitiveField reports whether a field or parameter name looks like a credential
+func (r *Redactor) sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, f := range r.fields {
+		if strings.Contains(name, f) {
+			return true
+		}
+	}
+	return false
+}
+
+// scrub replaces literal secret values
+func (r *Redactor) scrub(s string) string {
+	for _, v := range r.secrets() {
+		s = strings.ReplaceAll(s, v, Redacted)
+	}
+	return s
+}
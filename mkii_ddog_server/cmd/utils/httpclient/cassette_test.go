@@ -0,0 +1,214 @@
+package httpclient
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecorderRecordsRedactedAndReplays(t *testing.T) {
+	t.Setenv("DD_API_KEY", "live-api-key-0123456789")
+
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=abc")
+		w.Write([]byte(`{"echo": ` + string(body) + `, "path": "` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "datadog.json")
+	recorder, err := NewRecorder(path, CassetteRecord, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder(record) error = %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+
+	req, _ := http.NewRequest("POST", server.URL+"/api/v1/validate?b=2&api_key=live-api-key-0123456789&a=1",
+		strings.NewReader(`{"name": "cpu", "app_key": "secret-app-key", "note": "key live-api-key-0123456789"}`))
+	req.Header.Set("DD-API-KEY", "live-api-key-0123456789")
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("record request error = %v", err)
+	}
+	recordedBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.Contains(string(recordedBody), "secret-app-key") {
+		t.Errorf("recording changed the live response: %s", recordedBody)
+	}
+
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("cassette written before Close: %v", err)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+	cassette, err := LoadCassette(path)
+	if err != nil {
+		t.Fatalf("LoadCassette() error = %v", err)
+	}
+	if len(cassette.Interactions) != 1 {
+		t.Fatalf("interactions = %d, want 1", len(cassette.Interactions))
+	}
+	got := cassette.Interactions[0]
+	for _, s := range []string{got.Request.URL, got.Request.Body, got.Response.Body, strings.Join(got.Request.Headers["Dd-Api-Key"], "")} {
+		if strings.Contains(s, "live-api-key") || strings.Contains(s, "secret-app-key") {
+			t.Errorf("cassette leaks a secret: %s", s)
+		}
+	}
+	if got.Request.Headers.Get("DD-API-KEY") != Redacted || got.Response.Headers.Get("Set-Cookie") != Redacted {
+		t.Errorf("headers not redacted: %v / %v", got.Request.Headers, got.Response.Headers)
+	}
+	if !strings.HasSuffix(got.Request.URL, "/api/v1/validate?a=1&api_key=REDACTED&b=2") {
+		t.Errorf("URL = %s, want sorted query with redacted api_key", got.Request.URL)
+	}
+
+	server.Close()
+	replayer, err := NewRecorder(path, CassetteReplay, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder(replay) error = %v", err)
+	}
+	client = &http.Client{Transport: replayer}
+
+	// Same request with the query in another order and a different key still matches
+	req, _ = http.NewRequest("POST", server.URL+"/api/v1/validate?a=1&b=2&api_key=other-key-0000000000",
+		strings.NewReader(`{"name": "cpu", "app_key": "another", "note": "key"}`))
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("replay request error = %v", err)
+	}
+	replayedBody, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(replayedBody), `"path":"/api/v1/validate"`) {
+		t.Errorf("replay = %d %s", resp.StatusCode, replayedBody)
+	}
+	if hits != 1 {
+		t.Errorf("server hits = %d, want 1 (replay must not touch the network)", hits)
+	}
+
+	// The interaction is consumed
+	req, _ = http.NewRequest("POST", server.URL+"/api/v1/validate?a=1&b=2", nil)
+	if _, err := client.Do(req); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("second replay error = %v, want ErrNoInteraction", err)
+	}
+	if len(replayer.Unused()) != 0 {
+		t.Errorf("Unused() = %d, want 0", len(replayer.Unused()))
+	}
+}
+
+func TestRecorderScrubsAccountCredentials(t *testing.T) {
+	// Keys of a non-default account: not in the environment, only in headers
+	// and the credential provider
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"seen": "` + r.Header.Get("DD-API-KEY") + `/` + r.Header.Get("DD-APPLICATION-KEY") + `", "token": "provider-secret-42"}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "datadog.json")
+	redactor := NewRedactor().AddSecretSource(func() []string { return []string{"provider-secret-42", "short"} })
+	recorder, err := NewRecorder(path, CassetteRecord, nil, redactor)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+
+	req, _ := http.NewRequest("GET", server.URL+"/api/v1/monitor", nil)
+	req.Header.Set("DD-API-KEY", "account-api-key-abcdef")
+	req.Header.Set("DD-APPLICATION-KEY", "account-app-key-ghijkl")
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("record request error = %v", err)
+	}
+	resp.Body.Close()
+	if err := recorder.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read cassette: %v", err)
+	}
+	for _, secret := range []string{"account-api-key-abcdef", "account-app-key-ghijkl", "provider-secret-42"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette leaks %s: %s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "/api/v1/monitor") {
+		t.Errorf("cassette lost the request: %s", data)
+	}
+}
+
+func TestRecorderReplayPrefersMatchingBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.json")
+	cassette := &Cassette{Name: "agent", Interactions: []Interaction{
+		{Request: RecordedRequest{Method: "POST", URL: "http://sidecar/analyze", Body: `{"monitor_id":1}`},
+			Response: RecordedResponse{Status: 200, Body: "one"}},
+		{Request: RecordedRequest{Method: "POST", URL: "http://sidecar/analyze", Body: `{"monitor_id":2}`},
+			Response: RecordedResponse{Status: 200, Body: "two"}},
+	}}
+	if err := cassette.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	recorder, err := NewRecorder(path, CassetteReplay, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRecorder() error = %v", err)
+	}
+	client := &http.Client{Transport: recorder}
+
+	for _, tc := range []struct{ body, want string }{
+		{`{"monitor_id": 2}`, "two"},
+		{`{"monitor_id": 1}`, "one"},
+	} {
+		resp, err := client.Post("http://sidecar/analyze", "application/json", strings.NewReader(tc.body))
+		if err != nil {
+			t.Fatalf("replay %s error = %v", tc.body, err)
+		}
+		body, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if string(body) != tc.want {
+			t.Errorf("replay %s = %q, want %q", tc.body, body, tc.want)
+		}
+	}
+}
+
+func TestUseCassettesSwapsSharedClients(t *testing.T) {
+	dir := t.TempDir()
+	cassette := &Cassette{Name: "agent", Interactions: []Interaction{
+		{Request: RecordedRequest{Method: "GET", URL: "http://sidecar/health"},
+			Response: RecordedResponse{Status: 200, Body: `{"status":"ok"}`}},
+	}}
+	if err := cassette.Save(filepath.Join(dir, "agent.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	original := AgentClient.Transport
+	restore, err := UseCassettes(CassetteConfig{Mode: CassetteReplay, Dir: dir})
+	if err != nil {
+		t.Fatalf("UseCassettes() error = %v", err)
+	}
+
+	resp, err := AgentClient.Get("http://sidecar/health")
+	if err != nil {
+		t.Fatalf("AgentClient replay error = %v", err)
+	}
+	resp.Body.Close()
+
+	// No datadog.json: replay fails instead of calling out
+	if _, err := DatadogClient.Get("http://datadog.invalid/api/v1/validate"); !errors.Is(err, ErrNoInteraction) {
+		t.Errorf("DatadogClient error = %v, want ErrNoInteraction", err)
+	}
+
+	restore()
+	if AgentClient.Transport != original {
+		t.Error("restore did not put the original transport back")
+	}
+}
@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// CassetteConfig selects record/replay mode for the shared clients
+type CassetteConfig struct {
+	Mode CassetteMode
+	Dir  string
+
+	// Secrets returns credentials to scrub from recordings besides
+	// DD_API_KEY/DD_APP_KEY and redacted headers (e.g. every Datadog
+	// account's keys); asked for each interaction. Optional.
+	Secrets func() []string
+}
+
+// CassetteConfigFromEnv reads HTTP_CASSETTE_MODE (record or replay; empty
+// disables) and HTTP_CASSETTE_DIR (default testdata/cassettes)
+func CassetteConfigFromEnv() CassetteConfig {
+	config := CassetteConfig{
+		Mode: CassetteMode(os.Getenv("HTTP_CASSETTE_MODE")),
+		Dir:  os.Getenv("HTTP_CASSETTE_DIR"),
+	}
+	if config.Dir == "" {
+		config.Dir = filepath.Join("testdata", "cassettes")
+	}
+	return config
+}
+
+// cassetteClients are the shared clients that can be recorded, by cassette name.
+// Notification and forwarding clients are left out: their URLs embed webhook secrets.
+var cassetteClients = []struct {
+	name   string
+	client *http.Client
+}{
+	{"datadog", DatadogClient},
+	{"agent", AgentClient},
+	{"requests", RequestsClient},
+	{"default", DefaultClient},
+}
+
+// UseCassettes switches the shared Datadog, agent, requests and default
+// clients to record or replay <dir>/<name>.json. The returned restore func
+// puts the original transports back and, when recording, writes the
+// cassettes. CassetteOff is a no-op.
+func UseCassettes(config CassetteConfig) (restore func(), err error) {
+	if config.Mode == CassetteOff {
+		return func() {}, nil
+	}
+	newRedactor := func() *Redactor {
+		return NewRedactor().AddSecretSource(config.Secrets)
+	}
+
+	recorders := make([]*Recorder, len(cassetteClients))
+	for i, c := range cassetteClients {
+		path := filepath.Join(config.Dir, c.name+".json")
+		if config.Mode == CassetteReplay {
+			if _, err := os.Stat(path); os.IsNotExist(err) {
+				// No cassette for this client: replay must still never hit the network
+				recorders[i] = &Recorder{mode: CassetteReplay, path: path, redactor: newRedactor(), cassette: &Cassette{Name: c.name}}
+				continue
+			}
+		}
+		recorder, err := NewRecorder(path, config.Mode, c.client.Transport, newRedactor())
+		if err != nil {
+			return nil, fmt.Errorf("cassette %s: %w", c.name, err)
+		}
+		recorders[i] = recorder
+	}
+
+	originals := make([]http.RoundTripper, len(cassetteClients))
+	for i, c := range cassetteClients {
+		originals[i] = c.client.Transport
+		c.client.Transport = recorders[i]
+	}
+	log.Printf("[HTTPCLIENT] Cassette %s mode enabled (dir %s)", config.Mode, config.Dir)
+
+	return func() {
+		for i, c := range cassetteClients {
+			c.client.Transport = originals[i]
+			if err := recorders[i].Close(); err != nil {
+				log.Printf("[HTTPCLIENT] Failed to write cassette %s: %v", c.name, err)
+			}
+		}
+	}, nil
+}
+
+// UseCassette switches a single client to record or replay path (tests that
+// stub one dependency). The returned restore func puts the original transport
+// back and, when recording, writes the cassette.
+func UseCassette(client *http.Client, path string, mode CassetteMode) (*Recorder, func(), error) {
+	recorder, err := NewRecorder(path, mode, client.Transport, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	original := client.Transport
+	client.Transport = recorder
+	return recorder, func() {
+		client.Transport = original
+		if err := recorder.Close(); err != nil {
+			log.Printf("[HTTPCLIENT] Failed to write cassette %s: %v", path, err)
+		}
+	}, nil
+}
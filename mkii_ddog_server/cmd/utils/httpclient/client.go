@@ -87,3 +87,11 @@ var DatadogClient = httptrace.WrapClient(&http.Client{
 		}).DialContext,
 	},
 })
+
+// RequestsClient is the shared HTTP client behind the requests package's
+// generic Datadog helpers. Spans are named after the method and path.
+var RequestsClient = httptrace.WrapClient(&http.Client{
+	Timeout: 30 * time.Second,
+}, httptrace.RTWithResourceNamer(func(req *http.Request) string {
+	return req.Method + " " + req.URL.Path
+}))
@@ -0,0 +1,116 @@
+// Package operator authenticates human operators on privileged endpoints
+// (remediation approvals, visitor erasure). The API has no user accounts, so
+// each operator gets a bearer token; the name it maps to is the identity
+// written to audit logs, never a name supplied by the client.
+package operator
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// ErrUnauthenticated is returned when a request carries no valid operator token
+var ErrUnauthenticated = errors.New("operator token required")
+
+// Authenticator maps bearer tokens and Slack users to operator names
+type Authenticator struct {
+	tokens     map[[sha256.Size]byte]string // token hash -> operator name
+	slackUsers map[string]string            // Slack user ID -> operator name
+}
+
+// NewAuthenticator creates an authenticator from operator name -> token.
+// Entries with an empty name or token are ignored.
+func NewAuthenticator(tokens map[string]string) *Authenticator {
+	a := &Authenticator{tokens: make(map[[sha256.Size]byte]string)}
+	for name, token := range tokens {
+		name, token = strings.TrimSpace(name), strings.TrimSpace(token)
+		if name != "" && token != "" {
+			a.tokens[sha256.Sum256([]byte(token))] = name
+		}
+	}
+	return a
+}
+
+// FromEnv reads OPERATOR_TOKENS as comma-separated "name:token" pairs, e.g.
+// "alice:3f9c...,bob:81d2...", and OPERATOR_SLACK_USERS as "name:slack user
+// ID" pairs, e.g. "alice:U024BE7LH". With none set every request is refused.
+func FromEnv() *Authenticator {
+	a := NewAuthenticator(pairsFromEnv("OPERATOR_TOKENS"))
+	a.SetSlackUsers(pairsFromEnv("OPERATOR_SLACK_USERS"))
+	return a
+}
+
+// pairsFromEnv parses comma-separated "name:value" pairs
+func pairsFromEnv(key string) map[string]string {
+	pairs := make(map[string]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		if name, value, ok := strings.Cut(pair, ":"); ok {
+			pairs[name] = value
+		}
+	}
+	return pairs
+}
+
+// SetSlackUsers maps operator name -> Slack user ID, so a decision made in
+// Slack resolves to the same identity as that operator's token. Entries
+// with an empty name or ID are ignored.
+func (a *Authenticator) SetSlackUsers(users map[string]string) {
+	a.slackUsers = make(map[string]string)
+	for name, userID := range users {
+		name, userID = strings.TrimSpace(name), strings.TrimSpace(userID)
+		if name != "" && userID != "" {
+			a.slackUsers[userID] = name
+		}
+	}
+}
+
+// Enabled reports whether any operator token is configured
+func (a *Authenticator) Enabled() bool {
+	return a.Count() > 0
+}
+
+// Count returns the number of configured operators
+func (a *Authenticator) Count() int {
+	if a == nil {
+		return 0
+	}
+	return len(a.tokens)
+}
+
+// Identify returns the operator named by the request's
+// "Authorization: Bearer <token>" header as "operator:<name>"
+func (a *Authenticator) Identify(r *http.Request) (string, error) {
+	if !a.Enabled() {
+		return "", ErrUnauthenticated
+	}
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+		return "", ErrUnauthenticated
+	}
+
+	// Compare hashes in constant time so lookups don't leak token prefixes
+	sum := sha256.Sum256([]byte(strings.TrimSpace(token)))
+	for hash, name := range a.tokens {
+		if subtle.ConstantTimeCompare(hash[:], sum[:]) == 1 {
+			return "operator:" + name, nil
+		}
+	}
+	return "", ErrUnauthenticated
+}
+
+// IdentifySlack returns the operator mapped to a Slack user ID as
+// "operator:<name>". Slack users without a mapping are refused.
+func (a *Authenticator) IdentifySlack(userID string) (string, error) {
+	if a == nil {
+		return "", ErrUnauthenticated
+	}
+	name, ok := a.slackUsers[strings.TrimSpace(userID)]
+	if !ok {
+		return "", ErrUnauthenticated
+	}
+	return "operator:" + name, nil
+}
@@ -0,0 +1,70 @@
+package operator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAuthenticator_Identify(t *testing.T) {
+	t.Setenv("OPERATOR_TOKENS", "alice:s3cret, bob : t0ken ,broken,:orphan")
+	auth := FromEnv()
+
+	tests := map[string]struct {
+		header string
+		want   string
+	}{
+		"alice":            {"Bearer s3cret", "operator:alice"},
+		"trimmed pair":     {"bearer t0ken", "operator:bob"},
+		"wrong token":      {"Bearer nope", ""},
+		"no scheme":        {"s3cret", ""},
+		"basic auth":       {"Basic s3cret", ""},
+		"missing":          {"", ""},
+		"empty name token": {"Bearer orphan", ""},
+	}
+	for name, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.header != "" {
+			r.Header.Set("Authorization", tt.header)
+		}
+		got, err := auth.Identify(r)
+		if got != tt.want || (tt.want == "") != errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%s: got %q (%v), want %q", name, got, err, tt.want)
+		}
+	}
+}
+
+func TestAuthenticator_DisabledRefusesEverything(t *testing.T) {
+	t.Setenv("OPERATOR_TOKENS", "")
+	auth := FromEnv()
+	if auth.Enabled() {
+		t.Fatal("expected no operators")
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	if _, err := auth.Identify(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated, got %v", err)
+	}
+	var nilAuth *Authenticator
+	if _, err := nilAuth.Identify(r); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a nil authenticator to refuse, got %v", err)
+	}
+}
+
+func TestAuthenticator_IdentifySlack(t *testing.T) {
+	t.Setenv("OPERATOR_TOKENS", "alice:s3cret")
+	t.Setenv("OPERATOR_SLACK_USERS", "alice: U1 ,bob:U2,:U3")
+	auth := FromEnv()
+
+	for userID, want := range map[string]string{"U1": "operator:alice", "U2": "operator:bob", "U3": "", "U9": "", "": ""} {
+		got, err := auth.IdentifySlack(userID)
+		if got != want || (want == "") != errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%q: got %q (%v), want %q", userID, got, err, want)
+		}
+	}
+	var nilAuth *Authenticator
+	if _, err := nilAuth.IdentifySlack("U1"); !errors.Is(err, ErrUnauthenticated) {
+		t.Fatalf("expected a nil authenticator to refuse, got %v", err)
+	}
+}
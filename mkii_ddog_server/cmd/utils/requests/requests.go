@@ -8,19 +8,15 @@ import (
 	"io"
 	"log"
 	"net/http"
-	"time"
 
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
-	httptrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/net/http"
 )
 
-// tracedClient is a shared HTTP client with Datadog APM tracing enabled
-// for all outgoing requests to the Datadog API
-var tracedClient = httptrace.WrapClient(&http.Client{
-	Timeout: 30 * time.Second,
-}, httptrace.RTWithResourceNamer(func(req *http.Request) string {
-	return req.Method + " " + req.URL.Path
-}))
+// tracedClient is the shared HTTP client with Datadog APM tracing enabled
+// for all outgoing requests to the Datadog API (record/replay capable, see
+// httpclient.UseCassettes)
+var tracedClient = httpclient.RequestsClient
 
 func Get[T any](w http.ResponseWriter, r *http.Request, url string) (T, int, error) {
 	var parsedResponse T
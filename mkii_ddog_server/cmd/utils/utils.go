@@ -58,6 +58,16 @@ func GetEnv(key string, fallback string) string {
 	return value
 }
 
+// FirstNonEmpty returns the first non-empty string, or "" when all are empty
+func FirstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
 func ParseJson[T any](r *http.Request, payload *T) (int, any, error) {
 	zero := new(*T)
 	if r.Body == nil {
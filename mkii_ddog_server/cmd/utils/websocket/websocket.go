@@ -0,0 +1,304 @@
+// Package websocket is a minimal server-side RFC 6455 implementation for
+// pushing JSON to browsers and CLIs. It supports text frames from the server,
+// answers pings, and honours client close frames. Client data frames are read
+// and discarded; it is not a general-purpose WebSocket library.
+package websocket
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// acceptGUID is the fixed GUID from RFC 6455 section 1.3
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Frame opcodes
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xA
+)
+
+// maxClientFrame caps client frames; this server only expects control frames
+const maxClientFrame = 1 << 20
+
+// writeTimeout bounds each frame write so a stalled client can't block the sender
+const writeTimeout = 10 * time.Second
+
+// ErrClosed is returned when writing to a closed connection
+var ErrClosed = errors.New("websocket: connection closed")
+
+// ErrForbiddenOrigin is returned by Upgrade for a browser request from an
+// origin that isn't allowed; callers should answer 403
+var ErrForbiddenOrigin = errors.New("websocket: origin not allowed")
+
+// DefaultAllowedOrigins is the local frontend
+var DefaultAllowedOrigins = []string{"http://localhost:3000"}
+
+// AllowedOriginsFromEnv reads FRONTEND_ORIGINS (comma-separated, e.g.
+// "https://n0kos.com,https://www.n0kos.com"), defaulting to the local frontend
+func AllowedOriginsFromEnv() []string {
+	v := os.Getenv("FRONTEND_ORIGINS")
+	if v == "" {
+		return DefaultAllowedOrigins
+	}
+	var origins []string
+	for _, origin := range strings.Split(v, ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return origins
+}
+
+// Conn is an upgraded server-side WebSocket connection
+type Conn struct {
+	conn      net.Conn
+	rw        *bufio.ReadWriter
+	writeMu   sync.Mutex
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// IsUpgradeRequest reports whether r asks for a WebSocket upgrade
+func IsUpgradeRequest(r *http.Request) bool {
+	return headerContainsToken(r.Header, "Connection", "upgrade") &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket")
+}
+
+// Upgrade performs the opening handshake and takes over the connection.
+// Browser requests must come from the server's own origin or one of
+// allowedOrigins (see CheckOrigin); others fail with ErrForbiddenOrigin.
+// On error nothing has been written to w, so the caller can still respond.
+func Upgrade(w http.ResponseWriter, r *http.Request, allowedOrigins []string) (*Conn, error) {
+	if r.Method != http.MethodGet {
+		return nil, errors.New("websocket: method must be GET")
+	}
+	if !IsUpgradeRequest(r) {
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	if !CheckOrigin(r, allowedOrigins) {
+		return nil, ErrForbiddenOrigin
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, errors.New("websocket: unsupported version (need 13)")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, errors.New("websocket: missing Sec-WebSocket-Key")
+	}
+
+	netConn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: hijack: %w", err)
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := rw.WriteString(response); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket: write handshake: %w", err)
+	}
+	if err := rw.Flush(); err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("websocket: write handshake: %w", err)
+	}
+
+	c := &Conn{conn: netConn, rw: rw, done: make(chan struct{})}
+	go c.readLoop()
+	return c, nil
+}
+
+// Done is closed when the connection ends (peer close, read error or Close)
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// WriteText sends a text frame
+func (c *Conn) WriteText(data []byte) error {
+	return c.writeFrame(opText, data)
+}
+
+// WriteJSON encodes v and sends it as a text frame
+func (c *Conn) WriteJSON(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return c.WriteText(data)
+}
+
+// Close sends a normal-closure frame and closes the connection
+func (c *Conn) Close() error {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, 1000)
+	c.writeFrame(opClose, payload)
+	c.shutdown()
+	return nil
+}
+
+// shutdown closes the socket once
+func (c *Conn) shutdown() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// writeFrame writes a single unfragmented, unmasked frame
+func (c *Conn) writeFrame(opcode byte, payload []byte) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+
+	select {
+	case <-c.done:
+		return ErrClosed
+	default:
+	}
+
+	header := []byte{0x80 | opcode}
+	switch n := len(payload); {
+	case n < 126:
+		header = append(header, byte(n))
+	case n <= 0xFFFF:
+		header = append(header, 126, byte(n>>8), byte(n))
+	default:
+		header = append(header, 127)
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+
+	c.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+	if _, err := c.rw.Write(header); err != nil {
+		return err
+	}
+	if _, err := c.rw.Write(payload); err != nil {
+		return err
+	}
+	return c.rw.Flush()
+}
+
+// readLoop handles control frames from the client until the connection ends
+func (c *Conn) readLoop() {
+	defer c.shutdown()
+
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+
+		switch opcode {
+		case opClose:
+			c.writeFrame(opClose, payload)
+			return
+		case opPing:
+			c.writeFrame(opPong, payload)
+		}
+	}
+}
+
+// readFrame reads one client frame and unmasks its payload
+func (c *Conn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+
+	opcode := head[0] & 0x0F
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7F)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+
+	if !masked {
+		return 0, nil, errors.New("websocket: client frames must be masked")
+	}
+	if length > maxClientFrame {
+		return 0, nil, errors.New("websocket: client frame too large")
+	}
+
+	var mask [4]byte
+	if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+		return 0, nil, err
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return opcode, payload, nil
+}
+
+// acceptKey computes Sec-WebSocket-Accept for a client key
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// CheckOrigin reports whether r may open a WebSocket. Browsers always send
+// Origin, so without one the client isn't a web page (CLIs, curl) and
+// cross-site hijacking doesn't apply. Otherwise the origin must match the
+// request's host or one of allowed (scheme and host, case-insensitive).
+func CheckOrigin(r *http.Request, allowed []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, a := range allowed {
+		if strings.EqualFold(strings.TrimSuffix(a, "/"), u.Scheme+"://"+u.Host) {
+			return true
+		}
+	}
+	return false
+}
+
+// headerContainsToken checks a comma-separated header for a token (case-insensitive)
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, value := range h.Values(name) {
+		for _, part := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package websocket
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAcceptKey(t *testing.T) {
+	// Example from RFC 6455 section 1.3
+	if got := acceptKey("dGhlIHNhbXBsZSBub25jZQ=="); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept key %q", got)
+	}
+}
+
+func TestUpgradeAndWrite(t *testing.T) {
+	closed := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := Upgrade(w, r, nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		conn.WriteJSON(map[string]string{"type": "hello"})
+		<-conn.Done()
+		close(closed)
+	}))
+	defer server.Close()
+
+	c, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+
+	handshake := "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := c.Write([]byte(handshake)); err != nil {
+		t.Fatalf("write handshake: %v", err)
+	}
+
+	reader := bufio.NewReader(c)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatalf("read handshake response: %v", err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("expected 101, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept header %q", resp.Header.Get("Sec-WebSocket-Accept"))
+	}
+
+	head := make([]byte, 2)
+	if _, err := io.ReadFull(reader, head); err != nil {
+		t.Fatalf("read frame header: %v", err)
+	}
+	if head[0] != 0x80|opText {
+		t.Errorf("expected final text frame, got %#x", head[0])
+	}
+	payload := make([]byte, head[1]&0x7F)
+	if _, err := io.ReadFull(reader, payload); err != nil {
+		t.Fatalf("read payload: %v", err)
+	}
+	if string(payload) != `{"type":"hello"}` {
+		t.Errorf("unexpected payload %s", payload)
+	}
+
+	// Masked close frame with status 1000
+	mask := []byte{1, 2, 3, 4}
+	body := []byte{0x03, 0xE8}
+	frame := []byte{0x80 | opClose, 0x80 | byte(len(body))}
+	frame = append(frame, mask...)
+	for i, b := range body {
+		frame = append(frame, b^mask[i%4])
+	}
+	if _, err := c.Write(frame); err != nil {
+		t.Fatalf("write close: %v", err)
+	}
+
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not observe close")
+	}
+}
+
+func TestUpgradeRejectsPlainRequest(t *testing.T) {
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	if _, err := Upgrade(w, r, nil); err == nil {
+		t.Error("expected error for non-upgrade request")
+	}
+}
+
+func TestUpgradeChecksOrigin(t *testing.T) {
+	allowed := []string{"https://rayne.example.com/"}
+	for origin, ok := range map[string]bool{
+		"":                           true, // not a browser
+		"https://rayne.example.com":  true,
+		"HTTPS://Rayne.Example.com":  true,
+		"http://api.internal:8080":   true, // same origin as the request
+		"https://evil.example.com":   false,
+		"https://rayne.example.com.": false,
+		"null":                       false,
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://api.internal:8080/v1/agents/analyses/a1/stream", nil)
+		r.Header.Set("Connection", "Upgrade")
+		r.Header.Set("Upgrade", "websocket")
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		if got := CheckOrigin(r, allowed); got != ok {
+			t.Errorf("origin %q: got %v, want %v", origin, got, ok)
+		}
+		if !ok {
+			if _, err := Upgrade(httptest.NewRecorder(), r, allowed); !errors.Is(err, ErrForbiddenOrigin) {
+				t.Errorf("origin %q: expected ErrForbiddenOrigin, got %v", origin, err)
+			}
+		}
+	}
+}
@@ -214,6 +214,19 @@ func (m *AccountManager) GetAll() ([]Account, error) {
 	return m.storage.GetAll()
 }
 
+// Secrets returns the API and application keys of every cached account
+// (used to scrub them from HTTP recordings)
+func (m *AccountManager) Secrets() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	secrets := make([]string, 0, 2*len(m.nameCache))
+	for _, acct := range m.nameCache {
+		secrets = append(secrets, acct.APIKey, acct.AppKey)
+	}
+	return secrets
+}
+
 // Stats returns cache statistics
 func (m *AccountManager) Stats() map[string]interface{} {
 	m.mu.RLock()
@@ -50,11 +50,13 @@ func (c Credentials) BuildURL(path string) string {
 
 // Common Datadog API paths
 const (
-	PathDowntime  = "/api/v2/downtime"
-	PathEvents    = "/api/v1/events"
-	PathHosts     = "/api/v1/hosts"
-	PathMonitors  = "/api/v1/monitor"
-	PathNotebooks = "/api/v1/notebooks"
+	PathDowntime            = "/api/v2/downtime"
+	PathEvents              = "/api/v1/events"
+	PathHosts               = "/api/v1/hosts"
+	PathMonitors            = "/api/v1/monitor"
+	PathNotebooks           = "/api/v1/notebooks"
+	PathServiceDefinitions  = "/api/v2/services/definitions"
+	PathServiceDependencies = "/api/v1/service_dependencies"
 )
 
 // Common Datadog base URLs
@@ -0,0 +1,266 @@
+package agents
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// AnalysisStorage persists analysis records in Postgres (`agent_analyses`).
+// The agent context and conversation are stored as JSONB so follow-up
+// questions can resume an investigation after a restart.
+type AnalysisStorage struct {
+	db *sql.DB
+}
+
+// NewAnalysisStorage creates a new analysis storage
+func NewAnalysisStorage(db *sql.DB) *AnalysisStorage {
+	return &AnalysisStorage{db: db}
+}
+
+// InitTables creates the analyses table
+func (s *AnalysisStorage) InitTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS agent_analyses (
+			analysis_id VARCHAR(64) PRIMARY KEY,
+			event_id BIGINT,
+			monitor_id BIGINT,
+			monitor_name TEXT,
+			account_name VARCHAR(255),
+			agent VARCHAR(100),
+			agent_role VARCHAR(50),
+			event JSONB NOT NULL,
+			result JSONB,
+			context JSONB NOT NULL,
+			conversation JSONB NOT NULL DEFAULT '[]',
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Recovery linking (added after the table shipped)
+		ALTER TABLE agent_analyses ADD COLUMN IF NOT EXISTS scope TEXT NOT NULL DEFAULT '';
+		ALTER TABLE agent_analyses ADD COLUMN IF NOT EXISTS resolution JSONB;
+		ALTER TABLE agent_analyses ADD COLUMN IF NOT EXISTS resolved_at TIMESTAMP;
+
+		CREATE INDEX IF NOT EXISTS idx_agent_analyses_monitor_id ON agent_analyses(monitor_id);
+		CREATE INDEX IF NOT EXISTS idx_agent_analyses_created_at ON agent_analyses(created_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_agent_analyses_open ON agent_analyses(monitor_id, scope) WHERE resolved_at IS NULL;
+	`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// SaveAnalysis inserts or replaces a record
+func (s *AnalysisStorage) SaveAnalysis(ctx context.Context, record *AnalysisRecord) error {
+	event, err := json.Marshal(record.Event)
+	if err != nil {
+		return fmt.Errorf("marshal analysis event: %w", err)
+	}
+	result, err := json.Marshal(record.Result)
+	if err != nil {
+		return fmt.Errorf("marshal analysis result: %w", err)
+	}
+	agentCtx, err := json.Marshal(record.Context)
+	if err != nil {
+		return fmt.Errorf("marshal agent context: %w", err)
+	}
+	conversation := record.Conversation
+	if conversation == nil {
+		conversation = []ConversationTurn{}
+	}
+	turns, err := json.Marshal(conversation)
+	if err != nil {
+		return fmt.Errorf("marshal conversation: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO agent_analyses
+			(analysis_id, event_id, monitor_id, monitor_name, account_name, scope, agent, agent_role,
+			 event, result, context, conversation, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		ON CONFLICT (analysis_id) DO UPDATE SET
+			result = EXCLUDED.result,
+			context = EXCLUDED.context,
+			conversation = EXCLUDED.conversation,
+			updated_at = EXCLUDED.updated_at
+	`, record.AnalysisID, record.EventID, record.MonitorID, record.MonitorName, record.AccountName, record.Scope,
+		record.Agent, record.AgentRole, event, result, agentCtx, turns, record.CreatedAt, record.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("save analysis %s: %w", record.AnalysisID, err)
+	}
+	return nil
+}
+
+// analysisColumns are the columns read by scanAnalysis
+const analysisColumns = `analysis_id, event_id, monitor_id, monitor_name, account_name, scope, agent, agent_role,
+	event, result, context, conversation, resolution, created_at, updated_at`
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// GetAnalysis loads a record by ID
+func (s *AnalysisStorage) GetAnalysis(ctx context.Context, analysisID string) (*AnalysisRecord, error) {
+	row := s.db.QueryRowContext(ctx, `
+		SELECT `+analysisColumns+`
+		FROM agent_analyses
+		WHERE analysis_id = $1
+	`, analysisID)
+
+	record, err := scanAnalysis(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrAnalysisNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get analysis %s: %w", analysisID, err)
+	}
+	return record, nil
+}
+
+// OpenAnalyses returns unresolved records for the monitor and scope, oldest first
+func (s *AnalysisStorage) OpenAnalyses(ctx context.Context, monitorID int64, scope string) ([]AnalysisRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+analysisColumns+`
+		FROM agent_analyses
+		WHERE monitor_id = $1 AND COALESCE(scope, '') = $2 AND resolved_at IS NULL
+		ORDER BY created_at ASC
+	`, monitorID, scope)
+	if err != nil {
+		return nil, fmt.Errorf("list open analyses for monitor %d: %w", monitorID, err)
+	}
+	defer rows.Close()
+
+	var records []AnalysisRecord
+	for rows.Next() {
+		record, err := scanAnalysis(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list open analyses for monitor %d: %w", monitorID, err)
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// AnalysesForMonitor returns the monitor's records created in [from, to], oldest first
+func (s *AnalysisStorage) AnalysesForMonitor(ctx context.Context, monitorID int64, from, to time.Time) ([]AnalysisRecord, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+analysisColumns+`
+		FROM agent_analyses
+		WHERE monitor_id = $1 AND created_at BETWEEN $2 AND $3
+		ORDER BY created_at ASC
+	`, monitorID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("list analyses for monitor %d: %w", monitorID, err)
+	}
+	defer rows.Close()
+
+	var records []AnalysisRecord
+	for rows.Next() {
+		record, err := scanAnalysis(rows)
+		if err != nil {
+			return nil, fmt.Errorf("list analyses for monitor %d: %w", monitorID, err)
+		}
+		records = append(records, *record)
+	}
+	return records, rows.Err()
+}
+
+// ResolveAnalysis stores the resolution and marks the record resolved
+func (s *AnalysisStorage) ResolveAnalysis(ctx context.Context, analysisID string, resolution Resolution) error {
+	resolutionJSON, err := json.Marshal(resolution)
+	if err != nil {
+		return fmt.Errorf("marshal resolution: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE agent_analyses
+		SET resolution = $2,
+			resolved_at = $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE analysis_id = $1
+	`, analysisID, resolutionJSON, resolution.RecoveredAt)
+	if err != nil {
+		return fmt.Errorf("resolve analysis %s: %w", analysisID, err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrAnalysisNotFound
+	}
+	return nil
+}
+
+// scanAnalysis decodes one agent_analyses row selected with analysisColumns
+func scanAnalysis(row rowScanner) (*AnalysisRecord, error) {
+	var record AnalysisRecord
+	var event, result, agentCtx, turns, resolution []byte
+	var monitorName, accountName, scope, agent, role sql.NullString
+	var eventID, monitorID sql.NullInt64
+
+	err := row.Scan(&record.AnalysisID, &eventID, &monitorID, &monitorName, &accountName, &scope, &agent, &role,
+		&event, &result, &agentCtx, &turns, &resolution, &record.CreatedAt, &record.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	record.EventID = eventID.Int64
+	record.MonitorID = monitorID.Int64
+	record.MonitorName = monitorName.String
+	record.AccountName = accountName.String
+	record.Scope = scope.String
+	record.Agent = agent.String
+	record.AgentRole = AgentRole(role.String)
+
+	if err := json.Unmarshal(event, &record.Event); err != nil {
+		return nil, fmt.Errorf("decode analysis event: %w", err)
+	}
+	if len(result) > 0 {
+		if err := json.Unmarshal(result, &record.Result); err != nil {
+			return nil, fmt.Errorf("decode analysis result: %w", err)
+		}
+	}
+	if err := json.Unmarshal(agentCtx, &record.Context); err != nil {
+		return nil, fmt.Errorf("decode agent context: %w", err)
+	}
+	if err := json.Unmarshal(turns, &record.Conversation); err != nil {
+		return nil, fmt.Errorf("decode conversation: %w", err)
+	}
+	if len(resolution) > 0 {
+		if err := json.Unmarshal(resolution, &record.Resolution); err != nil {
+			return nil, fmt.Errorf("decode resolution: %w", err)
+		}
+	}
+	return &record, nil
+}
+
+// AppendTurn atomically appends a turn and replaces the agent context
+func (s *AnalysisStorage) AppendTurn(ctx context.Context, analysisID string, turn ConversationTurn, snapshot AgentContextSnapshot) error {
+	turnJSON, err := json.Marshal(turn)
+	if err != nil {
+		return fmt.Errorf("marshal conversation turn: %w", err)
+	}
+	agentCtx, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("marshal agent context: %w", err)
+	}
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE agent_analyses
+		SET conversation = conversation || jsonb_build_array($2::jsonb),
+			context = $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE analysis_id = $1
+	`, analysisID, turnJSON, agentCtx)
+	if err != nil {
+		return fmt.Errorf("append conversation turn to %s: %w", analysisID, err)
+	}
+
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrAnalysisNotFound
+	}
+	return nil
+}
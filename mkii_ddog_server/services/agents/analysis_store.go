@@ -0,0 +1,329 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// ErrAnalysisNotFound is returned for unknown analysis IDs
+var ErrAnalysisNotFound = errors.New("analysis not found")
+
+// AnalysisRecord is a completed analysis with the agent state needed to resume it
+type AnalysisRecord struct {
+	AnalysisID   string               `json:"analysis_id"`
+	EventID      int64                `json:"event_id"`
+	MonitorID    int64                `json:"monitor_id"`
+	MonitorName  string               `json:"monitor_name"`
+	AccountName  string               `json:"account_name,omitempty"`
+	Scope        string               `json:"scope,omitempty"`
+	Agent        string               `json:"agent"`
+	AgentRole    AgentRole            `json:"agent_role"`
+	Event        *types.AlertEvent    `json:"event"`
+	Result       *AnalysisResult      `json:"result"`
+	Context      AgentContextSnapshot `json:"context"`
+	Conversation []ConversationTurn   `json:"conversation"`
+	Resolution   *Resolution          `json:"resolution,omitempty"` // set once the monitor recovers
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+}
+
+// ConversationTurn is one follow-up question and the agent's answer
+type ConversationTurn struct {
+	ID         string        `json:"id"`
+	Question   string        `json:"question"`
+	Answer     string        `json:"answer"`
+	AskedBy    string        `json:"asked_by,omitempty"`
+	Agent      string        `json:"agent"`
+	Success    bool          `json:"success"`
+	Findings   []Finding     `json:"findings,omitempty"` // new findings gathered for this answer
+	Queries    int           `json:"queries"`            // sub-queries run for this answer
+	TokensUsed int64         `json:"tokens_used,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	Error      string        `json:"error,omitempty"`
+	AskedAt    time.Time     `json:"asked_at"`
+}
+
+// AgentContextSnapshot is the serializable part of an AgentContext
+type AgentContextSnapshot struct {
+	Iterations      int                    `json:"iterations"`
+	QueryHistory    []QueryRecord          `json:"query_history"`
+	Findings        []Finding              `json:"findings"`
+	Hypotheses      []string               `json:"hypotheses"`
+	RootCause       string                 `json:"root_cause"`
+	Recommendations []string               `json:"recommendations"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// QueryRecord is a QueryResult with its error flattened to text
+type QueryRecord struct {
+	AgentName string        `json:"agent_name"`
+	Query     string        `json:"query"`
+	Priority  int           `json:"priority,omitempty"`
+	Required  bool          `json:"required,omitempty"`
+	Result    string        `json:"result"`
+	Error     string        `json:"error,omitempty"`
+	Duration  time.Duration `json:"duration"`
+	Timestamp time.Time     `json:"timestamp"`
+}
+
+// snapshotAgentContext captures agentCtx for storage
+func snapshotAgentContext(agentCtx AgentContext) AgentContextSnapshot {
+	snapshot := AgentContextSnapshot{
+		Iterations:      agentCtx.Iteration,
+		Findings:        append([]Finding(nil), agentCtx.Findings...),
+		Hypotheses:      append([]string(nil), agentCtx.Hypotheses...),
+		RootCause:       agentCtx.RootCause,
+		Recommendations: append([]string(nil), agentCtx.Recommendations...),
+		Metadata:        make(map[string]interface{}, len(agentCtx.Metadata)),
+	}
+	for k, v := range agentCtx.Metadata {
+		snapshot.Metadata[k] = v
+	}
+	for _, q := range agentCtx.QueryHistory {
+		record := QueryRecord{
+			AgentName: q.Query.AgentName,
+			Query:     q.Query.Query,
+			Priority:  q.Query.Priority,
+			Required:  q.Query.Required,
+			Result:    q.Result,
+			Duration:  q.Duration,
+			Timestamp: q.Timestamp,
+		}
+		if q.Error != nil {
+			record.Error = q.Error.Error()
+		}
+		snapshot.QueryHistory = append(snapshot.QueryHistory, record)
+	}
+	return snapshot
+}
+
+// restore rebuilds an AgentContext for event from the snapshot
+func (s AgentContextSnapshot) restore(event *types.AlertEvent) AgentContext {
+	agentCtx := NewAgentContext(event)
+	agentCtx.Iteration = s.Iterations
+	agentCtx.Findings = append(agentCtx.Findings, s.Findings...)
+	agentCtx.Hypotheses = append(agentCtx.Hypotheses, s.Hypotheses...)
+	agentCtx.RootCause = s.RootCause
+	agentCtx.Recommendations = append([]string(nil), s.Recommendations...)
+	for k, v := range s.Metadata {
+		agentCtx.Metadata[k] = v
+	}
+	for _, q := range s.QueryHistory {
+		result := QueryResult{
+			Query: SubQuery{
+				AgentName: q.AgentName,
+				Query:     q.Query,
+				Priority:  q.Priority,
+				Required:  q.Required,
+			},
+			Result:    q.Result,
+			Duration:  q.Duration,
+			Timestamp: q.Timestamp,
+		}
+		if q.Error != "" {
+			result.Error = errors.New(q.Error)
+		}
+		agentCtx.QueryHistory = append(agentCtx.QueryHistory, result)
+	}
+	return agentCtx
+}
+
+// AnalysisStore persists analysis records for follow-up questions
+type AnalysisStore interface {
+	// SaveAnalysis creates or replaces a record
+	SaveAnalysis(ctx context.Context, record *AnalysisRecord) error
+
+	// GetAnalysis loads a record; returns ErrAnalysisNotFound if unknown
+	GetAnalysis(ctx context.Context, analysisID string) (*AnalysisRecord, error)
+
+	// AppendTurn adds a conversation turn and replaces the stored agent context
+	AppendTurn(ctx context.Context, analysisID string, turn ConversationTurn, snapshot AgentContextSnapshot) error
+
+	// OpenAnalyses returns unresolved records for a monitor and scope, oldest first
+	OpenAnalyses(ctx context.Context, monitorID int64, scope string) ([]AnalysisRecord, error)
+
+	// ResolveAnalysis records how an analysis's alert was resolved
+	ResolveAnalysis(ctx context.Context, analysisID string, resolution Resolution) error
+}
+
+// MemoryAnalysisStore keeps analysis records in memory (tests and DB-less runs)
+type MemoryAnalysisStore struct {
+	records map[string]*AnalysisRecord
+	mu      sync.RWMutex
+}
+
+// NewMemoryAnalysisStore creates an empty in-memory store
+func NewMemoryAnalysisStore() *MemoryAnalysisStore {
+	return &MemoryAnalysisStore{records: make(map[string]*AnalysisRecord)}
+}
+
+// SaveAnalysis stores a copy of record
+func (s *MemoryAnalysisStore) SaveAnalysis(ctx context.Context, record *AnalysisRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *record
+	stored.Conversation = append([]ConversationTurn(nil), record.Conversation...)
+	s.records[record.AnalysisID] = &stored
+	return nil
+}
+
+// GetAnalysis returns a copy of the stored record
+func (s *MemoryAnalysisStore) GetAnalysis(ctx context.Context, analysisID string) (*AnalysisRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	record, ok := s.records[analysisID]
+	if !ok {
+		return nil, ErrAnalysisNotFound
+	}
+	found := *record
+	found.Conversation = append([]ConversationTurn(nil), record.Conversation...)
+	return &found, nil
+}
+
+// AppendTurn appends turn and replaces the agent context
+func (s *MemoryAnalysisStore) AppendTurn(ctx context.Context, analysisID string, turn ConversationTurn, snapshot AgentContextSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[analysisID]
+	if !ok {
+		return ErrAnalysisNotFound
+	}
+	record.Conversation = append(record.Conversation, turn)
+	record.Context = snapshot
+	record.UpdatedAt = time.Now()
+	return nil
+}
+
+// OpenAnalyses returns copies of the unresolved records for the monitor and scope
+func (s *MemoryAnalysisStore) OpenAnalyses(ctx context.Context, monitorID int64, scope string) ([]AnalysisRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var open []AnalysisRecord
+	for _, record := range s.records {
+		if record.MonitorID == monitorID && record.Scope == scope && record.Resolution == nil {
+			found := *record
+			found.Conversation = append([]ConversationTurn(nil), record.Conversation...)
+			open = append(open, found)
+		}
+	}
+	sort.Slice(open, func(i, j int) bool {
+		return open[i].CreatedAt.Before(open[j].CreatedAt)
+	})
+	return open, nil
+}
+
+// AnalysesForMonitor returns copies of the monitor's records created in [from, to], oldest first
+func (s *MemoryAnalysisStore) AnalysesForMonitor(ctx context.Context, monitorID int64, from, to time.Time) ([]AnalysisRecord, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []AnalysisRecord
+	for _, record := range s.records {
+		if record.MonitorID != monitorID || record.CreatedAt.Before(from) || record.CreatedAt.After(to) {
+			continue
+		}
+		copied := *record
+		copied.Conversation = append([]ConversationTurn(nil), record.Conversation...)
+		found = append(found, copied)
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].CreatedAt.Before(found[j].CreatedAt)
+	})
+	return found, nil
+}
+
+// ResolveAnalysis sets the record's resolution
+func (s *MemoryAnalysisStore) ResolveAnalysis(ctx context.Context, analysisID string, resolution Resolution) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	record, ok := s.records[analysisID]
+	if !ok {
+		return ErrAnalysisNotFound
+	}
+	record.Resolution = &resolution
+	record.UpdatedAt = time.Now()
+	return nil
+}
+
+// contextCapture collects the final AgentContext of every agent run under
+// one analysis, so the orchestrator can store them for follow-ups
+type contextCapture struct {
+	entries []capturedContext
+	mu      sync.Mutex
+}
+
+// capturedContext is one agent's final state
+type capturedContext struct {
+	agent    string
+	role     AgentRole
+	agentCtx AgentContext
+}
+
+type captureKey struct{}
+
+// withCapture attaches a capture to ctx
+func withCapture(ctx context.Context, capture *contextCapture) context.Context {
+	return context.WithValue(ctx, captureKey{}, capture)
+}
+
+// captureFrom returns the capture attached to ctx, or nil
+func captureFrom(ctx context.Context) *contextCapture {
+	capture, _ := ctx.Value(captureKey{}).(*contextCapture)
+	return capture
+}
+
+// record stores an agent's final context; safe on a nil capture
+func (c *contextCapture) record(agent Agent, agentCtx AgentContext) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = append(c.entries, capturedContext{agent: agent.Name(), role: agent.Role(), agentCtx: agentCtx})
+}
+
+// merged returns the context of the agent matching role (or the first run)
+// with the findings, hypotheses and queries of any collaborating agents
+// appended, so a follow-up sees the whole investigation
+func (c *contextCapture) merged(role AgentRole) (capturedContext, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.entries) == 0 {
+		return capturedContext{}, false
+	}
+
+	primary := 0
+	for i, entry := range c.entries {
+		if entry.role == role {
+			primary = i
+			break
+		}
+	}
+
+	merged := c.entries[primary]
+	for i, entry := range c.entries {
+		if i == primary {
+			continue
+		}
+		for _, f := range entry.agentCtx.Findings {
+			if f.AgentRole == "" {
+				f.AgentRole = entry.role
+			}
+			merged.agentCtx.Findings = append(merged.agentCtx.Findings, f)
+		}
+		merged.agentCtx.Hypotheses = append(merged.agentCtx.Hypotheses, entry.agentCtx.Hypotheses...)
+		merged.agentCtx.QueryHistory = append(merged.agentCtx.QueryHistory, entry.agentCtx.QueryHistory...)
+	}
+	return merged, true
+}
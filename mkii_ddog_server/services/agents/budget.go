@@ -0,0 +1,305 @@
+package agents
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SkipReason explains why an analysis was not run
+type SkipReason string
+
+const (
+	SkipMonitorCooldown      SkipReason = "monitor_cooldown"
+	SkipGlobalHourlyLimit    SkipReason = "global_hourly_limit"
+	SkipAccountHourlyLimit   SkipReason = "account_hourly_limit"
+	SkipGlobalTokenBudget    SkipReason = "global_token_budget"
+	SkipAccountTokenBudget   SkipReason = "account_token_budget"
+	SkipCircuitOpen          SkipReason = "circuit_open"
+	SkipNoFallbackConfigured SkipReason = "no_fallback_agent"
+	SkipQueueFull            SkipReason = "queue_full"
+)
+
+// BudgetConfig holds spend limits for agent analysis. Zero values disable a limit.
+type BudgetConfig struct {
+	// GlobalAnalysesPerHour caps analyses across all accounts in a sliding hour
+	GlobalAnalysesPerHour int
+
+	// AccountAnalysesPerHour caps analyses per Datadog account in a sliding hour
+	AccountAnalysesPerHour int
+
+	// GlobalTokensPerDay caps sidecar tokens across all accounts per UTC day
+	GlobalTokensPerDay int64
+
+	// AccountTokensPerDay caps sidecar tokens per Datadog account per UTC day
+	AccountTokensPerDay int64
+
+	// MonitorCooldown is the minimum time between analyses of the same monitor
+	MonitorCooldown time.Duration
+}
+
+// BudgetConfigFromEnv reads AGENT_BUDGET_* and AGENT_MONITOR_COOLDOWN
+func BudgetConfigFromEnv() BudgetConfig {
+	config := BudgetConfig{
+		MonitorCooldown: 10 * time.Minute,
+	}
+	if v, err := strconv.Atoi(os.Getenv("AGENT_BUDGET_ANALYSES_PER_HOUR")); err == nil && v >= 0 {
+		config.GlobalAnalysesPerHour = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AGENT_BUDGET_ACCOUNT_ANALYSES_PER_HOUR")); err == nil && v >= 0 {
+		config.AccountAnalysesPerHour = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("AGENT_BUDGET_TOKENS_PER_DAY"), 10, 64); err == nil && v >= 0 {
+		config.GlobalTokensPerDay = v
+	}
+	if v, err := strconv.ParseInt(os.Getenv("AGENT_BUDGET_ACCOUNT_TOKENS_PER_DAY"), 10, 64); err == nil && v >= 0 {
+		config.AccountTokensPerDay = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("AGENT_MONITOR_COOLDOWN")); err == nil && v >= 0 {
+		config.MonitorCooldown = v
+	}
+	return config
+}
+
+// globalBudgetKey is the usage bucket shared by all accounts
+const globalBudgetKey = "*"
+
+// tokenUsage tracks tokens spent during one UTC day
+type tokenUsage struct {
+	day  string
+	used int64
+}
+
+// BudgetGuard enforces analysis rate limits, token budgets and per-monitor cooldowns
+type BudgetGuard struct {
+	config BudgetConfig
+	now    func() time.Time
+
+	analyses    map[string][]time.Time // account (or global) -> analysis start times in the last hour
+	tokens      map[string]*tokenUsage // account (or global) -> tokens used today
+	lastMonitor map[string]time.Time   // account/monitor -> last admitted analysis
+	mu          sync.Mutex
+}
+
+// NewBudgetGuard creates a budget guard
+func NewBudgetGuard(config BudgetConfig) *BudgetGuard {
+	return &BudgetGuard{
+		config:      config,
+		now:         time.Now,
+		analyses:    make(map[string][]time.Time),
+		tokens:      make(map[string]*tokenUsage),
+		lastMonitor: make(map[string]time.Time),
+	}
+}
+
+// Admit checks every limit for an analysis and, when allowed, reserves it
+// against the hourly windows and the monitor cooldown. Callers release the
+// cooldown with ReleaseCooldown when the analysis is shed or fails.
+// Returns the skip reason and a human-readable detail when denied.
+func (b *BudgetGuard) Admit(account string, monitorID int64) (bool, SkipReason, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	account = normalizeAccount(account)
+	monitorKey := fmt.Sprintf("%s/%d", account, monitorID)
+
+	if b.config.MonitorCooldown > 0 && monitorID != 0 {
+		if last, ok := b.lastMonitor[monitorKey]; ok {
+			if remaining := b.config.MonitorCooldown - now.Sub(last); remaining > 0 {
+				return false, SkipMonitorCooldown,
+					fmt.Sprintf("monitor %d analyzed %s ago (cooldown %s)", monitorID, now.Sub(last).Round(time.Second), b.config.MonitorCooldown)
+			}
+		}
+	}
+
+	global := b.pruneWindow(globalBudgetKey, now)
+	if b.config.GlobalAnalysesPerHour > 0 && len(global) >= b.config.GlobalAnalysesPerHour {
+		return false, SkipGlobalHourlyLimit,
+			fmt.Sprintf("%d analyses in the last hour (limit %d)", len(global), b.config.GlobalAnalysesPerHour)
+	}
+
+	perAccount := b.pruneWindow(account, now)
+	if b.config.AccountAnalysesPerHour > 0 && len(perAccount) >= b.config.AccountAnalysesPerHour {
+		return false, SkipAccountHourlyLimit,
+			fmt.Sprintf("account %s: %d analyses in the last hour (limit %d)", account, len(perAccount), b.config.AccountAnalysesPerHour)
+	}
+
+	if ok, reason, detail := b.checkTokensLocked(account, now); !ok {
+		return false, reason, detail
+	}
+
+	b.analyses[globalBudgetKey] = append(global, now)
+	b.analyses[account] = append(perAccount, now)
+	if monitorID != 0 && b.config.MonitorCooldown > 0 {
+		b.pruneCooldowns(now)
+		b.lastMonitor[monitorKey] = now
+	}
+
+	return true, "", ""
+}
+
+// ReleaseCooldown drops a monitor's cooldown so that the next alert is
+// analyzed; used when an admitted analysis produced nothing
+func (b *BudgetGuard) ReleaseCooldown(account string, monitorID int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.lastMonitor, fmt.Sprintf("%s/%d", normalizeAccount(account), monitorID))
+}
+
+// AdmitFollowUp checks only the daily token budgets. Follow-up questions are
+// human-initiated, so hourly analysis limits and monitor cooldowns do not apply.
+func (b *BudgetGuard) AdmitFollowUp(account string) (bool, SkipReason, string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.checkTokensLocked(normalizeAccount(account), b.now())
+}
+
+// checkTokensLocked checks the global and account daily token budgets (caller holds mu)
+func (b *BudgetGuard) checkTokensLocked(account string, now time.Time) (bool, SkipReason, string) {
+	if used := b.tokensToday(globalBudgetKey, now); b.config.GlobalTokensPerDay > 0 && used >= b.config.GlobalTokensPerDay {
+		return false, SkipGlobalTokenBudget,
+			fmt.Sprintf("%d tokens used today (budget %d)", used, b.config.GlobalTokensPerDay)
+	}
+
+	if used := b.tokensToday(account, now); b.config.AccountTokensPerDay > 0 && used >= b.config.AccountTokensPerDay {
+		return false, SkipAccountTokenBudget,
+			fmt.Sprintf("account %s: %d tokens used today (budget %d)", account, used, b.config.AccountTokensPerDay)
+	}
+
+	return true, "", ""
+}
+
+// RecordTokens charges token usage to the global and account daily budgets
+func (b *BudgetGuard) RecordTokens(account string, tokens int64) {
+	if tokens <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	for _, key := range []string{globalBudgetKey, normalizeAccount(account)} {
+		b.tokensToday(key, now)
+		b.tokens[key].used += tokens
+	}
+}
+
+// Stats returns current budget usage
+func (b *BudgetGuard) Stats() BudgetStats {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	stats := BudgetStats{
+		Limits:             budgetLimits(b.config),
+		AnalysesLastHour:   len(b.pruneWindow(globalBudgetKey, now)),
+		TokensToday:        b.tokensToday(globalBudgetKey, now),
+		MonitorsInCooldown: 0,
+		Accounts:           make(map[string]AccountBudgetUsage),
+	}
+
+	for key := range b.analyses {
+		if key == globalBudgetKey {
+			continue
+		}
+		usage := stats.Accounts[key]
+		usage.AnalysesLastHour = len(b.pruneWindow(key, now))
+		stats.Accounts[key] = usage
+	}
+	for key := range b.tokens {
+		if key == globalBudgetKey {
+			continue
+		}
+		usage := stats.Accounts[key]
+		usage.TokensToday = b.tokensToday(key, now)
+		stats.Accounts[key] = usage
+	}
+
+	b.pruneCooldowns(now)
+	stats.MonitorsInCooldown = len(b.lastMonitor)
+
+	return stats
+}
+
+// pruneCooldowns drops monitors whose cooldown has passed (caller holds mu)
+func (b *BudgetGuard) pruneCooldowns(now time.Time) {
+	for key, last := range b.lastMonitor {
+		if now.Sub(last) >= b.config.MonitorCooldown {
+			delete(b.lastMonitor, key)
+		}
+	}
+}
+
+// pruneWindow drops analysis timestamps older than an hour (caller holds mu)
+func (b *BudgetGuard) pruneWindow(key string, now time.Time) []time.Time {
+	window := b.analyses[key]
+	cutoff := now.Add(-time.Hour)
+
+	i := 0
+	for i < len(window) && !window[i].After(cutoff) {
+		i++
+	}
+	if i > 0 {
+		window = append(window[:0], window[i:]...)
+		b.analyses[key] = window
+	}
+	return window
+}
+
+// tokensToday returns the tokens used on the current UTC day (caller holds mu)
+func (b *BudgetGuard) tokensToday(key string, now time.Time) int64 {
+	day := now.UTC().Format("2006-01-02")
+	usage, ok := b.tokens[key]
+	if !ok || usage.day != day {
+		usage = &tokenUsage{day: day}
+		b.tokens[key] = usage
+	}
+	return usage.used
+}
+
+// normalizeAccount maps an empty account name to the default bucket
+func normalizeAccount(account string) string {
+	if account == "" {
+		return "default"
+	}
+	return account
+}
+
+// budgetLimits converts the config to its JSON representation
+func budgetLimits(config BudgetConfig) BudgetLimits {
+	return BudgetLimits{
+		GlobalAnalysesPerHour:  config.GlobalAnalysesPerHour,
+		AccountAnalysesPerHour: config.AccountAnalysesPerHour,
+		GlobalTokensPerDay:     config.GlobalTokensPerDay,
+		AccountTokensPerDay:    config.AccountTokensPerDay,
+		MonitorCooldown:        config.MonitorCooldown.String(),
+	}
+}
+
+// BudgetLimits holds the configured limits (0 = unlimited)
+type BudgetLimits struct {
+	GlobalAnalysesPerHour  int    `json:"global_analyses_per_hour"`
+	AccountAnalysesPerHour int    `json:"account_analyses_per_hour"`
+	GlobalTokensPerDay     int64  `json:"global_tokens_per_day"`
+	AccountTokensPerDay    int64  `json:"account_tokens_per_day"`
+	MonitorCooldown        string `json:"monitor_cooldown"`
+}
+
+// AccountBudgetUsage holds one account's current usage
+type AccountBudgetUsage struct {
+	AnalysesLastHour int   `json:"analyses_last_hour"`
+	TokensToday      int64 `json:"tokens_today"`
+}
+
+// BudgetStats holds budget usage for the stats endpoint
+type BudgetStats struct {
+	Limits             BudgetLimits                  `json:"limits"`
+	AnalysesLastHour   int                           `json:"analyses_last_hour"`
+	TokensToday        int64                         `json:"tokens_today"`
+	MonitorsInCooldown int                           `json:"monitors_in_cooldown"`
+	Accounts           map[string]AccountBudgetUsage `json:"accounts"`
+}
@@ -0,0 +1,140 @@
+package agents
+
+import (
+	"testing"
+	"time"
+)
+
+// fakeClock is a controllable time source for budget and circuit tests
+type fakeClock struct {
+	t time.Time
+}
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newTestBudget(config BudgetConfig) (*BudgetGuard, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	b := NewBudgetGuard(config)
+	b.now = clock.now
+	return b, clock
+}
+
+func TestBudgetGuard_MonitorCooldown(t *testing.T) {
+	b, clock := newTestBudget(BudgetConfig{MonitorCooldown: 10 * time.Minute})
+
+	if ok, _, _ := b.Admit("prod", 1); !ok {
+		t.Fatal("first analysis should be admitted")
+	}
+	if ok, reason, _ := b.Admit("prod", 1); ok || reason != SkipMonitorCooldown {
+		t.Errorf("expected monitor_cooldown, got ok=%v reason=%s", ok, reason)
+	}
+
+	// Cooldown is per account and monitor
+	if ok, _, _ := b.Admit("staging", 1); !ok {
+		t.Error("same monitor ID in another account should be admitted")
+	}
+	if ok, _, _ := b.Admit("prod", 2); !ok {
+		t.Error("another monitor should be admitted")
+	}
+
+	clock.advance(10 * time.Minute)
+	if ok, _, _ := b.Admit("prod", 1); !ok {
+		t.Error("analysis should be admitted after cooldown")
+	}
+}
+
+func TestBudgetGuard_ReleaseAndPruneCooldowns(t *testing.T) {
+	b, clock := newTestBudget(BudgetConfig{MonitorCooldown: 10 * time.Minute})
+
+	b.Admit("prod", 1)
+	b.ReleaseCooldown("prod", 1)
+	if ok, _, _ := b.Admit("prod", 1); !ok {
+		t.Fatal("a released cooldown should admit the next analysis")
+	}
+
+	// Expired cooldowns are dropped when new ones are taken
+	b.Admit("prod", 2)
+	clock.advance(10 * time.Minute)
+	b.Admit("prod", 3)
+	if len(b.lastMonitor) != 1 {
+		t.Fatalf("expected only the live cooldown kept, got %v", b.lastMonitor)
+	}
+}
+
+func TestBudgetGuard_HourlyLimits(t *testing.T) {
+	b, clock := newTestBudget(BudgetConfig{GlobalAnalysesPerHour: 3, AccountAnalysesPerHour: 2})
+
+	for i := int64(1); i <= 2; i++ {
+		if ok, _, detail := b.Admit("prod", i); !ok {
+			t.Fatalf("analysis %d should be admitted: %s", i, detail)
+		}
+	}
+	if ok, reason, _ := b.Admit("prod", 3); ok || reason != SkipAccountHourlyLimit {
+		t.Errorf("expected account_hourly_limit, got ok=%v reason=%s", ok, reason)
+	}
+	if ok, _, _ := b.Admit("staging", 4); !ok {
+		t.Error("other account should still have budget")
+	}
+	if ok, reason, _ := b.Admit("dev", 5); ok || reason != SkipGlobalHourlyLimit {
+		t.Errorf("expected global_hourly_limit, got ok=%v reason=%s", ok, reason)
+	}
+
+	// Denied attempts must not consume budget; the window slides
+	clock.advance(time.Hour + time.Second)
+	if ok, _, _ := b.Admit("prod", 6); !ok {
+		t.Error("window should have slid past earlier analyses")
+	}
+
+	stats := b.Stats()
+	if stats.AnalysesLastHour != 1 {
+		t.Errorf("expected 1 analysis in the last hour, got %d", stats.AnalysesLastHour)
+	}
+}
+
+func TestBudgetGuard_TokenBudget(t *testing.T) {
+	b, clock := newTestBudget(BudgetConfig{GlobalTokensPerDay: 1000, AccountTokensPerDay: 600})
+
+	b.Admit("prod", 1)
+	b.RecordTokens("prod", 600)
+	if ok, reason, _ := b.Admit("prod", 2); ok || reason != SkipAccountTokenBudget {
+		t.Errorf("expected account_token_budget, got ok=%v reason=%s", ok, reason)
+	}
+
+	b.Admit("staging", 3)
+	b.RecordTokens("staging", 400)
+	if ok, reason, _ := b.Admit("staging", 4); ok || reason != SkipGlobalTokenBudget {
+		t.Errorf("expected global_token_budget, got ok=%v reason=%s", ok, reason)
+	}
+
+	stats := b.Stats()
+	if stats.TokensToday != 1000 {
+		t.Errorf("expected 1000 tokens today, got %d", stats.TokensToday)
+	}
+	if stats.Accounts["prod"].TokensToday != 600 {
+		t.Errorf("expected prod to have used 600 tokens, got %d", stats.Accounts["prod"].TokensToday)
+	}
+
+	// Budgets reset on the next UTC day
+	clock.advance(12 * time.Hour)
+	if ok, _, detail := b.Admit("prod", 5); !ok {
+		t.Errorf("token budget should reset at UTC midnight: %s", detail)
+	}
+}
+
+func TestBudgetGuard_ZeroConfigIsUnlimited(t *testing.T) {
+	b, _ := newTestBudget(BudgetConfig{})
+
+	for i := 0; i < 100; i++ {
+		if ok, reason, _ := b.Admit("", 1); !ok {
+			t.Fatalf("analysis %d denied with empty config: %s", i, reason)
+		}
+	}
+	b.RecordTokens("", 1_000_000)
+	if ok, _, _ := b.Admit("", 1); !ok {
+		t.Error("token usage should not deny without a budget")
+	}
+	if _, ok := b.Stats().Accounts["default"]; !ok {
+		t.Error("empty account should be tracked as default")
+	}
+}
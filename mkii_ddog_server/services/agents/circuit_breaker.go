@@ -0,0 +1,138 @@
+package agents
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// CircuitState is the state of a circuit breaker
+type CircuitState string
+
+const (
+	CircuitClosed   CircuitState = "closed"
+	CircuitOpen     CircuitState = "open"
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// CircuitBreaker stops calling the agent sidecar after consecutive failures.
+// After the cooldown a single probe is let through (half-open); success closes
+// the circuit, failure re-opens it.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	state       CircuitState
+	failures    int
+	openedAt    time.Time
+	probing     bool
+	totalOpened int64
+	mu          sync.Mutex
+}
+
+// NewCircuitBreaker creates a breaker that opens after threshold consecutive failures
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if threshold <= 0 {
+		threshold = 5
+	}
+	if cooldown <= 0 {
+		cooldown = 2 * time.Minute
+	}
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     CircuitClosed,
+	}
+}
+
+// Allow reports whether a call to the protected dependency may proceed
+func (cb *CircuitBreaker) Allow() bool {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.state {
+	case CircuitClosed:
+		return true
+	case CircuitOpen:
+		if cb.now().Sub(cb.openedAt) < cb.cooldown {
+			return false
+		}
+		cb.state = CircuitHalfOpen
+		log.Printf("[AGENT-CIRCUIT] Cooldown elapsed, circuit half-open (probing)")
+		fallthrough
+	default: // half-open: one probe at a time
+		if cb.probing {
+			return false
+		}
+		cb.probing = true
+		return true
+	}
+}
+
+// RecordSuccess closes the circuit
+func (cb *CircuitBreaker) RecordSuccess() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.state != CircuitClosed {
+		log.Printf("[AGENT-CIRCUIT] Probe succeeded, circuit closed")
+	}
+	cb.state = CircuitClosed
+	cb.failures = 0
+	cb.probing = false
+}
+
+// Release ends a half-open probe without recording an outcome
+func (cb *CircuitBreaker) Release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.probing = false
+}
+
+// RecordFailure counts a failure and opens the circuit at the threshold
+func (cb *CircuitBreaker) RecordFailure() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.failures++
+	cb.probing = false
+
+	if cb.state == CircuitHalfOpen || (cb.state == CircuitClosed && cb.failures >= cb.threshold) {
+		cb.state = CircuitOpen
+		cb.openedAt = cb.now()
+		cb.totalOpened++
+		log.Printf("[AGENT-CIRCUIT] Circuit opened after %d consecutive failures (cooldown %s)",
+			cb.failures, cb.cooldown)
+	}
+}
+
+// Stats returns the breaker state
+func (cb *CircuitBreaker) Stats() CircuitStats {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	stats := CircuitStats{
+		State:               cb.state,
+		ConsecutiveFailures: cb.failures,
+		Threshold:           cb.threshold,
+		Cooldown:            cb.cooldown.String(),
+		TimesOpened:         cb.totalOpened,
+	}
+	if cb.state != CircuitClosed {
+		openedAt := cb.openedAt
+		stats.OpenedAt = &openedAt
+	}
+	return stats
+}
+
+// CircuitStats holds circuit breaker state for the stats endpoint
+type CircuitStats struct {
+	State               CircuitState `json:"state"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	Threshold           int          `json:"threshold"`
+	Cooldown            string       `json:"cooldown"`
+	TimesOpened         int64        `json:"times_opened"`
+	OpenedAt            *time.Time   `json:"opened_at,omitempty"`
+}
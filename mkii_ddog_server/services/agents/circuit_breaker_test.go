@@ -0,0 +1,91 @@
+package agents
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestCircuit(threshold int, cooldown time.Duration) (*CircuitBreaker, *fakeClock) {
+	clock := &fakeClock{t: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	cb := NewCircuitBreaker(threshold, cooldown)
+	cb.now = clock.now
+	return cb, clock
+}
+
+func TestCircuitBreaker_OpensAfterThreshold(t *testing.T) {
+	cb, _ := newTestCircuit(3, time.Minute)
+
+	for i := 0; i < 2; i++ {
+		cb.RecordFailure()
+	}
+	if !cb.Allow() {
+		t.Fatal("circuit should stay closed below the threshold")
+	}
+
+	// A success resets the consecutive failure count
+	cb.RecordSuccess()
+	cb.RecordFailure()
+	cb.RecordFailure()
+	if !cb.Allow() {
+		t.Fatal("success should reset consecutive failures")
+	}
+
+	cb.RecordFailure()
+	if cb.Allow() {
+		t.Error("circuit should be open after 3 consecutive failures")
+	}
+
+	stats := cb.Stats()
+	if stats.State != CircuitOpen || stats.TimesOpened != 1 || stats.OpenedAt == nil {
+		t.Errorf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestCircuitBreaker_HalfOpenProbe(t *testing.T) {
+	cb, clock := newTestCircuit(1, time.Minute)
+
+	cb.RecordFailure()
+	if cb.Allow() {
+		t.Fatal("circuit should be open")
+	}
+
+	clock.advance(time.Minute)
+	if !cb.Allow() {
+		t.Fatal("one probe should be allowed after cooldown")
+	}
+	if cb.Allow() {
+		t.Error("only one probe should be in flight")
+	}
+
+	// Failed probe re-opens for another cooldown
+	cb.RecordFailure()
+	if cb.Allow() {
+		t.Error("failed probe should re-open the circuit")
+	}
+
+	clock.advance(time.Minute)
+	if !cb.Allow() {
+		t.Fatal("probe should be allowed after second cooldown")
+	}
+	cb.RecordSuccess()
+	if !cb.Allow() || !cb.Allow() {
+		t.Error("successful probe should close the circuit")
+	}
+	if cb.Stats().State != CircuitClosed {
+		t.Errorf("expected closed, got %s", cb.Stats().State)
+	}
+}
+
+func TestCircuitBreaker_ReleaseFreesProbe(t *testing.T) {
+	cb, clock := newTestCircuit(1, time.Minute)
+
+	cb.RecordFailure()
+	clock.advance(time.Minute)
+	if !cb.Allow() {
+		t.Fatal("probe should be allowed after cooldown")
+	}
+	cb.Release()
+	if !cb.Allow() {
+		t.Error("released probe slot should allow another probe")
+	}
+}
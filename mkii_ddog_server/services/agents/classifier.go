@@ -1,166 +1,218 @@
 package agents
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
 )
 
-// RoleClassifier determines which specialist agent should handle an alert
-type RoleClassifier struct {
-	// monitorTypeRules maps Datadog monitor types to agent roles
-	monitorTypeRules map[string]AgentRole
+// Rule names reported for classifications that don't come from the rule set
+const (
+	RuleWatchdogBuiltin = "builtin:watchdog"
+	RuleDefaultFallback = "builtin:default"
+)
 
-	// tagRules maps tag prefixes to agent roles
-	tagRules map[string]AgentRole
+// maxAlternateReasons caps how many lower-precedence matches are reported
+const maxAlternateReasons = 5
 
-	// servicePatterns maps service name patterns to agent roles
-	servicePatterns map[string]AgentRole
+// Classification is the outcome of routing an alert to an agent role
+type Classification struct {
+	Role       AgentRole `json:"role"`
+	Rule       string    `json:"rule"`
+	Confidence float64   `json:"confidence"`
+	Reasons    []string  `json:"reasons"`
 
-	// hostnamePatterns maps hostname patterns to agent roles
-	hostnamePatterns map[string]AgentRole
+	// Candidates ranks every role that matched, best first. The first
+	// candidate is always Role; collaborative analysis uses the rest.
+	Candidates []RoleCandidate `json:"candidates"`
+}
+
+// RoleCandidate is a role that matched at least one rule
+type RoleCandidate struct {
+	Role       AgentRole `json:"role"`
+	Rule       string    `json:"rule"`
+	Confidence float64   `json:"confidence"`
+}
+
+// TopCandidates returns up to n candidates with at least minConfidence.
+// The primary role is always included.
+func (c Classification) TopCandidates(n int, minConfidence float64) []RoleCandidate {
+	if len(c.Candidates) == 0 {
+		return []RoleCandidate{{Role: c.Role, Rule: c.Rule, Confidence: c.Confidence}}
+	}
+
+	top := make([]RoleCandidate, 0, n)
+	for i, candidate := range c.Candidates {
+		if len(top) >= n && n > 0 {
+			break
+		}
+		if i > 0 && candidate.Confidence < minConfidence {
+			continue
+		}
+		top = append(top, candidate)
+	}
+	return top
+}
+
+// ClassifierInfo describes the rule set currently loaded into a classifier
+type ClassifierInfo struct {
+	Source      string    `json:"source"`
+	RuleCount   int       `json:"rule_count"`
+	Fingerprint string    `json:"fingerprint"`
+	LoadedAt    time.Time `json:"loaded_at"`
+}
+
+// RoleClassifier determines which specialist agent should handle an alert.
+// Rules are evaluated in a fixed order (see compileRules) so the same payload
+// always routes the same way. The rule set can be swapped at runtime.
+type RoleClassifier struct {
+	rules       []ClassifierRule
+	source      string
+	fingerprint string
+	loadedAt    time.Time
+	mu          sync.RWMutex
 }
 
 // NewRoleClassifier creates a new classifier with default rules
 func NewRoleClassifier() *RoleClassifier {
-	return &RoleClassifier{
-		monitorTypeRules: map[string]AgentRole{
-			// APM and application monitors
-			"apm":               RoleApplication,
-			"trace-analytics":   RoleApplication,
-			"rum":               RoleApplication,
-			"error tracking":    RoleApplication,
-			"profiling":         RoleApplication,
-
-			// Infrastructure monitors
-			"metric":            RoleInfrastructure,
-			"host":              RoleInfrastructure,
-			"process":           RoleInfrastructure,
-			"integration":       RoleInfrastructure,
-			"service check":     RoleInfrastructure,
-			"outlier":           RoleInfrastructure,
-			"forecast":          RoleInfrastructure,
-			"anomaly":           RoleInfrastructure,
-
-			// Database monitors
-			"dbm":               RoleDatabase,
-			"database":          RoleDatabase,
-
-			// Log monitors
-			"logs":              RoleLogs,
-			"log":               RoleLogs,
-
-			// Network monitors
-			"synthetics":        RoleNetwork,
-			"network":           RoleNetwork,
-			"network performance": RoleNetwork,
-		},
-
-		tagRules: map[string]AgentRole{
-			"monitor_type:apm":           RoleApplication,
-			"monitor_type:dbm":           RoleDatabase,
-			"monitor_type:logs":          RoleLogs,
-			"monitor_type:network":       RoleNetwork,
-			"monitor_type:infrastructure": RoleInfrastructure,
-			"service_type:database":      RoleDatabase,
-			"service_type:web":           RoleApplication,
-			"service_type:api":           RoleApplication,
-			"tier:database":              RoleDatabase,
-			"tier:application":           RoleApplication,
-			"tier:network":               RoleNetwork,
-		},
-
-		servicePatterns: map[string]AgentRole{
-			// Database services
-			"postgres":  RoleDatabase,
-			"mysql":     RoleDatabase,
-			"mongodb":   RoleDatabase,
-			"redis":     RoleDatabase,
-			"memcached": RoleDatabase,
-			"cassandra": RoleDatabase,
-			"db":        RoleDatabase,
-			"database":  RoleDatabase,
-			"rds":       RoleDatabase,
-			"aurora":    RoleDatabase,
-			"dynamo":    RoleDatabase,
-
-			// Application services
-			"api":       RoleApplication,
-			"web":       RoleApplication,
-			"frontend":  RoleApplication,
-			"backend":   RoleApplication,
-			"service":   RoleApplication,
-			"app":       RoleApplication,
-			"graphql":   RoleApplication,
-			"rest":      RoleApplication,
-
-			// Network services
-			"nginx":     RoleNetwork,
-			"haproxy":   RoleNetwork,
-			"loadbalancer": RoleNetwork,
-			"lb":        RoleNetwork,
-			"cdn":       RoleNetwork,
-			"gateway":   RoleNetwork,
-			"proxy":     RoleNetwork,
-			"dns":       RoleNetwork,
-
-			// Infrastructure
-			"kubernetes": RoleInfrastructure,
-			"k8s":        RoleInfrastructure,
-			"docker":     RoleInfrastructure,
-			"container":  RoleInfrastructure,
-			"ec2":        RoleInfrastructure,
-			"lambda":     RoleInfrastructure,
-			"ecs":        RoleInfrastructure,
-		},
-
-		hostnamePatterns: map[string]AgentRole{
-			"db":      RoleDatabase,
-			"mysql":   RoleDatabase,
-			"postgres": RoleDatabase,
-			"redis":   RoleDatabase,
-			"mongo":   RoleDatabase,
-			"web":     RoleApplication,
-			"api":     RoleApplication,
-			"app":     RoleApplication,
-			"lb":      RoleNetwork,
-			"proxy":   RoleNetwork,
-			"nginx":   RoleNetwork,
-		},
+	c, err := NewRoleClassifierWithRules(DefaultClassifierRules(), "defaults")
+	if err != nil {
+		// Built-in rules are static; failing to compile them is a programming error
+		panic(fmt.Sprintf("compile default classifier rules: %v", err))
+	}
+	return c
+}
+
+// NewRoleClassifierWithRules creates a classifier from an explicit rule set
+func NewRoleClassifierWithRules(rules []ClassifierRule, source string) (*RoleClassifier, error) {
+	c := &RoleClassifier{}
+	if err := c.SetRules(rules, source); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// SetRules validates and atomically replaces the classifier's rule set.
+// On error the existing rules are left untouched.
+func (c *RoleClassifier) SetRules(rules []ClassifierRule, source string) error {
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return err
+	}
+
+	fingerprint := fingerprintRules(compiled)
+
+	c.mu.Lock()
+	c.rules = compiled
+	c.source = source
+	c.fingerprint = fingerprint
+	c.loadedAt = time.Now()
+	c.mu.Unlock()
+
+	return nil
+}
+
+// Rules returns a copy of the active rules in evaluation order
+func (c *RoleClassifier) Rules() []ClassifierRule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	rules := make([]ClassifierRule, len(c.rules))
+	copy(rules, c.rules)
+	return rules
+}
+
+// Info returns metadata about the active rule set
+func (c *RoleClassifier) Info() ClassifierInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	return ClassifierInfo{
+		Source:      c.source,
+		RuleCount:   len(c.rules),
+		Fingerprint: c.fingerprint,
+		LoadedAt:    c.loadedAt,
 	}
 }
 
 // Classify determines the appropriate agent role for an alert event
-func (c *RoleClassifier) Classify(event *types.AlertEvent) AgentRole {
+func (c *RoleClassifier) Classify(event *types.AlertEvent) Classification {
 	payload := event.Payload
 
-	// 0. Check for Watchdog monitors first (highest priority, special Datadog feature)
+	// Watchdog monitors are a special Datadog feature and always take precedence
 	if IsWatchdog(payload.MonitorType, payload.MonitorName, payload.AlertTitle, payload.Tags) {
-		return RoleWatchdog
+		return Classification{
+			Role:       RoleWatchdog,
+			Rule:       RuleWatchdogBuiltin,
+			Confidence: 1.0,
+			Reasons:    []string{"monitor type, name, title or tags identify a Datadog Watchdog monitor"},
+			Candidates: []RoleCandidate{{Role: RoleWatchdog, Rule: RuleWatchdogBuiltin, Confidence: 1.0}},
+		}
 	}
 
-	// 1. Check explicit monitor type
-	if role := c.classifyByMonitorType(payload.MonitorType); role != RoleGeneral {
-		return role
-	}
+	fields := fieldValues(payload)
 
-	// 2. Check tags for monitor_type or service_type hints
-	if role := c.classifyByTags(payload.Tags); role != RoleGeneral {
-		return role
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result *Classification
+	alternates := 0
+	seenRoles := make(map[AgentRole]bool)
+
+	for i := range c.rules {
+		rule := &c.rules[i]
+		value, ok := firstMatch(rule, fields[rule.Field])
+		if !ok {
+			continue
+		}
+
+		if result == nil {
+			result = &Classification{
+				Role:       rule.Role,
+				Rule:       rule.Name,
+				Confidence: rule.Confidence,
+				Reasons: []string{fmt.Sprintf("rule %q matched %s=%q (%s %q, priority %d) -> %s",
+					rule.Name, rule.Field, value, rule.Match, rule.Pattern, rule.Priority, rule.Role)},
+			}
+		} else {
+			if alternates < maxAlternateReasons {
+				result.Reasons = append(result.Reasons, fmt.Sprintf("lower precedence: rule %q matched %s=%q -> %s",
+					rule.Name, rule.Field, value, rule.Role))
+			}
+			alternates++
+		}
+
+		// The first (highest precedence) match for each role ranks that role
+		if !seenRoles[rule.Role] {
+			seenRoles[rule.Role] = true
+			result.Candidates = append(result.Candidates,
+				RoleCandidate{Role: rule.Role, Rule: rule.Name, Confidence: rule.Confidence})
+		}
 	}
 
-	// 3. Infer from service name patterns
-	if role := c.classifyByService(payload.Service); role != RoleGeneral {
-		return role
+	if result == nil {
+		// Default to infrastructure (most common)
+		return Classification{
+			Role:       RoleInfrastructure,
+			Rule:       RuleDefaultFallback,
+			Confidence: 0.3,
+			Reasons:    []string{"no rule matched; defaulting to infrastructure"},
+			Candidates: []RoleCandidate{{Role: RoleInfrastructure, Rule: RuleDefaultFallback, Confidence: 0.3}},
+		}
 	}
 
-	// 4. Infer from hostname patterns
-	if role := c.classifyByHostname(payload.Hostname); role != RoleGeneral {
-		return role
+	if alternates > maxAlternateReasons {
+		result.Reasons = append(result.Reasons, fmt.Sprintf("%d more lower-precedence matches omitted", alternates-maxAlternateReasons))
 	}
 
-	// 5. Default to infrastructure (most common)
-	return RoleInfrastructure
+	return *result
 }
 
 // IsWatchdog determines if a monitor is a Datadog Watchdog anomaly detection monitor.
@@ -192,79 +244,41 @@ func IsWatchdog(monitorType, monitorName, alertTitle string, tags []string) bool
 	return false
 }
 
-// classifyByMonitorType checks the monitor type field
-func (c *RoleClassifier) classifyByMonitorType(monitorType string) AgentRole {
-	if monitorType == "" {
-		return RoleGeneral
-	}
-
-	mt := strings.ToLower(monitorType)
-	if role, ok := c.monitorTypeRules[mt]; ok {
-		return role
+// fieldValues extracts the lower-cased payload values each rule field inspects
+func fieldValues(payload types.AlertPayload) map[RuleField][]string {
+	tags := make([]string, 0, len(payload.Tags))
+	for _, tag := range payload.Tags {
+		tags = append(tags, strings.ToLower(tag))
 	}
 
-	// Check for partial matches
-	for pattern, role := range c.monitorTypeRules {
-		if strings.Contains(mt, pattern) {
-			return role
-		}
+	return map[RuleField][]string{
+		FieldMonitorType: {strings.ToLower(payload.MonitorType)},
+		FieldTag:         tags,
+		FieldService:     {strings.ToLower(payload.Service)},
+		FieldHostname:    {strings.ToLower(payload.Hostname)},
+		FieldMonitorName: {strings.ToLower(payload.MonitorName)},
 	}
-
-	return RoleGeneral
 }
 
-// classifyByTags checks tags for role hints
-func (c *RoleClassifier) classifyByTags(tags []string) AgentRole {
-	for _, tag := range tags {
-		tagLower := strings.ToLower(tag)
-
-		// Check exact tag matches
-		if role, ok := c.tagRules[tagLower]; ok {
-			return role
-		}
-
-		// Check for monitor_type: prefix
-		if strings.HasPrefix(tagLower, "monitor_type:") {
-			monitorType := strings.TrimPrefix(tagLower, "monitor_type:")
-			if role, ok := c.monitorTypeRules[monitorType]; ok {
-				return role
-			}
-		}
-	}
-
-	return RoleGeneral
-}
-
-// classifyByService checks service name for patterns
-func (c *RoleClassifier) classifyByService(service string) AgentRole {
-	if service == "" {
-		return RoleGeneral
-	}
-
-	svc := strings.ToLower(service)
-	for pattern, role := range c.servicePatterns {
-		if strings.Contains(svc, pattern) {
-			return role
+// firstMatch returns the first value the rule matches
+func firstMatch(rule *ClassifierRule, values []string) (string, bool) {
+	for _, v := range values {
+		if rule.matches(v) {
+			return v, true
 		}
 	}
-
-	return RoleGeneral
+	return "", false
 }
 
-// classifyByHostname checks hostname for patterns
-func (c *RoleClassifier) classifyByHostname(hostname string) AgentRole {
-	if hostname == "" {
-		return RoleGeneral
+// fingerprintRules hashes a compiled rule set so reloads can detect changes
+func fingerprintRules(rules []ClassifierRule) string {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		log.Printf("[AGENT-CLASSIFIER] Failed to fingerprint rules: %v", err)
+		return ""
 	}
-
-	host := strings.ToLower(hostname)
-	for pattern, role := range c.hostnamePatterns {
-		if strings.Contains(host, pattern) {
-			return role
-		}
-	}
-
-	return RoleGeneral
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:8])
 }
 
 // containsAny checks if s contains any of the patterns
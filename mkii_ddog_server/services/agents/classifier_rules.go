@@ -0,0 +1,374 @@
+package agents
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// RuleField identifies which part of an alert payload a classifier rule inspects
+type RuleField string
+
+const (
+	FieldMonitorType RuleField = "monitor_type"
+	FieldTag         RuleField = "tag"
+	FieldService     RuleField = "service"
+	FieldHostname    RuleField = "hostname"
+	FieldMonitorName RuleField = "monitor_name"
+)
+
+// MatchType determines how a rule pattern is compared against a field value
+type MatchType string
+
+const (
+	MatchExact    MatchType = "exact"
+	MatchContains MatchType = "contains"
+	MatchPrefix   MatchType = "prefix"
+	MatchRegex    MatchType = "regex"
+)
+
+// Default rule priorities per field. Higher priorities are evaluated first,
+// which preserves the original precedence: monitor type > tags > service > hostname.
+const (
+	priorityMonitorTypeExact    = 400
+	priorityMonitorTypeContains = 350
+	priorityTag                 = 300
+	priorityService             = 200
+	priorityHostname            = 100
+)
+
+// ClassifierRule maps a payload field match to an agent role.
+// Rules are evaluated in priority order (highest first); ties are broken by
+// pattern length (longest first) and then by name so results are deterministic.
+type ClassifierRule struct {
+	Name       string    `json:"name" yaml:"name"`
+	Field      RuleField `json:"field" yaml:"field"`
+	Match      MatchType `json:"match" yaml:"match"`
+	Pattern    string    `json:"pattern" yaml:"pattern"`
+	Role       AgentRole `json:"role" yaml:"role"`
+	Priority   int       `json:"priority" yaml:"priority"`
+	Confidence float64   `json:"confidence,omitempty" yaml:"confidence,omitempty"`
+	UpdatedBy  string    `json:"updated_by,omitempty" yaml:"-"` // operator who last saved a stored rule
+
+	re *regexp.Regexp
+}
+
+// compile validates the rule and prepares it for matching.
+// Patterns are lower-cased (regexes are made case-insensitive) because
+// payload values are compared case-insensitively.
+func (r *ClassifierRule) compile() error {
+	if r.Pattern == "" {
+		return fmt.Errorf("rule %q: pattern is required", r.Name)
+	}
+	if !isKnownRole(r.Role) {
+		return fmt.Errorf("rule %q: unknown role %q", r.Name, r.Role)
+	}
+
+	switch r.Field {
+	case FieldMonitorType, FieldTag, FieldService, FieldHostname, FieldMonitorName:
+	default:
+		return fmt.Errorf("rule %q: unknown field %q", r.Name, r.Field)
+	}
+
+	if r.Match == "" {
+		r.Match = MatchContains
+	}
+	switch r.Match {
+	case MatchExact, MatchContains, MatchPrefix:
+		r.Pattern = strings.ToLower(r.Pattern)
+	case MatchRegex:
+		re, err := regexp.Compile("(?i)" + r.Pattern)
+		if err != nil {
+			return fmt.Errorf("rule %q: invalid regex: %w", r.Name, err)
+		}
+		r.re = re
+	default:
+		return fmt.Errorf("rule %q: unknown match type %q", r.Name, r.Match)
+	}
+
+	if r.Name == "" {
+		r.Name = fmt.Sprintf("%s-%s-%s", r.Field, r.Match, r.Pattern)
+	}
+	if r.Confidence <= 0 || r.Confidence > 1 {
+		r.Confidence = defaultConfidence(r.Field, r.Match)
+	}
+	return nil
+}
+
+// matches reports whether the (already lower-cased) value satisfies the rule
+func (r *ClassifierRule) matches(value string) bool {
+	if value == "" {
+		return false
+	}
+	switch r.Match {
+	case MatchExact:
+		return value == r.Pattern
+	case MatchPrefix:
+		return strings.HasPrefix(value, r.Pattern)
+	case MatchRegex:
+		return r.re != nil && r.re.MatchString(value)
+	default:
+		return strings.Contains(value, r.Pattern)
+	}
+}
+
+// defaultConfidence returns the confidence for rules that don't specify one.
+// Explicit monitor types are the strongest signal, hostname substrings the weakest.
+func defaultConfidence(field RuleField, match MatchType) float64 {
+	switch field {
+	case FieldMonitorType:
+		if match == MatchExact {
+			return 0.9
+		}
+		return 0.75
+	case FieldTag:
+		return 0.8
+	case FieldService:
+		return 0.6
+	case FieldMonitorName:
+		return 0.55
+	default:
+		return 0.5
+	}
+}
+
+// isKnownRole reports whether role is one of the defined agent roles
+func isKnownRole(role AgentRole) bool {
+	switch role {
+	case RoleInfrastructure, RoleApplication, RoleNetwork, RoleDatabase,
+		RoleLogs, RoleWatchdog, RoleGeneral:
+		return true
+	}
+	return false
+}
+
+// compileRules validates and sorts a rule set into evaluation order.
+// The input slice is copied so callers can keep using it.
+func compileRules(rules []ClassifierRule) ([]ClassifierRule, error) {
+	compiled := make([]ClassifierRule, len(rules))
+	copy(compiled, rules)
+
+	seen := make(map[string]bool, len(compiled))
+	for i := range compiled {
+		if err := compiled[i].compile(); err != nil {
+			return nil, err
+		}
+		if seen[compiled[i].Name] {
+			return nil, fmt.Errorf("duplicate rule name %q", compiled[i].Name)
+		}
+		seen[compiled[i].Name] = true
+	}
+
+	sort.SliceStable(compiled, func(i, j int) bool {
+		a, b := compiled[i], compiled[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if len(a.Pattern) != len(b.Pattern) {
+			return len(a.Pattern) > len(b.Pattern)
+		}
+		return a.Name < b.Name
+	})
+
+	return compiled, nil
+}
+
+// mergeRules layers custom rules over base rules; a custom rule replaces a
+// base rule with the same name.
+func mergeRules(base, custom []ClassifierRule) []ClassifierRule {
+	overridden := make(map[string]bool, len(custom))
+	for _, r := range custom {
+		overridden[r.Name] = true
+	}
+
+	merged := make([]ClassifierRule, 0, len(base)+len(custom))
+	for _, r := range base {
+		if !overridden[r.Name] {
+			merged = append(merged, r)
+		}
+	}
+	return append(merged, custom...)
+}
+
+// DefaultClassifierRules returns the built-in routing rules
+func DefaultClassifierRules() []ClassifierRule {
+	monitorTypes := []struct {
+		pattern string
+		role    AgentRole
+	}{
+		// APM and application monitors
+		{"apm", RoleApplication},
+		{"trace-analytics", RoleApplication},
+		{"rum", RoleApplication},
+		{"error tracking", RoleApplication},
+		{"profiling", RoleApplication},
+
+		// Infrastructure monitors
+		{"metric", RoleInfrastructure},
+		{"host", RoleInfrastructure},
+		{"process", RoleInfrastructure},
+		{"integration", RoleInfrastructure},
+		{"service check", RoleInfrastructure},
+		{"outlier", RoleInfrastructure},
+		{"forecast", RoleInfrastructure},
+		{"anomaly", RoleInfrastructure},
+
+		// Database monitors
+		{"dbm", RoleDatabase},
+		{"database", RoleDatabase},
+
+		// Log monitors
+		{"logs", RoleLogs},
+		{"log", RoleLogs},
+
+		// Network monitors
+		{"synthetics", RoleNetwork},
+		{"network", RoleNetwork},
+		{"network performance", RoleNetwork},
+	}
+
+	tags := []struct {
+		pattern string
+		role    AgentRole
+	}{
+		{"monitor_type:infrastructure", RoleInfrastructure},
+		{"service_type:database", RoleDatabase},
+		{"service_type:web", RoleApplication},
+		{"service_type:api", RoleApplication},
+		{"tier:database", RoleDatabase},
+		{"tier:application", RoleApplication},
+		{"tier:network", RoleNetwork},
+	}
+
+	services := []struct {
+		pattern string
+		role    AgentRole
+	}{
+		// Database services
+		{"postgres", RoleDatabase},
+		{"mysql", RoleDatabase},
+		{"mongodb", RoleDatabase},
+		{"redis", RoleDatabase},
+		{"memcached", RoleDatabase},
+		{"cassandra", RoleDatabase},
+		{"db", RoleDatabase},
+		{"database", RoleDatabase},
+		{"rds", RoleDatabase},
+		{"aurora", RoleDatabase},
+		{"dynamo", RoleDatabase},
+
+		// Application services
+		{"api", RoleApplication},
+		{"web", RoleApplication},
+		{"frontend", RoleApplication},
+		{"backend", RoleApplication},
+		{"service", RoleApplication},
+		{"app", RoleApplication},
+		{"graphql", RoleApplication},
+		{"rest", RoleApplication},
+
+		// Network services
+		{"nginx", RoleNetwork},
+		{"haproxy", RoleNetwork},
+		{"loadbalancer", RoleNetwork},
+		{"lb", RoleNetwork},
+		{"cdn", RoleNetwork},
+		{"gateway", RoleNetwork},
+		{"proxy", RoleNetwork},
+		{"dns", RoleNetwork},
+
+		// Infrastructure
+		{"kubernetes", RoleInfrastructure},
+		{"k8s", RoleInfrastructure},
+		{"docker", RoleInfrastructure},
+		{"container", RoleInfrastructure},
+		{"ec2", RoleInfrastructure},
+		{"lambda", RoleInfrastructure},
+		{"ecs", RoleInfrastructure},
+	}
+
+	hostnames := []struct {
+		pattern string
+		role    AgentRole
+	}{
+		{"db", RoleDatabase},
+		{"mysql", RoleDatabase},
+		{"postgres", RoleDatabase},
+		{"redis", RoleDatabase},
+		{"mongo", RoleDatabase},
+		{"web", RoleApplication},
+		{"api", RoleApplication},
+		{"app", RoleApplication},
+		{"lb", RoleNetwork},
+		{"proxy", RoleNetwork},
+		{"nginx", RoleNetwork},
+	}
+
+	var rules []ClassifierRule
+
+	for _, mt := range monitorTypes {
+		rules = append(rules,
+			ClassifierRule{
+				Name:     "monitor-type-exact:" + mt.pattern,
+				Field:    FieldMonitorType,
+				Match:    MatchExact,
+				Pattern:  mt.pattern,
+				Role:     mt.role,
+				Priority: priorityMonitorTypeExact,
+			},
+			ClassifierRule{
+				Name:     "monitor-type-contains:" + mt.pattern,
+				Field:    FieldMonitorType,
+				Match:    MatchContains,
+				Pattern:  mt.pattern,
+				Role:     mt.role,
+				Priority: priorityMonitorTypeContains,
+			},
+			// monitor_type:<type> tags route the same way as the monitor type itself
+			ClassifierRule{
+				Name:     "tag:monitor_type:" + mt.pattern,
+				Field:    FieldTag,
+				Match:    MatchExact,
+				Pattern:  "monitor_type:" + mt.pattern,
+				Role:     mt.role,
+				Priority: priorityTag,
+			},
+		)
+	}
+
+	for _, t := range tags {
+		rules = append(rules, ClassifierRule{
+			Name:     "tag:" + t.pattern,
+			Field:    FieldTag,
+			Match:    MatchExact,
+			Pattern:  t.pattern,
+			Role:     t.role,
+			Priority: priorityTag,
+		})
+	}
+
+	for _, s := range services {
+		rules = append(rules, ClassifierRule{
+			Name:     "service:" + s.pattern,
+			Field:    FieldService,
+			Match:    MatchContains,
+			Pattern:  s.pattern,
+			Role:     s.role,
+			Priority: priorityService,
+		})
+	}
+
+	for _, h := range hostnames {
+		rules = append(rules, ClassifierRule{
+			Name:     "hostname:" + h.pattern,
+			Field:    FieldHostname,
+			Match:    MatchContains,
+			Pattern:  h.pattern,
+			Role:     h.role,
+			Priority: priorityHostname,
+		})
+	}
+
+	return rules
+}
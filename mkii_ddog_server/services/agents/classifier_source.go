@@ -0,0 +1,259 @@
+package agents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleSource loads classifier rules from an external store
+type RuleSource interface {
+	// Name identifies the source in logs and ClassifierInfo
+	Name() string
+
+	// Load returns the complete rule set to install (defaults included)
+	Load(ctx context.Context) ([]ClassifierRule, error)
+}
+
+// RuleFile is the on-disk YAML format for classifier rules.
+//
+//	replace_defaults: false
+//	rules:
+//	  - name: payments-db
+//	    field: service
+//	    match: regex
+//	    pattern: "^payments-(pg|db)"
+//	    role: database
+//	    priority: 250
+type RuleFile struct {
+	// ReplaceDefaults discards the built-in rules instead of layering on top of them
+	ReplaceDefaults bool             `yaml:"replace_defaults"`
+	Rules           []ClassifierRule `yaml:"rules"`
+}
+
+// FileRuleSource loads classifier rules from a YAML file
+type FileRuleSource struct {
+	path string
+}
+
+// NewFileRuleSource creates a rule source backed by a YAML file
+func NewFileRuleSource(path string) *FileRuleSource {
+	return &FileRuleSource{path: path}
+}
+
+// Name returns the source identifier
+func (s *FileRuleSource) Name() string {
+	return "file:" + s.path
+}
+
+// Load reads and parses the rule file
+func (s *FileRuleSource) Load(ctx context.Context) ([]ClassifierRule, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, fmt.Errorf("read rule file: %w", err)
+	}
+
+	var file RuleFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("parse rule file %s: %w", s.path, err)
+	}
+
+	if file.ReplaceDefaults {
+		return file.Rules, nil
+	}
+	return mergeRules(DefaultClassifierRules(), file.Rules), nil
+}
+
+// RuleStorage persists custom classifier rules in Postgres.
+// Stored rules are layered over the built-in defaults; a stored rule with the
+// same name as a default replaces it.
+type RuleStorage struct {
+	db *sql.DB
+}
+
+// NewRuleStorage creates a new classifier rule storage
+func NewRuleStorage(db *sql.DB) *RuleStorage {
+	return &RuleStorage{db: db}
+}
+
+// InitTables creates the classifier rules table
+func (s *RuleStorage) InitTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS agent_classifier_rules (
+			name VARCHAR(255) PRIMARY KEY,
+			field VARCHAR(50) NOT NULL,
+			match_type VARCHAR(20) NOT NULL DEFAULT 'contains',
+			pattern TEXT NOT NULL,
+			role VARCHAR(50) NOT NULL,
+			priority INT NOT NULL DEFAULT 0,
+			confidence DOUBLE PRECISION NOT NULL DEFAULT 0,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		ALTER TABLE agent_classifier_rules ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255) NOT NULL DEFAULT '';
+
+		CREATE INDEX IF NOT EXISTS idx_agent_classifier_rules_priority ON agent_classifier_rules(priority DESC);
+	`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// Name returns the source identifier
+func (s *RuleStorage) Name() string {
+	return "postgres:agent_classifier_rules"
+}
+
+// Load returns the stored rules layered over the built-in defaults
+func (s *RuleStorage) Load(ctx context.Context) ([]ClassifierRule, error) {
+	custom, err := s.ListRules(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return mergeRules(DefaultClassifierRules(), custom), nil
+}
+
+// ListRules retrieves all stored custom rules
+func (s *RuleStorage) ListRules(ctx context.Context) ([]ClassifierRule, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT name, field, match_type, pattern, role, priority, confidence, updated_by
+		FROM agent_classifier_rules
+		ORDER BY priority DESC, name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list classifier rules: %w", err)
+	}
+	defer rows.Close()
+
+	var rules []ClassifierRule
+	for rows.Next() {
+		var r ClassifierRule
+		if err := rows.Scan(&r.Name, &r.Field, &r.Match, &r.Pattern, &r.Role, &r.Priority, &r.Confidence, &r.UpdatedBy); err != nil {
+			return nil, fmt.Errorf("scan classifier rule: %w", err)
+		}
+		rules = append(rules, r)
+	}
+
+	return rules, rows.Err()
+}
+
+// UpsertRule creates or replaces a custom rule. The rule is validated first.
+func (s *RuleStorage) UpsertRule(ctx context.Context, rule ClassifierRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("rule name is required")
+	}
+	if _, err := compileRules([]ClassifierRule{rule}); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO agent_classifier_rules (name, field, match_type, pattern, role, priority, confidence, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (name) DO UPDATE SET
+			field = EXCLUDED.field,
+			match_type = EXCLUDED.match_type,
+			pattern = EXCLUDED.pattern,
+			role = EXCLUDED.role,
+			priority = EXCLUDED.priority,
+			confidence = EXCLUDED.confidence,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = CURRENT_TIMESTAMP
+	`, rule.Name, rule.Field, rule.Match, rule.Pattern, rule.Role, rule.Priority, rule.Confidence, rule.UpdatedBy)
+	if err != nil {
+		return fmt.Errorf("upsert classifier rule: %w", err)
+	}
+	return nil
+}
+
+// DeleteRule removes a custom rule by name
+func (s *RuleStorage) DeleteRule(ctx context.Context, name string) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM agent_classifier_rules WHERE name = $1`, name)
+	if err != nil {
+		return fmt.Errorf("delete classifier rule: %w", err)
+	}
+
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// RuleSourceFromEnv builds the rule source selected by AGENT_CLASSIFIER_RULES:
+// empty for built-in rules only, "postgres" for the database, or a YAML file path.
+func RuleSourceFromEnv(db *sql.DB) RuleSource {
+	switch setting := os.Getenv("AGENT_CLASSIFIER_RULES"); setting {
+	case "":
+		return nil
+	case "postgres":
+		return NewRuleStorage(db)
+	default:
+		return NewFileRuleSource(setting)
+	}
+}
+
+// RuleReloadIntervalFromEnv returns AGENT_CLASSIFIER_RELOAD_INTERVAL (default 30s)
+func RuleReloadIntervalFromEnv() time.Duration {
+	if v := os.Getenv("AGENT_CLASSIFIER_RELOAD_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+		log.Printf("[AGENT-CLASSIFIER] Invalid AGENT_CLASSIFIER_RELOAD_INTERVAL %q, using 30s", v)
+	}
+	return 30 * time.Second
+}
+
+// Reload loads rules from the source and installs them if they changed.
+// Returns true when a new rule set was installed.
+func (c *RoleClassifier) Reload(ctx context.Context, source RuleSource) (bool, error) {
+	rules, err := source.Load(ctx)
+	if err != nil {
+		return false, fmt.Errorf("load rules from %s: %w", source.Name(), err)
+	}
+
+	compiled, err := compileRules(rules)
+	if err != nil {
+		return false, fmt.Errorf("validate rules from %s: %w", source.Name(), err)
+	}
+
+	if fingerprintRules(compiled) == c.Info().Fingerprint {
+		return false, nil
+	}
+
+	if err := c.SetRules(rules, source.Name()); err != nil {
+		return false, err
+	}
+
+	info := c.Info()
+	log.Printf("[AGENT-CLASSIFIER] Loaded %d rules from %s (fingerprint %s)",
+		info.RuleCount, info.Source, info.Fingerprint)
+	return true, nil
+}
+
+// WatchRules polls the source and hot-reloads the classifier until ctx is cancelled.
+// Invalid rule sets are logged and ignored so a bad edit never drops routing.
+func (c *RoleClassifier) WatchRules(ctx context.Context, source RuleSource, interval time.Duration) {
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := c.Reload(ctx, source); err != nil {
+				log.Printf("[AGENT-CLASSIFIER] Reload failed, keeping current rules: %v", err)
+			}
+		}
+	}
+}
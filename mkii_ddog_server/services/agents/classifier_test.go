@@ -1,9 +1,16 @@
 package agents
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
 )
 
 func TestRoleClassifier_ClassifyByMonitorType(t *testing.T) {
@@ -38,7 +45,7 @@ func TestRoleClassifier_ClassifyByMonitorType(t *testing.T) {
 					MonitorType: tt.monitorType,
 				},
 			}
-			role := classifier.Classify(event)
+			role := classifier.Classify(event).Role
 			if role != tt.expected {
 				t.Errorf("MonitorType %q: expected %s, got %s", tt.monitorType, tt.expected, role)
 			}
@@ -93,7 +100,7 @@ func TestRoleClassifier_ClassifyByTags(t *testing.T) {
 					Tags: tt.tags,
 				},
 			}
-			role := classifier.Classify(event)
+			role := classifier.Classify(event).Role
 			if role != tt.expected {
 				t.Errorf("Tags %v: expected %s, got %s", tt.tags, tt.expected, role)
 			}
@@ -131,7 +138,7 @@ func TestRoleClassifier_ClassifyByService(t *testing.T) {
 					Service: tt.service,
 				},
 			}
-			role := classifier.Classify(event)
+			role := classifier.Classify(event).Role
 			if role != tt.expected {
 				t.Errorf("Service %q: expected %s, got %s", tt.service, tt.expected, role)
 			}
@@ -166,7 +173,7 @@ func TestRoleClassifier_ClassifyByHostname(t *testing.T) {
 					Hostname: tt.hostname,
 				},
 			}
-			role := classifier.Classify(event)
+			role := classifier.Classify(event).Role
 			if role != tt.expected {
 				t.Errorf("Hostname %q: expected %s, got %s", tt.hostname, tt.expected, role)
 			}
@@ -181,11 +188,11 @@ func TestRoleClassifier_Priority(t *testing.T) {
 	t.Run("MonitorType beats tags", func(t *testing.T) {
 		event := &types.AlertEvent{
 			Payload: types.AlertPayload{
-				MonitorType: "apm",                           // Application
-				Tags:        []string{"monitor_type:logs"},   // Would be Logs
+				MonitorType: "apm",                         // Application
+				Tags:        []string{"monitor_type:logs"}, // Would be Logs
 			},
 		}
-		role := classifier.Classify(event)
+		role := classifier.Classify(event).Role
 		if role != RoleApplication {
 			t.Errorf("Expected Application (from MonitorType), got %s", role)
 		}
@@ -199,7 +206,7 @@ func TestRoleClassifier_Priority(t *testing.T) {
 				Service: "api-server",                 // Would be Application
 			},
 		}
-		role := classifier.Classify(event)
+		role := classifier.Classify(event).Role
 		if role != RoleDatabase {
 			t.Errorf("Expected Database (from Tags), got %s", role)
 		}
@@ -213,7 +220,7 @@ func TestRoleClassifier_Priority(t *testing.T) {
 				Hostname: "web-server-01",    // Would be Application
 			},
 		}
-		role := classifier.Classify(event)
+		role := classifier.Classify(event).Role
 		if role != RoleDatabase {
 			t.Errorf("Expected Database (from Service), got %s", role)
 		}
@@ -240,7 +247,7 @@ func TestRoleClassifier_CaseInsensitive(t *testing.T) {
 					MonitorType: tt.monitorType,
 				},
 			}
-			role := classifier.Classify(event)
+			role := classifier.Classify(event).Role
 			if role != tt.expected {
 				t.Errorf("MonitorType %q: expected %s, got %s", tt.monitorType, tt.expected, role)
 			}
@@ -254,17 +261,271 @@ func TestNewRoleClassifier(t *testing.T) {
 	if classifier == nil {
 		t.Fatal("Classifier should not be nil")
 	}
-	if len(classifier.monitorTypeRules) == 0 {
-		t.Error("monitorTypeRules should not be empty")
+
+	byField := make(map[RuleField]int)
+	for _, rule := range classifier.Rules() {
+		byField[rule.Field]++
+	}
+	for _, field := range []RuleField{FieldMonitorType, FieldTag, FieldService, FieldHostname} {
+		if byField[field] == 0 {
+			t.Errorf("expected default rules for field %s", field)
+		}
+	}
+
+	if info := classifier.Info(); info.Source != "defaults" || info.Fingerprint == "" {
+		t.Errorf("unexpected classifier info: %+v", info)
+	}
+}
+
+func TestRoleClassifier_Classification(t *testing.T) {
+	classifier := NewRoleClassifier()
+
+	t.Run("reports winning rule and alternates", func(t *testing.T) {
+		c := classifier.Classify(&types.AlertEvent{
+			Payload: types.AlertPayload{
+				Service:  "postgres-primary",
+				Hostname: "web-server-01",
+			},
+		})
+		if c.Role != RoleDatabase {
+			t.Fatalf("expected database, got %s", c.Role)
+		}
+		if c.Rule != "service:postgres" {
+			t.Errorf("expected rule service:postgres, got %s", c.Rule)
+		}
+		if c.Confidence <= 0 || c.Confidence > 1 {
+			t.Errorf("confidence out of range: %v", c.Confidence)
+		}
+		if len(c.Reasons) < 2 {
+			t.Errorf("expected hostname match listed as lower precedence, got %v", c.Reasons)
+		}
+		if len(c.Candidates) != 2 || c.Candidates[0].Role != RoleDatabase || c.Candidates[1].Role != RoleApplication {
+			t.Errorf("expected ranked candidates [database application], got %+v", c.Candidates)
+		}
+		if top := c.TopCandidates(1, 0); len(top) != 1 {
+			t.Errorf("expected TopCandidates to honour n, got %+v", top)
+		}
+		if top := c.TopCandidates(3, 0.95); len(top) != 1 || top[0].Role != RoleDatabase {
+			t.Errorf("expected primary only above confidence floor, got %+v", top)
+		}
+	})
+
+	t.Run("default fallback", func(t *testing.T) {
+		c := classifier.Classify(&types.AlertEvent{})
+		if c.Role != RoleInfrastructure || c.Rule != RuleDefaultFallback {
+			t.Errorf("expected default infrastructure fallback, got %+v", c)
+		}
+	})
+
+	t.Run("watchdog builtin", func(t *testing.T) {
+		c := classifier.Classify(&types.AlertEvent{Payload: types.AlertPayload{MonitorType: "watchdog"}})
+		if c.Rule != RuleWatchdogBuiltin || c.Confidence != 1.0 {
+			t.Errorf("expected watchdog builtin rule, got %+v", c)
+		}
+	})
+}
+
+func TestRoleClassifier_DeterministicSubstringMatches(t *testing.T) {
+	classifier := NewRoleClassifier()
+
+	// "restful-db-api" contains "rest", "db" and "api"; the result must not
+	// depend on map iteration order. Equal priority resolves by longest pattern.
+	event := &types.AlertEvent{Payload: types.AlertPayload{Service: "restful-db-api"}}
+
+	first := classifier.Classify(event)
+	for i := 0; i < 50; i++ {
+		c := classifier.Classify(event)
+		if c.Rule != first.Rule || c.Role != first.Role {
+			t.Fatalf("classification changed between runs: %+v vs %+v", first, c)
+		}
 	}
-	if len(classifier.tagRules) == 0 {
-		t.Error("tagRules should not be empty")
+	if first.Rule != "service:rest" {
+		t.Errorf("expected longest pattern service:rest to win, got %s", first.Rule)
+	}
+}
+
+func TestRoleClassifier_CustomRules(t *testing.T) {
+	rules := mergeRules(DefaultClassifierRules(), []ClassifierRule{
+		{
+			Name:     "payments-db",
+			Field:    FieldService,
+			Match:    MatchRegex,
+			Pattern:  `^payments-(pg|store)\b`,
+			Role:     RoleDatabase,
+			Priority: 250,
+		},
+		{
+			Name:     "edge-hosts",
+			Field:    FieldHostname,
+			Match:    MatchPrefix,
+			Pattern:  "EDGE-",
+			Role:     RoleNetwork,
+			Priority: 150,
+		},
+	})
+
+	classifier, err := NewRoleClassifierWithRules(rules, "test")
+	if err != nil {
+		t.Fatalf("NewRoleClassifierWithRules: %v", err)
 	}
-	if len(classifier.servicePatterns) == 0 {
-		t.Error("servicePatterns should not be empty")
+
+	tests := []struct {
+		name     string
+		payload  types.AlertPayload
+		expected AgentRole
+		rule     string
+	}{
+		{"regex beats service substring", types.AlertPayload{Service: "Payments-Store-API"}, RoleDatabase, "payments-db"},
+		{"regex not matched", types.AlertPayload{Service: "payments-api"}, RoleApplication, "service:api"},
+		{"prefix on hostname", types.AlertPayload{Hostname: "edge-app-01"}, RoleNetwork, "edge-hosts"},
 	}
-	if len(classifier.hostnamePatterns) == 0 {
-		t.Error("hostnamePatterns should not be empty")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := classifier.Classify(&types.AlertEvent{Payload: tt.payload})
+			if c.Role != tt.expected || c.Rule != tt.rule {
+				t.Errorf("expected %s via %s, got %s via %s", tt.expected, tt.rule, c.Role, c.Rule)
+			}
+		})
+	}
+}
+
+func TestRoleClassifier_InvalidRulesRejected(t *testing.T) {
+	classifier := NewRoleClassifier()
+	before := classifier.Info()
+
+	tests := []struct {
+		name string
+		rule ClassifierRule
+	}{
+		{"unknown role", ClassifierRule{Name: "bad-role", Field: FieldService, Pattern: "x", Role: "sre"}},
+		{"unknown field", ClassifierRule{Name: "bad-field", Field: "region", Pattern: "x", Role: RoleNetwork}},
+		{"bad regex", ClassifierRule{Name: "bad-regex", Field: FieldService, Match: MatchRegex, Pattern: "(", Role: RoleNetwork}},
+		{"empty pattern", ClassifierRule{Name: "empty", Field: FieldService, Role: RoleNetwork}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := classifier.SetRules([]ClassifierRule{tt.rule}, "test"); err == nil {
+				t.Error("expected validation error")
+			}
+		})
+	}
+
+	dup := []ClassifierRule{
+		{Name: "same", Field: FieldService, Pattern: "a", Role: RoleNetwork},
+		{Name: "same", Field: FieldService, Pattern: "b", Role: RoleNetwork},
+	}
+	if err := classifier.SetRules(dup, "test"); err == nil {
+		t.Error("expected duplicate name error")
+	}
+
+	if after := classifier.Info(); after.Fingerprint != before.Fingerprint {
+		t.Error("rejected rule sets must not replace the active rules")
+	}
+}
+
+func TestFileRuleSource_Reload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write rules: %v", err)
+		}
+	}
+
+	write(`
+rules:
+  - name: checkout
+    field: monitor_name
+    match: contains
+    pattern: checkout
+    role: application
+    priority: 500
+`)
+
+	classifier := NewRoleClassifier()
+	source := NewFileRuleSource(path)
+	ctx := context.Background()
+
+	changed, err := classifier.Reload(ctx, source)
+	if err != nil || !changed {
+		t.Fatalf("expected initial reload to install rules, changed=%v err=%v", changed, err)
+	}
+
+	event := &types.AlertEvent{Payload: types.AlertPayload{MonitorName: "Checkout latency", Service: "postgres"}}
+	if c := classifier.Classify(event); c.Rule != "checkout" {
+		t.Errorf("expected custom rule to win, got %s", c.Rule)
+	}
+	if c := classifier.Classify(&types.AlertEvent{Payload: types.AlertPayload{Service: "redis"}}); c.Role != RoleDatabase {
+		t.Errorf("defaults should still apply, got %s", c.Role)
+	}
+
+	// Unchanged file is a no-op
+	if changed, _ := classifier.Reload(ctx, source); changed {
+		t.Error("reload of unchanged file should report no change")
+	}
+
+	// Invalid edits keep the current rules
+	write("rules:\n  - name: broken\n    field: service\n    pattern: x\n    role: nope\n")
+	if _, err := classifier.Reload(ctx, source); err == nil {
+		t.Error("expected error for invalid rule file")
+	}
+	if c := classifier.Classify(event); c.Rule != "checkout" {
+		t.Errorf("invalid reload must keep previous rules, got %s", c.Rule)
+	}
+
+	// replace_defaults drops the built-in rules
+	write(`
+replace_defaults: true
+rules:
+  - name: only
+    field: service
+    pattern: redis
+    role: network
+`)
+	if _, err := classifier.Reload(ctx, source); err != nil {
+		t.Fatalf("reload: %v", err)
+	}
+	if n := len(classifier.Rules()); n != 1 {
+		t.Errorf("expected 1 rule after replace_defaults, got %d", n)
+	}
+	if c := classifier.Classify(&types.AlertEvent{Payload: types.AlertPayload{Service: "redis"}}); c.Role != RoleNetwork {
+		t.Errorf("expected network from replacement rule, got %s", c.Role)
+	}
+}
+
+func TestHandler_ClassifierChangesNeedOperator(t *testing.T) {
+	handler := NewHandler(NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1}), nil)
+	handler.SetOperators(operator.NewAuthenticator(map[string]string{"alice": "a-token"}))
+
+	request := func(method, path, token string) *http.Request {
+		r := httptest.NewRequest(method, path, strings.NewReader(`{"name":"db","field":"service","pattern":"postgres","role":"database"}`))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+	deleteRule := func(w http.ResponseWriter, r *http.Request) (int, any) {
+		return handler.DeleteClassifierRule(w, r, "db")
+	}
+
+	for name, h := range map[string]func(http.ResponseWriter, *http.Request) (int, any){
+		"save":   handler.SaveClassifierRule,
+		"delete": deleteRule,
+		"reload": handler.ReloadClassifier,
+	} {
+		for token, want := range map[string]int{"": http.StatusUnauthorized, "forged": http.StatusUnauthorized, "a-token": http.StatusBadRequest} {
+			// With a token the request gets past auth to the missing rule source
+			if status, _ := h(httptest.NewRecorder(), request(http.MethodPost, "/v1/agents/classifier/rules", token)); status != want {
+				t.Errorf("%s with token %q: got %d, want %d", name, token, status, want)
+			}
+		}
+	}
+
+	// Reading rules stays open
+	if status, _ := handler.GetClassifierRules(httptest.NewRecorder(), request(http.MethodGet, "/v1/agents/classifier/rules", "")); status != http.StatusOK {
+		t.Errorf("expected rules readable without a token, got %d", status)
 	}
 }
 
@@ -355,7 +616,7 @@ func TestRoleClassifier_Watchdog(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			role := classifier.Classify(tt.event)
+			role := classifier.Classify(tt.event).Role
 			if role != tt.expected {
 				t.Errorf("expected %s, got %s", tt.expected, role)
 			}
@@ -7,10 +7,12 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
 )
 
 // ClaudeAgent implements the Agent interface using the Claude AI sidecar
@@ -54,10 +56,14 @@ func (a *ClaudeAgent) Plan(ctx context.Context, event *types.AlertEvent, agentCt
 	// First iteration: delegate to Claude AI sidecar (Analyze phase calls invokeAnalysis)
 	// RLM sets Iteration to 1 before the first Plan call
 	if agentCtx.Iteration <= 1 {
+		reasoning := "Delegating to Claude AI sidecar for comprehensive analysis"
+		if agentCtx.Question != "" {
+			reasoning = "Asking Claude AI sidecar the follow-up question"
+		}
 		return AgentPlan{
 			Complete:  false,
 			Queries:   []SubQuery{}, // No sub-queries needed — sidecar call happens in Analyze()
-			Reasoning: "Delegating to Claude AI sidecar for comprehensive analysis",
+			Reasoning: reasoning,
 		}
 	}
 
@@ -70,8 +76,13 @@ func (a *ClaudeAgent) Plan(ctx context.Context, event *types.AlertEvent, agentCt
 
 // Analyze processes query results (minimal for Claude since it's single-shot)
 func (a *ClaudeAgent) Analyze(ctx context.Context, results []QueryResult, agentCtx AgentContext) AgentContext {
+	if agentCtx.Question != "" {
+		return a.answerFollowUp(ctx, agentCtx)
+	}
+
 	// For Claude, we perform the actual analysis here
-	analysis, notebookURL, err := a.invokeAnalysis(ctx, agentCtx.Event)
+	analysis, notebookURL, tokens, err := a.invokeAnalysis(ctx, agentCtx.Event, agentCtx.Findings)
+	agentCtx.Metadata["tokens_used"] = tokens
 	if err != nil {
 		agentCtx.Findings = append(agentCtx.Findings, Finding{
 			Source:    a.name,
@@ -84,6 +95,13 @@ func (a *ClaudeAgent) Analyze(ctx context.Context, results []QueryResult, agentC
 		return agentCtx
 	}
 
+	// Structured remediation proposals are lifted out of the prose
+	proposals, analysis := remediation.ParseProposals(analysis)
+	for i := range proposals {
+		proposals[i].ProposedBy = a.name
+	}
+	agentCtx.ProposedActions = append(agentCtx.ProposedActions, proposals...)
+
 	// Store the analysis result
 	agentCtx.RootCause = analysis
 	agentCtx.Findings = append(agentCtx.Findings, Finding{
@@ -103,6 +121,34 @@ func (a *ClaudeAgent) Analyze(ctx context.Context, results []QueryResult, agentC
 	return agentCtx
 }
 
+// answerFollowUp asks the sidecar a follow-up question; the root cause is kept
+func (a *ClaudeAgent) answerFollowUp(ctx context.Context, agentCtx AgentContext) AgentContext {
+	answer, tokens, err := a.invokeFollowUp(ctx, agentCtx)
+	agentCtx.Metadata["tokens_used"] = tokens
+	if err != nil {
+		agentCtx.Findings = append(agentCtx.Findings, Finding{
+			Source:    a.name,
+			Category:  "error",
+			Summary:   "Follow-up invocation failed",
+			Details:   err.Error(),
+			Severity:  "warning",
+			Timestamp: time.Now(),
+		})
+		return agentCtx
+	}
+
+	agentCtx.Answer = answer
+	agentCtx.Findings = append(agentCtx.Findings, Finding{
+		Source:    a.name,
+		Category:  "follow_up",
+		Summary:   "Q: " + truncate(agentCtx.Question, 200),
+		Details:   answer,
+		Severity:  "info",
+		Timestamp: time.Now(),
+	})
+	return agentCtx
+}
+
 // Conclude generates the final analysis result
 func (a *ClaudeAgent) Conclude(ctx context.Context, agentCtx AgentContext) *AnalysisResult {
 	event := agentCtx.Event
@@ -121,6 +167,7 @@ func (a *ClaudeAgent) Conclude(ctx context.Context, agentCtx AgentContext) *Anal
 	if url, ok := agentCtx.Metadata["notebook_url"].(string); ok {
 		notebookURL = url
 	}
+	tokensUsed, _ := agentCtx.Metadata["tokens_used"].(int64)
 
 	return &AnalysisResult{
 		MonitorID:       event.Payload.MonitorID,
@@ -133,55 +180,25 @@ func (a *ClaudeAgent) Conclude(ctx context.Context, agentCtx AgentContext) *Anal
 		Details:         agentCtx.RootCause,
 		Findings:        agentCtx.Findings,
 		Recommendations: agentCtx.Recommendations,
+		ProposedActions: agentCtx.ProposedActions,
 		NotebookURL:     notebookURL,
+		TokensUsed:      tokensUsed,
 	}
 }
 
 // invokeAnalysis calls the Claude agent sidecar.
 // Routes watchdog monitors to /watchdog endpoint, all others to /analyze.
-// Returns the analysis text, an optional notebook URL, and any error.
-func (a *ClaudeAgent) invokeAnalysis(ctx context.Context, event *types.AlertEvent) (string, string, error) {
-	payload := event.Payload
-
-	// Use fallbacks for monitor_id and monitor_name
-	monitorID := payload.MonitorID
-	if monitorID == 0 {
-		monitorID = payload.AlertID
-	}
-	monitorName := payload.MonitorName
-	if monitorName == "" {
-		monitorName = payload.AlertTitleCustom
-	}
-	if monitorName == "" {
-		monitorName = payload.AlertTitle
-	}
-
-	req := claudeRequest{
-		Payload: claudePayload{
-			MonitorID:           monitorID,
-			MonitorName:         monitorName,
-			AlertStatus:         payload.AlertStatus,
-			Hostname:            payload.Hostname,
-			Service:             payload.Service,
-			Scope:               payload.Scope,
-			Tags:                payload.Tags,
-			AlertState:          payload.AlertState,
-			AlertTitle:          payload.AlertTitleCustom,
-			ApplicationTeam:     payload.ApplicationTeam,
-			ApplicationLongname: payload.ApplicationLongname,
-			DetailedDescription: payload.DetailedDescription,
-			Impact:              payload.Impact,
-			Metric:              payload.Metric,
-			SupportGroup:        payload.SupportGroup,
-			Threshold:           payload.Threshold,
-			Value:               payload.Value,
-			Urgency:             payload.Urgency,
-		},
+// Findings gathered before the agent ran (e.g. recent deploys) are sent as context.
+// Returns the analysis text, an optional notebook URL, the tokens used and any error.
+func (a *ClaudeAgent) invokeAnalysis(ctx context.Context, event *types.AlertEvent, findings []Finding) (string, string, int64, error) {
+	req := claudeRequest{Payload: newClaudePayload(event)}
+	if len(findings) > 0 {
+		req.Context = &claudeAnalyzeContext{Findings: askFindings(findings)}
 	}
 
 	jsonBody, err := json.Marshal(req)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to marshal request: %w", err)
+		return "", "", 0, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
 	// Route watchdog monitors to the /watchdog endpoint
@@ -192,19 +209,19 @@ func (a *ClaudeAgent) invokeAnalysis(ctx context.Context, event *types.AlertEven
 
 	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.agentURL+endpoint, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return "", "", fmt.Errorf("failed to create request: %w", err)
+		return "", "", 0, fmt.Errorf("failed to create request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := httpclient.AgentClient.Do(httpReq)
 	if err != nil {
-		return "", "", fmt.Errorf("request failed: %w", err)
+		return "", "", 0, fmt.Errorf("request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var response claudeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return "", "", fmt.Errorf("failed to decode response: %w", err)
+		return "", "", 0, fmt.Errorf("failed to decode response: %w", err)
 	}
 
 	if response.Error != "" {
@@ -215,7 +232,7 @@ func (a *ClaudeAgent) invokeAnalysis(ctx context.Context, event *types.AlertEven
 		if response.RetriesExhausted {
 			errorDetail += " (retries exhausted)"
 		}
-		return "", "", fmt.Errorf("agent error: %s", errorDetail)
+		return "", "", estimateTokens(len(jsonBody)), fmt.Errorf("agent error: %s", errorDetail)
 	}
 
 	var notebookURL string
@@ -223,12 +240,32 @@ func (a *ClaudeAgent) invokeAnalysis(ctx context.Context, event *types.AlertEven
 		notebookURL = response.Notebook.URL
 	}
 
-	return response.Analysis, notebookURL, nil
+	// Prefer the sidecar's reported usage; otherwise estimate from payload sizes
+	tokens := estimateTokens(len(jsonBody) + len(response.Analysis))
+	if response.Usage != nil {
+		tokens = response.Usage.InputTokens + response.Usage.OutputTokens
+	}
+
+	return response.Analysis, notebookURL, tokens, nil
 }
 
-// InvokeRecovery calls the Claude agent sidecar's /recover endpoint
-// to update an existing notebook when a monitor recovers.
-func (a *ClaudeAgent) InvokeRecovery(ctx context.Context, event *types.AlertEvent) error {
+// askFindings maps findings to the sidecar's finding shape
+func askFindings(findings []Finding) []claudeAskFinding {
+	var mapped []claudeAskFinding
+	for _, f := range findings {
+		mapped = append(mapped, claudeAskFinding{
+			Source:   f.Source,
+			Category: f.Category,
+			Summary:  f.Summary,
+			Details:  f.Details,
+		})
+	}
+	return mapped
+}
+
+// newClaudePayload maps an alert to the sidecar payload, falling back to the
+// alert ID and titles when the monitor ID or name is missing
+func newClaudePayload(event *types.AlertEvent) claudePayload {
 	payload := event.Payload
 
 	monitorID := payload.MonitorID
@@ -243,61 +280,195 @@ func (a *ClaudeAgent) InvokeRecovery(ctx context.Context, event *types.AlertEven
 		monitorName = payload.AlertTitle
 	}
 
-	req := claudeRequest{
-		Payload: claudePayload{
-			MonitorID:           monitorID,
-			MonitorName:         monitorName,
-			AlertStatus:         payload.AlertStatus,
-			Hostname:            payload.Hostname,
-			Service:             payload.Service,
-			Scope:               payload.Scope,
-			Tags:                payload.Tags,
-			AlertState:          payload.AlertState,
-			AlertTitle:          payload.AlertTitleCustom,
-			ApplicationTeam:     payload.ApplicationTeam,
-			ApplicationLongname: payload.ApplicationLongname,
-			DetailedDescription: payload.DetailedDescription,
-			Impact:              payload.Impact,
-			Metric:              payload.Metric,
-			SupportGroup:        payload.SupportGroup,
-			Threshold:           payload.Threshold,
-			Value:               payload.Value,
-			Urgency:             payload.Urgency,
+	return claudePayload{
+		MonitorID:           monitorID,
+		MonitorName:         monitorName,
+		AlertStatus:         payload.AlertStatus,
+		Hostname:            payload.Hostname,
+		Service:             payload.Service,
+		Scope:               payload.Scope,
+		Tags:                payload.Tags,
+		AlertState:          payload.AlertState,
+		AlertTitle:          payload.AlertTitleCustom,
+		ApplicationTeam:     payload.ApplicationTeam,
+		ApplicationLongname: payload.ApplicationLongname,
+		DetailedDescription: payload.DetailedDescription,
+		Impact:              payload.Impact,
+		Metric:              payload.Metric,
+		SupportGroup:        payload.SupportGroup,
+		Threshold:           payload.Threshold,
+		Value:               payload.Value,
+		Urgency:             payload.Urgency,
+	}
+}
+
+// invokeFollowUp calls the sidecar /ask endpoint with the question and the
+// investigation so far. Returns the answer, the tokens used and any error.
+func (a *ClaudeAgent) invokeFollowUp(ctx context.Context, agentCtx AgentContext) (string, int64, error) {
+	req := claudeAskRequest{
+		Payload:  newClaudePayload(agentCtx.Event),
+		Question: agentCtx.Question,
+		Context: claudeAskContext{
+			RootCause:       agentCtx.RootCause,
+			Hypotheses:      agentCtx.Hypotheses,
+			Recommendations: agentCtx.Recommendations,
 		},
 	}
+	req.Context.Findings = askFindings(agentCtx.Findings)
+	for _, q := range agentCtx.QueryHistory {
+		req.Context.Queries = append(req.Context.Queries, claudeAskQuery{
+			AgentName: q.Query.AgentName,
+			Query:     q.Query.Query,
+			Result:    truncate(q.Result, 2000),
+		})
+	}
+	for _, turn := range agentCtx.Conversation {
+		req.Context.Conversation = append(req.Context.Conversation, claudeAskTurn{
+			Question: turn.Question,
+			Answer:   turn.Answer,
+		})
+	}
+	if url, ok := agentCtx.Metadata["notebook_url"].(string); ok {
+		req.Context.NotebookURL = url
+	}
 
 	jsonBody, err := json.Marshal(req)
 	if err != nil {
-		return fmt.Errorf("failed to marshal recovery request: %w", err)
+		return "", 0, fmt.Errorf("failed to marshal follow-up request: %w", err)
 	}
 
-	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.agentURL+"/recover", bytes.NewBuffer(jsonBody))
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.agentURL+"/ask", bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("failed to create recovery request: %w", err)
+		return "", 0, fmt.Errorf("failed to create follow-up request: %w", err)
 	}
 	httpReq.Header.Set("Content-Type", "application/json")
 
 	resp, err := httpclient.AgentClient.Do(httpReq)
 	if err != nil {
-		return fmt.Errorf("recovery request failed: %w", err)
+		return "", 0, fmt.Errorf("follow-up request failed: %w", err)
 	}
 	defer resp.Body.Close()
 
 	var response claudeResponse
 	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
-		return fmt.Errorf("failed to decode recovery response: %w", err)
+		return "", 0, fmt.Errorf("failed to decode follow-up response: %w", err)
+	}
+
+	if response.Error != "" {
+		return "", estimateTokens(len(jsonBody)), fmt.Errorf("agent error: %s", response.Error)
+	}
+
+	tokens := estimateTokens(len(jsonBody) + len(response.Analysis))
+	if response.Usage != nil {
+		tokens = response.Usage.InputTokens + response.Usage.OutputTokens
+	}
+
+	return response.Analysis, tokens, nil
+}
+
+// estimateTokens approximates token count from a byte length (~4 bytes per token)
+func estimateTokens(n int) int64 {
+	return int64(n+3) / 4
+}
+
+// Recover asks the sidecar for a short "what changed" summary of the open
+// analysis (via /ask), then calls /recover to mark its notebook resolved with
+// the time-to-resolve and summary. Without an open analysis, or when the
+// summary call fails, the deterministic describeRecovery summary is used.
+func (a *ClaudeAgent) Recover(ctx context.Context, event *types.AlertEvent, rc RecoveryContext) (*RecoveryOutcome, error) {
+	outcome := &RecoveryOutcome{Summary: describeRecovery(event, rc)}
+
+	var notebookID string
+	if rc.Analysis != nil {
+		agentCtx := rc.Analysis.Context.restore(rc.Analysis.Event)
+		agentCtx.Question = recoveryQuestion(event, rc)
+		agentCtx.Conversation = rc.Analysis.Conversation
+		if rc.Analysis.Result != nil && rc.Analysis.Result.NotebookURL != "" {
+			agentCtx.Metadata["notebook_url"] = rc.Analysis.Result.NotebookURL
+			notebookID = notebookIDFromURL(rc.Analysis.Result.NotebookURL)
+		}
+
+		answer, tokens, err := a.invokeFollowUp(ctx, agentCtx)
+		outcome.TokensUsed += tokens
+		if err == nil && strings.TrimSpace(answer) != "" {
+			outcome.Summary = strings.TrimSpace(answer)
+		}
+	}
+
+	notebookURL, err := a.invokeRecovery(ctx, event, notebookID, claudeResolution{
+		TimeToResolve: formatTimeToResolve(rc.TimeToResolve),
+		Summary:       outcome.Summary,
+	})
+	outcome.NotebookURL = notebookURL
+	return outcome, err
+}
+
+// InvokeRecovery calls the Claude agent sidecar's /recover endpoint
+// to update an existing notebook when a monitor recovers.
+func (a *ClaudeAgent) InvokeRecovery(ctx context.Context, event *types.AlertEvent) error {
+	_, err := a.invokeRecovery(ctx, event, "", claudeResolution{})
+	return err
+}
+
+// invokeRecovery posts to /recover. notebookID is the sidecar's fallback when
+// its registry has no notebook for the monitor (e.g. after a restart).
+// Returns the resolved notebook URL, if any.
+func (a *ClaudeAgent) invokeRecovery(ctx context.Context, event *types.AlertEvent, notebookID string, resolution claudeResolution) (string, error) {
+	req := claudeRecoverRequest{Payload: newClaudePayload(event)}
+	req.Payload.NotebookID = notebookID
+	if resolution.Summary != "" || resolution.TimeToResolve != "" {
+		req.Resolution = &resolution
+	}
+
+	jsonBody, err := json.Marshal(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal recovery request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", a.agentURL+"/recover", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create recovery request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpclient.AgentClient.Do(httpReq)
+	if err != nil {
+		return "", fmt.Errorf("recovery request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var response claudeRecoverResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode recovery response: %w", err)
 	}
 
 	if response.Error != "" {
-		return fmt.Errorf("recovery error: %s", response.Error)
+		return "", fmt.Errorf("recovery error: %s", response.Error)
 	}
 
-	return nil
+	if !response.NotebookUpdated {
+		return "", nil
+	}
+	return response.NotebookURL, nil
+}
+
+// formatTimeToResolve renders a duration for the notebook ("" when unknown)
+func formatTimeToResolve(d time.Duration) string {
+	if d <= 0 {
+		return ""
+	}
+	return d.String()
 }
 
 // Claude sidecar request/response types
 type claudeRequest struct {
-	Payload claudePayload `json:"payload"`
+	Payload claudePayload         `json:"payload"`
+	Context *claudeAnalyzeContext `json:"context,omitempty"`
+}
+
+// claudeAnalyzeContext carries findings gathered before the analysis
+type claudeAnalyzeContext struct {
+	Findings []claudeAskFinding `json:"findings"`
 }
 
 type claudePayload struct {
@@ -319,6 +490,61 @@ type claudePayload struct {
 	Threshold           string   `json:"THRESHOLD"`
 	Value               string   `json:"VALUE"`
 	Urgency             string   `json:"URGENCY"`
+	NotebookID          string   `json:"notebook_id,omitempty"`
+}
+
+// claudeRecoverRequest is the /recover body
+type claudeRecoverRequest struct {
+	Payload    claudePayload     `json:"payload"`
+	Resolution *claudeResolution `json:"resolution,omitempty"`
+}
+
+// claudeResolution is added to the resolved notebook
+type claudeResolution struct {
+	TimeToResolve string `json:"time_to_resolve,omitempty"`
+	Summary       string `json:"summary,omitempty"`
+}
+
+type claudeRecoverResponse struct {
+	Success         bool   `json:"success"`
+	Error           string `json:"error,omitempty"`
+	NotebookUpdated bool   `json:"notebookUpdated"`
+	NotebookURL     string `json:"notebookUrl"`
+}
+
+// claudeAskRequest is the /ask body: the alert, the question and the investigation so far
+type claudeAskRequest struct {
+	Payload  claudePayload    `json:"payload"`
+	Question string           `json:"question"`
+	Context  claudeAskContext `json:"context"`
+}
+
+type claudeAskContext struct {
+	RootCause       string             `json:"root_cause"`
+	Findings        []claudeAskFinding `json:"findings"`
+	Hypotheses      []string           `json:"hypotheses"`
+	Recommendations []string           `json:"recommendations"`
+	Queries         []claudeAskQuery   `json:"queries,omitempty"`
+	Conversation    []claudeAskTurn    `json:"conversation,omitempty"`
+	NotebookURL     string             `json:"notebook_url,omitempty"`
+}
+
+type claudeAskFinding struct {
+	Source   string `json:"source"`
+	Category string `json:"category"`
+	Summary  string `json:"summary"`
+	Details  string `json:"details"`
+}
+
+type claudeAskQuery struct {
+	AgentName string `json:"agent_name"`
+	Query     string `json:"query"`
+	Result    string `json:"result"`
+}
+
+type claudeAskTurn struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
 }
 
 type claudeResponse struct {
@@ -331,6 +557,12 @@ type claudeResponse struct {
 		URL string `json:"url"`
 	} `json:"notebook,omitempty"`
 
+	// Token usage, when the sidecar reports it
+	Usage *struct {
+		InputTokens  int64 `json:"input_tokens"`
+		OutputTokens int64 `json:"output_tokens"`
+	} `json:"usage,omitempty"`
+
 	// Failure alerting fields (populated on error responses)
 	ErrorType        string `json:"error_type,omitempty"`
 	RetriesExhausted bool   `json:"retries_exhausted,omitempty"`
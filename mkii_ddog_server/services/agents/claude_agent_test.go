@@ -0,0 +1,59 @@
+package agents
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+)
+
+// TestClaudeAgentReplaysSidecarCassette runs a full RLM analysis against a
+// recorded sidecar response, without the sidecar
+func TestClaudeAgentReplaysSidecarCassette(t *testing.T) {
+	t.Setenv("CLAUDE_AGENT_URL", "http://claude-agent:9000")
+
+	recorder, restore, err := httpclient.UseCassette(httpclient.AgentClient,
+		filepath.Join("testdata", "cassettes", "agent.json"), httpclient.CassetteReplay)
+	if err != nil {
+		t.Fatalf("UseCassette() error = %v", err)
+	}
+	defer restore()
+
+	event := &types.AlertEvent{ID: 1, Payload: types.AlertPayload{
+		MonitorID:   410001,
+		MonitorName: "Container memory near limit",
+		AlertStatus: "Alert",
+		Hostname:    "k8s-node-3",
+		Service:     "checkout-worker",
+		Scope:       "kube_deployment:checkout-worker",
+		Tags:        []string{"env:prod"},
+		Metric:      "kubernetes.memory.usage_pct",
+		Threshold:   "95",
+		Value:       "97.2",
+	}}
+
+	result, err := NewRLMCoordinator(3).Execute(context.Background(), NewClaudeAgent(RoleInfrastructure), event)
+	if err != nil {
+		t.Fatalf("Execute() error = %v", err)
+	}
+
+	if !result.Success || !strings.Contains(result.RootCause, "OOMKilled") {
+		t.Errorf("result = success %v, root cause %q", result.Success, result.RootCause)
+	}
+	if strings.Contains(result.RootCause, "```remediation") {
+		t.Error("remediation block was not lifted out of the analysis")
+	}
+	if len(result.ProposedActions) != 1 || result.ProposedActions[0].Type != remediation.ActionRestartDeployment {
+		t.Errorf("ProposedActions = %+v, want one restart_deployment", result.ProposedActions)
+	}
+	if result.NotebookURL != "https://app.datadoghq.com/notebook/1234567" || result.TokensUsed != 19114 {
+		t.Errorf("NotebookURL = %q, TokensUsed = %d", result.NotebookURL, result.TokensUsed)
+	}
+	if unused := recorder.Unused(); len(unused) != 0 {
+		t.Errorf("%d recorded interactions were not replayed", len(unused))
+	}
+}
@@ -0,0 +1,74 @@
+package agents
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// contextSourceTimeout bounds each source so a slow lookup can't delay analysis
+const contextSourceTimeout = 5 * time.Second
+
+// ContextSource adds findings to an analysis before any agent runs, e.g.
+// recent deploys to the alerting service (see services/changes). Every
+// agent starts with these findings in its AgentContext.
+type ContextSource interface {
+	Name() string
+	Findings(ctx context.Context, event *types.AlertEvent) ([]Finding, error)
+}
+
+// AddContextSource registers a source of initial findings
+func (o *AgentOrchestrator) AddContextSource(source ContextSource) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.contextSources = append(o.contextSources, source)
+	log.Printf("[AGENT-ORCH] Added context source: %s", source.Name())
+}
+
+// gatherContext collects the initial findings for an event. Source errors are
+// logged and skipped; context is a bonus, not a requirement.
+func (o *AgentOrchestrator) gatherContext(ctx context.Context, event *types.AlertEvent) []Finding {
+	o.mu.RLock()
+	sources := o.contextSources
+	o.mu.RUnlock()
+
+	progress := progressFrom(ctx)
+	var findings []Finding
+	for _, source := range sources {
+		sourceCtx, cancel := context.WithTimeout(ctx, contextSourceTimeout)
+		found, err := source.Findings(sourceCtx, event)
+		cancel()
+		if err != nil {
+			log.Printf("[AGENT-ORCH] Context source %s failed for monitor %d: %v",
+				source.Name(), event.Payload.MonitorID, err)
+			continue
+		}
+		for _, f := range found {
+			progress.emit(ProgressFinding, 0, f.Summary, map[string]any{
+				"source":   f.Source,
+				"category": f.Category,
+				"severity": f.Severity,
+			})
+		}
+		findings = append(findings, found...)
+	}
+	return findings
+}
+
+type seedKey struct{}
+
+// withSeedFindings attaches the initial findings to ctx
+func withSeedFindings(ctx context.Context, findings []Finding) context.Context {
+	if len(findings) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, seedKey{}, findings)
+}
+
+// seedFindingsFrom returns a copy of the initial findings attached to ctx
+func seedFindingsFrom(ctx context.Context) []Finding {
+	findings, _ := ctx.Value(seedKey{}).([]Finding)
+	return append([]Finding(nil), findings...)
+}
@@ -0,0 +1,68 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// stubContextSource returns canned findings or an error
+type stubContextSource struct {
+	name     string
+	findings []Finding
+	err      error
+}
+
+func (s *stubContextSource) Name() string {
+	return s.name
+}
+
+func (s *stubContextSource) Findings(ctx context.Context, event *types.AlertEvent) ([]Finding, error) {
+	return s.findings, s.err
+}
+
+func TestAgentOrchestrator_ContextSourcesSeedFindings(t *testing.T) {
+	orch := NewAgentOrchestrator(DefaultOrchestratorConfig())
+	orch.SetDefaultAgent(NewHeuristicAgent(orch.Classifier()))
+
+	deploy := Finding{Source: "changes", Category: "change", Summary: "deploy checkout v2 5m0s before the alert", Severity: "warning"}
+	orch.AddContextSource(&stubContextSource{name: "broken", err: errors.New("db down")})
+	orch.AddContextSource(&stubContextSource{name: "changes", findings: []Finding{deploy}})
+
+	event := &types.AlertEvent{Payload: types.AlertPayload{MonitorID: 9, MonitorName: "Checkout errors", AlertStatus: "Alert"}}
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if len(result.Findings) != 2 || result.Findings[0].Summary != deploy.Summary {
+		t.Fatalf("expected the seeded change first, got %+v", result.Findings)
+	}
+	if !strings.Contains(result.Details, deploy.Summary) {
+		t.Errorf("heuristic details should mention the change, got %q", result.Details)
+	}
+}
+
+func TestSynthesize_SeedFindingsOnce(t *testing.T) {
+	seed := []Finding{{Source: "changes", Category: "change", Summary: "deploy", Timestamp: time.Unix(100, 0)}}
+	own := func(summary string) []Finding {
+		return append(append([]Finding(nil), seed...), Finding{Source: "agent", Summary: summary})
+	}
+
+	outcomes := []specialistOutcome{
+		{candidate: RoleCandidate{Role: RoleDatabase}, result: &AnalysisResult{Success: true, Findings: own("pool")}},
+		{candidate: RoleCandidate{Role: RoleApplication}, result: &AnalysisResult{Success: true, Findings: own("retries")}},
+	}
+	result := synthesize(&types.AlertEvent{}, seed, outcomes, time.Now())
+
+	if len(result.Findings) != 3 {
+		t.Fatalf("expected seed + one finding per specialist, got %+v", result.Findings)
+	}
+	if result.Findings[0].AgentRole != "" || result.Findings[1].AgentRole != RoleDatabase {
+		t.Errorf("seed should be unattributed and specialists attributed, got %+v", result.Findings)
+	}
+}
@@ -0,0 +1,209 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// scriptedAgent asks every common sub-agent about the alert on the first
+// iteration and reports what they said, so the score depends only on the
+// fixtures and the classifier
+type scriptedAgent struct {
+	role agents.AgentRole
+}
+
+func (a *scriptedAgent) Name() string {
+	return "scripted-" + string(a.role)
+}
+
+func (a *scriptedAgent) Role() agents.AgentRole {
+	return a.role
+}
+
+func (a *scriptedAgent) Plan(ctx context.Context, event *types.AlertEvent, agentCtx agents.AgentContext) agents.AgentPlan {
+	if agentCtx.Iteration > 1 {
+		return agents.AgentPlan{Complete: true, Reasoning: "sub-agents answered"}
+	}
+	query := event.Payload.MonitorName + " " + event.Payload.Metric
+	var queries []agents.SubQuery
+	for i, name := range []string{"metrics", "logs", "traces", "synthetics"} {
+		queries = append(queries, agents.SubQuery{AgentName: name, Query: query, Priority: i})
+	}
+	return agents.AgentPlan{Queries: queries, Reasoning: "ask every sub-agent"}
+}
+
+func (a *scriptedAgent) Analyze(ctx context.Context, results []agents.QueryResult, agentCtx agents.AgentContext) agents.AgentContext {
+	for _, r := range results {
+		if r.Error != nil {
+			continue
+		}
+		agentCtx.Findings = append(agentCtx.Findings, agents.Finding{
+			Source:  r.Query.AgentName,
+			Summary: r.Result,
+		})
+	}
+	return agentCtx
+}
+
+func (a *scriptedAgent) Conclude(ctx context.Context, agentCtx agents.AgentContext) *agents.AnalysisResult {
+	var summaries []string
+	for _, f := range agentCtx.Findings {
+		summaries = append(summaries, f.Summary)
+	}
+	return &agents.AnalysisResult{
+		Success:   true,
+		AgentRole: a.role,
+		RootCause: strings.Join(summaries, " "),
+		Findings:  agentCtx.Findings,
+	}
+}
+
+func scriptedFactory(role agents.AgentRole) agents.Agent {
+	return &scriptedAgent{role: role}
+}
+
+func TestGoldenFixtures(t *testing.T) {
+	fixtures, err := LoadFixtures("fixtures")
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+
+	report := RunTest(t, NewRunner(), scriptedFactory, fixtures, Thresholds{
+		MinClassificationAccuracy: 1,
+		MinCategoryAccuracy:       1,
+		MinFindingCoverage:        1,
+		MaxMeanIterations:         2,
+		RequireAllPassed:          true,
+	})
+
+	if report.Summary.Cases != len(fixtures) {
+		t.Errorf("Cases = %d, want %d", report.Summary.Cases, len(fixtures))
+	}
+	if report.Summary.UnansweredQueries != 0 {
+		t.Errorf("UnansweredQueries = %d, want 0", report.Summary.UnansweredQueries)
+	}
+}
+
+func TestRunnerScoresWrongAnswers(t *testing.T) {
+	fixtures, err := LoadFixtures(filepath.Join("fixtures", "database_replication_lag.json"))
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	fixtures[0].Expected.Role = agents.RoleNetwork
+	fixtures[0].Expected.Category = "memory"
+	fixtures[0].Expected.Findings = []string{"replication", "kernel panic"}
+	fixtures[0].Expected.MaxIterations = 1
+
+	report := NewRunner().Run(context.Background(), scriptedFactory, fixtures)
+	c := report.Cases[0]
+
+	if c.Passed {
+		t.Fatal("expected case to fail")
+	}
+	if *c.ClassificationCorrect || c.ClassifiedRole != agents.RoleDatabase {
+		t.Errorf("classification = %s (correct=%v), want database (incorrect)", c.ClassifiedRole, *c.ClassificationCorrect)
+	}
+	if *c.CategoryCorrect || c.PredictedCategory != "database" {
+		t.Errorf("category = %q (correct=%v), want database (incorrect)", c.PredictedCategory, *c.CategoryCorrect)
+	}
+	if c.FindingCoverage != 0.5 || len(c.MissingFindings) != 1 || c.MissingFindings[0] != "kernel panic" {
+		t.Errorf("coverage = %v missing %v, want 0.5 missing [kernel panic]", c.FindingCoverage, c.MissingFindings)
+	}
+	if len(c.Failures) != 3 {
+		t.Errorf("Failures = %v, want classification, category and iterations", c.Failures)
+	}
+
+	err = report.Check(Thresholds{MinClassificationAccuracy: 1, MinFindingCoverage: 0.9})
+	if err == nil || !strings.Contains(err.Error(), "classification accuracy") || !strings.Contains(err.Error(), "finding coverage") {
+		t.Errorf("Check() error = %v, want classification and coverage violations", err)
+	}
+}
+
+func TestRunnerRecordsUnansweredQueries(t *testing.T) {
+	fixture := Fixture{
+		Name:    "unanswered",
+		Payload: types.AlertPayload{MonitorName: "CPU high", MonitorType: "metric alert"},
+		SubAgents: map[string][]CannedResponse{
+			"metrics": {{Match: "memory", Response: "memory is fine"}},
+			"logs":    {{Error: "log index unavailable"}},
+		},
+		Expected: Expectation{Role: agents.RoleInfrastructure},
+	}
+
+	report := NewRunner().Run(context.Background(), scriptedFactory, []Fixture{fixture})
+	c := report.Cases[0]
+
+	if len(c.UnansweredQueries) != 1 || c.UnansweredQueries[0] != "CPU high " {
+		t.Errorf("UnansweredQueries = %q, want the metrics query", c.UnansweredQueries)
+	}
+	if !c.Passed {
+		t.Errorf("Failures = %v, want pass (only the role is expected)", c.Failures)
+	}
+}
+
+func TestLoadFixturesValidation(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("a.json", `[{"name": "one", "payload": {"monitor_name": "m"}, "expected": {"role": "logs"}}]`)
+	write("b.json", `{"name": "two", "payload": {"monitor_name": "m"}, "expected": {"category": "disk"}}`)
+	fixtures, err := LoadFixtures(dir)
+	if err != nil {
+		t.Fatalf("LoadFixtures() error = %v", err)
+	}
+	if len(fixtures) != 2 || fixtures[0].Name != "one" || fixtures[1].Source != filepath.Join(dir, "b.json") {
+		t.Errorf("fixtures = %+v", fixtures)
+	}
+
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"duplicate", `{"name": "one", "payload": {"monitor_name": "m"}, "expected": {"role": "logs"}}`, "duplicate fixture"},
+		{"no expectation", `{"name": "three", "payload": {"monitor_name": "m"}}`, "nothing to score"},
+		{"unknown category", `{"name": "three", "payload": {"monitor_name": "m"}, "expected": {"category": "gremlins"}}`, "unknown category"},
+		{"no payload", `{"name": "three", "expected": {"role": "logs"}}`, "monitor_name or alert_title"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			write("c.json", tt.body)
+			defer os.Remove(filepath.Join(dir, "c.json"))
+
+			_, err := LoadFixtures(dir)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("LoadFixtures() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCategorize(t *testing.T) {
+	result := &agents.AnalysisResult{
+		RootCause: "Deadlock between two transactions exhausted the connection pool",
+		Summary:   "Database slow query",
+	}
+	if got := Categorize(result, nil); got != "database" {
+		t.Errorf("Categorize() = %q, want database", got)
+	}
+
+	result = &agents.AnalysisResult{RootCause: "Lock wait timeout on the orders table"}
+	if got := Categorize(result, nil); got != "" {
+		t.Errorf("Categorize(no match) = %q, want empty", got)
+	}
+	override := &Category{Name: "locking", Keywords: []string{"lock wait"}}
+	if got := Categorize(result, override); got != "locking" {
+		t.Errorf("Categorize(override) = %q, want locking", got)
+	}
+}
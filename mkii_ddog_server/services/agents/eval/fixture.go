@@ -0,0 +1,151 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// Fixture is a recorded alert with canned sub-agent responses and the
+// expected outcome. Fixtures are JSON so payloads can be pasted straight from
+// /v1/webhooks/events; a file may hold one fixture or an array of them.
+type Fixture struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	AccountName string             `json:"account_name,omitempty"`
+	Payload     types.AlertPayload `json:"payload"`
+
+	// SubAgents maps sub-agent name to its canned responses
+	SubAgents map[string][]CannedResponse `json:"sub_agents,omitempty"`
+
+	Expected Expectation `json:"expected"`
+
+	// Source is the file the fixture was loaded from
+	Source string `json:"-"`
+}
+
+// CannedResponse answers sub-agent queries containing Match (case-insensitive;
+// empty matches any query). The first matching response wins.
+type CannedResponse struct {
+	Match    string `json:"match,omitempty"`
+	Response string `json:"response"`
+	Error    string `json:"error,omitempty"`
+	DelayMS  int    `json:"delay_ms,omitempty"`
+}
+
+// Expectation is what a good analysis of the fixture looks like
+type Expectation struct {
+	// Role the classifier should route the alert to
+	Role agents.AgentRole `json:"role,omitempty"`
+
+	// Category is the root-cause category (see Categories); CategoryKeywords
+	// overrides the built-in keywords for it
+	Category         string   `json:"category,omitempty"`
+	CategoryKeywords []string `json:"category_keywords,omitempty"`
+
+	// Findings are keywords that should appear in the findings or root cause
+	Findings []string `json:"findings,omitempty"`
+
+	// MaxIterations fails the case when the agent needs more RLM iterations
+	MaxIterations int `json:"max_iterations,omitempty"`
+}
+
+// Event builds the alert event replayed through the agent
+func (f Fixture) Event(id int64) *types.AlertEvent {
+	return &types.AlertEvent{
+		ID:          id,
+		AccountName: f.AccountName,
+		Payload:     f.Payload,
+		Status:      "pending",
+	}
+}
+
+// Validate checks the fixture is usable
+func (f Fixture) Validate() error {
+	if f.Name == "" {
+		return fmt.Errorf("fixture has no name")
+	}
+	if f.Payload.MonitorName == "" && f.Payload.AlertTitle == "" {
+		return fmt.Errorf("fixture %s: payload needs monitor_name or alert_title", f.Name)
+	}
+	if f.Expected.Role == "" && f.Expected.Category == "" && len(f.Expected.Findings) == 0 {
+		return fmt.Errorf("fixture %s: expected has nothing to score", f.Name)
+	}
+	if f.Expected.Category != "" && len(f.Expected.CategoryKeywords) == 0 {
+		if _, ok := categoryKeywords(f.Expected.Category); !ok {
+			return fmt.Errorf("fixture %s: unknown category %q (add category_keywords)", f.Name, f.Expected.Category)
+		}
+	}
+	return nil
+}
+
+// LoadFixtures reads fixtures from a JSON file or every *.json file in a
+// directory, sorted by file name
+func LoadFixtures(path string) ([]Fixture, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("read fixtures: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		if files, err = filepath.Glob(filepath.Join(path, "*.json")); err != nil {
+			return nil, fmt.Errorf("list fixtures: %w", err)
+		}
+		sort.Strings(files)
+	}
+
+	var fixtures []Fixture
+	names := make(map[string]string)
+	for _, file := range files {
+		loaded, err := loadFixtureFile(file)
+		if err != nil {
+			return nil, err
+		}
+		for _, f := range loaded {
+			if err := f.Validate(); err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if other, dup := names[f.Name]; dup {
+				return nil, fmt.Errorf("%s: duplicate fixture %q (also in %s)", file, f.Name, other)
+			}
+			names[f.Name] = file
+			fixtures = append(fixtures, f)
+		}
+	}
+	if len(fixtures) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", path)
+	}
+	return fixtures, nil
+}
+
+// loadFixtureFile decodes a single fixture or an array
+func loadFixtureFile(file string) ([]Fixture, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read fixture %s: %w", file, err)
+	}
+
+	var fixtures []Fixture
+	if strings.HasPrefix(strings.TrimSpace(string(data)), "[") {
+		err = json.Unmarshal(data, &fixtures)
+	} else {
+		var single Fixture
+		err = json.Unmarshal(data, &single)
+		fixtures = []Fixture{single}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decode fixture %s: %w", file, err)
+	}
+
+	for i := range fixtures {
+		fixtures[i].Source = file
+	}
+	return fixtures, nil
+}
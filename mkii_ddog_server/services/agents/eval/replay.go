@@ -0,0 +1,76 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReplaySubAgent answers queries from canned responses so analyses can be
+// replayed offline. Queries without a matching response fail and are
+// reported as unanswered.
+type ReplaySubAgent struct {
+	name      string
+	responses []CannedResponse
+
+	mu         sync.Mutex
+	queries    []string
+	unanswered []string
+}
+
+// NewReplaySubAgent creates a replay sub-agent
+func NewReplaySubAgent(name string, responses []CannedResponse) *ReplaySubAgent {
+	return &ReplaySubAgent{name: name, responses: responses}
+}
+
+// Name returns the sub-agent name
+func (s *ReplaySubAgent) Name() string {
+	return s.name
+}
+
+// Query returns the first canned response matching the query
+func (s *ReplaySubAgent) Query(ctx context.Context, query string) (string, error) {
+	s.mu.Lock()
+	s.queries = append(s.queries, query)
+	s.mu.Unlock()
+
+	lower := strings.ToLower(query)
+	for _, r := range s.responses {
+		if r.Match != "" && !strings.Contains(lower, strings.ToLower(r.Match)) {
+			continue
+		}
+		if r.DelayMS > 0 {
+			select {
+			case <-time.After(time.Duration(r.DelayMS) * time.Millisecond):
+			case <-ctx.Done():
+				return "", ctx.Err()
+			}
+		}
+		if r.Error != "" {
+			return "", errors.New(r.Error)
+		}
+		return r.Response, nil
+	}
+
+	s.mu.Lock()
+	s.unanswered = append(s.unanswered, query)
+	s.mu.Unlock()
+	return "", fmt.Errorf("no canned %s response for query %q", s.name, query)
+}
+
+// Queries returns every query received
+func (s *ReplaySubAgent) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.queries...)
+}
+
+// Unanswered returns queries that had no canned response
+func (s *ReplaySubAgent) Unanswered() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.unanswered...)
+}
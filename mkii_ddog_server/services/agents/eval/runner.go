@@ -0,0 +1,333 @@
+package eval
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// AgentFactory returns the agent to evaluate for the role the classifier
+// picked. Return the same agent for every role to evaluate a single agent.
+type AgentFactory func(role agents.AgentRole) agents.Agent
+
+// Runner replays fixtures through agents and scores the results
+type Runner struct {
+	// Classifier routes fixtures; nil uses the default rules
+	Classifier *agents.RoleClassifier
+	// MaxIterations caps the RLM loop (default 5)
+	MaxIterations int
+	// Timeout bounds each case (default 2m)
+	Timeout time.Duration
+}
+
+// NewRunner creates a runner with the default classifier
+func NewRunner() *Runner {
+	return &Runner{Classifier: agents.NewRoleClassifier()}
+}
+
+// CaseResult is the score for one fixture
+type CaseResult struct {
+	Name   string `json:"name"`
+	Source string `json:"source,omitempty"`
+	Agent  string `json:"agent"`
+
+	ExpectedRole          agents.AgentRole `json:"expected_role,omitempty"`
+	ClassifiedRole        agents.AgentRole `json:"classified_role"`
+	ClassificationRule    string           `json:"classification_rule,omitempty"`
+	ClassificationCorrect *bool            `json:"classification_correct,omitempty"`
+
+	ExpectedCategory  string `json:"expected_category,omitempty"`
+	PredictedCategory string `json:"predicted_category,omitempty"`
+	CategoryCorrect   *bool  `json:"category_correct,omitempty"`
+
+	FindingCoverage   float64  `json:"finding_coverage"`
+	MissingFindings   []string `json:"missing_findings,omitempty"`
+	UnansweredQueries []string `json:"unanswered_queries,omitempty"`
+
+	Iterations int           `json:"iterations"`
+	Latency    time.Duration `json:"latency"`
+	RootCause  string        `json:"root_cause,omitempty"`
+	Error      string        `json:"error,omitempty"`
+
+	Passed   bool     `json:"passed"`
+	Failures []string `json:"failures,omitempty"`
+}
+
+// Summary aggregates case results. Accuracies only count cases that set the
+// corresponding expectation.
+type Summary struct {
+	Cases                  int           `json:"cases"`
+	Passed                 int           `json:"passed"`
+	Failed                 int           `json:"failed"`
+	Errors                 int           `json:"errors"`
+	ClassificationAccuracy float64       `json:"classification_accuracy"`
+	CategoryAccuracy       float64       `json:"category_accuracy"`
+	MeanFindingCoverage    float64       `json:"mean_finding_coverage"`
+	MeanIterations         float64       `json:"mean_iterations"`
+	LatencyP50             time.Duration `json:"latency_p50"`
+	LatencyP95             time.Duration `json:"latency_p95"`
+	UnansweredQueries      int           `json:"unanswered_queries"`
+}
+
+// Report is the outcome of an evaluation run
+type Report struct {
+	Cases     []CaseResult  `json:"cases"`
+	Summary   Summary       `json:"summary"`
+	StartedAt time.Time     `json:"started_at"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// Thresholds are the minimum scores a run must reach; zero values are not checked
+type Thresholds struct {
+	MinClassificationAccuracy float64
+	MinCategoryAccuracy       float64
+	MinFindingCoverage        float64
+	MaxMeanIterations         float64
+	MaxLatencyP95             time.Duration
+	RequireAllPassed          bool
+}
+
+// Run replays every fixture and scores the results. Cases run sequentially
+// so latencies are comparable between runs.
+func (r *Runner) Run(ctx context.Context, factory AgentFactory, fixtures []Fixture) *Report {
+	report := &Report{StartedAt: time.Now()}
+	for i, fixture := range fixtures {
+		if ctx.Err() != nil {
+			break
+		}
+		report.Cases = append(report.Cases, r.runCase(ctx, factory, fixture, int64(i+1)))
+	}
+	report.Duration = time.Since(report.StartedAt)
+	report.Summary = summarize(report.Cases)
+	return report
+}
+
+// runCase replays one fixture
+func (r *Runner) runCase(ctx context.Context, factory AgentFactory, fixture Fixture, id int64) CaseResult {
+	classifier := r.Classifier
+	if classifier == nil {
+		classifier = agents.NewRoleClassifier()
+	}
+	timeout := r.Timeout
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+
+	event := fixture.Event(id)
+	classification := classifier.Classify(event)
+
+	cr := CaseResult{
+		Name:               fixture.Name,
+		Source:             fixture.Source,
+		ExpectedRole:       fixture.Expected.Role,
+		ClassifiedRole:     classification.Role,
+		ClassificationRule: classification.Rule,
+		ExpectedCategory:   fixture.Expected.Category,
+	}
+	if fixture.Expected.Role != "" {
+		cr.ClassificationCorrect = boolPtr(classification.Role == fixture.Expected.Role)
+	}
+
+	agent := factory(classification.Role)
+	if agent == nil {
+		cr.Error = fmt.Sprintf("no agent for role %s", classification.Role)
+		cr.Failures = append(cr.Failures, cr.Error)
+		return cr
+	}
+	cr.Agent = agent.Name()
+
+	coordinator := agents.NewRLMCoordinator(r.MaxIterations)
+	replays := make([]*ReplaySubAgent, 0, len(fixture.SubAgents))
+	for name, responses := range fixture.SubAgents {
+		replay := NewReplaySubAgent(name, responses)
+		replays = append(replays, replay)
+		coordinator.RegisterSubAgent(replay)
+	}
+
+	caseCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := coordinator.Execute(caseCtx, agent, event)
+	cr.Latency = time.Since(start)
+
+	for _, replay := range replays {
+		cr.UnansweredQueries = append(cr.UnansweredQueries, replay.Unanswered()...)
+	}
+	sort.Strings(cr.UnansweredQueries)
+
+	if err != nil {
+		cr.Error = err.Error()
+	} else if result != nil && result.Error != "" {
+		cr.Error = result.Error
+	}
+	if result != nil {
+		cr.Iterations = result.Iterations
+		cr.RootCause = result.RootCause
+	}
+
+	cr.FindingCoverage, cr.MissingFindings = findingCoverage(result, fixture.Expected.Findings)
+
+	if fixture.Expected.Category != "" {
+		var override *Category
+		if len(fixture.Expected.CategoryKeywords) > 0 {
+			override = &Category{Name: fixture.Expected.Category, Keywords: fixture.Expected.CategoryKeywords}
+		}
+		cr.PredictedCategory = Categorize(result, override)
+		cr.CategoryCorrect = boolPtr(cr.PredictedCategory == fixture.Expected.Category)
+	}
+
+	if cr.Error != "" {
+		cr.Failures = append(cr.Failures, "error: "+cr.Error)
+	}
+	if cr.ClassificationCorrect != nil && !*cr.ClassificationCorrect {
+		cr.Failures = append(cr.Failures, fmt.Sprintf("classified as %s, want %s", cr.ClassifiedRole, cr.ExpectedRole))
+	}
+	if cr.CategoryCorrect != nil && !*cr.CategoryCorrect {
+		cr.Failures = append(cr.Failures, fmt.Sprintf("root cause category %q, want %q", cr.PredictedCategory, cr.ExpectedCategory))
+	}
+	if max := fixture.Expected.MaxIterations; max > 0 && cr.Iterations > max {
+		cr.Failures = append(cr.Failures, fmt.Sprintf("%d iterations, want <= %d", cr.Iterations, max))
+	}
+	cr.Passed = len(cr.Failures) == 0
+	return cr
+}
+
+// summarize aggregates case results
+func summarize(cases []CaseResult) Summary {
+	s := Summary{Cases: len(cases)}
+	if len(cases) == 0 {
+		return s
+	}
+
+	var classified, classifiedOK, categorized, categorizedOK int
+	var coverage, iterations float64
+	latencies := make([]time.Duration, 0, len(cases))
+
+	for _, c := range cases {
+		if c.Passed {
+			s.Passed++
+		} else {
+			s.Failed++
+		}
+		if c.Error != "" {
+			s.Errors++
+		}
+		if c.ClassificationCorrect != nil {
+			classified++
+			if *c.ClassificationCorrect {
+				classifiedOK++
+			}
+		}
+		if c.CategoryCorrect != nil {
+			categorized++
+			if *c.CategoryCorrect {
+				categorizedOK++
+			}
+		}
+		coverage += c.FindingCoverage
+		iterations += float64(c.Iterations)
+		latencies = append(latencies, c.Latency)
+		s.UnansweredQueries += len(c.UnansweredQueries)
+	}
+
+	s.ClassificationAccuracy = ratio(classifiedOK, classified)
+	s.CategoryAccuracy = ratio(categorizedOK, categorized)
+	s.MeanFindingCoverage = coverage / float64(len(cases))
+	s.MeanIterations = iterations / float64(len(cases))
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.LatencyP50 = percentile(latencies, 0.50)
+	s.LatencyP95 = percentile(latencies, 0.95)
+	return s
+}
+
+// Check returns an error describing every threshold the report misses
+func (rep *Report) Check(t Thresholds) error {
+	s := rep.Summary
+	var violations []string
+	if t.MinClassificationAccuracy > 0 && s.ClassificationAccuracy < t.MinClassificationAccuracy {
+		violations = append(violations, fmt.Sprintf("classification accuracy %.2f < %.2f", s.ClassificationAccuracy, t.MinClassificationAccuracy))
+	}
+	if t.MinCategoryAccuracy > 0 && s.CategoryAccuracy < t.MinCategoryAccuracy {
+		violations = append(violations, fmt.Sprintf("category accuracy %.2f < %.2f", s.CategoryAccuracy, t.MinCategoryAccuracy))
+	}
+	if t.MinFindingCoverage > 0 && s.MeanFindingCoverage < t.MinFindingCoverage {
+		violations = append(violations, fmt.Sprintf("finding coverage %.2f < %.2f", s.MeanFindingCoverage, t.MinFindingCoverage))
+	}
+	if t.MaxMeanIterations > 0 && s.MeanIterations > t.MaxMeanIterations {
+		violations = append(violations, fmt.Sprintf("mean iterations %.2f > %.2f", s.MeanIterations, t.MaxMeanIterations))
+	}
+	if t.MaxLatencyP95 > 0 && s.LatencyP95 > t.MaxLatencyP95 {
+		violations = append(violations, fmt.Sprintf("p95 latency %s > %s", s.LatencyP95, t.MaxLatencyP95))
+	}
+	if t.RequireAllPassed && s.Failed > 0 {
+		violations = append(violations, fmt.Sprintf("%d of %d cases failed", s.Failed, s.Cases))
+	}
+	if len(violations) > 0 {
+		return fmt.Errorf("evaluation below thresholds: %s", strings.Join(violations, "; "))
+	}
+	return nil
+}
+
+// WriteText writes a human-readable table of the report
+func (rep *Report) WriteText(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "CASE\tAGENT\tROLE\tCATEGORY\tCOVERAGE\tITER\tLATENCY\tRESULT")
+	for _, c := range rep.Cases {
+		role := string(c.ClassifiedRole)
+		if c.ClassificationCorrect != nil && !*c.ClassificationCorrect {
+			role += " (want " + string(c.ExpectedRole) + ")"
+		}
+		category := c.PredictedCategory
+		if c.CategoryCorrect != nil && !*c.CategoryCorrect {
+			category = fmt.Sprintf("%q (want %s)", c.PredictedCategory, c.ExpectedCategory)
+		}
+		result := "PASS"
+		if !c.Passed {
+			result = "FAIL: " + strings.Join(c.Failures, "; ")
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%.0f%%\t%d\t%s\t%s\n", c.Name, c.Agent, role, category,
+			c.FindingCoverage*100, c.Iterations, c.Latency.Round(time.Millisecond), result)
+	}
+	tw.Flush()
+
+	s := rep.Summary
+	fmt.Fprintf(w, "\n%d cases: %d passed, %d failed (%d errors)\n", s.Cases, s.Passed, s.Failed, s.Errors)
+	fmt.Fprintf(w, "classification accuracy %.0f%%, category accuracy %.0f%%, finding coverage %.0f%%\n",
+		s.ClassificationAccuracy*100, s.CategoryAccuracy*100, s.MeanFindingCoverage*100)
+	fmt.Fprintf(w, "mean iterations %.1f, latency p50 %s p95 %s, unanswered sub-agent queries %d\n",
+		s.MeanIterations, s.LatencyP50.Round(time.Millisecond), s.LatencyP95.Round(time.Millisecond), s.UnansweredQueries)
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func ratio(n, d int) float64 {
+	if d == 0 {
+		return 0
+	}
+	return float64(n) / float64(d)
+}
+
+// percentile returns the nearest-rank percentile of sorted durations
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted))*p+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,100 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// Category is a root-cause category with the keywords that identify it
+type Category struct {
+	Name     string
+	Keywords []string
+}
+
+// Categories is the built-in root-cause taxonomy, in tie-break order
+var Categories = []Category{
+	{Name: "memory", Keywords: []string{"memory", "oom", "heap", "leak", "garbage collection"}},
+	{Name: "cpu", Keywords: []string{"cpu", "throttl", "load average", "hot loop"}},
+	{Name: "disk", Keywords: []string{"disk", "inode", "filesystem", "volume full", "iops"}},
+	{Name: "database", Keywords: []string{"database", "slow query", "deadlock", "replication", "connection pool", "lock contention", "postgres", "mysql"}},
+	{Name: "network", Keywords: []string{"network", "dns", "packet loss", "tls", "certificate", "load balancer", "connection refused"}},
+	{Name: "deployment", Keywords: []string{"deploy", "release", "rollout", "rollback", "config change", "feature flag"}},
+	{Name: "dependency", Keywords: []string{"upstream", "downstream", "third-party", "dependency", "external api"}},
+	{Name: "traffic", Keywords: []string{"traffic", "spike", "surge", "rate limit", "request volume"}},
+	{Name: "application", Keywords: []string{"exception", "panic", "null pointer", "stack trace", "error rate", "bug"}},
+}
+
+// categoryKeywords returns the built-in keywords for a category
+func categoryKeywords(name string) ([]string, bool) {
+	for _, c := range Categories {
+		if c.Name == name {
+			return c.Keywords, true
+		}
+	}
+	return nil, false
+}
+
+// Categorize picks the category whose keywords occur most often in the
+// result's root cause and summary. override replaces the keywords of one
+// category (a fixture's category_keywords). Returns "" when nothing matches.
+func Categorize(result *agents.AnalysisResult, override *Category) string {
+	if result == nil {
+		return ""
+	}
+	text := strings.ToLower(result.RootCause + "\n" + result.Summary)
+
+	categories := Categories
+	if override != nil {
+		categories = make([]Category, 0, len(Categories)+1)
+		replaced := false
+		for _, c := range Categories {
+			if c.Name == override.Name {
+				c = *override
+				replaced = true
+			}
+			categories = append(categories, c)
+		}
+		if !replaced {
+			categories = append(categories, *override)
+		}
+	}
+
+	best, bestHits := "", 0
+	for _, c := range categories {
+		hits := 0
+		for _, kw := range c.Keywords {
+			hits += strings.Count(text, strings.ToLower(kw))
+		}
+		if hits > bestHits {
+			best, bestHits = c.Name, hits
+		}
+	}
+	return best
+}
+
+// findingCoverage returns the fraction of expected keywords found in the
+// result's findings or root cause, and the keywords that were missing
+func findingCoverage(result *agents.AnalysisResult, expected []string) (float64, []string) {
+	if len(expected) == 0 {
+		return 1, nil
+	}
+	if result == nil {
+		return 0, append([]string(nil), expected...)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(result.RootCause)
+	for _, f := range result.Findings {
+		sb.WriteString("\n" + f.Summary + "\n" + f.Details)
+	}
+	text := strings.ToLower(sb.String())
+
+	var missing []string
+	for _, kw := range expected {
+		if !strings.Contains(text, strings.ToLower(kw)) {
+			missing = append(missing, kw)
+		}
+	}
+	return float64(len(expected)-len(missing)) / float64(len(expected)), missing
+}
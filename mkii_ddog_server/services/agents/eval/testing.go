@@ -0,0 +1,30 @@
+package eval
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// RunTest replays fixtures as part of a Go test: failing cases are logged and
+// the test fails when the report misses the thresholds.
+//
+//	fixtures, _ := eval.LoadFixtures("../eval/fixtures")
+//	eval.RunTest(t, eval.NewRunner(), func(agents.AgentRole) agents.Agent { return myAgent }, fixtures,
+//		eval.Thresholds{MinClassificationAccuracy: 1})
+func RunTest(t testing.TB, runner *Runner, factory AgentFactory, fixtures []Fixture, thresholds Thresholds) *Report {
+	t.Helper()
+
+	report := runner.Run(context.Background(), factory, fixtures)
+	for _, c := range report.Cases {
+		if !c.Passed {
+			t.Logf("%s: %s", c.Name, strings.Join(c.Failures, "; "))
+		}
+	}
+	if err := report.Check(thresholds); err != nil {
+		var sb strings.Builder
+		report.WriteText(&sb)
+		t.Errorf("%v\n%s", err, sb.String())
+	}
+	return report
+}
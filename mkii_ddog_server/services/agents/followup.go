@@ -0,0 +1,192 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/google/uuid"
+)
+
+// FollowUpPriority ranks a follow-up question (an engineer is waiting on the
+// answer) above alert analyses but below recoveries
+const FollowUpPriority = 500
+
+// ErrAnalysisStoreDisabled is returned by Ask when no AnalysisStore is configured
+var ErrAnalysisStoreDisabled = errors.New("analysis storage is not configured")
+
+// FollowUpDeniedError reports a follow-up refused by the token budget or circuit breaker
+type FollowUpDeniedError struct {
+	Reason SkipReason
+	Detail string
+}
+
+func (e *FollowUpDeniedError) Error() string {
+	return fmt.Sprintf("follow-up denied (%s): %s", e.Reason, e.Detail)
+}
+
+// saveAnalysis stores a completed analysis with its captured agent context.
+// Storage failures are logged; they never fail the analysis itself.
+func (o *AgentOrchestrator) saveAnalysis(ctx context.Context, analysisID string, event *types.AlertEvent, result *AnalysisResult, capture *contextCapture) {
+	store := o.AnalysisStore()
+	if store == nil {
+		return
+	}
+
+	captured, ok := capture.merged(result.AgentRole)
+	if !ok {
+		return // no agent ran (e.g. no agent for the role)
+	}
+
+	now := time.Now()
+	record := &AnalysisRecord{
+		AnalysisID:  analysisID,
+		EventID:     event.ID,
+		MonitorID:   event.Payload.MonitorID,
+		MonitorName: event.Payload.MonitorName,
+		AccountName: event.AccountName,
+		Scope:       event.Payload.Scope,
+		Agent:       captured.agent,
+		AgentRole:   captured.role,
+		Event:       event,
+		Result:      result,
+		Context:     snapshotAgentContext(captured.agentCtx),
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	if err := store.SaveAnalysis(context.WithoutCancel(ctx), record); err != nil {
+		log.Printf("[AGENT-ORCH] Failed to store analysis %s for monitor %d: %v", analysisID, event.Payload.MonitorID, err)
+	}
+}
+
+// Ask resumes a stored analysis to answer a follow-up question. The agent
+// restarts the RLM loop with its previous findings, query history and
+// hypotheses, plus the question and earlier turns, so it can run more
+// sub-queries before answering. The turn is appended to the stored record.
+//
+// Follow-ups are charged to the daily token budgets and refused while the
+// sidecar circuit is open, unless the agent is a LocalAgent. The returned turn
+// is non-nil once the agent ran, even when the run failed.
+func (o *AgentOrchestrator) Ask(ctx context.Context, analysisID, question, askedBy string) (*ConversationTurn, error) {
+	store := o.AnalysisStore()
+	if store == nil {
+		return nil, ErrAnalysisStoreDisabled
+	}
+
+	record, err := store.GetAnalysis(ctx, analysisID)
+	if err != nil {
+		return nil, err
+	}
+	event := record.Event
+
+	if ok, reason, detail := o.budget.AdmitFollowUp(record.AccountName); !ok {
+		return nil, &FollowUpDeniedError{Reason: reason, Detail: detail}
+	}
+
+	agent := o.followUpAgent(record)
+	if agent == nil {
+		return nil, fmt.Errorf("no agent available for role: %s", record.AgentRole)
+	}
+	// Local agents answer without the sidecar and don't touch the circuit
+	local := isLocal(agent)
+	if !local && !o.circuit.Allow() {
+		return nil, &FollowUpDeniedError{Reason: SkipCircuitOpen, Detail: "agent sidecar circuit is open"}
+	}
+
+	release, err := o.scheduler.Acquire(ctx, FollowUpPriority, monitorLabel(event)+"/ask")
+	if err != nil {
+		if !local {
+			o.circuit.Release()
+		}
+		return nil, err
+	}
+	defer release()
+
+	atomic.AddInt64(&o.activeCount, 1)
+	defer atomic.AddInt64(&o.activeCount, -1)
+	atomic.AddInt64(&o.totalFollowUps, 1)
+
+	log.Printf("[AGENT-ORCH] Follow-up on analysis %s (monitor %d) with %s: %s",
+		analysisID, event.Payload.MonitorID, agent.Name(), truncate(question, 100))
+
+	agentCtx := record.Context.restore(event)
+	agentCtx.Question = question
+	agentCtx.Conversation = record.Conversation
+	delete(agentCtx.Metadata, "tokens_used") // per-run usage, set again by the agent
+	seenFindings := len(agentCtx.Findings)
+	seenQueries := len(agentCtx.QueryHistory)
+
+	askedAt := time.Now()
+	result, final, runErr := o.rlmCoordinator.Resume(ctx, agent, agentCtx)
+
+	answer := final.Answer
+	if answer == "" && runErr == nil && result != nil && result.Success {
+		// Agents without follow-up support answer with their conclusion
+		answer = result.Summary
+	}
+
+	turn := ConversationTurn{
+		ID:       uuid.NewString(),
+		Question: question,
+		Answer:   answer,
+		AskedBy:  askedBy,
+		Agent:    agent.Name(),
+		Success:  runErr == nil && answer != "",
+		Findings: final.Findings[min(seenFindings, len(final.Findings)):],
+		Queries:  len(final.QueryHistory) - seenQueries,
+		Duration: time.Since(askedAt),
+		AskedAt:  askedAt,
+	}
+	if result != nil {
+		turn.TokensUsed = result.TokensUsed
+		if !turn.Success && result.Error != "" {
+			turn.Error = result.Error
+		}
+	}
+	if runErr != nil {
+		turn.Error = runErr.Error()
+	}
+
+	o.budget.RecordTokens(record.AccountName, turn.TokensUsed)
+	switch {
+	case local:
+	case turn.Success:
+		o.circuit.RecordSuccess()
+	case errors.Is(runErr, context.Canceled):
+		o.circuit.Release()
+	default:
+		o.circuit.RecordFailure()
+	}
+
+	if err := store.AppendTurn(context.WithoutCancel(ctx), analysisID, turn, snapshotAgentContext(final)); err != nil {
+		log.Printf("[AGENT-ORCH] Failed to store follow-up on analysis %s: %v", analysisID, err)
+	}
+
+	log.Printf("[AGENT-ORCH] Follow-up on analysis %s answered: success=%v, queries=%d, duration=%v",
+		analysisID, turn.Success, turn.Queries, turn.Duration)
+
+	return &turn, runErr
+}
+
+// followUpAgent returns the agent that produced the record, falling back to
+// the agent for its role
+func (o *AgentOrchestrator) followUpAgent(record *AnalysisRecord) Agent {
+	o.mu.RLock()
+	candidates := []Agent{o.defaultAgent}
+	for _, agent := range o.agents {
+		candidates = append(candidates, agent)
+	}
+	o.mu.RUnlock()
+
+	for _, agent := range candidates {
+		if agent != nil && agent.Name() == record.Agent {
+			return agent
+		}
+	}
+	return o.getAgent(record.AgentRole)
+}
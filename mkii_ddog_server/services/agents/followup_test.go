@@ -0,0 +1,143 @@
+package agents
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// conversationalAgent queries a sub-agent on the first iteration and answers
+// follow-up questions from the accumulated findings
+type conversationalAgent struct {
+	name string
+}
+
+func (a *conversationalAgent) Name() string    { return a.name }
+func (a *conversationalAgent) Role() AgentRole { return RoleDatabase }
+
+func (a *conversationalAgent) Plan(ctx context.Context, event *types.AlertEvent, agentCtx AgentContext) AgentPlan {
+	if agentCtx.Iteration > 1 {
+		return AgentPlan{Complete: true, Reasoning: "done"}
+	}
+	query := "connections"
+	if agentCtx.Question != "" {
+		query = "replication lag"
+	}
+	return AgentPlan{Queries: []SubQuery{{AgentName: "db", Query: query}}, Reasoning: "query db"}
+}
+
+func (a *conversationalAgent) Analyze(ctx context.Context, results []QueryResult, agentCtx AgentContext) AgentContext {
+	for _, r := range results {
+		agentCtx.Findings = append(agentCtx.Findings, Finding{Source: r.Query.AgentName, Summary: r.Query.Query + ": " + r.Result})
+	}
+	if agentCtx.Question == "" {
+		agentCtx.RootCause = "connection pool exhausted"
+		agentCtx.Hypotheses = append(agentCtx.Hypotheses, "slow queries hold connections")
+		return agentCtx
+	}
+	agentCtx.Answer = "Given " + agentCtx.RootCause + " and " + agentCtx.Findings[len(agentCtx.Findings)-1].Summary
+	return agentCtx
+}
+
+func (a *conversationalAgent) Conclude(ctx context.Context, agentCtx AgentContext) *AnalysisResult {
+	return &AnalysisResult{
+		MonitorID: agentCtx.Event.Payload.MonitorID,
+		Success:   agentCtx.RootCause != "",
+		AgentRole: a.Role(),
+		RootCause: agentCtx.RootCause,
+		Summary:   agentCtx.RootCause,
+		Findings:  agentCtx.Findings,
+	}
+}
+
+func TestAgentOrchestrator_AskResumesStoredContext(t *testing.T) {
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1, RLMMaxIterations: 3})
+	orch.SetDefaultAgent(&conversationalAgent{name: "db-agent"})
+	orch.RegisterSubAgent(newMockSubAgent("db", "ok"))
+	store := NewMemoryAnalysisStore()
+	orch.SetAnalysisStore(store)
+
+	event := &types.AlertEvent{ID: 3, Payload: types.AlertPayload{MonitorID: 42, MonitorName: "DB connections", AlertStatus: "Alert"}}
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil || !result.Success {
+		t.Fatalf("Analyze failed: %v (%+v)", err, result)
+	}
+
+	record, err := store.GetAnalysis(context.Background(), result.AnalysisID)
+	if err != nil {
+		t.Fatalf("expected analysis to be stored: %v", err)
+	}
+	if record.Agent != "db-agent" || len(record.Context.QueryHistory) != 1 || len(record.Context.Hypotheses) != 1 {
+		t.Fatalf("unexpected stored record: %+v", record)
+	}
+
+	turn, err := orch.Ask(context.Background(), result.AnalysisID, "Is replication involved?", "oncall@example.com")
+	if err != nil {
+		t.Fatalf("Ask failed: %v", err)
+	}
+	if !turn.Success || !strings.Contains(turn.Answer, "connection pool exhausted") || !strings.Contains(turn.Answer, "replication lag") {
+		t.Errorf("expected answer built on stored root cause and new query, got %+v", turn)
+	}
+	if turn.Queries != 1 || len(turn.Findings) != 1 {
+		t.Errorf("expected 1 new query and finding, got queries=%d findings=%d", turn.Queries, len(turn.Findings))
+	}
+
+	record, _ = store.GetAnalysis(context.Background(), result.AnalysisID)
+	if len(record.Conversation) != 1 || record.Conversation[0].AskedBy != "oncall@example.com" {
+		t.Errorf("expected turn appended to record, got %+v", record.Conversation)
+	}
+	if len(record.Context.QueryHistory) != 2 || record.Context.RootCause != "connection pool exhausted" {
+		t.Errorf("expected context to accumulate queries and keep the root cause, got %+v", record.Context)
+	}
+	if orch.Stats().FollowUps != 1 {
+		t.Errorf("expected 1 follow-up in stats, got %d", orch.Stats().FollowUps)
+	}
+}
+
+func TestAgentOrchestrator_AskErrors(t *testing.T) {
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1})
+	if _, err := orch.Ask(context.Background(), "a1", "why?", ""); !errors.Is(err, ErrAnalysisStoreDisabled) {
+		t.Errorf("expected ErrAnalysisStoreDisabled, got %v", err)
+	}
+
+	orch.SetAnalysisStore(NewMemoryAnalysisStore())
+	if _, err := orch.Ask(context.Background(), "missing", "why?", ""); !errors.Is(err, ErrAnalysisNotFound) {
+		t.Errorf("expected ErrAnalysisNotFound, got %v", err)
+	}
+}
+
+func TestAgentContextSnapshot_RoundTrip(t *testing.T) {
+	event := &types.AlertEvent{Payload: types.AlertPayload{MonitorID: 1}}
+	agentCtx := NewAgentContext(event)
+	agentCtx.Iteration = 2
+	agentCtx.RootCause = "disk full"
+	agentCtx.QueryHistory = append(agentCtx.QueryHistory, QueryResult{
+		Query:     SubQuery{AgentName: "logs", Query: "errors"},
+		Result:    "none",
+		Error:     errors.New("timeout"),
+		Duration:  time.Second,
+		Timestamp: time.Now(),
+	})
+
+	data, err := json.Marshal(snapshotAgentContext(agentCtx))
+	if err != nil {
+		t.Fatalf("marshal snapshot: %v", err)
+	}
+	var snapshot AgentContextSnapshot
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		t.Fatalf("unmarshal snapshot: %v", err)
+	}
+
+	restored := snapshot.restore(event)
+	if restored.RootCause != "disk full" || restored.Iteration != 2 || len(restored.QueryHistory) != 1 {
+		t.Fatalf("unexpected restored context: %+v", restored)
+	}
+	if q := restored.QueryHistory[0]; q.Query.AgentName != "logs" || q.Error == nil || q.Error.Error() != "timeout" {
+		t.Errorf("expected query error to survive the round trip, got %+v", q)
+	}
+}
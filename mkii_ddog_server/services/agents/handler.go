@@ -0,0 +1,209 @@
+package agents
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/websocket"
+)
+
+// Handler handles agent orchestrator HTTP requests
+type Handler struct {
+	orchestrator   *AgentOrchestrator
+	ruleSource     RuleSource
+	ruleStorage    *RuleStorage
+	allowedOrigins []string // browser origins that may open WebSocket streams
+	operators      *operator.Authenticator
+}
+
+// NewHandler creates a new agent handler. ruleSource may be nil when the
+// classifier only uses its built-in rules.
+func NewHandler(orchestrator *AgentOrchestrator, ruleSource RuleSource) *Handler {
+	h := &Handler{
+		orchestrator:   orchestrator,
+		ruleSource:     ruleSource,
+		allowedOrigins: websocket.DefaultAllowedOrigins,
+	}
+	if storage, ok := ruleSource.(*RuleStorage); ok {
+		h.ruleStorage = storage
+	}
+	return h
+}
+
+// SetAllowedOrigins sets the browser origins (besides the API's own) that may
+// open WebSocket progress streams
+func (h *Handler) SetAllowedOrigins(origins []string) {
+	h.allowedOrigins = origins
+}
+
+// SetOperators sets who may change and reload classifier rules; without
+// operators those endpoints refuse every request
+func (h *Handler) SetOperators(operators *operator.Authenticator) {
+	h.operators = operators
+}
+
+// GetStats returns orchestrator statistics (GET /v1/agents/stats)
+func (h *Handler) GetStats(w http.ResponseWriter, r *http.Request) (int, any) {
+	return http.StatusOK, h.orchestrator.Stats()
+}
+
+// ClassifyAlert explains how a webhook payload would be routed (POST /v1/agents/classify)
+func (h *Handler) ClassifyAlert(w http.ResponseWriter, r *http.Request) (int, any) {
+	var payload types.AlertPayload
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid payload: %v", err)}
+	}
+
+	event := &types.AlertEvent{Payload: payload}
+	return http.StatusOK, h.orchestrator.Explain(event)
+}
+
+// GetClassifierRules lists the active classifier rules in evaluation order (GET /v1/agents/classifier/rules)
+func (h *Handler) GetClassifierRules(w http.ResponseWriter, r *http.Request) (int, any) {
+	classifier := h.orchestrator.Classifier()
+	return http.StatusOK, map[string]any{
+		"info":  classifier.Info(),
+		"rules": classifier.Rules(),
+	}
+}
+
+// ReloadClassifier reloads rules from the configured source (POST /v1/agents/classifier/reload).
+// Requires an operator token.
+func (h *Handler) ReloadClassifier(w http.ResponseWriter, r *http.Request) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	if h.ruleSource == nil {
+		return http.StatusBadRequest, map[string]string{"error": "no classifier rule source configured"}
+	}
+
+	changed, err := h.orchestrator.Classifier().Reload(r.Context(), h.ruleSource)
+	if err != nil {
+		return http.StatusUnprocessableEntity, map[string]string{"error": err.Error()}
+	}
+	log.Printf("[AGENT-CLASSIFIER] Rules reloaded by %s (changed=%v)", actor, changed)
+
+	return http.StatusOK, map[string]any{
+		"changed": changed,
+		"info":    h.orchestrator.Classifier().Info(),
+	}
+}
+
+// SaveClassifierRule creates or replaces a stored rule and reloads (POST /v1/agents/classifier/rules).
+// The authenticated operator is recorded as the rule's updated_by.
+func (h *Handler) SaveClassifierRule(w http.ResponseWriter, r *http.Request) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	if h.ruleStorage == nil {
+		return http.StatusBadRequest, map[string]string{"error": "classifier rules are not stored in the database"}
+	}
+
+	var rule ClassifierRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid rule: %v", err)}
+	}
+	rule.UpdatedBy = actor
+
+	if err := h.ruleStorage.UpsertRule(r.Context(), rule); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+
+	if _, err := h.orchestrator.Classifier().Reload(r.Context(), h.ruleStorage); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("reload classifier: %v", err)}
+	}
+
+	return http.StatusOK, map[string]any{
+		"rule": rule,
+		"info": h.orchestrator.Classifier().Info(),
+	}
+}
+
+// DeleteClassifierRule removes a stored rule and reloads (DELETE /v1/agents/classifier/rules/{name}).
+// Requires an operator token.
+func (h *Handler) DeleteClassifierRule(w http.ResponseWriter, r *http.Request, name string) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	if h.ruleStorage == nil {
+		return http.StatusBadRequest, map[string]string{"error": "classifier rules are not stored in the database"}
+	}
+
+	if err := h.ruleStorage.DeleteRule(r.Context(), name); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return http.StatusNotFound, map[string]string{"error": fmt.Sprintf("rule not found: %s", name)}
+		}
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+
+	if _, err := h.orchestrator.Classifier().Reload(r.Context(), h.ruleStorage); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": fmt.Sprintf("reload classifier: %v", err)}
+	}
+
+	log.Printf("[AGENT-CLASSIFIER] Rule %s deleted by %s", name, actor)
+	return http.StatusOK, map[string]string{"message": fmt.Sprintf("rule %s deleted", name)}
+}
+
+// askRequest is the body of POST /v1/agents/analyses/{id}/ask
+type askRequest struct {
+	Question string `json:"question"`
+	AskedBy  string `json:"asked_by"`
+}
+
+// GetAnalysis returns a stored analysis with its conversation (GET /v1/agents/analyses/{id})
+func (h *Handler) GetAnalysis(w http.ResponseWriter, r *http.Request, id string) (int, any) {
+	store := h.orchestrator.AnalysisStore()
+	if store == nil {
+		return http.StatusServiceUnavailable, map[string]string{"error": ErrAnalysisStoreDisabled.Error()}
+	}
+
+	record, err := store.GetAnalysis(r.Context(), id)
+	if errors.Is(err, ErrAnalysisNotFound) {
+		return http.StatusNotFound, map[string]string{"error": fmt.Sprintf("analysis not found: %s", id)}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, record
+}
+
+// AskAnalysis asks a follow-up question on a completed analysis (POST /v1/agents/analyses/{id}/ask)
+func (h *Handler) AskAnalysis(w http.ResponseWriter, r *http.Request, id string) (int, any) {
+	var req askRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+	}
+	if req.Question == "" {
+		return http.StatusBadRequest, map[string]string{"error": "question is required"}
+	}
+
+	turn, err := h.orchestrator.Ask(r.Context(), id, req.Question, req.AskedBy)
+
+	var denied *FollowUpDeniedError
+	switch {
+	case errors.Is(err, ErrAnalysisStoreDisabled):
+		return http.StatusServiceUnavailable, map[string]string{"error": err.Error()}
+	case errors.Is(err, ErrAnalysisNotFound):
+		return http.StatusNotFound, map[string]string{"error": fmt.Sprintf("analysis not found: %s", id)}
+	case errors.As(err, &denied) && denied.Reason == SkipCircuitOpen:
+		return http.StatusServiceUnavailable, map[string]string{"error": err.Error(), "reason": string(denied.Reason)}
+	case errors.As(err, &denied):
+		return http.StatusTooManyRequests, map[string]string{"error": err.Error(), "reason": string(denied.Reason)}
+	case errors.Is(err, ErrSchedulerFull):
+		return http.StatusServiceUnavailable, map[string]string{"error": err.Error()}
+	case err != nil && turn != nil:
+		return http.StatusBadGateway, turn
+	case err != nil:
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, turn
+}
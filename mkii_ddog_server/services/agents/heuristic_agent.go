@@ -0,0 +1,147 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+)
+
+// HeuristicAgent is a cheap, LLM-free fallback used when the sidecar circuit
+// is open. It summarizes the alert payload and suggests role-specific first steps.
+type HeuristicAgent struct {
+	classifier *RoleClassifier
+}
+
+// NewHeuristicAgent creates a heuristic fallback agent. The classifier picks
+// role-specific recommendations; pass the orchestrator's so rule reloads apply.
+func NewHeuristicAgent(classifier *RoleClassifier) *HeuristicAgent {
+	if classifier == nil {
+		classifier = NewRoleClassifier()
+	}
+	return &HeuristicAgent{classifier: classifier}
+}
+
+// Name returns the agent's unique identifier
+func (a *HeuristicAgent) Name() string {
+	return "heuristic"
+}
+
+// Role returns the agent's specialist role
+func (a *HeuristicAgent) Role() AgentRole {
+	return RoleGeneral
+}
+
+// Plan completes immediately; there is nothing to query
+func (a *HeuristicAgent) Plan(ctx context.Context, event *types.AlertEvent, agentCtx AgentContext) AgentPlan {
+	return AgentPlan{
+		Complete:  true,
+		Reasoning: "Heuristic fallback: summarizing alert payload without LLM",
+	}
+}
+
+// Analyze is a no-op because Plan always completes
+func (a *HeuristicAgent) Analyze(ctx context.Context, results []QueryResult, agentCtx AgentContext) AgentContext {
+	return agentCtx
+}
+
+// Conclude builds a result from the alert payload alone
+func (a *HeuristicAgent) Conclude(ctx context.Context, agentCtx AgentContext) *AnalysisResult {
+	event := agentCtx.Event
+	p := event.Payload
+
+	role := a.classifier.Classify(event).Role
+
+	name := p.MonitorName
+	if name == "" {
+		name = p.AlertTitle
+	}
+
+	var parts []string
+	parts = append(parts, fmt.Sprintf("%s is in %s state", name, utils.FirstNonEmpty(p.AlertStatus, p.AlertState, "alert")))
+	if p.Metric != "" {
+		metric := fmt.Sprintf("metric %s", p.Metric)
+		if p.Value != "" {
+			metric += fmt.Sprintf(" = %s", p.Value)
+		}
+		if p.Threshold != "" {
+			metric += fmt.Sprintf(" (threshold %s)", p.Threshold)
+		}
+		parts = append(parts, metric)
+	}
+	if scope := utils.FirstNonEmpty(p.Scope, p.Hostname, p.Service); scope != "" {
+		parts = append(parts, "scope "+scope)
+	}
+	summary := strings.Join(parts, "; ")
+
+	details := summary
+	if p.DetailedDescription != "" {
+		details += "\n\n" + p.DetailedDescription
+	}
+	// Findings from context sources (e.g. recent deploys) are the best lead we have
+	if len(agentCtx.Findings) > 0 {
+		details += "\n\nContext:"
+		for _, f := range agentCtx.Findings {
+			details += "\n- " + f.Summary
+		}
+	}
+
+	return &AnalysisResult{
+		MonitorID:   p.MonitorID,
+		MonitorName: p.MonitorName,
+		AlertStatus: p.AlertStatus,
+		Success:     true,
+		AgentRole:   role,
+		RootCause:   "Undetermined (heuristic fallback, LLM analysis unavailable)",
+		Summary:     summary,
+		Details:     details,
+		Findings: append(agentCtx.Findings, Finding{
+			Source:    a.Name(),
+			Category:  "heuristic",
+			Summary:   summary,
+			Details:   details,
+			Severity:  "info",
+			Timestamp: time.Now(),
+		}),
+		Recommendations: heuristicRecommendations(role),
+	}
+}
+
+// Recover summarizes the recovery from the payload and the stored analysis
+func (a *HeuristicAgent) Recover(ctx context.Context, event *types.AlertEvent, rc RecoveryContext) (*RecoveryOutcome, error) {
+	return &RecoveryOutcome{Summary: describeRecovery(event, rc)}, nil
+}
+
+// heuristicRecommendations returns generic first steps for a role
+func heuristicRecommendations(role AgentRole) []string {
+	switch role {
+	case RoleDatabase:
+		return []string{
+			"Check connection counts and pool saturation",
+			"Look for slow or blocked queries in Database Monitoring",
+			"Verify replication lag and disk usage",
+		}
+	case RoleApplication:
+		return []string{
+			"Review recent deployments for the affected service",
+			"Inspect error traces and latency breakdown in APM",
+		}
+	case RoleNetwork:
+		return []string{
+			"Check load balancer and upstream health checks",
+			"Review DNS resolution and synthetic test results",
+		}
+	case RoleLogs:
+		return []string{"Search logs around the alert window for new error patterns"}
+	case RoleWatchdog:
+		return []string{"Open the Watchdog story to review the detected anomaly and impacted services"}
+	default:
+		return []string{
+			"Check host CPU, memory and disk on the affected scope",
+			"Correlate with recent infrastructure or configuration changes",
+		}
+	}
+}
@@ -2,29 +2,58 @@ package agents
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"strconv"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/google/uuid"
 )
 
 // AgentOrchestrator is the single entry point for all agent-based analysis.
-// It provides semaphore-bounded concurrency, role classification, and RLM coordination.
+// It provides priority-scheduled bounded concurrency, role classification, and RLM coordination.
 type AgentOrchestrator struct {
-	classifier      *RoleClassifier
-	agents          map[AgentRole]Agent
-	defaultAgent    Agent
-	rlmCoordinator  *RLMCoordinator
-	failureAlerter  *FailureAlerter
-	semaphore       chan struct{}
-	mu              sync.RWMutex
+	classifier     *RoleClassifier
+	agents         map[AgentRole]Agent
+	defaultAgent   Agent
+	rlmCoordinator *RLMCoordinator
+	failureAlerter *FailureAlerter
+	scheduler      *AnalysisScheduler
+	progress       *ProgressBus
+	store          AnalysisStore
+	remediation    RemediationSink
+	contextSources []ContextSource
+	priorityAdjust PriorityAdjuster
+	mu             sync.RWMutex
+
+	// importantMonitors get a priority boost in the scheduler
+	importantMonitors map[int64]bool
+
+	// Spend guardrails: budgets and cooldowns are checked before any agent
+	// runs; the circuit breaker diverts to fallbackAgent while the sidecar fails
+	budget        *BudgetGuard
+	circuit       *CircuitBreaker
+	fallbackAgent Agent
+	skipped       []SkippedAnalysis // ring buffer of recent skips
+	skipCounts    map[SkipReason]int64
+	skipMu        sync.Mutex
+
+	// Collaboration settings (see OrchestratorConfig)
+	collaborationMaxRoles      int
+	collaborationMinConfidence float64
 
 	// Metrics
-	activeCount    int64
-	totalProcessed int64
-	totalErrors    int64
+	activeCount        int64
+	totalProcessed     int64
+	totalErrors        int64
+	totalCollaborative int64
+	totalFallback      int64
+	totalFollowUps     int64
 }
 
 // OrchestratorConfig holds configuration for the agent orchestrator
@@ -36,14 +65,88 @@ type OrchestratorConfig struct {
 	// RLMMaxIterations is the maximum number of RLM iterations per analysis
 	// Default: 5
 	RLMMaxIterations int
+
+	// CollaborationMaxRoles is how many top-ranked specialist roles analyze an
+	// alert in parallel before their results are synthesized. 1 disables collaboration.
+	// Default: 1
+	CollaborationMaxRoles int
+
+	// CollaborationMinConfidence is the minimum classification confidence a
+	// secondary role needs to join a collaborative analysis
+	// Default: 0.5
+	CollaborationMinConfidence float64
+
+	// Budget limits analyses per hour, tokens per day and per-monitor frequency
+	Budget BudgetConfig
+
+	// CircuitThreshold is the number of consecutive sidecar failures that open the circuit
+	// Default: 5
+	CircuitThreshold int
+
+	// CircuitCooldown is how long the circuit stays open before a probe is allowed
+	// Default: 2m
+	CircuitCooldown time.Duration
+
+	// PriorityAgingPerMinute is how many priority points a waiting analysis
+	// gains per minute, so low-priority alerts are not starved
+	// Default: 5
+	PriorityAgingPerMinute float64
+
+	// MaxQueued caps analyses waiting for a slot; when full the lowest-priority
+	// waiter is shed. 0 means unbounded.
+	// Default: 100
+	MaxQueued int
+
+	// ImportantMonitors are monitor IDs boosted in the analysis queue
+	ImportantMonitors []int64
 }
 
 // DefaultOrchestratorConfig returns sensible defaults
 func DefaultOrchestratorConfig() OrchestratorConfig {
 	return OrchestratorConfig{
-		MaxConcurrent:    3,
-		RLMMaxIterations: 5,
+		MaxConcurrent:              3,
+		RLMMaxIterations:           5,
+		CollaborationMaxRoles:      1,
+		CollaborationMinConfidence: 0.5,
+		Budget:                     BudgetConfig{MonitorCooldown: 10 * time.Minute},
+		CircuitThreshold:           5,
+		CircuitCooldown:            2 * time.Minute,
+		PriorityAgingPerMinute:     5,
+		MaxQueued:                  100,
+	}
+}
+
+// OrchestratorConfigFromEnv returns the defaults overridden by
+// AGENT_MAX_CONCURRENT, AGENT_COLLABORATION_ROLES, AGENT_COLLABORATION_MIN_CONFIDENCE,
+// AGENT_CIRCUIT_THRESHOLD, AGENT_CIRCUIT_COOLDOWN, AGENT_PRIORITY_AGING_PER_MINUTE,
+// AGENT_MAX_QUEUED, AGENT_IMPORTANT_MONITORS and the budget variables
+// (see BudgetConfigFromEnv)
+func OrchestratorConfigFromEnv() OrchestratorConfig {
+	config := DefaultOrchestratorConfig()
+	config.Budget = BudgetConfigFromEnv()
+	config.ImportantMonitors = ImportantMonitorsFromEnv()
+	if v, err := strconv.ParseFloat(os.Getenv("AGENT_PRIORITY_AGING_PER_MINUTE"), 64); err == nil && v >= 0 {
+		config.PriorityAgingPerMinute = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AGENT_MAX_QUEUED")); err == nil && v >= 0 {
+		config.MaxQueued = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AGENT_MAX_CONCURRENT")); err == nil && v > 0 {
+		config.MaxConcurrent = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AGENT_COLLABORATION_ROLES")); err == nil && v > 0 {
+		config.CollaborationMaxRoles = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("AGENT_COLLABORATION_MIN_CONFIDENCE"), 64); err == nil && v >= 0 {
+		config.CollaborationMinConfidence = v
 	}
+	if v, err := strconv.Atoi(os.Getenv("AGENT_CIRCUIT_THRESHOLD")); err == nil && v > 0 {
+		config.CircuitThreshold = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("AGENT_CIRCUIT_COOLDOWN")); err == nil && v > 0 {
+		config.CircuitCooldown = v
+	}
+	return config
 }
 
 // NewAgentOrchestrator creates a new agent orchestrator
@@ -54,13 +157,28 @@ func NewAgentOrchestrator(config OrchestratorConfig) *AgentOrchestrator {
 	if config.RLMMaxIterations <= 0 {
 		config.RLMMaxIterations = 5
 	}
+	if config.CollaborationMaxRoles <= 0 {
+		config.CollaborationMaxRoles = 1
+	}
+
+	important := make(map[int64]bool, len(config.ImportantMonitors))
+	for _, id := range config.ImportantMonitors {
+		important[id] = true
+	}
 
 	return &AgentOrchestrator{
-		classifier:     NewRoleClassifier(),
-		agents:         make(map[AgentRole]Agent),
-		rlmCoordinator: NewRLMCoordinator(config.RLMMaxIterations),
-		failureAlerter: NewFailureAlerter(),
-		semaphore:      make(chan struct{}, config.MaxConcurrent),
+		classifier:                 NewRoleClassifier(),
+		agents:                     make(map[AgentRole]Agent),
+		rlmCoordinator:             NewRLMCoordinator(config.RLMMaxIterations),
+		failureAlerter:             NewFailureAlerter(),
+		scheduler:                  NewAnalysisScheduler(config.MaxConcurrent, config.PriorityAgingPerMinute, config.MaxQueued),
+		importantMonitors:          important,
+		progress:                   NewProgressBus(),
+		collaborationMaxRoles:      config.CollaborationMaxRoles,
+		collaborationMinConfidence: config.CollaborationMinConfidence,
+		budget:                     NewBudgetGuard(config.Budget),
+		circuit:                    NewCircuitBreaker(config.CircuitThreshold, config.CircuitCooldown),
+		skipCounts:                 make(map[SkipReason]int64),
 	}
 }
 
@@ -80,6 +198,47 @@ func (o *AgentOrchestrator) SetDefaultAgent(agent Agent) {
 	log.Printf("[AGENT-ORCH] Set default agent: %s", agent.Name())
 }
 
+// SetFallbackAgent sets the cheaper agent used while the sidecar circuit is open
+func (o *AgentOrchestrator) SetFallbackAgent(agent Agent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.fallbackAgent = agent
+	log.Printf("[AGENT-ORCH] Set fallback agent: %s", agent.Name())
+}
+
+// SetPriorityAdjuster adds an adjuster's score to every analysis priority
+func (o *AgentOrchestrator) SetPriorityAdjuster(adjuster PriorityAdjuster) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.priorityAdjust = adjuster
+}
+
+// adjustPriority returns the adjuster's extra priority for an event (0 without one)
+func (o *AgentOrchestrator) adjustPriority(ctx context.Context, event *types.AlertEvent) int {
+	o.mu.RLock()
+	adjuster := o.priorityAdjust
+	o.mu.RUnlock()
+	if adjuster == nil {
+		return 0
+	}
+	return adjuster.AdjustPriority(ctx, event)
+}
+
+// SetAnalysisStore enables persistence of completed analyses so they can be
+// resumed with follow-up questions (see Ask)
+func (o *AgentOrchestrator) SetAnalysisStore(store AnalysisStore) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.store = store
+}
+
+// AnalysisStore returns the configured analysis store, or nil
+func (o *AgentOrchestrator) AnalysisStore() AnalysisStore {
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	return o.store
+}
+
 // RegisterSubAgent adds a sub-agent to the RLM coordinator
 func (o *AgentOrchestrator) RegisterSubAgent(subAgent SubAgent) {
 	o.rlmCoordinator.RegisterSubAgent(subAgent)
@@ -87,16 +246,218 @@ func (o *AgentOrchestrator) RegisterSubAgent(subAgent SubAgent) {
 
 // Analyze performs a bounded agent analysis on a webhook event.
 // This is the single entry point - all agent calls should go through here.
+// When collaboration is enabled and several roles match, the top specialists
+// run in parallel (each holding a scheduler slot) and their results are synthesized.
+// Slots go to the highest-priority waiting alert (see AnalysisPriority).
+//
+// Budgets and monitor cooldowns are checked first; a denied analysis returns a
+// result with Skipped set and a nil error. While the sidecar circuit is open the
+// fallback agent (if any) handles the alert instead.
 func (o *AgentOrchestrator) Analyze(ctx context.Context, event *types.AlertEvent) (*AnalysisResult, error) {
-	// Acquire semaphore (bounded concurrency)
-	select {
-	case o.semaphore <- struct{}{}:
-		defer func() { <-o.semaphore }()
-	case <-ctx.Done():
-		log.Printf("[AGENT-ORCH] Context cancelled while waiting for semaphore: monitor %d",
-			event.Payload.MonitorID)
-		return nil, ctx.Err()
+	// Classify the alert to determine which agent(s) to use
+	classification := o.classifier.Classify(event)
+	priority := AnalysisPriority(event, o.importantMonitors) + o.adjustPriority(ctx, event)
+	log.Printf("[AGENT-ORCH] Classified monitor %d as role: %s (rule: %s, confidence: %.2f, priority: %d)",
+		event.Payload.MonitorID, classification.Role, classification.Rule, classification.Confidence, priority)
+
+	// Every analysis gets an ID and a progress stream (GET /v1/agents/analyses/{id}/stream)
+	analysisID := uuid.NewString()
+	o.progress.Start(analysisID, event.ID, event.Payload.MonitorID, event.Payload.MonitorName)
+	defer o.progress.Finish(analysisID)
+
+	progress := &progressReporter{bus: o.progress, analysisID: analysisID, monitorID: event.Payload.MonitorID}
+	ctx = withProgress(ctx, progress)
+	progress.emit(ProgressAnalysisStarted, 0, "Analysis started for "+monitorLabel(event), map[string]any{
+		"event_id":   event.ID,
+		"role":       classification.Role,
+		"rule":       classification.Rule,
+		"confidence": classification.Confidence,
+		"priority":   priority,
+	})
+
+	// Capture each agent's final context so the analysis can be resumed later
+	capture := &contextCapture{}
+	ctx = withCapture(ctx, capture)
+
+	// Context sources (e.g. recent deploys) seed every agent's findings
+	ctx = withSeedFindings(ctx, o.gatherContext(ctx, event))
+
+	result, err := o.analyze(ctx, event, classification, priority)
+	if result != nil {
+		result.AnalysisID = analysisID
+		if !result.Skipped {
+			o.proposeRemediation(ctx, analysisID, event, result)
+			o.saveAnalysis(ctx, analysisID, event, result, capture)
+		}
+	}
+
+	switch {
+	case result != nil && result.Skipped:
+		progress.emit(ProgressAnalysisSkipped, 0, result.Summary, map[string]any{"reason": result.SkipReason})
+	case result != nil:
+		progress.emit(ProgressAnalysisCompleted, result.Iterations, result.Summary, map[string]any{
+			"success":      result.Success,
+			"agent_role":   result.AgentRole,
+			"root_cause":   truncate(result.RootCause, 500),
+			"notebook_url": result.NotebookURL,
+			"duration_ms":  result.Duration.Milliseconds(),
+			"error":        result.Error,
+		})
+	case err != nil:
+		progress.emit(ProgressAnalysisCompleted, 0, "Analysis failed: "+err.Error(), map[string]any{"success": false})
+	}
+
+	return result, err
+}
+
+// analyze applies budgets and the circuit breaker, then runs the selected agent(s)
+func (o *AgentOrchestrator) analyze(ctx context.Context, event *types.AlertEvent, classification Classification, priority int) (result *AnalysisResult, err error) {
+	if ok, reason, detail := o.budget.Admit(event.AccountName, event.Payload.MonitorID); !ok {
+		return o.skip(event, classification.Role, reason, detail), nil
+	}
+	// A shed or failed analysis must not hold the monitor's cooldown, or the
+	// next alert for it would be suppressed with nothing analyzed
+	defer func() {
+		if err != nil || result == nil || result.Skipped || !result.Success {
+			o.budget.ReleaseCooldown(event.AccountName, event.Payload.MonitorID)
+		}
+	}()
+
+	// Local agents don't need the sidecar, so they run even while the circuit
+	// is open and never collaborate with sidecar specialists
+	if agent := o.getAgent(classification.Role); agent != nil && isLocal(agent) {
+		result, err := o.runAgent(ctx, event, classification.Role, agent, priority)
+		if errors.Is(err, ErrSchedulerFull) {
+			return o.skip(event, classification.Role, SkipQueueFull, "analysis queue is full; shed for higher-priority alerts"), nil
+		}
+		if result != nil {
+			o.budget.RecordTokens(event.AccountName, result.TokensUsed)
+		}
+		return result, err
+	}
+
+	if !o.circuit.Allow() {
+		o.mu.RLock()
+		fallback := o.fallbackAgent
+		o.mu.RUnlock()
+
+		if fallback == nil {
+			return o.skip(event, classification.Role, SkipNoFallbackConfigured, "agent sidecar circuit is open"), nil
+		}
+
+		log.Printf("[AGENT-ORCH] Circuit open, using fallback agent %s for monitor %d",
+			fallback.Name(), event.Payload.MonitorID)
+		o.recordSkip(event, SkipCircuitOpen, "analyzed by fallback agent "+fallback.Name())
+		atomic.AddInt64(&o.totalFallback, 1)
+		result, err := o.runAgent(ctx, event, classification.Role, fallback, priority)
+		if errors.Is(err, ErrSchedulerFull) {
+			return o.skip(event, classification.Role, SkipQueueFull, "analysis queue is full"), nil
+		}
+		return result, err
+	}
+
+	if specialists := o.selectSpecialists(classification); len(specialists) > 1 {
+		result, err = o.analyzeCollaborative(ctx, event, specialists, priority)
+	} else {
+		result, err = o.analyzeSingle(ctx, event, classification.Role, priority)
+	}
+
+	if errors.Is(err, ErrSchedulerFull) {
+		o.circuit.Release()
+		return o.skip(event, classification.Role, SkipQueueFull, "analysis queue is full; shed for higher-priority alerts"), nil
+	}
+
+	o.recordOutcome(event, result, err)
+	return result, err
+}
+
+// recordOutcome charges token usage and feeds the circuit breaker.
+// Cancellations, timeouts while still queued for a scheduler slot and
+// results that never reached an agent say nothing about sidecar health and
+// only release a half-open probe.
+func (o *AgentOrchestrator) recordOutcome(event *types.AlertEvent, result *AnalysisResult, err error) {
+	if result != nil {
+		o.budget.RecordTokens(event.AccountName, result.TokensUsed)
+	}
+	neverRan := result == nil || result.Iterations == 0
+
+	switch {
+	case err == nil && result != nil && result.Success:
+		o.circuit.RecordSuccess()
+	case errors.Is(err, context.Canceled),
+		errors.Is(err, context.DeadlineExceeded) && neverRan,
+		err == nil && neverRan:
+		o.circuit.Release()
+	default:
+		o.circuit.RecordFailure()
+	}
+}
+
+// skip records a skipped analysis and returns its result
+func (o *AgentOrchestrator) skip(event *types.AlertEvent, role AgentRole, reason SkipReason, detail string) *AnalysisResult {
+	log.Printf("[AGENT-ORCH] Skipping analysis for monitor %d: %s (%s)", event.Payload.MonitorID, reason, detail)
+	o.recordSkip(event, reason, detail)
+
+	now := time.Now()
+	return &AnalysisResult{
+		MonitorID:   event.Payload.MonitorID,
+		MonitorName: event.Payload.MonitorName,
+		AlertStatus: event.Payload.AlertStatus,
+		AgentRole:   role,
+		Summary:     "Analysis skipped: " + detail,
+		Skipped:     true,
+		SkipReason:  reason,
+		StartedAt:   now,
+		CompletedAt: now,
+	}
+}
+
+// recordSkip appends to the recent-skips ring buffer and bumps the reason counter
+func (o *AgentOrchestrator) recordSkip(event *types.AlertEvent, reason SkipReason, detail string) {
+	o.skipMu.Lock()
+	defer o.skipMu.Unlock()
+
+	o.skipCounts[reason]++
+	o.skipped = append(o.skipped, SkippedAnalysis{
+		MonitorID:   event.Payload.MonitorID,
+		MonitorName: event.Payload.MonitorName,
+		Account:     normalizeAccount(event.AccountName),
+		Reason:      reason,
+		Detail:      detail,
+		At:          time.Now(),
+	})
+	if len(o.skipped) > maxRecentSkipped {
+		o.skipped = o.skipped[len(o.skipped)-maxRecentSkipped:]
 	}
+}
+
+// RecentSkipped returns the most recent skipped analyses, newest first
+func (o *AgentOrchestrator) RecentSkipped() []SkippedAnalysis {
+	o.skipMu.Lock()
+	defer o.skipMu.Unlock()
+
+	recent := make([]SkippedAnalysis, len(o.skipped))
+	for i, s := range o.skipped {
+		recent[len(o.skipped)-1-i] = s
+	}
+	return recent
+}
+
+// analyzeSingle runs the agent for role through the RLM loop
+func (o *AgentOrchestrator) analyzeSingle(ctx context.Context, event *types.AlertEvent, role AgentRole, priority int) (*AnalysisResult, error) {
+	return o.runAgent(ctx, event, role, o.getAgent(role), priority)
+}
+
+// runAgent runs one agent through the RLM loop once the scheduler grants a slot
+func (o *AgentOrchestrator) runAgent(ctx context.Context, event *types.AlertEvent, role AgentRole, agent Agent, priority int) (*AnalysisResult, error) {
+	// Wait for a slot (bounded concurrency, highest priority first)
+	progressFrom(ctx).emit(ProgressQueued, 0, "Waiting for an analysis slot", map[string]any{"priority": priority})
+	release, err := o.scheduler.Acquire(ctx, priority, monitorLabel(event))
+	if err != nil {
+		log.Printf("[AGENT-ORCH] Not scheduled: monitor %d: %v", event.Payload.MonitorID, err)
+		return nil, err
+	}
+	defer release()
 
 	atomic.AddInt64(&o.activeCount, 1)
 	defer atomic.AddInt64(&o.activeCount, -1)
@@ -104,12 +465,6 @@ func (o *AgentOrchestrator) Analyze(ctx context.Context, event *types.AlertEvent
 	log.Printf("[AGENT-ORCH] Starting analysis for monitor %d (active: %d)",
 		event.Payload.MonitorID, atomic.LoadInt64(&o.activeCount))
 
-	// Classify the alert to determine which agent to use
-	role := o.classifier.Classify(event)
-	log.Printf("[AGENT-ORCH] Classified monitor %d as role: %s", event.Payload.MonitorID, role)
-
-	// Get the appropriate agent
-	agent := o.getAgent(role)
 	if agent == nil {
 		atomic.AddInt64(&o.totalErrors, 1)
 		log.Printf("[AGENT-ORCH] No agent available for role %s (monitor %d)", role, event.Payload.MonitorID)
@@ -146,6 +501,90 @@ func (o *AgentOrchestrator) Analyze(ctx context.Context, event *types.AlertEvent
 	return result, nil
 }
 
+// specialist pairs a ranked role candidate with the agent that will handle it
+type specialist struct {
+	candidate RoleCandidate
+	agent     Agent
+}
+
+// selectSpecialists picks the agents for a collaborative analysis.
+// Candidates that resolve to the same agent (e.g. the default agent) run once.
+func (o *AgentOrchestrator) selectSpecialists(classification Classification) []specialist {
+	if o.collaborationMaxRoles <= 1 {
+		return nil
+	}
+
+	var selected []specialist
+	seen := make(map[string]bool)
+	for _, candidate := range classification.TopCandidates(o.collaborationMaxRoles, o.collaborationMinConfidence) {
+		agent := o.getAgent(candidate.Role)
+		if agent == nil || seen[agent.Name()] {
+			continue
+		}
+		seen[agent.Name()] = true
+		selected = append(selected, specialist{candidate: candidate, agent: agent})
+	}
+	return selected
+}
+
+// analyzeCollaborative runs several specialists in parallel and synthesizes their results
+func (o *AgentOrchestrator) analyzeCollaborative(ctx context.Context, event *types.AlertEvent, specialists []specialist, priority int) (*AnalysisResult, error) {
+	startTime := time.Now()
+
+	names := make([]string, len(specialists))
+	for i, s := range specialists {
+		names[i] = s.agent.Name()
+	}
+	log.Printf("[AGENT-ORCH] Starting collaborative analysis for monitor %d with %v",
+		event.Payload.MonitorID, names)
+
+	outcomes := make([]specialistOutcome, len(specialists))
+	var wg sync.WaitGroup
+
+	for i, s := range specialists {
+		outcomes[i] = specialistOutcome{candidate: s.candidate, agent: s.agent.Name()}
+
+		wg.Add(1)
+		go func(i int, s specialist) {
+			defer wg.Done()
+
+			// Each specialist holds its own slot so collaboration stays within MaxConcurrent
+			release, err := o.scheduler.Acquire(ctx, priority, monitorLabel(event)+"/"+string(s.candidate.Role))
+			if err != nil {
+				outcomes[i].err = err
+				return
+			}
+			defer release()
+
+			atomic.AddInt64(&o.activeCount, 1)
+			defer atomic.AddInt64(&o.activeCount, -1)
+
+			outcomes[i].result, outcomes[i].err = o.rlmCoordinator.Execute(ctx, s.agent, event)
+		}(i, s)
+	}
+
+	wg.Wait()
+
+	result := synthesize(event, seedFindingsFrom(ctx), outcomes, startTime)
+
+	atomic.AddInt64(&o.totalProcessed, 1)
+	atomic.AddInt64(&o.totalCollaborative, 1)
+	if !result.Success {
+		atomic.AddInt64(&o.totalErrors, 1)
+		go o.failureAlerter.ReportFailure(ctx, result, ctx.Err())
+	}
+
+	if err := ctx.Err(); err != nil && !result.Success {
+		log.Printf("[AGENT-ORCH] Collaborative analysis cancelled for monitor %d: %v", event.Payload.MonitorID, err)
+		return result, err
+	}
+
+	log.Printf("[AGENT-ORCH] Collaborative analysis completed for monitor %d: success=%v, contributors=%d, conflicts=%d, duration=%v",
+		event.Payload.MonitorID, result.Success, len(result.Contributors), len(result.Conflicts), result.Duration)
+
+	return result, nil
+}
+
 // ShouldAnalyze determines if an event should trigger agent analysis.
 // Custom webhook templates often use uppercase fields (ALERT_STATE) while
 // standard fields (alert_status) are empty, so we check both.
@@ -186,79 +625,51 @@ func (o *AgentOrchestrator) ShouldRecover(event *types.AlertEvent) bool {
 	return false
 }
 
-// Recover notifies the agent sidecar that a monitor has recovered,
-// so it can update the existing notebook's status to RESOLVED.
-func (o *AgentOrchestrator) Recover(ctx context.Context, event *types.AlertEvent) (*AnalysisResult, error) {
-	// Acquire semaphore (bounded concurrency)
-	select {
-	case o.semaphore <- struct{}{}:
-		defer func() { <-o.semaphore }()
-	case <-ctx.Done():
-		return nil, ctx.Err()
+// Progress returns the bus that streams analysis progress events
+func (o *AgentOrchestrator) Progress() *ProgressBus {
+	return o.progress
+}
+
+// Classifier returns the role classifier used for routing
+func (o *AgentOrchestrator) Classifier() *RoleClassifier {
+	return o.classifier
+}
+
+// Explain describes how an event would be routed without running any analysis
+func (o *AgentOrchestrator) Explain(event *types.AlertEvent) RoutingExplanation {
+	classification := o.classifier.Classify(event)
+
+	explanation := RoutingExplanation{
+		Classification: classification,
+		Action:         RoutingActionSkip,
 	}
 
-	log.Printf("[AGENT-ORCH] Starting recovery for monitor %d (status: %s)",
-		event.Payload.MonitorID, event.Payload.AlertStatus)
+	switch {
+	case o.ShouldRecover(event):
+		explanation.Action = RoutingActionRecover
+	case o.ShouldAnalyze(event):
+		explanation.Action = RoutingActionAnalyze
+	}
 
-	// Use the default agent (ClaudeAgent) to invoke recovery
-	agent := o.defaultAgent
-	if agent == nil {
-		// Try to find any registered agent
-		o.mu.RLock()
-		for _, a := range o.agents {
-			agent = a
-			break
-		}
-		o.mu.RUnlock()
+	o.mu.RLock()
+	agent, ok := o.agents[classification.Role]
+	if !ok {
+		agent = o.defaultAgent
+		explanation.UsedDefaultAgent = agent != nil
 	}
+	o.mu.RUnlock()
 
-	if agent == nil {
-		return &AnalysisResult{
-			MonitorID:   event.Payload.MonitorID,
-			MonitorName: event.Payload.MonitorName,
-			AlertStatus: event.Payload.AlertStatus,
-			Success:     false,
-			Error:       "no agent available for recovery",
-			StartedAt:   time.Now(),
-			CompletedAt: time.Now(),
-		}, nil
+	if agent != nil {
+		explanation.Agent = agent.Name()
 	}
 
-	// Type-assert to ClaudeAgent to call recovery-specific method
-	if claudeAgent, ok := agent.(*ClaudeAgent); ok {
-		err := claudeAgent.InvokeRecovery(ctx, event)
-		if err != nil {
-			return &AnalysisResult{
-				MonitorID:   event.Payload.MonitorID,
-				MonitorName: event.Payload.MonitorName,
-				AlertStatus: event.Payload.AlertStatus,
-				Success:     false,
-				Error:       err.Error(),
-				StartedAt:   time.Now(),
-				CompletedAt: time.Now(),
-			}, nil
+	if specialists := o.selectSpecialists(classification); len(specialists) > 1 {
+		for _, s := range specialists {
+			explanation.Collaborators = append(explanation.Collaborators, s.agent.Name())
 		}
-		return &AnalysisResult{
-			MonitorID:   event.Payload.MonitorID,
-			MonitorName: event.Payload.MonitorName,
-			AlertStatus: event.Payload.AlertStatus,
-			Success:     true,
-			Summary:     "Recovery notification sent to agent sidecar",
-			AgentRole:   claudeAgent.Role(),
-			StartedAt:   time.Now(),
-			CompletedAt: time.Now(),
-		}, nil
 	}
 
-	return &AnalysisResult{
-		MonitorID:   event.Payload.MonitorID,
-		MonitorName: event.Payload.MonitorName,
-		AlertStatus: event.Payload.AlertStatus,
-		Success:     false,
-		Error:       "agent does not support recovery (not a ClaudeAgent)",
-		StartedAt:   time.Now(),
-		CompletedAt: time.Now(),
-	}, nil
+	return explanation
 }
 
 // getAgent returns the appropriate agent for a role
@@ -276,24 +687,98 @@ func (o *AgentOrchestrator) getAgent(role AgentRole) Agent {
 func (o *AgentOrchestrator) Stats() OrchestratorStats {
 	o.mu.RLock()
 	agentCount := len(o.agents)
+	fallbackName := ""
+	if o.fallbackAgent != nil {
+		fallbackName = o.fallbackAgent.Name()
+	}
 	o.mu.RUnlock()
 
+	o.skipMu.Lock()
+	skippedByReason := make(map[SkipReason]int64, len(o.skipCounts))
+	for reason, count := range o.skipCounts {
+		skippedByReason[reason] = count
+	}
+	o.skipMu.Unlock()
+
 	return OrchestratorStats{
-		ActiveAnalyses:  atomic.LoadInt64(&o.activeCount),
-		MaxConcurrent:   cap(o.semaphore),
-		TotalProcessed:  atomic.LoadInt64(&o.totalProcessed),
-		TotalErrors:     atomic.LoadInt64(&o.totalErrors),
-		RegisteredAgents: agentCount,
-		SubAgents:       o.rlmCoordinator.ListSubAgents(),
+		ActiveAnalyses:        atomic.LoadInt64(&o.activeCount),
+		MaxConcurrent:         o.scheduler.slots,
+		TotalProcessed:        atomic.LoadInt64(&o.totalProcessed),
+		TotalErrors:           atomic.LoadInt64(&o.totalErrors),
+		CollaborativeAnalyses: atomic.LoadInt64(&o.totalCollaborative),
+		CollaborationMaxRoles: o.collaborationMaxRoles,
+		RegisteredAgents:      agentCount,
+		SubAgents:             o.rlmCoordinator.ListSubAgents(),
+		FollowUps:             atomic.LoadInt64(&o.totalFollowUps),
+		Scheduler:             o.scheduler.Stats(),
+		Budget:                o.budget.Stats(),
+		Circuit:               o.circuit.Stats(),
+		FallbackAgent:         fallbackName,
+		FallbackAnalyses:      atomic.LoadInt64(&o.totalFallback),
+		SkippedByReason:       skippedByReason,
+		RecentSkipped:         o.RecentSkipped(),
 	}
 }
 
+// Routing actions reported by Explain
+const (
+	RoutingActionAnalyze = "analyze"
+	RoutingActionRecover = "recover"
+	RoutingActionSkip    = "skip"
+)
+
+// RoutingExplanation describes how an alert would be handled by the orchestrator
+type RoutingExplanation struct {
+	Classification   Classification `json:"classification"`
+	Action           string         `json:"action"`
+	Agent            string         `json:"agent,omitempty"`
+	UsedDefaultAgent bool           `json:"used_default_agent"`
+
+	// Collaborators lists every agent that would run when collaboration applies
+	Collaborators []string `json:"collaborators,omitempty"`
+}
+
 // OrchestratorStats holds orchestrator statistics
 type OrchestratorStats struct {
-	ActiveAnalyses   int64    `json:"active_analyses"`
-	MaxConcurrent    int      `json:"max_concurrent"`
-	TotalProcessed   int64    `json:"total_processed"`
-	TotalErrors      int64    `json:"total_errors"`
-	RegisteredAgents int      `json:"registered_agents"`
-	SubAgents        []string `json:"sub_agents"`
+	ActiveAnalyses        int64    `json:"active_analyses"`
+	MaxConcurrent         int      `json:"max_concurrent"`
+	TotalProcessed        int64    `json:"total_processed"`
+	TotalErrors           int64    `json:"total_errors"`
+	CollaborativeAnalyses int64    `json:"collaborative_analyses"`
+	CollaborationMaxRoles int      `json:"collaboration_max_roles"`
+	RegisteredAgents      int      `json:"registered_agents"`
+	SubAgents             []string `json:"sub_agents"`
+	FollowUps             int64    `json:"follow_ups"`
+
+	// Analysis queue
+	Scheduler SchedulerStats `json:"scheduler"`
+
+	// Spend guardrails
+	Budget           BudgetStats          `json:"budget"`
+	Circuit          CircuitStats         `json:"circuit"`
+	FallbackAgent    string               `json:"fallback_agent,omitempty"`
+	FallbackAnalyses int64                `json:"fallback_analyses"`
+	SkippedByReason  map[SkipReason]int64 `json:"skipped_by_reason"`
+	RecentSkipped    []SkippedAnalysis    `json:"recent_skipped"`
+}
+
+// monitorLabel identifies an event in scheduler logs and stats
+func monitorLabel(event *types.AlertEvent) string {
+	if event.Payload.MonitorName != "" {
+		return fmt.Sprintf("monitor %d (%s)", event.Payload.MonitorID, event.Payload.MonitorName)
+	}
+	return fmt.Sprintf("monitor %d", event.Payload.MonitorID)
+}
+
+// maxRecentSkipped caps the recent-skips list reported in stats
+const maxRecentSkipped = 100
+
+// SkippedAnalysis records an alert that was not analyzed (or was diverted to the fallback agent)
+type SkippedAnalysis struct {
+	MonitorID   int64      `json:"monitor_id"`
+	MonitorName string     `json:"monitor_name"`
+	Account     string     `json:"account"`
+	Reason      SkipReason `json:"reason"`
+	Detail      string     `json:"detail"`
+	At          time.Time  `json:"at"`
 }
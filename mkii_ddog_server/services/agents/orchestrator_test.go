@@ -2,6 +2,7 @@ package agents
 
 import (
 	"context"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -338,6 +339,154 @@ func TestAgentOrchestrator_NoAgentAvailable(t *testing.T) {
 	}
 }
 
+func TestAgentOrchestrator_Explain(t *testing.T) {
+	orch := NewAgentOrchestrator(DefaultOrchestratorConfig())
+	orch.RegisterAgent(newMockAgent("db-agent", RoleDatabase))
+	orch.SetDefaultAgent(newMockAgent("default-agent", RoleGeneral))
+
+	tests := []struct {
+		name        string
+		payload     types.AlertPayload
+		action      string
+		agent       string
+		usedDefault bool
+	}{
+		{"alert routed to specialist", types.AlertPayload{AlertStatus: "Alert", Service: "mysql-primary"}, RoutingActionAnalyze, "db-agent", false},
+		{"recovery", types.AlertPayload{AlertStatus: "OK", Service: "mysql-primary"}, RoutingActionRecover, "db-agent", false},
+		{"no data is skipped", types.AlertPayload{AlertStatus: "No Data", MonitorType: "apm"}, RoutingActionSkip, "default-agent", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orch.Explain(&types.AlertEvent{Payload: tt.payload})
+			if got.Action != tt.action {
+				t.Errorf("expected action %s, got %s", tt.action, got.Action)
+			}
+			if got.Agent != tt.agent || got.UsedDefaultAgent != tt.usedDefault {
+				t.Errorf("expected agent %s (default=%v), got %s (default=%v)",
+					tt.agent, tt.usedDefault, got.Agent, got.UsedDefaultAgent)
+			}
+			if got.Classification.Rule == "" {
+				t.Error("expected classification rule to be reported")
+			}
+		})
+	}
+}
+
+func TestAgentOrchestrator_CollaborativeAnalysis(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.CollaborationMaxRoles = 2
+	orch := NewAgentOrchestrator(config)
+
+	dbAgent := newMockAgent("db", RoleDatabase)
+	dbAgent.concludeResult = &AnalysisResult{
+		Success:         true,
+		AgentRole:       RoleDatabase,
+		RootCause:       "connection pool exhausted",
+		Summary:         "db saturated",
+		Findings:        []Finding{{Source: "dbm", Summary: "pool at 100%"}},
+		Recommendations: []string{"Increase pool size", "Add a read replica"},
+	}
+	appAgent := newMockAgent("app", RoleApplication)
+	appAgent.concludeResult = &AnalysisResult{
+		Success:         true,
+		AgentRole:       RoleApplication,
+		RootCause:       "retry storm from checkout",
+		Summary:         "app retries",
+		Findings:        []Finding{{Source: "apm", Summary: "5xx spike"}},
+		Recommendations: []string{"increase  pool size", "Add jitter to retries"},
+	}
+	orch.RegisterAgent(dbAgent)
+	orch.RegisterAgent(appAgent)
+
+	event := &types.AlertEvent{
+		Payload: types.AlertPayload{
+			MonitorID:   42,
+			AlertStatus: "Alert",
+			Service:     "postgres-primary",
+			Hostname:    "api-server-01",
+		},
+	}
+
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if atomic.LoadInt64(&dbAgent.concludeCalls) != 1 || atomic.LoadInt64(&appAgent.concludeCalls) != 1 {
+		t.Fatal("expected both specialists to run")
+	}
+	if !result.Success || result.AgentRole != RoleDatabase {
+		t.Errorf("expected successful database-led result, got success=%v role=%s", result.Success, result.AgentRole)
+	}
+	if result.RootCause != "connection pool exhausted" {
+		t.Errorf("expected primary root cause, got %q", result.RootCause)
+	}
+	if len(result.Contributors) != 2 {
+		t.Errorf("expected 2 contributors, got %d", len(result.Contributors))
+	}
+	if len(result.Findings) != 2 || result.Findings[0].AgentRole != RoleDatabase || result.Findings[1].AgentRole != RoleApplication {
+		t.Errorf("expected findings attributed to each role, got %+v", result.Findings)
+	}
+	if len(result.Recommendations) != 3 || result.Recommendations[0] != "[database, application] Increase pool size" {
+		t.Errorf("expected deduplicated, attributed recommendations, got %v", result.Recommendations)
+	}
+	if len(result.Conflicts) != 1 {
+		t.Errorf("expected root cause conflict note, got %v", result.Conflicts)
+	}
+	if stats := orch.Stats(); stats.CollaborativeAnalyses != 1 {
+		t.Errorf("expected 1 collaborative analysis, got %d", stats.CollaborativeAnalyses)
+	}
+
+	explanation := orch.Explain(event)
+	if len(explanation.Collaborators) != 2 {
+		t.Errorf("expected explain to list collaborators, got %v", explanation.Collaborators)
+	}
+}
+
+func TestAgentOrchestrator_CollaborationSharesDefaultAgent(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.CollaborationMaxRoles = 3
+	orch := NewAgentOrchestrator(config)
+
+	defaultAgent := newMockAgent("default", RoleGeneral)
+	orch.SetDefaultAgent(defaultAgent)
+
+	// Both matched roles fall back to the same default agent, so it runs once
+	event := &types.AlertEvent{
+		Payload: types.AlertPayload{AlertStatus: "Alert", Service: "postgres-primary", Hostname: "api-01"},
+	}
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if calls := atomic.LoadInt64(&defaultAgent.concludeCalls); calls != 1 {
+		t.Errorf("expected default agent to run once, ran %d times", calls)
+	}
+	if len(result.Contributors) != 0 {
+		t.Errorf("single-agent analysis should not report contributors, got %d", len(result.Contributors))
+	}
+}
+
+func TestSynthesize_AllFailed(t *testing.T) {
+	event := &types.AlertEvent{Payload: types.AlertPayload{MonitorID: 7}}
+	outcomes := []specialistOutcome{
+		{candidate: RoleCandidate{Role: RoleDatabase}, agent: "db", result: &AnalysisResult{Success: false, Error: "sidecar down"}},
+		{candidate: RoleCandidate{Role: RoleNetwork}, agent: "net", err: context.Canceled},
+	}
+
+	result := synthesize(event, nil, outcomes, time.Now())
+	if result.Success {
+		t.Fatal("expected failure when no specialist succeeded")
+	}
+	if result.Error != "database: sidecar down; network: context canceled" {
+		t.Errorf("unexpected error summary: %q", result.Error)
+	}
+	if result.AgentRole != RoleDatabase {
+		t.Errorf("expected top-ranked role, got %s", result.AgentRole)
+	}
+}
+
 func TestDefaultOrchestratorConfig(t *testing.T) {
 	config := DefaultOrchestratorConfig()
 
@@ -362,3 +511,193 @@ func TestNewAgentOrchestrator_DefaultValues(t *testing.T) {
 		t.Errorf("Expected default MaxConcurrent 3, got %d", stats.MaxConcurrent)
 	}
 }
+
+func TestAgentOrchestrator_BudgetSkips(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.Budget = BudgetConfig{MonitorCooldown: time.Hour}
+	orch := NewAgentOrchestrator(config)
+
+	agent := newMockAgent("default", RoleGeneral)
+	orch.SetDefaultAgent(agent)
+
+	event := &types.AlertEvent{
+		AccountName: "prod",
+		Payload:     types.AlertPayload{MonitorID: 9, MonitorName: "CPU high", AlertStatus: "Alert"},
+	}
+
+	if result, err := orch.Analyze(context.Background(), event); err != nil || result.Skipped {
+		t.Fatalf("first analysis should run: err=%v result=%+v", err, result)
+	}
+
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("skip should not be an error: %v", err)
+	}
+	if !result.Skipped || result.SkipReason != SkipMonitorCooldown {
+		t.Errorf("expected monitor_cooldown skip, got %+v", result)
+	}
+	if calls := atomic.LoadInt64(&agent.concludeCalls); calls != 1 {
+		t.Errorf("agent should have run once, ran %d times", calls)
+	}
+
+	stats := orch.Stats()
+	if stats.SkippedByReason[SkipMonitorCooldown] != 1 {
+		t.Errorf("expected 1 cooldown skip, got %v", stats.SkippedByReason)
+	}
+	if len(stats.RecentSkipped) != 1 || stats.RecentSkipped[0].Account != "prod" {
+		t.Errorf("unexpected recent skips: %+v", stats.RecentSkipped)
+	}
+	if stats.Budget.MonitorsInCooldown != 1 {
+		t.Errorf("expected 1 monitor in cooldown, got %d", stats.Budget.MonitorsInCooldown)
+	}
+}
+
+func TestAgentOrchestrator_FailedAnalysisReleasesCooldown(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.Budget = BudgetConfig{MonitorCooldown: time.Hour}
+	orch := NewAgentOrchestrator(config)
+
+	agent := newMockAgent("default", RoleGeneral)
+	agent.concludeResult = &AnalysisResult{Success: false, AgentRole: RoleGeneral, Summary: "sidecar error"}
+	orch.SetDefaultAgent(agent)
+
+	event := &types.AlertEvent{
+		AccountName: "prod",
+		Payload:     types.AlertPayload{MonitorID: 9, MonitorName: "CPU high", AlertStatus: "Alert"},
+	}
+
+	for i := 0; i < 2; i++ {
+		if result, _ := orch.Analyze(context.Background(), event); result == nil || result.Skipped {
+			t.Fatalf("analysis %d should run after a failure, got %+v", i, result)
+		}
+	}
+	if stats := orch.Stats(); stats.Budget.MonitorsInCooldown != 0 {
+		t.Errorf("expected no monitor in cooldown, got %d", stats.Budget.MonitorsInCooldown)
+	}
+}
+
+func TestAgentOrchestrator_CircuitFallback(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.Budget = BudgetConfig{}
+	config.CircuitThreshold = 2
+	config.CircuitCooldown = time.Hour
+	orch := NewAgentOrchestrator(config)
+
+	failing := newMockAgent("claude", RoleGeneral)
+	failing.concludeResult = &AnalysisResult{Success: false, Error: "sidecar unavailable"}
+	orch.SetDefaultAgent(failing)
+
+	event := &types.AlertEvent{
+		Payload: types.AlertPayload{MonitorID: 5, MonitorName: "DB connections", AlertStatus: "Alert"},
+	}
+
+	for i := 0; i < 2; i++ {
+		orch.Analyze(context.Background(), event)
+	}
+
+	// Without a fallback the open circuit skips analysis
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.Skipped || result.SkipReason != SkipNoFallbackConfigured {
+		t.Errorf("expected no_fallback_agent skip, got %+v", result)
+	}
+
+	orch.SetFallbackAgent(NewHeuristicAgent(orch.Classifier()))
+	result, err = orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Skipped || !result.Success {
+		t.Errorf("fallback agent should produce a result, got %+v", result)
+	}
+	if calls := atomic.LoadInt64(&failing.concludeCalls); calls != 2 {
+		t.Errorf("sidecar agent should not be called while open, called %d times", calls)
+	}
+
+	stats := orch.Stats()
+	if stats.Circuit.State != CircuitOpen {
+		t.Errorf("expected open circuit, got %s", stats.Circuit.State)
+	}
+	if stats.FallbackAnalyses != 1 || stats.SkippedByReason[SkipCircuitOpen] != 1 {
+		t.Errorf("unexpected fallback stats: fallback=%d skips=%v", stats.FallbackAnalyses, stats.SkippedByReason)
+	}
+}
+
+func TestAgentOrchestrator_QueueTimeoutLeavesCircuitClosed(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.Budget = BudgetConfig{}
+	config.MaxConcurrent = 1
+	config.CircuitThreshold = 1
+	config.CircuitCooldown = time.Hour
+	orch := NewAgentOrchestrator(config)
+
+	agent := newMockAgent("claude", RoleGeneral)
+	orch.SetDefaultAgent(agent)
+
+	// Hold the only slot so the analysis times out in the queue
+	release, err := orch.scheduler.Acquire(context.Background(), 0, "busy")
+	if err != nil {
+		t.Fatalf("acquire: %v", err)
+	}
+	defer release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = orch.Analyze(ctx, &types.AlertEvent{
+		Payload: types.AlertPayload{MonitorID: 2, MonitorName: "queued", AlertStatus: "Alert"},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the queued analysis to time out, got %v", err)
+	}
+	if stats := orch.Stats().Circuit; stats.State != CircuitClosed || stats.ConsecutiveFailures != 0 {
+		t.Errorf("a queue timeout must not count against the sidecar, got %+v", stats)
+	}
+	if calls := atomic.LoadInt64(&agent.planCalls); calls != 0 {
+		t.Errorf("expected the agent never to run, got %d plan calls", calls)
+	}
+}
+
+// localMockAgent is a mockAgent that runs without the sidecar
+type localMockAgent struct {
+	*mockAgent
+}
+
+func (m localMockAgent) Local() bool {
+	return true
+}
+
+func TestAgentOrchestrator_LocalAgentBypassesCircuit(t *testing.T) {
+	config := DefaultOrchestratorConfig()
+	config.Budget = BudgetConfig{}
+	config.CircuitThreshold = 1
+	config.CircuitCooldown = time.Hour
+	orch := NewAgentOrchestrator(config)
+
+	failing := newMockAgent("claude", RoleGeneral)
+	failing.concludeResult = &AnalysisResult{Success: false, Error: "sidecar unavailable"}
+	orch.SetDefaultAgent(failing)
+	watchdog := localMockAgent{newMockAgent("watchdog-story", RoleWatchdog)}
+	orch.RegisterAgent(watchdog)
+
+	orch.Analyze(context.Background(), &types.AlertEvent{
+		Payload: types.AlertPayload{MonitorID: 5, MonitorName: "DB connections", AlertStatus: "Alert"},
+	})
+	if state := orch.Stats().Circuit.State; state != CircuitOpen {
+		t.Fatalf("expected open circuit, got %s", state)
+	}
+
+	result, err := orch.Analyze(context.Background(), &types.AlertEvent{
+		Payload: types.AlertPayload{MonitorID: 6, MonitorName: "[Watchdog] Latency increase", MonitorType: "watchdog", AlertStatus: "Alert"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Skipped || !result.Success || atomic.LoadInt64(&watchdog.concludeCalls) != 1 {
+		t.Errorf("local agent should run while the circuit is open, got %+v", result)
+	}
+	if state := orch.Stats().Circuit.State; state != CircuitOpen {
+		t.Errorf("local agent success must not close the circuit, got %s", state)
+	}
+}
@@ -0,0 +1,106 @@
+package agents
+
+import (
+	"context"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// RecoveryPriority ranks recovery notifications above every analysis; they
+// are quick sidecar calls that close out notebooks.
+const RecoveryPriority = 1000
+
+// priorityScores maps Datadog priority (P1-P5, also "1"-"5", normal/low)
+var priorityScores = map[string]int{
+	"p1": 50, "1": 50,
+	"p2": 40, "2": 40,
+	"p3": 30, "3": 30, "normal": 30,
+	"p4": 20, "4": 20,
+	"p5": 10, "5": 10, "low": 10,
+}
+
+// severityScores maps URGENCY / IMPACT values from custom webhook templates
+var severityScores = map[string]int{
+	"critical": 20, "sev1": 20, "1": 20,
+	"high": 15, "sev2": 15, "2": 15,
+	"medium": 8, "moderate": 8, "sev3": 8, "3": 8,
+	"low": 0, "sev4": 0, "4": 0,
+}
+
+// defaultPriorityScore is used when an alert carries no priority at all
+const defaultPriorityScore = 25
+
+// importantMonitorBonus is added for monitors listed in AGENT_IMPORTANT_MONITORS
+// or tagged as critical (tier:1, tier:critical, critical:true)
+const importantMonitorBonus = 25
+
+// PriorityAdjuster adds history-aware priority on top of AnalysisPriority;
+// implemented by *baselines.Engine (alerts that deviate from the monitor's
+// baseline run sooner)
+type PriorityAdjuster interface {
+	AdjustPriority(ctx context.Context, event *types.AlertEvent) int
+}
+
+// ImportantMonitorsFromEnv parses AGENT_IMPORTANT_MONITORS (comma-separated monitor IDs)
+func ImportantMonitorsFromEnv() []int64 {
+	var ids []int64
+	for _, field := range strings.Split(os.Getenv("AGENT_IMPORTANT_MONITORS"), ",") {
+		if id, err := strconv.ParseInt(strings.TrimSpace(field), 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// AnalysisPriority scores an alert for the analysis scheduler (higher runs first).
+// It combines Datadog priority (falling back to a priority:pN tag), URGENCY,
+// IMPACT, Alert vs Warn, and monitor importance.
+func AnalysisPriority(event *types.AlertEvent, important map[int64]bool) int {
+	p := event.Payload
+
+	priority := strings.ToLower(strings.TrimSpace(p.Priority))
+	if _, ok := priorityScores[priority]; !ok {
+		priority = tagValue(p.Tags, "priority")
+	}
+	score, ok := priorityScores[priority]
+	if !ok {
+		score = defaultPriorityScore
+	}
+
+	score += severityScores[strings.ToLower(strings.TrimSpace(p.Urgency))]
+	score += severityScores[strings.ToLower(strings.TrimSpace(p.Impact))]
+
+	if p.AlertStatus == "Alert" || p.AlertState == "Triggered" || p.AlertState == "Alert" {
+		score += 10
+	}
+
+	if important[p.MonitorID] || isCriticalMonitor(p.Tags) {
+		score += importantMonitorBonus
+	}
+
+	return score
+}
+
+// isCriticalMonitor checks tags that mark a monitor as business-critical
+func isCriticalMonitor(tags []string) bool {
+	switch tagValue(tags, "tier") {
+	case "1", "0", "critical":
+		return true
+	}
+	return tagValue(tags, "critical") == "true"
+}
+
+// tagValue returns the lower-cased value of the first key:value tag with key
+func tagValue(tags []string, key string) string {
+	prefix := key + ":"
+	for _, tag := range tags {
+		tag = strings.ToLower(tag)
+		if strings.HasPrefix(tag, prefix) {
+			return strings.TrimPrefix(tag, prefix)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,287 @@
+package agents
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ProgressEventType identifies a step in an analysis
+type ProgressEventType string
+
+const (
+	ProgressAnalysisStarted   ProgressEventType = "analysis_started"
+	ProgressQueued            ProgressEventType = "queued"
+	ProgressAgentStarted      ProgressEventType = "agent_started"
+	ProgressPlan              ProgressEventType = "plan"
+	ProgressSubQueryStarted   ProgressEventType = "subquery_started"
+	ProgressSubQueryFinished  ProgressEventType = "subquery_finished"
+	ProgressFinding           ProgressEventType = "finding"
+	ProgressConcluded         ProgressEventType = "concluded"
+	ProgressActionProposed    ProgressEventType = "remediation_proposed"
+	ProgressAnalysisSkipped   ProgressEventType = "analysis_skipped"
+	ProgressAnalysisCompleted ProgressEventType = "analysis_completed"
+)
+
+// Stream retention: completed streams stay replayable for a while so a client
+// that connects late still sees the whole investigation
+const (
+	progressHistoryLimit   = 500
+	progressRetention      = 30 * time.Minute
+	progressMaxStreams     = 200
+	progressSubscriberSize = 64
+)
+
+// ProgressEvent is one structured step of an analysis
+type ProgressEvent struct {
+	AnalysisID string            `json:"analysis_id"`
+	Seq        int               `json:"seq"`
+	Type       ProgressEventType `json:"type"`
+	MonitorID  int64             `json:"monitor_id"`
+	Agent      string            `json:"agent,omitempty"`
+	Iteration  int               `json:"iteration,omitempty"`
+	Message    string            `json:"message"`
+	Data       map[string]any    `json:"data,omitempty"`
+	Timestamp  time.Time         `json:"timestamp"`
+}
+
+// AnalysisInfo summarizes an analysis known to the progress bus
+type AnalysisInfo struct {
+	AnalysisID  string     `json:"analysis_id"`
+	EventID     int64      `json:"event_id"`
+	MonitorID   int64      `json:"monitor_id"`
+	MonitorName string     `json:"monitor_name"`
+	Status      string     `json:"status"` // running, completed
+	Events      int        `json:"events"`
+	StartedAt   time.Time  `json:"started_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// analysisStream holds one analysis's event history and live subscribers
+type analysisStream struct {
+	info        AnalysisInfo
+	events      []ProgressEvent
+	seq         int
+	subscribers map[chan ProgressEvent]struct{}
+}
+
+// ProgressBus is an in-process pub/sub for analysis progress. Subscribers
+// receive the history so far, then live events; their channel is closed when
+// the analysis completes. Slow subscribers drop events rather than block analysis.
+type ProgressBus struct {
+	streams map[string]*analysisStream
+	mu      sync.Mutex
+}
+
+// NewProgressBus creates an empty progress bus
+func NewProgressBus() *ProgressBus {
+	return &ProgressBus{streams: make(map[string]*analysisStream)}
+}
+
+// Start registers a new analysis stream
+func (b *ProgressBus) Start(analysisID string, eventID, monitorID int64, monitorName string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pruneLocked(time.Now())
+	b.streams[analysisID] = &analysisStream{
+		info: AnalysisInfo{
+			AnalysisID:  analysisID,
+			EventID:     eventID,
+			MonitorID:   monitorID,
+			MonitorName: monitorName,
+			Status:      "running",
+			StartedAt:   time.Now(),
+		},
+		subscribers: make(map[chan ProgressEvent]struct{}),
+	}
+}
+
+// Publish appends an event to its analysis stream and fans it out
+func (b *ProgressBus) Publish(event ProgressEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams[event.AnalysisID]
+	if !ok || stream.info.CompletedAt != nil {
+		return
+	}
+
+	stream.seq++
+	event.Seq = stream.seq
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	stream.events = append(stream.events, event)
+	if len(stream.events) > progressHistoryLimit {
+		stream.events = stream.events[len(stream.events)-progressHistoryLimit:]
+	}
+	stream.info.Events = stream.seq
+
+	for ch := range stream.subscribers {
+		select {
+		case ch <- event:
+		default: // slow subscriber; it can re-read history on reconnect
+		}
+	}
+}
+
+// Finish marks an analysis complete and closes its subscribers
+func (b *ProgressBus) Finish(analysisID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams[analysisID]
+	if !ok || stream.info.CompletedAt != nil {
+		return
+	}
+
+	now := time.Now()
+	stream.info.Status = "completed"
+	stream.info.CompletedAt = &now
+	for ch := range stream.subscribers {
+		close(ch)
+	}
+	stream.subscribers = nil
+}
+
+// Subscribe returns events after afterSeq and a channel of live events.
+// The channel is nil when the analysis has already completed. Call cancel
+// when done listening. ok is false for unknown analyses.
+func (b *ProgressBus) Subscribe(analysisID string, afterSeq int) (history []ProgressEvent, live <-chan ProgressEvent, cancel func(), ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams[analysisID]
+	if !ok {
+		return nil, nil, func() {}, false
+	}
+
+	for _, event := range stream.events {
+		if event.Seq > afterSeq {
+			history = append(history, event)
+		}
+	}
+
+	if stream.info.CompletedAt != nil {
+		return history, nil, func() {}, true
+	}
+
+	ch := make(chan ProgressEvent, progressSubscriberSize)
+	stream.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	cancel = func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			if _, subscribed := stream.subscribers[ch]; subscribed {
+				delete(stream.subscribers, ch)
+				close(ch)
+			}
+		})
+	}
+	return history, ch, cancel, true
+}
+
+// Info returns metadata for one analysis
+func (b *ProgressBus) Info(analysisID string) (AnalysisInfo, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	stream, ok := b.streams[analysisID]
+	if !ok {
+		return AnalysisInfo{}, false
+	}
+	return stream.info, true
+}
+
+// List returns known analyses, newest first
+func (b *ProgressBus) List() []AnalysisInfo {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.pruneLocked(time.Now())
+	list := make([]AnalysisInfo, 0, len(b.streams))
+	for _, stream := range b.streams {
+		list = append(list, stream.info)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].StartedAt.After(list[j].StartedAt)
+	})
+	return list
+}
+
+// pruneLocked drops expired completed streams and, past the cap, the oldest
+// completed ones (caller holds mu)
+func (b *ProgressBus) pruneLocked(now time.Time) {
+	var completed []*analysisStream
+	for id, stream := range b.streams {
+		if stream.info.CompletedAt == nil {
+			continue
+		}
+		if now.Sub(*stream.info.CompletedAt) > progressRetention {
+			delete(b.streams, id)
+			continue
+		}
+		completed = append(completed, stream)
+	}
+
+	if excess := len(b.streams) - progressMaxStreams; excess > 0 {
+		sort.Slice(completed, func(i, j int) bool {
+			return completed[i].info.CompletedAt.Before(*completed[j].info.CompletedAt)
+		})
+		for i := 0; i < excess && i < len(completed); i++ {
+			delete(b.streams, completed[i].info.AnalysisID)
+		}
+	}
+}
+
+// progressReporter publishes events for one analysis (and one agent within it)
+type progressReporter struct {
+	bus        *ProgressBus
+	analysisID string
+	monitorID  int64
+	agent      string
+}
+
+type progressKey struct{}
+
+// withProgress attaches a reporter to ctx so the RLM loop can emit events
+func withProgress(ctx context.Context, reporter *progressReporter) context.Context {
+	return context.WithValue(ctx, progressKey{}, reporter)
+}
+
+// progressFrom returns the reporter attached to ctx, or nil
+func progressFrom(ctx context.Context) *progressReporter {
+	reporter, _ := ctx.Value(progressKey{}).(*progressReporter)
+	return reporter
+}
+
+// forAgent returns a copy of the reporter that attributes events to agent
+func (p *progressReporter) forAgent(agent string) *progressReporter {
+	if p == nil {
+		return nil
+	}
+	scoped := *p
+	scoped.agent = agent
+	return &scoped
+}
+
+// emit publishes an event; safe on a nil reporter
+func (p *progressReporter) emit(eventType ProgressEventType, iteration int, message string, data map[string]any) {
+	if p == nil || p.bus == nil {
+		return
+	}
+	p.bus.Publish(ProgressEvent{
+		AnalysisID: p.analysisID,
+		Type:       eventType,
+		MonitorID:  p.monitorID,
+		Agent:      p.agent,
+		Iteration:  iteration,
+		Message:    message,
+		Data:       data,
+	})
+}
@@ -0,0 +1,171 @@
+package agents
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+func TestProgressBus_HistoryThenLive(t *testing.T) {
+	bus := NewProgressBus()
+	bus.Start("a1", 1, 42, "CPU high")
+
+	bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressAnalysisStarted})
+	bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressPlan})
+
+	history, live, cancel, ok := bus.Subscribe("a1", 0)
+	defer cancel()
+	if !ok {
+		t.Fatal("expected analysis a1 to exist")
+	}
+	if len(history) != 2 || history[0].Seq != 1 || history[1].Seq != 2 {
+		t.Fatalf("expected history seq 1,2, got %+v", history)
+	}
+
+	bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressFinding})
+	select {
+	case event := <-live:
+		if event.Type != ProgressFinding || event.Seq != 3 {
+			t.Errorf("expected live finding seq 3, got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for live event")
+	}
+
+	bus.Finish("a1")
+	if _, open := <-live; open {
+		t.Error("expected live channel to close when the analysis finishes")
+	}
+
+	info, _ := bus.Info("a1")
+	if info.Status != "completed" || info.Events != 3 {
+		t.Errorf("expected completed with 3 events, got %+v", info)
+	}
+}
+
+func TestProgressBus_LateSubscriberResumes(t *testing.T) {
+	bus := NewProgressBus()
+	bus.Start("a1", 1, 42, "CPU high")
+	for i := 0; i < 4; i++ {
+		bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressFinding})
+	}
+	bus.Finish("a1")
+
+	// Publishing after Finish is ignored
+	bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressFinding})
+
+	history, live, cancel, ok := bus.Subscribe("a1", 2)
+	defer cancel()
+	if !ok {
+		t.Fatal("expected completed analysis to stay replayable")
+	}
+	if live != nil {
+		t.Error("expected nil live channel for a completed analysis")
+	}
+	if len(history) != 2 || history[0].Seq != 3 {
+		t.Errorf("expected events after seq 2, got %+v", history)
+	}
+
+	if _, _, _, ok := bus.Subscribe("missing", 0); ok {
+		t.Error("expected unknown analysis to report not found")
+	}
+}
+
+func TestAgentOrchestrator_EmitsProgress(t *testing.T) {
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1, RLMMaxIterations: 2})
+	orch.SetDefaultAgent(newMockAgent("mock", RoleGeneral))
+
+	event := &types.AlertEvent{
+		ID:      7,
+		Payload: types.AlertPayload{MonitorID: 42, MonitorName: "CPU high", AlertStatus: "Alert"},
+	}
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+	if result.AnalysisID == "" {
+		t.Fatal("expected result to carry an analysis ID")
+	}
+
+	history, _, cancel, ok := orch.Progress().Subscribe(result.AnalysisID, 0)
+	defer cancel()
+	if !ok {
+		t.Fatal("expected progress stream for the analysis")
+	}
+
+	var got []ProgressEventType
+	for _, e := range history {
+		got = append(got, e.Type)
+	}
+	want := []ProgressEventType{ProgressAnalysisStarted, ProgressAgentStarted, ProgressPlan, ProgressConcluded, ProgressAnalysisCompleted}
+	for _, w := range want {
+		found := false
+		for _, g := range got {
+			if g == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected %s event, got %v", w, got)
+		}
+	}
+	if got[0] != ProgressAnalysisStarted || got[len(got)-1] != ProgressAnalysisCompleted {
+		t.Errorf("expected stream to start with analysis_started and end with analysis_completed, got %v", got)
+	}
+}
+
+func TestHandler_StreamAnalysisSSE(t *testing.T) {
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1})
+	bus := orch.Progress()
+	bus.Start("a1", 1, 42, "CPU high")
+	bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressAnalysisStarted, Message: "started"})
+
+	handler := NewHandler(orch, nil)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/agents/analyses/{id}/stream", handler.StreamAnalysis)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/agents/analyses/a1/stream")
+	if err != nil {
+		t.Fatalf("GET stream: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	go func() {
+		bus.Publish(ProgressEvent{AnalysisID: "a1", Type: ProgressFinding, Message: "found"})
+		bus.Finish("a1")
+	}()
+
+	var events []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := scanner.Text(); strings.HasPrefix(line, "event: ") {
+			events = append(events, strings.TrimPrefix(line, "event: "))
+		}
+	}
+
+	want := []string{"analysis_started", "finding", "end"}
+	if strings.Join(events, ",") != strings.Join(want, ",") {
+		t.Errorf("expected events %v, got %v", want, events)
+	}
+
+	missing, err := http.Get(server.URL + "/v1/agents/analyses/nope/stream")
+	if err != nil {
+		t.Fatalf("GET missing stream: %v", err)
+	}
+	missing.Body.Close()
+	if missing.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for unknown analysis, got %d", missing.StatusCode)
+	}
+}
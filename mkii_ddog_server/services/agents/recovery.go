@@ -0,0 +1,288 @@
+package agents
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+)
+
+// RecoverableAgent is an agent that can handle a monitor's recovery: close
+// out the incident it analyzed and summarize what changed
+type RecoverableAgent interface {
+	Agent
+
+	// Recover handles a recovery event. rc links it to the open analysis (if
+	// any); the outcome's Summary is stored on that analysis.
+	Recover(ctx context.Context, event *types.AlertEvent, rc RecoveryContext) (*RecoveryOutcome, error)
+}
+
+// RecoveryContext links a recovery event to the alert it resolves
+type RecoveryContext struct {
+	// Analysis is the newest open analysis for the monitor and scope (nil when
+	// the alert was never analyzed or the store is disabled)
+	Analysis *AnalysisRecord
+
+	TriggeredAt   time.Time // when the oldest open alert fired (zero if unknown)
+	RecoveredAt   time.Time
+	TimeToResolve time.Duration
+}
+
+// RecoveryOutcome is what an agent reports after handling a recovery
+type RecoveryOutcome struct {
+	Summary     string // what changed between alert and recovery
+	NotebookURL string // notebook marked resolved, if any
+	TokensUsed  int64
+}
+
+// Resolution records how an analyzed alert was resolved
+type Resolution struct {
+	RecoveryEventID int64         `json:"recovery_event_id,omitempty"`
+	TriggeredAt     time.Time     `json:"triggered_at,omitempty"`
+	RecoveredAt     time.Time     `json:"recovered_at"`
+	TimeToResolve   time.Duration `json:"time_to_resolve,omitempty"`
+	Summary         string        `json:"summary"`
+	Agent           string        `json:"agent,omitempty"`
+	NotebookURL     string        `json:"notebook_url,omitempty"`
+}
+
+// Recover handles a monitor recovery. It finds the open analyses for the
+// monitor and scope, computes time-to-resolve from the first alert, lets a
+// RecoverableAgent summarize the recovery (and resolve its notebook), then
+// annotates every open analysis with the resolution.
+func (o *AgentOrchestrator) Recover(ctx context.Context, event *types.AlertEvent) (*AnalysisResult, error) {
+	// Recoveries are quick and jump the analysis queue
+	release, err := o.scheduler.Acquire(ctx, RecoveryPriority, monitorLabel(event)+"/recover")
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	startedAt := time.Now()
+	log.Printf("[AGENT-ORCH] Starting recovery for monitor %d (status: %s)",
+		event.Payload.MonitorID, event.Payload.AlertStatus)
+
+	open := o.openAnalyses(ctx, event)
+	rc := newRecoveryContext(event, open, startedAt)
+
+	result := &AnalysisResult{
+		MonitorID:   event.Payload.MonitorID,
+		MonitorName: event.Payload.MonitorName,
+		AlertStatus: event.Payload.AlertStatus,
+		StartedAt:   startedAt,
+	}
+	if rc.Analysis != nil {
+		result.AnalysisID = rc.Analysis.AnalysisID
+	}
+
+	agent := o.recoveryAgent(rc.Analysis)
+	if agent == nil {
+		result.Error = "no agent available for recovery"
+		result.CompletedAt = time.Now()
+		return result, nil
+	}
+	result.AgentRole = agent.Role()
+
+	outcome, recoverErr := agent.Recover(ctx, event, rc)
+	if outcome == nil {
+		outcome = &RecoveryOutcome{}
+	}
+	o.budget.RecordTokens(event.AccountName, outcome.TokensUsed)
+	if outcome.Summary == "" {
+		outcome.Summary = describeRecovery(event, rc)
+	}
+	if outcome.NotebookURL == "" && rc.Analysis != nil && rc.Analysis.Result != nil {
+		outcome.NotebookURL = rc.Analysis.Result.NotebookURL
+	}
+
+	resolution := Resolution{
+		RecoveryEventID: event.ID,
+		TriggeredAt:     rc.TriggeredAt,
+		RecoveredAt:     rc.RecoveredAt,
+		TimeToResolve:   rc.TimeToResolve,
+		Summary:         outcome.Summary,
+		Agent:           agent.Name(),
+		NotebookURL:     outcome.NotebookURL,
+	}
+	o.resolveAnalyses(ctx, open, resolution)
+
+	result.Success = recoverErr == nil
+	result.Summary = outcome.Summary
+	result.NotebookURL = outcome.NotebookURL
+	result.TokensUsed = outcome.TokensUsed
+	result.Resolution = &resolution
+	if recoverErr != nil {
+		result.Error = recoverErr.Error()
+	}
+	result.CompletedAt = time.Now()
+	result.Duration = result.CompletedAt.Sub(startedAt)
+
+	log.Printf("[AGENT-ORCH] Recovery for monitor %d handled by %s: success=%v, open_analyses=%d, time_to_resolve=%v",
+		event.Payload.MonitorID, agent.Name(), result.Success, len(open), rc.TimeToResolve)
+
+	return result, nil
+}
+
+// openAnalyses loads the unresolved analyses for the event's monitor and
+// scope. Lookup failures are logged; recovery proceeds without linking.
+func (o *AgentOrchestrator) openAnalyses(ctx context.Context, event *types.AlertEvent) []AnalysisRecord {
+	store := o.AnalysisStore()
+	if store == nil {
+		return nil
+	}
+
+	open, err := store.OpenAnalyses(ctx, event.Payload.MonitorID, event.Payload.Scope)
+	if err != nil {
+		log.Printf("[AGENT-ORCH] Failed to look up open analyses for monitor %d: %v", event.Payload.MonitorID, err)
+		return nil
+	}
+	return open
+}
+
+// resolveAnalyses annotates every open analysis with the resolution.
+// Storage failures are logged; they never fail the recovery.
+func (o *AgentOrchestrator) resolveAnalyses(ctx context.Context, open []AnalysisRecord, resolution Resolution) {
+	store := o.AnalysisStore()
+	if store == nil {
+		return
+	}
+
+	for _, record := range open {
+		if err := store.ResolveAnalysis(context.WithoutCancel(ctx), record.AnalysisID, resolution); err != nil {
+			log.Printf("[AGENT-ORCH] Failed to resolve analysis %s: %v", record.AnalysisID, err)
+		}
+	}
+}
+
+// recoveryAgent picks the agent that handled the open analysis if it can
+// recover, then the default agent, then any registered recoverable agent
+func (o *AgentOrchestrator) recoveryAgent(analysis *AnalysisRecord) RecoverableAgent {
+	var candidates []Agent
+	if analysis != nil {
+		candidates = append(candidates, o.followUpAgent(analysis))
+	}
+
+	o.mu.RLock()
+	candidates = append(candidates, o.defaultAgent)
+	for _, agent := range o.agents {
+		candidates = append(candidates, agent)
+	}
+	candidates = append(candidates, o.fallbackAgent)
+	o.mu.RUnlock()
+
+	for _, agent := range candidates {
+		if recoverable, ok := agent.(RecoverableAgent); ok {
+			return recoverable
+		}
+	}
+	return nil
+}
+
+// newRecoveryContext links the recovery to the open analyses (oldest first).
+// Time-to-resolve runs from the first alert of the incident.
+func newRecoveryContext(event *types.AlertEvent, open []AnalysisRecord, now time.Time) RecoveryContext {
+	rc := RecoveryContext{RecoveredAt: event.ReceivedAt}
+	if rc.RecoveredAt.IsZero() {
+		rc.RecoveredAt = now
+	}
+	if len(open) == 0 {
+		return rc
+	}
+
+	newest := open[len(open)-1]
+	rc.Analysis = &newest
+
+	first := open[0]
+	rc.TriggeredAt = first.CreatedAt
+	if first.Event != nil && !first.Event.ReceivedAt.IsZero() {
+		rc.TriggeredAt = first.Event.ReceivedAt
+	}
+	if rc.RecoveredAt.After(rc.TriggeredAt) {
+		rc.TimeToResolve = rc.RecoveredAt.Sub(rc.TriggeredAt).Round(time.Second)
+	}
+	return rc
+}
+
+// describeRecovery is the deterministic recovery summary used when no agent
+// (or no LLM) can write one: time-to-resolve, how the metric moved and the
+// root cause found when the alert fired
+func describeRecovery(event *types.AlertEvent, rc RecoveryContext) string {
+	name := utils.FirstNonEmpty(event.Payload.MonitorName, event.Payload.AlertTitleCustom, event.Payload.AlertTitle,
+		fmt.Sprintf("monitor %d", event.Payload.MonitorID))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s recovered", name)
+	if rc.TimeToResolve > 0 {
+		fmt.Fprintf(&b, " after %s", rc.TimeToResolve)
+	}
+	b.WriteString(".")
+
+	var alertValue string
+	if rc.Analysis != nil && rc.Analysis.Event != nil {
+		alertValue = rc.Analysis.Event.Payload.Value
+	}
+	if metric := valueChange(alertValue, event.Payload.Value); metric != "" {
+		metricName := utils.FirstNonEmpty(event.Payload.Metric, "The metric")
+		fmt.Fprintf(&b, " %s %s", metricName, metric)
+		if event.Payload.Threshold != "" {
+			fmt.Fprintf(&b, " (threshold %s)", event.Payload.Threshold)
+		}
+		b.WriteString(".")
+	}
+
+	if rc.Analysis != nil && rc.Analysis.Context.RootCause != "" {
+		rootCause := strings.TrimSpace(strings.SplitN(rc.Analysis.Context.RootCause, "\n", 2)[0])
+		fmt.Fprintf(&b, " Root cause identified at alert time: %s", truncate(rootCause, 200))
+	}
+	return b.String()
+}
+
+// valueChange describes how the monitored value moved between alert and recovery
+func valueChange(alertValue, recoveryValue string) string {
+	alertValue, recoveryValue = strings.TrimSpace(alertValue), strings.TrimSpace(recoveryValue)
+	switch {
+	case alertValue != "" && recoveryValue != "":
+		from, errFrom := strconv.ParseFloat(alertValue, 64)
+		to, errTo := strconv.ParseFloat(recoveryValue, 64)
+		if errFrom == nil && errTo == nil && from != 0 {
+			return fmt.Sprintf("went from %s to %s (%+.0f%%)", alertValue, recoveryValue, (to-from)/from*100)
+		}
+		return fmt.Sprintf("went from %s to %s", alertValue, recoveryValue)
+	case recoveryValue != "":
+		return "is now " + recoveryValue
+	}
+	return ""
+}
+
+// recoveryQuestion asks the agent for a short post-resolution summary
+func recoveryQuestion(event *types.AlertEvent, rc RecoveryContext) string {
+	question := "The monitor has recovered"
+	if rc.TimeToResolve > 0 {
+		question += fmt.Sprintf(" after %s", rc.TimeToResolve)
+	}
+	if event.Payload.Value != "" {
+		question += fmt.Sprintf(" (current value %s", event.Payload.Value)
+		if event.Payload.Threshold != "" {
+			question += ", threshold " + event.Payload.Threshold
+		}
+		question += ")"
+	}
+	return question + ". In at most three sentences, summarize what changed between the alert and the recovery " +
+		"and whether the root cause you identified explains the resolution. Do not repeat the original analysis."
+}
+
+// notebookIDFromURL extracts the notebook ID from a Datadog notebook URL
+func notebookIDFromURL(notebookURL string) string {
+	_, id, ok := strings.Cut(notebookURL, "/notebook/")
+	if !ok {
+		return ""
+	}
+	id, _, _ = strings.Cut(id, "/")
+	id, _, _ = strings.Cut(id, "?")
+	return id
+}
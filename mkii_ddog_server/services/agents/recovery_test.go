@@ -0,0 +1,147 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// recoveringAgent is a non-Claude agent that records the recovery context it was given
+type recoveringAgent struct {
+	conversationalAgent
+	got     *RecoveryContext
+	summary string
+	err     error
+}
+
+func (a *recoveringAgent) Recover(ctx context.Context, event *types.AlertEvent, rc RecoveryContext) (*RecoveryOutcome, error) {
+	a.got = &rc
+	return &RecoveryOutcome{Summary: a.summary, TokensUsed: 10}, a.err
+}
+
+func TestAgentOrchestrator_RecoverLinksOpenAnalysis(t *testing.T) {
+	agent := &recoveringAgent{conversationalAgent: conversationalAgent{name: "db-agent"}, summary: "Pool drained after the slow query was killed"}
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1, RLMMaxIterations: 3})
+	orch.SetDefaultAgent(agent)
+	orch.RegisterSubAgent(newMockSubAgent("db", "ok"))
+	store := NewMemoryAnalysisStore()
+	orch.SetAnalysisStore(store)
+
+	triggered := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	payload := types.AlertPayload{MonitorID: 42, MonitorName: "DB connections", AlertStatus: "Alert", Scope: "host:db-1"}
+	alert := &types.AlertEvent{ID: 1, Payload: payload, ReceivedAt: triggered}
+	analysis, err := orch.Analyze(context.Background(), alert)
+	if err != nil || !analysis.Success {
+		t.Fatalf("Analyze failed: %v (%+v)", err, analysis)
+	}
+
+	// Same monitor, other scope: must stay open
+	other := payload
+	other.Scope = "host:db-2"
+	otherAnalysis, err := orch.Analyze(context.Background(), &types.AlertEvent{ID: 2, Payload: other, ReceivedAt: triggered})
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	recovered := payload
+	recovered.AlertStatus = "OK"
+	recovery := &types.AlertEvent{ID: 3, Payload: recovered, ReceivedAt: triggered.Add(15 * time.Minute)}
+	result, err := orch.Recover(context.Background(), recovery)
+	if err != nil {
+		t.Fatalf("Recover failed: %v", err)
+	}
+
+	if !result.Success || result.AnalysisID != analysis.AnalysisID || result.Summary != agent.summary {
+		t.Fatalf("unexpected recovery result: %+v", result)
+	}
+	if agent.got == nil || agent.got.Analysis == nil || agent.got.Analysis.AnalysisID != analysis.AnalysisID {
+		t.Fatalf("agent was not given the open analysis: %+v", agent.got)
+	}
+	if agent.got.TimeToResolve != 15*time.Minute || result.Resolution.TimeToResolve != 15*time.Minute {
+		t.Fatalf("expected 15m time to resolve, got %v", agent.got.TimeToResolve)
+	}
+
+	record, err := store.GetAnalysis(context.Background(), analysis.AnalysisID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if record.Resolution == nil || record.Resolution.Summary != agent.summary || record.Resolution.RecoveryEventID != 3 {
+		t.Fatalf("analysis not annotated with resolution: %+v", record.Resolution)
+	}
+
+	open, _ := store.OpenAnalyses(context.Background(), 42, "host:db-2")
+	if len(open) != 1 || open[0].AnalysisID != otherAnalysis.AnalysisID {
+		t.Fatalf("analysis for other scope should stay open, got %+v", open)
+	}
+}
+
+func TestAgentOrchestrator_RecoverAgentErrorStillAnnotates(t *testing.T) {
+	agent := &recoveringAgent{conversationalAgent: conversationalAgent{name: "db-agent"}, err: errors.New("sidecar down")}
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1, RLMMaxIterations: 3})
+	orch.SetDefaultAgent(agent)
+	orch.RegisterSubAgent(newMockSubAgent("db", "ok"))
+	store := NewMemoryAnalysisStore()
+	orch.SetAnalysisStore(store)
+
+	payload := types.AlertPayload{MonitorID: 7, MonitorName: "Disk usage", AlertStatus: "Alert", Metric: "system.disk.in_use", Value: "0.95"}
+	analysis, err := orch.Analyze(context.Background(), &types.AlertEvent{ID: 1, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	payload.AlertStatus = "OK"
+	payload.Value = "0.60"
+	result, err := orch.Recover(context.Background(), &types.AlertEvent{ID: 2, Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || result.Error != "sidecar down" {
+		t.Fatalf("expected failed recovery, got %+v", result)
+	}
+	if !strings.Contains(result.Summary, "went from 0.95 to 0.60") || !strings.Contains(result.Summary, "connection pool exhausted") {
+		t.Fatalf("expected deterministic summary, got %q", result.Summary)
+	}
+
+	record, _ := store.GetAnalysis(context.Background(), analysis.AnalysisID)
+	if record.Resolution == nil || record.Resolution.Summary != result.Summary {
+		t.Fatalf("analysis not annotated: %+v", record.Resolution)
+	}
+}
+
+func TestAgentOrchestrator_RecoverWithoutRecoverableAgent(t *testing.T) {
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1})
+	orch.SetDefaultAgent(&conversationalAgent{name: "db-agent"})
+
+	event := &types.AlertEvent{ID: 1, Payload: types.AlertPayload{MonitorID: 1, AlertStatus: "OK"}}
+	result, err := orch.Recover(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Success || result.Error != "no agent available for recovery" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	// The heuristic fallback can always recover
+	orch.SetFallbackAgent(NewHeuristicAgent(orch.Classifier()))
+	result, _ = orch.Recover(context.Background(), event)
+	if !result.Success || !strings.Contains(result.Summary, "monitor 1 recovered") {
+		t.Fatalf("expected heuristic recovery, got %+v", result)
+	}
+}
+
+func TestNotebookIDFromURL(t *testing.T) {
+	cases := map[string]string{
+		"https://app.datadoghq.com/notebook/12345":         "12345",
+		"https://app.datadoghq.com/notebook/12345/foo?x=1": "12345",
+		"https://app.datadoghq.com/dashboard/abc":          "",
+	}
+	for url, want := range cases {
+		if got := notebookIDFromURL(url); got != want {
+			t.Errorf("notebookIDFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
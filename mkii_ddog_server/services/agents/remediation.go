@@ -0,0 +1,56 @@
+package agents
+
+import (
+	"context"
+	"log"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+)
+
+// RemediationSink interface at consumer side (interface ownership);
+// implemented by *remediation.Manager
+type RemediationSink interface {
+	Propose(ctx context.Context, proposal remediation.Proposal) (*remediation.Action, error)
+}
+
+// SetRemediation submits agent-proposed actions for human approval.
+// Without a sink, proposals are only reported on the analysis result.
+func (o *AgentOrchestrator) SetRemediation(sink RemediationSink) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.remediation = sink
+}
+
+// proposeRemediation submits the result's proposed actions and records the
+// resulting action IDs. Proposals that fail validation stay on the result
+// without an ActionID.
+func (o *AgentOrchestrator) proposeRemediation(ctx context.Context, analysisID string, event *types.AlertEvent, result *AnalysisResult) {
+	o.mu.RLock()
+	sink := o.remediation
+	o.mu.RUnlock()
+	if sink == nil || len(result.ProposedActions) == 0 {
+		return
+	}
+
+	progress := progressFrom(ctx)
+	for i := range result.ProposedActions {
+		proposal := &result.ProposedActions[i]
+		proposal.AnalysisID = analysisID
+		proposal.MonitorID = event.Payload.MonitorID
+		proposal.MonitorName = event.Payload.MonitorName
+		proposal.AccountName = event.AccountName
+
+		action, err := sink.Propose(context.WithoutCancel(ctx), *proposal)
+		if err != nil {
+			log.Printf("[AGENT-ORCH] Rejected %s proposal for monitor %d: %v", proposal.Type, event.Payload.MonitorID, err)
+			continue
+		}
+		proposal.ActionID = action.ID
+		progress.emit(ProgressActionProposed, 0, action.Summary, map[string]any{
+			"action_id": action.ID,
+			"type":      action.Type,
+			"status":    action.Status,
+		})
+	}
+}
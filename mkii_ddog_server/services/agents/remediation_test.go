@@ -0,0 +1,90 @@
+package agents
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+)
+
+// proposingAgent concludes immediately with the configured proposals
+type proposingAgent struct {
+	proposals []remediation.Proposal
+}
+
+func (a *proposingAgent) Name() string    { return "proposer" }
+func (a *proposingAgent) Role() AgentRole { return RoleInfrastructure }
+
+func (a *proposingAgent) Plan(ctx context.Context, event *types.AlertEvent, agentCtx AgentContext) AgentPlan {
+	return AgentPlan{Complete: true}
+}
+
+func (a *proposingAgent) Analyze(ctx context.Context, results []QueryResult, agentCtx AgentContext) AgentContext {
+	return agentCtx
+}
+
+func (a *proposingAgent) Conclude(ctx context.Context, agentCtx AgentContext) *AnalysisResult {
+	return &AnalysisResult{
+		MonitorID:       agentCtx.Event.Payload.MonitorID,
+		Success:         true,
+		AgentRole:       a.Role(),
+		RootCause:       "checkout pods leaking memory",
+		ProposedActions: append([]remediation.Proposal(nil), a.proposals...),
+	}
+}
+
+func TestAgentOrchestrator_SubmitsProposedActions(t *testing.T) {
+	replicas := 4
+	orch := NewAgentOrchestrator(OrchestratorConfig{MaxConcurrent: 1})
+	orch.SetDefaultAgent(&proposingAgent{proposals: []remediation.Proposal{
+		{Type: remediation.ActionRestartDeployment, Params: remediation.ActionParams{Namespace: "prod", Deployment: "checkout"}, Reason: "memory leak", ProposedBy: "proposer"},
+		{Type: remediation.ActionScaleDeployment, Params: remediation.ActionParams{Namespace: "prod", Deployment: "checkout", Replicas: &replicas}},
+		{Type: remediation.ActionScaleDeployment, Params: remediation.ActionParams{Namespace: "Bad Name", Deployment: "checkout", Replicas: &replicas}},
+	}})
+
+	manager := remediation.NewManager(remediation.NewMemoryStore(), remediation.Config{DryRun: true})
+	executor := remediation.NewFakeExecutor()
+	manager.RegisterExecutor(executor)
+	orch.SetRemediation(manager)
+
+	event := &types.AlertEvent{AccountName: "prod-account", Payload: types.AlertPayload{MonitorID: 77, MonitorName: "checkout memory", AlertStatus: "Alert"}}
+	result, err := orch.Analyze(context.Background(), event)
+	if err != nil {
+		t.Fatalf("Analyze failed: %v", err)
+	}
+
+	if len(result.ProposedActions) != 3 {
+		t.Fatalf("expected all proposals reported on the result, got %d", len(result.ProposedActions))
+	}
+	if result.ProposedActions[0].ActionID == "" || result.ProposedActions[1].ActionID == "" {
+		t.Fatalf("expected valid proposals to be submitted, got %+v", result.ProposedActions)
+	}
+	if result.ProposedActions[2].ActionID != "" {
+		t.Errorf("expected invalid proposal to stay unsubmitted, got %+v", result.ProposedActions[2])
+	}
+
+	detail, err := manager.Get(context.Background(), result.ProposedActions[0].ActionID)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	action := detail.Action
+	if action.Status != remediation.StatusPendingApproval || action.AnalysisID != result.AnalysisID ||
+		action.MonitorID != 77 || action.AccountName != "prod-account" || action.ProposedBy != "proposer" {
+		t.Errorf("unexpected submitted action: %+v", action)
+	}
+	if len(executor.Calls()) != 0 {
+		t.Errorf("nothing may execute before approval, got %+v", executor.Calls())
+	}
+
+	history, _, _, _ := orch.Progress().Subscribe(result.AnalysisID, 0)
+	proposed := 0
+	for _, e := range history {
+		if e.Type == ProgressActionProposed {
+			proposed++
+		}
+	}
+	if proposed != 2 {
+		t.Errorf("expected 2 remediation_proposed events, got %d", proposed)
+	}
+}
@@ -56,10 +56,38 @@ func (r *RLMCoordinator) ListSubAgents() []string {
 	return names
 }
 
-// Execute runs the RLM loop for a specialist agent
+// Execute runs the RLM loop for a specialist agent.
+// When ctx carries a progress reporter (see AgentOrchestrator.Analyze), each
+// plan, sub-query, new finding and conclusion is published to the progress bus.
+// Findings gathered from context sources are in the context from the start.
 func (r *RLMCoordinator) Execute(ctx context.Context, agent Agent, event *types.AlertEvent) (*AnalysisResult, error) {
-	startTime := time.Now()
 	agentCtx := NewAgentContext(event)
+	agentCtx.Findings = append(agentCtx.Findings, seedFindingsFrom(ctx)...)
+	result, _, err := r.run(ctx, agent, agentCtx)
+	return result, err
+}
+
+// Resume continues the loop from a stored context, e.g. to answer a follow-up
+// question. Iterations restart so the agent plans afresh, while findings,
+// query history and hypotheses carry over. Returns the final context as well.
+func (r *RLMCoordinator) Resume(ctx context.Context, agent Agent, agentCtx AgentContext) (*AnalysisResult, AgentContext, error) {
+	agentCtx.Iteration = 0
+	if agentCtx.Metadata == nil {
+		agentCtx.Metadata = make(map[string]interface{})
+	}
+	return r.run(ctx, agent, agentCtx)
+}
+
+// run drives Plan -> Query -> Analyze -> Conclude from agentCtx. The final
+// context is handed to any capture attached to ctx so it can be stored.
+func (r *RLMCoordinator) run(ctx context.Context, agent Agent, agentCtx AgentContext) (*AnalysisResult, AgentContext, error) {
+	startTime := time.Now()
+	event := agentCtx.Event
+	defer func() { captureFrom(ctx).record(agent, agentCtx) }()
+
+	progress := progressFrom(ctx).forAgent(agent.Name())
+	ctx = withProgress(ctx, progress)
+	progress.emit(ProgressAgentStarted, 0, "Agent "+agent.Name()+" started", nil)
 
 	log.Printf("[RLM] Starting analysis for monitor %d with %s agent (max %d iterations)",
 		event.Payload.MonitorID, agent.Name(), r.maxIterations)
@@ -67,7 +95,7 @@ func (r *RLMCoordinator) Execute(ctx context.Context, agent Agent, event *types.
 	for iteration := 0; iteration < r.maxIterations; iteration++ {
 		select {
 		case <-ctx.Done():
-			return r.buildCancelledResult(event, agent, agentCtx, startTime, ctx.Err()), ctx.Err()
+			return r.buildCancelledResult(event, agent, agentCtx, startTime, ctx.Err()), agentCtx, ctx.Err()
 		default:
 		}
 
@@ -78,6 +106,10 @@ func (r *RLMCoordinator) Execute(ctx context.Context, agent Agent, event *types.
 		plan := agent.Plan(ctx, event, agentCtx)
 		log.Printf("[RLM] Plan: %d queries, complete=%v, reason=%s",
 			len(plan.Queries), plan.Complete, truncate(plan.Reasoning, 100))
+		progress.emit(ProgressPlan, agentCtx.Iteration, plan.Reasoning, map[string]any{
+			"queries":  len(plan.Queries),
+			"complete": plan.Complete,
+		})
 
 		// Check if analysis is complete
 		if plan.Complete {
@@ -88,7 +120,8 @@ func (r *RLMCoordinator) Execute(ctx context.Context, agent Agent, event *types.
 			result.CompletedAt = time.Now()
 			log.Printf("[RLM] Analysis complete for monitor %d after %d iterations",
 				event.Payload.MonitorID, result.Iterations)
-			return result, nil
+			emitConcluded(progress, result)
+			return result, agentCtx, nil
 		}
 
 		// QUERY: Fan-out to sub-agents (if any)
@@ -101,14 +134,22 @@ func (r *RLMCoordinator) Execute(ctx context.Context, agent Agent, event *types.
 			for _, result := range results {
 				if result.Query.Required && result.Error != nil {
 					log.Printf("[RLM] Required query failed: %s - %v", result.Query.AgentName, result.Error)
-					return r.buildErrorResult(event, agent, agentCtx, startTime, result.Error), result.Error
+					return r.buildErrorResult(event, agent, agentCtx, startTime, result.Error), agentCtx, result.Error
 				}
 			}
 		}
 
 		// ANALYZE: Always call Analyze — agents like ClaudeAgent invoke
 		// the sidecar here even without sub-queries
+		seen := len(agentCtx.Findings)
 		agentCtx = agent.Analyze(ctx, results, agentCtx)
+		for _, f := range agentCtx.Findings[min(seen, len(agentCtx.Findings)):] {
+			progress.emit(ProgressFinding, agentCtx.Iteration, f.Summary, map[string]any{
+				"source":   f.Source,
+				"category": f.Category,
+				"severity": f.Severity,
+			})
+		}
 	}
 
 	// Max iterations reached - conclude with available data
@@ -120,7 +161,17 @@ func (r *RLMCoordinator) Execute(ctx context.Context, agent Agent, event *types.
 	result.Duration = time.Since(startTime)
 	result.StartedAt = startTime
 	result.CompletedAt = time.Now()
-	return result, nil
+	emitConcluded(progress, result)
+	return result, agentCtx, nil
+}
+
+// emitConcluded publishes an agent's final result
+func emitConcluded(progress *progressReporter, result *AnalysisResult) {
+	progress.emit(ProgressConcluded, result.Iterations, result.Summary, map[string]any{
+		"success":    result.Success,
+		"root_cause": truncate(result.RootCause, 500),
+		"error":      result.Error,
+	})
 }
 
 // executeSubQueries runs queries concurrently using fan-out pattern
@@ -134,6 +185,7 @@ func (r *RLMCoordinator) executeSubQueries(ctx context.Context, queries []SubQue
 
 	results := make(chan QueryResult, len(queries))
 	var wg sync.WaitGroup
+	progress := progressFrom(ctx)
 
 	for _, q := range queries {
 		wg.Add(1)
@@ -154,12 +206,24 @@ func (r *RLMCoordinator) executeSubQueries(ctx context.Context, queries []SubQue
 				return
 			}
 
+			progress.emit(ProgressSubQueryStarted, 0, query.AgentName+": "+truncate(query.Query, 200), nil)
+
 			startTime := time.Now()
 			queryResult, err := agent.Query(ctx, query.Query)
 			result.Duration = time.Since(startTime)
 			result.Result = queryResult
 			result.Error = err
 
+			finished := map[string]any{
+				"sub_agent":   query.AgentName,
+				"duration_ms": result.Duration.Milliseconds(),
+				"bytes":       len(queryResult),
+			}
+			if err != nil {
+				finished["error"] = err.Error()
+			}
+			progress.emit(ProgressSubQueryFinished, 0, query.AgentName+": "+truncate(query.Query, 200), finished)
+
 			if err != nil {
 				log.Printf("[RLM] Query failed: %s/%s - %v (took %v)",
 					query.AgentName, truncate(query.Query, 50), err, result.Duration)
@@ -0,0 +1,271 @@
+package agents
+
+import (
+	"container/heap"
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+)
+
+// ErrSchedulerFull is returned when the wait queue is full and the request
+// ranks below everything already waiting
+var ErrSchedulerFull = errors.New("analysis queue is full")
+
+// AnalysisScheduler bounds concurrent analyses like a semaphore, but hands
+// freed slots to the highest-priority waiter instead of the first to arrive.
+//
+// Waiters age: a request's effective priority grows by agingPerMinute for
+// every minute it waits, so low-priority work is eventually served. Because
+// every waiter ages at the same rate, ordering by
+// priority - agingPerMinute*enqueuedMinutes is stable and the heap never
+// needs re-sorting.
+type AnalysisScheduler struct {
+	slots          int
+	inUse          int
+	agingPerMinute float64
+	maxQueued      int
+	epoch          time.Time
+	now            func() time.Time
+
+	queue waitQueue
+	seq   uint64
+	mu    sync.Mutex
+
+	// Metrics
+	granted   int64
+	shed      int64
+	cancelled int64
+	totalWait time.Duration
+	maxWait   time.Duration
+}
+
+// NewAnalysisScheduler creates a scheduler with the given number of slots.
+// maxQueued <= 0 means the wait queue is unbounded.
+func NewAnalysisScheduler(slots int, agingPerMinute float64, maxQueued int) *AnalysisScheduler {
+	if slots <= 0 {
+		slots = 3
+	}
+	if agingPerMinute < 0 {
+		agingPerMinute = 0
+	}
+	return &AnalysisScheduler{
+		slots:          slots,
+		agingPerMinute: agingPerMinute,
+		maxQueued:      maxQueued,
+		epoch:          time.Now(),
+		now:            time.Now,
+	}
+}
+
+// waiter is a queued Acquire call
+type waiter struct {
+	key        float64
+	priority   int
+	label      string
+	seq        uint64
+	enqueuedAt time.Time
+	ready      chan struct{}
+	index      int // heap index; -1 once granted or removed
+}
+
+// Acquire blocks until a slot is available for a request with the given
+// priority (higher runs first) and returns a function that frees the slot.
+// Returns ctx.Err() if the context ends first, or ErrSchedulerFull if the
+// request was shed from a full queue.
+func (s *AnalysisScheduler) Acquire(ctx context.Context, priority int, label string) (func(), error) {
+	s.mu.Lock()
+
+	if s.inUse < s.slots && len(s.queue) == 0 {
+		s.inUse++
+		s.granted++
+		s.mu.Unlock()
+		return s.releaseFunc(), nil
+	}
+
+	now := s.now()
+	w := &waiter{
+		key:        float64(priority) - s.agingPerMinute*now.Sub(s.epoch).Minutes(),
+		priority:   priority,
+		label:      label,
+		seq:        s.seq,
+		enqueuedAt: now,
+		ready:      make(chan struct{}),
+	}
+	s.seq++
+
+	if s.maxQueued > 0 && len(s.queue) >= s.maxQueued {
+		lowest := s.queue.lowest()
+		if !s.queue.outranks(w, s.queue[lowest]) {
+			s.shed++
+			s.mu.Unlock()
+			return nil, ErrSchedulerFull
+		}
+		evicted := heap.Remove(&s.queue, lowest).(*waiter)
+		evicted.index = -2 // shed marker
+		close(evicted.ready)
+		s.shed++
+		log.Printf("[AGENT-SCHED] Queue full, shed %s (priority %d) for %s (priority %d)",
+			evicted.label, evicted.priority, label, priority)
+	}
+
+	heap.Push(&s.queue, w)
+	log.Printf("[AGENT-SCHED] Queued %s (priority %d, %d waiting, %d/%d slots busy)",
+		label, priority, len(s.queue), s.inUse, s.slots)
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		s.mu.Lock()
+		shed := w.index == -2
+		s.mu.Unlock()
+		if shed {
+			return nil, ErrSchedulerFull
+		}
+		return s.releaseFunc(), nil
+	case <-ctx.Done():
+		s.mu.Lock()
+		if w.index >= 0 {
+			heap.Remove(&s.queue, w.index)
+			w.index = -1
+			s.cancelled++
+			s.mu.Unlock()
+			return nil, ctx.Err()
+		}
+		shed := w.index == -2
+		s.mu.Unlock()
+
+		// Granted or shed concurrently with cancellation
+		if !shed {
+			s.releaseFunc()()
+		}
+		return nil, ctx.Err()
+	}
+}
+
+// releaseFunc returns an idempotent function that frees one slot
+func (s *AnalysisScheduler) releaseFunc() func() {
+	var once sync.Once
+	return func() {
+		once.Do(s.release)
+	}
+}
+
+// release hands the slot to the best waiter, or frees it
+func (s *AnalysisScheduler) release() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		s.inUse--
+		return
+	}
+
+	// The slot transfers directly; inUse is unchanged
+	w := heap.Pop(&s.queue).(*waiter)
+	w.index = -1
+	wait := s.now().Sub(w.enqueuedAt)
+	s.granted++
+	s.totalWait += wait
+	if wait > s.maxWait {
+		s.maxWait = wait
+	}
+	close(w.ready)
+}
+
+// Stats returns scheduler state
+func (s *AnalysisScheduler) Stats() SchedulerStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := SchedulerStats{
+		Slots:          s.slots,
+		InUse:          s.inUse,
+		Queued:         len(s.queue),
+		MaxQueued:      s.maxQueued,
+		AgingPerMinute: s.agingPerMinute,
+		Granted:        s.granted,
+		Shed:           s.shed,
+		Cancelled:      s.cancelled,
+		MaxWait:        s.maxWait.String(),
+	}
+
+	now := s.now()
+	for _, w := range s.queue {
+		stats.Waiting = append(stats.Waiting, QueuedAnalysis{
+			Label:    w.label,
+			Priority: w.priority,
+			Waited:   now.Sub(w.enqueuedAt).Round(time.Second).String(),
+		})
+	}
+	return stats
+}
+
+// SchedulerStats holds scheduler state for the stats endpoint
+type SchedulerStats struct {
+	Slots          int              `json:"slots"`
+	InUse          int              `json:"in_use"`
+	Queued         int              `json:"queued"`
+	MaxQueued      int              `json:"max_queued"`
+	AgingPerMinute float64          `json:"aging_per_minute"`
+	Granted        int64            `json:"granted"`
+	Shed           int64            `json:"shed"`
+	Cancelled      int64            `json:"cancelled"`
+	MaxWait        string           `json:"max_wait"`
+	Waiting        []QueuedAnalysis `json:"waiting,omitempty"`
+}
+
+// QueuedAnalysis describes a request waiting for a slot
+type QueuedAnalysis struct {
+	Label    string `json:"label"`
+	Priority int    `json:"priority"`
+	Waited   string `json:"waited"`
+}
+
+// waitQueue is a max-heap of waiters ordered by aged priority, then arrival
+type waitQueue []*waiter
+
+func (q waitQueue) Len() int { return len(q) }
+
+func (q waitQueue) Less(i, j int) bool { return q.outranks(q[i], q[j]) }
+
+func (q waitQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *waitQueue) Push(x any) {
+	w := x.(*waiter)
+	w.index = len(*q)
+	*q = append(*q, w)
+}
+
+func (q *waitQueue) Pop() any {
+	old := *q
+	n := len(old)
+	w := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return w
+}
+
+// outranks reports whether a should be served before b
+func (q waitQueue) outranks(a, b *waiter) bool {
+	if a.key != b.key {
+		return a.key > b.key
+	}
+	return a.seq < b.seq
+}
+
+// lowest returns the index of the waiter that would be served last
+func (q waitQueue) lowest() int {
+	idx := 0
+	for i := 1; i < len(q); i++ {
+		if q.outranks(q[idx], q[i]) {
+			idx = i
+		}
+	}
+	return idx
+}
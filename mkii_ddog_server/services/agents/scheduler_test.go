@@ -0,0 +1,186 @@
+package agents
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// queueWaiter starts an Acquire in the background and reports the grant order
+func queueWaiter(t *testing.T, s *AnalysisScheduler, ctx context.Context, priority int, label string, order chan<- string) {
+	t.Helper()
+	before := s.Stats().Queued
+	go func() {
+		release, err := s.Acquire(ctx, priority, label)
+		if err != nil {
+			order <- label + ":" + err.Error()
+			return
+		}
+		order <- label
+		release()
+	}()
+	// Wait until the request is queued so arrival order is deterministic
+	deadline := time.Now().Add(time.Second)
+	for s.Stats().Queued == before {
+		if time.Now().After(deadline) {
+			t.Fatalf("%s was never queued", label)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestAnalysisScheduler_HighestPriorityFirst(t *testing.T) {
+	s := NewAnalysisScheduler(1, 0, 0)
+
+	release, err := s.Acquire(context.Background(), 10, "running")
+	if err != nil {
+		t.Fatalf("first acquire should not block: %v", err)
+	}
+
+	order := make(chan string, 3)
+	queueWaiter(t, s, context.Background(), 10, "p5-warn", order)
+	queueWaiter(t, s, context.Background(), 60, "p1-alert", order)
+	queueWaiter(t, s, context.Background(), 30, "p3", order)
+
+	release()
+
+	expected := []string{"p1-alert", "p3", "p5-warn"}
+	for _, want := range expected {
+		if got := <-order; got != want {
+			t.Errorf("expected %s, got %s", want, got)
+		}
+	}
+
+	stats := s.Stats()
+	if stats.InUse != 0 || stats.Queued != 0 || stats.Granted != 4 {
+		t.Errorf("unexpected stats after drain: %+v", stats)
+	}
+}
+
+func TestAnalysisScheduler_AgingPreventsStarvation(t *testing.T) {
+	s := NewAnalysisScheduler(1, 10, 0)
+	clock := &fakeClock{t: s.epoch}
+	s.now = clock.now
+
+	release, _ := s.Acquire(context.Background(), 0, "running")
+
+	order := make(chan string, 2)
+	queueWaiter(t, s, context.Background(), 10, "old-low", order)
+
+	// Six minutes later the low-priority request has aged to 70 > 60
+	clock.advance(6 * time.Minute)
+	queueWaiter(t, s, context.Background(), 60, "new-high", order)
+
+	release()
+	if got := <-order; got != "old-low" {
+		t.Errorf("aged request should run first, got %s", got)
+	}
+	<-order
+}
+
+func TestAnalysisScheduler_ShedsLowestWhenFull(t *testing.T) {
+	s := NewAnalysisScheduler(1, 0, 1)
+	release, _ := s.Acquire(context.Background(), 0, "running")
+
+	order := make(chan string, 2)
+	queueWaiter(t, s, context.Background(), 10, "low", order)
+
+	// Lower-ranked request is rejected outright
+	if _, err := s.Acquire(context.Background(), 5, "lower"); !errors.Is(err, ErrSchedulerFull) {
+		t.Errorf("expected ErrSchedulerFull, got %v", err)
+	}
+
+	// Higher-ranked request evicts the queued low-priority one
+	go func() {
+		r, err := s.Acquire(context.Background(), 50, "high")
+		if err == nil {
+			order <- "high"
+			r()
+		}
+	}()
+	if got := <-order; got != "low:"+ErrSchedulerFull.Error() {
+		t.Errorf("expected low to be shed, got %s", got)
+	}
+
+	release()
+	if got := <-order; got != "high" {
+		t.Errorf("expected high to run, got %s", got)
+	}
+	if shed := s.Stats().Shed; shed != 2 {
+		t.Errorf("expected 2 shed requests, got %d", shed)
+	}
+}
+
+func TestAnalysisScheduler_CancelWhileQueued(t *testing.T) {
+	s := NewAnalysisScheduler(1, 0, 0)
+	release, _ := s.Acquire(context.Background(), 0, "running")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	order := make(chan string, 1)
+	queueWaiter(t, s, ctx, 10, "cancelled", order)
+	cancel()
+
+	if got := <-order; got != "cancelled:"+context.Canceled.Error() {
+		t.Errorf("expected cancellation, got %s", got)
+	}
+
+	release()
+	stats := s.Stats()
+	if stats.InUse != 0 || stats.Queued != 0 || stats.Cancelled != 1 {
+		t.Errorf("slot should be free after cancellation: %+v", stats)
+	}
+}
+
+func TestAnalysisPriority(t *testing.T) {
+	important := map[int64]bool{99: true}
+
+	tests := []struct {
+		name    string
+		payload types.AlertPayload
+		higher  types.AlertPayload
+	}{
+		{
+			name:    "P1 outranks P5",
+			payload: types.AlertPayload{Priority: "P5", AlertStatus: "Alert"},
+			higher:  types.AlertPayload{Priority: "P1", AlertStatus: "Alert"},
+		},
+		{
+			name:    "Alert outranks Warn",
+			payload: types.AlertPayload{Priority: "P3", AlertStatus: "Warn"},
+			higher:  types.AlertPayload{Priority: "P3", AlertStatus: "Alert"},
+		},
+		{
+			name:    "urgency and impact raise priority",
+			payload: types.AlertPayload{AlertStatus: "Warn"},
+			higher:  types.AlertPayload{AlertStatus: "Warn", Urgency: "High", Impact: "Critical"},
+		},
+		{
+			name:    "important monitor boosted",
+			payload: types.AlertPayload{MonitorID: 1, Priority: "P2"},
+			higher:  types.AlertPayload{MonitorID: 99, Priority: "P2"},
+		},
+		{
+			name:    "priority tag used when field empty",
+			payload: types.AlertPayload{Tags: []string{"priority:p4"}},
+			higher:  types.AlertPayload{Tags: []string{"priority:p1"}},
+		},
+		{
+			name:    "critical tier tag",
+			payload: types.AlertPayload{Tags: []string{"tier:3"}},
+			higher:  types.AlertPayload{Tags: []string{"tier:critical"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			low := AnalysisPriority(&types.AlertEvent{Payload: tt.payload}, important)
+			high := AnalysisPriority(&types.AlertEvent{Payload: tt.higher}, important)
+			if high <= low {
+				t.Errorf("expected %d > %d", high, low)
+			}
+		})
+	}
+}
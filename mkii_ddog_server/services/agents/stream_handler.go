@@ -0,0 +1,159 @@
+package agents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/websocket"
+)
+
+// sseHeartbeat keeps idle SSE connections open through proxies
+const sseHeartbeat = 15 * time.Second
+
+// ListAnalyses lists recent and running analyses with progress streams (GET /v1/agents/analyses)
+func (h *Handler) ListAnalyses(w http.ResponseWriter, r *http.Request) (int, any) {
+	analyses := h.orchestrator.Progress().List()
+	return http.StatusOK, map[string]any{
+		"analyses": analyses,
+		"count":    len(analyses),
+	}
+}
+
+// StreamAnalysis streams progress events for one analysis
+// (GET /v1/agents/analyses/{id}/stream). Plain requests get Server-Sent Events;
+// requests with WebSocket upgrade headers get one JSON message per event.
+// History is replayed first, so late subscribers see the whole investigation.
+//
+// This writes the response itself, so it is registered directly on the mux
+// rather than through utils.Endpoint.
+func (h *Handler) StreamAnalysis(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	afterSeq := lastEventID(r)
+
+	history, live, cancel, ok := h.orchestrator.Progress().Subscribe(id, afterSeq)
+	if !ok {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(map[string]string{"error": fmt.Sprintf("analysis not found: %s", id)})
+		return
+	}
+	defer cancel()
+
+	if websocket.IsUpgradeRequest(r) {
+		h.streamWebSocket(w, r, id, history, live)
+		return
+	}
+	h.streamSSE(w, r, id, history, live)
+}
+
+// streamSSE writes events as text/event-stream until the analysis completes or the client leaves
+func (h *Handler) streamSSE(w http.ResponseWriter, r *http.Request, id string, history []ProgressEvent, live <-chan ProgressEvent) {
+	rc := http.NewResponseController(w)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event ProgressEvent) bool {
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("[AGENT-STREAM] Failed to encode event for %s: %v", id, err)
+			return true
+		}
+		if _, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, data); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	for _, event := range history {
+		if !send(event) {
+			return
+		}
+	}
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for live != nil {
+		select {
+		case event, open := <-live:
+			if !open {
+				live = nil
+				continue
+			}
+			if !send(event) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	fmt.Fprintf(w, "event: end\ndata: {\"analysis_id\":%q}\n\n", id)
+	rc.Flush()
+}
+
+// streamWebSocket writes events as WebSocket JSON messages, then closes
+func (h *Handler) streamWebSocket(w http.ResponseWriter, r *http.Request, id string, history []ProgressEvent, live <-chan ProgressEvent) {
+	conn, err := websocket.Upgrade(w, r, h.allowedOrigins)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, websocket.ErrForbiddenOrigin) {
+			log.Printf("[AGENT-STREAM] Rejected WebSocket for %s from origin %q", id, r.Header.Get("Origin"))
+			status = http.StatusForbidden
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	defer conn.Close()
+
+	for _, event := range history {
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	for live != nil {
+		select {
+		case event, open := <-live:
+			if !open {
+				live = nil
+				continue
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-conn.Done():
+			return
+		}
+	}
+
+	conn.WriteJSON(map[string]string{"type": "end", "analysis_id": id})
+}
+
+// lastEventID reads the resume point from Last-Event-ID (SSE reconnects) or ?after=
+func lastEventID(r *http.Request) int {
+	value := r.Header.Get("Last-Event-ID")
+	if value == "" {
+		value = r.URL.Query().Get("after")
+	}
+	seq, err := strconv.Atoi(value)
+	if err != nil || seq < 0 {
+		return 0
+	}
+	return seq
+}
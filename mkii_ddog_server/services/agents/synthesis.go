@@ -0,0 +1,237 @@
+package agents
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+// specialistOutcome is one specialist's result in a collaborative analysis
+type specialistOutcome struct {
+	candidate RoleCandidate
+	agent     string
+	result    *AnalysisResult
+	err       error
+}
+
+// synthesize merges specialist results into a single AnalysisResult.
+// Outcomes must be in candidate rank order; the best-ranked successful
+// specialist supplies the root cause, the others are attributed and any
+// disagreement is recorded in Conflicts. Seed findings (from context sources,
+// shared by every specialist) appear once and unattributed.
+func synthesize(event *types.AlertEvent, seed []Finding, outcomes []specialistOutcome, startTime time.Time) *AnalysisResult {
+	merged := &AnalysisResult{
+		MonitorID:   event.Payload.MonitorID,
+		MonitorName: event.Payload.MonitorName,
+		AlertStatus: event.Payload.AlertStatus,
+		Findings:    append([]Finding(nil), seed...),
+		StartedAt:   startTime,
+	}
+	if len(outcomes) > 0 {
+		merged.AgentRole = outcomes[0].candidate.Role
+	}
+
+	var primary *specialistOutcome
+	var errs []string
+	var details []string
+
+	for i := range outcomes {
+		o := &outcomes[i]
+		contribution := Contribution{
+			AgentRole:  o.candidate.Role,
+			Agent:      o.agent,
+			Rule:       o.candidate.Rule,
+			Confidence: o.candidate.Confidence,
+		}
+
+		if o.result != nil {
+			contribution.Success = o.result.Success
+			contribution.RootCause = o.result.RootCause
+			contribution.Summary = o.result.Summary
+			contribution.NotebookURL = o.result.NotebookURL
+			contribution.Duration = o.result.Duration
+			contribution.Error = o.result.Error
+			contribution.TokensUsed = o.result.TokensUsed
+			merged.TokensUsed += o.result.TokensUsed
+
+			if o.result.Iterations > merged.Iterations {
+				merged.Iterations = o.result.Iterations
+			}
+			if merged.NotebookURL == "" {
+				merged.NotebookURL = o.result.NotebookURL
+			}
+		}
+		if o.err != nil && contribution.Error == "" {
+			contribution.Error = o.err.Error()
+		}
+		merged.Contributors = append(merged.Contributors, contribution)
+
+		if !contribution.Success {
+			errs = append(errs, fmt.Sprintf("%s: %s", o.candidate.Role, contribution.Error))
+			continue
+		}
+
+		if primary == nil {
+			primary = o
+			merged.AgentRole = o.candidate.Role
+			merged.RootCause = o.result.RootCause
+			if o.result.NotebookURL != "" {
+				merged.NotebookURL = o.result.NotebookURL
+			}
+		}
+
+		merged.Findings = append(merged.Findings, attributeFindings(o.candidate.Role, withoutSeed(o.result.Findings, seed))...)
+		merged.ProposedActions = append(merged.ProposedActions, o.result.ProposedActions...)
+
+		if o.result.Summary != "" || o.result.Details != "" {
+			details = append(details, fmt.Sprintf("### %s\n%s\n%s",
+				o.candidate.Role, o.result.Summary, o.result.Details))
+		}
+	}
+
+	merged.Recommendations = mergeRecommendations(outcomes)
+	merged.Conflicts = findConflicts(outcomes)
+	merged.Details = strings.TrimSpace(strings.Join(details, "\n\n"))
+	merged.Success = primary != nil
+
+	roles := make([]string, len(outcomes))
+	for i, o := range outcomes {
+		roles[i] = string(o.candidate.Role)
+	}
+
+	if primary != nil {
+		merged.Summary = fmt.Sprintf("%s (collaborative analysis: %s)",
+			primary.result.Summary, strings.Join(roles, ", "))
+	} else {
+		merged.Summary = fmt.Sprintf("Collaborative analysis failed (%s)", strings.Join(roles, ", "))
+		merged.Error = strings.Join(errs, "; ")
+	}
+
+	merged.CompletedAt = time.Now()
+	merged.Duration = merged.CompletedAt.Sub(startTime)
+	return merged
+}
+
+// attributeFindings copies findings and tags them with the contributing role
+func attributeFindings(role AgentRole, findings []Finding) []Finding {
+	attributed := make([]Finding, len(findings))
+	for i, f := range findings {
+		if f.AgentRole == "" {
+			f.AgentRole = role
+		}
+		attributed[i] = f
+	}
+	return attributed
+}
+
+// withoutSeed drops the seed findings a specialist started with
+func withoutSeed(findings, seed []Finding) []Finding {
+	if len(seed) == 0 {
+		return findings
+	}
+
+	type key struct {
+		source, category, summary string
+		at                        time.Time
+	}
+	seeded := make(map[key]bool, len(seed))
+	for _, f := range seed {
+		seeded[key{f.Source, f.Category, f.Summary, f.Timestamp}] = true
+	}
+
+	var kept []Finding
+	for _, f := range findings {
+		if !seeded[key{f.Source, f.Category, f.Summary, f.Timestamp}] {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+// mergeRecommendations deduplicates recommendations across specialists,
+// prefixing each with the roles that made it, e.g. "[database, application] ...".
+func mergeRecommendations(outcomes []specialistOutcome) []string {
+	type recommendation struct {
+		text  string
+		roles []string
+	}
+
+	var ordered []*recommendation
+	byKey := make(map[string]*recommendation)
+
+	for _, o := range outcomes {
+		if o.result == nil || !o.result.Success {
+			continue
+		}
+		for _, rec := range o.result.Recommendations {
+			key := normalizeText(rec)
+			if key == "" {
+				continue
+			}
+			existing, ok := byKey[key]
+			if !ok {
+				existing = &recommendation{text: strings.TrimSpace(rec)}
+				byKey[key] = existing
+				ordered = append(ordered, existing)
+			}
+			role := string(o.candidate.Role)
+			if !containsString(existing.roles, role) {
+				existing.roles = append(existing.roles, role)
+			}
+		}
+	}
+
+	merged := make([]string, len(ordered))
+	for i, rec := range ordered {
+		merged[i] = fmt.Sprintf("[%s] %s", strings.Join(rec.roles, ", "), rec.text)
+	}
+	return merged
+}
+
+// findConflicts notes where successful specialists reached different root causes
+func findConflicts(outcomes []specialistOutcome) []string {
+	causes := make(map[string][]string)
+	var order []string
+
+	for _, o := range outcomes {
+		if o.result == nil || !o.result.Success {
+			continue
+		}
+		key := normalizeText(o.result.RootCause)
+		if key == "" {
+			continue
+		}
+		if _, ok := causes[key]; !ok {
+			order = append(order, key)
+		}
+		causes[key] = append(causes[key], fmt.Sprintf("%s: %q", o.candidate.Role, truncate(o.result.RootCause, 200)))
+	}
+
+	if len(order) < 2 {
+		return nil
+	}
+
+	conflicts := make([]string, 0, len(order))
+	for _, key := range order {
+		conflicts = append(conflicts, strings.Join(causes[key], "; "))
+	}
+
+	return []string{fmt.Sprintf("specialists disagree on root cause: %s", strings.Join(conflicts, " vs "))}
+}
+
+// normalizeText lower-cases and collapses whitespace for comparisons
+func normalizeText(s string) string {
+	return strings.Join(strings.Fields(strings.ToLower(s)), " ")
+}
+
+// containsString reports whether list contains s
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
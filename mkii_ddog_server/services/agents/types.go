@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
 )
 
 // AgentRole identifies the type of specialist agent for alert routing
@@ -38,6 +39,22 @@ type Agent interface {
 	Conclude(ctx context.Context, agentCtx AgentContext) *AnalysisResult
 }
 
+// LocalAgent is an agent that runs in-process without the agent sidecar (e.g.
+// the Watchdog story agent in services/watchdog). The sidecar circuit breaker
+// neither blocks nor learns from local agents.
+type LocalAgent interface {
+	Agent
+
+	// Local reports whether the agent can run while the sidecar is down
+	Local() bool
+}
+
+// isLocal reports whether an agent runs without the sidecar
+func isLocal(agent Agent) bool {
+	local, ok := agent.(LocalAgent)
+	return ok && local.Local()
+}
+
 // SubAgent defines the interface for data-fetching sub-agents
 type SubAgent interface {
 	// Name returns the unique identifier for this sub-agent
@@ -49,14 +66,25 @@ type SubAgent interface {
 
 // AgentContext holds the accumulated state during RLM iterations
 type AgentContext struct {
-	Event          *types.AlertEvent
-	Iteration      int
-	QueryHistory   []QueryResult
-	Findings       []Finding
-	Hypotheses     []string
-	RootCause      string
+	Event           *types.AlertEvent
+	Iteration       int
+	QueryHistory    []QueryResult
+	Findings        []Finding
+	Hypotheses      []string
+	RootCause       string
 	Recommendations []string
-	Metadata       map[string]interface{}
+	Metadata        map[string]interface{}
+
+	// Question is set when the loop is resumed to answer a follow-up
+	// (see AgentOrchestrator.Ask); agents that support follow-ups put their
+	// reply in Answer. Conversation holds the earlier turns.
+	Question     string
+	Answer       string
+	Conversation []ConversationTurn
+
+	// ProposedActions are remediation actions the agent suggests; they only
+	// run after a human approves them (see services/remediation)
+	ProposedActions []remediation.Proposal
 }
 
 // NewAgentContext creates a new agent context for an event
@@ -109,46 +137,80 @@ type QueryResult struct {
 
 // Finding represents a discovered fact during analysis
 type Finding struct {
-	Source     string                 `json:"source"`      // Which sub-agent/query produced this
-	Category   string                 `json:"category"`    // e.g., "metric", "log", "trace", "config"
-	Summary    string                 `json:"summary"`     // Brief description
-	Details    string                 `json:"details"`     // Full details
-	Severity   string                 `json:"severity"`    // "info", "warning", "critical"
-	Timestamp  time.Time              `json:"timestamp"`
-	Metadata   map[string]interface{} `json:"metadata,omitempty"`
+	Source    string                 `json:"source"`   // Which sub-agent/query produced this
+	Category  string                 `json:"category"` // e.g., "metric", "log", "trace", "config"
+	Summary   string                 `json:"summary"`  // Brief description
+	Details   string                 `json:"details"`  // Full details
+	Severity  string                 `json:"severity"` // "info", "warning", "critical"
+	Timestamp time.Time              `json:"timestamp"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+
+	// AgentRole attributes the finding to a specialist in collaborative analyses
+	AgentRole AgentRole `json:"agent_role,omitempty"`
 }
 
 // AnalysisResult contains the final output of an agent analysis
 type AnalysisResult struct {
 	// Event identification
-	MonitorID   int64     `json:"monitor_id"`
-	MonitorName string    `json:"monitor_name"`
-	AlertStatus string    `json:"alert_status"`
+	AnalysisID  string `json:"analysis_id,omitempty"`
+	MonitorID   int64  `json:"monitor_id"`
+	MonitorName string `json:"monitor_name"`
+	AlertStatus string `json:"alert_status"`
 
 	// Analysis outcome
-	Success     bool      `json:"success"`
-	AgentRole   AgentRole `json:"agent_role"`
-	RootCause   string    `json:"root_cause"`
-	Summary     string    `json:"summary"`
-	Details     string    `json:"details"`
+	Success   bool      `json:"success"`
+	AgentRole AgentRole `json:"agent_role"`
+	RootCause string    `json:"root_cause"`
+	Summary   string    `json:"summary"`
+	Details   string    `json:"details"`
 
 	// Supporting data
-	Findings       []Finding `json:"findings"`
+	Findings        []Finding `json:"findings"`
 	Recommendations []string  `json:"recommendations"`
 
+	// Remediation actions proposed by the agent; ActionID is set once submitted for approval
+	ProposedActions []remediation.Proposal `json:"proposed_actions,omitempty"`
+
 	// Notebook created during analysis (URL from Claude sidecar)
 	NotebookURL string `json:"notebook_url,omitempty"`
 
+	// Resolution is set on recovery results (time-to-resolve, what changed)
+	Resolution *Resolution `json:"resolution,omitempty"`
+
+	// Collaborative analysis: per-specialist outcomes and disagreements
+	Contributors []Contribution `json:"contributors,omitempty"`
+	Conflicts    []string       `json:"conflicts,omitempty"`
+
 	// Execution metadata
 	Iterations int           `json:"iterations"`
 	Duration   time.Duration `json:"duration"`
 	Error      string        `json:"error,omitempty"`
+	TokensUsed int64         `json:"tokens_used,omitempty"`
+
+	// Skipped is set when budgets, cooldowns or the circuit breaker prevented analysis
+	Skipped    bool       `json:"skipped,omitempty"`
+	SkipReason SkipReason `json:"skip_reason,omitempty"`
 
 	// Timestamps
 	StartedAt   time.Time `json:"started_at"`
 	CompletedAt time.Time `json:"completed_at"`
 }
 
+// Contribution summarizes one specialist's part in a collaborative analysis
+type Contribution struct {
+	AgentRole   AgentRole     `json:"agent_role"`
+	Agent       string        `json:"agent"`
+	Rule        string        `json:"rule"`
+	Confidence  float64       `json:"confidence"`
+	Success     bool          `json:"success"`
+	RootCause   string        `json:"root_cause,omitempty"`
+	Summary     string        `json:"summary,omitempty"`
+	NotebookURL string        `json:"notebook_url,omitempty"`
+	Duration    time.Duration `json:"duration"`
+	Error       string        `json:"error,omitempty"`
+	TokensUsed  int64         `json:"tokens_used,omitempty"`
+}
+
 // JobResult represents the result of a dispatched webhook job
 type JobResult struct {
 	EventID     int64
@@ -160,11 +222,11 @@ type JobResult struct {
 
 // DispatcherStats holds statistics about the dispatcher
 type DispatcherStats struct {
-	QueueSize       int   `json:"queue_size"`
-	QueueCapacity   int   `json:"queue_capacity"`
-	ActiveWorkers   int   `json:"active_workers"`
-	TotalWorkers    int   `json:"total_workers"`
-	ProcessedCount  int64 `json:"processed_count"`
-	ErrorCount      int64 `json:"error_count"`
-	DroppedCount    int64 `json:"dropped_count"`
+	QueueSize      int   `json:"queue_size"`
+	QueueCapacity  int   `json:"queue_capacity"`
+	ActiveWorkers  int   `json:"active_workers"`
+	TotalWorkers   int   `json:"total_workers"`
+	ProcessedCount int64 `json:"processed_count"`
+	ErrorCount     int64 `json:"error_count"`
+	DroppedCount   int64 `json:"dropped_count"`
 }
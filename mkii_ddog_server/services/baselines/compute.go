@@ -0,0 +1,162 @@
+package baselines
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// Episode is one alert on a scope: from its first non-OK event to the recovery
+type Episode struct {
+	Scope  string
+	Start  time.Time
+	End    *time.Time
+	Events []webhooks.WebhookEvent // alert events then the recovery, oldest first
+}
+
+// Recovery returns the event that closed the episode (nil while open)
+func (e Episode) Recovery() *webhooks.WebhookEvent {
+	if e.End == nil || len(e.Events) == 0 {
+		return nil
+	}
+	return &e.Events[len(e.Events)-1]
+}
+
+// isRecovery reports whether a webhook payload ends an alert
+func isRecovery(status, state string) bool {
+	switch strings.ToLower(status) {
+	case "ok", "recovered", "resolved":
+		return true
+	}
+	switch strings.ToLower(state) {
+	case "ok", "recovered", "resolved":
+		return true
+	}
+	return false
+}
+
+// Episodes splits a monitor's history (oldest first) into alert episodes per
+// scope, ordered by start. Re-notifications of an open alert do not start a new
+// episode, and a recovery without a preceding alert in history is ignored.
+func Episodes(history []webhooks.WebhookEvent) []Episode {
+	open := make(map[string]int)
+	var out []Episode
+	for _, event := range history {
+		scope := event.Payload.Scope
+		i, ok := open[scope]
+		if isRecovery(event.Payload.AlertStatus, event.Payload.AlertState) {
+			if ok {
+				end := event.ReceivedAt
+				out[i].End = &end
+				out[i].Events = append(out[i].Events, event)
+				delete(open, scope)
+			}
+			continue
+		}
+		if !ok {
+			open[scope] = len(out)
+			out = append(out, Episode{Scope: scope, Start: event.ReceivedAt})
+		}
+		out[open[scope]].Events = append(out[open[scope]].Events, event)
+	}
+	return out
+}
+
+// Compute builds a monitor's baseline from its history in [from, to], oldest first.
+// Hour and weekday patterns use loc (UTC when nil).
+func Compute(monitorID int64, history []webhooks.WebhookEvent, from, to time.Time, loc *time.Location) *Baseline {
+	if loc == nil {
+		loc = time.UTC
+	}
+	baseline := &Baseline{
+		MonitorID:  monitorID,
+		Window:     to.Sub(from),
+		From:       from,
+		To:         to,
+		ComputedAt: time.Now(),
+		Timezone:   loc.String(),
+	}
+
+	var inWindow []webhooks.WebhookEvent
+	for _, event := range history {
+		if event.ReceivedAt.Before(from) || event.ReceivedAt.After(to) {
+			continue
+		}
+		inWindow = append(inWindow, event)
+		if baseline.MonitorName == "" {
+			baseline.MonitorName = utils.FirstNonEmpty(event.Payload.MonitorName, event.Payload.AlertTitle)
+		}
+	}
+	baseline.Events = len(inWindow)
+	if len(inWindow) == 0 {
+		return baseline
+	}
+
+	// A monitor first seen mid-window is measured from its first event
+	observedFrom := from
+	if first := inWindow[0].ReceivedAt; first.After(from) {
+		observedFrom = first
+	}
+	baseline.ObservedDays = math.Max(1, to.Sub(observedFrom).Hours()/24)
+
+	var durations []time.Duration
+	for _, ep := range Episodes(inWindow) {
+		start := ep.Start
+		baseline.Alerts++
+		if baseline.FirstAlertAt == nil {
+			baseline.FirstAlertAt = &start
+		}
+		baseline.LastAlertAt = &start
+
+		local := start.In(loc)
+		baseline.HourCounts[local.Hour()]++
+		baseline.WeekdayCounts[local.Weekday()]++
+
+		if ep.End == nil {
+			baseline.Ongoing++
+			continue
+		}
+		durations = append(durations, ep.End.Sub(start))
+	}
+	baseline.AlertsPerDay = float64(baseline.Alerts) / baseline.ObservedDays
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		var total time.Duration
+		for _, d := range durations {
+			total += d
+		}
+		baseline.Resolved = len(durations)
+		baseline.MTTR = total / time.Duration(len(durations))
+		baseline.MedianDuration = percentile(durations, 0.5)
+		baseline.P90Duration = percentile(durations, 0.9)
+	}
+	return baseline
+}
+
+// alertsBetween counts the alert episodes that started in [from, to]
+func alertsBetween(history []webhooks.WebhookEvent, from, to time.Time) int {
+	count := 0
+	for _, ep := range Episodes(history) {
+		if !ep.Start.Before(from) && !ep.Start.After(to) {
+			count++
+		}
+	}
+	return count
+}
+
+// percentile returns the q-th percentile of sorted durations (nearest rank)
+func percentile(sorted []time.Duration, q float64) time.Duration {
+	idx := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,119 @@
+package baselines
+
+import (
+	"fmt"
+	"time"
+)
+
+// minRateAlerts is the fewest alerts in 24h that can be flagged as a rate
+// anomaly, so a second alert on a quiet monitor is not "20x usual rate"
+const minRateAlerts = 3
+
+// Priority added to an analysis per flag, capped at maxPriorityBoost
+const (
+	warningBoost     = 10
+	infoBoost        = 5
+	maxPriorityBoost = 25
+)
+
+// Evaluate compares an alert that started at `at` with the monitor's baseline,
+// which should cover the window before the alert. recent is the number of
+// alerts that started in the 24h up to and including this one.
+func Evaluate(b *Baseline, at time.Time, recent int, config Config) []Flag {
+	if b.Alerts == 0 {
+		return []Flag{{
+			Kind:     FlagFirst,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("first alert in %s", days(b.Window)),
+			Observed: 1,
+		}}
+	}
+
+	var flags []Flag
+
+	if recent >= minRateAlerts && b.AlertsPerDay > 0 {
+		ratio := float64(recent) / b.AlertsPerDay
+		if ratio >= config.RateFactor {
+			flags = append(flags, Flag{
+				Kind:     FlagRate,
+				Severity: SeverityWarning,
+				Message:  fmt.Sprintf("%.1fx usual rate: %d alerts in 24h, usually %.1f per day", ratio, recent, b.AlertsPerDay),
+				Observed: float64(recent),
+				Expected: b.AlertsPerDay,
+			})
+		}
+	}
+
+	// Time patterns need enough history to mean anything
+	if b.Alerts < config.MinAlerts {
+		return flags
+	}
+
+	local := at.In(config.location())
+	weekday := local.Weekday()
+	if b.WeekdayCounts[weekday] == 0 {
+		message := fmt.Sprintf("this monitor never fires on %ss (0 of %d alerts in %s)", weekday, b.Alerts, days(b.Window))
+		if isWeekend(weekday) && b.WeekdayCounts[time.Saturday]+b.WeekdayCounts[time.Sunday] == 0 {
+			message = fmt.Sprintf("this monitor never fires on weekends (0 of %d alerts in %s)", b.Alerts, days(b.Window))
+		}
+		flags = append(flags, Flag{
+			Kind:     FlagDayOfWeek,
+			Severity: SeverityWarning,
+			Message:  message,
+			Observed: 1,
+		})
+	}
+
+	if hour := local.Hour(); b.HourCounts[hour] == 0 {
+		flags = append(flags, Flag{
+			Kind:     FlagHourOfDay,
+			Severity: SeverityInfo,
+			Message:  fmt.Sprintf("has not fired between %02d:00 and %02d:00 %s in %s", hour, (hour+1)%24, b.Timezone, days(b.Window)),
+			Observed: 1,
+		})
+	}
+
+	return flags
+}
+
+// durationFlag flags an open alert that has lasted well past the usual recovery time
+func durationFlag(b *Baseline, openFor time.Duration) *Flag {
+	if b.Resolved == 0 || b.P90Duration <= 0 || openFor <= 2*b.P90Duration {
+		return nil
+	}
+	return &Flag{
+		Kind:     FlagDuration,
+		Severity: SeverityWarning,
+		Message:  fmt.Sprintf("open for %s, usually recovers within %s (p90)", openFor.Round(time.Minute), b.P90Duration.Round(time.Minute)),
+		Observed: openFor.Minutes(),
+		Expected: b.P90Duration.Minutes(),
+	}
+}
+
+// PriorityBoost converts flags into extra analysis priority
+func PriorityBoost(flags []Flag) int {
+	boost := 0
+	for _, flag := range flags {
+		switch flag.Severity {
+		case SeverityWarning:
+			boost += warningBoost
+		case SeverityInfo:
+			boost += infoBoost
+		}
+	}
+	return min(boost, maxPriorityBoost)
+}
+
+// isWeekend reports whether a weekday is Saturday or Sunday
+func isWeekend(day time.Weekday) bool {
+	return day == time.Saturday || day == time.Sunday
+}
+
+// days renders a window as a whole number of days
+func days(window time.Duration) string {
+	n := int(window.Round(24*time.Hour) / (24 * time.Hour))
+	if n == 1 {
+		return "1 day"
+	}
+	return fmt.Sprintf("%d days", n)
+}
@@ -0,0 +1,359 @@
+package baselines
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// checkCacheSize bounds the memoized per-event flags (the same alert is
+// checked for notifications and again for analysis priority)
+const checkCacheSize = 256
+
+// Config controls baseline computation and anomaly flags
+type Config struct {
+	// Window is how much alert history a baseline covers
+	// Default: 30 days
+	Window time.Duration
+
+	// RefreshInterval is how often the job recomputes every monitor's
+	// baseline; 0 disables the job and baselines are computed on demand
+	// Default: 1h
+	RefreshInterval time.Duration
+
+	// MinAlerts is the history needed before day/hour patterns are flagged
+	// Default: 5
+	MinAlerts int
+
+	// RateFactor flags alerting at this multiple of the usual daily rate
+	// Default: 3
+	RateFactor float64
+
+	// Location is the timezone for hour and weekday patterns
+	// Default: UTC
+	Location *time.Location
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Window:          30 * 24 * time.Hour,
+		RefreshInterval: time.Hour,
+		MinAlerts:       5,
+		RateFactor:      3,
+		Location:        time.UTC,
+	}
+}
+
+// ConfigFromEnv returns the defaults overridden by BASELINE_WINDOW,
+// BASELINE_REFRESH_INTERVAL, BASELINE_MIN_ALERTS, BASELINE_RATE_FACTOR and
+// BASELINE_TIMEZONE
+func ConfigFromEnv() Config {
+	config := DefaultConfig()
+	if v, err := time.ParseDuration(os.Getenv("BASELINE_WINDOW")); err == nil && v > 0 {
+		config.Window = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("BASELINE_REFRESH_INTERVAL")); err == nil && v >= 0 {
+		config.RefreshInterval = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("BASELINE_MIN_ALERTS")); err == nil && v > 0 {
+		config.MinAlerts = v
+	}
+	if v, err := strconv.ParseFloat(os.Getenv("BASELINE_RATE_FACTOR"), 64); err == nil && v > 1 {
+		config.RateFactor = v
+	}
+	if name := os.Getenv("BASELINE_TIMEZONE"); name != "" {
+		if loc, err := time.LoadLocation(name); err == nil {
+			config.Location = loc
+		} else {
+			log.Printf("[BASELINES] Ignoring BASELINE_TIMEZONE %q: %v", name, err)
+		}
+	}
+	return config
+}
+
+// location returns the configured timezone (UTC when unset)
+func (c Config) location() *time.Location {
+	if c.Location == nil {
+		return time.UTC
+	}
+	return c.Location
+}
+
+// EventSource interface at consumer side; implemented by *webhooks.Storage
+type EventSource interface {
+	GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]webhooks.WebhookEvent, error)
+	GetMonitorIDsSince(since time.Time) ([]int64, error)
+}
+
+// Engine computes per-monitor baselines from stored alert history and flags
+// alerts that deviate from them
+type Engine struct {
+	events EventSource
+	store  Store
+	config Config
+	now    func() time.Time
+
+	mu           sync.Mutex
+	checked      map[int64][]Flag
+	checkedOrder []int64
+}
+
+// NewEngine creates a baseline engine
+func NewEngine(events EventSource, store Store, config Config) *Engine {
+	defaults := DefaultConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.MinAlerts <= 0 {
+		config.MinAlerts = defaults.MinAlerts
+	}
+	if config.RateFactor <= 1 {
+		config.RateFactor = defaults.RateFactor
+	}
+	return &Engine{
+		events:  events,
+		store:   store,
+		config:  config,
+		now:     time.Now,
+		checked: make(map[int64][]Flag),
+	}
+}
+
+// Run recomputes all baselines every RefreshInterval until ctx is cancelled
+func (e *Engine) Run(ctx context.Context) {
+	if e.config.RefreshInterval <= 0 {
+		log.Printf("[BASELINES] Refresh job disabled; baselines are computed on demand")
+		return
+	}
+
+	ticker := time.NewTicker(e.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := e.Refresh(ctx); err != nil {
+			log.Printf("[BASELINES] Refresh failed: %v", err)
+		} else {
+			log.Printf("[BASELINES] Refreshed %d monitor baselines", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh recomputes the baseline of every monitor with events in the window
+func (e *Engine) Refresh(ctx context.Context) (int, error) {
+	now := e.now()
+	ids, err := e.events.GetMonitorIDsSince(now.Add(-e.config.Window))
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return refreshed, ctx.Err()
+		}
+		if _, err := e.compute(ctx, id, now); err != nil {
+			log.Printf("[BASELINES] Failed to compute baseline for monitor %d: %v", id, err)
+			continue
+		}
+		refreshed++
+	}
+	return refreshed, nil
+}
+
+// Baseline returns the stored baseline, recomputing it when missing or stale
+func (e *Engine) Baseline(ctx context.Context, monitorID int64) (*Baseline, error) {
+	return e.baselineAt(ctx, monitorID, e.now())
+}
+
+// baselineAt returns a baseline covering the window before at
+func (e *Engine) baselineAt(ctx context.Context, monitorID int64, at time.Time) (*Baseline, error) {
+	baseline, err := e.store.Get(ctx, monitorID)
+	if err == nil && !baseline.To.After(at) && at.Sub(baseline.To) <= e.maxAge() {
+		return baseline, nil
+	}
+	if err != nil && !errors.Is(err, ErrBaselineNotFound) {
+		return nil, err
+	}
+	// Leave the alert being checked out of its own baseline
+	return e.compute(ctx, monitorID, at.Add(-time.Millisecond))
+}
+
+// maxAge is how old a stored baseline may be before it is recomputed
+func (e *Engine) maxAge() time.Duration {
+	if e.config.RefreshInterval > 0 {
+		return 2 * e.config.RefreshInterval
+	}
+	return time.Hour
+}
+
+// compute builds and stores the baseline for the window ending at to
+func (e *Engine) compute(ctx context.Context, monitorID int64, to time.Time) (*Baseline, error) {
+	from := to.Add(-e.config.Window)
+	history, err := e.events.GetMonitorEventsBetween(monitorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	baseline := Compute(monitorID, history, from, to, e.config.location())
+	if err := e.store.Save(ctx, baseline); err != nil {
+		log.Printf("[BASELINES] Failed to store baseline for monitor %d: %v", monitorID, err)
+	}
+	return baseline, nil
+}
+
+// Check flags how an incoming alert deviates from its monitor's baseline.
+// Recoveries and events without a monitor are never flagged. Errors are
+// logged and yield no flags so alert processing is never blocked.
+func (e *Engine) Check(ctx context.Context, event *types.AlertEvent) []Flag {
+	p := event.Payload
+	if p.MonitorID == 0 || isRecovery(p.AlertStatus, p.AlertState) {
+		return nil
+	}
+	if flags, ok := e.cached(event.ID); ok {
+		return flags
+	}
+
+	at := event.ReceivedAt
+	if at.IsZero() {
+		at = e.now()
+	}
+
+	baseline, err := e.baselineAt(ctx, p.MonitorID, at)
+	if err != nil {
+		log.Printf("[BASELINES] Failed to load baseline for monitor %d: %v", p.MonitorID, err)
+		return nil
+	}
+	recent, err := e.events.GetMonitorEventsBetween(p.MonitorID, at.Add(-24*time.Hour), at)
+	if err != nil {
+		log.Printf("[BASELINES] Failed to load recent alerts for monitor %d: %v", p.MonitorID, err)
+		return nil
+	}
+
+	// The alert being checked counts even if it was not stored first
+	count := max(alertsBetween(recent, at.Add(-24*time.Hour), at), 1)
+	flags := Evaluate(baseline, at, count, e.config)
+	if len(flags) > 0 {
+		log.Printf("[BASELINES] Event %d (monitor %d) deviates from baseline: %d flags", event.ID, p.MonitorID, len(flags))
+	}
+	e.remember(event.ID, flags)
+	return flags
+}
+
+// Anomalies returns the flag messages for an alert (webhooks.AnomalyDetector)
+func (e *Engine) Anomalies(ctx context.Context, event *types.AlertEvent) []string {
+	var messages []string
+	for _, flag := range e.Check(ctx, event) {
+		messages = append(messages, flag.Message)
+	}
+	return messages
+}
+
+// AdjustPriority raises the analysis priority of anomalous alerts (agents.PriorityAdjuster)
+func (e *Engine) AdjustPriority(ctx context.Context, event *types.AlertEvent) int {
+	return PriorityBoost(e.Check(ctx, event))
+}
+
+// Insights returns a monitor's baseline, its recent activity and the flags for
+// its latest alert (evaluated against the history before that alert)
+func (e *Engine) Insights(ctx context.Context, monitorID int64) (*Insights, error) {
+	now := e.now()
+	from := now.Add(-e.config.Window)
+	history, err := e.events.GetMonitorEventsBetween(monitorID, from, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(history) == 0 {
+		return nil, ErrNoHistory
+	}
+
+	baseline := Compute(monitorID, history, from, now, e.config.location())
+	if err := e.store.Save(ctx, baseline); err != nil {
+		log.Printf("[BASELINES] Failed to store baseline for monitor %d: %v", monitorID, err)
+	}
+
+	insights := &Insights{
+		MonitorID: monitorID,
+		Baseline:  baseline,
+		Current: Current{
+			At:            now,
+			AlertsLast24h: alertsBetween(history, now.Add(-24*time.Hour), now),
+		},
+		Flags: []Flag{},
+	}
+	if baseline.AlertsPerDay > 0 {
+		insights.Current.RateRatio = float64(insights.Current.AlertsLast24h) / baseline.AlertsPerDay
+	}
+
+	all := Episodes(history)
+	if len(all) == 0 {
+		return insights, nil
+	}
+	for _, ep := range all {
+		if ep.End == nil && (insights.Current.OpenSince == nil || ep.Start.Before(*insights.Current.OpenSince)) {
+			start := ep.Start
+			insights.Current.OpenSince = &start
+			insights.Current.OpenFor = now.Sub(start)
+		}
+	}
+
+	// Flag the latest alert while it is still open or happened in the last day
+	latest := all[len(all)-1]
+	if latest.End == nil || now.Sub(latest.Start) <= 24*time.Hour {
+		var before []webhooks.WebhookEvent
+		for _, event := range history {
+			if event.ReceivedAt.Before(latest.Start) {
+				before = append(before, event)
+			}
+		}
+		prior := Compute(monitorID, before, latest.Start.Add(-e.config.Window), latest.Start.Add(-time.Millisecond), e.config.location())
+		recent := alertsBetween(history, latest.Start.Add(-24*time.Hour), latest.Start)
+		insights.Flags = append(insights.Flags, Evaluate(prior, latest.Start, recent, e.config)...)
+	}
+	if insights.Current.OpenSince != nil {
+		if flag := durationFlag(baseline, insights.Current.OpenFor); flag != nil {
+			insights.Flags = append(insights.Flags, *flag)
+		}
+	}
+	return insights, nil
+}
+
+// cached returns memoized flags for an event
+func (e *Engine) cached(eventID int64) ([]Flag, bool) {
+	if eventID == 0 {
+		return nil, false
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	flags, ok := e.checked[eventID]
+	return flags, ok
+}
+
+// remember memoizes an event's flags, evicting the oldest beyond checkCacheSize
+func (e *Engine) remember(eventID int64, flags []Flag) {
+	if eventID == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.checked[eventID]; !ok {
+		e.checkedOrder = append(e.checkedOrder, eventID)
+	}
+	e.checked[eventID] = flags
+	if len(e.checkedOrder) > checkCacheSize {
+		delete(e.checked, e.checkedOrder[0])
+		e.checkedOrder = e.checkedOrder[1:]
+	}
+}
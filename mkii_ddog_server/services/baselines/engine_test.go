@@ -0,0 +1,165 @@
+package baselines
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// now is Saturday 2026-06-06 03:30 UTC
+var now = time.Date(2026, 6, 6, 3, 30, 0, 0, time.UTC)
+
+// fakeEvents serves an in-memory webhook history (oldest first)
+type fakeEvents struct {
+	history []webhooks.WebhookEvent
+}
+
+func (f *fakeEvents) add(monitorID int64, status, scope string, at time.Time) int64 {
+	id := int64(len(f.history) + 1)
+	f.history = append(f.history, webhooks.WebhookEvent{
+		ID:         id,
+		ReceivedAt: at,
+		Payload:    webhooks.WebhookPayload{MonitorID: monitorID, MonitorName: "Checkout latency", AlertStatus: status, Scope: scope},
+	})
+	return id
+}
+
+func (f *fakeEvents) GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]webhooks.WebhookEvent, error) {
+	var out []webhooks.WebhookEvent
+	for _, event := range f.history {
+		if event.Payload.MonitorID == monitorID && !event.ReceivedAt.Before(from) && !event.ReceivedAt.After(to) {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEvents) GetMonitorIDsSince(since time.Time) ([]int64, error) {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, event := range f.history {
+		if id := event.Payload.MonitorID; !seen[id] && !event.ReceivedAt.Before(since) {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// weekdayHistory adds a 20-minute alert at 10:00 UTC on every weekday of the
+// four weeks before now, with a re-notification on the first one
+func weekdayHistory(monitorID int64) *fakeEvents {
+	events := &fakeEvents{}
+	start := time.Date(2026, 5, 9, 10, 0, 0, 0, time.UTC)
+	for day := start; day.Before(now); day = day.Add(24 * time.Hour) {
+		if isWeekend(day.Weekday()) {
+			continue
+		}
+		events.add(monitorID, "Alert", "env:prod", day)
+		if len(events.history) == 1 {
+			events.add(monitorID, "Alert", "env:prod", day.Add(5*time.Minute))
+		}
+		events.add(monitorID, "OK", "env:prod", day.Add(20*time.Minute))
+	}
+	return events
+}
+
+func newTestEngine(events EventSource) *Engine {
+	engine := NewEngine(events, NewMemoryStore(), DefaultConfig())
+	engine.now = func() time.Time { return now }
+	return engine
+}
+
+func TestCompute(t *testing.T) {
+	events := weekdayHistory(7)
+	events.add(7, "Alert", "env:staging", now.Add(-2*time.Hour)) // other scope, still open
+
+	b := Compute(7, events.history, now.Add(-30*24*time.Hour), now, time.UTC)
+
+	if b.Alerts != 21 || b.Ongoing != 1 || b.Resolved != 20 {
+		t.Fatalf("expected 20 resolved weekday alerts plus one open, got %+v", b)
+	}
+	if b.HourCounts[10] != 20 || b.WeekdayCounts[time.Saturday]+b.WeekdayCounts[time.Sunday] != 1 {
+		t.Errorf("unexpected patterns hours=%v weekdays=%v", b.HourCounts, b.WeekdayCounts)
+	}
+	if b.MTTR != 20*time.Minute || b.P90Duration != 20*time.Minute {
+		t.Errorf("expected 20m recoveries, got mttr=%v p90=%v", b.MTTR, b.P90Duration)
+	}
+	if b.ObservedDays >= 26 || b.AlertsPerDay < 0.8 || b.AlertsPerDay > 0.85 {
+		t.Errorf("rate should be measured from the first event, got %.2f/day over %.1f days", b.AlertsPerDay, b.ObservedDays)
+	}
+}
+
+func TestEngine_Check(t *testing.T) {
+	events := weekdayHistory(7)
+	events.add(7, "Alert", "host:a", now.Add(-9*time.Hour)) // Friday evening
+	events.add(7, "Alert", "host:b", now.Add(-8*time.Hour))
+	id := events.add(7, "Alert", "host:c", now)
+	engine := newTestEngine(events)
+
+	alert := &types.AlertEvent{ID: id, ReceivedAt: now, Payload: types.AlertPayload{MonitorID: 7, AlertStatus: "Alert"}}
+	flags := engine.Check(context.Background(), alert)
+
+	kinds := make(map[FlagKind]Flag)
+	for _, flag := range flags {
+		kinds[flag.Kind] = flag
+	}
+	if len(flags) != 3 {
+		t.Fatalf("expected rate, weekend and hour flags, got %+v", flags)
+	}
+	if !strings.HasPrefix(kinds[FlagDayOfWeek].Message, "this monitor never fires on weekends") {
+		t.Errorf("unexpected day flag %q", kinds[FlagDayOfWeek].Message)
+	}
+	if rate := kinds[FlagRate]; rate.Observed != 4 || !strings.Contains(rate.Message, "x usual rate") {
+		t.Errorf("unexpected rate flag %+v", rate)
+	}
+	if boost := engine.AdjustPriority(context.Background(), alert); boost != maxPriorityBoost {
+		t.Errorf("expected the capped priority boost, got %d", boost)
+	}
+
+	recovery := &types.AlertEvent{ReceivedAt: now, Payload: types.AlertPayload{MonitorID: 7, AlertStatus: "OK"}}
+	if flags := engine.Check(context.Background(), recovery); flags != nil {
+		t.Errorf("recoveries should not be flagged, got %+v", flags)
+	}
+
+	fresh := &types.AlertEvent{ReceivedAt: now, Payload: types.AlertPayload{MonitorID: 99, AlertStatus: "Alert"}}
+	if messages := engine.Anomalies(context.Background(), fresh); len(messages) != 1 || messages[0] != "first alert in 30 days" {
+		t.Errorf("expected a first-alert flag, got %v", messages)
+	}
+}
+
+func TestEngine_Insights(t *testing.T) {
+	events := weekdayHistory(7)
+	events.add(7, "Alert", "env:prod", now.Add(-3*time.Hour))
+	engine := newTestEngine(events)
+
+	insights, err := engine.Insights(context.Background(), 7)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if insights.Current.OpenFor != 3*time.Hour || insights.Current.AlertsLast24h != 2 {
+		t.Fatalf("unexpected current activity %+v", insights.Current)
+	}
+
+	var duration *Flag
+	for i, flag := range insights.Flags {
+		if flag.Kind == FlagDuration {
+			duration = &insights.Flags[i]
+		}
+	}
+	if duration == nil || duration.Message != "open for 3h0m0s, usually recovers within 20m0s (p90)" {
+		t.Errorf("expected a duration flag, got %+v", insights.Flags)
+	}
+	if stored, err := engine.store.Get(context.Background(), 7); err != nil || stored.Alerts != insights.Baseline.Alerts {
+		t.Errorf("expected the baseline to be stored, got %+v (%v)", stored, err)
+	}
+
+	if _, err := engine.Insights(context.Background(), 99); !errors.Is(err, ErrNoHistory) {
+		t.Errorf("expected ErrNoHistory, got %v", err)
+	}
+}
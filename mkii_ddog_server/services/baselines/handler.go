@@ -0,0 +1,35 @@
+package baselines
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// Handler handles monitor insight HTTP requests
+type Handler struct {
+	engine *Engine
+}
+
+// NewHandler creates a new baseline handler
+func NewHandler(engine *Engine) *Handler {
+	return &Handler{engine: engine}
+}
+
+// GetMonitorInsights returns a monitor's baseline and how its latest alert
+// deviates from it (GET /v1/webhooks/monitor/{monitorId}/insights)
+func (h *Handler) GetMonitorInsights(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	monitorID, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid monitor ID"}
+	}
+
+	insights, err := h.engine.Insights(r.Context(), monitorID)
+	if errors.Is(err, ErrNoHistory) {
+		return http.StatusNotFound, map[string]string{"error": err.Error()}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, insights
+}
@@ -0,0 +1,73 @@
+package baselines
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Storage persists baselines in Postgres (`monitor_baselines`, one row per monitor)
+type Storage struct {
+	db *sql.DB
+}
+
+// NewStorage creates a new baseline storage
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// InitTables creates the baselines table
+func (s *Storage) InitTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS monitor_baselines (
+			monitor_id BIGINT PRIMARY KEY,
+			monitor_name TEXT,
+			baseline JSONB NOT NULL,
+			computed_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+	`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// Save upserts the monitor's baseline
+func (s *Storage) Save(ctx context.Context, baseline *Baseline) error {
+	data, err := json.Marshal(baseline)
+	if err != nil {
+		return fmt.Errorf("encode baseline: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO monitor_baselines (monitor_id, monitor_name, baseline, computed_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (monitor_id) DO UPDATE
+		SET monitor_name = EXCLUDED.monitor_name, baseline = EXCLUDED.baseline, computed_at = EXCLUDED.computed_at
+	`, baseline.MonitorID, baseline.MonitorName, data, baseline.ComputedAt)
+	if err != nil {
+		return fmt.Errorf("save baseline for monitor %d: %w", baseline.MonitorID, err)
+	}
+	return nil
+}
+
+// Get returns the monitor's latest baseline
+func (s *Storage) Get(ctx context.Context, monitorID int64) (*Baseline, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `
+		SELECT baseline FROM monitor_baselines WHERE monitor_id = $1
+	`, monitorID).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrBaselineNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get baseline for monitor %d: %w", monitorID, err)
+	}
+
+	var baseline Baseline
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return nil, fmt.Errorf("decode baseline for monitor %d: %w", monitorID, err)
+	}
+	return &baseline, nil
+}
@@ -0,0 +1,42 @@
+package baselines
+
+import (
+	"context"
+	"sync"
+)
+
+// Store persists the latest baseline per monitor
+type Store interface {
+	Save(ctx context.Context, baseline *Baseline) error
+	Get(ctx context.Context, monitorID int64) (*Baseline, error)
+}
+
+// MemoryStore keeps baselines in memory (tests and DB-less runs)
+type MemoryStore struct {
+	baselines map[int64]Baseline
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{baselines: make(map[int64]Baseline)}
+}
+
+// Save replaces the monitor's baseline with a copy
+func (s *MemoryStore) Save(ctx context.Context, baseline *Baseline) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baselines[baseline.MonitorID] = *baseline
+	return nil
+}
+
+// Get returns a copy of the monitor's baseline
+func (s *MemoryStore) Get(ctx context.Context, monitorID int64) (*Baseline, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	baseline, ok := s.baselines[monitorID]
+	if !ok {
+		return nil, ErrBaselineNotFound
+	}
+	return &baseline, nil
+}
@@ -0,0 +1,86 @@
+package baselines
+
+import (
+	"errors"
+	"time"
+)
+
+// Sentinel errors
+var (
+	ErrBaselineNotFound = errors.New("baseline not found")
+	ErrNoHistory        = errors.New("monitor has no alert history")
+)
+
+// FlagKind identifies how an alert deviates from its monitor's baseline
+type FlagKind string
+
+const (
+	FlagRate      FlagKind = "rate"        // alerting well above the usual rate
+	FlagDayOfWeek FlagKind = "day_of_week" // fired on a day it normally never does
+	FlagHourOfDay FlagKind = "hour_of_day" // fired at an hour it normally never does
+	FlagDuration  FlagKind = "duration"    // open longer than it usually takes to recover
+	FlagFirst     FlagKind = "first_alert" // no alerts in the whole window
+)
+
+// Flag severities
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+)
+
+// Baseline summarizes a monitor's alert history over a window. An alert is
+// one episode: the first non-OK event on a scope up to its recovery.
+type Baseline struct {
+	MonitorID   int64         `json:"monitor_id"`
+	MonitorName string        `json:"monitor_name"`
+	Window      time.Duration `json:"window"`
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	ComputedAt  time.Time     `json:"computed_at"`
+	Timezone    string        `json:"timezone"`
+
+	// Frequency
+	Alerts       int        `json:"alerts"`
+	Events       int        `json:"events"`
+	ObservedDays float64    `json:"observed_days"`
+	AlertsPerDay float64    `json:"alerts_per_day"`
+	FirstAlertAt *time.Time `json:"first_alert_at,omitempty"`
+	LastAlertAt  *time.Time `json:"last_alert_at,omitempty"`
+
+	// Time-of-day and day-of-week patterns (alert starts, in Timezone)
+	HourCounts    [24]int `json:"hour_counts"`
+	WeekdayCounts [7]int  `json:"weekday_counts"` // Sunday first
+
+	// Duration of resolved alerts
+	Resolved       int           `json:"resolved"`
+	Ongoing        int           `json:"ongoing"`
+	MTTR           time.Duration `json:"mttr"`
+	MedianDuration time.Duration `json:"median_duration"`
+	P90Duration    time.Duration `json:"p90_duration"`
+}
+
+// Flag describes one deviation from the baseline
+type Flag struct {
+	Kind     FlagKind `json:"kind"`
+	Severity string   `json:"severity"`
+	Message  string   `json:"message"`
+	Observed float64  `json:"observed"`
+	Expected float64  `json:"expected"`
+}
+
+// Current is the monitor's recent activity compared against the baseline
+type Current struct {
+	At            time.Time     `json:"at"`
+	AlertsLast24h int           `json:"alerts_last_24h"`
+	RateRatio     float64       `json:"rate_ratio"`
+	OpenSince     *time.Time    `json:"open_since,omitempty"`
+	OpenFor       time.Duration `json:"open_for,omitempty"`
+}
+
+// Insights is the response of GET /v1/webhooks/monitor/{id}/insights
+type Insights struct {
+	MonitorID int64     `json:"monitor_id"`
+	Baseline  *Baseline `json:"baseline"`
+	Current   Current   `json:"current"`
+	Flags     []Flag    `json:"flags"`
+}
@@ -0,0 +1,306 @@
+package changes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+)
+
+// AccountLister interface at consumer side (interface ownership)
+type AccountLister interface {
+	GetAll() ([]accounts.Account, error)
+}
+
+// PollerConfig controls the Datadog deployment event poller
+type PollerConfig struct {
+	// Interval between polls; 0 disables polling
+	// Default: 5m
+	Interval time.Duration
+
+	// Tags filters events (Events API `tags`, comma-separated)
+	// Default: deployment
+	Tags string
+
+	// Sources filters events by source (Events API `sources`, comma-separated)
+	Sources string
+}
+
+// PollerConfigFromEnv reads CHANGES_DATADOG_POLL_INTERVAL, CHANGES_DATADOG_TAGS
+// and CHANGES_DATADOG_SOURCES
+func PollerConfigFromEnv() PollerConfig {
+	config := PollerConfig{Interval: 5 * time.Minute, Tags: "deployment"}
+	if v := os.Getenv("CHANGES_DATADOG_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			config.Interval = d
+		} else {
+			log.Printf("[CHANGES] Invalid CHANGES_DATADOG_POLL_INTERVAL %q, using %s", v, config.Interval)
+		}
+	}
+	if v, ok := os.LookupEnv("CHANGES_DATADOG_TAGS"); ok {
+		config.Tags = v
+	}
+	config.Sources = os.Getenv("CHANGES_DATADOG_SOURCES")
+	return config
+}
+
+// DatadogPoller pulls deployment events from the Datadog Events API of every
+// active account and records them as changes
+type DatadogPoller struct {
+	client   *http.Client
+	tracker  *Tracker
+	accounts AccountLister
+	config   PollerConfig
+	lastPoll map[string]time.Time
+	mu       sync.Mutex
+}
+
+// NewDatadogPoller creates a poller; accounts may be nil to use env credentials
+func NewDatadogPoller(tracker *Tracker, accounts AccountLister, config PollerConfig) *DatadogPoller {
+	return &DatadogPoller{
+		client:   httpclient.DatadogClient,
+		tracker:  tracker,
+		accounts: accounts,
+		config:   config,
+		lastPoll: make(map[string]time.Time),
+	}
+}
+
+// Run polls until ctx is cancelled. A zero interval disables polling.
+func (p *DatadogPoller) Run(ctx context.Context) {
+	if p.config.Interval <= 0 {
+		log.Printf("[CHANGES] Datadog deployment polling disabled")
+		return
+	}
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		if recorded, err := p.Poll(ctx); err != nil {
+			log.Printf("[CHANGES] Datadog poll failed: %v", err)
+		} else if recorded > 0 {
+			log.Printf("[CHANGES] Recorded %d Datadog deployment events", recorded)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll fetches events since the last poll (or the lookback window) for every
+// account and returns how many new changes were recorded. Accounts that fail
+// are logged and retried from the same point next time.
+func (p *DatadogPoller) Poll(ctx context.Context) (int, error) {
+	targets, err := p.targets()
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for _, target := range targets {
+		n, err := p.pollAccount(ctx, target.name, target.creds)
+		if err != nil {
+			log.Printf("[CHANGES] Datadog poll failed for account %q: %v", target.name, err)
+			continue
+		}
+		recorded += n
+	}
+	return recorded, nil
+}
+
+// pollTarget is one account to poll
+type pollTarget struct {
+	name  string
+	creds keys.Credentials
+}
+
+// targets returns the active accounts, or the env credentials without an account manager
+func (p *DatadogPoller) targets() ([]pollTarget, error) {
+	if p.accounts == nil {
+		return []pollTarget{{name: "", creds: keys.Default()}}, nil
+	}
+
+	all, err := p.accounts.GetAll()
+	if err != nil {
+		return nil, fmt.Errorf("list accounts: %w", err)
+	}
+
+	var targets []pollTarget
+	for _, account := range all {
+		if !account.Active {
+			continue
+		}
+		targets = append(targets, pollTarget{
+			name:  account.Name,
+			creds: keys.Credentials{APIKey: account.APIKey, AppKey: account.AppKey, BaseURL: account.BaseURL},
+		})
+	}
+	return targets, nil
+}
+
+// pollAccount fetches and records one account's events
+func (p *DatadogPoller) pollAccount(ctx context.Context, accountName string, creds keys.Credentials) (int, error) {
+	now := p.tracker.now()
+
+	p.mu.Lock()
+	from, ok := p.lastPoll[accountName]
+	p.mu.Unlock()
+	if !ok {
+		from = now.Add(-p.tracker.Lookback())
+	}
+
+	events, err := p.fetchEvents(ctx, creds, from, now)
+	if err != nil {
+		return 0, err
+	}
+
+	recorded := 0
+	for _, event := range events {
+		_, created, err := p.tracker.Record(ctx, datadogChange(event, accountName, creds.BaseURL))
+		if err != nil {
+			log.Printf("[CHANGES] Skipping Datadog event %d: %v", event.ID, err)
+			continue
+		}
+		if created {
+			recorded++
+		}
+	}
+
+	// Overlap polls slightly; duplicates are skipped by external ID
+	p.mu.Lock()
+	p.lastPoll[accountName] = now.Add(-time.Minute)
+	p.mu.Unlock()
+	return recorded, nil
+}
+
+// fetchEvents calls GET /api/v1/events for the window
+func (p *DatadogPoller) fetchEvents(ctx context.Context, creds keys.Credentials, from, to time.Time) ([]datadogEvent, error) {
+	params := url.Values{}
+	params.Set("start", strconv.FormatInt(from.Unix(), 10))
+	params.Set("end", strconv.FormatInt(to.Unix(), 10))
+	params.Set("unaggregated", "true")
+	if p.config.Tags != "" {
+		params.Set("tags", p.config.Tags)
+	}
+	if p.config.Sources != "" {
+		params.Set("sources", p.config.Sources)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", creds.BuildURL(accounts.PathEvents)+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("DD-API-KEY", creds.APIKey)
+	req.Header.Set("DD-APPLICATION-KEY", creds.AppKey)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("events API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var decoded datadogEventsResponse
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		return nil, fmt.Errorf("decode events: %w", err)
+	}
+	return decoded.Events, nil
+}
+
+// datadogChange maps a Datadog event to a change. service:, env:, version:
+// and host: tags fill the target; change_type:config marks config changes.
+func datadogChange(event datadogEvent, accountName, baseURL string) Change {
+	change := Change{
+		Source:      SourceDatadog,
+		ExternalID:  fmt.Sprintf("%s:%d", accountName, event.ID),
+		Kind:        KindDeploy,
+		Host:        event.Host,
+		Title:       event.Title,
+		Description: truncate(event.Text, 1000),
+		Tags:        event.Tags,
+		OccurredAt:  time.Unix(event.DateHappened, 0),
+	}
+
+	for _, tag := range event.Tags {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "service":
+			change.Service = value
+		case "env":
+			change.Env = value
+		case "version":
+			change.Version = value
+		case "host":
+			if change.Host == "" {
+				change.Host = value
+			}
+		case "change_type":
+			if value == string(KindConfig) {
+				change.Kind = KindConfig
+			}
+		}
+	}
+
+	if event.URL != "" {
+		change.URL = event.URL
+		if strings.HasPrefix(event.URL, "/") {
+			change.URL = appURL(baseURL) + event.URL
+		}
+	}
+	return change
+}
+
+// appURL maps an API base URL (https://api.<site>) to the web app URL
+func appURL(baseURL string) string {
+	if baseURL == "" {
+		baseURL = keys.DefaultBaseURL
+	}
+	return strings.Replace(strings.TrimSuffix(baseURL, "/"), "://api.", "://app.", 1)
+}
+
+// truncate shortens s to n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// Datadog Events API v1 types
+type datadogEventsResponse struct {
+	Events []datadogEvent `json:"events"`
+}
+
+type datadogEvent struct {
+	ID           int64    `json:"id"`
+	Title        string   `json:"title"`
+	Text         string   `json:"text"`
+	DateHappened int64    `json:"date_happened"`
+	Host         string   `json:"host"`
+	Tags         []string `json:"tags"`
+	URL          string   `json:"url"`
+}
@@ -0,0 +1,70 @@
+package changes
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+)
+
+// fakeAccounts lists canned accounts
+type fakeAccounts []accounts.Account
+
+func (f fakeAccounts) GetAll() ([]accounts.Account, error) {
+	return f, nil
+}
+
+func TestDatadogPoller_Poll(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != accounts.PathEvents || r.Header.Get("DD-API-KEY") != "api" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		queries = append(queries, r.URL.RawQuery)
+		w.Write([]byte(`{"events": [
+			{"id": 101, "title": "Deployed checkout v3", "text": "argo sync", "date_happened": 1780313400,
+			 "tags": ["service:checkout", "env:prod", "version:v3", "deployment"], "url": "/event/event?id=101"},
+			{"id": 102, "title": "Rotated pgbouncer config", "date_happened": 1780314000, "host": "db-1",
+			 "tags": ["change_type:config", "deployment"]},
+			{"id": 103, "title": "Deploy with no target", "date_happened": 1780314000, "tags": ["deployment"]}
+		]}`))
+	}))
+	defer server.Close()
+
+	tracker := newTestTracker(DefaultConfig())
+	poller := NewDatadogPoller(tracker, fakeAccounts{
+		{Name: "prod", APIKey: "api", AppKey: "app", BaseURL: server.URL, Active: true},
+		{Name: "old", APIKey: "api", AppKey: "app", BaseURL: server.URL, Active: false},
+	}, PollerConfig{Tags: "deployment"})
+
+	recorded, err := poller.Poll(context.Background())
+	if err != nil || recorded != 2 {
+		t.Fatalf("expected 2 changes recorded, got %d (%v)", recorded, err)
+	}
+	if len(queries) != 1 {
+		t.Fatalf("expected only the active account to be polled, got %d requests", len(queries))
+	}
+
+	// Overlapping polls return the same events; they are not recorded twice
+	if recorded, _ := poller.Poll(context.Background()); recorded != 0 {
+		t.Fatalf("expected duplicates to be skipped, got %d", recorded)
+	}
+
+	changes, _ := tracker.List(context.Background(), Query{})
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 stored changes, got %+v", changes)
+	}
+	config, deploy := changes[0], changes[1]
+	if deploy.Kind != KindDeploy || deploy.Service != "checkout" || deploy.Version != "v3" || deploy.ExternalID != "prod:101" {
+		t.Errorf("unexpected deploy %+v", deploy)
+	}
+	if deploy.URL != server.URL+"/event/event?id=101" {
+		t.Errorf("expected relative event URL to be resolved, got %s", deploy.URL)
+	}
+	if config.Kind != KindConfig || config.Host != "db-1" {
+		t.Errorf("unexpected config change %+v", config)
+	}
+}
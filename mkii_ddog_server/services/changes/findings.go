@@ -0,0 +1,99 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// FindingCategory is the category of change findings in an AgentContext
+const FindingCategory = "change"
+
+// suspectWindow is how close to the alert a change is flagged as a warning
+const suspectWindow = 30 * time.Minute
+
+// AgentContextSource attaches recent changes to every agent analysis
+// (implements agents.ContextSource)
+type AgentContextSource struct {
+	tracker *Tracker
+}
+
+// NewAgentContextSource creates a context source backed by the tracker
+func NewAgentContextSource(tracker *Tracker) *AgentContextSource {
+	return &AgentContextSource{tracker: tracker}
+}
+
+// Name returns the source name
+func (s *AgentContextSource) Name() string {
+	return "changes"
+}
+
+// Findings returns one finding per change related to the alert, most recent first
+func (s *AgentContextSource) Findings(ctx context.Context, event *types.AlertEvent) ([]agents.Finding, error) {
+	related, err := s.tracker.ForAlert(ctx, event)
+	if err != nil {
+		return nil, err
+	}
+
+	at := event.ReceivedAt
+	if at.IsZero() {
+		at = s.tracker.now()
+	}
+
+	findings := make([]agents.Finding, 0, len(related))
+	for _, change := range related {
+		findings = append(findings, changeFinding(change, at))
+	}
+	return findings, nil
+}
+
+// changeFinding describes a change relative to the alert time
+func changeFinding(change Change, alertAt time.Time) agents.Finding {
+	before := alertAt.Sub(change.OccurredAt).Round(time.Minute)
+
+	summary := fmt.Sprintf("%s %s", change.Kind, utils.FirstNonEmpty(change.Service, change.Host))
+	if change.Version != "" {
+		summary += " " + change.Version
+	}
+	if change.Author != "" {
+		summary += " by " + change.Author
+	}
+	summary += fmt.Sprintf(" %s before the alert", before)
+
+	var details []string
+	details = append(details, change.Title)
+	if change.Description != "" {
+		details = append(details, change.Description)
+	}
+	if change.URL != "" {
+		details = append(details, change.URL)
+	}
+
+	severity := "info"
+	if before <= suspectWindow {
+		severity = "warning"
+	}
+
+	return agents.Finding{
+		Source:    "changes",
+		Category:  FindingCategory,
+		Summary:   summary,
+		Details:   strings.Join(details, "\n"),
+		Severity:  severity,
+		Timestamp: change.OccurredAt,
+		Metadata: map[string]interface{}{
+			"change_id": change.ID,
+			"source":    change.Source,
+			"kind":      change.Kind,
+			"service":   change.Service,
+			"host":      change.Host,
+			"version":   change.Version,
+			"url":       change.URL,
+		},
+	}
+}
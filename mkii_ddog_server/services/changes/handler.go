@@ -0,0 +1,160 @@
+package changes
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+)
+
+// Handler handles change event HTTP requests
+type Handler struct {
+	tracker   *Tracker
+	operators *operator.Authenticator
+}
+
+// NewHandler creates a new change handler
+func NewHandler(tracker *Tracker) *Handler {
+	return &Handler{tracker: tracker}
+}
+
+// SetOperators sets who may record changes over the API (deploy pipelines
+// get their own operator token); without operators POST /v1/changes refuses
+// every request
+func (h *Handler) SetOperators(operators *operator.Authenticator) {
+	h.operators = operators
+}
+
+// changeRequest is the body of POST /v1/changes
+type changeRequest struct {
+	ExternalID  string    `json:"external_id"`
+	Kind        Kind      `json:"kind"`
+	Service     string    `json:"service"`
+	Host        string    `json:"host"`
+	Env         string    `json:"env"`
+	Version     string    `json:"version"`
+	Repo        string    `json:"repo"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Author      string    `json:"author"`
+	URL         string    `json:"url"`
+	Tags        []string  `json:"tags"`
+	OccurredAt  time.Time `json:"occurred_at"`
+}
+
+// RecordChange records a deploy or config change (POST /v1/changes).
+// Resending the same external_id returns the stored change with 200.
+// Changes feed incident correlation, so an operator token is required.
+func (h *Handler) RecordChange(w http.ResponseWriter, r *http.Request) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+
+	var req changeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+	}
+
+	change, created, err := h.tracker.Record(r.Context(), Change{
+		Source:      SourceAPI,
+		ExternalID:  req.ExternalID,
+		Kind:        req.Kind,
+		Service:     req.Service,
+		Host:        req.Host,
+		Env:         req.Env,
+		Version:     req.Version,
+		Repo:        req.Repo,
+		Title:       req.Title,
+		Description: req.Description,
+		Author:      req.Author,
+		URL:         req.URL,
+		Tags:        req.Tags,
+		OccurredAt:  req.OccurredAt,
+	})
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	if !created {
+		return http.StatusOK, change
+	}
+	log.Printf("[CHANGES] Recorded %s %d for %s by %s", change.Kind, change.ID, change.Service, actor)
+	return http.StatusCreated, change
+}
+
+// ListChanges lists recent changes (GET /v1/changes?service=checkout&host=web-1&kind=deploy&since=24h&limit=50).
+// service and host may be repeated or comma-separated.
+func (h *Handler) ListChanges(w http.ResponseWriter, r *http.Request) (int, any) {
+	query := r.URL.Query()
+
+	q := Query{
+		Services: splitParam(query["service"]),
+		Hosts:    splitParam(query["host"]),
+		Kind:     Kind(query.Get("kind")),
+		Limit:    50,
+	}
+	if v, err := strconv.Atoi(query.Get("limit")); err == nil && v > 0 && v <= 500 {
+		q.Limit = v
+	}
+	if v := query.Get("since"); v != "" {
+		since, err := time.ParseDuration(v)
+		if err != nil || since <= 0 {
+			return http.StatusBadRequest, map[string]string{"error": "since must be a positive duration, e.g. 24h"}
+		}
+		q.From = time.Now().Add(-since)
+	}
+
+	changes, err := h.tracker.List(r.Context(), q)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if changes == nil {
+		changes = []Change{}
+	}
+	return http.StatusOK, ChangeListResponse{Changes: changes, Count: len(changes)}
+}
+
+// GetChange returns one change (GET /v1/changes/{id})
+func (h *Handler) GetChange(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid change ID"}
+	}
+
+	change, err := h.tracker.Get(r.Context(), id)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, change
+}
+
+// splitParam flattens repeated and comma-separated query values
+func splitParam(values []string) []string {
+	var out []string
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				out = append(out, v)
+			}
+		}
+	}
+	return out
+}
+
+// errorStatus maps change errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrInvalidChange):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrChangeNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
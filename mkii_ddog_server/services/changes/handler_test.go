@@ -0,0 +1,34 @@
+package changes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+)
+
+func TestHandler_RecordChangeNeedsOperator(t *testing.T) {
+	tracker := newTestTracker(Config{})
+	handler := NewHandler(tracker)
+	handler.SetOperators(operator.NewAuthenticator(map[string]string{"deploybot": "d-token"}))
+
+	record := func(token string) int {
+		r := httptest.NewRequest(http.MethodPost, "/v1/changes", strings.NewReader(`{"kind":"deploy","service":"checkout","version":"v2"}`))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		status, _ := handler.RecordChange(httptest.NewRecorder(), r)
+		return status
+	}
+
+	for token, want := range map[string]int{"": http.StatusUnauthorized, "forged": http.StatusUnauthorized, "d-token": http.StatusCreated} {
+		if status := record(token); status != want {
+			t.Errorf("token %q: got %d, want %d", token, status, want)
+		}
+	}
+	if recorded, _ := tracker.List(t.Context(), Query{}); len(recorded) != 1 {
+		t.Fatalf("expected only the authenticated change recorded, got %+v", recorded)
+	}
+}
@@ -0,0 +1,180 @@
+package changes
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Storage persists change events in Postgres (`change_events`)
+type Storage struct {
+	db *sql.DB
+}
+
+// NewStorage creates a new change storage
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// InitTables creates the change_events table
+func (s *Storage) InitTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS change_events (
+			id SERIAL PRIMARY KEY,
+			source VARCHAR(32) NOT NULL,
+			external_id VARCHAR(255) NOT NULL DEFAULT '',
+			kind VARCHAR(32) NOT NULL,
+			service VARCHAR(255),
+			host VARCHAR(255),
+			env VARCHAR(100),
+			version VARCHAR(255),
+			repo VARCHAR(255),
+			title TEXT NOT NULL,
+			description TEXT,
+			author VARCHAR(255),
+			url TEXT,
+			tags TEXT[],
+			occurred_at TIMESTAMP NOT NULL,
+			received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_change_events_external
+			ON change_events(source, external_id) WHERE external_id <> '';
+		CREATE INDEX IF NOT EXISTS idx_change_events_occurred_at ON change_events(occurred_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_change_events_service ON change_events(LOWER(service), occurred_at DESC);
+		CREATE INDEX IF NOT EXISTS idx_change_events_host ON change_events(LOWER(host), occurred_at DESC);
+	`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+const changeColumns = `id, source, external_id, kind, service, host, env, version, repo, title,
+	description, author, url, tags, occurred_at, received_at`
+
+// Add inserts a change; redeliveries of the same (source, external_id) are skipped
+func (s *Storage) Add(ctx context.Context, change *Change) (bool, error) {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO change_events
+			(source, external_id, kind, service, host, env, version, repo, title,
+			 description, author, url, tags, occurred_at, received_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
+		ON CONFLICT (source, external_id) WHERE external_id <> '' DO NOTHING
+		RETURNING id
+	`, change.Source, change.ExternalID, change.Kind, change.Service, change.Host, change.Env,
+		change.Version, change.Repo, change.Title, change.Description, change.Author, change.URL,
+		pq.Array(change.Tags), change.OccurredAt, change.ReceivedAt).Scan(&change.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Already stored: report the existing ID
+		if err := s.db.QueryRowContext(ctx, `
+			SELECT id FROM change_events WHERE source = $1 AND external_id = $2
+		`, change.Source, change.ExternalID).Scan(&change.ID); err != nil {
+			return false, fmt.Errorf("find existing change %s/%s: %w", change.Source, change.ExternalID, err)
+		}
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("insert change: %w", err)
+	}
+	return true, nil
+}
+
+// Get loads a change by ID
+func (s *Storage) Get(ctx context.Context, id int64) (*Change, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+changeColumns+` FROM change_events WHERE id = $1`, id)
+	change, err := scanChange(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrChangeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get change %d: %w", id, err)
+	}
+	return change, nil
+}
+
+// List returns matching changes, most recent first
+func (s *Storage) List(ctx context.Context, q Query) ([]Change, error) {
+	limit := q.Limit
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var from, to sql.NullTime
+	if !q.From.IsZero() {
+		from = sql.NullTime{Time: q.From, Valid: true}
+	}
+	if !q.To.IsZero() {
+		to = sql.NullTime{Time: q.To, Valid: true}
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+changeColumns+`
+		FROM change_events
+		WHERE ($1 = '' OR kind = $1)
+		  AND ($2::timestamp IS NULL OR occurred_at >= $2)
+		  AND ($3::timestamp IS NULL OR occurred_at <= $3)
+		  AND ((cardinality($4::text[]) = 0 AND cardinality($5::text[]) = 0)
+		       OR LOWER(service) = ANY($4) OR LOWER(host) = ANY($5))
+		ORDER BY occurred_at DESC
+		LIMIT $6
+	`, q.Kind, from, to, pq.Array(lower(q.Services)), pq.Array(lower(q.Hosts)), limit)
+	if err != nil {
+		return nil, fmt.Errorf("list changes: %w", err)
+	}
+	defer rows.Close()
+
+	var changes []Change
+	for rows.Next() {
+		change, err := scanChange(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan change: %w", err)
+		}
+		changes = append(changes, *change)
+	}
+	return changes, rows.Err()
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanChange reads one row selected with changeColumns
+func scanChange(row rowScanner) (*Change, error) {
+	var change Change
+	var service, host, env, version, repo, description, author, url sql.NullString
+	var tags pq.StringArray
+	var receivedAt sql.NullTime
+
+	err := row.Scan(&change.ID, &change.Source, &change.ExternalID, &change.Kind, &service, &host,
+		&env, &version, &repo, &change.Title, &description, &author, &url, &tags,
+		&change.OccurredAt, &receivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	change.Service = service.String
+	change.Host = host.String
+	change.Env = env.String
+	change.Version = version.String
+	change.Repo = repo.String
+	change.Description = description.String
+	change.Author = author.String
+	change.URL = url.String
+	change.Tags = tags
+	change.ReceivedAt = receivedAt.Time
+	return &change, nil
+}
+
+// lower lower-cases values for case-insensitive matching
+func lower(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = strings.ToLower(v)
+	}
+	return out
+}
@@ -0,0 +1,112 @@
+package changes
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Store persists change events. Add is idempotent on (source, external_id):
+// it reports false when the change was already stored, so GitHub redeliveries
+// and overlapping Datadog polls don't duplicate changes.
+type Store interface {
+	Add(ctx context.Context, change *Change) (bool, error)
+	Get(ctx context.Context, id int64) (*Change, error)
+	List(ctx context.Context, q Query) ([]Change, error)
+}
+
+// MemoryStore keeps changes in memory (tests and DB-less runs)
+type MemoryStore struct {
+	changes []Change
+	nextID  int64
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+// Add stores a copy of change and sets its ID
+func (s *MemoryStore) Add(ctx context.Context, change *Change) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if change.ExternalID != "" {
+		for _, existing := range s.changes {
+			if existing.Source == change.Source && existing.ExternalID == change.ExternalID {
+				change.ID = existing.ID
+				return false, nil
+			}
+		}
+	}
+
+	s.nextID++
+	change.ID = s.nextID
+	s.changes = append(s.changes, *change)
+	return true, nil
+}
+
+// Get returns a copy of the change
+func (s *MemoryStore) Get(ctx context.Context, id int64) (*Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, change := range s.changes {
+		if change.ID == id {
+			return &change, nil
+		}
+	}
+	return nil, ErrChangeNotFound
+}
+
+// List returns matching changes, most recent first
+func (s *MemoryStore) List(ctx context.Context, q Query) ([]Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var found []Change
+	for _, change := range s.changes {
+		if q.matches(change) {
+			found = append(found, change)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].OccurredAt.After(found[j].OccurredAt)
+	})
+	if q.Limit > 0 && len(found) > q.Limit {
+		found = found[:q.Limit]
+	}
+	return found, nil
+}
+
+// matches applies the query filters to one change (MemoryStore; Storage does it in SQL)
+func (q Query) matches(change Change) bool {
+	if q.Kind != "" && change.Kind != q.Kind {
+		return false
+	}
+	if !q.From.IsZero() && change.OccurredAt.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && change.OccurredAt.After(q.To) {
+		return false
+	}
+	if len(q.Services) == 0 && len(q.Hosts) == 0 {
+		return true
+	}
+	return containsFold(q.Services, change.Service) || containsFold(q.Hosts, change.Host)
+}
+
+// containsFold reports whether values contains s, ignoring case
+func containsFold(values []string, s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, v := range values {
+		if strings.EqualFold(v, s) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package changes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+)
+
+// Config controls change correlation
+type Config struct {
+	// Lookback is how far before an alert changes are considered related
+	// Default: 2h
+	Lookback time.Duration
+
+	// MaxRelated caps the changes attached to one alert
+	// Default: 10
+	MaxRelated int
+
+	// RepoServices maps GitHub repositories (owner/name) to service names;
+	// unmapped repositories use the repository name
+	RepoServices map[string]string
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{Lookback: 2 * time.Hour, MaxRelated: 10}
+}
+
+// ConfigFromEnv returns the defaults overridden by CHANGES_LOOKBACK,
+// CHANGES_MAX_RELATED and CHANGES_REPO_SERVICES (`owner/repo=service,...`)
+func ConfigFromEnv() Config {
+	config := DefaultConfig()
+	if v, err := time.ParseDuration(os.Getenv("CHANGES_LOOKBACK")); err == nil && v > 0 {
+		config.Lookback = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("CHANGES_MAX_RELATED")); err == nil && v > 0 {
+		config.MaxRelated = v
+	}
+	config.RepoServices = parseRepoServices(os.Getenv("CHANGES_REPO_SERVICES"))
+	return config
+}
+
+// parseRepoServices parses `owner/repo=service,...`
+func parseRepoServices(value string) map[string]string {
+	mapping := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		repo, service, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if ok && strings.TrimSpace(repo) != "" && strings.TrimSpace(service) != "" {
+			mapping[strings.ToLower(strings.TrimSpace(repo))] = strings.TrimSpace(service)
+		}
+	}
+	return mapping
+}
+
+// Tracker records change events and finds the ones related to an alert
+type Tracker struct {
+	store  Store
+	config Config
+	now    func() time.Time
+}
+
+// NewTracker creates a change tracker
+func NewTracker(store Store, config Config) *Tracker {
+	if config.Lookback <= 0 {
+		config.Lookback = 2 * time.Hour
+	}
+	if config.MaxRelated <= 0 {
+		config.MaxRelated = 10
+	}
+	return &Tracker{store: store, config: config, now: time.Now}
+}
+
+// Lookback returns how far before an alert changes are considered related
+func (t *Tracker) Lookback() time.Duration {
+	return t.config.Lookback
+}
+
+// Record validates and stores a change. It reports false when the change was
+// already recorded (same source and external ID).
+func (t *Tracker) Record(ctx context.Context, change Change) (*Change, bool, error) {
+	t.normalize(&change)
+	if err := validate(change); err != nil {
+		return nil, false, err
+	}
+
+	created, err := t.store.Add(ctx, &change)
+	if err != nil {
+		return nil, false, err
+	}
+	if created {
+		log.Printf("[CHANGES] Recorded %s %s from %s: %s (service=%s host=%s)",
+			change.Kind, utils.FirstNonEmpty(change.Version, "-"), change.Source, change.Title, change.Service, change.Host)
+	}
+	return &change, created, nil
+}
+
+// Get returns a change by ID
+func (t *Tracker) Get(ctx context.Context, id int64) (*Change, error) {
+	return t.store.Get(ctx, id)
+}
+
+// List returns matching changes, most recent first
+func (t *Tracker) List(ctx context.Context, q Query) ([]Change, error) {
+	return t.store.List(ctx, q)
+}
+
+// Related returns the changes to the target in the lookback window before at,
+// most recent first
+func (t *Tracker) Related(ctx context.Context, target Target, at time.Time) ([]Change, error) {
+	if target.Empty() {
+		return nil, nil
+	}
+	return t.store.List(ctx, Query{
+		Services: target.Services,
+		Hosts:    target.Hosts,
+		From:     at.Add(-t.config.Lookback),
+		To:       at,
+		Limit:    t.config.MaxRelated,
+	})
+}
+
+// ForAlert returns the changes related to an alert's service and host
+func (t *Tracker) ForAlert(ctx context.Context, event *types.AlertEvent) ([]Change, error) {
+	at := event.ReceivedAt
+	if at.IsZero() {
+		at = t.now()
+	}
+	return t.Related(ctx, AlertTarget(event.Payload), at)
+}
+
+// AlertTarget extracts the services and hosts an alert is about from its
+// service and hostname fields, scope and `service:`/`host:` tags
+func AlertTarget(p types.AlertPayload) Target {
+	var target Target
+	add := func(values *[]string, v string) {
+		v = strings.TrimSpace(v)
+		if v == "" || containsFold(*values, v) {
+			return
+		}
+		*values = append(*values, v)
+	}
+
+	add(&target.Services, p.Service)
+	add(&target.Hosts, p.Hostname)
+
+	tags := append(strings.Split(p.Scope, ","), p.Tags...)
+	for _, tag := range tags {
+		key, value, ok := strings.Cut(strings.TrimSpace(tag), ":")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "service":
+			add(&target.Services, value)
+		case "host":
+			add(&target.Hosts, value)
+		}
+	}
+	return target
+}
+
+// normalize trims fields, fills timestamps and maps repositories to services
+func (t *Tracker) normalize(change *Change) {
+	change.Service = strings.TrimSpace(change.Service)
+	change.Host = strings.TrimSpace(change.Host)
+	change.Repo = strings.TrimSpace(change.Repo)
+	change.Title = strings.TrimSpace(change.Title)
+	if change.Source == "" {
+		change.Source = SourceAPI
+	}
+	if change.Kind == "" {
+		change.Kind = KindDeploy
+	}
+
+	now := t.now()
+	if change.OccurredAt.IsZero() {
+		change.OccurredAt = now
+	}
+	change.ReceivedAt = now
+
+	if change.Service == "" && change.Repo != "" {
+		change.Service = t.serviceForRepo(change.Repo)
+	}
+	if change.Title == "" {
+		change.Title = fmt.Sprintf("%s %s", change.Kind, utils.FirstNonEmpty(change.Service, change.Host, change.Repo))
+		if change.Version != "" {
+			change.Title += " " + change.Version
+		}
+	}
+}
+
+// serviceForRepo maps owner/name to a service via RepoServices, else the repository name
+func (t *Tracker) serviceForRepo(repo string) string {
+	if service, ok := t.config.RepoServices[strings.ToLower(repo)]; ok {
+		return service
+	}
+	if i := strings.LastIndex(repo, "/"); i >= 0 {
+		return repo[i+1:]
+	}
+	return repo
+}
+
+// validate checks a normalized change
+func validate(change Change) error {
+	switch change.Kind {
+	case KindDeploy, KindConfig, KindPush, KindRelease:
+	default:
+		return fmt.Errorf("%w: unknown kind %q (want deploy, config, push or release)", ErrInvalidChange, change.Kind)
+	}
+	switch change.Source {
+	case SourceAPI, SourceGitHub, SourceDatadog:
+	default:
+		return fmt.Errorf("%w: unknown source %q", ErrInvalidChange, change.Source)
+	}
+	if change.Service == "" && change.Host == "" {
+		return fmt.Errorf("%w: service, host or repo is required", ErrInvalidChange)
+	}
+	return nil
+}
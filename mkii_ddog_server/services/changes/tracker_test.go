@@ -0,0 +1,106 @@
+package changes
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+var t0 = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func newTestTracker(config Config) *Tracker {
+	tracker := NewTracker(NewMemoryStore(), config)
+	tracker.now = func() time.Time { return t0 }
+	return tracker
+}
+
+func TestTracker_RecordNormalizesAndDeduplicates(t *testing.T) {
+	tracker := newTestTracker(Config{RepoServices: map[string]string{"acme/checkout-api": "checkout"}})
+	ctx := context.Background()
+
+	change, created, err := tracker.Record(ctx, Change{Source: SourceGitHub, ExternalID: "d-1", Kind: KindRelease, Repo: "acme/checkout-api", Version: "v2.1.0"})
+	if err != nil || !created {
+		t.Fatalf("expected a new change, got %v (%v)", created, err)
+	}
+	if change.Service != "checkout" || change.Title != "release checkout v2.1.0" || !change.OccurredAt.Equal(t0) {
+		t.Fatalf("unexpected normalized change %+v", change)
+	}
+
+	again, created, err := tracker.Record(ctx, Change{Source: SourceGitHub, ExternalID: "d-1", Kind: KindRelease, Repo: "acme/checkout-api"})
+	if err != nil || created || again.ID != change.ID {
+		t.Fatalf("expected the redelivery to be skipped, got created=%v id=%d (%v)", created, again.ID, err)
+	}
+
+	unmapped, _, _ := tracker.Record(ctx, Change{Kind: KindDeploy, Repo: "acme/billing"})
+	if unmapped.Service != "billing" || unmapped.Source != SourceAPI {
+		t.Fatalf("expected repo name as service and api source, got %+v", unmapped)
+	}
+
+	for _, bad := range []Change{{Kind: "rollback", Service: "x"}, {Kind: KindConfig}} {
+		if _, _, err := tracker.Record(ctx, bad); !errors.Is(err, ErrInvalidChange) {
+			t.Errorf("expected ErrInvalidChange for %+v, got %v", bad, err)
+		}
+	}
+}
+
+func TestTracker_ForAlert(t *testing.T) {
+	tracker := newTestTracker(Config{Lookback: time.Hour})
+	ctx := context.Background()
+
+	record := func(c Change) {
+		if _, _, err := tracker.Record(ctx, c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	record(Change{Kind: KindDeploy, Service: "Checkout", Version: "v2", Author: "alice", OccurredAt: t0.Add(-10 * time.Minute)})
+	record(Change{Kind: KindConfig, Host: "db-1", Title: "max_connections 100 -> 50", OccurredAt: t0.Add(-50 * time.Minute)})
+	record(Change{Kind: KindDeploy, Service: "checkout", OccurredAt: t0.Add(-2 * time.Hour)})  // outside the lookback
+	record(Change{Kind: KindDeploy, Service: "search", OccurredAt: t0.Add(-5 * time.Minute)})  // other service
+	record(Change{Kind: KindDeploy, Service: "checkout", OccurredAt: t0.Add(5 * time.Minute)}) // after the alert
+
+	event := &types.AlertEvent{
+		ReceivedAt: t0,
+		Payload: types.AlertPayload{
+			Service: "checkout",
+			Scope:   "host:db-1,env:prod",
+		},
+	}
+	related, err := tracker.ForAlert(ctx, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(related) != 2 || related[0].Version != "v2" || related[1].Host != "db-1" {
+		t.Fatalf("expected the deploy and the config change, most recent first, got %+v", related)
+	}
+
+	findings, err := NewAgentContextSource(tracker).Findings(ctx, event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if findings[0].Summary != "deploy Checkout v2 by alice 10m0s before the alert" || findings[0].Severity != "warning" {
+		t.Errorf("unexpected deploy finding %+v", findings[0])
+	}
+	if findings[1].Severity != "info" || !strings.Contains(findings[1].Details, "max_connections") {
+		t.Errorf("unexpected config finding %+v", findings[1])
+	}
+
+	if none, _ := tracker.ForAlert(ctx, &types.AlertEvent{ReceivedAt: t0}); len(none) != 0 {
+		t.Errorf("alerts without a service or host should have no related changes, got %+v", none)
+	}
+}
+
+func TestAlertTarget(t *testing.T) {
+	target := AlertTarget(types.AlertPayload{
+		Service:  "checkout",
+		Hostname: "web-1",
+		Scope:    "host:web-1, service:cart",
+		Tags:     []string{"service:Checkout", "env:prod"},
+	})
+	if strings.Join(target.Services, ",") != "checkout,cart" || strings.Join(target.Hosts, ",") != "web-1" {
+		t.Fatalf("unexpected target %+v", target)
+	}
+}
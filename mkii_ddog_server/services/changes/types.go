@@ -0,0 +1,79 @@
+package changes
+
+import (
+	"errors"
+	"time"
+)
+
+// Sentinel errors for change operations
+var (
+	ErrInvalidChange  = errors.New("invalid change event")
+	ErrChangeNotFound = errors.New("change event not found")
+)
+
+// Kind is what changed
+type Kind string
+
+const (
+	KindDeploy  Kind = "deploy"
+	KindConfig  Kind = "config"
+	KindPush    Kind = "push"
+	KindRelease Kind = "release"
+)
+
+// Source is where a change event came from
+type Source string
+
+const (
+	SourceAPI     Source = "api"
+	SourceGitHub  Source = "github"
+	SourceDatadog Source = "datadog"
+)
+
+// Change is a deploy, config change, push or release affecting a service or host
+type Change struct {
+	ID          int64     `json:"id"`
+	Source      Source    `json:"source"`
+	ExternalID  string    `json:"external_id,omitempty"` // dedup key within the source (delivery ID, Datadog event ID)
+	Kind        Kind      `json:"kind"`
+	Service     string    `json:"service,omitempty"`
+	Host        string    `json:"host,omitempty"`
+	Env         string    `json:"env,omitempty"`
+	Version     string    `json:"version,omitempty"` // image tag, release tag or commit SHA
+	Repo        string    `json:"repo,omitempty"`
+	Title       string    `json:"title"`
+	Description string    `json:"description,omitempty"`
+	Author      string    `json:"author,omitempty"`
+	URL         string    `json:"url,omitempty"`
+	Tags        []string  `json:"tags,omitempty"`
+	OccurredAt  time.Time `json:"occurred_at"`
+	ReceivedAt  time.Time `json:"received_at"`
+}
+
+// Query filters changes. A change matches when it affects any of the
+// services or hosts; with neither set every change in the window matches.
+type Query struct {
+	Services []string
+	Hosts    []string
+	Kind     Kind
+	From     time.Time
+	To       time.Time
+	Limit    int
+}
+
+// Target is what an alert is about, used to find related changes
+type Target struct {
+	Services []string `json:"services,omitempty"`
+	Hosts    []string `json:"hosts,omitempty"`
+}
+
+// Empty reports whether the target names no service or host
+func (t Target) Empty() bool {
+	return len(t.Services) == 0 && len(t.Hosts) == 0
+}
+
+// ChangeListResponse is the response for listing changes
+type ChangeListResponse struct {
+	Changes []Change `json:"changes"`
+	Count   int      `json:"count"`
+}
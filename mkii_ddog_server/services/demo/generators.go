@@ -115,7 +115,7 @@ func GenerateRUMSession() (rum.Visitor, rum.Session, []rum.RUMEvent) {
 		SessionCount: 1,
 		TotalViews:   pageViews,
 		UserAgent:    userAgent,
-		IPHash:       fmt.Sprintf("hash_%d", rand.Intn(1000000)),
+		IPHash:       fmt.Sprintf("%d:%032x", startTime.Unix()/86400, rand.Uint64()), // shaped like rum IP pseudonyms
 	}
 
 	session := rum.Session{
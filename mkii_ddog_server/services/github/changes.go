@@ -0,0 +1,175 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/changes"
+)
+
+// maxPushCommits caps the commit lines in a push change description
+const maxPushCommits = 10
+
+// ChangeRecorder interface at consumer side (interface ownership);
+// implemented by *changes.Tracker
+type ChangeRecorder interface {
+	Record(ctx context.Context, change changes.Change) (*changes.Change, bool, error)
+}
+
+// PushEvent represents a GitHub push webhook event payload.
+// See: https://docs.github.com/en/webhooks/webhook-events-and-payloads#push
+type PushEvent struct {
+	Ref        string       `json:"ref"`
+	Before     string       `json:"before"`
+	After      string       `json:"after"`
+	Deleted    bool         `json:"deleted"`
+	Compare    string       `json:"compare"`
+	Commits    []PushCommit `json:"commits"`
+	HeadCommit *PushCommit  `json:"head_commit"`
+	Pusher     struct {
+		Name string `json:"name"`
+	} `json:"pusher"`
+	Sender User     `json:"sender"`
+	Repo   PushRepo `json:"repository"`
+}
+
+// PushCommit is a commit in a push event
+type PushCommit struct {
+	ID        string    `json:"id"`
+	Message   string    `json:"message"`
+	URL       string    `json:"url"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// PushRepo is the repository in a push event (adds the default branch and
+// when the push happened)
+type PushRepo struct {
+	Repo
+	DefaultBranch string    `json:"default_branch"`
+	PushedAt      Timestamp `json:"pushed_at"`
+}
+
+// Timestamp decodes GitHub times that push payloads send as Unix seconds
+// and other payloads as RFC 3339 strings; null or empty leaves it zero
+type Timestamp struct {
+	time.Time
+}
+
+// UnmarshalJSON accepts a Unix timestamp or an RFC 3339 string
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" || string(data) == `""` {
+		return nil
+	}
+	var seconds int64
+	if err := json.Unmarshal(data, &seconds); err == nil {
+		t.Time = time.Unix(seconds, 0).UTC()
+		return nil
+	}
+	return json.Unmarshal(data, &t.Time)
+}
+
+// ReleaseEvent represents a GitHub release webhook event payload.
+// See: https://docs.github.com/en/webhooks/webhook-events-and-payloads#release
+type ReleaseEvent struct {
+	Action  string  `json:"action"` // published, created, edited, deleted, prereleased, released, unpublished
+	Release Release `json:"release"`
+	Sender  User    `json:"sender"`
+	Repo    Repo    `json:"repository"`
+}
+
+// Release represents a GitHub release
+type Release struct {
+	ID          int64      `json:"id"`
+	TagName     string     `json:"tag_name"`
+	Name        string     `json:"name"`
+	Body        string     `json:"body"`
+	HTMLURL     string     `json:"html_url"`
+	Prerelease  bool       `json:"prerelease"`
+	Author      User       `json:"author"`
+	PublishedAt *time.Time `json:"published_at"`
+}
+
+// SetChangeRecorder enables ingesting push and release events as change events
+func (h *Handler) SetChangeRecorder(recorder ChangeRecorder) {
+	h.changes = recorder
+}
+
+// pushChange maps a push to the default branch to a change. Pushes to other
+// branches and branch deletions are not changes to anything running. The
+// change happened when the push did (repository.pushed_at), not when the
+// head commit was authored; without pushed_at the tracker uses the arrival time.
+func pushChange(event PushEvent, deliveryID string) (changes.Change, bool) {
+	if event.Deleted || event.HeadCommit == nil || event.Ref != "refs/heads/"+event.Repo.DefaultBranch {
+		return changes.Change{}, false
+	}
+
+	head := event.HeadCommit
+	var lines []string
+	for i, commit := range event.Commits {
+		if i == maxPushCommits {
+			lines = append(lines, fmt.Sprintf("... %d more commits", len(event.Commits)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("%s %s", shortSHA(commit.ID), firstLine(commit.Message)))
+	}
+
+	return changes.Change{
+		Source:      changes.SourceGitHub,
+		ExternalID:  deliveryID,
+		Kind:        changes.KindPush,
+		Version:     shortSHA(head.ID),
+		Repo:        event.Repo.FullName,
+		Title:       fmt.Sprintf("Push to %s@%s: %s", event.Repo.FullName, event.Repo.DefaultBranch, firstLine(head.Message)),
+		Description: strings.Join(lines, "\n"),
+		Author:      utils.FirstNonEmpty(event.Pusher.Name, event.Sender.Login),
+		URL:         utils.FirstNonEmpty(event.Compare, head.URL),
+		OccurredAt:  event.Repo.PushedAt.Time,
+	}, true
+}
+
+// releaseChange maps a published release to a change
+func releaseChange(event ReleaseEvent, deliveryID string) (changes.Change, bool) {
+	if event.Action != "published" {
+		return changes.Change{}, false
+	}
+
+	release := event.Release
+	title := fmt.Sprintf("Release %s of %s", release.TagName, event.Repo.FullName)
+	if release.Name != "" && release.Name != release.TagName {
+		title += ": " + release.Name
+	}
+
+	change := changes.Change{
+		Source:      changes.SourceGitHub,
+		ExternalID:  deliveryID,
+		Kind:        changes.KindRelease,
+		Version:     release.TagName,
+		Repo:        event.Repo.FullName,
+		Title:       title,
+		Description: truncate(release.Body, 1000),
+		Author:      utils.FirstNonEmpty(release.Author.Login, event.Sender.Login),
+		URL:         release.HTMLURL,
+	}
+	if release.PublishedAt != nil {
+		change.OccurredAt = *release.PublishedAt
+	}
+	return change, true
+}
+
+// shortSHA abbreviates a commit SHA
+func shortSHA(sha string) string {
+	if len(sha) > 12 {
+		return sha[:12]
+	}
+	return sha
+}
+
+// firstLine returns the first line of a commit message
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
@@ -0,0 +1,49 @@
+package github
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestPushChange_OccurredAtIsPushTime(t *testing.T) {
+	// The head commit was authored a week before it was pushed
+	payload := `{
+		"ref": "refs/heads/main",
+		"after": "0123456789abcdef0123",
+		"head_commit": {"id": "0123456789abcdef0123", "message": "Fix pool size", "timestamp": "2026-05-01T09:00:00Z"},
+		"repository": {"full_name": "acme/checkout", "default_branch": "main", "pushed_at": 1778490000}
+	}`
+	var event PushEvent
+	if err := json.Unmarshal([]byte(payload), &event); err != nil {
+		t.Fatalf("unmarshal push: %v", err)
+	}
+
+	change, ok := pushChange(event, "delivery-1")
+	if !ok {
+		t.Fatal("expected a push to the default branch to be a change")
+	}
+	if want := time.Unix(1778490000, 0).UTC(); !change.OccurredAt.Equal(want) {
+		t.Fatalf("OccurredAt = %s, want the push time %s", change.OccurredAt, want)
+	}
+
+	// Without pushed_at the tracker falls back to the arrival time
+	event.Repo.PushedAt = Timestamp{}
+	if change, _ := pushChange(event, "delivery-2"); !change.OccurredAt.IsZero() {
+		t.Fatalf("OccurredAt = %s, want zero (arrival time)", change.OccurredAt)
+	}
+}
+
+func TestTimestamp_UnmarshalJSON(t *testing.T) {
+	want := time.Date(2026, 5, 11, 9, 0, 0, 0, time.UTC)
+	for _, input := range []string{`1778490000`, `"2026-05-11T09:00:00Z"`} {
+		var ts Timestamp
+		if err := json.Unmarshal([]byte(input), &ts); err != nil || !ts.Equal(want) {
+			t.Errorf("%s: got %s (%v), want %s", input, ts.Time, err, want)
+		}
+	}
+	var ts Timestamp
+	if err := json.Unmarshal([]byte(`null`), &ts); err != nil || !ts.IsZero() {
+		t.Errorf("null: got %s (%v), want zero", ts.Time, err)
+	}
+}
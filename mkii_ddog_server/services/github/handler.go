@@ -15,6 +15,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/changes"
 )
 
 const maxBodySize = 1 << 20 // 1 MB
@@ -25,6 +27,7 @@ type Handler struct {
 	secret      string
 	notifier    *Notifier
 	agentClient *AgentClient
+	changes     ChangeRecorder // Optional: records push/release events as changes
 }
 
 // NewHandler creates a new GitHub webhook handler.
@@ -39,14 +42,16 @@ func NewHandler(storage *Storage) *Handler {
 
 // ReceiveIssueEvent handles incoming GitHub issue webhook events.
 // Verifies HMAC-SHA256 signature, filters for "issues" event type, deduplicates
-// by X-GitHub-Delivery header, and stores the payload.
+// by X-GitHub-Delivery header, and stores the payload. With a ChangeRecorder,
+// "push" and "release" events are also accepted and recorded as change events.
 func (h *Handler) ReceiveIssueEvent(w http.ResponseWriter, r *http.Request) (int, any) {
 	// Verify X-GitHub-Event header
 	eventType := r.Header.Get("X-GitHub-Event")
 	if eventType == "ping" {
 		return http.StatusOK, map[string]string{"status": "pong"}
 	}
-	if eventType != "issues" {
+	isChange := h.changes != nil && (eventType == "push" || eventType == "release")
+	if eventType != "issues" && !isChange {
 		return http.StatusBadRequest, map[string]string{"error": "unsupported event type"}
 	}
 
@@ -75,6 +80,10 @@ func (h *Handler) ReceiveIssueEvent(w http.ResponseWriter, r *http.Request) (int
 		return http.StatusUnauthorized, map[string]string{"error": "invalid signature"}
 	}
 
+	if isChange {
+		return h.receiveChangeEvent(r.Context(), eventType, deliveryID, body)
+	}
+
 	var payload IssueEvent
 	if err := json.Unmarshal(body, &payload); err != nil {
 		log.Printf("[GITHUB] Invalid payload: %v", err)
@@ -116,6 +125,50 @@ func (h *Handler) ReceiveIssueEvent(w http.ResponseWriter, r *http.Request) (int
 	}
 }
 
+// receiveChangeEvent records a verified push or release event as a change.
+// Events that are not changes (other branches, unpublished releases) are ignored.
+func (h *Handler) receiveChangeEvent(ctx context.Context, eventType, deliveryID string, body []byte) (int, any) {
+	var change changes.Change
+	var ok bool
+	switch eventType {
+	case "push":
+		var payload PushEvent
+		if err := json.Unmarshal(body, &payload); err != nil {
+			log.Printf("[GITHUB] Invalid push payload: %v", err)
+			return http.StatusBadRequest, map[string]string{"error": "invalid JSON payload"}
+		}
+		change, ok = pushChange(payload, deliveryID)
+	case "release":
+		var payload ReleaseEvent
+		if err := json.Unmarshal(body, &payload); err != nil {
+			log.Printf("[GITHUB] Invalid release payload: %v", err)
+			return http.StatusBadRequest, map[string]string{"error": "invalid JSON payload"}
+		}
+		change, ok = releaseChange(payload, deliveryID)
+	}
+	if !ok {
+		return http.StatusOK, map[string]string{"status": "ignored", "event": eventType, "delivery_id": deliveryID}
+	}
+
+	recorded, created, err := h.changes.Record(ctx, change)
+	if err != nil {
+		log.Printf("[GITHUB] Failed to record %s change: %v", eventType, err)
+		return http.StatusInternalServerError, map[string]string{"error": "internal server error"}
+	}
+	if !created {
+		return http.StatusOK, map[string]string{"status": "already processed", "delivery_id": deliveryID}
+	}
+
+	log.Printf("[GITHUB] Recorded %s change for %s (%s) delivery=%s", eventType, recorded.Repo, recorded.Version, deliveryID)
+	return http.StatusAccepted, map[string]any{
+		"change_id":   recorded.ID,
+		"status":      "accepted",
+		"kind":        recorded.Kind,
+		"service":     recorded.Service,
+		"delivery_id": deliveryID,
+	}
+}
+
 // GetIssueEvents retrieves stored GitHub issue events with pagination.
 func (h *Handler) GetIssueEvents(w http.ResponseWriter, r *http.Request) (int, any) {
 	page := 1
@@ -0,0 +1,668 @@
+package incidents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// History windows: how far from the requested event to look for the rest of
+// the episode, and how long after recovery agent records still belong to it
+const (
+	historyWindow      = 7 * 24 * time.Hour
+	analysisSlack      = 15 * time.Minute
+	maxRemediationScan = 500
+	maxFindings        = 10
+)
+
+// ErrNotebooksDisabled is returned by PublishNotebook without a NotebookPublisher
+var ErrNotebooksDisabled = errors.New("notebook publishing is not configured")
+
+// ErrSummariesDisabled is returned by Summarize without a Summarizer and SummaryStore
+var ErrSummariesDisabled = errors.New("summarisation is not configured")
+
+// EventSource reads webhook events (interface at consumer side; *webhooks.Storage)
+type EventSource interface {
+	GetEventByID(id int64) (*webhooks.WebhookEvent, error)
+	GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]webhooks.WebhookEvent, error)
+}
+
+// AnalysisSource reads stored agent analyses (*agents.AnalysisStorage, *agents.MemoryAnalysisStore)
+type AnalysisSource interface {
+	AnalysesForMonitor(ctx context.Context, monitorID int64, from, to time.Time) ([]agents.AnalysisRecord, error)
+}
+
+// ActionSource lists remediation actions (*remediation.Manager)
+type ActionSource interface {
+	List(ctx context.Context, status remediation.ActionStatus, limit int) ([]remediation.Action, error)
+}
+
+// Summarizer writes the postmortem summary from the assembled document (LLM)
+type Summarizer interface {
+	Summarize(ctx context.Context, postmortem *Postmortem) (string, error)
+}
+
+// NotebookPublisher turns a postmortem into a notebook and returns its URL
+type NotebookPublisher interface {
+	Publish(ctx context.Context, postmortem *Postmortem) (string, error)
+}
+
+// GenerateOptions controls postmortem generation
+type GenerateOptions struct {
+	// Summarize uses the stored agent summary instead of the generated one.
+	// It never calls the Summarizer; Generator.Summarize does.
+	Summarize bool
+}
+
+// Generator assembles postmortems from webhook events, processor runs,
+// agent analyses, remediation actions and acknowledgements. Only the event
+// source is required; missing sources leave their sections empty.
+type Generator struct {
+	events     EventSource
+	acks       AckStore
+	analyses   AnalysisSource
+	actions    ActionSource
+	summarizer Summarizer
+	summaries  SummaryStore
+	notebooks  NotebookPublisher
+	now        func() time.Time
+}
+
+// NewGenerator creates a postmortem generator; acks may be nil
+func NewGenerator(events EventSource, acks AckStore) *Generator {
+	return &Generator{events: events, acks: acks, now: time.Now}
+}
+
+// SetAnalysisSource adds agent analyses (root cause, findings, follow-ups, resolution)
+func (g *Generator) SetAnalysisSource(source AnalysisSource) {
+	g.analyses = source
+}
+
+// SetActionSource adds remediation actions (timeline and action items)
+func (g *Generator) SetActionSource(source ActionSource) {
+	g.actions = source
+}
+
+// SetSummarizer enables LLM summarisation (Generator.Summarize)
+func (g *Generator) SetSummarizer(summarizer Summarizer) {
+	g.summarizer = summarizer
+}
+
+// SetSummaryStore keeps agent summaries per incident (GenerateOptions.Summarize)
+func (g *Generator) SetSummaryStore(store SummaryStore) {
+	g.summaries = store
+}
+
+// SetNotebookPublisher enables PublishNotebook
+func (g *Generator) SetNotebookPublisher(publisher NotebookPublisher) {
+	g.notebooks = publisher
+}
+
+// Incident resolves the incident containing a webhook event
+func (g *Generator) Incident(ctx context.Context, eventID int64) (*Incident, error) {
+	incident, _, err := g.incident(eventID)
+	return incident, err
+}
+
+// incident loads the event, its monitor history and the episode around it
+func (g *Generator) incident(eventID int64) (*Incident, []webhooks.WebhookEvent, error) {
+	event, err := g.events.GetEventByID(eventID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, ErrIncidentNotFound
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("load event %d: %w", eventID, err)
+	}
+
+	history, err := g.events.GetMonitorEventsBetween(event.Payload.MonitorID,
+		event.ReceivedAt.Add(-historyWindow), event.ReceivedAt.Add(historyWindow))
+	if err != nil {
+		return nil, nil, fmt.Errorf("load history for monitor %d: %w", event.Payload.MonitorID, err)
+	}
+
+	episode, err := findEpisode(sameScope(history, event.Payload.Scope), eventID)
+	if err != nil {
+		return nil, nil, err
+	}
+	incident := newIncident(episode, g.now())
+	return &incident, episode, nil
+}
+
+// Acknowledge records that actor has taken the incident containing eventID
+func (g *Generator) Acknowledge(ctx context.Context, eventID int64, actor, note string) (*Acknowledgement, error) {
+	if g.acks == nil {
+		return nil, fmt.Errorf("acknowledgement storage is not configured")
+	}
+	incident, err := g.Incident(ctx, eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	ack := &Acknowledgement{
+		IncidentID:     incident.ID,
+		MonitorID:      incident.MonitorID,
+		Actor:          strings.TrimSpace(actor),
+		Note:           strings.TrimSpace(note),
+		AcknowledgedAt: g.now(),
+	}
+	if err := g.acks.AddAck(ctx, ack); err != nil {
+		return nil, err
+	}
+	log.Printf("[INCIDENTS] Incident %d (monitor %d) acknowledged by %s", incident.ID, incident.MonitorID, ack.Actor)
+	return ack, nil
+}
+
+// Generate builds the postmortem for the incident containing eventID
+func (g *Generator) Generate(ctx context.Context, eventID int64, opts GenerateOptions) (*Postmortem, error) {
+	incident, episode, err := g.incident(eventID)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Postmortem{
+		Incident:      *incident,
+		Title:         "Postmortem: " + incident.MonitorName,
+		Timeline:      eventEntries(episode),
+		ActionItems:   []ActionItem{},
+		SummarySource: "generated",
+		GeneratedAt:   g.now(),
+	}
+
+	records := g.incidentAnalyses(ctx, incident)
+	p.Timeline = append(p.Timeline, analysisEntries(records)...)
+	primary := primaryAnalysis(records)
+
+	actions := g.incidentActions(ctx, incident, records)
+	p.Timeline = append(p.Timeline, actionEntries(actions)...)
+
+	if g.acks != nil {
+		acks, err := g.acks.ListAcks(ctx, incident.ID)
+		if err != nil {
+			log.Printf("[INCIDENTS] Failed to load acknowledgements for incident %d: %v", incident.ID, err)
+		}
+		for _, ack := range acks {
+			p.Timeline = append(p.Timeline, TimelineEntry{
+				At:     ack.AcknowledgedAt,
+				Kind:   EntryAck,
+				Title:  "Acknowledged by " + ack.Actor,
+				Detail: ack.Note,
+				Actor:  ack.Actor,
+			})
+		}
+	}
+
+	sort.SliceStable(p.Timeline, func(i, j int) bool {
+		return p.Timeline[i].At.Before(p.Timeline[j].At)
+	})
+
+	p.Impact = impact(incident, episode, primary)
+	p.RootCause, p.Findings = rootCause(primary)
+	p.Resolution = resolution(incident, records)
+	p.ActionItems = actionItems(primary, actions)
+	if primary != nil {
+		p.AnalysisID = primary.AnalysisID
+		if primary.Result != nil {
+			p.NotebookURL = primary.Result.NotebookURL
+		}
+	}
+	p.Summary = generatedSummary(p, len(actions))
+
+	if opts.Summarize {
+		g.storedSummary(ctx, p)
+	}
+	return p, nil
+}
+
+// Summarize asks the Summarizer for a new summary of the incident containing
+// eventID, stores it and returns the postmortem using it. This is the only
+// path that calls the LLM; reads use the stored summary.
+func (g *Generator) Summarize(ctx context.Context, eventID int64) (*Postmortem, error) {
+	if g.summarizer == nil || g.summaries == nil {
+		return nil, ErrSummariesDisabled
+	}
+	p, err := g.Generate(ctx, eventID, GenerateOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := g.summarizer.Summarize(ctx, p)
+	if err != nil {
+		return nil, fmt.Errorf("summarize incident %d: %w", p.Incident.ID, err)
+	}
+	if text = strings.TrimSpace(text); text == "" {
+		return nil, fmt.Errorf("summarize incident %d: agent returned an empty summary", p.Incident.ID)
+	}
+
+	summary := &IncidentSummary{
+		IncidentID:   p.Incident.ID,
+		Summary:      text,
+		AnalysisID:   p.AnalysisID,
+		SummarizedAt: g.now(),
+	}
+	if err := g.summaries.SaveSummary(ctx, summary); err != nil {
+		return nil, err
+	}
+	log.Printf("[INCIDENTS] Stored agent summary for incident %d", p.Incident.ID)
+	applySummary(p, summary)
+	return p, nil
+}
+
+// PublishNotebook creates a notebook from the postmortem and returns its URL
+func (g *Generator) PublishNotebook(ctx context.Context, p *Postmortem) (string, error) {
+	if g.notebooks == nil {
+		return "", ErrNotebooksDisabled
+	}
+	url, err := g.notebooks.Publish(ctx, p)
+	if err != nil {
+		return "", err
+	}
+	log.Printf("[INCIDENTS] Published postmortem for incident %d: %s", p.Incident.ID, url)
+	return url, nil
+}
+
+// storedSummary replaces the generated summary with the stored agent one;
+// without one the generated summary stays and SummaryError says why
+func (g *Generator) storedSummary(ctx context.Context, p *Postmortem) {
+	if g.summaries == nil {
+		p.SummaryError = ErrSummariesDisabled.Error()
+		return
+	}
+	summary, err := g.summaries.GetSummary(ctx, p.Incident.ID)
+	if err != nil {
+		log.Printf("[INCIDENTS] Failed to load summary for incident %d: %v", p.Incident.ID, err)
+		p.SummaryError = err.Error()
+		return
+	}
+	if summary == nil {
+		p.SummaryError = "no agent summary yet; POST /v1/incidents/{id}/postmortem/summary writes one"
+		return
+	}
+	applySummary(p, summary)
+}
+
+// applySummary puts a stored agent summary on the postmortem
+func applySummary(p *Postmortem, summary *IncidentSummary) {
+	summarizedAt := summary.SummarizedAt
+	p.Summary = summary.Summary
+	p.SummarySource = "agent"
+	p.SummarizedAt = &summarizedAt
+}
+
+// incidentAnalyses loads the analyses stored during the incident for its scope
+func (g *Generator) incidentAnalyses(ctx context.Context, incident *Incident) []agents.AnalysisRecord {
+	if g.analyses == nil {
+		return nil
+	}
+
+	records, err := g.analyses.AnalysesForMonitor(ctx, incident.MonitorID,
+		incident.TriggeredAt.Add(-time.Minute), incidentEnd(incident, g.now()).Add(analysisSlack))
+	if err != nil {
+		log.Printf("[INCIDENTS] Failed to load analyses for incident %d: %v", incident.ID, err)
+		return nil
+	}
+
+	var matched []agents.AnalysisRecord
+	for _, record := range records {
+		// Records stored before scopes were tracked have none
+		if record.Scope == incident.Scope || record.Scope == "" {
+			matched = append(matched, record)
+		}
+	}
+	return matched
+}
+
+// incidentActions loads the remediation actions proposed for the incident
+func (g *Generator) incidentActions(ctx context.Context, incident *Incident, records []agents.AnalysisRecord) []remediation.Action {
+	if g.actions == nil {
+		return nil
+	}
+
+	actions, err := g.actions.List(ctx, "", maxRemediationScan)
+	if err != nil {
+		log.Printf("[INCIDENTS] Failed to load remediation actions for incident %d: %v", incident.ID, err)
+		return nil
+	}
+
+	analysisIDs := make(map[string]bool, len(records))
+	for _, record := range records {
+		analysisIDs[record.AnalysisID] = true
+	}
+	end := incidentEnd(incident, g.now())
+
+	var matched []remediation.Action
+	for _, action := range actions {
+		inWindow := action.MonitorID == incident.MonitorID &&
+			!action.CreatedAt.Before(incident.TriggeredAt) && !action.CreatedAt.After(end)
+		if analysisIDs[action.AnalysisID] || inWindow {
+			matched = append(matched, action)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return matched[i].CreatedAt.Before(matched[j].CreatedAt)
+	})
+	return matched
+}
+
+// incidentEnd is the recovery time, or now while ongoing
+func incidentEnd(incident *Incident, now time.Time) time.Time {
+	if incident.ResolvedAt != nil {
+		return *incident.ResolvedAt
+	}
+	return now
+}
+
+// eventEntries turns webhook events and their processor runs into timeline entries
+func eventEntries(episode []webhooks.WebhookEvent) []TimelineEntry {
+	var entries []TimelineEntry
+	previous := ""
+	for i, event := range episode {
+		status := utils.FirstNonEmpty(event.Payload.AlertStatus, "unknown")
+		entry := TimelineEntry{
+			At:     event.ReceivedAt,
+			Detail: event.Payload.AlertTitle,
+			Ref:    fmt.Sprintf("event:%d", event.ID),
+		}
+		switch {
+		case i == 0:
+			entry.Kind, entry.Title = EntryAlert, "Monitor triggered ("+status+")"
+		case isRecovery(event):
+			entry.Kind, entry.Title = EntryRecovery, "Monitor recovered ("+status+")"
+		case status == previous:
+			entry.Kind, entry.Title = EntryTransition, "Re-notified ("+status+")"
+		default:
+			entry.Kind, entry.Title = EntryTransition, "Status changed to "+status
+		}
+		previous = status
+		entries = append(entries, entry)
+
+		if event.ProcessedAt != nil {
+			var detail []string
+			if len(event.ForwardedTo) > 0 {
+				detail = append(detail, "forwarded to "+strings.Join(event.ForwardedTo, ", "))
+			}
+			if event.DowntimeID != "" {
+				detail = append(detail, "created downtime "+event.DowntimeID)
+			}
+			if event.Error != "" {
+				detail = append(detail, "errors: "+event.Error)
+			}
+			entries = append(entries, TimelineEntry{
+				At:     *event.ProcessedAt,
+				Kind:   EntryProcessing,
+				Title:  fmt.Sprintf("Webhook %s", event.Status),
+				Detail: strings.Join(detail, "; "),
+				Ref:    fmt.Sprintf("event:%d", event.ID),
+			})
+		}
+	}
+	return entries
+}
+
+// analysisEntries turns agent analyses and follow-up questions into timeline entries
+func analysisEntries(records []agents.AnalysisRecord) []TimelineEntry {
+	var entries []TimelineEntry
+	for _, record := range records {
+		entry := TimelineEntry{
+			At:    record.CreatedAt,
+			Kind:  EntryAnalysis,
+			Title: fmt.Sprintf("Agent analysis by %s (%s)", record.Agent, record.AgentRole),
+			Actor: record.Agent,
+			Ref:   "analysis:" + record.AnalysisID,
+		}
+		if result := record.Result; result != nil {
+			if !result.StartedAt.IsZero() {
+				entry.At = result.StartedAt
+			}
+			entry.Detail = truncate(firstLine(result.Summary), 300)
+			if !result.Success {
+				entry.Title += " failed"
+				entry.Detail = result.Error
+			}
+		}
+		entries = append(entries, entry)
+
+		for _, turn := range record.Conversation {
+			entries = append(entries, TimelineEntry{
+				At:     turn.AskedAt,
+				Kind:   EntryFollowUp,
+				Title:  "Follow-up: " + truncate(turn.Question, 120),
+				Detail: truncate(firstLine(turn.Answer), 300),
+				Actor:  turn.AskedBy,
+				Ref:    "analysis:" + record.AnalysisID,
+			})
+		}
+	}
+	return entries
+}
+
+// actionEntries turns remediation proposals, decisions and executions into timeline entries
+func actionEntries(actions []remediation.Action) []TimelineEntry {
+	var entries []TimelineEntry
+	for _, action := range actions {
+		ref := "remediation:" + action.ID
+		entries = append(entries, TimelineEntry{
+			At:     action.CreatedAt,
+			Kind:   EntryRemediation,
+			Title:  "Remediation proposed: " + action.Summary,
+			Detail: action.Reason,
+			Actor:  action.ProposedBy,
+			Ref:    ref,
+		})
+
+		if action.DecisionAt != nil {
+			decision := "approved"
+			if action.Status == remediation.StatusRejected || action.Status == remediation.StatusExpired {
+				decision = string(action.Status)
+			}
+			entries = append(entries, TimelineEntry{
+				At:    *action.DecisionAt,
+				Kind:  EntryRemediation,
+				Title: "Remediation " + decision,
+				Actor: action.DecidedBy,
+				Ref:   ref,
+			})
+		}
+
+		if action.ExecutedAt != nil {
+			title := "Remediation " + string(action.Status)
+			if action.DryRun {
+				title += " (dry run)"
+			}
+			entries = append(entries, TimelineEntry{
+				At:     *action.ExecutedAt,
+				Kind:   EntryRemediation,
+				Title:  title,
+				Detail: utils.FirstNonEmpty(action.Error, action.Output),
+				Ref:    ref,
+			})
+		}
+	}
+	return entries
+}
+
+// primaryAnalysis is the newest successful analysis with a root cause
+func primaryAnalysis(records []agents.AnalysisRecord) *agents.AnalysisRecord {
+	for i := len(records) - 1; i >= 0; i-- {
+		record := records[i]
+		if record.Result != nil && record.Result.Success && analysisRootCause(record) != "" {
+			return &records[i]
+		}
+	}
+	return nil
+}
+
+// analysisRootCause prefers the result's root cause over the stored context
+func analysisRootCause(record agents.AnalysisRecord) string {
+	if record.Result != nil && record.Result.RootCause != "" {
+		return record.Result.RootCause
+	}
+	return record.Context.RootCause
+}
+
+// impact lists who and what the incident affected
+func impact(incident *Incident, episode []webhooks.WebhookEvent, primary *agents.AnalysisRecord) []string {
+	duration := incident.Duration.String()
+	if incident.Status == StatusOngoing {
+		duration += " so far (ongoing)"
+	}
+	items := []string{"Duration: " + duration}
+
+	if incident.Scope != "" {
+		items = append(items, "Scope: "+incident.Scope)
+	}
+	if incident.Service != "" {
+		items = append(items, "Service: "+incident.Service)
+	}
+	if incident.Hostname != "" {
+		items = append(items, "Host: "+incident.Hostname)
+	}
+	if incident.Priority != "" {
+		items = append(items, "Priority: "+incident.Priority)
+	}
+
+	notifications := 0
+	for _, event := range episode {
+		if !isRecovery(event) {
+			notifications++
+		}
+	}
+	if notifications > 1 {
+		items = append(items, fmt.Sprintf("%d alert notifications (%d re-notifications or transitions)", notifications, notifications-1))
+	}
+
+	// Custom webhook fields are only kept on the analyzed alert
+	if primary != nil && primary.Event != nil {
+		payload := primary.Event.Payload
+		if payload.Impact != "" {
+			items = append(items, "Stated impact: "+payload.Impact)
+		}
+		if payload.Urgency != "" {
+			items = append(items, "Urgency: "+payload.Urgency)
+		}
+		if team := utils.FirstNonEmpty(payload.ApplicationTeam, payload.SupportGroup); team != "" {
+			items = append(items, "Team: "+team)
+		}
+		if payload.ApplicationLongname != "" {
+			items = append(items, "Application: "+payload.ApplicationLongname)
+		}
+	}
+	return items
+}
+
+// rootCause returns the primary analysis's root cause and notable findings
+func rootCause(primary *agents.AnalysisRecord) (string, []string) {
+	if primary == nil {
+		return "Not determined: no agent analysis completed for this incident.", nil
+	}
+
+	var findings []string
+	for _, f := range primary.Context.Findings {
+		if f.Category == "analysis" || f.Category == "error" || f.Summary == "" {
+			continue // the analysis text is the root cause itself
+		}
+		findings = append(findings, fmt.Sprintf("[%s] %s", f.Source, f.Summary))
+		if len(findings) == maxFindings {
+			break
+		}
+	}
+	return analysisRootCause(*primary), findings
+}
+
+// resolution is the stored recovery summary, newest first
+func resolution(incident *Incident, records []agents.AnalysisRecord) string {
+	for i := len(records) - 1; i >= 0; i-- {
+		if res := records[i].Resolution; res != nil && res.Summary != "" {
+			return res.Summary
+		}
+	}
+	if incident.ResolvedAt != nil {
+		return fmt.Sprintf("Monitor recovered at %s after %s.", formatTime(*incident.ResolvedAt), incident.Duration)
+	}
+	return ""
+}
+
+// actionItems are the agent's recommendations plus remediation actions
+func actionItems(primary *agents.AnalysisRecord, actions []remediation.Action) []ActionItem {
+	items := []ActionItem{}
+	seen := make(map[string]bool)
+	if primary != nil {
+		recommendations := primary.Context.Recommendations
+		if primary.Result != nil && len(primary.Result.Recommendations) > 0 {
+			recommendations = primary.Result.Recommendations
+		}
+		for _, rec := range recommendations {
+			if rec = strings.TrimSpace(rec); rec != "" && !seen[rec] {
+				seen[rec] = true
+				items = append(items, ActionItem{Description: rec, Source: "agent", Status: "open", Ref: "analysis:" + primary.AnalysisID})
+			}
+		}
+	}
+	for _, action := range actions {
+		items = append(items, ActionItem{
+			Description: action.Summary,
+			Source:      "remediation",
+			Status:      string(action.Status),
+			Ref:         "remediation:" + action.ID,
+		})
+	}
+	return items
+}
+
+// generatedSummary is the deterministic summary used without an LLM
+func generatedSummary(p *Postmortem, actions int) string {
+	incident := p.Incident
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s alerted at %s", incident.MonitorName, formatTime(incident.TriggeredAt))
+	if incident.Scope != "" {
+		fmt.Fprintf(&b, " on %s", incident.Scope)
+	}
+	if incident.Status == StatusResolved {
+		fmt.Fprintf(&b, " and recovered after %s.", incident.Duration)
+	} else {
+		fmt.Fprintf(&b, " and is still ongoing (%s so far).", incident.Duration)
+	}
+	if p.AnalysisID != "" {
+		fmt.Fprintf(&b, " Root cause: %s", truncate(firstLine(p.RootCause), 200))
+		if !strings.HasSuffix(b.String(), ".") {
+			b.WriteString(".")
+		}
+	}
+	if actions > 0 {
+		fmt.Fprintf(&b, " %d remediation action(s) proposed.", actions)
+	}
+	return b.String()
+}
+
+// formatTime renders timestamps in UTC for the document
+func formatTime(t time.Time) string {
+	return t.UTC().Format("2006-01-02 15:04:05 UTC")
+}
+
+// firstLine returns the first non-empty line of s
+func firstLine(s string) string {
+	for _, line := range strings.Split(s, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
+// truncate shortens s to n bytes with an ellipsis
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,285 @@
+package incidents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+var t0 = time.Date(2026, 5, 4, 9, 0, 0, 0, time.UTC)
+
+// fakeEvents serves webhook events from memory
+type fakeEvents struct {
+	events []webhooks.WebhookEvent
+}
+
+func (f *fakeEvents) GetEventByID(id int64) (*webhooks.WebhookEvent, error) {
+	for _, event := range f.events {
+		if event.ID == id {
+			return &event, nil
+		}
+	}
+	return nil, sql.ErrNoRows
+}
+
+func (f *fakeEvents) GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]webhooks.WebhookEvent, error) {
+	var found []webhooks.WebhookEvent
+	for _, event := range f.events {
+		if event.Payload.MonitorID == monitorID && !event.ReceivedAt.Before(from) && !event.ReceivedAt.After(to) {
+			found = append(found, event)
+		}
+	}
+	return found, nil
+}
+
+// fakeActions lists canned remediation actions
+type fakeActions []remediation.Action
+
+func (f fakeActions) List(ctx context.Context, status remediation.ActionStatus, limit int) ([]remediation.Action, error) {
+	return f, nil
+}
+
+// fakeAsker answers every question with a fixed summary
+type fakeAsker struct {
+	question  string
+	questions int
+}
+
+func (f *fakeAsker) Ask(ctx context.Context, analysisID, question, askedBy string) (*agents.ConversationTurn, error) {
+	f.question = question
+	f.questions++
+	return &agents.ConversationTurn{Answer: "Checkout was down for 25 minutes because of pool exhaustion.", Success: true}, nil
+}
+
+func webhookEvent(id int64, status, scope string, at time.Time) webhooks.WebhookEvent {
+	processed := at.Add(2 * time.Second)
+	return webhooks.WebhookEvent{
+		ID: id,
+		Payload: webhooks.WebhookPayload{
+			MonitorID:   42,
+			MonitorName: "Checkout DB connections",
+			AlertStatus: status,
+			AlertTitle:  "[" + status + "] Checkout DB connections",
+			Scope:       scope,
+			Service:     "checkout",
+		},
+		ReceivedAt:  at,
+		ProcessedAt: &processed,
+		Status:      "processed",
+		ForwardedTo: []string{"desktop_notify"},
+	}
+}
+
+// history has two incidents on host:db-1 and an overlapping one on host:db-2
+func history() *fakeEvents {
+	return &fakeEvents{events: []webhooks.WebhookEvent{
+		webhookEvent(1, "Alert", "host:db-1", t0.Add(-3*time.Hour)),
+		webhookEvent(2, "OK", "host:db-1", t0.Add(-2*time.Hour)),
+		webhookEvent(3, "Warn", "host:db-1", t0),
+		webhookEvent(4, "Alert", "host:db-1", t0.Add(5*time.Minute)),
+		webhookEvent(5, "Alert", "host:db-2", t0.Add(10*time.Minute)),
+		webhookEvent(6, "Alert", "host:db-1", t0.Add(15*time.Minute)),
+		webhookEvent(7, "OK", "host:db-1", t0.Add(25*time.Minute)),
+	}}
+}
+
+func TestGenerator_IncidentFindsEpisode(t *testing.T) {
+	g := NewGenerator(history(), nil)
+
+	for _, id := range []int64{3, 4, 6, 7} {
+		incident, err := g.Incident(context.Background(), id)
+		if err != nil {
+			t.Fatalf("event %d: %v", id, err)
+		}
+		if incident.ID != 3 || incident.RecoveryEventID != 7 || incident.Status != StatusResolved {
+			t.Fatalf("event %d: unexpected incident %+v", id, incident)
+		}
+		if incident.Duration != 25*time.Minute || len(incident.EventIDs) != 4 {
+			t.Fatalf("event %d: expected 25m over 4 events, got %v over %v", id, incident.Duration, incident.EventIDs)
+		}
+	}
+
+	ongoing, err := g.Incident(context.Background(), 5)
+	if err != nil || ongoing.Status != StatusOngoing || ongoing.ID != 5 {
+		t.Fatalf("expected ongoing incident for the other scope, got %+v (%v)", ongoing, err)
+	}
+
+	if _, err := g.Incident(context.Background(), 99); !errors.Is(err, ErrIncidentNotFound) {
+		t.Fatalf("expected ErrIncidentNotFound, got %v", err)
+	}
+
+	orphan := &fakeEvents{events: []webhooks.WebhookEvent{webhookEvent(1, "OK", "", t0)}}
+	if _, err := NewGenerator(orphan, nil).Incident(context.Background(), 1); !errors.Is(err, ErrNoAlert) {
+		t.Fatalf("expected ErrNoAlert, got %v", err)
+	}
+}
+
+func TestGenerator_GenerateAssemblesPostmortem(t *testing.T) {
+	analyses := agents.NewMemoryAnalysisStore()
+	analyses.SaveAnalysis(context.Background(), &agents.AnalysisRecord{
+		AnalysisID: "an-1",
+		MonitorID:  42,
+		Scope:      "host:db-1",
+		Agent:      "claude-database",
+		AgentRole:  agents.RoleDatabase,
+		Event: &types.AlertEvent{Payload: types.AlertPayload{
+			MonitorID: 42, Impact: "Checkout unavailable", ApplicationTeam: "payments",
+		}},
+		Result: &agents.AnalysisResult{
+			Success:         true,
+			RootCause:       "Connection pool exhausted by a slow migration query",
+			Summary:         "Connection pool exhausted",
+			Recommendations: []string{"Add a statement timeout to migrations"},
+			NotebookURL:     "https://app.datadoghq.com/notebook/123",
+			StartedAt:       t0.Add(6 * time.Minute),
+		},
+		Context: agents.AgentContextSnapshot{
+			Findings: []agents.Finding{
+				{Source: "db", Category: "metrics", Summary: "active connections at 100/100"},
+				{Source: "claude-database", Category: "analysis", Summary: "Claude AI analysis"},
+			},
+		},
+		Conversation: []agents.ConversationTurn{
+			{Question: "Which query?", Answer: "ALTER TABLE orders", AskedBy: "oncall", AskedAt: t0.Add(12 * time.Minute)},
+		},
+		Resolution: &agents.Resolution{Summary: "Connections dropped to 20 after the migration was cancelled."},
+		CreatedAt:  t0.Add(8 * time.Minute),
+	})
+
+	decided := t0.Add(14 * time.Minute)
+	acks := NewMemoryStore()
+	g := NewGenerator(history(), acks)
+	g.now = func() time.Time { return t0.Add(time.Hour) }
+	g.SetAnalysisSource(analyses)
+	g.SetActionSource(fakeActions{
+		{ID: "act-1", MonitorID: 42, AnalysisID: "an-1", Summary: "Restart deployment checkout-api", ProposedBy: "claude-database",
+			Status: remediation.StatusRejected, DecidedBy: "oncall", DecisionAt: &decided, CreatedAt: t0.Add(9 * time.Minute)},
+		{ID: "act-2", MonitorID: 7, Summary: "unrelated", CreatedAt: t0.Add(9 * time.Minute)},
+	})
+
+	if _, err := g.Acknowledge(context.Background(), 4, "oncall", "looking"); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := g.Generate(context.Background(), 6, GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.Incident.ID != 3 || p.AnalysisID != "an-1" || p.NotebookURL == "" {
+		t.Fatalf("unexpected postmortem header: %+v", p.Incident)
+	}
+	if p.RootCause != "Connection pool exhausted by a slow migration query" || len(p.Findings) != 1 {
+		t.Fatalf("unexpected root cause %q / findings %v", p.RootCause, p.Findings)
+	}
+	if !strings.Contains(p.Resolution, "migration was cancelled") {
+		t.Fatalf("expected stored resolution, got %q", p.Resolution)
+	}
+	if len(p.ActionItems) != 2 || p.ActionItems[1].Status != "rejected" {
+		t.Fatalf("unexpected action items: %+v", p.ActionItems)
+	}
+	if !strings.Contains(strings.Join(p.Impact, "\n"), "Stated impact: Checkout unavailable") {
+		t.Fatalf("expected payload impact, got %v", p.Impact)
+	}
+
+	kinds := make(map[EntryKind]int)
+	for i, entry := range p.Timeline {
+		kinds[entry.Kind]++
+		if i > 0 && entry.At.Before(p.Timeline[i-1].At) {
+			t.Fatalf("timeline out of order at %d: %+v", i, p.Timeline)
+		}
+	}
+	want := map[EntryKind]int{EntryAlert: 1, EntryTransition: 2, EntryRecovery: 1, EntryProcessing: 4,
+		EntryAnalysis: 1, EntryFollowUp: 1, EntryRemediation: 2, EntryAck: 1}
+	for kind, n := range want {
+		if kinds[kind] != n {
+			t.Errorf("expected %d %s entries, got %d", n, kind, kinds[kind])
+		}
+	}
+
+	markdown := p.Markdown()
+	for _, section := range []string{"# Postmortem: Checkout DB connections", "## Summary", "## Impact", "## Timeline", "## Root Cause", "## Resolution", "## Action Items"} {
+		if !strings.Contains(markdown, section) {
+			t.Errorf("markdown missing %q", section)
+		}
+	}
+	if p.SummarySource != "generated" || !strings.Contains(p.Summary, "recovered after 25m0s") {
+		t.Fatalf("unexpected generated summary %q", p.Summary)
+	}
+}
+
+func TestGenerator_Summarize(t *testing.T) {
+	analyses := agents.NewMemoryAnalysisStore()
+	analyses.SaveAnalysis(context.Background(), &agents.AnalysisRecord{
+		AnalysisID: "an-1", MonitorID: 42, Scope: "host:db-1",
+		Result:    &agents.AnalysisResult{Success: true, RootCause: "pool exhausted"},
+		CreatedAt: t0.Add(8 * time.Minute),
+	})
+
+	g := NewGenerator(history(), nil)
+	if _, err := g.Summarize(context.Background(), 3); !errors.Is(err, ErrSummariesDisabled) {
+		t.Fatalf("expected ErrSummariesDisabled, got %v", err)
+	}
+
+	asker := &fakeAsker{}
+	g.SetAnalysisSource(analyses)
+	g.SetSummarizer(NewAgentSummarizer(asker))
+	g.SetSummaryStore(NewMemoryStore())
+
+	// Reads never ask the agent; before a summary is stored they say so
+	p, err := g.Generate(context.Background(), 3, GenerateOptions{Summarize: true})
+	if err != nil || p.SummaryError == "" || p.SummarySource != "generated" || asker.questions != 0 {
+		t.Fatalf("expected generated summary before one is stored, got %+v (%v)", p, err)
+	}
+
+	p, err = g.Summarize(context.Background(), 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.SummarySource != "agent" || !strings.Contains(p.Summary, "25 minutes") || p.SummarizedAt == nil {
+		t.Fatalf("expected agent summary, got %q (%s)", p.Summary, p.SummaryError)
+	}
+	if !strings.Contains(asker.question, "Monitor recovered") {
+		t.Fatalf("question should carry the timeline: %s", asker.question)
+	}
+
+	for i := 0; i < 3; i++ {
+		p, err = g.Generate(context.Background(), 6, GenerateOptions{Summarize: true})
+		if err != nil || p.SummarySource != "agent" || !strings.Contains(p.Summary, "25 minutes") {
+			t.Fatalf("expected the stored summary, got %q (%v)", p.Summary, err)
+		}
+	}
+	if asker.questions != 1 {
+		t.Fatalf("expected the agent asked once, got %d", asker.questions)
+	}
+}
+
+func TestNewNotebookRequest(t *testing.T) {
+	p, err := NewGenerator(history(), nil).Generate(context.Background(), 3, GenerateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := newNotebookRequest(p)
+	if req.Data.Attributes.Name != "[Postmortem] Checkout DB connections" {
+		t.Fatalf("unexpected name %q", req.Data.Attributes.Name)
+	}
+	if len(req.Data.Attributes.Cells) != len(p.sections()) {
+		t.Fatalf("expected one cell per section, got %d", len(req.Data.Attributes.Cells))
+	}
+	if req.Data.Attributes.Time.Start != "2026-05-04T08:30:00Z" || req.Data.Attributes.Time.End != "2026-05-04T09:55:00Z" {
+		t.Fatalf("unexpected time frame %+v", req.Data.Attributes.Time)
+	}
+
+	if got := notebookURL("https://api.ddog-gov.com", "77"); got != "https://app.ddog-gov.com/notebook/77" {
+		t.Fatalf("unexpected notebook URL %s", got)
+	}
+}
@@ -0,0 +1,136 @@
+package incidents
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+)
+
+// Handler handles incident HTTP requests. Incidents are addressed by any of
+// their webhook event IDs.
+type Handler struct {
+	generator *Generator
+}
+
+// NewHandler creates a new incident handler
+func NewHandler(generator *Generator) *Handler {
+	return &Handler{generator: generator}
+}
+
+// GetPostmortem renders the postmortem (GET /v1/incidents/{id}/postmortem?format=markdown&summarize=true).
+// JSON by default; Markdown with format=markdown or Accept: text/markdown.
+// summarize=true uses the stored agent summary; it never calls the agent.
+// It writes its own response so Markdown is not JSON-encoded.
+func (h *Handler) GetPostmortem(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		utils.WriteJson(w, http.StatusBadRequest, map[string]string{"error": "invalid incident id"})
+		return
+	}
+
+	postmortem, err := h.generator.Generate(r.Context(), id, GenerateOptions{Summarize: wantsSummary(r)})
+	if err != nil {
+		utils.WriteJson(w, errorStatus(err), map[string]string{"error": err.Error()})
+		return
+	}
+
+	if r.URL.Query().Get("format") == "markdown" || strings.Contains(r.Header.Get("Accept"), "text/markdown") {
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(postmortem.Markdown()))
+		return
+	}
+	utils.WriteJson(w, http.StatusOK, postmortem)
+}
+
+// SummarizePostmortem asks the agent for a new incident summary and stores
+// it for later reads (POST /v1/incidents/{id}/postmortem/summary)
+func (h *Handler) SummarizePostmortem(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid incident id"}
+	}
+
+	postmortem, err := h.generator.Summarize(r.Context(), id)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusCreated, postmortem
+}
+
+// PublishPostmortem creates a Datadog notebook from the postmortem (POST /v1/incidents/{id}/postmortem/notebook?summarize=true).
+// summarize=true uses the stored agent summary.
+func (h *Handler) PublishPostmortem(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid incident id"}
+	}
+
+	postmortem, err := h.generator.Generate(r.Context(), id, GenerateOptions{Summarize: wantsSummary(r)})
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+
+	url, err := h.generator.PublishNotebook(r.Context(), postmortem)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusCreated, map[string]any{
+		"incident_id":    postmortem.Incident.ID,
+		"notebook_url":   url,
+		"summary_source": postmortem.SummarySource,
+	}
+}
+
+// AcknowledgeIncident records who is handling an incident (POST /v1/incidents/{id}/ack)
+func (h *Handler) AcknowledgeIncident(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid incident id"}
+	}
+
+	var req ackRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+	}
+	if strings.TrimSpace(req.Actor) == "" {
+		return http.StatusBadRequest, map[string]string{"error": "actor is required"}
+	}
+
+	ack, err := h.generator.Acknowledge(r.Context(), id, req.Actor, req.Note)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusCreated, ack
+}
+
+// ackRequest is the body of POST /v1/incidents/{id}/ack
+type ackRequest struct {
+	Actor string `json:"actor"`
+	Note  string `json:"note"`
+}
+
+// wantsSummary reports whether the stored agent summary was requested
+func wantsSummary(r *http.Request) bool {
+	summarize, _ := strconv.ParseBool(r.URL.Query().Get("summarize"))
+	return summarize
+}
+
+// errorStatus maps incident errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrIncidentNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNoAlert), errors.Is(err, ErrNoAnalysis):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, ErrNotebooksDisabled), errors.Is(err, ErrSummariesDisabled):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,99 @@
+package incidents
+
+import (
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// isRecovery reports whether a webhook event closes an incident
+func isRecovery(event webhooks.WebhookEvent) bool {
+	switch strings.ToLower(event.Payload.AlertStatus) {
+	case "ok", "recovered", "resolved":
+		return true
+	}
+	return false
+}
+
+// findEpisode returns the events of the incident containing eventID: back to
+// the first alert after the previous recovery, forward to the next recovery.
+// history must be one monitor and scope, oldest first.
+func findEpisode(history []webhooks.WebhookEvent, eventID int64) ([]webhooks.WebhookEvent, error) {
+	idx := -1
+	for i, event := range history {
+		if event.ID == eventID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, ErrIncidentNotFound
+	}
+
+	start, end := idx, idx
+	if isRecovery(history[idx]) {
+		// A recovery closes the episode before it
+		if idx == 0 || isRecovery(history[idx-1]) {
+			return nil, ErrNoAlert
+		}
+		start = idx - 1
+	} else {
+		// Forward to the recovery, or the newest event while still ongoing
+		for end < len(history)-1 && !isRecovery(history[end]) {
+			end++
+		}
+	}
+	for start > 0 && !isRecovery(history[start-1]) {
+		start--
+	}
+	return history[start : end+1], nil
+}
+
+// newIncident summarizes an episode
+func newIncident(episode []webhooks.WebhookEvent, now time.Time) Incident {
+	first := episode[0]
+	last := episode[len(episode)-1]
+
+	incident := Incident{
+		ID:          first.ID,
+		MonitorID:   first.Payload.MonitorID,
+		MonitorName: utils.FirstNonEmpty(first.Payload.MonitorName, first.Payload.AlertTitle),
+		Scope:       first.Payload.Scope,
+		AccountName: first.AccountName,
+		Hostname:    first.Payload.Hostname,
+		Service:     first.Payload.Service,
+		Priority:    first.Payload.Priority,
+		Status:      StatusOngoing,
+		TriggeredAt: first.ReceivedAt,
+		Link:        first.Payload.Link,
+	}
+	for _, event := range episode {
+		incident.EventIDs = append(incident.EventIDs, event.ID)
+	}
+
+	end := now
+	if isRecovery(last) {
+		resolvedAt := last.ReceivedAt
+		incident.Status = StatusResolved
+		incident.ResolvedAt = &resolvedAt
+		incident.RecoveryEventID = last.ID
+		end = resolvedAt
+	}
+	if end.After(incident.TriggeredAt) {
+		incident.Duration = end.Sub(incident.TriggeredAt).Round(time.Second)
+	}
+	return incident
+}
+
+// sameScope keeps the events matching scope (history is one monitor)
+func sameScope(history []webhooks.WebhookEvent, scope string) []webhooks.WebhookEvent {
+	var matched []webhooks.WebhookEvent
+	for _, event := range history {
+		if event.Payload.Scope == scope {
+			matched = append(matched, event)
+		}
+	}
+	return matched
+}
@@ -0,0 +1,106 @@
+package incidents
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Markdown renders the postmortem as a Markdown document
+func (p *Postmortem) Markdown() string {
+	var b strings.Builder
+	for _, section := range p.sections() {
+		b.WriteString(section)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// sections renders each part of the document; notebooks use one cell per section
+func (p *Postmortem) sections() []string {
+	incident := p.Incident
+
+	var header strings.Builder
+	fmt.Fprintf(&header, "# %s\n\n", p.Title)
+	header.WriteString("| Field | Value |\n|-------|-------|\n")
+	fmt.Fprintf(&header, "| Incident | %d |\n", incident.ID)
+	fmt.Fprintf(&header, "| Monitor | %s (ID: %d) |\n", escapeCell(incident.MonitorName), incident.MonitorID)
+	if incident.Scope != "" {
+		fmt.Fprintf(&header, "| Scope | %s |\n", escapeCell(incident.Scope))
+	}
+	fmt.Fprintf(&header, "| Status | %s |\n", strings.ToUpper(incident.Status))
+	fmt.Fprintf(&header, "| Triggered | %s |\n", formatTime(incident.TriggeredAt))
+	if incident.ResolvedAt != nil {
+		fmt.Fprintf(&header, "| Resolved | %s |\n", formatTime(*incident.ResolvedAt))
+	}
+	fmt.Fprintf(&header, "| Duration | %s |\n", incident.Duration)
+	if p.NotebookURL != "" {
+		fmt.Fprintf(&header, "| Analysis notebook | %s |\n", p.NotebookURL)
+	}
+
+	summary := "## Summary\n\n" + p.Summary + "\n"
+	if p.SummarySource == "agent" {
+		summary += "\n> Summary written by the analysis agent from the timeline below.\n"
+	}
+
+	var impact strings.Builder
+	impact.WriteString("## Impact\n\n")
+	for _, item := range p.Impact {
+		fmt.Fprintf(&impact, "- %s\n", item)
+	}
+
+	var timeline strings.Builder
+	timeline.WriteString("## Timeline\n\n")
+	timeline.WriteString("| Time (UTC) | Event | Details |\n|------------|-------|---------|\n")
+	for _, entry := range p.Timeline {
+		title := entry.Title
+		if entry.Actor != "" && !strings.Contains(title, entry.Actor) {
+			title += " — " + entry.Actor
+		}
+		fmt.Fprintf(&timeline, "| %s | %s | %s |\n",
+			entry.At.UTC().Format("2006-01-02 15:04:05"), escapeCell(title), escapeCell(entry.Detail))
+	}
+
+	var rootCause strings.Builder
+	rootCause.WriteString("## Root Cause\n\n")
+	rootCause.WriteString(p.RootCause + "\n")
+	if len(p.Findings) > 0 {
+		rootCause.WriteString("\n### Supporting findings\n\n")
+		for _, finding := range p.Findings {
+			fmt.Fprintf(&rootCause, "- %s\n", finding)
+		}
+	}
+
+	sections := []string{header.String(), summary, impact.String(), timeline.String(), rootCause.String()}
+
+	if p.Resolution != "" {
+		sections = append(sections, "## Resolution\n\n"+p.Resolution+"\n")
+	}
+
+	var items strings.Builder
+	items.WriteString("## Action Items\n\n")
+	if len(p.ActionItems) == 0 {
+		items.WriteString("_None recorded._\n")
+	}
+	for _, item := range p.ActionItems {
+		box := " "
+		if item.Status == "succeeded" {
+			box = "x"
+		}
+		fmt.Fprintf(&items, "- [%s] %s (%s", box, item.Description, item.Source)
+		if item.Source != "agent" && item.Status != "" {
+			fmt.Fprintf(&items, ", %s", item.Status)
+		}
+		items.WriteString(")\n")
+	}
+	sections = append(sections, items.String())
+
+	footer := fmt.Sprintf("---\n\n_Generated %s from webhook events, processor runs, agent analyses, remediation actions and acknowledgements._\n",
+		formatTime(p.GeneratedAt))
+	return append(sections, footer)
+}
+
+// escapeCell keeps text on one table row
+func escapeCell(s string) string {
+	s = strings.ReplaceAll(s, "|", "\\|")
+	return strings.Join(strings.Fields(s), " ")
+}
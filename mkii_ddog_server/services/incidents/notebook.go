@@ -0,0 +1,179 @@
+package incidents
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+)
+
+// CredentialProvider interface at consumer side (interface ownership)
+type CredentialProvider interface {
+	GetByName(name string) (*accounts.Account, error)
+	GetDefault() *accounts.Account
+}
+
+// DatadogNotebookPublisher creates a Datadog notebook per postmortem, with
+// one Markdown cell per section, using the incident account's credentials.
+// Every call creates a new notebook.
+type DatadogNotebookPublisher struct {
+	client   *http.Client
+	accounts CredentialProvider
+}
+
+// NewDatadogNotebookPublisher creates a publisher; accounts may be nil to use env credentials
+func NewDatadogNotebookPublisher(accounts CredentialProvider) *DatadogNotebookPublisher {
+	return &DatadogNotebookPublisher{
+		client:   httpclient.DatadogClient,
+		accounts: accounts,
+	}
+}
+
+// Publish creates the notebook and returns its app URL
+func (n *DatadogNotebookPublisher) Publish(ctx context.Context, p *Postmortem) (string, error) {
+	creds := n.credentials(p.Incident.AccountName)
+
+	jsonBody, err := json.Marshal(newNotebookRequest(p))
+	if err != nil {
+		return "", fmt.Errorf("marshal notebook: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", creds.BuildURL(accounts.PathNotebooks), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("DD-API-KEY", creds.APIKey)
+	req.Header.Set("DD-APPLICATION-KEY", creds.AppKey)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return "", fmt.Errorf("notebook API returned %d: %s", resp.StatusCode, string(body))
+	}
+
+	var created struct {
+		Data struct {
+			ID json.Number `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.Data.ID == "" {
+		return "", fmt.Errorf("decode notebook response: %s", string(body))
+	}
+	return notebookURL(creds.BaseURL, created.Data.ID.String()), nil
+}
+
+// credentials returns the named account's credentials, the default account's, or env keys
+func (n *DatadogNotebookPublisher) credentials(accountName string) keys.Credentials {
+	if n.accounts == nil {
+		return keys.Default()
+	}
+
+	var account *accounts.Account
+	if accountName != "" {
+		account, _ = n.accounts.GetByName(accountName)
+	}
+	if account == nil {
+		account = n.accounts.GetDefault()
+	}
+	if account == nil {
+		return keys.Default()
+	}
+
+	return keys.Credentials{
+		APIKey:  account.APIKey,
+		AppKey:  account.AppKey,
+		BaseURL: account.BaseURL,
+	}
+}
+
+// newNotebookRequest builds the notebook: one Markdown cell per section,
+// with the time frame covering the incident
+func newNotebookRequest(p *Postmortem) notebookRequest {
+	start := p.Incident.TriggeredAt.Add(-30 * time.Minute)
+	end := p.GeneratedAt
+	if p.Incident.ResolvedAt != nil {
+		end = p.Incident.ResolvedAt.Add(30 * time.Minute)
+	}
+
+	var cells []notebookCell
+	for _, section := range p.sections() {
+		cells = append(cells, notebookCell{
+			Type: "notebook_cells",
+			Attributes: notebookCellAttributes{
+				Definition: notebookMarkdown{Type: "markdown", Text: section},
+			},
+		})
+	}
+
+	return notebookRequest{
+		Data: notebookData{
+			Type: "notebooks",
+			Attributes: notebookAttributes{
+				Name:   "[Postmortem] " + p.Incident.MonitorName,
+				Cells:  cells,
+				Time:   notebookTime{Start: start.UTC().Format(time.RFC3339), End: end.UTC().Format(time.RFC3339)},
+				Status: "published",
+			},
+		},
+	}
+}
+
+// notebookURL maps an API base URL (https://api.<site>) to the app notebook URL
+func notebookURL(baseURL, id string) string {
+	if baseURL == "" {
+		baseURL = keys.DefaultBaseURL
+	}
+	appURL := strings.Replace(strings.TrimSuffix(baseURL, "/"), "://api.", "://app.", 1)
+	return appURL + "/notebook/" + id
+}
+
+// Datadog notebook API types
+type notebookRequest struct {
+	Data notebookData `json:"data"`
+}
+
+type notebookData struct {
+	Type       string             `json:"type"`
+	Attributes notebookAttributes `json:"attributes"`
+}
+
+type notebookAttributes struct {
+	Name   string         `json:"name"`
+	Cells  []notebookCell `json:"cells"`
+	Time   notebookTime   `json:"time"`
+	Status string         `json:"status"`
+}
+
+type notebookCell struct {
+	Type       string                 `json:"type"`
+	Attributes notebookCellAttributes `json:"attributes"`
+}
+
+type notebookCellAttributes struct {
+	Definition notebookMarkdown `json:"definition"`
+}
+
+type notebookMarkdown struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type notebookTime struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
@@ -0,0 +1,121 @@
+package incidents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// Storage persists acknowledgements and agent summaries in Postgres
+// (`incident_acknowledgements`, `incident_summaries`)
+type Storage struct {
+	db *sql.DB
+}
+
+// NewStorage creates a new incident storage
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// InitTables creates the acknowledgements and summaries tables
+func (s *Storage) InitTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS incident_acknowledgements (
+			id SERIAL PRIMARY KEY,
+			incident_id BIGINT NOT NULL,
+			monitor_id BIGINT,
+			actor VARCHAR(255) NOT NULL,
+			note TEXT,
+			acknowledged_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_incident_acks_incident_id ON incident_acknowledgements(incident_id);
+
+		CREATE TABLE IF NOT EXISTS incident_summaries (
+			incident_id BIGINT PRIMARY KEY,
+			summary TEXT NOT NULL,
+			analysis_id VARCHAR(64),
+			summarized_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+		);
+	`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+// AddAck inserts an acknowledgement and sets its ID
+func (s *Storage) AddAck(ctx context.Context, ack *Acknowledgement) error {
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO incident_acknowledgements (incident_id, monitor_id, actor, note, acknowledged_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, ack.IncidentID, ack.MonitorID, ack.Actor, ack.Note, ack.AcknowledgedAt).Scan(&ack.ID)
+	if err != nil {
+		return fmt.Errorf("acknowledge incident %d: %w", ack.IncidentID, err)
+	}
+	return nil
+}
+
+// ListAcks returns an incident's acknowledgements, oldest first
+func (s *Storage) ListAcks(ctx context.Context, incidentID int64) ([]Acknowledgement, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, incident_id, monitor_id, actor, note, acknowledged_at
+		FROM incident_acknowledgements
+		WHERE incident_id = $1
+		ORDER BY acknowledged_at ASC, id ASC
+	`, incidentID)
+	if err != nil {
+		return nil, fmt.Errorf("list acknowledgements for incident %d: %w", incidentID, err)
+	}
+	defer rows.Close()
+
+	var acks []Acknowledgement
+	for rows.Next() {
+		var ack Acknowledgement
+		var monitorID sql.NullInt64
+		var note sql.NullString
+		if err := rows.Scan(&ack.ID, &ack.IncidentID, &monitorID, &ack.Actor, &note, &ack.AcknowledgedAt); err != nil {
+			return nil, fmt.Errorf("scan acknowledgement: %w", err)
+		}
+		ack.MonitorID = monitorID.Int64
+		ack.Note = note.String
+		acks = append(acks, ack)
+	}
+	return acks, rows.Err()
+}
+
+// SaveSummary upserts the incident's agent summary
+func (s *Storage) SaveSummary(ctx context.Context, summary *IncidentSummary) error {
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO incident_summaries (incident_id, summary, analysis_id, summarized_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (incident_id) DO UPDATE SET
+			summary = EXCLUDED.summary,
+			analysis_id = EXCLUDED.analysis_id,
+			summarized_at = EXCLUDED.summarized_at
+	`, summary.IncidentID, summary.Summary, summary.AnalysisID, summary.SummarizedAt)
+	if err != nil {
+		return fmt.Errorf("save summary for incident %d: %w", summary.IncidentID, err)
+	}
+	return nil
+}
+
+// GetSummary returns the incident's stored summary, or nil
+func (s *Storage) GetSummary(ctx context.Context, incidentID int64) (*IncidentSummary, error) {
+	var summary IncidentSummary
+	var analysisID sql.NullString
+	err := s.db.QueryRowContext(ctx, `
+		SELECT incident_id, summary, analysis_id, summarized_at
+		FROM incident_summaries
+		WHERE incident_id = $1
+	`, incidentID).Scan(&summary.IncidentID, &summary.Summary, &analysisID, &summary.SummarizedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load summary for incident %d: %w", incidentID, err)
+	}
+	summary.AnalysisID = analysisID.String
+	return &summary, nil
+}
@@ -0,0 +1,83 @@
+package incidents
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// AckStore persists incident acknowledgements
+type AckStore interface {
+	AddAck(ctx context.Context, ack *Acknowledgement) error
+	ListAcks(ctx context.Context, incidentID int64) ([]Acknowledgement, error)
+}
+
+// SummaryStore persists the agent-written summary of each incident so reads
+// never call the LLM
+type SummaryStore interface {
+	SaveSummary(ctx context.Context, summary *IncidentSummary) error
+	// GetSummary returns nil when the incident has no stored summary
+	GetSummary(ctx context.Context, incidentID int64) (*IncidentSummary, error)
+}
+
+// MemoryStore keeps acknowledgements and summaries in memory (tests and DB-less runs)
+type MemoryStore struct {
+	acks      []Acknowledgement
+	summaries map[int64]IncidentSummary
+	nextID    int64
+	mu        sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{summaries: make(map[int64]IncidentSummary)}
+}
+
+// AddAck stores ack and assigns its ID
+func (s *MemoryStore) AddAck(ctx context.Context, ack *Acknowledgement) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	ack.ID = s.nextID
+	s.acks = append(s.acks, *ack)
+	return nil
+}
+
+// ListAcks returns an incident's acknowledgements, oldest first
+func (s *MemoryStore) ListAcks(ctx context.Context, incidentID int64) ([]Acknowledgement, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var acks []Acknowledgement
+	for _, ack := range s.acks {
+		if ack.IncidentID == incidentID {
+			acks = append(acks, ack)
+		}
+	}
+	sort.SliceStable(acks, func(i, j int) bool {
+		return acks[i].AcknowledgedAt.Before(acks[j].AcknowledgedAt)
+	})
+	return acks, nil
+}
+
+// SaveSummary stores summary, replacing the incident's previous one
+func (s *MemoryStore) SaveSummary(ctx context.Context, summary *IncidentSummary) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summaries[summary.IncidentID] = *summary
+	return nil
+}
+
+// GetSummary returns the incident's stored summary, or nil
+func (s *MemoryStore) GetSummary(ctx context.Context, incidentID int64) (*IncidentSummary, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary, ok := s.summaries[incidentID]
+	if !ok {
+		return nil, nil
+	}
+	return &summary, nil
+}
@@ -0,0 +1,76 @@
+package incidents
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// maxSummaryTimeline caps the timeline lines sent to the agent
+const maxSummaryTimeline = 40
+
+// Asker answers follow-up questions on a stored analysis (*agents.AgentOrchestrator)
+type Asker interface {
+	Ask(ctx context.Context, analysisID, question, askedBy string) (*agents.ConversationTurn, error)
+}
+
+// AgentSummarizer writes the summary by asking the incident's analysis agent
+// a follow-up question with the timeline attached. It goes through the
+// orchestrator, so token budgets and the circuit breaker apply, and the
+// question is kept in the analysis conversation.
+type AgentSummarizer struct {
+	asker Asker
+}
+
+// NewAgentSummarizer creates a summarizer backed by the agent orchestrator
+func NewAgentSummarizer(asker Asker) *AgentSummarizer {
+	return &AgentSummarizer{asker: asker}
+}
+
+// Summarize asks the agent for an executive summary of the postmortem
+func (s *AgentSummarizer) Summarize(ctx context.Context, p *Postmortem) (string, error) {
+	if p.AnalysisID == "" {
+		return "", ErrNoAnalysis
+	}
+
+	turn, err := s.asker.Ask(ctx, p.AnalysisID, summaryQuestion(p), "postmortem")
+	if err != nil {
+		return "", err
+	}
+	if !turn.Success {
+		return "", fmt.Errorf("agent could not summarize: %s", turn.Error)
+	}
+	return turn.Answer, nil
+}
+
+// summaryQuestion asks for the summary with the assembled facts attached
+func summaryQuestion(p *Postmortem) string {
+	var b strings.Builder
+	b.WriteString("Write the executive summary for this incident's postmortem in 3-5 sentences: ")
+	b.WriteString("what happened, the impact, the root cause and how it was resolved. ")
+	b.WriteString("Use only the facts below and your investigation; do not invent times or numbers.\n\n")
+
+	fmt.Fprintf(&b, "Incident: %s, status %s, duration %s\n", p.Incident.MonitorName, p.Incident.Status, p.Incident.Duration)
+	b.WriteString("Impact:\n")
+	for _, item := range p.Impact {
+		fmt.Fprintf(&b, "- %s\n", item)
+	}
+	b.WriteString("Timeline:\n")
+	for i, entry := range p.Timeline {
+		if i == maxSummaryTimeline {
+			fmt.Fprintf(&b, "- ... %d more entries\n", len(p.Timeline)-i)
+			break
+		}
+		fmt.Fprintf(&b, "- %s %s", formatTime(entry.At), entry.Title)
+		if entry.Detail != "" {
+			fmt.Fprintf(&b, ": %s", truncate(entry.Detail, 200))
+		}
+		b.WriteString("\n")
+	}
+	if p.Resolution != "" {
+		fmt.Fprintf(&b, "Resolution: %s\n", p.Resolution)
+	}
+	return b.String()
+}
@@ -0,0 +1,113 @@
+package incidents
+
+import (
+	"errors"
+	"time"
+)
+
+// Sentinel errors
+var (
+	ErrIncidentNotFound = errors.New("incident not found")
+	ErrNoAlert          = errors.New("event is a recovery with no preceding alert")
+	ErrNoAnalysis       = errors.New("incident has no agent analysis to summarize")
+)
+
+// Incident is one alert episode of a monitor and scope: from the first
+// non-OK webhook event to the recovery that closed it
+type Incident struct {
+	ID              int64         `json:"id"` // webhook event ID of the first alert
+	MonitorID       int64         `json:"monitor_id"`
+	MonitorName     string        `json:"monitor_name"`
+	Scope           string        `json:"scope,omitempty"`
+	AccountName     string        `json:"account_name,omitempty"`
+	Hostname        string        `json:"hostname,omitempty"`
+	Service         string        `json:"service,omitempty"`
+	Priority        string        `json:"priority,omitempty"`
+	Status          string        `json:"status"` // ongoing, resolved
+	TriggeredAt     time.Time     `json:"triggered_at"`
+	ResolvedAt      *time.Time    `json:"resolved_at,omitempty"`
+	Duration        time.Duration `json:"duration"`
+	RecoveryEventID int64         `json:"recovery_event_id,omitempty"`
+	EventIDs        []int64       `json:"event_ids"`
+	Link            string        `json:"link,omitempty"`
+}
+
+// Incident statuses
+const (
+	StatusOngoing  = "ongoing"
+	StatusResolved = "resolved"
+)
+
+// EntryKind classifies a timeline entry
+type EntryKind string
+
+const (
+	EntryAlert       EntryKind = "alert"
+	EntryTransition  EntryKind = "transition"
+	EntryRecovery    EntryKind = "recovery"
+	EntryProcessing  EntryKind = "processing"
+	EntryAnalysis    EntryKind = "analysis"
+	EntryFollowUp    EntryKind = "follow_up"
+	EntryRemediation EntryKind = "remediation"
+	EntryAck         EntryKind = "acknowledged"
+)
+
+// TimelineEntry is one thing that happened during the incident
+type TimelineEntry struct {
+	At     time.Time `json:"at"`
+	Kind   EntryKind `json:"kind"`
+	Title  string    `json:"title"`
+	Detail string    `json:"detail,omitempty"`
+	Actor  string    `json:"actor,omitempty"`
+	Ref    string    `json:"ref,omitempty"` // event, analysis or action ID
+}
+
+// ActionItem is a follow-up task for the postmortem
+type ActionItem struct {
+	Description string `json:"description"`
+	Source      string `json:"source"`           // agent, remediation
+	Status      string `json:"status,omitempty"` // open, or the remediation action status
+	Ref         string `json:"ref,omitempty"`
+}
+
+// Postmortem is the structured incident write-up
+type Postmortem struct {
+	Incident    Incident        `json:"incident"`
+	Title       string          `json:"title"`
+	Summary     string          `json:"summary"`
+	Impact      []string        `json:"impact"`
+	Timeline    []TimelineEntry `json:"timeline"`
+	RootCause   string          `json:"root_cause"`
+	Findings    []string        `json:"findings,omitempty"`
+	Resolution  string          `json:"resolution,omitempty"`
+	ActionItems []ActionItem    `json:"action_items"`
+
+	// AnalysisID is the agent analysis the root cause came from
+	AnalysisID  string `json:"analysis_id,omitempty"`
+	NotebookURL string `json:"notebook_url,omitempty"` // analysis notebook
+
+	// SummarySource is "generated" or "agent" (stored LLM summary)
+	SummarySource string     `json:"summary_source"`
+	SummaryError  string     `json:"summary_error,omitempty"`
+	SummarizedAt  *time.Time `json:"summarized_at,omitempty"`
+	GeneratedAt   time.Time  `json:"generated_at"`
+}
+
+// IncidentSummary is the agent-written summary stored for an incident. It
+// is only written by POST /v1/incidents/{id}/postmortem/summary.
+type IncidentSummary struct {
+	IncidentID   int64     `json:"incident_id"`
+	Summary      string    `json:"summary"`
+	AnalysisID   string    `json:"analysis_id,omitempty"`
+	SummarizedAt time.Time `json:"summarized_at"`
+}
+
+// Acknowledgement records someone taking ownership of an incident
+type Acknowledgement struct {
+	ID             int64     `json:"id"`
+	IncidentID     int64     `json:"incident_id"`
+	MonitorID      int64     `json:"monitor_id"`
+	Actor          string    `json:"actor"`
+	Note           string    `json:"note,omitempty"`
+	AcknowledgedAt time.Time `json:"acknowledged_at"`
+}
@@ -1,11 +1,15 @@
 package monitors
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/requests"
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/urls"
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
 )
 
 // ListMonitors retrieves all monitors with pagination
@@ -92,13 +96,12 @@ func GetTriggeredMonitors(w http.ResponseWriter, r *http.Request) (int, any) {
 
 // GetMonitorByID retrieves a specific monitor by ID
 func GetMonitorByID(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
-	id, err := strconv.Atoi(idStr)
+	id, err := strconv.ParseInt(idStr, 10, 64)
 	if err != nil {
 		return http.StatusBadRequest, map[string]string{"error": "invalid monitor ID"}
 	}
 
-	url := urls.ByMonitorId(id)
-	result, status, err := requests.Get[Monitor](w, r, url)
+	result, status, err := FetchMonitor(r.Context(), id, keys.Default())
 	if err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
 	}
@@ -106,6 +109,13 @@ func GetMonitorByID(w http.ResponseWriter, r *http.Request, idStr string) (int,
 	return status, result
 }
 
+// FetchMonitor retrieves a monitor definition (including options) with the
+// given account credentials. Shared by GetMonitorByID and the noise report.
+func FetchMonitor(ctx context.Context, id int64, creds keys.Credentials) (Monitor, int, error) {
+	url := creds.BuildURL(fmt.Sprintf("%s/%d", accounts.PathMonitors, id))
+	return requests.GetWithCreds[Monitor](ctx, url, creds)
+}
+
 // GetMonitorIDs retrieves just the monitor IDs and names
 func GetMonitorIDs(w http.ResponseWriter, r *http.Request) (int, any) {
 	result, status, err := requests.Get[MonitorSearchResponse](w, r, urls.SearchMontiors)
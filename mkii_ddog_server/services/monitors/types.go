@@ -20,6 +20,27 @@ type Monitor struct {
 	Modified     string   `json:"modified"`
 	Creator      Creator  `json:"creator"`
 	OverallState string   `json:"overall_state"`
+
+	// Options is only returned for single-monitor requests (GetMonitorByID)
+	Options *MonitorOptions `json:"options,omitempty"`
+}
+
+// MonitorOptions holds the monitor settings that control when and how it notifies
+type MonitorOptions struct {
+	Thresholds        *MonitorThresholds `json:"thresholds,omitempty"`
+	EvaluationDelay   *int64             `json:"evaluation_delay,omitempty"`
+	NewGroupDelay     *int64             `json:"new_group_delay,omitempty"`
+	NotifyNoData      bool               `json:"notify_no_data"`
+	RenotifyInterval  *int64             `json:"renotify_interval,omitempty"` // minutes; 0 or nil = never
+	RequireFullWindow *bool              `json:"require_full_window,omitempty"`
+}
+
+// MonitorThresholds are the alert and recovery thresholds of a monitor
+type MonitorThresholds struct {
+	Critical         *float64 `json:"critical,omitempty"`
+	Warning          *float64 `json:"warning,omitempty"`
+	CriticalRecovery *float64 `json:"critical_recovery,omitempty"`
+	WarningRecovery  *float64 `json:"warning_recovery,omitempty"`
 }
 
 // Metadata represents pagination metadata
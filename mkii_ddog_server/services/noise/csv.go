@@ -0,0 +1,61 @@
+package noise
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// csvHeader lists the report columns in export order
+var csvHeader = []string{
+	"rank", "monitor_id", "monitor_name", "account", "score", "alerts", "alerts_per_day",
+	"flap_rate", "short_alert_rate", "auto_recovered_rate", "auto_downtimes",
+	"median_duration", "recommendations",
+}
+
+// CSV renders the report with one row per ranked monitor
+func (r *Report) CSV() ([]byte, error) {
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	if err := w.Write(csvHeader); err != nil {
+		return nil, err
+	}
+
+	for _, m := range r.Monitors {
+		var recs []string
+		for _, rec := range m.Recommendations {
+			recs = append(recs, recommendationText(rec))
+		}
+		row := []string{
+			strconv.Itoa(m.Rank),
+			strconv.FormatInt(m.MonitorID, 10),
+			m.MonitorName,
+			m.AccountName,
+			formatValue(m.Score),
+			strconv.Itoa(m.Stats.Alerts),
+			formatValue(m.Stats.AlertsPerDay),
+			formatValue(m.Stats.FlapRate),
+			formatValue(m.Stats.ShortAlertRate),
+			formatValue(m.Stats.AutoRecoveredRate),
+			strconv.Itoa(m.Stats.AutoDowntimes),
+			m.Stats.MedianDuration.String(),
+			strings.Join(recs, "; "),
+		}
+		if err := w.Write(row); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	return buf.Bytes(), w.Error()
+}
+
+// recommendationText renders a recommendation as a single line
+func recommendationText(rec Recommendation) string {
+	if rec.Current != "" {
+		return fmt.Sprintf("%s: %s -> %s", rec.Setting, rec.Current, rec.Suggested)
+	}
+	return fmt.Sprintf("%s: %s", rec.Setting, rec.Suggested)
+}
@@ -0,0 +1,71 @@
+package noise
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+)
+
+// Handler handles noisy-monitor report requests
+type Handler struct {
+	reporter *Reporter
+}
+
+// NewHandler creates a new noise handler
+func NewHandler(reporter *Reporter) *Handler {
+	return &Handler{reporter: reporter}
+}
+
+// GetNoiseReport ranks monitors by noise (GET /v1/monitors/noise?window=168h&limit=20&refresh=true&format=csv).
+// Serves the latest scheduled report unless a window, limit or refresh is
+// given. JSON by default; CSV with format=csv or Accept: text/csv.
+// It writes its own response so CSV is not JSON-encoded.
+func (h *Handler) GetNoiseReport(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var window time.Duration
+	if v := query.Get("window"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			utils.WriteJson(w, http.StatusBadRequest, map[string]string{"error": "invalid window"})
+			return
+		}
+		window = d
+	}
+	var limit int
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			utils.WriteJson(w, http.StatusBadRequest, map[string]string{"error": "invalid limit"})
+			return
+		}
+		limit = n
+	}
+
+	report := h.reporter.Latest()
+	if report == nil || window > 0 || limit > 0 || query.Get("refresh") == "true" {
+		var err error
+		report, err = h.reporter.Generate(r.Context(), window, limit)
+		if err != nil {
+			utils.WriteJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	if query.Get("format") == "csv" || strings.Contains(r.Header.Get("Accept"), "text/csv") {
+		body, err := report.CSV()
+		if err != nil {
+			utils.WriteJson(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="noisy-monitors.csv"`)
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+		return
+	}
+	utils.WriteJson(w, http.StatusOK, report)
+}
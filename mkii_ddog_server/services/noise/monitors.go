@@ -0,0 +1,63 @@
+package noise
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+	"github.com/Nokodoko/mkii_ddog_server/services/monitors"
+)
+
+// CredentialProvider interface at consumer side (interface ownership)
+type CredentialProvider interface {
+	GetByName(name string) (*accounts.Account, error)
+	GetDefault() *accounts.Account
+}
+
+// DatadogMonitorSource fetches monitor definitions from Datadog using the
+// account the monitor's alerts arrived on
+type DatadogMonitorSource struct {
+	accounts CredentialProvider
+}
+
+// NewDatadogMonitorSource creates a monitor source; accounts may be nil to use env credentials
+func NewDatadogMonitorSource(accounts CredentialProvider) *DatadogMonitorSource {
+	return &DatadogMonitorSource{accounts: accounts}
+}
+
+// Monitor fetches a monitor definition (GET /api/v1/monitor/{id})
+func (s *DatadogMonitorSource) Monitor(ctx context.Context, monitorID int64, accountName string) (*monitors.Monitor, error) {
+	monitor, status, err := monitors.FetchMonitor(ctx, monitorID, s.credentials(accountName))
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, fmt.Errorf("monitor API returned %d", status)
+	}
+	return &monitor, nil
+}
+
+// credentials returns the named account's credentials, the default account's, or env keys
+func (s *DatadogMonitorSource) credentials(accountName string) keys.Credentials {
+	if s.accounts == nil {
+		return keys.Default()
+	}
+
+	var account *accounts.Account
+	if accountName != "" {
+		account, _ = s.accounts.GetByName(accountName)
+	}
+	if account == nil {
+		account = s.accounts.GetDefault()
+	}
+	if account == nil {
+		return keys.Default()
+	}
+
+	return keys.Credentials{
+		APIKey:  account.APIKey,
+		AppKey:  account.AppKey,
+		BaseURL: account.BaseURL,
+	}
+}
@@ -0,0 +1,180 @@
+package noise
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/monitors"
+)
+
+// Rates at which a noise input produces a recommendation
+const (
+	autoRecoveredRecommendRate = 0.6
+	flapRecommendRate          = 0.3
+	shortAlertRecommendRate    = 0.5
+	autoDowntimeRecommendRate  = 0.5
+)
+
+// windowPattern matches a metric query's evaluation window (avg(last_5m):...)
+var windowPattern = regexp.MustCompile(`last_(\d+)([mhdw])`)
+
+// comparatorPattern matches the threshold comparison at the end of a query
+var comparatorPattern = regexp.MustCompile(`(>=|<=|>|<)\s*(-?[0-9]*\.?[0-9]+)\s*$`)
+
+// recommend suggests threshold, evaluation-window and notify-policy changes
+// for a noisy monitor. monitor is the current definition; without it the
+// suggestions are generic.
+func recommend(stats Stats, monitor *monitors.Monitor, config Config) []Recommendation {
+	recs := []Recommendation{}
+	critical, above, hasCritical := criticalThreshold(monitor)
+	var options monitors.MonitorOptions
+	if monitor != nil && monitor.Options != nil {
+		options = *monitor.Options
+	}
+
+	if stats.Resolved > 0 && stats.AutoRecoveredRate >= autoRecoveredRecommendRate {
+		reason := fmt.Sprintf("%d of %d resolved alerts recovered on their own with no acknowledgement or remediation",
+			stats.AutoRecovered, stats.Resolved)
+		rec := Recommendation{
+			Kind:      KindThreshold,
+			Setting:   "options.thresholds.critical",
+			Suggested: "raise the alert threshold so only actionable values alert",
+			Reason:    reason,
+		}
+		if hasCritical {
+			rec.Current = formatValue(critical)
+			rec.Suggested = formatValue(scale(critical, above, 0.2))
+		}
+		recs = append(recs, rec)
+
+		if options.RenotifyInterval != nil && *options.RenotifyInterval > 0 {
+			recs = append(recs, Recommendation{
+				Kind:      KindNotifyPolicy,
+				Setting:   "options.renotify_interval",
+				Current:   fmt.Sprintf("%d minutes", *options.RenotifyInterval),
+				Suggested: "0 (notify once per alert)",
+				Reason:    reason,
+			})
+		}
+	}
+
+	if stats.Alerts > 0 && stats.FlapRate >= flapRecommendRate {
+		rec := Recommendation{
+			Kind:      KindThreshold,
+			Setting:   "options.thresholds.critical_recovery",
+			Current:   "not set",
+			Suggested: "set a recovery threshold past the alert threshold so the monitor stops flapping",
+			Reason: fmt.Sprintf("%d of %d alerts re-triggered within %s of recovering",
+				stats.Flaps, stats.Alerts, config.FlapWindow),
+		}
+		var recovery *float64
+		if options.Thresholds != nil {
+			recovery = options.Thresholds.CriticalRecovery
+		}
+		switch {
+		case hasCritical && recovery == nil:
+			rec.Suggested = formatValue(scale(critical, above, -0.1))
+		case hasCritical:
+			// Double the hysteresis gap
+			rec.Current = formatValue(*recovery)
+			rec.Suggested = formatValue(critical - 2*(critical-*recovery))
+		}
+		recs = append(recs, rec)
+	}
+
+	if stats.Resolved > 0 && stats.ShortAlertRate >= shortAlertRecommendRate {
+		rec := Recommendation{
+			Kind:      KindEvaluationWindow,
+			Setting:   "query evaluation window",
+			Suggested: "evaluate over a longer window",
+			Reason: fmt.Sprintf("%d of %d resolved alerts lasted under %s",
+				stats.ShortAlerts, stats.Resolved, config.ShortAlert),
+		}
+		if monitor != nil {
+			if window, ok := queryWindow(monitor.Query); ok {
+				rec.Current = formatWindow(window)
+				rec.Suggested = formatWindow(3 * window)
+			}
+		}
+		recs = append(recs, rec)
+	}
+
+	if stats.Resolved > 0 && stats.AutoDowntimeRate >= autoDowntimeRecommendRate {
+		recs = append(recs, Recommendation{
+			Kind:      KindNotifyPolicy,
+			Setting:   "notification routing",
+			Current:   fmt.Sprintf("auto-downtime after %d of %d recoveries", stats.AutoDowntimes, stats.Resolved),
+			Suggested: "schedule a recurring downtime or route to a non-paging channel",
+			Reason:    "the monitor is silenced after most recoveries, so its alerts are not being acted on",
+		})
+	}
+
+	return recs
+}
+
+// criticalThreshold returns the critical threshold from the options or the
+// query, and whether values above it alert
+func criticalThreshold(monitor *monitors.Monitor) (float64, bool, bool) {
+	if monitor == nil {
+		return 0, true, false
+	}
+
+	above := true
+	var critical float64
+	found := false
+	if m := comparatorPattern.FindStringSubmatch(monitor.Query); m != nil {
+		above = m[1] == ">" || m[1] == ">="
+		if v, err := strconv.ParseFloat(m[2], 64); err == nil {
+			critical, found = v, true
+		}
+	}
+	if monitor.Options != nil && monitor.Options.Thresholds != nil && monitor.Options.Thresholds.Critical != nil {
+		critical, found = *monitor.Options.Thresholds.Critical, true
+	}
+	return critical, above, found
+}
+
+// scale moves a threshold by a fraction in the alerting direction (negative
+// fractions move it back, towards recovery)
+func scale(value float64, above bool, fraction float64) float64 {
+	if !above {
+		fraction = -fraction
+	}
+	if value < 0 {
+		fraction = -fraction
+	}
+	return value * (1 + fraction)
+}
+
+// queryWindow parses the evaluation window of a metric query
+func queryWindow(query string) (time.Duration, bool) {
+	m := windowPattern.FindStringSubmatch(query)
+	if m == nil {
+		return 0, false
+	}
+	n, err := strconv.Atoi(m[1])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	unit := map[string]time.Duration{"m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour}[m[2]]
+	return time.Duration(n) * unit, true
+}
+
+// formatWindow renders a window in query syntax (last_15m, last_2h, last_1d)
+func formatWindow(window time.Duration) string {
+	switch {
+	case window%(24*time.Hour) == 0:
+		return fmt.Sprintf("last_%dd", window/(24*time.Hour))
+	case window%time.Hour == 0:
+		return fmt.Sprintf("last_%dh", window/time.Hour)
+	default:
+		return fmt.Sprintf("last_%dm", window/time.Minute)
+	}
+}
+
+// formatValue renders a threshold without trailing zeros
+func formatValue(v float64) string {
+	return strconv.FormatFloat(round(v), 'f', -1, 64)
+}
@@ -0,0 +1,339 @@
+package noise
+
+import (
+	"context"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/baselines"
+	"github.com/Nokodoko/mkii_ddog_server/services/incidents"
+	"github.com/Nokodoko/mkii_ddog_server/services/monitors"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// maxActionScan bounds how many remediation actions are checked per report
+const maxActionScan = 500
+
+// Config controls the noisy-monitor report
+type Config struct {
+	// Window is how much alert history the report covers
+	// Default: 7 days
+	Window time.Duration
+
+	// Interval is how often the scheduled job regenerates the report;
+	// 0 disables the job and reports are generated on request
+	// Default: 24h
+	Interval time.Duration
+
+	// MinAlerts is the alert count below which a monitor is not ranked
+	// Default: 3
+	MinAlerts int
+
+	// Limit is how many monitors the report ranks
+	// Default: 20
+	Limit int
+
+	// FlapWindow counts an alert as a flap when it re-triggers this soon
+	// after recovering
+	// Default: 30m
+	FlapWindow time.Duration
+
+	// ShortAlert is the duration under which a resolved alert was too brief
+	// to act on
+	// Default: 5m
+	ShortAlert time.Duration
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		Window:     7 * 24 * time.Hour,
+		Interval:   24 * time.Hour,
+		MinAlerts:  3,
+		Limit:      20,
+		FlapWindow: 30 * time.Minute,
+		ShortAlert: 5 * time.Minute,
+	}
+}
+
+// ConfigFromEnv returns the defaults overridden by NOISE_WINDOW,
+// NOISE_REPORT_INTERVAL, NOISE_MIN_ALERTS and NOISE_REPORT_LIMIT
+func ConfigFromEnv() Config {
+	config := DefaultConfig()
+	if v, err := time.ParseDuration(os.Getenv("NOISE_WINDOW")); err == nil && v > 0 {
+		config.Window = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("NOISE_REPORT_INTERVAL")); err == nil && v >= 0 {
+		config.Interval = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("NOISE_MIN_ALERTS")); err == nil && v > 0 {
+		config.MinAlerts = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("NOISE_REPORT_LIMIT")); err == nil && v > 0 {
+		config.Limit = v
+	}
+	return config
+}
+
+// EventSource interface at consumer side; implemented by *webhooks.Storage
+type EventSource interface {
+	GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]webhooks.WebhookEvent, error)
+	GetMonitorIDsSince(since time.Time) ([]int64, error)
+}
+
+// MonitorSource fetches current monitor definitions; implemented by *DatadogMonitorSource
+type MonitorSource interface {
+	Monitor(ctx context.Context, monitorID int64, accountName string) (*monitors.Monitor, error)
+}
+
+// AckSource interface at consumer side; implemented by *incidents.Storage
+type AckSource interface {
+	ListAcks(ctx context.Context, incidentID int64) ([]incidents.Acknowledgement, error)
+}
+
+// ActionSource interface at consumer side; implemented by *remediation.Manager
+type ActionSource interface {
+	List(ctx context.Context, status remediation.ActionStatus, limit int) ([]remediation.Action, error)
+}
+
+// Reporter ranks monitors by noise and recommends tuning changes
+type Reporter struct {
+	events   EventSource
+	monitors MonitorSource
+	acks     AckSource
+	actions  ActionSource
+	config   Config
+	now      func() time.Time
+
+	mu     sync.RWMutex
+	latest *Report
+}
+
+// NewReporter creates a reporter; monitors may be nil to skip fetching definitions
+func NewReporter(events EventSource, monitors MonitorSource, config Config) *Reporter {
+	defaults := DefaultConfig()
+	if config.Window <= 0 {
+		config.Window = defaults.Window
+	}
+	if config.MinAlerts <= 0 {
+		config.MinAlerts = defaults.MinAlerts
+	}
+	if config.Limit <= 0 {
+		config.Limit = defaults.Limit
+	}
+	if config.FlapWindow <= 0 {
+		config.FlapWindow = defaults.FlapWindow
+	}
+	if config.ShortAlert <= 0 {
+		config.ShortAlert = defaults.ShortAlert
+	}
+	return &Reporter{
+		events:   events,
+		monitors: monitors,
+		config:   config,
+		now:      time.Now,
+	}
+}
+
+// SetAckSource lets acknowledged alerts count as acted on
+func (r *Reporter) SetAckSource(acks AckSource) {
+	r.acks = acks
+}
+
+// SetActionSource lets remediated alerts count as acted on
+func (r *Reporter) SetActionSource(actions ActionSource) {
+	r.actions = actions
+}
+
+// Config returns the reporter's configuration
+func (r *Reporter) Config() Config {
+	return r.config
+}
+
+// Run regenerates the report every Interval until ctx is cancelled
+func (r *Reporter) Run(ctx context.Context) {
+	if r.config.Interval <= 0 {
+		log.Printf("[NOISE] Scheduled report disabled; reports are generated on request")
+		return
+	}
+
+	ticker := time.NewTicker(r.config.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := r.Refresh(ctx); err != nil {
+			log.Printf("[NOISE] Report failed: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Refresh generates a report with the configured window and limit, caches
+// it as the latest and logs the noisiest monitors
+func (r *Reporter) Refresh(ctx context.Context) (*Report, error) {
+	report, err := r.Generate(ctx, r.config.Window, r.config.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	r.latest = report
+	r.mu.Unlock()
+
+	log.Printf("[NOISE] Ranked %d of %d monitors over the last %s", len(report.Monitors), report.Scanned, report.Window)
+	for _, m := range report.Monitors[:min(len(report.Monitors), 5)] {
+		log.Printf("[NOISE] #%d monitor %d (%s): %d alerts, %.0f%% auto-recovered, %.0f%% flaps, %d recommendations",
+			m.Rank, m.MonitorID, m.MonitorName, m.Stats.Alerts,
+			m.Stats.AutoRecoveredRate*100, m.Stats.FlapRate*100, len(m.Recommendations))
+	}
+	return report, nil
+}
+
+// Latest returns the last scheduled report (nil before the first run)
+func (r *Reporter) Latest() *Report {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.latest
+}
+
+// Generate ranks the monitors that alerted in the window before now by noise
+// and recommends tuning changes for the top limit
+func (r *Reporter) Generate(ctx context.Context, window time.Duration, limit int) (*Report, error) {
+	if window <= 0 {
+		window = r.config.Window
+	}
+	if limit <= 0 {
+		limit = r.config.Limit
+	}
+	to := r.now()
+	from := to.Add(-window)
+
+	ids, err := r.events.GetMonitorIDsSince(from)
+	if err != nil {
+		return nil, err
+	}
+	actions := r.listActions(ctx)
+
+	report := &Report{
+		GeneratedAt: to,
+		From:        from,
+		To:          to,
+		Window:      window,
+		Scanned:     len(ids),
+		Monitors:    []MonitorNoise{},
+	}
+
+	for _, id := range ids {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		// Include the flap window before from so the first alert can count as a flap
+		history, err := r.events.GetMonitorEventsBetween(id, from.Add(-r.config.FlapWindow), to)
+		if err != nil {
+			log.Printf("[NOISE] Failed to load history for monitor %d: %v", id, err)
+			continue
+		}
+
+		stats := computeStats(history, from, to, r.config, func(ep baselines.Episode) bool {
+			return r.actedOn(ctx, id, ep, actions)
+		})
+		if stats.Alerts < r.config.MinAlerts {
+			continue
+		}
+
+		entry := MonitorNoise{MonitorID: id, Score: score(stats), Stats: stats}
+		for i := len(history) - 1; i >= 0; i-- {
+			p := history[i].Payload
+			if entry.MonitorName == "" {
+				entry.MonitorName = utils.FirstNonEmpty(p.MonitorName, p.AlertTitle)
+			}
+			if entry.AccountName == "" {
+				entry.AccountName = history[i].AccountName
+			}
+		}
+		report.Monitors = append(report.Monitors, entry)
+	}
+
+	sort.SliceStable(report.Monitors, func(i, j int) bool {
+		a, b := report.Monitors[i], report.Monitors[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.MonitorID < b.MonitorID
+	})
+	if len(report.Monitors) > limit {
+		report.Monitors = report.Monitors[:limit]
+	}
+
+	// Definitions are only fetched for the monitors that made the report
+	for i := range report.Monitors {
+		entry := &report.Monitors[i]
+		entry.Rank = i + 1
+		if r.monitors != nil {
+			monitor, err := r.monitors.Monitor(ctx, entry.MonitorID, entry.AccountName)
+			if err != nil {
+				log.Printf("[NOISE] Failed to fetch definition of monitor %d: %v", entry.MonitorID, err)
+				entry.DefinitionError = err.Error()
+			} else {
+				entry.Monitor = monitor
+				if monitor.Name != "" {
+					entry.MonitorName = monitor.Name
+				}
+			}
+		}
+		entry.Recommendations = recommend(entry.Stats, entry.Monitor, r.config)
+	}
+	return report, nil
+}
+
+// listActions loads recent remediation actions once per report
+func (r *Reporter) listActions(ctx context.Context) []remediation.Action {
+	if r.actions == nil {
+		return nil
+	}
+	actions, err := r.actions.List(ctx, "", maxActionScan)
+	if err != nil {
+		log.Printf("[NOISE] Failed to list remediation actions: %v", err)
+		return nil
+	}
+	return actions
+}
+
+// actedOn reports whether a resolved alert was acknowledged or had a
+// remediation action approved while it was open
+func (r *Reporter) actedOn(ctx context.Context, monitorID int64, ep baselines.Episode, actions []remediation.Action) bool {
+	for _, action := range actions {
+		if action.MonitorID != monitorID || action.DecisionAt == nil {
+			continue
+		}
+		switch action.Status {
+		case remediation.StatusApproved, remediation.StatusSucceeded, remediation.StatusFailed:
+		default:
+			continue
+		}
+		if !action.DecisionAt.Before(ep.Start) && !action.DecisionAt.After(*ep.End) {
+			return true
+		}
+	}
+
+	if r.acks == nil || len(ep.Events) == 0 {
+		return false
+	}
+	// Incidents are keyed by the first event of the episode
+	acks, err := r.acks.ListAcks(ctx, ep.Events[0].ID)
+	if err != nil {
+		log.Printf("[NOISE] Failed to list acks for event %d: %v", ep.Events[0].ID, err)
+		return false
+	}
+	return len(acks) > 0
+}
@@ -0,0 +1,250 @@
+package noise
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/incidents"
+	"github.com/Nokodoko/mkii_ddog_server/services/monitors"
+	"github.com/Nokodoko/mkii_ddog_server/services/remediation"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+var now = time.Date(2026, 6, 8, 12, 0, 0, 0, time.UTC)
+
+// fakeEvents serves an in-memory webhook history (oldest first)
+type fakeEvents struct {
+	history []webhooks.WebhookEvent
+}
+
+func (f *fakeEvents) add(monitorID int64, status string, at time.Time, downtimeID string) int64 {
+	id := int64(len(f.history) + 1)
+	f.history = append(f.history, webhooks.WebhookEvent{
+		ID:          id,
+		ReceivedAt:  at,
+		AccountName: "prod",
+		DowntimeID:  downtimeID,
+		Payload:     webhooks.WebhookPayload{MonitorID: monitorID, MonitorName: "monitor", AlertStatus: status, Scope: "env:prod"},
+	})
+	return id
+}
+
+// alert adds an alert at start that recovers after duration
+func (f *fakeEvents) alert(monitorID int64, start time.Time, duration time.Duration) int64 {
+	return f.downtimedAlert(monitorID, start, duration, "")
+}
+
+// downtimedAlert is an alert whose recovery created downtimeID (none when empty)
+func (f *fakeEvents) downtimedAlert(monitorID int64, start time.Time, duration time.Duration, downtimeID string) int64 {
+	id := f.add(monitorID, "Alert", start, "")
+	f.add(monitorID, "OK", start.Add(duration), downtimeID)
+	return id
+}
+
+func (f *fakeEvents) GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]webhooks.WebhookEvent, error) {
+	var out []webhooks.WebhookEvent
+	for _, event := range f.history {
+		if event.Payload.MonitorID == monitorID && !event.ReceivedAt.Before(from) && !event.ReceivedAt.After(to) {
+			out = append(out, event)
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeEvents) GetMonitorIDsSince(since time.Time) ([]int64, error) {
+	seen := make(map[int64]bool)
+	var ids []int64
+	for _, event := range f.history {
+		if id := event.Payload.MonitorID; !seen[id] && !event.ReceivedAt.Before(since) {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+type fakeMonitors map[int64]*monitors.Monitor
+
+func (f fakeMonitors) Monitor(ctx context.Context, monitorID int64, accountName string) (*monitors.Monitor, error) {
+	if m, ok := f[monitorID]; ok {
+		return m, nil
+	}
+	return nil, errors.New("monitor API returned 404")
+}
+
+type fakeAcks map[int64]bool
+
+func (f fakeAcks) ListAcks(ctx context.Context, incidentID int64) ([]incidents.Acknowledgement, error) {
+	if f[incidentID] {
+		return []incidents.Acknowledgement{{IncidentID: incidentID, Actor: "oncall"}}, nil
+	}
+	return nil, nil
+}
+
+type fakeActions []remediation.Action
+
+func (f fakeActions) List(ctx context.Context, status remediation.ActionStatus, limit int) ([]remediation.Action, error) {
+	return f, nil
+}
+
+func float(v float64) *float64 { return &v }
+func minutes(v int64) *int64   { return &v }
+
+// fixture builds four monitors:
+//   - 1 flaps: six 2-minute alerts 10 minutes apart, nobody acts on them
+//   - 2 is handled: four 1-hour alerts, three acked and one remediated
+//   - 3 alerted twice, below MinAlerts
+//   - 4 is auto-downtimed after every recovery and its definition is missing
+func fixture() (*fakeEvents, fakeAcks, fakeActions) {
+	events := &fakeEvents{}
+	acks := fakeAcks{}
+	var actions fakeActions
+
+	for i := 0; i < 6; i++ {
+		events.alert(1, now.Add(-48*time.Hour+time.Duration(i)*12*time.Minute), 2*time.Minute)
+	}
+	for i := 0; i < 4; i++ {
+		start := now.Add(-time.Duration(4-i) * 24 * time.Hour)
+		id := events.alert(2, start, time.Hour)
+		if i > 0 {
+			acks[id] = true
+			continue
+		}
+		decided := start.Add(10 * time.Minute)
+		actions = append(actions, remediation.Action{MonitorID: 2, Status: remediation.StatusSucceeded, DecisionAt: &decided})
+	}
+	for i := 0; i < 2; i++ {
+		events.alert(3, now.Add(-time.Duration(2-i)*24*time.Hour), time.Hour)
+	}
+	for i := 0; i < 3; i++ {
+		events.downtimedAlert(4, now.Add(-time.Duration(3-i)*30*time.Hour), 20*time.Minute, fmt.Sprintf("dt-%d", i))
+	}
+	return events, acks, actions
+}
+
+func newTestReporter() *Reporter {
+	events, acks, actions := fixture()
+	source := fakeMonitors{
+		1: {ID: 1, Name: "CPU high", Query: "avg(last_5m):avg:system.cpu.user{env:prod} > 90",
+			Options: &monitors.MonitorOptions{
+				Thresholds:       &monitors.MonitorThresholds{Critical: float(90)},
+				RenotifyInterval: minutes(60),
+			}},
+		2: {ID: 2, Name: "Disk full", Query: "avg(last_15m):avg:system.disk.in_use{*} > 0.95"},
+	}
+	reporter := NewReporter(events, source, DefaultConfig())
+	reporter.SetAckSource(acks)
+	reporter.SetActionSource(actions)
+	reporter.now = func() time.Time { return now }
+	return reporter
+}
+
+func TestReporter_Generate(t *testing.T) {
+	report, err := newTestReporter().Generate(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if report.Scanned != 4 {
+		t.Errorf("Scanned = %d, want 4", report.Scanned)
+	}
+
+	var ranked []int64
+	for _, m := range report.Monitors {
+		ranked = append(ranked, m.MonitorID)
+	}
+	if len(ranked) != 3 || ranked[0] != 1 || ranked[1] != 4 || ranked[2] != 2 {
+		t.Fatalf("ranked monitors = %v, want [1 4 2]", ranked)
+	}
+
+	flappy := report.Monitors[0]
+	if flappy.Rank != 1 || flappy.MonitorName != "CPU high" {
+		t.Errorf("top entry = #%d %q, want #1 CPU high", flappy.Rank, flappy.MonitorName)
+	}
+	stats := flappy.Stats
+	if stats.Alerts != 6 || stats.Flaps != 5 || stats.ShortAlerts != 6 || stats.AutoRecovered != 6 {
+		t.Errorf("stats = %+v, want 6 alerts, 5 flaps, 6 short, 6 auto-recovered", stats)
+	}
+	if stats.MedianDuration != 2*time.Minute {
+		t.Errorf("MedianDuration = %v, want 2m", stats.MedianDuration)
+	}
+
+	settings := make(map[string]Recommendation)
+	for _, rec := range flappy.Recommendations {
+		settings[rec.Setting] = rec
+	}
+	if rec := settings["options.thresholds.critical"]; rec.Current != "90" || rec.Suggested != "108" {
+		t.Errorf("critical threshold recommendation = %+v, want 90 -> 108", rec)
+	}
+	if rec := settings["options.thresholds.critical_recovery"]; rec.Suggested != "81" {
+		t.Errorf("recovery threshold recommendation = %+v, want 81", rec)
+	}
+	if rec := settings["query evaluation window"]; rec.Current != "last_5m" || rec.Suggested != "last_15m" {
+		t.Errorf("window recommendation = %+v, want last_5m -> last_15m", rec)
+	}
+	if rec := settings["options.renotify_interval"]; rec.Kind != KindNotifyPolicy {
+		t.Errorf("renotify recommendation = %+v, want notify_policy", rec)
+	}
+
+	downtimed := report.Monitors[1]
+	if downtimed.Stats.AutoDowntimes != 3 || downtimed.DefinitionError == "" || downtimed.Monitor != nil {
+		t.Errorf("downtimed entry = %+v, want 3 auto-downtimes and a definition error", downtimed)
+	}
+	found := false
+	for _, rec := range downtimed.Recommendations {
+		found = found || rec.Setting == "notification routing"
+	}
+	if !found {
+		t.Errorf("downtimed recommendations = %+v, want a routing change", downtimed.Recommendations)
+	}
+
+	handled := report.Monitors[2]
+	if handled.Stats.AutoRecovered != 0 || len(handled.Recommendations) != 0 {
+		t.Errorf("handled entry = %+v, want no auto-recoveries or recommendations", handled)
+	}
+}
+
+func TestReporter_GenerateLimit(t *testing.T) {
+	report, err := newTestReporter().Generate(context.Background(), 0, 1)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if len(report.Monitors) != 1 || report.Monitors[0].MonitorID != 1 {
+		t.Fatalf("monitors = %+v, want only monitor 1", report.Monitors)
+	}
+}
+
+func TestRecommend_BelowThreshold(t *testing.T) {
+	monitor := &monitors.Monitor{Query: "min(last_10m):avg:aws.ec2.cpucredit_balance{*} < 20"}
+	stats := Stats{Alerts: 4, Resolved: 4, AutoRecovered: 4, AutoRecoveredRate: 1}
+
+	recs := recommend(stats, monitor, DefaultConfig())
+	if len(recs) != 1 || recs[0].Current != "20" || recs[0].Suggested != "16" {
+		t.Fatalf("recommendations = %+v, want critical 20 -> 16", recs)
+	}
+}
+
+func TestReport_CSV(t *testing.T) {
+	report, err := newTestReporter().Generate(context.Background(), 0, 0)
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	body, err := report.CSV()
+	if err != nil {
+		t.Fatalf("CSV: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("CSV has %d lines, want header and 3 rows:\n%s", len(lines), body)
+	}
+	if !strings.HasPrefix(lines[0], "rank,monitor_id,monitor_name") {
+		t.Errorf("header = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "1,1,CPU high,prod,") || !strings.Contains(lines[1], "options.thresholds.critical: 90 -> 108") {
+		t.Errorf("first row = %q", lines[1])
+	}
+}
@@ -0,0 +1,91 @@
+package noise
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/baselines"
+	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
+)
+
+// computeStats measures a monitor's noise from its history (oldest first).
+// Alerts that started before from only count towards flap detection.
+// acted reports whether anyone acknowledged or remediated a resolved alert.
+func computeStats(history []webhooks.WebhookEvent, from, to time.Time, config Config, acted func(baselines.Episode) bool) Stats {
+	var stats Stats
+	var durations []time.Duration
+	lastEnd := make(map[string]time.Time)
+
+	for _, ep := range baselines.Episodes(history) {
+		prevEnd, hadPrev := lastEnd[ep.Scope]
+		if ep.End != nil {
+			lastEnd[ep.Scope] = *ep.End
+		}
+		if ep.Start.Before(from) || ep.Start.After(to) {
+			continue
+		}
+
+		stats.Alerts++
+		if hadPrev && ep.Start.Sub(prevEnd) <= config.FlapWindow {
+			stats.Flaps++
+		}
+
+		recovery := ep.Recovery()
+		if recovery == nil {
+			continue
+		}
+		stats.Resolved++
+		duration := ep.End.Sub(ep.Start)
+		durations = append(durations, duration)
+
+		if duration < config.ShortAlert {
+			stats.ShortAlerts++
+		}
+		if autoDowntimed(recovery) {
+			stats.AutoDowntimes++
+		}
+		if acted == nil || !acted(ep) {
+			stats.AutoRecovered++
+		}
+	}
+
+	days := math.Max(to.Sub(from).Hours()/24, 1)
+	stats.AlertsPerDay = round(float64(stats.Alerts) / days)
+	stats.FlapRate = share(stats.Flaps, stats.Alerts)
+	stats.ShortAlertRate = share(stats.ShortAlerts, stats.Resolved)
+	stats.AutoRecoveredRate = share(stats.AutoRecovered, stats.Resolved)
+	stats.AutoDowntimeRate = share(stats.AutoDowntimes, stats.Resolved)
+
+	if len(durations) > 0 {
+		sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+		stats.MedianDuration = durations[(len(durations)-1)/2]
+	}
+	return stats
+}
+
+// score weights the alert count by how much of it is noise, so a monitor
+// firing often and never needing attention ranks first
+func score(stats Stats) float64 {
+	noise := 1 + stats.FlapRate + stats.ShortAlertRate + stats.AutoRecoveredRate + stats.AutoDowntimeRate
+	return round(float64(stats.Alerts) * noise)
+}
+
+// autoDowntimed reports whether the downtime processor silenced the monitor
+// after this recovery (it stores the downtime it created on the event)
+func autoDowntimed(recovery *webhooks.WebhookEvent) bool {
+	return recovery.DowntimeID != ""
+}
+
+// share returns n/total rounded to two decimals (0 when total is 0)
+func share(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return round(float64(n) / float64(total))
+}
+
+// round rounds to two decimals
+func round(v float64) float64 {
+	return math.Round(v*100) / 100
+}
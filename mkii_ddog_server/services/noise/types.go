@@ -0,0 +1,66 @@
+package noise
+
+import (
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/monitors"
+)
+
+// RecommendationKind groups tuning suggestions by what they change
+type RecommendationKind string
+
+const (
+	KindThreshold        RecommendationKind = "threshold"
+	KindEvaluationWindow RecommendationKind = "evaluation_window"
+	KindNotifyPolicy     RecommendationKind = "notify_policy"
+)
+
+// Recommendation is one suggested monitor change, grounded in the current
+// definition when it could be fetched
+type Recommendation struct {
+	Kind      RecommendationKind `json:"kind"`
+	Setting   string             `json:"setting"`
+	Current   string             `json:"current,omitempty"`
+	Suggested string             `json:"suggested"`
+	Reason    string             `json:"reason"`
+}
+
+// Stats are a monitor's noise inputs over the report window. Rates are
+// shares of alerts (flaps) or of resolved alerts (the rest).
+type Stats struct {
+	Alerts            int           `json:"alerts"`
+	Resolved          int           `json:"resolved"`
+	AlertsPerDay      float64       `json:"alerts_per_day"`
+	Flaps             int           `json:"flaps"`
+	FlapRate          float64       `json:"flap_rate"`
+	ShortAlerts       int           `json:"short_alerts"`
+	ShortAlertRate    float64       `json:"short_alert_rate"`
+	AutoRecovered     int           `json:"auto_recovered"`
+	AutoRecoveredRate float64       `json:"auto_recovered_rate"`
+	AutoDowntimes     int           `json:"auto_downtimes"`
+	AutoDowntimeRate  float64       `json:"auto_downtime_rate"`
+	MedianDuration    time.Duration `json:"median_duration"`
+}
+
+// MonitorNoise is one ranked monitor in the report
+type MonitorNoise struct {
+	Rank            int               `json:"rank"`
+	MonitorID       int64             `json:"monitor_id"`
+	MonitorName     string            `json:"monitor_name"`
+	AccountName     string            `json:"account_name,omitempty"`
+	Score           float64           `json:"score"`
+	Stats           Stats             `json:"stats"`
+	Monitor         *monitors.Monitor `json:"monitor,omitempty"`
+	DefinitionError string            `json:"definition_error,omitempty"`
+	Recommendations []Recommendation  `json:"recommendations"`
+}
+
+// Report ranks monitors by noise over a window
+type Report struct {
+	GeneratedAt time.Time      `json:"generated_at"`
+	From        time.Time      `json:"from"`
+	To          time.Time      `json:"to"`
+	Window      time.Duration  `json:"window"`
+	Scanned     int            `json:"scanned"`
+	Monitors    []MonitorNoise `json:"monitors"`
+}
@@ -0,0 +1,168 @@
+package remediation
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Limits applied to every proposal regardless of executor
+const (
+	maxDowntimeMinutes = 7 * 24 * 60
+	defaultMaxReplicas = 20
+)
+
+// k8sNamePattern matches Kubernetes namespace and deployment names (RFC 1123 labels)
+var k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+
+// Validate checks a proposal's type and parameters. maxReplicas <= 0 uses the default.
+func Validate(p Proposal, maxReplicas int) error {
+	if maxReplicas <= 0 {
+		maxReplicas = defaultMaxReplicas
+	}
+	params := p.Params
+
+	switch p.Type {
+	case ActionCreateDowntime:
+		if params.MonitorID == 0 && (params.Scope == "" || params.Scope == "*") {
+			return fmt.Errorf("create_downtime needs a monitor_id or a specific scope")
+		}
+		if params.DurationMinutes <= 0 || params.DurationMinutes > maxDowntimeMinutes {
+			return fmt.Errorf("duration_minutes must be between 1 and %d", maxDowntimeMinutes)
+		}
+
+	case ActionMuteMonitor:
+		if params.MonitorID == 0 {
+			return fmt.Errorf("mute_monitor needs a monitor_id")
+		}
+		if params.DurationMinutes < 0 || params.DurationMinutes > maxDowntimeMinutes {
+			return fmt.Errorf("duration_minutes must be between 0 (indefinite) and %d", maxDowntimeMinutes)
+		}
+
+	case ActionRestartDeployment, ActionScaleDeployment:
+		if !k8sNamePattern.MatchString(params.Namespace) || len(params.Namespace) > 63 {
+			return fmt.Errorf("invalid namespace %q", params.Namespace)
+		}
+		if !k8sNamePattern.MatchString(params.Deployment) || len(params.Deployment) > 253 {
+			return fmt.Errorf("invalid deployment name %q", params.Deployment)
+		}
+		if p.Type == ActionScaleDeployment {
+			if params.Replicas == nil {
+				return fmt.Errorf("scale_deployment needs replicas")
+			}
+			if *params.Replicas < 0 || *params.Replicas > maxReplicas {
+				return fmt.Errorf("replicas must be between 0 and %d", maxReplicas)
+			}
+		}
+
+	case ActionRunScript:
+		if params.Script == "" {
+			return fmt.Errorf("run_script needs a script name")
+		}
+
+	default:
+		return fmt.Errorf("unknown action type %q", p.Type)
+	}
+
+	return nil
+}
+
+// Describe renders a one-line summary of what an action will do
+func Describe(p Proposal) string {
+	params := p.Params
+	switch p.Type {
+	case ActionCreateDowntime:
+		target := fmt.Sprintf("monitor %d", params.MonitorID)
+		if params.MonitorID == 0 {
+			target = "all monitors"
+		}
+		return fmt.Sprintf("Create a %d minute downtime for %s (scope %s)", params.DurationMinutes, target, scopeOrAll(params.Scope))
+	case ActionMuteMonitor:
+		if params.DurationMinutes == 0 {
+			return fmt.Sprintf("Mute monitor %d indefinitely (scope %s)", params.MonitorID, scopeOrAll(params.Scope))
+		}
+		return fmt.Sprintf("Mute monitor %d for %d minutes (scope %s)", params.MonitorID, params.DurationMinutes, scopeOrAll(params.Scope))
+	case ActionRestartDeployment:
+		return fmt.Sprintf("Rolling restart of deployment %s/%s", params.Namespace, params.Deployment)
+	case ActionScaleDeployment:
+		replicas := 0
+		if params.Replicas != nil {
+			replicas = *params.Replicas
+		}
+		return fmt.Sprintf("Scale deployment %s/%s to %d replicas", params.Namespace, params.Deployment, replicas)
+	case ActionRunScript:
+		if len(params.Args) == 0 {
+			return fmt.Sprintf("Run script %s", params.Script)
+		}
+		return fmt.Sprintf("Run script %s %s", params.Script, strings.Join(params.Args, " "))
+	}
+	return string(p.Type)
+}
+
+func scopeOrAll(scope string) string {
+	if scope == "" {
+		return "*"
+	}
+	return scope
+}
+
+// proposalFence opens the fenced JSON block agents use for structured proposals:
+//
+//	```remediation
+//	[{"type": "restart_deployment", "params": {...}, "reason": "..."}]
+//	```
+const proposalFence = "```remediation"
+
+// ParseProposals extracts proposals from fenced `remediation` blocks in agent
+// output and returns the text with those blocks removed. A block may hold a
+// single object or an array. Malformed blocks are left in the text.
+func ParseProposals(text string) ([]Proposal, string) {
+	var proposals []Proposal
+	var remaining strings.Builder
+
+	rest := text
+	for {
+		start := strings.Index(rest, proposalFence)
+		if start < 0 {
+			remaining.WriteString(rest)
+			break
+		}
+		bodyStart := start + len(proposalFence)
+		end := strings.Index(rest[bodyStart:], "```")
+		if end < 0 {
+			remaining.WriteString(rest)
+			break
+		}
+		body := strings.TrimSpace(rest[bodyStart : bodyStart+end])
+		blockEnd := bodyStart + end + len("```")
+
+		parsed, ok := decodeProposals(body)
+		if ok {
+			proposals = append(proposals, parsed...)
+			remaining.WriteString(rest[:start])
+		} else {
+			remaining.WriteString(rest[:blockEnd])
+		}
+		rest = rest[blockEnd:]
+	}
+
+	return proposals, strings.TrimSpace(remaining.String())
+}
+
+// decodeProposals accepts a JSON object or array of proposals
+func decodeProposals(body string) ([]Proposal, bool) {
+	if strings.HasPrefix(body, "[") {
+		var list []Proposal
+		if err := json.Unmarshal([]byte(body), &list); err != nil {
+			return nil, false
+		}
+		return list, true
+	}
+
+	var single Proposal
+	if err := json.Unmarshal([]byte(body), &single); err != nil || single.Type == "" {
+		return nil, false
+	}
+	return []Proposal{single}, true
+}
@@ -0,0 +1,71 @@
+package remediation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	replicas := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		proposal Proposal
+		wantErr  bool
+	}{
+		{"downtime for monitor", Proposal{Type: ActionCreateDowntime, Params: ActionParams{MonitorID: 1, DurationMinutes: 30}}, false},
+		{"downtime needs target", Proposal{Type: ActionCreateDowntime, Params: ActionParams{Scope: "*", DurationMinutes: 30}}, true},
+		{"downtime too long", Proposal{Type: ActionCreateDowntime, Params: ActionParams{MonitorID: 1, DurationMinutes: maxDowntimeMinutes + 1}}, true},
+		{"mute indefinitely", Proposal{Type: ActionMuteMonitor, Params: ActionParams{MonitorID: 1}}, false},
+		{"mute needs monitor", Proposal{Type: ActionMuteMonitor}, true},
+		{"restart", Proposal{Type: ActionRestartDeployment, Params: ActionParams{Namespace: "prod", Deployment: "api-v2"}}, false},
+		{"restart bad name", Proposal{Type: ActionRestartDeployment, Params: ActionParams{Namespace: "prod", Deployment: "API_v2"}}, true},
+		{"scale", Proposal{Type: ActionScaleDeployment, Params: ActionParams{Namespace: "prod", Deployment: "api", Replicas: replicas(3)}}, false},
+		{"scale needs replicas", Proposal{Type: ActionScaleDeployment, Params: ActionParams{Namespace: "prod", Deployment: "api"}}, true},
+		{"scale over cap", Proposal{Type: ActionScaleDeployment, Params: ActionParams{Namespace: "prod", Deployment: "api", Replicas: replicas(21)}}, true},
+		{"script", Proposal{Type: ActionRunScript, Params: ActionParams{Script: "flush-cache"}}, false},
+		{"unknown type", Proposal{Type: "delete_cluster"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.proposal, 0)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseProposals(t *testing.T) {
+	text := "# Root Cause\nPods are leaking memory.\n\n" +
+		"```remediation\n" +
+		`[{"type": "restart_deployment", "params": {"namespace": "prod", "deployment": "checkout"}, "reason": "leak"},
+		  {"type": "scale_deployment", "params": {"namespace": "prod", "deployment": "checkout", "replicas": 6}, "reason": "headroom"}]` +
+		"\n```\n\n" +
+		"```remediation\n" + `{"type": "mute_monitor", "params": {"duration_minutes": 30}, "reason": "noise"}` + "\n```\n" +
+		"```remediation\nnot json\n```\n" +
+		"Done."
+
+	proposals, remaining := ParseProposals(text)
+	if len(proposals) != 3 {
+		t.Fatalf("expected 3 proposals, got %d: %+v", len(proposals), proposals)
+	}
+	if proposals[0].Type != ActionRestartDeployment || proposals[1].Params.Replicas == nil || *proposals[1].Params.Replicas != 6 {
+		t.Errorf("unexpected proposals: %+v", proposals)
+	}
+	if proposals[2].Type != ActionMuteMonitor || proposals[2].Reason != "noise" {
+		t.Errorf("expected single-object block parsed, got %+v", proposals[2])
+	}
+
+	if strings.Contains(remaining, "restart_deployment") || strings.Contains(remaining, "mute_monitor") {
+		t.Errorf("parsed blocks should be stripped, got %q", remaining)
+	}
+	if !strings.Contains(remaining, "Pods are leaking memory.") || !strings.Contains(remaining, "not json") || !strings.HasSuffix(remaining, "Done.") {
+		t.Errorf("prose and malformed blocks should be kept, got %q", remaining)
+	}
+
+	if proposals, remaining := ParseProposals("no actions here"); len(proposals) != 0 || remaining != "no actions here" {
+		t.Errorf("expected text unchanged, got %v %q", proposals, remaining)
+	}
+}
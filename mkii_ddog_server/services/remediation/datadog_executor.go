@@ -0,0 +1,218 @@
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/httpclient"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+)
+
+// CredentialProvider interface at consumer side (interface ownership)
+type CredentialProvider interface {
+	GetByName(name string) (*accounts.Account, error)
+	GetDefault() *accounts.Account
+}
+
+// DatadogExecutor creates downtimes and mutes monitors through the Datadog
+// Downtime v2 API, using the credentials of the alert's account
+type DatadogExecutor struct {
+	client   *http.Client
+	accounts CredentialProvider
+}
+
+// NewDatadogExecutor creates a Datadog executor; accounts may be nil to use env credentials
+func NewDatadogExecutor(accounts CredentialProvider) *DatadogExecutor {
+	return &DatadogExecutor{
+		client:   httpclient.DatadogClient,
+		accounts: accounts,
+	}
+}
+
+// Name returns the executor identifier
+func (e *DatadogExecutor) Name() string {
+	return "datadog"
+}
+
+// Types returns the action types this executor handles
+func (e *DatadogExecutor) Types() []ActionType {
+	return []ActionType{ActionCreateDowntime, ActionMuteMonitor}
+}
+
+// Validate has nothing to add beyond the common checks
+func (e *DatadogExecutor) Validate(action *Action) error {
+	return nil
+}
+
+// Execute creates the downtime. A dry run checks the monitor exists instead.
+func (e *DatadogExecutor) Execute(ctx context.Context, action *Action, dryRun bool) (string, error) {
+	creds := e.credentials(action.AccountName)
+	params := action.Params
+
+	if dryRun {
+		if params.MonitorID != 0 {
+			if err := e.checkMonitor(ctx, params.MonitorID, creds); err != nil {
+				return "", err
+			}
+		}
+		return "dry run: would " + lowerFirst(action.Summary), nil
+	}
+
+	now := time.Now().UTC()
+	schedule := downtimeSchedule{Start: now.Format(time.RFC3339)}
+	if params.DurationMinutes > 0 {
+		schedule.End = now.Add(time.Duration(params.DurationMinutes) * time.Minute).Format(time.RFC3339)
+	}
+
+	identifier := monitorIdentifier{MonitorID: params.MonitorID}
+	if params.MonitorID == 0 {
+		identifier = monitorIdentifier{MonitorTags: []string{"*"}}
+	}
+
+	request := downtimeRequest{
+		Data: downtimeData{
+			Type: "downtime",
+			Attributes: downtimeAttributes{
+				Message:           fmt.Sprintf("Remediation %s approved by %s: %s", action.ID, action.DecidedBy, action.Reason),
+				MonitorIdentifier: identifier,
+				Scope:             formatScope(params.Scope),
+				Schedule:          schedule,
+			},
+		},
+	}
+
+	body, err := e.do(ctx, "POST", creds.BuildURL(accounts.PathDowntime), request, creds)
+	if err != nil {
+		return "", err
+	}
+
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	json.Unmarshal(body, &created)
+	return fmt.Sprintf("created downtime %s", created.Data.ID), nil
+}
+
+// checkMonitor verifies a monitor is visible with the account's credentials
+func (e *DatadogExecutor) checkMonitor(ctx context.Context, monitorID int64, creds keys.Credentials) error {
+	_, err := e.do(ctx, "GET", creds.BuildURL(fmt.Sprintf("%s/%d", accounts.PathMonitors, monitorID)), nil, creds)
+	if err != nil {
+		return fmt.Errorf("monitor %d: %w", monitorID, err)
+	}
+	return nil
+}
+
+// do sends a Datadog API request and returns the response body
+func (e *DatadogExecutor) do(ctx context.Context, method, url string, payload any, creds keys.Credentials) ([]byte, error) {
+	var reader io.Reader
+	if payload != nil {
+		jsonBody, err := json.Marshal(payload)
+		if err != nil {
+			return nil, fmt.Errorf("marshal request: %w", err)
+		}
+		reader = bytes.NewBuffer(jsonBody)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("DD-API-KEY", creds.APIKey)
+	req.Header.Set("DD-APPLICATION-KEY", creds.AppKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 400 {
+		return nil, fmt.Errorf("API returned %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
+// credentials returns the named account's credentials, the default account's, or env keys
+func (e *DatadogExecutor) credentials(accountName string) keys.Credentials {
+	if e.accounts == nil {
+		return keys.Default()
+	}
+
+	var account *accounts.Account
+	if accountName != "" {
+		account, _ = e.accounts.GetByName(accountName)
+	}
+	if account == nil {
+		account = e.accounts.GetDefault()
+	}
+	if account == nil {
+		return keys.Default()
+	}
+
+	return keys.Credentials{
+		APIKey:  account.APIKey,
+		AppKey:  account.AppKey,
+		BaseURL: account.BaseURL,
+	}
+}
+
+// formatScope converts comma-separated scope to Datadog format
+func formatScope(scope string) string {
+	var parts []string
+	for _, part := range strings.Split(scope, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// lowerFirst lower-cases the first letter of a summary for "would ..." phrasing
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+// Datadog API types
+type downtimeRequest struct {
+	Data downtimeData `json:"data"`
+}
+
+type downtimeData struct {
+	Type       string             `json:"type"`
+	Attributes downtimeAttributes `json:"attributes"`
+}
+
+type downtimeAttributes struct {
+	Message           string            `json:"message,omitempty"`
+	MonitorIdentifier monitorIdentifier `json:"monitor_identifier"`
+	Scope             string            `json:"scope"`
+	Schedule          downtimeSchedule  `json:"schedule"`
+}
+
+type monitorIdentifier struct {
+	MonitorID   int64    `json:"monitor_id,omitempty"`
+	MonitorTags []string `json:"monitor_tags,omitempty"`
+}
+
+type downtimeSchedule struct {
+	Start string `json:"start"`
+	End   string `json:"end,omitempty"`
+}
@@ -0,0 +1,93 @@
+package remediation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Executor carries out one or more action types.
+// Validate runs when an action is proposed, so unsupported targets (an
+// unregistered script, a namespace outside the allowlist) are refused before
+// anyone is asked to approve them. With dryRun set, Execute must not change
+// anything and returns what it would have done.
+type Executor interface {
+	Name() string
+	Types() []ActionType
+	Validate(action *Action) error
+	Execute(ctx context.Context, action *Action, dryRun bool) (string, error)
+}
+
+// FakeExecutor records executions instead of performing them (tests, demos)
+type FakeExecutor struct {
+	types []ActionType
+	err   error
+
+	mu    sync.Mutex
+	calls []FakeExecution
+}
+
+// FakeExecution is one call to FakeExecutor.Execute
+type FakeExecution struct {
+	ActionID string
+	Type     ActionType
+	Params   ActionParams
+	DryRun   bool
+}
+
+// NewFakeExecutor creates a fake handling the given types (all types when empty)
+func NewFakeExecutor(types ...ActionType) *FakeExecutor {
+	if len(types) == 0 {
+		types = []ActionType{ActionCreateDowntime, ActionMuteMonitor, ActionRestartDeployment, ActionScaleDeployment, ActionRunScript}
+	}
+	return &FakeExecutor{types: types}
+}
+
+// FailWith makes subsequent executions return err
+func (f *FakeExecutor) FailWith(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.err = err
+}
+
+// Name returns the executor identifier
+func (f *FakeExecutor) Name() string {
+	return "fake"
+}
+
+// Types returns the action types this fake handles
+func (f *FakeExecutor) Types() []ActionType {
+	return f.types
+}
+
+// Validate accepts every action
+func (f *FakeExecutor) Validate(action *Action) error {
+	return nil
+}
+
+// Execute records the call
+func (f *FakeExecutor) Execute(ctx context.Context, action *Action, dryRun bool) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.calls = append(f.calls, FakeExecution{
+		ActionID: action.ID,
+		Type:     action.Type,
+		Params:   action.Params,
+		DryRun:   dryRun,
+	})
+	if f.err != nil {
+		return "", f.err
+	}
+	if dryRun {
+		return "dry run: " + action.Summary, nil
+	}
+	return fmt.Sprintf("executed %s", action.Type), nil
+}
+
+// Calls returns the recorded executions
+func (f *FakeExecutor) Calls() []FakeExecution {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeExecution(nil), f.calls...)
+}
@@ -0,0 +1,237 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+)
+
+// Handler handles remediation HTTP requests
+type Handler struct {
+	manager       *Manager
+	slack         *SlackNotifier
+	signingSecret string
+	operators     *operator.Authenticator
+}
+
+// NewHandler creates a new remediation handler. slack may be nil; Slack
+// button clicks are only accepted when SLACK_SIGNING_SECRET is set.
+// Proposing, approving and rejecting need an operator token over the API or
+// a Slack user mapped to an operator (see SetOperators); until then no
+// decisions can be made.
+func NewHandler(manager *Manager, slack *SlackNotifier) *Handler {
+	return &Handler{
+		manager:       manager,
+		slack:         slack,
+		signingSecret: os.Getenv("SLACK_SIGNING_SECRET"),
+	}
+}
+
+// SetOperators sets who may propose, approve and reject over the API and
+// which Slack users may approve and reject in Slack
+func (h *Handler) SetOperators(operators *operator.Authenticator) {
+	h.operators = operators
+}
+
+// ListActions lists recent actions (GET /v1/remediation/actions?status=pending_approval&limit=50)
+func (h *Handler) ListActions(w http.ResponseWriter, r *http.Request) (int, any) {
+	limit := 50
+	if v, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && v > 0 {
+		limit = v
+	}
+	status := ActionStatus(r.URL.Query().Get("status"))
+
+	actions, err := h.manager.List(r.Context(), status, limit)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if actions == nil {
+		actions = []Action{}
+	}
+	return http.StatusOK, map[string]any{
+		"actions":   actions,
+		"count":     len(actions),
+		"dry_run":   h.manager.Config().DryRun,
+		"executors": h.manager.Executors(),
+	}
+}
+
+// ProposeAction submits an action for approval by hand (POST /v1/remediation/actions).
+// The authenticated operator is recorded as the proposer.
+func (h *Handler) ProposeAction(w http.ResponseWriter, r *http.Request) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	var proposal Proposal
+	if err := json.NewDecoder(r.Body).Decode(&proposal); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+	}
+	proposal.ProposedBy = actor
+
+	action, err := h.manager.Propose(r.Context(), proposal)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusCreated, action
+}
+
+// GetAction returns an action with its audit trail (GET /v1/remediation/actions/{id})
+func (h *Handler) GetAction(w http.ResponseWriter, r *http.Request, id string) (int, any) {
+	detail, err := h.manager.Get(r.Context(), id)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, detail
+}
+
+// ApproveAction approves and executes an action (POST /v1/remediation/actions/{id}/approve).
+// With "dry_run": true the action is previewed and stays pending. The
+// approver is the authenticated operator and must not be the proposer.
+func (h *Handler) ApproveAction(w http.ResponseWriter, r *http.Request, id string) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	var req DecisionRequest
+	if err := decodeDecision(r, &req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+	}
+
+	action, err := h.manager.Approve(r.Context(), id, actor, req.DryRun)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	if action.Status == StatusFailed || action.Error != "" {
+		return http.StatusBadGateway, action
+	}
+	return http.StatusOK, action
+}
+
+// RejectAction rejects an action (POST /v1/remediation/actions/{id}/reject)
+func (h *Handler) RejectAction(w http.ResponseWriter, r *http.Request, id string) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	var req DecisionRequest
+	if err := decodeDecision(r, &req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("invalid request: %v", err)}
+	}
+
+	action, err := h.manager.Reject(r.Context(), id, actor, req.Reason)
+	if err != nil {
+		return errorStatus(err), map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, action
+}
+
+// SlackInteraction handles Approve/Reject button clicks (POST /v1/remediation/slack/interactions).
+// Slack expects an answer within 3 seconds, so the decision runs in the
+// background and the outcome replaces the original message via response_url.
+func (h *Handler) SlackInteraction(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		http.Error(w, "failed to read body", http.StatusBadRequest)
+		return
+	}
+	if err := VerifySlackSignature(h.signingSecret, r.Header, body, time.Now()); err != nil {
+		log.Printf("[REMEDIATION] Rejected Slack interaction: %v", err)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form body", http.StatusBadRequest)
+		return
+	}
+	var interaction slackInteraction
+	if err := json.Unmarshal([]byte(form.Get("payload")), &interaction); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+
+	// Slack users act as the operator they're mapped to, so the four-eyes
+	// rule holds across the API and Slack
+	actor, err := h.operators.IdentifySlack(interaction.User.ID)
+	if err != nil {
+		log.Printf("[REMEDIATION] Refused Slack interaction from unmapped user %s", interaction.actor())
+		return
+	}
+
+	for _, clicked := range interaction.Actions {
+		if clicked.ActionID != slackActionApprove && clicked.ActionID != slackActionReject {
+			continue
+		}
+		go h.decideFromSlack(clicked.ActionID, clicked.Value, actor, interaction.ResponseURL)
+	}
+}
+
+// decideFromSlack applies a button click and reports the outcome back to Slack
+func (h *Handler) decideFromSlack(actionID, id, actor, responseURL string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	var action *Action
+	var err error
+	if actionID == slackActionApprove {
+		action, err = h.manager.Approve(ctx, id, actor, false)
+	} else {
+		action, err = h.manager.Reject(ctx, id, actor, "rejected in Slack")
+	}
+
+	if err != nil {
+		log.Printf("[REMEDIATION] Slack decision on %s by %s failed: %v", id, actor, err)
+		// Show the current state (already decided, expired, ...) instead
+		if detail, getErr := h.manager.Get(ctx, id); getErr == nil {
+			action = detail.Action
+		} else {
+			return
+		}
+	}
+
+	if h.slack != nil && responseURL != "" {
+		if err := h.slack.Respond(ctx, responseURL, action); err != nil {
+			log.Printf("[REMEDIATION] Failed to update Slack message for %s: %v", id, err)
+		}
+	}
+}
+
+// decodeDecision reads an optional decision body; the operator token alone
+// is enough to approve or reject
+func decodeDecision(r *http.Request, req *DecisionRequest) error {
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// errorStatus maps remediation errors to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrSelfApproval):
+		return http.StatusForbidden
+	case errors.Is(err, ErrActionNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrNotPending):
+		return http.StatusConflict
+	case errors.Is(err, ErrActionExpired):
+		return http.StatusGone
+	case errors.Is(err, ErrNoExecutor), errors.Is(err, ErrInvalidAction):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
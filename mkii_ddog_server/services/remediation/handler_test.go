@@ -0,0 +1,82 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+)
+
+func TestHandler_DecisionsNeedAnotherOperator(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+	handler := NewHandler(manager, nil)
+	handler.SetOperators(operator.NewAuthenticator(map[string]string{"alice": "a-token", "bob": "b-token"}))
+
+	request := func(path, token, body string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+
+	proposal := `{"type":"restart_deployment","params":{"namespace":"prod","deployment":"checkout"},"reason":"leak","proposed_by":"agent"}`
+	if status, _ := handler.ProposeAction(httptest.NewRecorder(), request("/v1/remediation/actions", "", proposal)); status != http.StatusUnauthorized {
+		t.Fatalf("expected an anonymous proposal refused, got %d", status)
+	}
+	status, result := handler.ProposeAction(httptest.NewRecorder(), request("/v1/remediation/actions", "a-token", proposal))
+	action, ok := result.(*Action)
+	if status != http.StatusCreated || !ok || action.ProposedBy != "operator:alice" {
+		t.Fatalf("expected the token's operator as proposer, got %d %+v", status, result)
+	}
+
+	approve := "/v1/remediation/actions/" + action.ID + "/approve"
+	for name, tt := range map[string]struct {
+		token, body string
+		want        int
+	}{
+		"no token":              {"", `{}`, http.StatusUnauthorized},
+		"unknown token":         {"forged", `{}`, http.StatusUnauthorized},
+		"proposer approving":    {"a-token", `{}`, http.StatusForbidden},
+		"claimed actor in body": {"a-token", `{"actor":"operator:bob"}`, http.StatusForbidden},
+	} {
+		if status, _ := handler.ApproveAction(httptest.NewRecorder(), request(approve, tt.token, tt.body), action.ID); status != tt.want {
+			t.Errorf("%s: got %d, want %d", name, status, tt.want)
+		}
+	}
+	if len(executor.Calls()) != 0 {
+		t.Fatalf("expected nothing executed, got %d calls", len(executor.Calls()))
+	}
+
+	// The proposer may still preview it
+	if status, _ := handler.ApproveAction(httptest.NewRecorder(), request(approve, "a-token", `{"dry_run":true}`), action.ID); status != http.StatusOK {
+		t.Fatalf("expected the proposer's preview allowed, got %d", status)
+	}
+
+	status, result = handler.ApproveAction(httptest.NewRecorder(), request(approve, "b-token", ""), action.ID)
+	if approved, ok := result.(*Action); status != http.StatusOK || !ok || approved.DecidedBy != "operator:bob" {
+		t.Fatalf("expected bob's approval to run, got %d %+v", status, result)
+	}
+
+	events := auditEvents(t, manager, action.ID)
+	data, _ := json.Marshal(events)
+	if !strings.Contains(string(data), string(AuditApprovalDenied)) {
+		t.Errorf("expected refused self-approvals audited, got %s", data)
+	}
+}
+
+func TestManager_RefusesSelfApproval(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+	action, _ := manager.Propose(context.Background(), restartProposal())
+
+	if _, err := manager.Approve(context.Background(), action.ID, action.ProposedBy, false); err != ErrSelfApproval {
+		t.Fatalf("expected ErrSelfApproval, got %v", err)
+	}
+	if _, err := manager.Approve(context.Background(), action.ID, "slack:alice", false); err != nil || len(executor.Calls()) != 1 {
+		t.Fatalf("expected another approver to succeed, got %v", err)
+	}
+}
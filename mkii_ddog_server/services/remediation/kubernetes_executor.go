@@ -0,0 +1,191 @@
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// In-cluster service account paths
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	restartAnnotation = "kubectl.kubernetes.io/restartedAt"
+)
+
+// KubernetesExecutor restarts and scales deployments through the Kubernetes
+// API, the same way `kubectl rollout restart` and `kubectl scale` do.
+// Dry runs are sent with dryRun=All, so the API server validates the change
+// (including RBAC) without persisting it.
+type KubernetesExecutor struct {
+	apiURL     string
+	token      func() (string, error)
+	client     *http.Client
+	namespaces map[string]bool // allowlist; empty allows every namespace
+}
+
+// NewKubernetesExecutor creates an executor for an explicit API server and bearer token
+func NewKubernetesExecutor(apiURL, token string, client *http.Client, namespaces []string) *KubernetesExecutor {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &KubernetesExecutor{
+		apiURL:     strings.TrimSuffix(apiURL, "/"),
+		token:      func() (string, error) { return token, nil },
+		client:     client,
+		namespaces: namespaceSet(namespaces),
+	}
+}
+
+// NewKubernetesExecutorInCluster uses the pod's service account. The token is
+// re-read on every request because projected tokens rotate.
+func NewKubernetesExecutorInCluster(namespaces []string) (*KubernetesExecutor, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a Kubernetes cluster")
+	}
+
+	caCert, err := os.ReadFile(serviceAccountDir + "/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("read service account CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("parse service account CA")
+	}
+
+	return &KubernetesExecutor{
+		apiURL: "https://" + net.JoinHostPort(host, port),
+		token: func() (string, error) {
+			token, err := os.ReadFile(serviceAccountDir + "/token")
+			return strings.TrimSpace(string(token)), err
+		},
+		client: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		namespaces: namespaceSet(namespaces),
+	}, nil
+}
+
+// Name returns the executor identifier
+func (e *KubernetesExecutor) Name() string {
+	return "kubernetes"
+}
+
+// Types returns the action types this executor handles
+func (e *KubernetesExecutor) Types() []ActionType {
+	return []ActionType{ActionRestartDeployment, ActionScaleDeployment}
+}
+
+// Validate enforces the namespace allowlist
+func (e *KubernetesExecutor) Validate(action *Action) error {
+	if len(e.namespaces) > 0 && !e.namespaces[action.Params.Namespace] {
+		return fmt.Errorf("namespace %q is not in the remediation allowlist", action.Params.Namespace)
+	}
+	return nil
+}
+
+// Execute patches the deployment (or its scale subresource)
+func (e *KubernetesExecutor) Execute(ctx context.Context, action *Action, dryRun bool) (string, error) {
+	params := action.Params
+	path := fmt.Sprintf("/apis/apps/v1/namespaces/%s/deployments/%s", params.Namespace, params.Deployment)
+
+	var patch any
+	contentType := "application/merge-patch+json"
+	switch action.Type {
+	case ActionRestartDeployment:
+		contentType = "application/strategic-merge-patch+json"
+		patch = map[string]any{
+			"spec": map[string]any{
+				"template": map[string]any{
+					"metadata": map[string]any{
+						"annotations": map[string]string{restartAnnotation: time.Now().UTC().Format(time.RFC3339)},
+					},
+				},
+			},
+		}
+	case ActionScaleDeployment:
+		path += "/scale"
+		patch = map[string]any{"spec": map[string]any{"replicas": *params.Replicas}}
+	default:
+		return "", fmt.Errorf("%w: %s", ErrNoExecutor, action.Type)
+	}
+
+	if dryRun {
+		path += "?dryRun=All"
+	}
+	if err := e.patch(ctx, path, contentType, patch); err != nil {
+		return "", err
+	}
+
+	if dryRun {
+		return "dry run accepted by API server: would " + lowerFirst(action.Summary), nil
+	}
+	return lowerFirst(action.Summary) + ": done", nil
+}
+
+// patch sends a PATCH to the API server
+func (e *KubernetesExecutor) patch(ctx context.Context, path, contentType string, patch any) error {
+	body, err := json.Marshal(patch)
+	if err != nil {
+		return fmt.Errorf("marshal patch: %w", err)
+	}
+
+	token, err := e.token()
+	if err != nil {
+		return fmt.Errorf("read service account token: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "PATCH", e.apiURL+path, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("execute request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("kubernetes API returned %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+// NamespacesFromEnv parses REMEDIATION_K8S_NAMESPACES (comma-separated; empty allows all)
+func NamespacesFromEnv() []string {
+	var namespaces []string
+	for _, ns := range strings.Split(os.Getenv("REMEDIATION_K8S_NAMESPACES"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			namespaces = append(namespaces, ns)
+		}
+	}
+	return namespaces
+}
+
+// namespaceSet builds the namespace allowlist
+func namespaceSet(namespaces []string) map[string]bool {
+	set := make(map[string]bool, len(namespaces))
+	for _, ns := range namespaces {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			set[ns] = true
+		}
+	}
+	return set
+}
@@ -0,0 +1,60 @@
+package remediation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestKubernetesExecutor_Execute(t *testing.T) {
+	type request struct {
+		method, path, query, contentType, auth string
+		body                                   map[string]any
+	}
+	requests := make(chan request, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]any
+		json.Unmarshal(raw, &body)
+		requests <- request{r.Method, r.URL.Path, r.URL.RawQuery, r.Header.Get("Content-Type"), r.Header.Get("Authorization"), body}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	executor := NewKubernetesExecutor(server.URL, "token", nil, []string{"prod"})
+	replicas := 5
+	scale := &Action{Type: ActionScaleDeployment, Params: ActionParams{Namespace: "prod", Deployment: "api", Replicas: &replicas}, Summary: "Scale deployment prod/api to 5 replicas"}
+
+	if err := executor.Validate(&Action{Params: ActionParams{Namespace: "kube-system"}}); err == nil {
+		t.Error("expected namespace outside allowlist refused")
+	}
+
+	if _, err := executor.Execute(context.Background(), scale, true); err != nil {
+		t.Fatalf("dry-run scale failed: %v", err)
+	}
+	req := <-requests
+	if req.method != "PATCH" || req.path != "/apis/apps/v1/namespaces/prod/deployments/api/scale" || req.query != "dryRun=All" ||
+		req.contentType != "application/merge-patch+json" || req.auth != "Bearer token" {
+		t.Errorf("unexpected scale request: %+v", req)
+	}
+	if spec, _ := req.body["spec"].(map[string]any); spec["replicas"] != float64(5) {
+		t.Errorf("unexpected scale body: %+v", req.body)
+	}
+
+	restart := &Action{Type: ActionRestartDeployment, Params: ActionParams{Namespace: "prod", Deployment: "api"}, Summary: "Rolling restart of deployment prod/api"}
+	if _, err := executor.Execute(context.Background(), restart, false); err != nil {
+		t.Fatalf("restart failed: %v", err)
+	}
+	req = <-requests
+	if req.path != "/apis/apps/v1/namespaces/prod/deployments/api" || req.query != "" || req.contentType != "application/strategic-merge-patch+json" {
+		t.Errorf("unexpected restart request: %+v", req)
+	}
+	if !strings.Contains(fmt.Sprint(req.body), restartAnnotation) {
+		t.Errorf("expected restartedAt annotation, got %+v", req.body)
+	}
+}
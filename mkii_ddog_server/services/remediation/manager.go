@@ -0,0 +1,315 @@
+package remediation
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Config controls approval and execution behaviour
+type Config struct {
+	DryRun      bool          // execute every approved action as a dry run
+	ApprovalTTL time.Duration // pending actions expire after this long
+	MaxReplicas int           // upper bound for scale_deployment
+}
+
+// ConfigFromEnv reads REMEDIATION_* settings. Dry-run mode is on unless
+// REMEDIATION_DRY_RUN is explicitly false.
+func ConfigFromEnv() Config {
+	config := Config{
+		DryRun:      true,
+		ApprovalTTL: time.Hour,
+		MaxReplicas: defaultMaxReplicas,
+	}
+	if v, err := strconv.ParseBool(os.Getenv("REMEDIATION_DRY_RUN")); err == nil {
+		config.DryRun = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("REMEDIATION_APPROVAL_TTL")); err == nil && v > 0 {
+		config.ApprovalTTL = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("REMEDIATION_MAX_REPLICAS")); err == nil && v > 0 {
+		config.MaxReplicas = v
+	}
+	return config
+}
+
+// Notifier tells humans an action is waiting for approval
+type Notifier interface {
+	Notify(ctx context.Context, action *Action) error
+}
+
+// Manager validates proposals, gates them on human approval, executes them
+// and records every step in the audit log. Nothing runs without an explicit
+// Approve call.
+type Manager struct {
+	store    Store
+	config   Config
+	notifier Notifier
+	now      func() time.Time
+
+	executors map[ActionType]Executor
+	mu        sync.RWMutex
+}
+
+// NewManager creates a remediation manager
+func NewManager(store Store, config Config) *Manager {
+	if config.ApprovalTTL <= 0 {
+		config.ApprovalTTL = time.Hour
+	}
+	return &Manager{
+		store:     store,
+		config:    config,
+		now:       time.Now,
+		executors: make(map[ActionType]Executor),
+	}
+}
+
+// Config returns the manager's configuration
+func (m *Manager) Config() Config {
+	return m.config
+}
+
+// SetNotifier sets the approval notifier (nil disables notifications)
+func (m *Manager) SetNotifier(notifier Notifier) {
+	m.notifier = notifier
+}
+
+// RegisterExecutor registers an executor for each of its action types
+func (m *Manager) RegisterExecutor(executor Executor) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, actionType := range executor.Types() {
+		m.executors[actionType] = executor
+	}
+	log.Printf("[REMEDIATION] Registered executor %s for %v", executor.Name(), executor.Types())
+}
+
+// executor returns the executor for an action type
+func (m *Manager) executor(actionType ActionType) (Executor, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	executor, ok := m.executors[actionType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrNoExecutor, actionType)
+	}
+	return executor, nil
+}
+
+// Propose validates a proposal and stores it as pending approval
+func (m *Manager) Propose(ctx context.Context, proposal Proposal) (*Action, error) {
+	// "Mute this monitor" from an agent usually omits the ID it is analyzing
+	if proposal.Type == ActionMuteMonitor && proposal.Params.MonitorID == 0 {
+		proposal.Params.MonitorID = proposal.MonitorID
+	}
+	if err := Validate(proposal, m.config.MaxReplicas); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAction, err)
+	}
+	executor, err := m.executor(proposal.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	now := m.now()
+	action := &Action{
+		ID:          uuid.NewString(),
+		Type:        proposal.Type,
+		Params:      proposal.Params,
+		Reason:      proposal.Reason,
+		Summary:     Describe(proposal),
+		AnalysisID:  proposal.AnalysisID,
+		MonitorID:   proposal.MonitorID,
+		MonitorName: proposal.MonitorName,
+		AccountName: proposal.AccountName,
+		ProposedBy:  proposal.ProposedBy,
+		Status:      StatusPendingApproval,
+		DryRun:      m.config.DryRun,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(m.config.ApprovalTTL),
+	}
+	if action.ProposedBy == "" {
+		action.ProposedBy = "unknown"
+	}
+	if err := executor.Validate(action); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidAction, err)
+	}
+
+	if err := m.store.CreateAction(ctx, action); err != nil {
+		return nil, err
+	}
+	m.audit(ctx, action.ID, AuditProposed, action.ProposedBy, action.Summary, map[string]any{
+		"type":   action.Type,
+		"params": action.Params,
+		"reason": action.Reason,
+	})
+	log.Printf("[REMEDIATION] Proposed %s by %s: %s", action.ID, action.ProposedBy, action.Summary)
+
+	if m.notifier != nil {
+		if err := m.notifier.Notify(ctx, action); err != nil {
+			log.Printf("[REMEDIATION] Failed to notify approvers for %s: %v", action.ID, err)
+		} else {
+			m.audit(ctx, action.ID, AuditNotified, "system", "", nil)
+		}
+	}
+
+	return action, nil
+}
+
+// Approve approves a pending action and executes it. When the manager is in
+// dry-run mode the execution is a dry run and the approval is consumed.
+// preview requests a dry run without consuming the approval: the action stays
+// pending so it can still be approved for real. actor must be a verified
+// identity and may not be the proposer (four-eyes rule), except for previews.
+func (m *Manager) Approve(ctx context.Context, id, actor string, preview bool) (*Action, error) {
+	action, err := m.pending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !preview && actor == action.ProposedBy {
+		m.audit(ctx, id, AuditApprovalDenied, actor, ErrSelfApproval.Error(), nil)
+		return nil, ErrSelfApproval
+	}
+	executor, err := m.executor(action.Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if preview {
+		output, err := executor.Execute(ctx, action, true)
+		detail := output
+		if err != nil {
+			detail = err.Error()
+		}
+		m.audit(ctx, id, AuditDryRun, actor, detail, map[string]any{"preview": true})
+		action.Output = output
+		action.DryRun = true
+		if err != nil {
+			action.Error = err.Error()
+		}
+		return action, nil
+	}
+
+	action, err = m.store.Transition(ctx, id, StatusPendingApproval, StatusApproved, actor, m.now())
+	if err != nil {
+		return nil, err
+	}
+	m.audit(ctx, id, AuditApproved, actor, "", nil)
+
+	dryRun := m.config.DryRun
+	output, execErr := executor.Execute(ctx, action, dryRun)
+
+	executedAt := m.now()
+	action.ExecutedAt = &executedAt
+	action.DryRun = dryRun
+	action.Output = output
+	if execErr != nil {
+		action.Status = StatusFailed
+		action.Error = execErr.Error()
+		m.audit(ctx, id, AuditFailed, executor.Name(), action.Error, map[string]any{"dry_run": dryRun, "output": output})
+		log.Printf("[REMEDIATION] %s failed after approval by %s: %v", id, actor, execErr)
+	} else {
+		action.Status = StatusSucceeded
+		event := AuditExecuted
+		if dryRun {
+			event = AuditDryRun
+		}
+		m.audit(ctx, id, event, executor.Name(), output, nil)
+		log.Printf("[REMEDIATION] %s executed (dry_run=%v) after approval by %s", id, dryRun, actor)
+	}
+
+	if err := m.store.SaveResult(context.WithoutCancel(ctx), action); err != nil {
+		log.Printf("[REMEDIATION] Failed to save result for %s: %v", id, err)
+	}
+	return action, nil
+}
+
+// Reject rejects a pending action
+func (m *Manager) Reject(ctx context.Context, id, actor, reason string) (*Action, error) {
+	if _, err := m.pending(ctx, id); err != nil {
+		return nil, err
+	}
+
+	action, err := m.store.Transition(ctx, id, StatusPendingApproval, StatusRejected, actor, m.now())
+	if err != nil {
+		return nil, err
+	}
+	m.audit(ctx, id, AuditRejected, actor, reason, nil)
+	log.Printf("[REMEDIATION] %s rejected by %s", id, actor)
+	return action, nil
+}
+
+// pending loads an action that is still awaiting a decision, expiring it
+// if its approval window has passed
+func (m *Manager) pending(ctx context.Context, id string) (*Action, error) {
+	action, err := m.store.GetAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if action.Status != StatusPendingApproval {
+		return nil, ErrNotPending
+	}
+	if m.now().After(action.ExpiresAt) {
+		if _, err := m.store.Transition(ctx, id, StatusPendingApproval, StatusExpired, "system", m.now()); err == nil {
+			m.audit(ctx, id, AuditExpired, "system", "", nil)
+		}
+		return nil, ErrActionExpired
+	}
+	return action, nil
+}
+
+// Get returns an action with its audit trail
+func (m *Manager) Get(ctx context.Context, id string) (*ActionDetail, error) {
+	action, err := m.store.GetAction(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := m.store.ListAudit(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if audit == nil {
+		audit = []AuditEntry{}
+	}
+	return &ActionDetail{Action: action, Audit: audit}, nil
+}
+
+// List returns recent actions, optionally filtered by status
+func (m *Manager) List(ctx context.Context, status ActionStatus, limit int) ([]Action, error) {
+	return m.store.ListActions(ctx, status, limit)
+}
+
+// Executors returns the registered executor names by action type
+func (m *Manager) Executors() map[ActionType]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make(map[ActionType]string, len(m.executors))
+	for actionType, executor := range m.executors {
+		names[actionType] = executor.Name()
+	}
+	return names
+}
+
+// audit appends an audit entry; failures are logged, never returned, so the
+// audit log can't block an approval that has already happened
+func (m *Manager) audit(ctx context.Context, actionID string, event AuditEvent, actor, detail string, data map[string]any) {
+	entry := AuditEntry{
+		ActionID: actionID,
+		Event:    event,
+		Actor:    strings.TrimSpace(actor),
+		Detail:   detail,
+		Data:     data,
+		At:       m.now(),
+	}
+	if err := m.store.AppendAudit(context.WithoutCancel(ctx), entry); err != nil {
+		log.Printf("[REMEDIATION] Failed to write audit entry %s for %s: %v", event, actionID, err)
+	}
+}
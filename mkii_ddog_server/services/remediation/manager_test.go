@@ -0,0 +1,217 @@
+package remediation
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestManager(config Config) (*Manager, *FakeExecutor, *MemoryStore) {
+	store := NewMemoryStore()
+	manager := NewManager(store, config)
+	executor := NewFakeExecutor()
+	manager.RegisterExecutor(executor)
+	return manager, executor, store
+}
+
+func restartProposal() Proposal {
+	return Proposal{
+		Type:       ActionRestartDeployment,
+		Params:     ActionParams{Namespace: "prod", Deployment: "checkout"},
+		Reason:     "memory leak",
+		ProposedBy: "claude-infrastructure",
+		MonitorID:  42,
+	}
+}
+
+func auditEvents(t *testing.T, manager *Manager, id string) []AuditEvent {
+	t.Helper()
+	detail, err := manager.Get(context.Background(), id)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	events := make([]AuditEvent, len(detail.Audit))
+	for i, entry := range detail.Audit {
+		events[i] = entry.Event
+	}
+	return events
+}
+
+func equalEvents(a, b []AuditEvent) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+type recordingNotifier struct {
+	mu      sync.Mutex
+	actions []string
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, action *Action) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.actions = append(n.actions, action.ID)
+	return nil
+}
+
+func TestManager_ProposeApproveExecutes(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+	notifier := &recordingNotifier{}
+	manager.SetNotifier(notifier)
+
+	action, err := manager.Propose(context.Background(), restartProposal())
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	if action.Status != StatusPendingApproval || action.Summary != "Rolling restart of deployment prod/checkout" {
+		t.Fatalf("unexpected proposed action: %+v", action)
+	}
+	if len(notifier.actions) != 1 || notifier.actions[0] != action.ID {
+		t.Errorf("expected approvers notified once, got %v", notifier.actions)
+	}
+	if len(executor.Calls()) != 0 {
+		t.Fatalf("nothing may execute before approval")
+	}
+
+	approved, err := manager.Approve(context.Background(), action.ID, "oncall@example.com", false)
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if approved.Status != StatusSucceeded || approved.DryRun || approved.DecidedBy != "oncall@example.com" || approved.ExecutedAt == nil {
+		t.Errorf("unexpected approved action: %+v", approved)
+	}
+
+	calls := executor.Calls()
+	if len(calls) != 1 || calls[0].DryRun || calls[0].Params.Deployment != "checkout" {
+		t.Errorf("expected one live execution, got %+v", calls)
+	}
+
+	want := []AuditEvent{AuditProposed, AuditNotified, AuditApproved, AuditExecuted}
+	if got := auditEvents(t, manager, action.ID); !equalEvents(got, want) {
+		t.Errorf("audit trail = %v, want %v", got, want)
+	}
+
+	if _, err := manager.Approve(context.Background(), action.ID, "someone-else", false); !errors.Is(err, ErrNotPending) {
+		t.Errorf("expected ErrNotPending on second approval, got %v", err)
+	}
+	if len(executor.Calls()) != 1 {
+		t.Errorf("second approval must not execute again")
+	}
+}
+
+func TestManager_DryRunMode(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: true})
+
+	action, _ := manager.Propose(context.Background(), restartProposal())
+	approved, err := manager.Approve(context.Background(), action.ID, "oncall", false)
+	if err != nil {
+		t.Fatalf("Approve failed: %v", err)
+	}
+	if !approved.DryRun || approved.Status != StatusSucceeded {
+		t.Errorf("expected dry-run execution, got %+v", approved)
+	}
+	if calls := executor.Calls(); len(calls) != 1 || !calls[0].DryRun {
+		t.Errorf("expected executor called with dryRun, got %+v", calls)
+	}
+	want := []AuditEvent{AuditProposed, AuditApproved, AuditDryRun}
+	if got := auditEvents(t, manager, action.ID); !equalEvents(got, want) {
+		t.Errorf("audit trail = %v, want %v", got, want)
+	}
+}
+
+func TestManager_PreviewKeepsActionPending(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+
+	action, _ := manager.Propose(context.Background(), restartProposal())
+	preview, err := manager.Approve(context.Background(), action.ID, "oncall", true)
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if !preview.DryRun || preview.Status != StatusPendingApproval || preview.Output == "" {
+		t.Errorf("expected pending dry-run preview, got %+v", preview)
+	}
+
+	if _, err := manager.Approve(context.Background(), action.ID, "oncall", false); err != nil {
+		t.Fatalf("approval after preview failed: %v", err)
+	}
+	calls := executor.Calls()
+	if len(calls) != 2 || !calls[0].DryRun || calls[1].DryRun {
+		t.Errorf("expected preview then live execution, got %+v", calls)
+	}
+}
+
+func TestManager_RejectAndExpire(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{ApprovalTTL: time.Minute})
+
+	rejected, _ := manager.Propose(context.Background(), restartProposal())
+	if _, err := manager.Reject(context.Background(), rejected.ID, "oncall", "not during peak"); err != nil {
+		t.Fatalf("Reject failed: %v", err)
+	}
+	if _, err := manager.Approve(context.Background(), rejected.ID, "oncall", false); !errors.Is(err, ErrNotPending) {
+		t.Errorf("expected ErrNotPending after reject, got %v", err)
+	}
+
+	expired, _ := manager.Propose(context.Background(), restartProposal())
+	manager.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	if _, err := manager.Approve(context.Background(), expired.ID, "oncall", false); !errors.Is(err, ErrActionExpired) {
+		t.Errorf("expected ErrActionExpired, got %v", err)
+	}
+	detail, _ := manager.Get(context.Background(), expired.ID)
+	if detail.Action.Status != StatusExpired {
+		t.Errorf("expected expired status, got %s", detail.Action.Status)
+	}
+
+	if len(executor.Calls()) != 0 {
+		t.Errorf("rejected and expired actions must not execute, got %+v", executor.Calls())
+	}
+	want := []AuditEvent{AuditProposed, AuditRejected}
+	if got := auditEvents(t, manager, rejected.ID); !equalEvents(got, want) {
+		t.Errorf("audit trail = %v, want %v", got, want)
+	}
+}
+
+func TestManager_ExecutionFailure(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+	executor.FailWith(errors.New("deployment not found"))
+
+	action, _ := manager.Propose(context.Background(), restartProposal())
+	failed, err := manager.Approve(context.Background(), action.ID, "oncall", false)
+	if err != nil {
+		t.Fatalf("Approve returned error: %v", err)
+	}
+	if failed.Status != StatusFailed || failed.Error != "deployment not found" {
+		t.Errorf("expected failed action, got %+v", failed)
+	}
+	detail, _ := manager.Get(context.Background(), action.ID)
+	if detail.Action.Status != StatusFailed {
+		t.Errorf("expected stored failure, got %s", detail.Action.Status)
+	}
+}
+
+func TestManager_ProposeRejectsInvalidOrUnsupported(t *testing.T) {
+	manager := NewManager(NewMemoryStore(), Config{})
+	manager.RegisterExecutor(NewFakeExecutor(ActionRestartDeployment))
+
+	if _, err := manager.Propose(context.Background(), Proposal{Type: ActionRestartDeployment, Params: ActionParams{Namespace: "Prod!", Deployment: "x"}}); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("expected ErrInvalidAction, got %v", err)
+	}
+	replicas := 2
+	if _, err := manager.Propose(context.Background(), Proposal{Type: ActionScaleDeployment, Params: ActionParams{Namespace: "prod", Deployment: "x", Replicas: &replicas}}); !errors.Is(err, ErrNoExecutor) {
+		t.Errorf("expected ErrNoExecutor, got %v", err)
+	}
+
+	scripts := NewScriptExecutor(map[string]string{"flush-cache": "/opt/flush.sh"}, 0)
+	manager.RegisterExecutor(scripts)
+	if _, err := manager.Propose(context.Background(), Proposal{Type: ActionRunScript, Params: ActionParams{Script: "rm-rf"}}); !errors.Is(err, ErrInvalidAction) {
+		t.Errorf("expected unregistered script refused, got %v", err)
+	}
+}
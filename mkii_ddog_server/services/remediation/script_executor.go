@@ -0,0 +1,130 @@
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// scriptOutputLimit caps the output kept in the action record and audit log
+const scriptOutputLimit = 8 * 1024
+
+// scriptEnvAllowlist are the only server environment variables scripts
+// inherit; everything else (API keys, operator tokens, database credentials)
+// stays in the server
+var scriptEnvAllowlist = []string{"PATH", "HOME", "LANG"}
+
+// ScriptExecutor runs operator-registered scripts by name. Agents can only
+// pick from the registry; arguments are passed as argv, never through a shell.
+type ScriptExecutor struct {
+	scripts map[string]string // name -> path
+	timeout time.Duration
+}
+
+// NewScriptExecutor creates an executor for the given name -> path registry
+func NewScriptExecutor(scripts map[string]string, timeout time.Duration) *ScriptExecutor {
+	if timeout <= 0 {
+		timeout = 2 * time.Minute
+	}
+	return &ScriptExecutor{scripts: scripts, timeout: timeout}
+}
+
+// ScriptsFromEnv parses REMEDIATION_SCRIPTS ("name=/path/to/script,other=/path")
+func ScriptsFromEnv() map[string]string {
+	scripts := make(map[string]string)
+	for _, entry := range strings.Split(os.Getenv("REMEDIATION_SCRIPTS"), ",") {
+		name, path, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if ok && name != "" && path != "" {
+			scripts[strings.TrimSpace(name)] = strings.TrimSpace(path)
+		}
+	}
+	return scripts
+}
+
+// Name returns the executor identifier
+func (e *ScriptExecutor) Name() string {
+	return "script"
+}
+
+// Types returns the action types this executor handles
+func (e *ScriptExecutor) Types() []ActionType {
+	return []ActionType{ActionRunScript}
+}
+
+// Scripts returns the registered script names
+func (e *ScriptExecutor) Scripts() []string {
+	names := make([]string, 0, len(e.scripts))
+	for name := range e.scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate requires a registered script
+func (e *ScriptExecutor) Validate(action *Action) error {
+	if _, ok := e.scripts[action.Params.Script]; !ok {
+		return fmt.Errorf("script %q is not registered", action.Params.Script)
+	}
+	return nil
+}
+
+// Execute runs the script. Dry runs do not start it; they only check that the
+// file is executable.
+func (e *ScriptExecutor) Execute(ctx context.Context, action *Action, dryRun bool) (string, error) {
+	path, ok := e.scripts[action.Params.Script]
+	if !ok {
+		return "", fmt.Errorf("script %q is not registered", action.Params.Script)
+	}
+
+	if dryRun {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("script %s: %w", action.Params.Script, err)
+		}
+		if info.Mode().Perm()&0o111 == 0 {
+			return "", fmt.Errorf("script %s (%s) is not executable", action.Params.Script, path)
+		}
+		return fmt.Sprintf("dry run: would run %s %s", path, strings.Join(action.Params.Args, " ")), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, e.timeout)
+	defer cancel()
+
+	var output bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, action.Params.Args...)
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+	cmd.Env = scriptEnv(action)
+
+	err := cmd.Run()
+	out := output.String()
+	if len(out) > scriptOutputLimit {
+		out = out[len(out)-scriptOutputLimit:]
+	}
+	if err != nil {
+		return out, fmt.Errorf("script %s failed: %w", action.Params.Script, err)
+	}
+	return out, nil
+}
+
+// scriptEnv builds a script's environment from the allowlist and the
+// action's REMEDIATION_* variables
+func scriptEnv(action *Action) []string {
+	var env []string
+	for _, name := range scriptEnvAllowlist {
+		if value, ok := os.LookupEnv(name); ok {
+			env = append(env, name+"="+value)
+		}
+	}
+	return append(env,
+		"REMEDIATION_ACTION_ID="+action.ID,
+		"REMEDIATION_APPROVED_BY="+action.DecidedBy,
+		fmt.Sprintf("REMEDIATION_MONITOR_ID=%d", action.MonitorID),
+	)
+}
@@ -0,0 +1,36 @@
+package remediation
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptExecutor_EnvironmentIsAllowlisted(t *testing.T) {
+	t.Setenv("OPERATOR_TOKENS", "alice:s3cret")
+	t.Setenv("DD_API_KEY", "dd-secret")
+	script := filepath.Join(t.TempDir(), "env.sh")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\nenv\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	executor := NewScriptExecutor(map[string]string{"env": script}, 0)
+	action := &Action{ID: "a1", DecidedBy: "operator:bob", MonitorID: 42, Params: ActionParams{Script: "env"}}
+	out, err := executor.Execute(context.Background(), action, false)
+	if err != nil {
+		t.Fatalf("execute: %v (%s)", err, out)
+	}
+
+	for _, secret := range []string{"OPERATOR_TOKENS", "s3cret", "DD_API_KEY", "dd-secret"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("expected %s hidden from the script, got:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"REMEDIATION_ACTION_ID=a1", "REMEDIATION_APPROVED_BY=operator:bob", "REMEDIATION_MONITOR_ID=42", "PATH="} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %s in the script environment, got:\n%s", want, out)
+		}
+	}
+}
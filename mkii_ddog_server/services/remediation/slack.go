@@ -0,0 +1,251 @@
+package remediation
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Slack interactive action IDs on the approval message buttons
+const (
+	slackActionApprove = "remediation_approve"
+	slackActionReject  = "remediation_reject"
+)
+
+// slackMaxSkew is how old a signed Slack request may be before it is refused
+const slackMaxSkew = 5 * time.Minute
+
+// SlackNotifier posts approval requests with Approve/Reject buttons.
+// The webhook must belong to a Slack app with interactivity enabled and its
+// request URL pointed at /v1/remediation/slack/interactions.
+//
+// Environment variables:
+//
+//	SLACK_REMEDIATION_WEBHOOK_URL - Incoming webhook for approvals (falls back to SLACK_WEBHOOK_URL)
+//	SLACK_SIGNING_SECRET          - Verifies button clicks (required to accept them)
+type SlackNotifier struct {
+	webhookURL string
+	client     *http.Client
+}
+
+// NewSlackNotifier creates a notifier from the environment; nil when no webhook is configured
+func NewSlackNotifier() *SlackNotifier {
+	webhookURL := os.Getenv("SLACK_REMEDIATION_WEBHOOK_URL")
+	if webhookURL == "" {
+		webhookURL = os.Getenv("SLACK_WEBHOOK_URL")
+	}
+	if webhookURL == "" {
+		return nil
+	}
+	return NewSlackNotifierWithConfig(webhookURL)
+}
+
+// NewSlackNotifierWithConfig creates a notifier with an explicit webhook URL
+func NewSlackNotifierWithConfig(webhookURL string) *SlackNotifier {
+	return &SlackNotifier{
+		webhookURL: webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify posts the approval request
+func (n *SlackNotifier) Notify(ctx context.Context, action *Action) error {
+	return n.post(ctx, n.webhookURL, buildApprovalMessage(action))
+}
+
+// Respond replaces the original approval message after a decision
+func (n *SlackNotifier) Respond(ctx context.Context, responseURL string, action *Action) error {
+	message := buildDecisionMessage(action)
+	message.ReplaceOriginal = true
+	return n.post(ctx, responseURL, message)
+}
+
+// post sends a message to a Slack webhook or response URL
+func (n *SlackNotifier) post(ctx context.Context, url string, message slackMessage) error {
+	jsonBody, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("failed to marshal message: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return fmt.Errorf("Slack API returned HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// buildApprovalMessage renders the pending action with Approve/Reject buttons
+func buildApprovalMessage(action *Action) slackMessage {
+	mode := "live"
+	if action.DryRun {
+		mode = "dry run"
+	}
+
+	fields := []slackText{
+		{Type: "mrkdwn", Text: "*Proposed by:*\n" + action.ProposedBy},
+		{Type: "mrkdwn", Text: "*Mode:*\n" + mode},
+		{Type: "mrkdwn", Text: "*Expires:*\n" + action.ExpiresAt.UTC().Format(time.RFC1123)},
+	}
+	if action.MonitorName != "" {
+		fields = append(fields, slackText{Type: "mrkdwn", Text: fmt.Sprintf("*Monitor:*\n%s (%d)", action.MonitorName, action.MonitorID)})
+	}
+
+	return slackMessage{
+		Text: "Remediation approval needed: " + action.Summary,
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: "Remediation approval needed"}},
+			{Type: "section", Text: &slackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", action.Summary, action.Reason)}},
+			{Type: "section", Fields: fields},
+			{Type: "actions", BlockID: "remediation:" + action.ID, Elements: []slackButton{
+				{Type: "button", ActionID: slackActionApprove, Value: action.ID, Style: "primary",
+					Text: slackText{Type: "plain_text", Text: "Approve"}},
+				{Type: "button", ActionID: slackActionReject, Value: action.ID, Style: "danger",
+					Text: slackText{Type: "plain_text", Text: "Reject"}},
+			}},
+			{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: "Action " + action.ID}}},
+		},
+	}
+}
+
+// buildDecisionMessage renders the outcome of a decision (no buttons)
+func buildDecisionMessage(action *Action) slackMessage {
+	var status string
+	switch action.Status {
+	case StatusSucceeded:
+		status = ":white_check_mark: Executed"
+		if action.DryRun {
+			status = ":test_tube: Dry run"
+		}
+	case StatusFailed:
+		status = ":x: Failed"
+	case StatusRejected:
+		status = ":no_entry_sign: Rejected"
+	case StatusExpired:
+		status = ":hourglass: Expired"
+	default:
+		status = string(action.Status)
+	}
+
+	text := fmt.Sprintf("%s by %s: *%s*", status, action.DecidedBy, action.Summary)
+	if action.Output != "" {
+		text += "\n```" + truncate(action.Output, 2000) + "```"
+	}
+	if action.Error != "" {
+		text += "\nError: " + truncate(action.Error, 500)
+	}
+
+	return slackMessage{
+		Text:   text,
+		Blocks: []slackBlock{{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}}},
+	}
+}
+
+// VerifySlackSignature checks X-Slack-Signature against the signing secret
+// (HMAC-SHA256 of "v0:timestamp:body") and rejects stale timestamps
+func VerifySlackSignature(secret string, header http.Header, body []byte, now time.Time) error {
+	if secret == "" {
+		return ErrInvalidSignature
+	}
+
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	if skew := now.Sub(time.Unix(ts, 0)); skew > slackMaxSkew || skew < -slackMaxSkew {
+		return ErrInvalidSignature
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	expected := "v0=" + hex.EncodeToString(mac.Sum(nil))
+
+	if !hmac.Equal([]byte(expected), []byte(header.Get("X-Slack-Signature"))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
+
+// slackInteraction is the subset of a block_actions payload we use
+type slackInteraction struct {
+	Type string `json:"type"`
+	User struct {
+		ID       string `json:"id"`
+		Username string `json:"username"`
+		Name     string `json:"name"`
+	} `json:"user"`
+	Actions []struct {
+		ActionID string `json:"action_id"`
+		Value    string `json:"value"`
+	} `json:"actions"`
+	ResponseURL string `json:"response_url"`
+}
+
+// actor names the Slack user in logs
+func (i slackInteraction) actor() string {
+	name := i.User.Username
+	if name == "" {
+		name = i.User.Name
+	}
+	if name == "" {
+		name = i.User.ID
+	}
+	return "slack:" + strings.TrimSpace(name)
+}
+
+// Slack API types
+type slackMessage struct {
+	Text            string       `json:"text,omitempty"`
+	Blocks          []slackBlock `json:"blocks,omitempty"`
+	ReplaceOriginal bool         `json:"replace_original,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string      `json:"type"`
+	BlockID  string      `json:"block_id,omitempty"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements any         `json:"elements,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
+	Text string `json:"text"`
+}
+
+type slackButton struct {
+	Type     string    `json:"type"`
+	ActionID string    `json:"action_id"`
+	Text     slackText `json:"text"`
+	Value    string    `json:"value"`
+	Style    string    `json:"style,omitempty"`
+}
@@ -0,0 +1,170 @@
+package remediation
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+)
+
+func signSlack(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "v0:%s:", timestamp)
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestVerifySlackSignature(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte("payload=%7B%7D")
+	ts := fmt.Sprint(now.Unix())
+
+	header := http.Header{}
+	header.Set("X-Slack-Request-Timestamp", ts)
+	header.Set("X-Slack-Signature", signSlack("secret", ts, body))
+
+	if err := VerifySlackSignature("secret", header, body, now); err != nil {
+		t.Errorf("expected valid signature, got %v", err)
+	}
+	if err := VerifySlackSignature("other", header, body, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected wrong secret to fail, got %v", err)
+	}
+	if err := VerifySlackSignature("secret", header, []byte("payload=tampered"), now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected tampered body to fail, got %v", err)
+	}
+	if err := VerifySlackSignature("secret", header, body, now.Add(10*time.Minute)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected stale timestamp to fail, got %v", err)
+	}
+	if err := VerifySlackSignature("", header, body, now); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected missing secret to fail, got %v", err)
+	}
+}
+
+// slackClick posts a signed button click by a Slack user
+func slackClick(handler *Handler, responseURL, userID, actionID, value, signature string) *httptest.ResponseRecorder {
+	payload := fmt.Sprintf(`{"type":"block_actions","user":{"id":%q,"username":"user-%s"},"response_url":%q,"actions":[{"action_id":%q,"value":%q}]}`,
+		userID, userID, responseURL, actionID, value)
+	body := []byte("payload=" + url.QueryEscape(payload))
+	ts := fmt.Sprint(time.Now().Unix())
+	if signature == "" {
+		signature = signSlack(handler.signingSecret, ts, body)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/remediation/slack/interactions", strings.NewReader(string(body)))
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", signature)
+	rec := httptest.NewRecorder()
+	handler.SlackInteraction(rec, req)
+	return rec
+}
+
+func newSlackTestHandler(t *testing.T, manager *Manager) (*Handler, chan slackMessage) {
+	t.Helper()
+	responses := make(chan slackMessage, 1)
+	slackServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var message slackMessage
+		json.NewDecoder(r.Body).Decode(&message)
+		responses <- message
+	}))
+	t.Cleanup(slackServer.Close)
+
+	handler := NewHandler(manager, NewSlackNotifierWithConfig(slackServer.URL))
+	handler.signingSecret = "secret"
+	operators := operator.NewAuthenticator(map[string]string{"alice": "a-token", "bob": "b-token"})
+	operators.SetSlackUsers(map[string]string{"alice": "U1", "bob": "U2"})
+	handler.SetOperators(operators)
+	return handler, responses
+}
+
+func awaitSlackUpdate(t *testing.T, responses chan slackMessage) slackMessage {
+	t.Helper()
+	select {
+	case message := <-responses:
+		return message
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for Slack response")
+		return slackMessage{}
+	}
+}
+
+func TestHandler_SlackApproveButton(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+	action, err := manager.Propose(context.Background(), restartProposal())
+	if err != nil {
+		t.Fatalf("Propose failed: %v", err)
+	}
+	handler, responses := newSlackTestHandler(t, manager)
+	responseURL := handler.slack.webhookURL
+
+	if rec := slackClick(handler, responseURL, "U1", slackActionApprove, action.ID, "v0=bad"); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unsigned request refused, got %d", rec.Code)
+	}
+	if rec := slackClick(handler, responseURL, "U1", slackActionApprove, action.ID, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+
+	message := awaitSlackUpdate(t, responses)
+	if !message.ReplaceOriginal || !strings.Contains(message.Text, "Executed") || !strings.Contains(message.Text, "operator:alice") {
+		t.Errorf("unexpected Slack update: %+v", message)
+	}
+
+	detail, _ := manager.Get(context.Background(), action.ID)
+	if detail.Action.Status != StatusSucceeded || detail.Action.DecidedBy != "operator:alice" || len(executor.Calls()) != 1 {
+		t.Errorf("expected action approved from Slack as alice, got %+v", detail.Action)
+	}
+}
+
+func TestHandler_SlackFourEyesAcrossChannels(t *testing.T) {
+	manager, executor, _ := newTestManager(Config{DryRun: false})
+	handler, responses := newSlackTestHandler(t, manager)
+	responseURL := handler.slack.webhookURL
+
+	// alice proposes over the API...
+	r := httptest.NewRequest(http.MethodPost, "/v1/remediation/actions", strings.NewReader(
+		`{"type":"restart_deployment","params":{"namespace":"prod","deployment":"checkout"},"reason":"leak"}`))
+	r.Header.Set("Authorization", "Bearer a-token")
+	status, result := handler.ProposeAction(httptest.NewRecorder(), r)
+	action, ok := result.(*Action)
+	if status != http.StatusCreated || !ok {
+		t.Fatalf("expected a proposal, got %d %+v", status, result)
+	}
+
+	// ...so unmapped Slack users are ignored and alice's Slack account can't approve
+	slackClick(handler, responseURL, "U9", slackActionApprove, action.ID, "")
+	slackClick(handler, responseURL, "U1", slackActionApprove, action.ID, "")
+	awaitSlackUpdate(t, responses)
+	if detail, _ := manager.Get(context.Background(), action.ID); detail.Action.Status != StatusPendingApproval || len(executor.Calls()) != 0 {
+		t.Fatalf("expected the action still pending, got %+v", detail.Action)
+	}
+	data, _ := json.Marshal(auditEvents(t, manager, action.ID))
+	if !strings.Contains(string(data), string(AuditApprovalDenied)) || strings.Contains(string(data), "U9") {
+		t.Errorf("expected only alice's self-approval audited as denied, got %s", data)
+	}
+
+	slackClick(handler, responseURL, "U2", slackActionApprove, action.ID, "")
+	awaitSlackUpdate(t, responses)
+	if detail, _ := manager.Get(context.Background(), action.ID); detail.Action.DecidedBy != "operator:bob" || len(executor.Calls()) != 1 {
+		t.Errorf("expected bob's Slack approval to run, got %+v", detail.Action)
+	}
+}
+
+func TestBuildApprovalMessage(t *testing.T) {
+	action := &Action{ID: "a1", Summary: "Rolling restart of deployment prod/api", ProposedBy: "agent", DryRun: true, ExpiresAt: time.Now()}
+	data, _ := json.Marshal(buildApprovalMessage(action))
+	for _, want := range []string{slackActionApprove, slackActionReject, `"value":"a1"`, "dry run"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("approval message missing %q: %s", want, data)
+		}
+	}
+}
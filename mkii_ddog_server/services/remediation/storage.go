@@ -0,0 +1,263 @@
+package remediation
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Storage persists actions in Postgres (`remediation_actions`) with an
+// append-only audit log (`remediation_audit`)
+type Storage struct {
+	db *sql.DB
+}
+
+// NewStorage creates a new remediation storage
+func NewStorage(db *sql.DB) *Storage {
+	return &Storage{db: db}
+}
+
+// InitTables creates the remediation tables
+func (s *Storage) InitTables() error {
+	query := `
+		CREATE TABLE IF NOT EXISTS remediation_actions (
+			id VARCHAR(64) PRIMARY KEY,
+			action_type VARCHAR(50) NOT NULL,
+			params JSONB NOT NULL,
+			reason TEXT,
+			summary TEXT,
+			analysis_id VARCHAR(64),
+			monitor_id BIGINT,
+			monitor_name TEXT,
+			account_name VARCHAR(255),
+			proposed_by VARCHAR(255),
+			status VARCHAR(32) NOT NULL,
+			decided_by VARCHAR(255),
+			decision_at TIMESTAMP,
+			dry_run BOOLEAN NOT NULL DEFAULT FALSE,
+			output TEXT,
+			error TEXT,
+			executed_at TIMESTAMP,
+			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+			expires_at TIMESTAMP NOT NULL
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_remediation_actions_status ON remediation_actions(status);
+		CREATE INDEX IF NOT EXISTS idx_remediation_actions_created_at ON remediation_actions(created_at DESC);
+
+		CREATE TABLE IF NOT EXISTS remediation_audit (
+			id SERIAL PRIMARY KEY,
+			action_id VARCHAR(64) NOT NULL,
+			event VARCHAR(32) NOT NULL,
+			actor VARCHAR(255),
+			detail TEXT,
+			data JSONB,
+			at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+		);
+
+		CREATE INDEX IF NOT EXISTS idx_remediation_audit_action_id ON remediation_audit(action_id);
+	`
+
+	_, err := s.db.Exec(query)
+	return err
+}
+
+const actionColumns = `id, action_type, params, reason, summary, analysis_id, monitor_id, monitor_name,
+	account_name, proposed_by, status, decided_by, decision_at, dry_run, output, error, executed_at,
+	created_at, expires_at`
+
+// CreateAction inserts a new action
+func (s *Storage) CreateAction(ctx context.Context, action *Action) error {
+	params, err := json.Marshal(action.Params)
+	if err != nil {
+		return fmt.Errorf("marshal action params: %w", err)
+	}
+
+	_, err = s.db.ExecContext(ctx, `
+		INSERT INTO remediation_actions
+			(id, action_type, params, reason, summary, analysis_id, monitor_id, monitor_name,
+			 account_name, proposed_by, status, dry_run, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	`, action.ID, action.Type, params, action.Reason, action.Summary, action.AnalysisID, action.MonitorID,
+		action.MonitorName, action.AccountName, action.ProposedBy, action.Status, action.DryRun,
+		action.CreatedAt, action.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("create remediation action: %w", err)
+	}
+	return nil
+}
+
+// GetAction loads an action by ID
+func (s *Storage) GetAction(ctx context.Context, id string) (*Action, error) {
+	row := s.db.QueryRowContext(ctx, `SELECT `+actionColumns+` FROM remediation_actions WHERE id = $1`, id)
+	action, err := scanAction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrActionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get remediation action %s: %w", id, err)
+	}
+	return action, nil
+}
+
+// ListActions returns actions newest first, optionally filtered by status
+func (s *Storage) ListActions(ctx context.Context, status ActionStatus, limit int) ([]Action, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+actionColumns+`
+		FROM remediation_actions
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+		LIMIT $2
+	`, status, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list remediation actions: %w", err)
+	}
+	defer rows.Close()
+
+	var actions []Action
+	for rows.Next() {
+		action, err := scanAction(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan remediation action: %w", err)
+		}
+		actions = append(actions, *action)
+	}
+	return actions, rows.Err()
+}
+
+// Transition moves an action from one status to another if it is still in from
+func (s *Storage) Transition(ctx context.Context, id string, from, to ActionStatus, actor string, at time.Time) (*Action, error) {
+	row := s.db.QueryRowContext(ctx, `
+		UPDATE remediation_actions
+		SET status = $3, decided_by = $4, decision_at = $5
+		WHERE id = $1 AND status = $2
+		RETURNING `+actionColumns, id, from, to, actor, at)
+	action, err := scanAction(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		// Either the action doesn't exist or someone else decided first
+		if _, getErr := s.GetAction(ctx, id); getErr != nil {
+			return nil, getErr
+		}
+		return nil, ErrNotPending
+	}
+	if err != nil {
+		return nil, fmt.Errorf("transition remediation action %s: %w", id, err)
+	}
+	return action, nil
+}
+
+// SaveResult records the execution outcome
+func (s *Storage) SaveResult(ctx context.Context, action *Action) error {
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE remediation_actions
+		SET status = $2, dry_run = $3, output = $4, error = $5, executed_at = $6
+		WHERE id = $1
+	`, action.ID, action.Status, action.DryRun, action.Output, action.Error, action.ExecutedAt)
+	if err != nil {
+		return fmt.Errorf("save remediation result %s: %w", action.ID, err)
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrActionNotFound
+	}
+	return nil
+}
+
+// AppendAudit adds an audit entry
+func (s *Storage) AppendAudit(ctx context.Context, entry AuditEntry) error {
+	var data []byte
+	if len(entry.Data) > 0 {
+		var err error
+		if data, err = json.Marshal(entry.Data); err != nil {
+			return fmt.Errorf("marshal audit data: %w", err)
+		}
+	}
+
+	_, err := s.db.ExecContext(ctx, `
+		INSERT INTO remediation_audit (action_id, event, actor, detail, data, at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, entry.ActionID, entry.Event, entry.Actor, entry.Detail, data, entry.At)
+	if err != nil {
+		return fmt.Errorf("append remediation audit: %w", err)
+	}
+	return nil
+}
+
+// ListAudit returns an action's audit trail, oldest first
+func (s *Storage) ListAudit(ctx context.Context, actionID string) ([]AuditEntry, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, action_id, event, actor, detail, data, at
+		FROM remediation_audit
+		WHERE action_id = $1
+		ORDER BY at, id
+	`, actionID)
+	if err != nil {
+		return nil, fmt.Errorf("list remediation audit: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var actor, detail sql.NullString
+		var data []byte
+		if err := rows.Scan(&entry.ID, &entry.ActionID, &entry.Event, &actor, &detail, &data, &entry.At); err != nil {
+			return nil, fmt.Errorf("scan remediation audit: %w", err)
+		}
+		entry.Actor = actor.String
+		entry.Detail = detail.String
+		if len(data) > 0 {
+			json.Unmarshal(data, &entry.Data)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// rowScanner is satisfied by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanAction reads one row selected with actionColumns
+func scanAction(row rowScanner) (*Action, error) {
+	var action Action
+	var params []byte
+	var reason, summary, analysisID, monitorName, accountName, proposedBy, decidedBy, output, errText sql.NullString
+	var monitorID sql.NullInt64
+	var decisionAt, executedAt sql.NullTime
+
+	err := row.Scan(&action.ID, &action.Type, &params, &reason, &summary, &analysisID, &monitorID, &monitorName,
+		&accountName, &proposedBy, &action.Status, &decidedBy, &decisionAt, &action.DryRun, &output, &errText,
+		&executedAt, &action.CreatedAt, &action.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(params, &action.Params); err != nil {
+		return nil, fmt.Errorf("decode action params: %w", err)
+	}
+	action.Reason = reason.String
+	action.Summary = summary.String
+	action.AnalysisID = analysisID.String
+	action.MonitorID = monitorID.Int64
+	action.MonitorName = monitorName.String
+	action.AccountName = accountName.String
+	action.ProposedBy = proposedBy.String
+	action.DecidedBy = decidedBy.String
+	action.Output = output.String
+	action.Error = errText.String
+	if decisionAt.Valid {
+		action.DecisionAt = &decisionAt.Time
+	}
+	if executedAt.Valid {
+		action.ExecutedAt = &executedAt.Time
+	}
+	return &action, nil
+}
@@ -0,0 +1,140 @@
+package remediation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Store persists actions and their audit trail.
+// Transition is a compare-and-set on the status column: it only succeeds when
+// the action is still in the from state, so two approvers racing (API and
+// Slack, or two Slack clicks) cannot both execute the same action.
+type Store interface {
+	CreateAction(ctx context.Context, action *Action) error
+	GetAction(ctx context.Context, id string) (*Action, error)
+	ListActions(ctx context.Context, status ActionStatus, limit int) ([]Action, error)
+	Transition(ctx context.Context, id string, from, to ActionStatus, actor string, at time.Time) (*Action, error)
+	SaveResult(ctx context.Context, action *Action) error
+	AppendAudit(ctx context.Context, entry AuditEntry) error
+	ListAudit(ctx context.Context, actionID string) ([]AuditEntry, error)
+}
+
+// MemoryStore keeps actions in memory (tests and DB-less runs)
+type MemoryStore struct {
+	actions map[string]*Action
+	audit   []AuditEntry
+	nextID  int64
+	mu      sync.RWMutex
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{actions: make(map[string]*Action)}
+}
+
+// CreateAction stores a copy of action
+func (s *MemoryStore) CreateAction(ctx context.Context, action *Action) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored := *action
+	s.actions[action.ID] = &stored
+	return nil
+}
+
+// GetAction returns a copy of the action
+func (s *MemoryStore) GetAction(ctx context.Context, id string) (*Action, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	action, ok := s.actions[id]
+	if !ok {
+		return nil, ErrActionNotFound
+	}
+	found := *action
+	return &found, nil
+}
+
+// ListActions returns actions newest first, optionally filtered by status
+func (s *MemoryStore) ListActions(ctx context.Context, status ActionStatus, limit int) ([]Action, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	actions := make([]Action, 0, len(s.actions))
+	for _, action := range s.actions {
+		if status == "" || action.Status == status {
+			actions = append(actions, *action)
+		}
+	}
+	sort.Slice(actions, func(i, j int) bool {
+		return actions[i].CreatedAt.After(actions[j].CreatedAt)
+	})
+	if limit > 0 && len(actions) > limit {
+		actions = actions[:limit]
+	}
+	return actions, nil
+}
+
+// Transition moves an action from one status to another if it is still in from
+func (s *MemoryStore) Transition(ctx context.Context, id string, from, to ActionStatus, actor string, at time.Time) (*Action, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	action, ok := s.actions[id]
+	if !ok {
+		return nil, ErrActionNotFound
+	}
+	if action.Status != from {
+		return nil, ErrNotPending
+	}
+	action.Status = to
+	action.DecidedBy = actor
+	action.DecisionAt = &at
+
+	updated := *action
+	return &updated, nil
+}
+
+// SaveResult records the execution outcome
+func (s *MemoryStore) SaveResult(ctx context.Context, action *Action) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.actions[action.ID]
+	if !ok {
+		return ErrActionNotFound
+	}
+	stored.Status = action.Status
+	stored.DryRun = action.DryRun
+	stored.Output = action.Output
+	stored.Error = action.Error
+	stored.ExecutedAt = action.ExecutedAt
+	return nil
+}
+
+// AppendAudit adds an audit entry
+func (s *MemoryStore) AppendAudit(ctx context.Context, entry AuditEntry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	entry.ID = s.nextID
+	s.audit = append(s.audit, entry)
+	return nil
+}
+
+// ListAudit returns an action's audit trail, oldest first
+func (s *MemoryStore) ListAudit(ctx context.Context, actionID string) ([]AuditEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var entries []AuditEntry
+	for _, entry := range s.audit {
+		if entry.ActionID == actionID {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
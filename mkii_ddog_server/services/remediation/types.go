@@ -0,0 +1,140 @@
+package remediation
+
+import (
+	"errors"
+	"time"
+)
+
+// ActionType identifies a remediation action
+type ActionType string
+
+const (
+	ActionCreateDowntime    ActionType = "create_downtime"
+	ActionMuteMonitor       ActionType = "mute_monitor"
+	ActionRestartDeployment ActionType = "restart_deployment"
+	ActionScaleDeployment   ActionType = "scale_deployment"
+	ActionRunScript         ActionType = "run_script"
+)
+
+// ActionStatus tracks an action through approval and execution
+type ActionStatus string
+
+const (
+	StatusPendingApproval ActionStatus = "pending_approval"
+	StatusApproved        ActionStatus = "approved" // claimed for execution
+	StatusRejected        ActionStatus = "rejected"
+	StatusExpired         ActionStatus = "expired"
+	StatusSucceeded       ActionStatus = "succeeded"
+	StatusFailed          ActionStatus = "failed"
+)
+
+// Sentinel errors for remediation operations
+var (
+	ErrActionNotFound   = errors.New("remediation action not found")
+	ErrInvalidAction    = errors.New("invalid remediation action")
+	ErrNotPending       = errors.New("remediation action is not pending approval")
+	ErrActionExpired    = errors.New("remediation action approval window expired")
+	ErrNoExecutor       = errors.New("no executor registered for action type")
+	ErrInvalidSignature = errors.New("invalid slack request signature")
+	ErrSelfApproval     = errors.New("remediation action cannot be approved by its proposer")
+)
+
+// ActionParams holds the typed parameters for every action type; each type
+// uses a subset (see Validate)
+type ActionParams struct {
+	// Datadog actions
+	MonitorID       int64  `json:"monitor_id,omitempty"`
+	Scope           string `json:"scope,omitempty"`
+	DurationMinutes int    `json:"duration_minutes,omitempty"`
+
+	// Kubernetes actions
+	Namespace  string `json:"namespace,omitempty"`
+	Deployment string `json:"deployment,omitempty"`
+	Replicas   *int   `json:"replicas,omitempty"`
+
+	// Registered scripts
+	Script string   `json:"script,omitempty"`
+	Args   []string `json:"args,omitempty"`
+}
+
+// Proposal is an action suggested by an agent (or a person) that needs approval
+type Proposal struct {
+	Type   ActionType   `json:"type"`
+	Params ActionParams `json:"params"`
+	Reason string       `json:"reason"`
+
+	// Set by the caller, not the agent
+	AnalysisID  string `json:"analysis_id,omitempty"`
+	MonitorID   int64  `json:"monitor_id,omitempty"`
+	MonitorName string `json:"monitor_name,omitempty"`
+	AccountName string `json:"account_name,omitempty"`
+	ProposedBy  string `json:"proposed_by,omitempty"`
+
+	// ActionID is filled in once the proposal has been submitted
+	ActionID string `json:"action_id,omitempty"`
+}
+
+// Action is a proposal under approval, with its execution outcome
+type Action struct {
+	ID          string       `json:"id"`
+	Type        ActionType   `json:"type"`
+	Params      ActionParams `json:"params"`
+	Reason      string       `json:"reason"`
+	Summary     string       `json:"summary"` // human-readable description of what will happen
+	AnalysisID  string       `json:"analysis_id,omitempty"`
+	MonitorID   int64        `json:"monitor_id,omitempty"`
+	MonitorName string       `json:"monitor_name,omitempty"`
+	AccountName string       `json:"account_name,omitempty"`
+	ProposedBy  string       `json:"proposed_by"`
+	Status      ActionStatus `json:"status"`
+
+	// Decision and execution
+	DecidedBy  string     `json:"decided_by,omitempty"`
+	DecisionAt *time.Time `json:"decision_at,omitempty"`
+	DryRun     bool       `json:"dry_run"`
+	Output     string     `json:"output,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	ExecutedAt *time.Time `json:"executed_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// AuditEvent names an entry in an action's audit trail
+type AuditEvent string
+
+const (
+	AuditProposed       AuditEvent = "proposed"
+	AuditNotified       AuditEvent = "notified"
+	AuditApproved       AuditEvent = "approved"
+	AuditRejected       AuditEvent = "rejected"
+	AuditExpired        AuditEvent = "expired"
+	AuditExecuted       AuditEvent = "executed"
+	AuditDryRun         AuditEvent = "dry_run"
+	AuditFailed         AuditEvent = "failed"
+	AuditApprovalDenied AuditEvent = "approval_denied" // the proposer tried to approve
+)
+
+// AuditEntry records who did what to an action, and when
+type AuditEntry struct {
+	ID       int64          `json:"id"`
+	ActionID string         `json:"action_id"`
+	Event    AuditEvent     `json:"event"`
+	Actor    string         `json:"actor"`
+	Detail   string         `json:"detail,omitempty"`
+	Data     map[string]any `json:"data,omitempty"`
+	At       time.Time      `json:"at"`
+}
+
+// DecisionRequest is the body of the approve/reject endpoints. The actor is
+// the authenticated operator, never a name in the body.
+type DecisionRequest struct {
+	Reason string `json:"reason,omitempty"`
+	DryRun bool   `json:"dry_run,omitempty"` // approve only: force a dry run
+}
+
+// ActionDetail is an action with its audit trail
+type ActionDetail struct {
+	Action *Action      `json:"action"`
+	Audit  []AuditEntry `json:"audit"`
+}
@@ -0,0 +1,175 @@
+package rum
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+)
+
+// defaultBotRules covers common crawlers, link previewers, uptime monitors,
+// headless browsers, HTTP libraries and the repo's traffic generators
+//
+//go:embed botrules.json
+var defaultBotRules []byte
+
+// BotRule flags a session as a bot. Every condition the rule sets must hold:
+// UserAgent is matched against both the reported and the request user agent,
+// Header must be present (matching Value if set), or absent when Absent is set.
+// Patterns are case-insensitive regular expressions.
+type BotRule struct {
+	Name      string `json:"name"`
+	Category  string `json:"category"` // crawler, preview, monitoring, headless, script, synthetic
+	UserAgent string `json:"user_agent,omitempty"`
+	Header    string `json:"header,omitempty"`
+	Value     string `json:"value,omitempty"`
+	Absent    bool   `json:"absent,omitempty"`
+
+	ua    *regexp.Regexp
+	value *regexp.Regexp
+}
+
+// BotDetector classifies clients with an ordered list of rules; the first
+// match wins. A nil *BotDetector matches nothing.
+type BotDetector struct {
+	rules []BotRule
+}
+
+// DefaultBotDetector returns a detector for the built-in rules
+func DefaultBotDetector() *BotDetector {
+	d, err := ParseBotRules(defaultBotRules)
+	if err != nil {
+		panic("rum: invalid built-in bot rules: " + err.Error())
+	}
+	return d
+}
+
+// LoadBotRules reads a rules file in the format of botrules.json, which it
+// replaces entirely. An empty path gives the built-in rules.
+func LoadBotRules(path string) (*BotDetector, error) {
+	if path == "" {
+		return DefaultBotDetector(), nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseBotRules(data)
+}
+
+// BotRulesFromEnv loads the rules file named by RUM_BOT_RULES, or the
+// built-in rules when it is unset
+func BotRulesFromEnv() (*BotDetector, error) {
+	return LoadBotRules(os.Getenv("RUM_BOT_RULES"))
+}
+
+// ParseBotRules compiles a JSON rules document
+func ParseBotRules(data []byte) (*BotDetector, error) {
+	var doc struct {
+		Rules []BotRule `json:"rules"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("invalid bot rules: %w", err)
+	}
+
+	d := &BotDetector{rules: doc.Rules}
+	for i := range d.rules {
+		rule := &d.rules[i]
+		if rule.Name == "" {
+			return nil, fmt.Errorf("bot rule %d: name is required", i)
+		}
+		if rule.UserAgent == "" && rule.Header == "" {
+			return nil, fmt.Errorf("bot rule %q: user_agent or header is required", rule.Name)
+		}
+		if rule.Value != "" && (rule.Header == "" || rule.Absent) {
+			return nil, fmt.Errorf("bot rule %q: value needs a header that must be present", rule.Name)
+		}
+		if rule.Category == "" {
+			rule.Category = "bot"
+		}
+
+		var err error
+		if rule.UserAgent != "" {
+			if rule.ua, err = regexp.Compile("(?i)" + rule.UserAgent); err != nil {
+				return nil, fmt.Errorf("bot rule %q: %w", rule.Name, err)
+			}
+		}
+		if rule.Value != "" {
+			if rule.value, err = regexp.Compile("(?i)" + rule.Value); err != nil {
+				return nil, fmt.Errorf("bot rule %q: %w", rule.Name, err)
+			}
+		}
+	}
+	return d, nil
+}
+
+// Rules returns the number of loaded rules
+func (d *BotDetector) Rules() int {
+	if d == nil {
+		return 0
+	}
+	return len(d.rules)
+}
+
+// Classify sets BotName and BotCategory on a client from the first matching
+// rule, and DeviceType to "bot"
+func (d *BotDetector) Classify(client *Client, header http.Header) {
+	if d == nil {
+		return
+	}
+	for i := range d.rules {
+		rule := &d.rules[i]
+		if !rule.matches(client.UserAgent, header) {
+			continue
+		}
+		client.BotName, client.BotCategory = rule.Name, rule.Category
+		client.DeviceType = DeviceBot
+		return
+	}
+}
+
+func (rule *BotRule) matches(ua string, header http.Header) bool {
+	if rule.ua != nil && !rule.ua.MatchString(ua) {
+		requestUA := header.Get("User-Agent")
+		if requestUA == "" || requestUA == ua || !rule.ua.MatchString(requestUA) {
+			return false
+		}
+	}
+	if rule.Header == "" {
+		return true
+	}
+	value := header.Get(rule.Header)
+	if rule.Absent {
+		return value == ""
+	}
+	return value != "" && (rule.value == nil || rule.value.MatchString(value))
+}
+
+// humanSessions is the condition dropping bot sessions from a query over
+// rum_sessions, or "" when bots are included; column is the is_bot column
+func humanSessions(column string, includeBots bool) string {
+	if includeBots {
+		return ""
+	}
+	return " AND NOT " + column
+}
+
+// humanEvents is the condition dropping rows that belong to bot sessions
+// from a query over events or vitals, or "" when bots are included
+func humanEvents(sessionColumn string, includeBots bool) string {
+	if includeBots {
+		return ""
+	}
+	return " AND NOT EXISTS (SELECT 1 FROM rum_sessions bs WHERE bs.session_id = " + sessionColumn + " AND bs.is_bot)"
+}
+
+// humanVisitors is the condition dropping visitors without a human session
+// from a query over rum_visitors, or "" when bots are included
+func humanVisitors(includeBots bool) string {
+	if includeBots {
+		return ""
+	}
+	return " AND EXISTS (SELECT 1 FROM rum_sessions hs WHERE hs.visitor_uuid = rum_visitors.uuid AND NOT hs.is_bot)"
+}
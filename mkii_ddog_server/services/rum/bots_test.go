@@ -0,0 +1,125 @@
+package rum
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const chromeUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36"
+
+func browserHeader() http.Header {
+	return http.Header{"Accept-Language": {"en-GB,en;q=0.9"}, "User-Agent": {chromeUA}}
+}
+
+func TestBotDetector_DefaultRules(t *testing.T) {
+	d := DefaultBotDetector()
+
+	cases := []struct {
+		name     string
+		ua       string
+		header   http.Header
+		bot      string
+		category string
+	}{
+		{"browser", chromeUA, browserHeader(), "", ""},
+		{"googlebot", "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)", browserHeader(), "Googlebot", "crawler"},
+		{"headless chrome", strings.Replace(chromeUA, "Chrome/", "HeadlessChrome/", 1), browserHeader(), "HeadlessChrome", "headless"},
+		{"curl", "curl/8.4.0", browserHeader(), "HTTP library", "script"},
+		{"unknown crawler", "ExampleSpider/1.0 (+https://example.com)", browserHeader(), "Generic bot", "crawler"},
+		{"cubot phone", "Mozilla/5.0 (Linux; Android 12; CUBOT X30) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Mobile Safari/537.36", browserHeader(), "", ""},
+		{"empty user agent", "", http.Header{"Accept-Language": {"en"}}, "Empty user agent", "script"},
+		{"bot request, browser-reported user agent", chromeUA, http.Header{"Accept-Language": {"en"}, "User-Agent": {"python-requests/2.31"}}, "HTTP library", "script"},
+		// headless-traffic-generator.js posts a realistic user agent from Node
+		{"synthetic header", chromeUA, http.Header{"X-Rum-Synthetic": {"headless-traffic-generator"}, "User-Agent": {chromeUA}}, "Synthetic traffic", "synthetic"},
+		{"no accept-language", chromeUA, http.Header{"User-Agent": {chromeUA}}, "No Accept-Language", "script"},
+	}
+
+	for _, tc := range cases {
+		client := ParseUserAgent(tc.ua)
+		d.Classify(&client, tc.header)
+		if client.BotName != tc.bot || client.BotCategory != tc.category || client.IsBot() != (tc.bot != "") {
+			t.Errorf("%s: expected %q/%q, got %q/%q", tc.name, tc.bot, tc.category, client.BotName, client.BotCategory)
+		}
+		if client.IsBot() && client.DeviceType != DeviceBot {
+			t.Errorf("%s: expected device type bot, got %q", tc.name, client.DeviceType)
+		}
+	}
+
+	// A nil detector flags nothing
+	client := ParseUserAgent("curl/8.4.0")
+	(*BotDetector)(nil).Classify(&client, nil)
+	if client.IsBot() {
+		t.Fatalf("expected no bot from a nil detector, got %+v", client)
+	}
+}
+
+func TestLoadBotRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.json")
+	os.WriteFile(path, []byte(`{"rules": [
+		{"name": "Load test", "header": "X-Load-Test", "value": "^k6$"},
+		{"name": "Internal", "category": "monitoring", "user_agent": "acme-probe"}
+	]}`), 0o644)
+
+	d, err := LoadBotRules(path)
+	if err != nil || d.Rules() != 2 {
+		t.Fatalf("expected 2 rules, got %d (%v)", d.Rules(), err)
+	}
+	client := ParseUserAgent(chromeUA)
+	d.Classify(&client, http.Header{"X-Load-Test": {"K6"}})
+	if client.BotName != "Load test" || client.BotCategory != "bot" {
+		t.Fatalf("expected the header rule with the default category, got %+v", client)
+	}
+	// A rules file replaces the built-in rules
+	client = ParseUserAgent("curl/8.4.0")
+	d.Classify(&client, http.Header{})
+	if client.IsBot() {
+		t.Fatalf("expected curl to pass custom rules, got %+v", client)
+	}
+
+	if d, err := LoadBotRules(""); err != nil || d.Rules() != DefaultBotDetector().Rules() {
+		t.Fatalf("expected the built-in rules for an empty path, got %v", err)
+	}
+	if _, err := LoadBotRules(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Fatal("expected an error for a missing file")
+	}
+	for _, doc := range []string{
+		`not json`,
+		`{"rules": [{"user_agent": "x"}]}`,
+		`{"rules": [{"name": "no conditions"}]}`,
+		`{"rules": [{"name": "bad regexp", "user_agent": "("}]}`,
+		`{"rules": [{"name": "value without header", "user_agent": "x", "value": "y"}]}`,
+	} {
+		if _, err := ParseBotRules([]byte(doc)); err == nil {
+			t.Errorf("expected an error for %s", doc)
+		}
+	}
+}
+
+func TestHumanConditions(t *testing.T) {
+	if humanSessions("s.is_bot", true) != "" || humanEvents("e.session_id", true) != "" || humanVisitors(true) != "" {
+		t.Fatal("expected no conditions when bots are included")
+	}
+	if got := humanSessions("s.is_bot", false); got != " AND NOT s.is_bot" {
+		t.Fatalf("unexpected session condition %q", got)
+	}
+	if got := humanEvents("e.session_id", false); !strings.Contains(got, "bs.session_id = e.session_id AND bs.is_bot") {
+		t.Fatalf("unexpected event condition %q", got)
+	}
+
+	// Funnels and paths drop bot sessions without renumbering parameters
+	f := checkoutFunnel()
+	f.validate()
+	query, args, _ := funnelQuery(f, SegmentFilter{})
+	if !strings.Contains(query, "AND NOT s.is_bot") || len(args) != 7 {
+		t.Fatalf("expected bot sessions excluded with 7 args, got %d:\n%s", len(args), query)
+	}
+	if query, _, _ := funnelQuery(f, SegmentFilter{IncludeBots: true}); strings.Contains(query, "is_bot") {
+		t.Fatalf("expected bots included:\n%s", query)
+	}
+	if !strings.Contains(pathSteps(SegmentFilter{}), "AND NOT s.is_bot") {
+		t.Fatal("expected paths to exclude bot sessions")
+	}
+}
@@ -0,0 +1,138 @@
+package rum
+
+import (
+	"fmt"
+	"time"
+)
+
+// Cohort limits
+const (
+	maxCohortPeriods = 24
+	maxActiveDays    = 366
+)
+
+// Cohort grains
+const (
+	GrainWeek  = "week"
+	GrainMonth = "month"
+)
+
+// cohortOffset is the SQL for the number of grains between a cohort and an
+// activity period (both truncated to the grain)
+var cohortOffset = map[string]string{
+	GrainWeek:  "((a.period::date - c.cohort::date) / 7)",
+	GrainMonth: "((EXTRACT(YEAR FROM a.period) - EXTRACT(YEAR FROM c.cohort)) * 12 + EXTRACT(MONTH FROM a.period) - EXTRACT(MONTH FROM c.cohort))::int",
+}
+
+// cohortStart truncates t to the start of its week (Monday, as date_trunc)
+// or month in UTC
+func cohortStart(grain string, t time.Time) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	if grain == GrainMonth {
+		return day.AddDate(0, 0, 1-day.Day())
+	}
+	return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+}
+
+// addGrains moves a cohort start by n weeks or months
+func addGrains(grain string, t time.Time, n int) time.Time {
+	if grain == GrainMonth {
+		return t.AddDate(0, n, 0)
+	}
+	return t.AddDate(0, 0, 7*n)
+}
+
+// cohortRange returns the first cohort and the end of the last of the
+// periods cohorts ending with the one containing now
+func cohortRange(grain string, periods int, now time.Time) (from, to time.Time, err error) {
+	if _, ok := cohortOffset[grain]; !ok {
+		return time.Time{}, time.Time{}, fmt.Errorf("grain must be %s or %s", GrainWeek, GrainMonth)
+	}
+	if periods < 1 || periods > maxCohortPeriods {
+		return time.Time{}, time.Time{}, fmt.Errorf("periods must be between 1 and %d", maxCohortPeriods)
+	}
+	current := cohortStart(grain, now)
+	return addGrains(grain, current, 1-periods), addGrains(grain, current, 1), nil
+}
+
+// cohortCell is one (cohort, offset, active visitors) row of the matrix query
+type cohortCell struct {
+	Cohort time.Time
+	Offset int
+	Active int64
+}
+
+// buildCohortReport assembles the retention matrix. Every cohort in range
+// gets a row; offsets that haven't elapsed by now are left out, so later
+// cohorts have shorter rows.
+func buildCohortReport(grain string, from, to, now time.Time, sizes map[time.Time]int64, cells []cohortCell) *CohortReport {
+	periods := 0
+	for c := from; c.Before(to); c = addGrains(grain, c, 1) {
+		periods++
+	}
+	report := &CohortReport{Grain: grain, From: from, To: to, Periods: periods, Cohorts: []CohortRow{}, Average: []float64{}}
+
+	active := make(map[time.Time]map[int]int64)
+	for _, cell := range cells {
+		cohort := cell.Cohort.UTC()
+		if active[cohort] == nil {
+			active[cohort] = make(map[int]int64)
+		}
+		active[cohort][cell.Offset] = cell.Active
+	}
+
+	retained := make([]int64, periods)
+	eligible := make([]int64, periods)
+	for c := from; c.Before(to); c = addGrains(grain, c, 1) {
+		row := CohortRow{Cohort: c, Size: sizes[c], Retained: []int64{}, Retention: []float64{}}
+		for offset := 0; offset < periods && !addGrains(grain, c, offset).After(now); offset++ {
+			n := active[c][offset]
+			row.Retained = append(row.Retained, n)
+			rate := 0.0
+			if row.Size > 0 {
+				rate = roundRate(float64(n) / float64(row.Size))
+			}
+			row.Retention = append(row.Retention, rate)
+			retained[offset] += n
+			eligible[offset] += row.Size
+		}
+		report.Cohorts = append(report.Cohorts, row)
+	}
+
+	for offset := range retained {
+		if eligible[offset] == 0 {
+			break
+		}
+		report.Average = append(report.Average, roundRate(float64(retained[offset])/float64(eligible[offset])))
+	}
+	return report
+}
+
+// buildActiveUsersReport fills in stickiness and the range summary
+func buildActiveUsersReport(from, to time.Time, series []ActiveUsersPoint) *ActiveUsersReport {
+	report := &ActiveUsersReport{From: from.Format("2006-01-02"), To: to.Format("2006-01-02"), Series: series}
+
+	var totalDAU int64
+	for i := range series {
+		p := &series[i]
+		totalDAU += p.DAU
+		if p.MAU > 0 {
+			p.Stickiness = roundRate(float64(p.DAU) / float64(p.MAU))
+		}
+		if p.WAU > 0 {
+			p.WeeklyStickiness = roundRate(float64(p.DAU) / float64(p.WAU))
+		}
+	}
+	if len(series) == 0 {
+		return report
+	}
+
+	last := series[len(series)-1]
+	report.AvgDAU = roundRate(float64(totalDAU) / float64(len(series)))
+	report.WAU, report.MAU = last.WAU, last.MAU
+	if last.MAU > 0 {
+		report.Stickiness = roundRate(report.AvgDAU / float64(last.MAU))
+	}
+	return report
+}
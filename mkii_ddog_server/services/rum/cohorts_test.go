@@ -0,0 +1,147 @@
+package rum
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func day(s string) time.Time {
+	t, _ := time.Parse("2006-01-02", s)
+	return t
+}
+
+func TestCohortStart(t *testing.T) {
+	// 2026-10-14 is a Wednesday
+	wed := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	if got := cohortStart(GrainWeek, wed); !got.Equal(day("2026-10-12")) {
+		t.Fatalf("expected Monday 2026-10-12, got %s", got)
+	}
+	if got := cohortStart(GrainWeek, day("2026-10-18")); !got.Equal(day("2026-10-12")) {
+		t.Fatalf("expected Sunday in the week starting Monday, got %s", got)
+	}
+	if got := cohortStart(GrainMonth, wed); !got.Equal(day("2026-10-01")) {
+		t.Fatalf("expected 2026-10-01, got %s", got)
+	}
+}
+
+func TestCohortRange(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	from, to, err := cohortRange(GrainWeek, 4, now)
+	if err != nil || !from.Equal(day("2026-09-21")) || !to.Equal(day("2026-10-19")) {
+		t.Fatalf("unexpected weekly range %s - %s (%v)", from, to, err)
+	}
+	from, to, _ = cohortRange(GrainMonth, 3, now)
+	if !from.Equal(day("2026-08-01")) || !to.Equal(day("2026-11-01")) {
+		t.Fatalf("unexpected monthly range %s - %s", from, to)
+	}
+	for _, tt := range []struct {
+		grain   string
+		periods int
+	}{{"day", 4}, {GrainWeek, 0}, {GrainMonth, maxCohortPeriods + 1}} {
+		if _, _, err := cohortRange(tt.grain, tt.periods, now); err == nil {
+			t.Errorf("%s/%d: expected an error", tt.grain, tt.periods)
+		}
+	}
+}
+
+func TestBuildCohortReport(t *testing.T) {
+	now := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	from, to, _ := cohortRange(GrainWeek, 3, now)
+	w1, w2, w3 := day("2026-09-28"), day("2026-10-05"), day("2026-10-12")
+
+	report := buildCohortReport(GrainWeek, from, to, now,
+		map[time.Time]int64{w1: 100, w3: 10},
+		[]cohortCell{
+			{w1, 0, 100}, {w1, 1, 40}, {w1, 2, 25},
+			{w3, 0, 10},
+		})
+
+	if report.Periods != 3 || len(report.Cohorts) != 3 {
+		t.Fatalf("expected 3 cohorts, got %+v", report)
+	}
+	first, empty, current := report.Cohorts[0], report.Cohorts[1], report.Cohorts[2]
+	if !slices.Equal(first.Retained, []int64{100, 40, 25}) || !slices.Equal(first.Retention, []float64{1, 0.4, 0.25}) {
+		t.Fatalf("unexpected first cohort %+v", first)
+	}
+	if !empty.Cohort.Equal(w2) || empty.Size != 0 || !slices.Equal(empty.Retention, []float64{0, 0}) {
+		t.Fatalf("expected an empty cohort with zero retention, got %+v", empty)
+	}
+	if len(current.Retained) != 1 {
+		t.Fatalf("expected only elapsed periods for the current cohort, got %+v", current)
+	}
+	// Offset 0 covers 110 visitors, offsets 1 and 2 only the first cohort
+	if !slices.Equal(report.Average, []float64{1, 0.4, 0.25}) {
+		t.Fatalf("unexpected average %v", report.Average)
+	}
+}
+
+func TestBuildActiveUsersReport(t *testing.T) {
+	report := buildActiveUsersReport(day("2026-10-01"), day("2026-10-02"), []ActiveUsersPoint{
+		{Date: "2026-10-01", DAU: 10, WAU: 40, MAU: 100},
+		{Date: "2026-10-02", DAU: 30, WAU: 60, MAU: 120},
+	})
+	if report.Series[0].Stickiness != 0.1 || report.Series[1].WeeklyStickiness != 0.5 {
+		t.Fatalf("unexpected stickiness %+v", report.Series)
+	}
+	if report.AvgDAU != 20 || report.MAU != 120 || report.WAU != 60 || report.Stickiness != 0.1667 {
+		t.Fatalf("unexpected summary %+v", report)
+	}
+
+	if empty := buildActiveUsersReport(day("2026-10-01"), day("2026-10-01"), nil); empty.Stickiness != 0 || empty.From != "2026-10-01" {
+		t.Fatalf("unexpected empty report %+v", empty)
+	}
+}
+
+func TestCohortBucketing_AcrossWeekBoundaries(t *testing.T) {
+	est := time.FixedZone("EST", -5*60*60)
+	tests := []struct {
+		name string
+		at   time.Time
+		want string
+	}{
+		{"last second of a Sunday", time.Date(2026, 12, 27, 23, 59, 59, 0, time.UTC), "2026-12-21"},
+		{"midnight Monday", time.Date(2026, 12, 28, 0, 0, 0, 0, time.UTC), "2026-12-28"},
+		{"New Year's Eve", time.Date(2026, 12, 31, 12, 0, 0, 0, time.UTC), "2026-12-28"},
+		{"Sunday after New Year", time.Date(2027, 1, 3, 18, 0, 0, 0, time.UTC), "2026-12-28"},
+		{"Sunday evening in EST is Monday in UTC", time.Date(2027, 1, 3, 22, 0, 0, 0, est), "2027-01-04"},
+		{"leap day", time.Date(2028, 2, 29, 8, 0, 0, 0, time.UTC), "2028-02-28"},
+	}
+	for _, tt := range tests {
+		if got := cohortStart(GrainWeek, tt.at); !got.Equal(day(tt.want)) {
+			t.Errorf("%s: expected week of %s, got %s", tt.name, tt.want, got.Format("2006-01-02"))
+		}
+	}
+
+	// Three weekly cohorts spanning the new year, a day into the last one
+	now := time.Date(2027, 1, 5, 9, 0, 0, 0, time.UTC)
+	from, to, err := cohortRange(GrainWeek, 3, now)
+	if err != nil || !from.Equal(day("2026-12-21")) || !to.Equal(day("2027-01-11")) {
+		t.Fatalf("unexpected range %s - %s (%v)", from, to, err)
+	}
+	dec21, dec28, jan4 := day("2026-12-21"), day("2026-12-28"), day("2027-01-04")
+	report := buildCohortReport(GrainWeek, from, to, now,
+		map[time.Time]int64{dec21: 50, dec28: 20, jan4: 5},
+		[]cohortCell{
+			{dec21, 0, 50}, {dec21, 1, 20}, {dec21, 2, 10},
+			// Cells scanned in another zone still land in their UTC cohort
+			{dec28.In(est), 0, 20}, {dec28.In(est), 1, 4},
+			{jan4, 0, 5},
+		})
+
+	var starts []string
+	for _, row := range report.Cohorts {
+		starts = append(starts, row.Cohort.Format("2006-01-02"))
+	}
+	if !slices.Equal(starts, []string{"2026-12-21", "2026-12-28", "2027-01-04"}) {
+		t.Fatalf("expected consecutive Monday cohorts across the year, got %v", starts)
+	}
+	for i, want := range [][]int64{{50, 20, 10}, {20, 4}, {5}} {
+		if got := report.Cohorts[i].Retained; !slices.Equal(got, want) {
+			t.Errorf("cohort %s: expected retained %v, got %v", starts[i], want, got)
+		}
+	}
+	if !slices.Equal(report.Average, []float64{1, 0.3429, 0.2}) {
+		t.Fatalf("unexpected average %v", report.Average)
+	}
+}
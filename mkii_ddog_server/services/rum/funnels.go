@@ -0,0 +1,182 @@
+package rum
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Funnel and path limits
+const (
+	minFunnelSteps   = 2
+	maxFunnelSteps   = 10
+	defaultPathDepth = 5
+	maxPathDepth     = 10
+)
+
+// Funnel step types
+const (
+	StepPage   = "page"
+	StepAction = "action"
+)
+
+// validate checks a funnel definition, normalizing step types and labels
+func (f *Funnel) validate() error {
+	f.Name = strings.TrimSpace(f.Name)
+	if f.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(f.Steps) < minFunnelSteps || len(f.Steps) > maxFunnelSteps {
+		return fmt.Errorf("a funnel needs %d to %d steps", minFunnelSteps, maxFunnelSteps)
+	}
+	for i := range f.Steps {
+		step := &f.Steps[i]
+		step.Type = strings.ToLower(strings.TrimSpace(step.Type))
+		step.Match = strings.TrimSpace(step.Match)
+		if step.Type != StepPage && step.Type != StepAction {
+			return fmt.Errorf("step %d: type must be page or action", i+1)
+		}
+		if step.Match == "" {
+			return fmt.Errorf("step %d: match is required", i+1)
+		}
+		if step.Label == "" {
+			step.Label = step.Type + " " + step.Match
+		}
+	}
+	if f.Window != "" {
+		if _, err := f.window(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// window parses the conversion window; zero means the whole session
+func (f *Funnel) window() (time.Duration, error) {
+	if f.Window == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(f.Window)
+	if err != nil || d < time.Second {
+		return 0, fmt.Errorf("window must be a duration of at least 1s, got %q", f.Window)
+	}
+	return d, nil
+}
+
+// likePattern turns a step match into a LIKE pattern: * is the only wildcard
+func likePattern(match string) string {
+	r := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`, `*`, `%`)
+	return r.Replace(match)
+}
+
+// segmentEvents selects the events of sessions in a segment; $1-$4 are
+// from, to, device and browser. Follow it with humanSessions("s.is_bot", ...)
+// to drop bot sessions without renumbering.
+const segmentEvents = `
+	SELECT e.id, e.session_id, e.timestamp, e.event_type, e.page_url, e.action_name
+	FROM rum_events e
+	JOIN rum_sessions s ON s.session_id = e.session_id
+	WHERE e.timestamp >= $1 AND e.timestamp <= $2
+		AND ($3 = '' OR s.device_type = $3)
+		AND ($4 = '' OR s.browser = $4)`
+
+// funnelQuery builds the query evaluating a funnel. Each step CTE holds, per
+// session, the earliest matching event after the previous step (and within
+// the window of the first), which is the best ordered match. The single
+// result row has each step's session count followed by the median ms from
+// the previous step for steps 2..n.
+func funnelQuery(f Funnel, filter SegmentFilter) (string, []any, error) {
+	window, err := f.window()
+	if err != nil {
+		return "", nil, err
+	}
+
+	args := []any{filter.From, filter.To, filter.Device, filter.Browser}
+	arg := func(v any) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	within := ""
+	if window > 0 {
+		within = " AND e.timestamp <= p.started + make_interval(secs => " + arg(window.Seconds()) + "::float8)"
+	}
+
+	var b strings.Builder
+	b.WriteString("WITH ev AS (" + segmentEvents + humanSessions("s.is_bot", filter.IncludeBots) + "\n\t\tAND e.event_type IN ('page_view', 'view', 'action')\n\t)")
+	for i, step := range f.Steps {
+		cond := "e.event_type IN ('page_view', 'view') AND e.page_url LIKE "
+		if step.Type == StepAction {
+			cond = "e.event_type = 'action' AND e.action_name LIKE "
+		}
+		cond += arg(likePattern(step.Match))
+
+		if i == 0 {
+			fmt.Fprintf(&b, `,
+	s1 AS (
+		SELECT e.session_id, MIN(e.timestamp) AS t, MIN(e.timestamp) AS started
+		FROM ev e WHERE %s
+		GROUP BY e.session_id
+	)`, cond)
+			continue
+		}
+
+		fmt.Fprintf(&b, `,
+	s%d AS (
+		SELECT e.session_id, MIN(e.timestamp) AS t, p.started
+		FROM ev e
+		JOIN s%d p ON p.session_id = e.session_id AND e.timestamp > p.t%s
+		WHERE %s
+		GROUP BY e.session_id, p.started
+	)`, i+1, i, within, cond)
+	}
+
+	var cols []string
+	for i := range f.Steps {
+		cols = append(cols, fmt.Sprintf("(SELECT COUNT(*) FROM s%d)", i+1))
+	}
+	for i := 1; i < len(f.Steps); i++ {
+		cols = append(cols, fmt.Sprintf(
+			"(SELECT percentile_cont(0.5) WITHIN GROUP (ORDER BY EXTRACT(EPOCH FROM c.t - p.t) * 1000) FROM s%d c JOIN s%d p ON p.session_id = c.session_id)",
+			i+1, i))
+	}
+	b.WriteString("\n\tSELECT " + strings.Join(cols, ",\n\t\t"))
+
+	return b.String(), args, nil
+}
+
+// funnelReport computes rates from per-step session counts and medians
+func funnelReport(f Funnel, filter SegmentFilter, counts []int64, medians []*float64) *FunnelReport {
+	report := &FunnelReport{Funnel: f, Filter: filter}
+	for i, step := range f.Steps {
+		result := FunnelStepResult{Step: i + 1, Label: step.Label, Sessions: counts[i]}
+		if counts[0] > 0 {
+			result.ConversionRate = roundRate(float64(counts[i]) / float64(counts[0]))
+		}
+		if i == 0 {
+			result.StepConversion = 1
+			if counts[0] == 0 {
+				result.StepConversion = 0
+			}
+		} else {
+			result.DropOff = counts[i-1] - counts[i]
+			if counts[i-1] > 0 {
+				result.StepConversion = roundRate(float64(counts[i]) / float64(counts[i-1]))
+				result.DropOffRate = roundRate(float64(result.DropOff) / float64(counts[i-1]))
+			}
+			result.MedianTimeFromMs = medians[i-1]
+		}
+		report.Steps = append(report.Steps, result)
+	}
+	report.Entered = counts[0]
+	report.Converted = counts[len(counts)-1]
+	report.ConversionRate = report.Steps[len(report.Steps)-1].ConversionRate
+	return report
+}
+
+// roundRate rounds a ratio to four decimal places
+func roundRate(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
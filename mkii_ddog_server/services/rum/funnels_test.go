@@ -0,0 +1,167 @@
+package rum
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func checkoutFunnel() Funnel {
+	return Funnel{
+		Name: "checkout",
+		Steps: []FunnelStep{
+			{Type: "page", Match: "/product/*"},
+			{Type: "ACTION", Match: "add_to_cart", Label: "Add to cart"},
+			{Type: "page", Match: "/checkout"},
+		},
+	}
+}
+
+func TestFunnel_Validate(t *testing.T) {
+	f := checkoutFunnel()
+	if err := f.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if f.Steps[1].Type != StepAction || f.Steps[0].Label != "page /product/*" || f.Steps[1].Label != "Add to cart" {
+		t.Fatalf("expected types and labels normalized, got %+v", f.Steps)
+	}
+
+	tests := map[string]func(f *Funnel){
+		"no name":      func(f *Funnel) { f.Name = " " },
+		"one step":     func(f *Funnel) { f.Steps = f.Steps[:1] },
+		"bad type":     func(f *Funnel) { f.Steps[0].Type = "click" },
+		"empty match":  func(f *Funnel) { f.Steps[2].Match = "" },
+		"bad window":   func(f *Funnel) { f.Window = "soon" },
+		"tiny window":  func(f *Funnel) { f.Window = "10ms" },
+		"eleven steps": func(f *Funnel) { f.Steps = make([]FunnelStep, maxFunnelSteps+1) },
+	}
+	for name, mutate := range tests {
+		f := checkoutFunnel()
+		mutate(&f)
+		if err := f.validate(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := map[string]string{
+		"/checkout":      "/checkout",
+		"/product/*":     "/product/%",
+		"50%_off":        `50\%\_off`,
+		`C:\path*`:       `C:\\path%`,
+		"*/confirmation": "%/confirmation",
+	}
+	for in, want := range tests {
+		if got := likePattern(in); got != want {
+			t.Errorf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+}
+
+func TestFunnelQuery(t *testing.T) {
+	f := checkoutFunnel()
+	f.validate()
+	f.Window = "30m"
+	filter := SegmentFilter{From: time.Unix(0, 0), To: time.Unix(100, 0), Device: "mobile"}
+
+	query, args, err := funnelQuery(f, filter)
+	if err != nil {
+		t.Fatalf("funnelQuery: %v", err)
+	}
+	for _, want := range []string{"s1 AS", "s2 AS", "s3 AS", "JOIN s2 p", "e.action_name LIKE $7", "(SELECT COUNT(*) FROM s3)", "FROM s3 c JOIN s2 p"} {
+		if !strings.Contains(query, want) {
+			t.Errorf("expected %q in query:\n%s", want, query)
+		}
+	}
+	// from, to, device, browser, the window, then one match per step
+	if len(args) != 8 || args[2] != "mobile" || args[4] != float64(1800) || args[5] != "/product/%" || args[6] != `add\_to\_cart` {
+		t.Fatalf("unexpected args %v", args)
+	}
+
+	f.Window = ""
+	if query, args, _ := funnelQuery(f, filter); strings.Contains(query, "make_interval") || len(args) != 7 {
+		t.Fatalf("expected no window condition, got %d args:\n%s", len(args), query)
+	}
+}
+
+func TestFunnelReport(t *testing.T) {
+	f := checkoutFunnel()
+	f.validate()
+	median := 4200.0
+
+	report := funnelReport(f, SegmentFilter{}, []int64{200, 50, 20}, []*float64{&median, nil})
+	if report.Entered != 200 || report.Converted != 20 || report.ConversionRate != 0.1 {
+		t.Fatalf("unexpected totals %+v", report)
+	}
+	second, third := report.Steps[1], report.Steps[2]
+	if second.DropOff != 150 || second.DropOffRate != 0.75 || second.StepConversion != 0.25 || *second.MedianTimeFromMs != 4200 {
+		t.Fatalf("unexpected second step %+v", second)
+	}
+	if third.StepConversion != 0.4 || third.ConversionRate != 0.1 || third.MedianTimeFromMs != nil {
+		t.Fatalf("unexpected third step %+v", third)
+	}
+
+	empty := funnelReport(f, SegmentFilter{}, []int64{0, 0, 0}, []*float64{nil, nil})
+	if empty.ConversionRate != 0 || empty.Steps[0].StepConversion != 0 || empty.Steps[1].DropOffRate != 0 {
+		t.Fatalf("expected zero rates without sessions, got %+v", empty)
+	}
+}
+
+func TestFunnelQuery_StepsMatchInOrder(t *testing.T) {
+	// Steps are matched in the order given: each step only counts events
+	// after the session's match for the previous step, and the window runs
+	// from the first step
+	f := Funnel{Name: "reverse", Window: "1h", Steps: []FunnelStep{
+		{Type: "page", Match: "/checkout"},
+		{Type: "action", Match: "add_to_cart"},
+		{Type: "page", Match: "/product/*"},
+		{Type: "page", Match: "/confirmation"},
+	}}
+	if err := f.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+
+	query, args, err := funnelQuery(f, SegmentFilter{})
+	if err != nil {
+		t.Fatalf("funnelQuery: %v", err)
+	}
+	// args: from, to, device, browser, window, then the steps' matches in order
+	for i, want := range []string{"/checkout", `add\_to\_cart`, "/product/%", "/confirmation"} {
+		if got := args[5+i]; got != want {
+			t.Errorf("step %d: expected match %q at $%d, got %v", i+1, want, 6+i, got)
+		}
+	}
+
+	for i := 2; i <= len(f.Steps); i++ {
+		step := query[strings.Index(query, fmt.Sprintf("s%d AS (", i)):]
+		step = step[:strings.Index(step, "GROUP BY")]
+		join := fmt.Sprintf("JOIN s%d p ON p.session_id = e.session_id AND e.timestamp > p.t AND e.timestamp <= p.started + make_interval(secs => $5::float8)", i-1)
+		if !strings.Contains(step, join) {
+			t.Errorf("step %d must follow step %d within the window:\n%s", i, i-1, step)
+		}
+		if !strings.Contains(step, fmt.Sprintf("LIKE $%d", 5+i)) {
+			t.Errorf("step %d must use its own match:\n%s", i, step)
+		}
+	}
+	if !strings.Contains(query, "MIN(e.timestamp) AS started") {
+		t.Errorf("the first step must start the window:\n%s", query)
+	}
+
+	// Swapping two steps swaps which events each can match
+	f.Steps[0], f.Steps[2] = f.Steps[2], f.Steps[0]
+	_, swapped, _ := funnelQuery(f, SegmentFilter{})
+	if swapped[5] != "/product/%" || swapped[7] != "/checkout" {
+		t.Fatalf("expected step order to follow the definition, got %v", swapped[5:])
+	}
+}
+
+func TestSegmentFilter(t *testing.T) {
+	filter := segmentFilter(httptest.NewRequest(http.MethodGet, "/v1/rum/paths?period=7d&device=mobile&browser=Safari", nil))
+	if filter.Device != "mobile" || filter.Browser != "Safari" || filter.To.Sub(filter.From) != 7*24*time.Hour {
+		t.Fatalf("unexpected filter %+v", filter)
+	}
+}
@@ -0,0 +1,136 @@
+package rum
+
+import (
+	"log"
+	"net/netip"
+	"os"
+	"strings"
+)
+
+// GeoIPConfig holds the paths of local MaxMind-format databases. Lookups
+// never leave the process; download and refresh the files out of band
+// (e.g. geoipupdate) and restart to pick them up.
+type GeoIPConfig struct {
+	// CityDB is a GeoIP2/GeoLite2 City or Country database (or a
+	// compatible one such as DB-IP Lite)
+	// Default: "" (no country, region or city)
+	CityDB string
+
+	// ASNDB is a GeoIP2/GeoLite2 ASN database
+	// Default: "" (no ASN)
+	ASNDB string
+}
+
+// GeoIPConfigFromEnv reads RUM_GEOIP_DB and RUM_GEOIP_ASN_DB
+func GeoIPConfigFromEnv() GeoIPConfig {
+	return GeoIPConfig{
+		CityDB: os.Getenv("RUM_GEOIP_DB"),
+		ASNDB:  os.Getenv("RUM_GEOIP_ASN_DB"),
+	}
+}
+
+// GeoIP resolves client addresses to a Location from local databases. A nil
+// *GeoIP resolves nothing.
+type GeoIP struct {
+	city *mmdbReader
+	asn  *mmdbReader
+}
+
+// NewGeoIP opens the configured databases. It returns nil without an error
+// when none are configured.
+func NewGeoIP(config GeoIPConfig) (*GeoIP, error) {
+	g := &GeoIP{}
+	var err error
+	if config.CityDB != "" {
+		if g.city, err = openMMDB(config.CityDB); err != nil {
+			return nil, err
+		}
+	}
+	if config.ASNDB != "" {
+		if g.asn, err = openMMDB(config.ASNDB); err != nil {
+			return nil, err
+		}
+	}
+	if g.city == nil && g.asn == nil {
+		return nil, nil
+	}
+	return g, nil
+}
+
+// Databases describes the loaded databases, for the startup banner
+func (g *GeoIP) Databases() string {
+	if g == nil {
+		return "disabled"
+	}
+	var names []string
+	for _, db := range []*mmdbReader{g.city, g.asn} {
+		if db != nil {
+			names = append(names, db.databaseType)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// Lookup returns the location of a client IP. Private, reserved and
+// unparseable addresses, and addresses missing from the databases, give an
+// empty Location.
+func (g *GeoIP) Lookup(ip string) Location {
+	var loc Location
+	if g == nil {
+		return loc
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil || addr.IsPrivate() || addr.IsLoopback() || addr.IsUnspecified() || addr.IsLinkLocalUnicast() {
+		return loc
+	}
+
+	if g.city != nil {
+		if record, err := g.city.Lookup(addr); err != nil {
+			log.Printf("[RUM] GeoIP lookup failed: %v", err)
+		} else if m, ok := record.(map[string]any); ok {
+			loc.Country = recordString(m, "country", "iso_code")
+			if loc.Country == "" {
+				loc.Country = recordString(m, "registered_country", "iso_code")
+			}
+			loc.City = recordString(m, "city", "names", "en")
+			if subdivisions, ok := m["subdivisions"].([]any); ok && len(subdivisions) > 0 {
+				if sub, ok := subdivisions[0].(map[string]any); ok {
+					loc.Region = regionName(loc.Country, recordString(sub, "iso_code"), recordString(sub, "names", "en"))
+				}
+			}
+		}
+	}
+
+	if g.asn != nil {
+		if record, err := g.asn.Lookup(addr); err != nil {
+			log.Printf("[RUM] GeoIP ASN lookup failed: %v", err)
+		} else if m, ok := record.(map[string]any); ok {
+			loc.ASN = int64(mmdbUint(m["autonomous_system_number"]))
+			loc.ASNOrg, _ = m["autonomous_system_organization"].(string)
+		}
+	}
+	return loc
+}
+
+// regionName prefers the ISO 3166-2 code ("US-CA") so regions of different
+// countries never collide, falling back to the English name
+func regionName(country, isoCode, name string) string {
+	if isoCode != "" && country != "" {
+		return country + "-" + isoCode
+	}
+	return name
+}
+
+// recordString walks nested maps of a decoded record to a string
+func recordString(m map[string]any, path ...string) string {
+	var v any = m
+	for _, key := range path {
+		next, ok := v.(map[string]any)
+		if !ok {
+			return ""
+		}
+		v = next[key]
+	}
+	s, _ := v.(string)
+	return s
+}
@@ -0,0 +1,318 @@
+package rum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// testMMDB builds a MaxMind DB with 24-bit records mapping networks to
+// records, the way the real writers lay it out
+type testMMDB struct {
+	ipVersion int
+	networks  map[string]map[string]any // prefix -> record
+}
+
+func encodeMMDB(t *testing.T, v any) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	var enc func(v any)
+	header := func(typ, size int) {
+		ctrl := byte(0)
+		ext := -1
+		if typ > 7 {
+			ext = typ - 7
+		} else {
+			ctrl = byte(typ << 5)
+		}
+		switch {
+		case size < 29:
+			b.WriteByte(ctrl | byte(size))
+		default:
+			b.WriteByte(ctrl | 29)
+		}
+		if ext >= 0 {
+			b.WriteByte(byte(ext))
+		}
+		if size >= 29 {
+			b.WriteByte(byte(size - 29))
+		}
+	}
+	uintBytes := func(typ int, n uint64) {
+		var buf []byte
+		for ; n > 0; n >>= 8 {
+			buf = append([]byte{byte(n)}, buf...)
+		}
+		header(typ, len(buf))
+		b.Write(buf)
+	}
+	enc = func(v any) {
+		switch v := v.(type) {
+		case string:
+			header(mmdbString, len(v))
+			b.WriteString(v)
+		case uint16:
+			uintBytes(mmdbUint16, uint64(v))
+		case uint32:
+			uintBytes(mmdbUint32, uint64(v))
+		case uint64:
+			uintBytes(mmdbUint64, v)
+		case []any:
+			header(mmdbArray, len(v))
+			for _, e := range v {
+				enc(e)
+			}
+		case map[string]any:
+			keys := make([]string, 0, len(v))
+			for k := range v {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			header(mmdbMap, len(keys))
+			for _, k := range keys {
+				enc(k)
+				enc(v[k])
+			}
+		default:
+			t.Fatalf("can't encode %T", v)
+		}
+	}
+	enc(v)
+	return b.Bytes()
+}
+
+func (db testMMDB) build(t *testing.T) []byte {
+	t.Helper()
+	const empty = -1
+	type node [2]int // node index, empty, or -(data offset + 2)
+	nodes := []node{{empty, empty}}
+	var data []byte
+
+	prefixes := make([]string, 0, len(db.networks))
+	for p := range db.networks {
+		prefixes = append(prefixes, p)
+	}
+	sort.Strings(prefixes)
+	for _, p := range prefixes {
+		prefix := netip.MustParsePrefix(p)
+		bits := prefix.Bits()
+		raw := prefix.Addr().AsSlice()
+		if prefix.Addr().Is4() && db.ipVersion == 6 {
+			raw = append(make([]byte, 12), raw...)
+			bits += 96
+		}
+
+		offset := len(data)
+		data = append(data, encodeMMDB(t, db.networks[p])...)
+
+		n := 0
+		for i := 0; i < bits; i++ {
+			bit := int(raw[i/8]>>(7-i%8)) & 1
+			if i == bits-1 {
+				nodes[n][bit] = -(offset + 2)
+				break
+			}
+			if nodes[n][bit] == empty {
+				nodes = append(nodes, node{empty, empty})
+				nodes[n][bit] = len(nodes) - 1
+			}
+			n = nodes[n][bit]
+		}
+	}
+
+	var out bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for _, r := range n {
+			v := uint32(count) // empty
+			if r >= 0 {
+				v = uint32(r)
+			} else if r != empty {
+				v = uint32(count + 16 + (-r - 2))
+			}
+			var buf [4]byte
+			binary.BigEndian.PutUint32(buf[:], v)
+			out.Write(buf[1:])
+		}
+	}
+	out.Write(make([]byte, 16))
+	out.Write(data)
+	out.Write(mmdbMetadataMarker)
+	out.Write(encodeMMDB(t, map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(db.ipVersion),
+		"database_type":               "Test-City",
+		"binary_format_major_version": uint16(2),
+		"languages":                   []any{"en"},
+	}))
+	return out.Bytes()
+}
+
+func cityRecord(country, region, regionName, city string) map[string]any {
+	return map[string]any{
+		"country":      map[string]any{"iso_code": country, "names": map[string]any{"en": "Somewhere"}},
+		"city":         map[string]any{"names": map[string]any{"en": city}},
+		"subdivisions": []any{map[string]any{"iso_code": region, "names": map[string]any{"en": regionName}}},
+	}
+}
+
+func TestMMDBReader_Lookup(t *testing.T) {
+	networks := map[string]map[string]any{
+		"81.2.69.0/24":    cityRecord("GB", "ENG", "England", "London"),
+		"2001:db8::/32":   cityRecord("DE", "BE", "Berlin", "Berlin"),
+		"216.160.83.0/26": cityRecord("US", "WA", "Washington", "Milton"),
+	}
+
+	for _, version := range []int{4, 6} {
+		db := testMMDB{ipVersion: version, networks: map[string]map[string]any{}}
+		for prefix, record := range networks {
+			if version == 6 || netip.MustParsePrefix(prefix).Addr().Is4() {
+				db.networks[prefix] = record
+			}
+		}
+		reader, err := newMMDBReader(db.build(t))
+		if err != nil {
+			t.Fatalf("v%d: open: %v", version, err)
+		}
+
+		record, _ := reader.Lookup(netip.MustParseAddr("2001:db8::1"))
+		if berlin := record != nil && recordString(record.(map[string]any), "city", "names", "en") == "Berlin"; berlin != (version == 6) {
+			t.Fatalf("v%d: unexpected IPv6 record %v", version, record)
+		}
+
+		record, err = reader.Lookup(netip.MustParseAddr("81.2.69.160"))
+		m, _ := record.(map[string]any)
+		if err != nil || recordString(m, "city", "names", "en") != "London" {
+			t.Fatalf("v%d: expected London, got %v (%v)", version, record, err)
+		}
+		// IPv4-mapped IPv6 addresses resolve as IPv4
+		if record, _ := reader.Lookup(netip.MustParseAddr("::ffff:216.160.83.5")); recordString(record.(map[string]any), "country", "iso_code") != "US" {
+			t.Fatalf("v%d: expected US for a mapped address, got %v", version, record)
+		}
+		if record, err := reader.Lookup(netip.MustParseAddr("216.160.83.200")); record != nil || err != nil {
+			t.Fatalf("v%d: expected no record outside the /26, got %v (%v)", version, record, err)
+		}
+	}
+}
+
+func TestMMDBDecoder_PointersAndExtendedSizes(t *testing.T) {
+	long := bytes.Repeat([]byte("x"), 300)
+	data := []byte{0x42, 'h', 'i'}                       // "hi" at offset 0
+	data = append(data, 0xE1, 0x41, 'k', 0x20, 0x00)     // {"k": pointer to 0} at offset 3
+	data = append(data, 0x5E, 0x00, byte(len(long)-285)) // 300-byte string at offset 8
+	data = append(data, long...)
+
+	d := &mmdbDecoder{data: data}
+	value, next, err := d.decode(3, 0)
+	if m, ok := value.(map[string]any); err != nil || !ok || m["k"] != "hi" || next != 8 {
+		t.Fatalf("expected {k: hi} ending at 8, got %v %d (%v)", value, next, err)
+	}
+	if value, _, err := d.decode(8, 0); err != nil || value != string(long) {
+		t.Fatalf("expected the long string, got %v (%v)", value, err)
+	}
+	if _, _, err := (&mmdbDecoder{data: []byte{0x20, 0x00}}).decode(0, 0); err == nil {
+		t.Fatal("expected an error for a pointer loop")
+	}
+}
+
+func TestMMDBDecoder_RejectsOversizedContainers(t *testing.T) {
+	tests := map[string][]byte{
+		// Map claiming 65821+16777215 entries in a 4-byte section
+		"map":   {0xFF, 0xFF, 0xFF, 0xFF},
+		"array": {0x1F, 0x04, 0xFF, 0xFF, 0xFF},
+		// Three entries need at least six bytes after the control byte
+		"short map": {0xE3, 0x41, 'k', 0x41, 'v'},
+	}
+	for name, data := range tests {
+		if value, _, err := (&mmdbDecoder{data: data}).decode(0, 0); err == nil {
+			t.Errorf("%s: expected an error, got %v", name, value)
+		}
+	}
+
+	// Sizes that fit still decode, including empty containers at the end
+	data := []byte{0xE1, 0x41, 'k', 0x00, 0x04, 0xE0}
+	value, next, err := (&mmdbDecoder{data: data}).decode(0, 0)
+	if m, ok := value.(map[string]any); err != nil || !ok || len(m["k"].([]any)) != 0 || next != 5 {
+		t.Fatalf("expected {k: []} ending at 5, got %v %d (%v)", value, next, err)
+	}
+	if value, _, err := (&mmdbDecoder{data: data}).decode(5, 0); err != nil || len(value.(map[string]any)) != 0 {
+		t.Fatalf("expected an empty map at the end, got %v (%v)", value, err)
+	}
+}
+
+func TestMMDBReader_RejectsGarbage(t *testing.T) {
+	if _, err := newMMDBReader([]byte("not a database")); !errors.Is(err, ErrInvalidMMDB) {
+		t.Fatalf("expected ErrInvalidMMDB, got %v", err)
+	}
+	truncated := testMMDB{ipVersion: 4, networks: map[string]map[string]any{"10.0.0.0/8": {"a": "b"}}}.build(t)
+	if _, err := newMMDBReader(truncated[:len(truncated)-5]); !errors.Is(err, ErrInvalidMMDB) {
+		t.Fatalf("expected ErrInvalidMMDB for truncated metadata, got %v", err)
+	}
+
+	// Corrupt node counts: a tree ending inside the metadata marker, and one
+	// so large its size overflows
+	for name, tt := range map[string]struct {
+		nodeCount  uint64
+		recordSize uint16
+	}{
+		"tree inside marker": {1, 24},
+		"overflowing tree":   {1 << 62, 32},
+	} {
+		buf := append(make([]byte, 10), mmdbMetadataMarker...)
+		buf = append(buf, encodeMMDB(t, map[string]any{
+			"node_count":  tt.nodeCount,
+			"record_size": tt.recordSize,
+			"ip_version":  uint16(6),
+		})...)
+		buf = append(buf, make([]byte, 256-len(buf))...)
+		if _, err := newMMDBReader(buf); !errors.Is(err, ErrInvalidMMDB) {
+			t.Errorf("%s: expected ErrInvalidMMDB, got %v", name, err)
+		}
+	}
+}
+
+func TestGeoIP_Lookup(t *testing.T) {
+	dir := t.TempDir()
+	city := filepath.Join(dir, "city.mmdb")
+	asn := filepath.Join(dir, "asn.mmdb")
+	os.WriteFile(city, testMMDB{ipVersion: 6, networks: map[string]map[string]any{
+		"81.2.69.0/24": cityRecord("GB", "ENG", "England", "London"),
+		"1.128.0.0/11": {"registered_country": map[string]any{"iso_code": "AU"}},
+	}}.build(t), 0o644)
+	os.WriteFile(asn, testMMDB{ipVersion: 6, networks: map[string]map[string]any{
+		"81.2.69.0/24": {"autonomous_system_number": uint32(20712), "autonomous_system_organization": "Andrews & Arnold Ltd"},
+	}}.build(t), 0o644)
+
+	geo, err := NewGeoIP(GeoIPConfig{CityDB: city, ASNDB: asn})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	loc := geo.Lookup("81.2.69.142")
+	want := Location{Country: "GB", Region: "GB-ENG", City: "London", ASN: 20712, ASNOrg: "Andrews & Arnold Ltd"}
+	if loc != want {
+		t.Fatalf("expected %+v, got %+v", want, loc)
+	}
+	if loc := geo.Lookup("1.130.0.1"); loc.Country != "AU" || loc.Region != "" {
+		t.Fatalf("expected the registered country as a fallback, got %+v", loc)
+	}
+	for _, ip := range []string{"10.1.2.3", "127.0.0.1", "::1", "garbage", "8.8.8.8"} {
+		if loc := geo.Lookup(ip); !loc.IsZero() {
+			t.Errorf("%s: expected no location, got %+v", ip, loc)
+		}
+	}
+
+	// Unconfigured GeoIP is a nil *GeoIP that resolves nothing
+	geo, err = NewGeoIP(GeoIPConfig{})
+	if err != nil || geo != nil || !geo.Lookup("81.2.69.142").IsZero() || geo.Databases() != "disabled" {
+		t.Fatalf("expected a nil GeoIP, got %v (%v)", geo, err)
+	}
+	if _, err := NewGeoIP(GeoIPConfig{CityDB: filepath.Join(dir, "missing.mmdb")}); err == nil {
+		t.Fatal("expected an error for a missing database")
+	}
+}
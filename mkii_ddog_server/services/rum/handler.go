@@ -1,27 +1,93 @@
 package rum
 
 import (
-	"crypto/sha256"
-	"encoding/hex"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
 	"github.com/google/uuid"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 )
 
+// Batch request limits
+const (
+	maxBatchEvents = 500
+	maxBatchBytes  = 1 << 20
+	maxEventAge    = 24 * time.Hour
+	maxEventSkew   = time.Minute
+)
+
+// validEventTypes are the accepted RUM event types
+var validEventTypes = map[string]bool{
+	"page_view": true,
+	"view":      true,
+	"action":    true,
+	"error":     true,
+	"resource":  true,
+	"long_task": true,
+}
+
 // Handler handles RUM HTTP requests
 type Handler struct {
-	storage *Storage
+	storage   *Storage
+	writer    *BatchWriter
+	symbols   *Symbolicator
+	replays   *Recorder
+	geo       *GeoIP
+	bots      *BotDetector
+	privacy   *Privacy
+	operators *operator.Authenticator
 }
 
 // NewHandler creates a new RUM handler
 func NewHandler(storage *Storage) *Handler {
-	return &Handler{storage: storage}
+	return &Handler{
+		storage: storage,
+		symbols: NewSymbolicator(storage),
+		bots:    DefaultBotDetector(),
+		privacy: NewPrivacy(storage, DefaultPrivacyConfig()),
+	}
+}
+
+// SetBatchWriter routes batched events through an async writer; without one
+// TrackBatch writes each batch synchronously
+func (h *Handler) SetBatchWriter(writer *BatchWriter) {
+	h.writer = writer
+}
+
+// SetRecorder enables session replay capture and playback
+func (h *Handler) SetRecorder(recorder *Recorder) {
+	h.replays = recorder
+}
+
+// SetGeoIP enables country, region, city and ASN enrichment of visitors
+func (h *Handler) SetGeoIP(geo *GeoIP) {
+	h.geo = geo
+}
+
+// SetBotDetector replaces the built-in bot rules; nil disables detection
+func (h *Handler) SetBotDetector(bots *BotDetector) {
+	h.bots = bots
+}
+
+// SetPrivacy replaces the default consent, IP pseudonymisation and
+// scrubbing settings
+func (h *Handler) SetPrivacy(privacy *Privacy) {
+	h.privacy = privacy
+}
+
+// SetOperators sets who may export and erase visitors and read the privacy
+// audit; without operators those endpoints refuse every request
+func (h *Handler) SetOperators(operators *operator.Authenticator) {
+	h.operators = operators
 }
 
 // getTraceContext extracts trace_id and span_id from the request context
@@ -60,13 +126,38 @@ func (h *Handler) InitVisitor(w http.ResponseWriter, r *http.Request) (int, any)
 		req.EntryPage = req.PageURL
 	}
 
+	if req.Consent != "" && !validConsent(req.Consent) {
+		return http.StatusBadRequest, map[string]string{"error": "consent must be granted, denied or unknown"}
+	}
+	req.Referrer = h.privacy.Scrubber().URL(req.Referrer)
+	req.EntryPage = h.privacy.Scrubber().URL(req.EntryPage)
+
 	// Get APM trace context for RUM-APM correlation
 	traceID, spanID := getTraceContext(r)
 
+	// Parse the client and flag crawlers, headless browsers and scripts
+	client := ParseUserAgent(req.UserAgent)
+	h.bots.Classify(&client, r.Header)
+
+	// Locate the client from local GeoIP databases while the raw IP is at hand
+	clientIP := getClientIP(r)
+	loc := h.geo.Lookup(clientIP)
+
 	// Check for existing visitor
 	if req.ExistingUUID != "" {
 		visitor, err := h.storage.GetVisitorByUUID(req.ExistingUUID)
 		if err == nil && visitor != nil {
+			consent := visitor.Consent
+			if req.Consent != "" && req.Consent != consent {
+				if _, err := h.privacy.SetConsent(req.ExistingUUID, req.Consent, "visitor"); err != nil {
+					return http.StatusInternalServerError, map[string]string{"error": "failed to record consent"}
+				}
+				consent = req.Consent
+			}
+			if !h.privacy.Tracks(consent) {
+				return http.StatusOK, untrackedVisitor(req.ExistingUUID, false, consent, traceID, spanID)
+			}
+
 			// Existing visitor - create new session
 			sessionID := uuid.New().String()
 
@@ -74,7 +165,13 @@ func (h *Handler) InitVisitor(w http.ResponseWriter, r *http.Request) (int, any)
 				return http.StatusInternalServerError, map[string]string{"error": "failed to update visitor"}
 			}
 
-			if err := h.storage.CreateSession(req.ExistingUUID, sessionID, req.Referrer, req.EntryPage, req.UserAgent); err != nil {
+			if !loc.IsZero() {
+				if err := h.storage.SetVisitorLocation(req.ExistingUUID, loc); err != nil {
+					log.Printf("[RUM] Failed to store visitor location: %v", err)
+				}
+			}
+
+			if err := h.storage.CreateClientSession(req.ExistingUUID, sessionID, req.Referrer, req.EntryPage, client); err != nil {
 				return http.StatusInternalServerError, map[string]string{"error": "failed to create session"}
 			}
 
@@ -83,6 +180,7 @@ func (h *Handler) InitVisitor(w http.ResponseWriter, r *http.Request) (int, any)
 				SessionID:   sessionID,
 				IsNew:       false,
 				Message:     "Welcome back!",
+				Consent:     consent,
 				TraceID:     traceID,
 				SpanID:      spanID,
 			}
@@ -92,15 +190,46 @@ func (h *Handler) InitVisitor(w http.ResponseWriter, r *http.Request) (int, any)
 	// Create new visitor
 	visitorUUID := uuid.New().String()
 	sessionID := uuid.New().String()
+	consent := req.Consent
+	if consent == "" {
+		consent = ConsentUnknown
+	}
 
-	// Hash IP for privacy
-	ipHash := hashIP(getClientIP(r))
+	// Without consent only the visitor and its consent state are stored,
+	// so the choice sticks across page loads
+	if !h.privacy.Tracks(consent) {
+		if err := h.storage.CreateVisitor(visitorUUID, "", ""); err != nil {
+			return http.StatusInternalServerError, map[string]string{"error": "failed to create visitor"}
+		}
+		if consent != ConsentUnknown {
+			if _, err := h.privacy.SetConsent(visitorUUID, consent, "visitor"); err != nil {
+				return http.StatusInternalServerError, map[string]string{"error": "failed to record consent"}
+			}
+		}
+		return http.StatusCreated, untrackedVisitor(visitorUUID, true, consent, traceID, spanID)
+	}
+
+	// Pseudonymize the IP with the current rotating salt
+	ipHash, err := h.privacy.Pseudonymize(clientIP)
+	if err != nil {
+		log.Printf("[RUM] Failed to pseudonymize client IP: %v", err)
+	}
 
 	if err := h.storage.CreateVisitor(visitorUUID, req.UserAgent, ipHash); err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": "failed to create visitor"}
 	}
+	if consent != ConsentUnknown {
+		if _, err := h.privacy.SetConsent(visitorUUID, consent, "visitor"); err != nil {
+			return http.StatusInternalServerError, map[string]string{"error": "failed to record consent"}
+		}
+	}
+	if !loc.IsZero() {
+		if err := h.storage.SetVisitorLocation(visitorUUID, loc); err != nil {
+			log.Printf("[RUM] Failed to store visitor location: %v", err)
+		}
+	}
 
-	if err := h.storage.CreateSession(visitorUUID, sessionID, req.Referrer, req.EntryPage, req.UserAgent); err != nil {
+	if err := h.storage.CreateClientSession(visitorUUID, sessionID, req.Referrer, req.EntryPage, client); err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": "failed to create session"}
 	}
 
@@ -109,6 +238,20 @@ func (h *Handler) InitVisitor(w http.ResponseWriter, r *http.Request) (int, any)
 		SessionID:   sessionID,
 		IsNew:       true,
 		Message:     "Welcome, new visitor!",
+		Consent:     consent,
+		TraceID:     traceID,
+		SpanID:      spanID,
+	}
+}
+
+// untrackedVisitor answers an init from a visitor whose data isn't recorded;
+// no session is started
+func untrackedVisitor(visitorUUID string, isNew bool, consent, traceID, spanID string) VisitorInitResponse {
+	return VisitorInitResponse{
+		VisitorUUID: visitorUUID,
+		IsNew:       isNew,
+		Message:     "Tracking disabled until consent is granted; init again afterwards",
+		Consent:     consent,
 		TraceID:     traceID,
 		SpanID:      spanID,
 	}
@@ -121,68 +264,507 @@ func (h *Handler) TrackEvent(w http.ResponseWriter, r *http.Request) (int, any)
 		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
 	}
 
-	if req.VisitorUUID == "" || req.SessionID == "" || req.EventType == "" {
-		return http.StatusBadRequest, map[string]string{"error": "visitor_uuid, session_id, and event_type are required"}
+	// Get APM trace context for RUM-APM correlation
+	traceID, spanID := getTraceContext(r)
+
+	event, err := newEvent(req, traceID, spanID, time.Now())
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	if !h.privacy.Allowed(event.VisitorUUID) {
+		return http.StatusAccepted, TrackEventResponse{Status: "event dropped without consent", TraceID: traceID, SpanID: spanID}
 	}
+	h.privacy.ScrubEvent(&event)
 
-	// Validate event type
-	validTypes := map[string]bool{
-		"page_view": true,
-		"view":      true,
-		"action":    true,
-		"error":     true,
-		"resource":  true,
-		"long_task": true,
+	if err := h.storage.StoreEvent(event); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": "failed to store event"}
 	}
-	if !validTypes[req.EventType] {
-		return http.StatusBadRequest, map[string]string{"error": "invalid event_type"}
+
+	return http.StatusAccepted, TrackEventResponse{
+		Status:  "event recorded",
+		TraceID: traceID,
+		SpanID:  spanID,
+	}
+}
+
+// TrackBatch records a batch of RUM events (POST /v1/rum/batch). The body is
+// either a BatchEventRequest or a bare array of events; navigator.sendBeacon
+// bodies (text/plain) are accepted as JSON. Invalid events are rejected
+// individually; events from visitors without consent are dropped. Returns
+// 503 with Retry-After when the write queue is full.
+func (h *Handler) TrackBatch(w http.ResponseWriter, r *http.Request) (int, any) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBatchBytes))
+	if err != nil {
+		return http.StatusRequestEntityTooLarge, map[string]string{"error": "batch too large"}
+	}
+
+	var batch BatchEventRequest
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '[' {
+		err = json.Unmarshal(trimmed, &batch.Events)
+	} else {
+		err = json.Unmarshal(trimmed, &batch)
+	}
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
+	}
+	if len(batch.Events) == 0 {
+		return http.StatusBadRequest, map[string]string{"error": "events are required"}
+	}
+	if len(batch.Events) > maxBatchEvents {
+		return http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("at most %d events per batch", maxBatchEvents)}
 	}
 
-	// Get APM trace context for RUM-APM correlation
 	traceID, spanID := getTraceContext(r)
+	now := time.Now()
 
-	// Include trace context in metadata if provided in request or from APM
-	if req.Metadata == nil {
-		req.Metadata = make(map[string]interface{})
+	resp := BatchEventResponse{TraceID: traceID, SpanID: spanID}
+	events := make([]RUMEvent, 0, len(batch.Events))
+	for i, req := range batch.Events {
+		if req.VisitorUUID == "" {
+			req.VisitorUUID = batch.VisitorUUID
+		}
+		if req.SessionID == "" {
+			req.SessionID = batch.SessionID
+		}
+		event, err := newEvent(req, traceID, spanID, now)
+		if err != nil {
+			resp.Rejected = append(resp.Rejected, BatchError{Index: i, Error: err.Error()})
+			continue
+		}
+		if !h.privacy.Allowed(event.VisitorUUID) {
+			resp.Dropped++
+			continue
+		}
+		h.privacy.ScrubEvent(&event)
+		events = append(events, event)
 	}
-	if req.TraceID != "" {
-		req.Metadata["frontend_trace_id"] = req.TraceID
+	resp.Accepted = len(events)
+
+	if len(events) > 0 {
+		if h.writer != nil {
+			if err := h.writer.Enqueue(events); err != nil {
+				w.Header().Set("Retry-After", "1")
+				return http.StatusServiceUnavailable, map[string]string{"error": err.Error()}
+			}
+		} else if err := h.storage.StoreEvents(events); err != nil {
+			return http.StatusInternalServerError, map[string]string{"error": "failed to store events"}
+		}
 	}
-	if req.SpanID != "" {
-		req.Metadata["frontend_span_id"] = req.SpanID
+
+	resp.Status = "events queued"
+	if h.writer == nil {
+		resp.Status = "events recorded"
 	}
-	if traceID != "" {
-		req.Metadata["backend_trace_id"] = traceID
+	return http.StatusAccepted, resp
+}
+
+// GetBatchStats returns batch writer metrics (GET /v1/rum/batch/stats)
+func (h *Handler) GetBatchStats(w http.ResponseWriter, r *http.Request) (int, any) {
+	if h.writer == nil {
+		return http.StatusOK, map[string]any{"enabled": false}
 	}
-	if spanID != "" {
-		req.Metadata["backend_span_id"] = spanID
+	return http.StatusOK, map[string]any{"enabled": true, "stats": h.writer.Stats()}
+}
+
+// TrackVitals records a page's Core Web Vitals, resource timings and long
+// tasks (POST /v1/rum/vitals). Accepts sendBeacon text/plain bodies.
+func (h *Handler) TrackVitals(w http.ResponseWriter, r *http.Request) (int, any) {
+	var req VitalsRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
 	}
 
-	event := RUMEvent{
-		VisitorUUID: req.VisitorUUID,
-		SessionID:   req.SessionID,
-		EventType:   req.EventType,
-		Timestamp:   time.Now(),
-		PageURL:     req.PageURL,
-		PageTitle:   req.PageTitle,
-		ActionName:  req.ActionName,
-		ActionType:  req.ActionType,
-		ErrorMsg:    req.ErrorMsg,
-		Duration:    req.Duration,
-		Metadata:    req.Metadata,
+	h.privacy.ScrubVitals(&req)
+	rows, err := req.rows()
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	if !h.privacy.Allowed(req.VisitorUUID) {
+		return http.StatusAccepted, map[string]any{"status": "vitals dropped without consent", "measurements": 0}
+	}
+	now := time.Now()
+	if req.Timestamp.Before(now.Add(-maxEventAge)) || req.Timestamp.After(now.Add(maxEventSkew)) {
+		req.Timestamp = now
 	}
 
-	if err := h.storage.StoreEvent(event); err != nil {
-		return http.StatusInternalServerError, map[string]string{"error": "failed to store event"}
+	if err := h.storage.StoreVitals(req, rows); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": "failed to store vitals"}
 	}
 
-	return http.StatusAccepted, TrackEventResponse{
-		Status:  "event recorded",
-		TraceID: traceID,
-		SpanID:  spanID,
+	return http.StatusAccepted, map[string]any{"status": "vitals recorded", "measurements": len(rows)}
+}
+
+// GetVitals returns p50/p75/p95 of performance metrics with a time-bucketed
+// trend (GET /v1/rum/analytics/vitals?period=7d&by=page&metric=lcp,inp&bucket=1h).
+// by is page, device, browser or resource_type; bucket defaults to 1h for
+// ranges up to two days and 24h beyond.
+func (h *Handler) GetVitals(w http.ResponseWriter, r *http.Request) (int, any) {
+	from, to := parseTimeRange(r)
+	query := r.URL.Query()
+
+	metrics, err := parseMetrics(query.Get("metric"))
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	groupBy := query.Get("by")
+	if _, ok := vitalDimensions[groupBy]; groupBy != "" && !ok {
+		return http.StatusBadRequest, map[string]string{"error": "by must be page, device, browser or resource_type"}
+	}
+	bucket, err := vitalBucket(query.Get("bucket"), to.Sub(from))
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+
+	includeBots := parseIncludeBots(r)
+	summaries, err := h.storage.GetVitalSummaries(from, to, metrics, groupBy, includeBots)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	trend, err := h.storage.GetVitalTrend(from, to, metrics, bucket, includeBots)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+
+	return http.StatusOK, VitalsReport{
+		From:    from,
+		To:      to,
+		GroupBy: groupBy,
+		Bucket:  bucket.String(),
+		Metrics: summaries,
+		Trend:   trend,
+	}
+}
+
+// TrackError records a JavaScript error, grouping it into an issue by
+// fingerprint (POST /v1/rum/errors). Stacks are symbolicated with the source
+// maps uploaded for the report's release before fingerprinting, so minified
+// and original frames group together.
+func (h *Handler) TrackError(w http.ResponseWriter, r *http.Request) (int, any) {
+	var req ErrorReport
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBatchBytes)).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
+	}
+
+	h.privacy.ScrubError(&req)
+	occ, issue, err := h.newOccurrence(req, time.Now())
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	if !h.privacy.Allowed(req.VisitorUUID) {
+		return http.StatusAccepted, map[string]any{"status": "error dropped without consent"}
+	}
+
+	stored, err := h.storage.RecordError(occ, issue)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": "failed to record error"}
+	}
+
+	return http.StatusAccepted, map[string]any{
+		"status":      "error recorded",
+		"fingerprint": stored.Fingerprint,
+		"count":       stored.Count,
 	}
 }
 
+// ListErrors returns error issues seen in a time range
+// (GET /v1/rum/errors?period=7d&release=&sort=count&limit=50).
+// sort is count, sessions or last_seen (default).
+func (h *Handler) ListErrors(w http.ResponseWriter, r *http.Request) (int, any) {
+	from, to := parseTimeRange(r)
+	query := r.URL.Query()
+
+	sortBy := query.Get("sort")
+	switch sortBy {
+	case "", "last_seen", "count", "sessions":
+	default:
+		return http.StatusBadRequest, map[string]string{"error": "sort must be last_seen, count or sessions"}
+	}
+	limit := 50
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 200 {
+			limit = parsed
+		}
+	}
+
+	issues, err := h.storage.ListErrorIssues(from, to, query.Get("release"), sortBy, limit)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+
+	return http.StatusOK, map[string]any{
+		"issues": issues,
+		"count":  len(issues),
+		"from":   from.Format(time.RFC3339),
+		"to":     to.Format(time.RFC3339),
+	}
+}
+
+// GetError returns an issue with its most recent occurrences
+// (GET /v1/rum/errors/{fingerprint}?limit=20)
+func (h *Handler) GetError(w http.ResponseWriter, r *http.Request, fingerprint string) (int, any) {
+	issue, err := h.storage.GetErrorIssue(fingerprint)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if issue == nil {
+		return http.StatusNotFound, map[string]string{"error": "issue not found"}
+	}
+
+	limit := 20
+	if l := r.URL.Query().Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+	occurrences, err := h.storage.GetErrorOccurrences(fingerprint, limit)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+
+	return http.StatusOK, map[string]any{
+		"issue":       issue,
+		"occurrences": occurrences,
+	}
+}
+
+// UploadSourceMap stores the source map for one script of a release
+// (POST /v1/rum/sourcemaps?release=v1.4.2&file=/static/js/app.3f2a.js, body is
+// the .map file). file is the script's path as it appears in stack traces;
+// scheme, host and query are ignored.
+func (h *Handler) UploadSourceMap(w http.ResponseWriter, r *http.Request) (int, any) {
+	release := r.URL.Query().Get("release")
+	file := r.URL.Query().Get("file")
+	if release == "" || file == "" {
+		return http.StatusBadRequest, map[string]string{"error": "release and file are required"}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSourceMapBytes))
+	if err != nil {
+		return http.StatusRequestEntityTooLarge, map[string]string{"error": fmt.Sprintf("source map larger than %d bytes", maxSourceMapBytes)}
+	}
+	sm, err := parseSourceMap(data)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+
+	file = sourceMapFile(file)
+	if err := h.storage.StoreSourceMap(release, file, data); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": "failed to store source map"}
+	}
+	h.symbols.Invalidate(release, file)
+
+	return http.StatusCreated, map[string]any{
+		"status":  "source map uploaded",
+		"release": release,
+		"file":    file,
+		"sources": len(sm.sources),
+	}
+}
+
+// CreateFunnel saves a funnel definition (POST /v1/rum/funnels)
+func (h *Handler) CreateFunnel(w http.ResponseWriter, r *http.Request) (int, any) {
+	var f Funnel
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
+	}
+	if err := f.validate(); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+
+	if err := h.storage.CreateFunnel(&f); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": "failed to save funnel"}
+	}
+	return http.StatusCreated, f
+}
+
+// ListFunnels returns saved funnel definitions (GET /v1/rum/funnels)
+func (h *Handler) ListFunnels(w http.ResponseWriter, r *http.Request) (int, any) {
+	funnels, err := h.storage.ListFunnels()
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, map[string]any{"funnels": funnels, "count": len(funnels)}
+}
+
+// GetFunnel evaluates a saved funnel
+// (GET /v1/rum/funnels/{id}?period=7d&device=mobile&browser=Chrome)
+func (h *Handler) GetFunnel(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid funnel id"}
+	}
+	f, err := h.storage.GetFunnel(id)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if f == nil {
+		return http.StatusNotFound, map[string]string{"error": "funnel not found"}
+	}
+
+	report, err := h.storage.EvaluateFunnel(*f, segmentFilter(r))
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, report
+}
+
+// EvaluateFunnel evaluates an unsaved funnel definition
+// (POST /v1/rum/funnels/evaluate, same filters as GetFunnel)
+func (h *Handler) EvaluateFunnel(w http.ResponseWriter, r *http.Request) (int, any) {
+	var f Funnel
+	if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
+	}
+	if f.Name == "" {
+		f.Name = "ad hoc"
+	}
+	if err := f.validate(); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+
+	report, err := h.storage.EvaluateFunnel(f, segmentFilter(r))
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, report
+}
+
+// DeleteFunnel removes a saved funnel (DELETE /v1/rum/funnels/{id})
+func (h *Handler) DeleteFunnel(w http.ResponseWriter, r *http.Request, idStr string) (int, any) {
+	id, err := strconv.ParseInt(idStr, 10, 64)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid funnel id"}
+	}
+	deleted, err := h.storage.DeleteFunnel(id)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if !deleted {
+		return http.StatusNotFound, map[string]string{"error": "funnel not found"}
+	}
+	return http.StatusOK, map[string]string{"status": "funnel deleted"}
+}
+
+// GetPaths returns page-path analysis
+// (GET /v1/rum/paths?page=/pricing&depth=5&limit=20&period=7d&device=&browser=).
+// With page set, the report includes the pages before and after it.
+func (h *Handler) GetPaths(w http.ResponseWriter, r *http.Request) (int, any) {
+	query := r.URL.Query()
+
+	depth := defaultPathDepth
+	if d := query.Get("depth"); d != "" {
+		parsed, err := strconv.Atoi(d)
+		if err != nil || parsed < 1 || parsed > maxPathDepth {
+			return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("depth must be between 1 and %d", maxPathDepth)}
+		}
+		depth = parsed
+	}
+	limit := 20
+	if l := query.Get("limit"); l != "" {
+		if parsed, err := strconv.Atoi(l); err == nil && parsed > 0 && parsed <= 100 {
+			limit = parsed
+		}
+	}
+
+	report, err := h.storage.GetPaths(segmentFilter(r), query.Get("page"), depth, limit)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, report
+}
+
+// GetCohorts returns a retention matrix of visitors grouped by the week or
+// month they were first seen (GET /v1/rum/analytics/cohorts?grain=week&periods=8).
+// periods defaults to 8 weeks or 6 months, ending with the current one.
+func (h *Handler) GetCohorts(w http.ResponseWriter, r *http.Request) (int, any) {
+	query := r.URL.Query()
+	grain := query.Get("grain")
+	if grain == "" {
+		grain = GrainWeek
+	}
+	periods := 8
+	if grain == GrainMonth {
+		periods = 6
+	}
+	if p := query.Get("periods"); p != "" {
+		parsed, err := strconv.Atoi(p)
+		if err != nil {
+			return http.StatusBadRequest, map[string]string{"error": "periods must be a number"}
+		}
+		periods = parsed
+	}
+
+	now := time.Now()
+	from, to, err := cohortRange(grain, periods, now)
+	if err != nil {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+
+	report, err := h.storage.GetCohorts(grain, from, to, now)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, report
+}
+
+// GetActiveUsers returns daily DAU, WAU, MAU and stickiness
+// (GET /v1/rum/analytics/active?period=30d, or from/to; default 30 days, max 366)
+func (h *Handler) GetActiveUsers(w http.ResponseWriter, r *http.Request) (int, any) {
+	from, to := parseTimeRange(r)
+	query := r.URL.Query()
+	if query.Get("from") == "" && query.Get("period") == "" {
+		from = to.AddDate(0, 0, -29)
+	}
+	if to.Before(from) || to.Sub(from) > maxActiveDays*24*time.Hour {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("range must be between 1 and %d days", maxActiveDays)}
+	}
+
+	report, err := h.storage.GetActiveUsers(from, to)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, report
+}
+
+// GetRollups returns hourly or daily views, sessions, errors and vitals
+// from the rollup tables. grain defaults to hour for ranges up to two days.
+func (h *Handler) GetRollups(w http.ResponseWriter, r *http.Request) (int, any) {
+	from, to := parseTimeRange(r)
+	query := r.URL.Query()
+	if to.Before(from) {
+		return http.StatusBadRequest, map[string]string{"error": "to must be after from"}
+	}
+
+	grain := query.Get("grain")
+	if grain == "" {
+		grain = GrainHour
+		if to.Sub(from) > 48*time.Hour {
+			grain = GrainDay
+		}
+	}
+	limit := maxRollupSeriesHours
+	switch grain {
+	case GrainHour:
+	case GrainDay:
+		limit = maxRollupSeriesDays
+	default:
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("grain must be %s or %s", GrainHour, GrainDay)}
+	}
+
+	from, to = truncateGrain(grain, from), addGrain(grain, truncateGrain(grain, to), 1)
+	if addGrain(grain, from, limit).Before(to) {
+		return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("at most %d %s buckets per request", limit, grain)}
+	}
+
+	filter := RollupFilter{Page: query.Get("page"), Device: query.Get("device"), Country: query.Get("country")}
+	series, err := h.storage.GetRollupSeries(grain, from, to, filter)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, series
+}
+
 // EndSession ends a visitor session
 func (h *Handler) EndSession(w http.ResponseWriter, r *http.Request) (int, any) {
 	var req SessionEndRequest
@@ -194,7 +776,7 @@ func (h *Handler) EndSession(w http.ResponseWriter, r *http.Request) (int, any)
 		return http.StatusBadRequest, map[string]string{"error": "session_id is required"}
 	}
 
-	if err := h.storage.EndSession(req.SessionID, req.ExitPage); err != nil {
+	if err := h.storage.EndSession(req.SessionID, h.privacy.Scrubber().URL(req.ExitPage)); err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": "failed to end session"}
 	}
 
@@ -214,6 +796,147 @@ func (h *Handler) GetVisitor(w http.ResponseWriter, r *http.Request, visitorUUID
 	return http.StatusOK, visitor
 }
 
+// UpdateConsent records a visitor's consent state
+// (POST /v1/rum/visitor/{uuid}/consent). Denying consent stops ingestion
+// but keeps what was recorded before; erase it with DeleteVisitor.
+func (h *Handler) UpdateConsent(w http.ResponseWriter, r *http.Request, visitorUUID string) (int, any) {
+	var req ConsentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
+	}
+	if !validConsent(req.State) {
+		return http.StatusBadRequest, map[string]string{"error": "state must be granted, denied or unknown"}
+	}
+	if req.Actor == "" {
+		req.Actor = "visitor"
+	}
+
+	found, err := h.privacy.SetConsent(visitorUUID, req.State, req.Actor)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if !found {
+		return http.StatusNotFound, map[string]string{"error": "visitor not found"}
+	}
+	return http.StatusOK, map[string]string{"visitor_uuid": visitorUUID, "consent": req.State}
+}
+
+// DeleteVisitor erases a visitor and everything recorded about them,
+// including session replays (DELETE /v1/rum/visitor/{uuid}?reason=). The
+// authenticated operator is recorded in the audit trail. Erasing an unknown
+// visitor succeeds with found=false, so requests can be retried.
+func (h *Handler) DeleteVisitor(w http.ResponseWriter, r *http.Request, visitorUUID string) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+
+	// Replays first: their data may live outside the database, and a
+	// failure here leaves the visitor in place to retry
+	replayChunks := 0
+	if h.replays != nil {
+		n, err := h.replays.DeleteVisitor(visitorUUID)
+		if err != nil {
+			return http.StatusInternalServerError, map[string]string{"error": "failed to delete replays: " + err.Error()}
+		}
+		replayChunks = n
+	}
+
+	erasure, err := h.storage.EraseVisitor(visitorUUID)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	erasure.Deleted["rum_replay_chunks"] += int64(replayChunks)
+	h.privacy.Forget(visitorUUID)
+
+	data := make(map[string]any, len(erasure.Deleted))
+	for table, n := range erasure.Deleted {
+		data[table] = n
+	}
+	if err := h.storage.AppendPrivacyAudit(PrivacyAuditEntry{
+		VisitorUUID: visitorUUID,
+		Event:       PrivacyAuditErase,
+		Actor:       actor,
+		Detail:      r.URL.Query().Get("reason"),
+		Data:        data,
+	}); err != nil {
+		log.Printf("[RUM] Visitor %s erased but not audited: %v", visitorUUID, err)
+		return http.StatusInternalServerError, map[string]string{"error": "visitor erased but the audit entry failed: " + err.Error()}
+	}
+
+	log.Printf("[RUM] Erased visitor %s for %s", visitorUUID, actor)
+	return http.StatusOK, erasure
+}
+
+// ExportVisitor returns everything recorded about a visitor as a JSON
+// download (GET /v1/rum/visitor/{uuid}/export?reason=). The authenticated
+// operator is recorded in the audit trail; nothing is returned unless the
+// export is audited.
+func (h *Handler) ExportVisitor(w http.ResponseWriter, r *http.Request, visitorUUID string) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+
+	export, err := h.storage.ExportVisitor(visitorUUID)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if export == nil {
+		return http.StatusNotFound, map[string]string{"error": "visitor not found"}
+	}
+
+	if err := h.storage.AppendPrivacyAudit(PrivacyAuditEntry{
+		VisitorUUID: visitorUUID,
+		Event:       PrivacyAuditExport,
+		Actor:       actor,
+		Detail:      r.URL.Query().Get("reason"),
+		Data: map[string]any{
+			"sessions":      len(export.Sessions),
+			"events":        len(export.Events),
+			"vitals":        len(export.Vitals),
+			"errors":        len(export.Errors),
+			"replay_chunks": len(export.ReplayChunks),
+			"truncated":     export.Truncated,
+		},
+	}); err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="rum-visitor-%s.json"`, export.Visitor.UUID))
+	return http.StatusOK, export
+}
+
+// GetPrivacyAudit returns recent consent changes, exports and erasures,
+// newest first (GET /v1/rum/privacy/audit?visitor=&limit=100). Without a
+// visitor only the calling operator's own entries are listed, so the audit
+// can't be used to enumerate visitors.
+func (h *Handler) GetPrivacyAudit(w http.ResponseWriter, r *http.Request) (int, any) {
+	actor, err := h.operators.Identify(r)
+	if err != nil {
+		return http.StatusUnauthorized, map[string]string{"error": err.Error()}
+	}
+	visitorUUID := r.URL.Query().Get("visitor")
+	if visitorUUID != "" {
+		actor = ""
+	}
+
+	limit := defaultAuditEntries
+	if l := r.URL.Query().Get("limit"); l != "" {
+		parsed, err := strconv.Atoi(l)
+		if err != nil || parsed <= 0 || parsed > maxAuditEntries {
+			return http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("limit must be between 1 and %d", maxAuditEntries)}
+		}
+		limit = parsed
+	}
+
+	entries, err := h.storage.ListPrivacyAudit(visitorUUID, actor, limit)
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	return http.StatusOK, entries
+}
+
 // GetSession retrieves session information
 func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request, sessionID string) (int, any) {
 	session, err := h.storage.GetSessionByID(sessionID)
@@ -227,17 +950,81 @@ func (h *Handler) GetSession(w http.ResponseWriter, r *http.Request, sessionID s
 	// Get events for the session
 	events, _ := h.storage.GetEventsBySession(sessionID)
 
-	return http.StatusOK, map[string]interface{}{
+	response := map[string]interface{}{
 		"session": session,
 		"events":  events,
 	}
+	if h.replays != nil {
+		if replay, err := h.replays.Summary(sessionID); err == nil && replay != nil {
+			response["replay"] = replay
+		}
+	}
+	return http.StatusOK, response
+}
+
+// UploadReplay stores a chunk of rrweb events for a session
+// (POST /v1/rum/session/{sessionId}/replay). Privacy masking is applied
+// before the chunk is written. Accepts sendBeacon text/plain bodies.
+func (h *Handler) UploadReplay(w http.ResponseWriter, r *http.Request, sessionID string) (int, any) {
+	if h.replays == nil {
+		return http.StatusNotFound, map[string]string{"error": "session replay is not enabled"}
+	}
+
+	var req ReplayUploadRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReplayChunkBytes)).Decode(&req); err != nil {
+		return http.StatusBadRequest, map[string]string{"error": "invalid request body"}
+	}
+	if !h.privacy.Allowed(req.VisitorUUID) {
+		return http.StatusAccepted, map[string]any{"status": "replay chunk dropped without consent"}
+	}
+
+	chunk, err := h.replays.Record(sessionID, req.VisitorUUID, req.Events)
+	if errors.Is(err, ErrInvalidReplay) {
+		return http.StatusBadRequest, map[string]string{"error": err.Error()}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": "failed to store replay chunk"}
+	}
+
+	return http.StatusAccepted, map[string]any{
+		"status":       "replay chunk recorded",
+		"chunk_id":     chunk.ID,
+		"event_count":  chunk.EventCount,
+		"stored_bytes": chunk.StoredBytes,
+	}
+}
+
+// GetReplay returns a session's masked replay events in playback order
+// (GET /v1/rum/session/{sessionId}/replay)
+func (h *Handler) GetReplay(w http.ResponseWriter, r *http.Request, sessionID string) (int, any) {
+	if h.replays == nil {
+		return http.StatusNotFound, map[string]string{"error": "session replay is not enabled"}
+	}
+
+	chunks, events, err := h.replays.Load(sessionID)
+	if errors.Is(err, ErrReplayTooLarge) {
+		return http.StatusRequestEntityTooLarge, map[string]string{"error": err.Error()}
+	}
+	if err != nil {
+		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
+	}
+	if len(chunks) == 0 {
+		return http.StatusNotFound, map[string]string{"error": "no replay recorded for session"}
+	}
+
+	return http.StatusOK, ReplayResponse{
+		SessionID: sessionID,
+		Summary:   *Summarize(chunks),
+		Chunks:    chunks,
+		Events:    events,
+	}
 }
 
 // GetUniqueVisitors returns count of unique visitors
 func (h *Handler) GetUniqueVisitors(w http.ResponseWriter, r *http.Request) (int, any) {
 	from, to := parseTimeRange(r)
 
-	count, err := h.storage.CountUniqueVisitors(from, to)
+	count, err := h.storage.CountUniqueVisitors(from, to, parseIncludeBots(r))
 	if err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
 	}
@@ -249,11 +1036,12 @@ func (h *Handler) GetUniqueVisitors(w http.ResponseWriter, r *http.Request) (int
 	}
 }
 
-// GetAnalytics returns comprehensive analytics
+// GetAnalytics returns comprehensive analytics; bot traffic is excluded
+// unless include_bots=true
 func (h *Handler) GetAnalytics(w http.ResponseWriter, r *http.Request) (int, any) {
 	from, to := parseTimeRange(r)
 
-	analytics, err := h.storage.GetAnalytics(from, to)
+	analytics, err := h.storage.GetAnalytics(from, to, parseIncludeBots(r))
 	if err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
 	}
@@ -278,7 +1066,7 @@ func (h *Handler) GetRecentSessions(w http.ResponseWriter, r *http.Request) (int
 		}
 	}
 
-	sessions, err := h.storage.GetRecentSessions(limit, offset)
+	sessions, err := h.storage.GetRecentSessions(limit, offset, parseIncludeBots(r))
 	if err != nil {
 		return http.StatusInternalServerError, map[string]string{"error": err.Error()}
 	}
@@ -288,9 +1076,105 @@ func (h *Handler) GetRecentSessions(w http.ResponseWriter, r *http.Request) (int
 
 // Helper functions
 
-func hashIP(ip string) string {
-	hash := sha256.Sum256([]byte(ip))
-	return hex.EncodeToString(hash[:])
+// newEvent validates a tracked event and builds it, recording trace context
+// in its metadata. Client timestamps outside [now-maxEventAge, now+maxEventSkew]
+// are replaced with now.
+func newEvent(req TrackEventRequest, traceID, spanID string, now time.Time) (RUMEvent, error) {
+	if req.VisitorUUID == "" || req.SessionID == "" || req.EventType == "" {
+		return RUMEvent{}, errors.New("visitor_uuid, session_id, and event_type are required")
+	}
+	if !validEventTypes[req.EventType] {
+		return RUMEvent{}, errors.New("invalid event_type")
+	}
+
+	// Include trace context in metadata if provided in request or from APM
+	if req.Metadata == nil {
+		req.Metadata = make(map[string]interface{})
+	}
+	if req.TraceID != "" {
+		req.Metadata["frontend_trace_id"] = req.TraceID
+	}
+	if req.SpanID != "" {
+		req.Metadata["frontend_span_id"] = req.SpanID
+	}
+	if traceID != "" {
+		req.Metadata["backend_trace_id"] = traceID
+	}
+	if spanID != "" {
+		req.Metadata["backend_span_id"] = spanID
+	}
+
+	timestamp := req.Timestamp
+	if timestamp.Before(now.Add(-maxEventAge)) || timestamp.After(now.Add(maxEventSkew)) {
+		timestamp = now
+	}
+
+	return RUMEvent{
+		VisitorUUID: req.VisitorUUID,
+		SessionID:   req.SessionID,
+		EventType:   req.EventType,
+		Timestamp:   timestamp,
+		PageURL:     req.PageURL,
+		PageTitle:   req.PageTitle,
+		ActionName:  req.ActionName,
+		ActionType:  req.ActionType,
+		ErrorMsg:    req.ErrorMsg,
+		Duration:    req.Duration,
+		Metadata:    req.Metadata,
+	}, nil
+}
+
+// newOccurrence validates an error report and builds its occurrence and the
+// issue it belongs to. Timestamps are clamped like newEvent's.
+func (h *Handler) newOccurrence(req ErrorReport, now time.Time) (ErrorOccurrence, ErrorIssue, error) {
+	if req.VisitorUUID == "" || req.SessionID == "" || strings.TrimSpace(req.Message) == "" {
+		return ErrorOccurrence{}, ErrorIssue{}, errors.New("visitor_uuid, session_id, and message are required")
+	}
+	if len(req.Stack) > maxStackBytes {
+		req.Stack = req.Stack[:maxStackBytes]
+	}
+	if req.Timestamp.Before(now.Add(-maxEventAge)) || req.Timestamp.After(now.Add(maxEventSkew)) {
+		req.Timestamp = now
+	}
+
+	errorType, message := req.Type, req.Message
+	if errorType == "" {
+		errorType, message = errorTypeOf(req.Message)
+	}
+	frames := h.symbols.Symbolicate(req.Release, parseStack(req.Stack))
+	fp := fingerprint(errorType, message, frames)
+
+	var top []StackFrame
+	for _, f := range frames {
+		if f.InApp {
+			top = append(top, f)
+		}
+		if len(top) == fingerprintFrames {
+			break
+		}
+	}
+	if len(top) == 0 && len(frames) > 0 {
+		top = frames[:min(len(frames), fingerprintFrames)]
+	}
+
+	occ := ErrorOccurrence{
+		Fingerprint: fp,
+		VisitorUUID: req.VisitorUUID,
+		SessionID:   req.SessionID,
+		Timestamp:   req.Timestamp,
+		PageURL:     req.PageURL,
+		Message:     message,
+		Release:     req.Release,
+		Frames:      frames,
+		RawStack:    req.Stack,
+	}
+	issue := ErrorIssue{
+		Fingerprint: fp,
+		Type:        errorType,
+		Message:     normalizeMessage(message),
+		TopFrames:   top,
+	}
+	return occ, issue, nil
 }
 
 func getClientIP(r *http.Request) string {
@@ -315,6 +1199,45 @@ func getClientIP(r *http.Request) string {
 	return ip
 }
 
+// vitalBucket parses the trend bucket size, defaulting by range length and
+// capping the trend at 1000 buckets
+func vitalBucket(value string, span time.Duration) (time.Duration, error) {
+	if value == "" {
+		if span <= 48*time.Hour {
+			return time.Hour, nil
+		}
+		return 24 * time.Hour, nil
+	}
+	bucket, err := time.ParseDuration(value)
+	if err != nil || bucket < time.Minute {
+		return 0, errors.New("bucket must be a duration of at least 1m")
+	}
+	if span/bucket > 1000 {
+		return 0, errors.New("bucket too small for the time range")
+	}
+	return bucket, nil
+}
+
+// segmentFilter reads the time range, the device and browser filters and
+// include_bots
+func segmentFilter(r *http.Request) SegmentFilter {
+	from, to := parseTimeRange(r)
+	return SegmentFilter{
+		From:        from,
+		To:          to,
+		Device:      r.URL.Query().Get("device"),
+		Browser:     r.URL.Query().Get("browser"),
+		IncludeBots: parseIncludeBots(r),
+	}
+}
+
+// parseIncludeBots reads the include_bots flag; analytics leave bot
+// sessions out by default
+func parseIncludeBots(r *http.Request) bool {
+	include, _ := strconv.ParseBool(r.URL.Query().Get("include_bots"))
+	return include
+}
+
 func parseTimeRange(r *http.Request) (time.Time, time.Time) {
 	now := time.Now()
 	from := now.Add(-24 * time.Hour) // Default: last 24 hours
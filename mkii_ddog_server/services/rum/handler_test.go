@@ -0,0 +1,60 @@
+package rum
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestHandler_Validation covers requests the handlers must reject with 400
+// before touching storage, so they run against a Storage without a database
+func TestHandler_Validation(t *testing.T) {
+	handler := NewHandler(&Storage{})
+
+	request := func(method, target, body string) *http.Request {
+		var r io.Reader
+		if body != "" {
+			r = strings.NewReader(body)
+		}
+		return httptest.NewRequest(method, target, r)
+	}
+	get := func(target string) *http.Request { return request(http.MethodGet, target, "") }
+	post := func(target, body string) *http.Request { return request(http.MethodPost, target, body) }
+
+	tests := []struct {
+		name    string
+		handle  func(http.ResponseWriter, *http.Request) (int, any)
+		request *http.Request
+	}{
+		{"negative vital", handler.TrackVitals, post("/v1/rum/vitals", `{"visitor_uuid":"v1","session_id":"s1","lcp":-5}`)},
+		{"vitals by unknown dimension", handler.GetVitals, get("/v1/rum/analytics/vitals?by=country")},
+		{"unknown vital", handler.GetVitals, get("/v1/rum/analytics/vitals?metric=fid")},
+		{"vitals bucket too small", handler.GetVitals, get("/v1/rum/analytics/vitals?bucket=1s")},
+
+		{"error without a session", handler.TrackError, post("/v1/rum/errors", `{"visitor_uuid":"v1","message":"boom"}`)},
+		{"unknown error sort", handler.ListErrors, get("/v1/rum/errors?sort=loudest")},
+		{"source map without a release", handler.UploadSourceMap, post("/v1/rum/sourcemaps?file=/app.js", testSourceMap)},
+		{"malformed source map", handler.UploadSourceMap, post("/v1/rum/sourcemaps?release=v1&file=/app.js", `{"version":3,"mappings":"!"}`)},
+
+		{"one-step funnel", handler.CreateFunnel, post("/v1/rum/funnels", `{"name":"signup","steps":[{"type":"page","match":"/signup"}]}`)},
+		{"unknown funnel step type", handler.EvaluateFunnel, post("/v1/rum/funnels/evaluate", `{"steps":[{"type":"page","match":"/"},{"type":"hover","match":"x"}]}`)},
+		{"bad funnel id", func(w http.ResponseWriter, r *http.Request) (int, any) { return handler.GetFunnel(w, r, "x") }, get("/v1/rum/funnels/x")},
+		{"path too deep", handler.GetPaths, get("/v1/rum/paths?depth=50")},
+
+		{"daily cohorts", handler.GetCohorts, get("/v1/rum/analytics/cohorts?grain=day")},
+		{"too many cohort periods", handler.GetCohorts, get("/v1/rum/analytics/cohorts?grain=month&periods=48")},
+		{"non-numeric cohort periods", handler.GetCohorts, get("/v1/rum/analytics/cohorts?periods=soon")},
+		{"active users over a year", handler.GetActiveUsers, get("/v1/rum/analytics/active?from=2020-01-01")},
+
+		{"weekly rollups", handler.GetRollups, get("/v1/rum/analytics/rollups?grain=week")},
+		{"hourly rollups over months", handler.GetRollups, get("/v1/rum/analytics/rollups?grain=hour&from=2026-01-01&to=2026-10-01")},
+		{"rollup range ends before it starts", handler.GetRollups, get("/v1/rum/analytics/rollups?from=2026-10-02&to=2026-10-01")},
+	}
+	for _, tt := range tests {
+		if status, body := tt.handle(httptest.NewRecorder(), tt.request); status != http.StatusBadRequest {
+			t.Errorf("%s: expected 400, got %d %v", tt.name, status, body)
+		}
+	}
+}
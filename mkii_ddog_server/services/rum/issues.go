@@ -0,0 +1,137 @@
+package rum
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Fingerprinting limits
+const (
+	fingerprintFrames = 3   // top in-app frames that identify an issue
+	maxStackFrames    = 100 // frames kept per occurrence
+	maxStackBytes     = 64 << 10
+)
+
+var (
+	// chromeFrame matches V8 frames: "at fn (https://x/app.js:10:15)" or "at https://x/app.js:10:15"
+	chromeFrame = regexp.MustCompile(`^\s*at (?:(.+?) \()?(.+?):(\d+):(\d+)\)?\s*$`)
+
+	// geckoFrame matches Firefox and Safari frames: "fn@https://x/app.js:10:15"
+	geckoFrame = regexp.MustCompile(`^\s*(.*?)@(.+?):(\d+):(\d+)\s*$`)
+
+	// Volatile parts of error messages replaced before fingerprinting
+	uuidPattern   = regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`)
+	urlPattern    = regexp.MustCompile(`\bhttps?://\S+`)
+	hexPattern    = regexp.MustCompile(`(?i)\b(0x)?[0-9a-f]{8,}\b`)
+	numberPattern = regexp.MustCompile(`\d+`)
+
+	// bundleHash matches content hashes in bundle names (app.3f2a9c1b.js)
+	bundleHash = regexp.MustCompile(`(?i)[.-][0-9a-f]{6,}(\.[a-z]+)$`)
+)
+
+// parseStack parses a JavaScript stack trace (V8, SpiderMonkey or
+// JavaScriptCore format), top frame first. Lines that aren't frames, such as
+// the leading "TypeError: ..." line, are skipped.
+func parseStack(stack string) []StackFrame {
+	if len(stack) > maxStackBytes {
+		stack = stack[:maxStackBytes]
+	}
+
+	var frames []StackFrame
+	for _, line := range strings.Split(stack, "\n") {
+		m := chromeFrame.FindStringSubmatch(line)
+		if m == nil {
+			m = geckoFrame.FindStringSubmatch(line)
+		}
+		if m == nil {
+			continue
+		}
+		lineNo, _ := strconv.Atoi(m[3])
+		column, _ := strconv.Atoi(m[4])
+		file := strings.TrimPrefix(m[2], "async ")
+		frames = append(frames, StackFrame{
+			Function: strings.TrimPrefix(m[1], "async "),
+			File:     file,
+			Line:     lineNo,
+			Column:   column,
+			InApp:    isInApp(file),
+		})
+		if len(frames) == maxStackFrames {
+			break
+		}
+	}
+	return frames
+}
+
+// isInApp reports whether a frame belongs to the application rather than
+// the browser, an extension or a third-party bundle
+func isInApp(file string) bool {
+	for _, marker := range []string{"extension://", "node_modules/", "<anonymous>", "native", "webpack/bootstrap"} {
+		if strings.Contains(file, marker) {
+			return false
+		}
+	}
+	return file != ""
+}
+
+// normalizeMessage replaces ids, URLs and numbers so the same error with
+// different values groups together
+func normalizeMessage(message string) string {
+	message = strings.TrimSpace(message)
+	message = uuidPattern.ReplaceAllString(message, "<uuid>")
+	message = urlPattern.ReplaceAllString(message, "<url>")
+	message = hexPattern.ReplaceAllString(message, "<hex>")
+	message = numberPattern.ReplaceAllString(message, "<n>")
+	return message
+}
+
+// frameKey identifies a frame across builds: the file without host, query or
+// content hash, and the function (or line when anonymous)
+func frameKey(f StackFrame) string {
+	file := f.File
+	if u, err := url.Parse(file); err == nil && u.Path != "" {
+		file = u.Path
+	}
+	file = bundleHash.ReplaceAllString(path.Base(file), "$1")
+	if f.Function != "" {
+		return file + ":" + f.Function
+	}
+	return file + ":" + strconv.Itoa(f.Line)
+}
+
+// fingerprint groups occurrences into issues: error type, normalized message
+// and the top in-app frames (all frames when none are in-app)
+func fingerprint(errorType, message string, frames []StackFrame) string {
+	var top []string
+	for _, f := range frames {
+		if f.InApp {
+			top = append(top, frameKey(f))
+		}
+		if len(top) == fingerprintFrames {
+			break
+		}
+	}
+	if len(top) == 0 {
+		for i := 0; i < len(frames) && i < fingerprintFrames; i++ {
+			top = append(top, frameKey(frames[i]))
+		}
+	}
+
+	parts := append([]string{errorType, normalizeMessage(message)}, top...)
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// errorTypeOf takes the error type from a "TypeError: message" string
+func errorTypeOf(message string) (errorType, rest string) {
+	name, rest, ok := strings.Cut(message, ": ")
+	if !ok || name == "" || strings.ContainsAny(name, " \t") {
+		return "Error", message
+	}
+	return name, rest
+}
@@ -0,0 +1,221 @@
+package rum
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+const chromeStack = `TypeError: Cannot read properties of undefined (reading 'id')
+    at handleClick (https://shop.example.com/static/js/app.3f2a9c1b.js:1:12)
+    at async submit (https://shop.example.com/static/js/app.3f2a9c1b.js:1:40)
+    at chrome-extension://abcdef/content.js:5:9
+    at https://shop.example.com/static/js/vendor.js:2:100`
+
+const geckoStack = `handleClick@https://shop.example.com/static/js/app.3f2a9c1b.js:1:12
+submit@https://shop.example.com/static/js/app.3f2a9c1b.js:1:40
+@https://shop.example.com/static/js/vendor.js:2:100`
+
+// testSourceMap maps app.js line 1: columns 0-9 to src/app.ts 1:1 and column
+// 10 onwards to handleClick at src/app.ts 5:3
+const testSourceMap = `{"version":3,"sourceRoot":"webpack://shop/","sources":["src/app.ts"],"names":["handleClick"],"mappings":"AAAA,UAIEA;"}`
+
+type fakeSourceMaps struct {
+	maps  map[string]string
+	calls int
+	err   error
+}
+
+func (f *fakeSourceMaps) SourceMap(release, file string) ([]byte, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	if data, ok := f.maps[release+"|"+file]; ok {
+		return []byte(data), nil
+	}
+	return nil, nil
+}
+
+func TestParseStack(t *testing.T) {
+	frames := parseStack(chromeStack)
+	if len(frames) != 4 {
+		t.Fatalf("expected 4 frames, got %+v", frames)
+	}
+	if frames[0] != (StackFrame{Function: "handleClick", File: "https://shop.example.com/static/js/app.3f2a9c1b.js", Line: 1, Column: 12, InApp: true}) {
+		t.Fatalf("unexpected top frame %+v", frames[0])
+	}
+	if frames[1].Function != "submit" {
+		t.Fatalf("expected async prefix stripped, got %q", frames[1].Function)
+	}
+	if frames[2].InApp || frames[3].Function != "" || frames[3].Line != 2 {
+		t.Fatalf("unexpected extension or anonymous frames %+v", frames[2:])
+	}
+
+	gecko := parseStack(geckoStack)
+	if len(gecko) != 3 || gecko[0].Function != "handleClick" || gecko[1].Column != 40 || gecko[2].Function != "" {
+		t.Fatalf("unexpected Firefox frames %+v", gecko)
+	}
+}
+
+func TestNormalizeMessage(t *testing.T) {
+	got := normalizeMessage(" Order 4211 failed for 3f2a9c1b-0000-4000-8000-00000000abcd at https://api.example.com/x?id=9 ")
+	if got != "Order <n> failed for <uuid> at <url>" {
+		t.Fatalf("unexpected normalized message %q", got)
+	}
+}
+
+func TestFingerprint_StableAcrossBuilds(t *testing.T) {
+	frames := parseStack(chromeStack)
+	fp := fingerprint("TypeError", "Cannot read properties of undefined (reading 'id')", frames)
+
+	// A new build changes the bundle hash, and line numbers of named frames
+	rebuilt := strings.ReplaceAll(chromeStack, "app.3f2a9c1b.js:1:", "app.77ab01ef.js:3:")
+	if got := fingerprint("TypeError", "Cannot read properties of undefined (reading 'id')", parseStack(rebuilt)); got != fp {
+		t.Fatalf("expected the same fingerprint after a rebuild, got %s and %s", fp, got)
+	}
+	// Firefox reports the same in-app frames
+	if got := fingerprint("TypeError", "Cannot read properties of undefined (reading 'id')", parseStack(geckoStack)); got != fp {
+		t.Fatalf("expected the same fingerprint across browsers, got %s and %s", fp, got)
+	}
+	if got := fingerprint("RangeError", "Cannot read properties of undefined (reading 'id')", frames); got == fp {
+		t.Fatal("expected a different error type to fingerprint differently")
+	}
+	if len(fp) != 16 {
+		t.Fatalf("expected a 16 character fingerprint, got %q", fp)
+	}
+}
+
+func TestErrorTypeOf(t *testing.T) {
+	if typ, rest := errorTypeOf("TypeError: x is undefined"); typ != "TypeError" || rest != "x is undefined" {
+		t.Fatalf("unexpected split %q %q", typ, rest)
+	}
+	if typ, rest := errorTypeOf("Script error."); typ != "Error" || rest != "Script error." {
+		t.Fatalf("unexpected split %q %q", typ, rest)
+	}
+	if typ, _ := errorTypeOf("Uncaught promise: failed"); typ != "Error" {
+		t.Fatalf("expected names with spaces rejected, got %q", typ)
+	}
+}
+
+func TestDecodeVLQ(t *testing.T) {
+	tests := map[string][]int{
+		"AAAA":  {0, 0, 0, 0},
+		"SAASA": {9, 0, 0, 9, 0},
+		"D":     {-1},
+		"gB":    {16},
+	}
+	for segment, want := range tests {
+		if got, err := decodeVLQ(segment); err != nil || !slices.Equal(got, want) {
+			t.Errorf("%s: expected %v, got %v (%v)", segment, want, got, err)
+		}
+	}
+	for _, bad := range []string{"g", "A!"} {
+		if _, err := decodeVLQ(bad); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestSourceMapLookup(t *testing.T) {
+	sm, err := parseSourceMap([]byte(testSourceMap))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	source, line, column, name, ok := sm.lookup(1, 12)
+	if !ok || source != "webpack://shop/src/app.ts" || line != 5 || column != 3 || name != "handleClick" {
+		t.Fatalf("unexpected lookup %s:%d:%d %q %v", source, line, column, name, ok)
+	}
+	if _, line, _, name, ok := sm.lookup(1, 1); !ok || line != 1 || name != "" {
+		t.Fatalf("expected the first segment for column 1, got line %d %q", line, name)
+	}
+	if _, _, _, _, ok := sm.lookup(2, 1); ok {
+		t.Fatal("expected no mapping on an empty line")
+	}
+
+	for _, bad := range []string{`{"version":2}`, `{"version":3,"sections":[{}]}`, `not json`} {
+		if _, err := parseSourceMap([]byte(bad)); err == nil {
+			t.Errorf("%s: expected an error", bad)
+		}
+	}
+}
+
+func TestSymbolicator(t *testing.T) {
+	store := &fakeSourceMaps{maps: map[string]string{"v1|/static/js/app.3f2a9c1b.js": testSourceMap}}
+	symbols := NewSymbolicator(store)
+
+	frames := symbols.Symbolicate("v1", parseStack(chromeStack))
+	if !frames[0].Symbolicated || frames[0].File != "webpack://shop/src/app.ts" || frames[0].Line != 5 || frames[0].Function != "handleClick" {
+		t.Fatalf("unexpected symbolicated frame %+v", frames[0])
+	}
+	if frames[3].Symbolicated || frames[3].File != "https://shop.example.com/static/js/vendor.js" {
+		t.Fatalf("expected frames without a map unchanged, got %+v", frames[3])
+	}
+
+	// app.js and vendor.js were loaded once each, including the missing map
+	calls := store.calls
+	symbols.Symbolicate("v1", parseStack(chromeStack))
+	if store.calls != calls {
+		t.Fatalf("expected cached maps reused, got %d loads after %d", store.calls, calls)
+	}
+	symbols.Invalidate("v1", "https://shop.example.com/static/js/vendor.js?v=2")
+	symbols.Symbolicate("v1", parseStack(chromeStack))
+	if store.calls != calls+1 {
+		t.Fatalf("expected only the invalidated map reloaded, got %d loads", store.calls-calls)
+	}
+
+	if got := symbols.Symbolicate("", parseStack(chromeStack)); got[0].Symbolicated {
+		t.Fatal("expected no symbolication without a release")
+	}
+
+	failing := NewSymbolicator(&fakeSourceMaps{err: errors.New("connection refused")})
+	if got := failing.Symbolicate("v1", parseStack(chromeStack)); got[0].Symbolicated || got[0].Line != 1 {
+		t.Fatalf("expected frames unchanged when the store fails, got %+v", got[0])
+	}
+}
+
+func TestHandler_NewOccurrence(t *testing.T) {
+	handler := NewHandler(&Storage{})
+	handler.symbols = NewSymbolicator(&fakeSourceMaps{})
+	now := time.Now()
+
+	occ, issue, err := handler.newOccurrence(ErrorReport{
+		VisitorUUID: "v1",
+		SessionID:   "s1",
+		Message:     "TypeError: Order 42 not found",
+		Stack:       chromeStack,
+		Release:     "v1",
+		Timestamp:   now.Add(-48 * time.Hour),
+	}, now)
+	if err != nil {
+		t.Fatalf("newOccurrence: %v", err)
+	}
+	if issue.Type != "TypeError" || issue.Message != "Order <n> not found" || occ.Message != "Order 42 not found" {
+		t.Fatalf("unexpected type or message %+v", issue)
+	}
+	if occ.Fingerprint != issue.Fingerprint || len(issue.TopFrames) != 3 || len(occ.Frames) != 4 {
+		t.Fatalf("unexpected frames %+v", issue.TopFrames)
+	}
+	if !occ.Timestamp.Equal(now) {
+		t.Fatalf("expected the stale timestamp replaced, got %s", occ.Timestamp)
+	}
+
+	if _, _, err := handler.newOccurrence(ErrorReport{VisitorUUID: "v1", SessionID: "s1", Message: " "}, now); err == nil {
+		t.Fatal("expected an empty message rejected")
+	}
+}
+
+func TestSourceMapFile(t *testing.T) {
+	for in, want := range map[string]string{
+		"https://cdn.example.com/static/js/app.js?v=3": "/static/js/app.js",
+		"static/js/app.js":     "/static/js/app.js",
+		"/static/../js/app.js": "/js/app.js",
+	} {
+		if got := sourceMapFile(in); got != want {
+			t.Errorf("%s: expected %s, got %s", in, want, got)
+		}
+	}
+}
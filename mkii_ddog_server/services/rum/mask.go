@@ -0,0 +1,284 @@
+package rum
+
+import (
+	"encoding/json"
+	"strings"
+	"unicode"
+)
+
+// rrweb event types and incremental snapshot sources this package inspects
+const (
+	rrwebFullSnapshot        = 2
+	rrwebIncrementalSnapshot = 3
+
+	rrwebSourceMutation = 0
+	rrwebSourceInput    = 5
+
+	rrwebElementNode = 2
+	rrwebTextNode    = 3
+)
+
+// selector is a simple CSS selector: tag, .class, #id, [attr] or [attr=value]
+type selector struct {
+	tag, class, id, attr, value string
+}
+
+// parseSelectors parses a selector list, ignoring empty entries
+func parseSelectors(list []string) []selector {
+	var selectors []selector
+	for _, raw := range list {
+		raw = strings.TrimSpace(raw)
+		switch {
+		case raw == "":
+			continue
+		case strings.HasPrefix(raw, "."):
+			selectors = append(selectors, selector{class: raw[1:]})
+		case strings.HasPrefix(raw, "#"):
+			selectors = append(selectors, selector{id: raw[1:]})
+		case strings.HasPrefix(raw, "[") && strings.HasSuffix(raw, "]"):
+			attr, value, _ := strings.Cut(raw[1:len(raw)-1], "=")
+			selectors = append(selectors, selector{attr: attr, value: strings.Trim(value, `"'`)})
+		default:
+			selectors = append(selectors, selector{tag: strings.ToLower(raw)})
+		}
+	}
+	return selectors
+}
+
+// matches reports whether an element with the given tag and attributes
+// matches the selector
+func (s selector) matches(tag string, attrs map[string]any) bool {
+	switch {
+	case s.tag != "":
+		return strings.EqualFold(tag, s.tag)
+	case s.class != "":
+		class, _ := attrs["class"].(string)
+		return containsField(class, s.class)
+	case s.id != "":
+		id, _ := attrs["id"].(string)
+		return id == s.id
+	case s.attr != "":
+		v, ok := attrs[s.attr]
+		if !ok {
+			return false
+		}
+		return s.value == "" || v == s.value
+	}
+	return false
+}
+
+func containsField(list, field string) bool {
+	for _, f := range strings.Fields(list) {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
+
+// maskState remembers which node ids of a session are masked or blocked so
+// mutations in later chunks are masked too. A full snapshot resets it.
+type maskState struct {
+	masked  map[int64]bool // text inside is masked
+	blocked map[int64]bool // content dropped
+	inputs  map[int64]bool // input values always masked (passwords)
+}
+
+func newMaskState() *maskState {
+	return &maskState{masked: map[int64]bool{}, blocked: map[int64]bool{}, inputs: map[int64]bool{}}
+}
+
+// masker applies the privacy rules of a ReplayConfig to rrweb events
+type masker struct {
+	maskAllInputs  bool
+	maskAllText    bool
+	maskSelectors  []selector
+	blockSelectors []selector
+}
+
+func newMasker(config ReplayConfig) *masker {
+	return &masker{
+		maskAllInputs:  config.MaskAllInputs,
+		maskAllText:    config.MaskAllText,
+		maskSelectors:  parseSelectors(config.MaskSelectors),
+		blockSelectors: parseSelectors(config.BlockSelectors),
+	}
+}
+
+// maskEvent rewrites a decoded rrweb event in place, updating state with
+// nodes seen in snapshots and mutations
+func (m *masker) maskEvent(event map[string]any, state *maskState) {
+	data, _ := event["data"].(map[string]any)
+	if data == nil {
+		return
+	}
+
+	switch eventType, _ := jsonInt(event["type"]); eventType {
+	case rrwebFullSnapshot:
+		*state = *newMaskState()
+		if node, ok := data["node"].(map[string]any); ok {
+			m.maskNode(node, false, state)
+		}
+
+	case rrwebIncrementalSnapshot:
+		switch source, _ := jsonInt(data["source"]); source {
+		case rrwebSourceMutation:
+			m.maskMutation(data, state)
+		case rrwebSourceInput:
+			id, _ := jsonInt(data["id"])
+			if text, ok := data["text"].(string); ok && (m.maskAllInputs || state.masked[id] || state.inputs[id]) {
+				data["text"] = maskText(text)
+			}
+		}
+	}
+}
+
+// maskNode walks a serialized node tree; masked is true inside a masked
+// ancestor
+func (m *masker) maskNode(node map[string]any, masked bool, state *maskState) {
+	id, _ := jsonInt(node["id"])
+	nodeType, _ := jsonInt(node["type"])
+
+	switch nodeType {
+	case rrwebTextNode:
+		if masked {
+			// Later text mutations reference the text node, not its element
+			state.masked[id] = true
+		}
+		if text, ok := node["textContent"].(string); ok && (masked || m.maskAllText) && !isStyleText(node) {
+			node["textContent"] = maskText(text)
+		}
+		return
+
+	case rrwebElementNode:
+		tag, _ := node["tagName"].(string)
+		attrs, _ := node["attributes"].(map[string]any)
+		if attrs == nil {
+			attrs = map[string]any{}
+		}
+
+		if matchesAny(m.blockSelectors, tag, attrs) {
+			state.blocked[id] = true
+			node["attributes"] = map[string]any{"rr_block": "true"}
+			node["childNodes"] = []any{}
+			return
+		}
+		if matchesAny(m.maskSelectors, tag, attrs) {
+			masked = true
+		}
+		if masked {
+			state.masked[id] = true
+		}
+
+		isInput := tag == "input" || tag == "textarea" || tag == "select"
+		if inputType, _ := attrs["type"].(string); strings.EqualFold(inputType, "password") {
+			state.inputs[id] = true
+			masked = true
+		}
+		if isInput && (m.maskAllInputs || masked) {
+			state.inputs[id] = true
+			if value, ok := attrs["value"].(string); ok {
+				attrs["value"] = maskText(value)
+			}
+			if tag == "textarea" {
+				masked = true
+			}
+		}
+	}
+
+	children, _ := node["childNodes"].([]any)
+	for _, child := range children {
+		if c, ok := child.(map[string]any); ok {
+			m.maskNode(c, masked, state)
+		}
+	}
+}
+
+// maskMutation masks added nodes, text changes and value attributes of a
+// mutation, dropping additions inside blocked elements
+func (m *masker) maskMutation(data map[string]any, state *maskState) {
+	if adds, ok := data["adds"].([]any); ok {
+		kept := adds[:0]
+		for _, add := range adds {
+			a, ok := add.(map[string]any)
+			if !ok {
+				continue
+			}
+			parent, _ := jsonInt(a["parentId"])
+			if state.blocked[parent] {
+				continue
+			}
+			if node, ok := a["node"].(map[string]any); ok {
+				m.maskNode(node, state.masked[parent], state)
+			}
+			kept = append(kept, a)
+		}
+		data["adds"] = kept
+	}
+
+	if texts, ok := data["texts"].([]any); ok {
+		for _, t := range texts {
+			text, ok := t.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := jsonInt(text["id"])
+			if value, ok := text["value"].(string); ok && (m.maskAllText || state.masked[id] || state.blocked[id]) {
+				text["value"] = maskText(value)
+			}
+		}
+	}
+
+	if attributes, ok := data["attributes"].([]any); ok {
+		for _, a := range attributes {
+			change, ok := a.(map[string]any)
+			if !ok {
+				continue
+			}
+			id, _ := jsonInt(change["id"])
+			attrs, _ := change["attributes"].(map[string]any)
+			if value, ok := attrs["value"].(string); ok && (m.maskAllInputs || state.masked[id] || state.inputs[id]) {
+				attrs["value"] = maskText(value)
+			}
+		}
+	}
+}
+
+func matchesAny(selectors []selector, tag string, attrs map[string]any) bool {
+	for _, s := range selectors {
+		if s.matches(tag, attrs) {
+			return true
+		}
+	}
+	return false
+}
+
+// isStyleText reports whether a text node is stylesheet content, which is
+// never masked so playback keeps its layout
+func isStyleText(node map[string]any) bool {
+	isStyle, _ := node["isStyle"].(bool)
+	return isStyle
+}
+
+// maskText replaces every non-space character with '*', keeping the length
+// and line breaks so masked layout stays close to the original
+func maskText(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return r
+		}
+		return '*'
+	}, text)
+}
+
+// jsonInt reads an integer decoded with UseNumber
+func jsonInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case json.Number:
+		i, err := n.Int64()
+		return i, err == nil
+	case float64:
+		return int64(n), true
+	}
+	return 0, false
+}
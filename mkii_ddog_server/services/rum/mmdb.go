@@ -0,0 +1,361 @@
+package rum
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"net/netip"
+	"os"
+)
+
+// mmdbMetadataMarker precedes the metadata map at the end of a MaxMind DB
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// Decoder limits; guard against corrupt files looping or recursing forever
+const (
+	mmdbMetadataMaxSize = 128 << 10
+	mmdbMaxDepth        = 32
+)
+
+// MMDB data section field types
+const (
+	mmdbExtended = iota
+	mmdbPointer
+	mmdbString
+	mmdbDouble
+	mmdbBytes
+	mmdbUint16
+	mmdbUint32
+	mmdbMap
+	mmdbInt32
+	mmdbUint64
+	mmdbUint128
+	mmdbArray
+	mmdbContainer
+	mmdbEndMarker
+	mmdbBool
+	mmdbFloat
+)
+
+// ErrInvalidMMDB is returned for files that aren't MaxMind DB format
+var ErrInvalidMMDB = errors.New("invalid MaxMind DB file")
+
+// mmdbReader looks addresses up in a MaxMind DB (.mmdb) file held in
+// memory. It implements just enough of the format for GeoIP enrichment:
+// the binary search tree and the data section decoded into maps, slices,
+// strings, numbers and bools.
+type mmdbReader struct {
+	buf          []byte
+	data         []byte // data section
+	nodeCount    uint
+	recordSize   uint
+	ipVersion    uint
+	databaseType string
+	ipv4Start    uint // node reached after the 96 zero bits of ::/96
+}
+
+// openMMDB reads and validates a MaxMind DB file
+func openMMDB(path string) (*mmdbReader, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return newMMDBReader(buf)
+}
+
+// newMMDBReader parses the metadata of a MaxMind DB held in buf
+func newMMDBReader(buf []byte) (*mmdbReader, error) {
+	tail := buf
+	if len(tail) > mmdbMetadataMaxSize {
+		tail = tail[len(tail)-mmdbMetadataMaxSize:]
+	}
+	i := bytes.LastIndex(tail, mmdbMetadataMarker)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: no metadata", ErrInvalidMMDB)
+	}
+	metaStart := len(buf) - len(tail) + i + len(mmdbMetadataMarker)
+
+	value, _, err := (&mmdbDecoder{data: buf[metaStart:]}).decode(0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("%w: metadata: %v", ErrInvalidMMDB, err)
+	}
+	meta, ok := value.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: metadata is not a map", ErrInvalidMMDB)
+	}
+
+	r := &mmdbReader{buf: buf}
+	r.nodeCount = uint(mmdbUint(meta["node_count"]))
+	r.recordSize = uint(mmdbUint(meta["record_size"]))
+	r.ipVersion = uint(mmdbUint(meta["ip_version"]))
+	r.databaseType, _ = meta["database_type"].(string)
+	if r.recordSize != 24 && r.recordSize != 28 && r.recordSize != 32 {
+		return nil, fmt.Errorf("%w: unsupported record size %d", ErrInvalidMMDB, r.recordSize)
+	}
+	if r.ipVersion != 4 && r.ipVersion != 6 {
+		return nil, fmt.Errorf("%w: unsupported ip version %d", ErrInvalidMMDB, r.ipVersion)
+	}
+
+	// Bound the node count before multiplying so a huge one can't wrap
+	// treeSize; the tree and the 16-byte separator end before the marker
+	dataEnd := uint(metaStart - len(mmdbMetadataMarker))
+	if r.nodeCount == 0 || r.nodeCount > uint(len(buf))*4/r.recordSize {
+		return nil, fmt.Errorf("%w: search tree exceeds file", ErrInvalidMMDB)
+	}
+	treeSize := r.nodeCount * r.recordSize / 4
+	if treeSize+16 > dataEnd {
+		return nil, fmt.Errorf("%w: search tree exceeds file", ErrInvalidMMDB)
+	}
+	r.data = buf[treeSize+16 : dataEnd]
+
+	if r.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < r.nodeCount; i++ {
+			node = r.record(node, 0)
+		}
+		r.ipv4Start = node
+	}
+	return r, nil
+}
+
+// record reads the left (bit 0) or right (bit 1) record of a node
+func (r *mmdbReader) record(node uint, bit uint) uint {
+	switch r.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		b := r.buf[off : off+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		b := r.buf[node*7 : node*7+7]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(r.buf[off : off+4]))
+	}
+}
+
+// Lookup returns the record for an address, or nil when the database has
+// none. IPv4 addresses are looked up in the IPv4 subtree of IPv6 databases.
+func (r *mmdbReader) Lookup(addr netip.Addr) (any, error) {
+	addr = addr.Unmap()
+	node := uint(0)
+	var ip []byte
+	switch {
+	case addr.Is4() && r.ipVersion == 6:
+		node = r.ipv4Start
+		a4 := addr.As4()
+		ip = a4[:]
+	case addr.Is4():
+		a4 := addr.As4()
+		ip = a4[:]
+	case r.ipVersion == 4:
+		return nil, nil
+	default:
+		a16 := addr.As16()
+		ip = a16[:]
+	}
+
+	for i := 0; i < len(ip)*8 && node < r.nodeCount; i++ {
+		bit := uint(ip[i/8]>>(7-i%8)) & 1
+		node = r.record(node, bit)
+	}
+	if node == r.nodeCount {
+		return nil, nil
+	}
+	if node < r.nodeCount {
+		return nil, fmt.Errorf("%w: tree deeper than the address", ErrInvalidMMDB)
+	}
+
+	offset := node - r.nodeCount - 16
+	if offset >= uint(len(r.data)) {
+		return nil, fmt.Errorf("%w: record points outside the data section", ErrInvalidMMDB)
+	}
+	value, _, err := (&mmdbDecoder{data: r.data}).decode(offset, 0)
+	return value, err
+}
+
+// mmdbDecoder decodes values of a data section; pointers are offsets into it
+type mmdbDecoder struct {
+	data []byte
+}
+
+// decode returns the value at offset and the offset after it
+func (d *mmdbDecoder) decode(offset uint, depth int) (any, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, errors.New("data nested too deeply")
+	}
+	typ, size, offset, err := d.control(offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if typ == mmdbPointer {
+		target, next, err := d.pointer(size, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := d.decode(target, depth+1)
+		return value, next, err
+	}
+
+	// Every map entry takes at least two bytes and every array element one,
+	// so a larger size is corrupt; checking first bounds the allocations
+	remaining := uint(len(d.data)) - offset
+	switch typ {
+	case mmdbMap:
+		if size > remaining/2 {
+			return nil, 0, fmt.Errorf("map of %d entries exceeds the data section", size)
+		}
+		m := make(map[string]any, size)
+		for i := uint(0); i < size; i++ {
+			var key, value any
+			if key, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			k, ok := key.(string)
+			if !ok {
+				return nil, 0, errors.New("map key is not a string")
+			}
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			m[k] = value
+		}
+		return m, offset, nil
+	case mmdbArray:
+		if size > remaining {
+			return nil, 0, fmt.Errorf("array of %d elements exceeds the data section", size)
+		}
+		a := make([]any, 0, size)
+		for i := uint(0); i < size; i++ {
+			var value any
+			if value, offset, err = d.decode(offset, depth+1); err != nil {
+				return nil, 0, err
+			}
+			a = append(a, value)
+		}
+		return a, offset, nil
+	case mmdbBool:
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(d.data)) {
+		return nil, 0, errors.New("value runs past the data section")
+	}
+	b := d.data[offset : offset+size]
+	next := offset + size
+
+	switch typ {
+	case mmdbString:
+		return string(b), next, nil
+	case mmdbBytes:
+		return append([]byte(nil), b...), next, nil
+	case mmdbDouble:
+		if size != 8 {
+			return nil, 0, errors.New("double is not 8 bytes")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(b)), next, nil
+	case mmdbFloat:
+		if size != 4 {
+			return nil, 0, errors.New("float is not 4 bytes")
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(b))), next, nil
+	case mmdbUint16, mmdbUint32, mmdbUint64:
+		if size > 8 {
+			return nil, 0, errors.New("unsigned integer too large")
+		}
+		return uintFromBytes(b), next, nil
+	case mmdbInt32:
+		if size > 4 {
+			return nil, 0, errors.New("int32 too large")
+		}
+		return int64(int32(uint32(uintFromBytes(b)))), next, nil
+	case mmdbUint128:
+		// Only used for IPv6 network values, which enrichment doesn't read
+		return append([]byte(nil), b...), next, nil
+	default:
+		return nil, 0, fmt.Errorf("unsupported data type %d", typ)
+	}
+}
+
+// control reads a field's control byte(s), returning its type, size and
+// the offset of its payload
+func (d *mmdbDecoder) control(offset uint) (typ, size, next uint, err error) {
+	if offset >= uint(len(d.data)) {
+		return 0, 0, 0, errors.New("offset outside the data section")
+	}
+	ctrl := d.data[offset]
+	offset++
+	typ = uint(ctrl >> 5)
+	if typ == mmdbExtended {
+		if offset >= uint(len(d.data)) {
+			return 0, 0, 0, errors.New("truncated extended type")
+		}
+		typ = 7 + uint(d.data[offset])
+		offset++
+	}
+	size = uint(ctrl & 0x1F)
+	if typ == mmdbPointer {
+		return typ, size, offset, nil
+	}
+
+	if size >= 29 {
+		n := size - 28 // 1, 2 or 3 extra bytes
+		if offset+n > uint(len(d.data)) {
+			return 0, 0, 0, errors.New("truncated size")
+		}
+		extra := uintFromBytes(d.data[offset : offset+n])
+		offset += n
+		switch n {
+		case 1:
+			size = 29 + uint(extra)
+		case 2:
+			size = 285 + uint(extra)
+		default:
+			size = 65821 + uint(extra)
+		}
+	}
+	return typ, size, offset, nil
+}
+
+// pointer decodes a pointer whose control byte carried size bits, returning
+// its target and the offset after it
+func (d *mmdbDecoder) pointer(size, offset uint) (target, next uint, err error) {
+	n := (size>>3)&0x3 + 1
+	if offset+n > uint(len(d.data)) {
+		return 0, 0, errors.New("truncated pointer")
+	}
+	b := uintFromBytes(d.data[offset : offset+n])
+	vvv := uint64(size & 0x7)
+	switch n {
+	case 1:
+		target = uint(vvv<<8 | b)
+	case 2:
+		target = uint(vvv<<16|b) + 2048
+	case 3:
+		target = uint(vvv<<24|b) + 526336
+	default:
+		target = uint(b)
+	}
+	return target, offset + n, nil
+}
+
+// uintFromBytes decodes a big-endian unsigned integer of up to 8 bytes
+func uintFromBytes(b []byte) uint64 {
+	var v uint64
+	for _, c := range b {
+		v = v<<8 | uint64(c)
+	}
+	return v
+}
+
+// mmdbUint returns a decoded unsigned value, or 0
+func mmdbUint(v any) uint64 {
+	n, _ := v.(uint64)
+	return n
+}
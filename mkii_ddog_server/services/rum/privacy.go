@@ -0,0 +1,294 @@
+package rum
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Visitor consent states
+const (
+	ConsentGranted = "granted"
+	ConsentDenied  = "denied"
+	ConsentUnknown = "unknown"
+)
+
+// Privacy audit events
+const (
+	PrivacyAuditConsent = "consent"
+	PrivacyAuditExport  = "export"
+	PrivacyAuditErase   = "erase"
+)
+
+// Privacy limits
+const (
+	consentCacheTTL     = time.Minute // how stale another replica's consent change can be
+	maxConsentCache     = 10000
+	ipSaltBytes         = 32
+	maxExportRows       = 10000 // per table
+	defaultAuditEntries = 100
+	maxAuditEntries     = 1000
+)
+
+// validConsent reports whether state is a consent state
+func validConsent(state string) bool {
+	return state == ConsentGranted || state == ConsentDenied || state == ConsentUnknown
+}
+
+// PrivacyConfig holds configuration for consent, IP pseudonymisation and
+// PII scrubbing
+type PrivacyConfig struct {
+	// RequireConsent drops data from visitors who haven't granted consent;
+	// otherwise only visitors who denied it are dropped
+	// Default: false
+	RequireConsent bool
+
+	// SaltRotation is how long an IP salt is used. Salts are deleted when
+	// they rotate, so IP pseudonyms from earlier periods can't be linked to
+	// an address or to each other.
+	// Default: 24h
+	SaltRotation time.Duration
+
+	// ScrubFields are metadata keys and URL parameters redacted in addition
+	// to the built-in list (passwords, tokens, emails, phone and card
+	// numbers...)
+	// Default: none
+	ScrubFields []string
+}
+
+// DefaultPrivacyConfig returns sensible defaults
+func DefaultPrivacyConfig() PrivacyConfig {
+	return PrivacyConfig{SaltRotation: 24 * time.Hour}
+}
+
+// PrivacyConfigFromEnv returns the defaults overridden by
+// RUM_REQUIRE_CONSENT, RUM_IP_SALT_ROTATION and RUM_SCRUB_FIELDS
+// (comma-separated, added to the built-in list)
+func PrivacyConfigFromEnv() PrivacyConfig {
+	config := DefaultPrivacyConfig()
+	if v, err := strconv.ParseBool(os.Getenv("RUM_REQUIRE_CONSENT")); err == nil {
+		config.RequireConsent = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RUM_IP_SALT_ROTATION")); err == nil && v >= time.Minute {
+		config.SaltRotation = v
+	}
+	if v := os.Getenv("RUM_SCRUB_FIELDS"); v != "" {
+		config.ScrubFields = strings.Split(v, ",")
+	}
+	return config
+}
+
+// PrivacyStore persists consent, IP salts and the privacy audit trail;
+// implemented by *Storage
+type PrivacyStore interface {
+	// VisitorConsent returns a visitor's consent state; found is false for
+	// unknown visitors
+	VisitorConsent(visitorUUID string) (state string, found bool, err error)
+	SetVisitorConsent(visitorUUID, state string) (found bool, err error)
+	// IPSalt returns the salt of an epoch, saving candidate if the epoch
+	// has none yet, so every replica uses the first salt written
+	IPSalt(epoch int64, candidate []byte) ([]byte, error)
+	PurgeIPSalts(beforeEpoch int64) (int64, error)
+	AppendPrivacyAudit(entry PrivacyAuditEntry) error
+}
+
+// Privacy enforces visitor consent, pseudonymises client IPs and scrubs PII
+// from ingested data
+type Privacy struct {
+	store    PrivacyStore
+	config   PrivacyConfig
+	scrubber *Scrubber
+	now      func() time.Time
+
+	mu        sync.Mutex
+	saltEpoch int64
+	salt      []byte
+	consent   map[string]cachedConsent
+}
+
+type cachedConsent struct {
+	state   string
+	expires time.Time
+}
+
+// NewPrivacy creates privacy controls backed by store
+func NewPrivacy(store PrivacyStore, config PrivacyConfig) *Privacy {
+	if config.SaltRotation < time.Minute {
+		config.SaltRotation = DefaultPrivacyConfig().SaltRotation
+	}
+	return &Privacy{
+		store:    store,
+		config:   config,
+		scrubber: NewScrubber(config.ScrubFields),
+		now:      time.Now,
+		consent:  make(map[string]cachedConsent),
+	}
+}
+
+// Config returns the effective configuration
+func (p *Privacy) Config() PrivacyConfig {
+	return p.config
+}
+
+// Scrubber returns the PII scrubber applied at ingestion
+func (p *Privacy) Scrubber() *Scrubber {
+	return p.scrubber
+}
+
+// Tracks reports whether data may be recorded for a visitor in a consent
+// state: never after a denial, and before a grant only when consent isn't
+// required
+func (p *Privacy) Tracks(state string) bool {
+	switch state {
+	case ConsentGranted:
+		return true
+	case ConsentDenied:
+		return false
+	default:
+		return !p.config.RequireConsent
+	}
+}
+
+// Consent returns a visitor's consent state, cached for consentCacheTTL.
+// Unknown visitors are ConsentUnknown.
+func (p *Privacy) Consent(visitorUUID string) (string, error) {
+	now := p.now()
+	p.mu.Lock()
+	cached, ok := p.consent[visitorUUID]
+	p.mu.Unlock()
+	if ok && now.Before(cached.expires) {
+		return cached.state, nil
+	}
+
+	state, found, err := p.store.VisitorConsent(visitorUUID)
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		state = ConsentUnknown
+	}
+	p.remember(visitorUUID, state)
+	return state, nil
+}
+
+// Allowed reports whether ingested data for a visitor may be stored. When
+// the consent state can't be read, data is kept unless consent is required.
+func (p *Privacy) Allowed(visitorUUID string) bool {
+	if visitorUUID == "" {
+		return p.Tracks(ConsentUnknown)
+	}
+	state, err := p.Consent(visitorUUID)
+	if err != nil {
+		log.Printf("[RUM] Failed to read consent of visitor %s: %v", visitorUUID, err)
+		return p.Tracks(ConsentUnknown)
+	}
+	return p.Tracks(state)
+}
+
+// SetConsent records a visitor's consent state and audits the change
+func (p *Privacy) SetConsent(visitorUUID, state, actor string) (bool, error) {
+	found, err := p.store.SetVisitorConsent(visitorUUID, state)
+	if err != nil || !found {
+		return found, err
+	}
+	p.remember(visitorUUID, state)
+	return true, p.store.AppendPrivacyAudit(PrivacyAuditEntry{
+		VisitorUUID: visitorUUID,
+		Event:       PrivacyAuditConsent,
+		Actor:       actor,
+		Detail:      state,
+	})
+}
+
+// Forget drops a visitor's cached consent state
+func (p *Privacy) Forget(visitorUUID string) {
+	p.mu.Lock()
+	delete(p.consent, visitorUUID)
+	p.mu.Unlock()
+}
+
+func (p *Privacy) remember(visitorUUID, state string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.consent) >= maxConsentCache {
+		p.consent = make(map[string]cachedConsent)
+	}
+	p.consent[visitorUUID] = cachedConsent{state: state, expires: p.now().Add(consentCacheTTL)}
+}
+
+// Pseudonymize returns a keyed hash of ip as "<epoch>:<hex>". The key is a
+// random salt shared by all replicas for one SaltRotation period; when the
+// period ends the old salt is deleted, so earlier pseudonyms can no longer
+// be recomputed from an address by enumeration.
+func (p *Privacy) Pseudonymize(ip string) (string, error) {
+	if ip == "" {
+		return "", nil
+	}
+	epoch := p.now().Unix() / int64(p.config.SaltRotation/time.Second)
+	salt, err := p.saltFor(epoch)
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(ip))
+	return fmt.Sprintf("%d:%s", epoch, hex.EncodeToString(mac.Sum(nil)[:16])), nil
+}
+
+// saltFor returns the salt of an epoch, creating it and purging older ones
+// on rotation
+func (p *Privacy) saltFor(epoch int64) ([]byte, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.salt != nil && p.saltEpoch == epoch {
+		return p.salt, nil
+	}
+
+	candidate := make([]byte, ipSaltBytes)
+	if _, err := rand.Read(candidate); err != nil {
+		return nil, fmt.Errorf("generate ip salt: %w", err)
+	}
+	salt, err := p.store.IPSalt(epoch, candidate)
+	if err != nil {
+		return nil, fmt.Errorf("load ip salt: %w", err)
+	}
+	if n, err := p.store.PurgeIPSalts(epoch); err != nil {
+		log.Printf("[RUM] Failed to purge rotated IP salts: %v", err)
+	} else if n > 0 {
+		log.Printf("[RUM] Rotated IP salt, deleted %d old salts", n)
+	}
+	p.saltEpoch, p.salt = epoch, salt
+	return salt, nil
+}
+
+// ScrubEvent removes PII from an event's URL, text fields and metadata
+func (p *Privacy) ScrubEvent(event *RUMEvent) {
+	s := p.scrubber
+	event.PageURL = s.URL(event.PageURL)
+	event.PageTitle = s.Text(event.PageTitle)
+	event.ActionName = s.Text(event.ActionName)
+	event.ErrorMsg = s.Text(event.ErrorMsg)
+	s.Metadata(event.Metadata)
+}
+
+// ScrubError removes PII from an error report's page URL, message and stack
+func (p *Privacy) ScrubError(req *ErrorReport) {
+	req.PageURL = p.scrubber.URL(req.PageURL)
+	req.Message = p.scrubber.Text(req.Message)
+	req.Stack = p.scrubber.Stack(req.Stack)
+}
+
+// ScrubVitals removes PII from a vitals report's page and resource URLs
+func (p *Privacy) ScrubVitals(req *VitalsRequest) {
+	req.PageURL = p.scrubber.URL(req.PageURL)
+	for i := range req.Resources {
+		req.Resources[i].Name = p.scrubber.URL(req.Resources[i].Name)
+	}
+}
@@ -0,0 +1,344 @@
+package rum
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/operator"
+)
+
+type fakePrivacyStore struct {
+	mu      sync.Mutex
+	consent map[string]string
+	salts   map[int64][]byte
+	audit   []PrivacyAuditEntry
+	lookups int
+}
+
+func newFakePrivacyStore(visitors map[string]string) *fakePrivacyStore {
+	if visitors == nil {
+		visitors = map[string]string{}
+	}
+	return &fakePrivacyStore{consent: visitors, salts: map[int64][]byte{}}
+}
+
+func (f *fakePrivacyStore) VisitorConsent(uuid string) (string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	state, ok := f.consent[uuid]
+	return state, ok, nil
+}
+
+func (f *fakePrivacyStore) SetVisitorConsent(uuid, state string) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.consent[uuid]; !ok {
+		return false, nil
+	}
+	f.consent[uuid] = state
+	return true, nil
+}
+
+func (f *fakePrivacyStore) IPSalt(epoch int64, candidate []byte) ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.salts[epoch]; !ok {
+		f.salts[epoch] = candidate
+	}
+	return f.salts[epoch], nil
+}
+
+func (f *fakePrivacyStore) PurgeIPSalts(beforeEpoch int64) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var n int64
+	for epoch := range f.salts {
+		if epoch < beforeEpoch {
+			delete(f.salts, epoch)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (f *fakePrivacyStore) AppendPrivacyAudit(entry PrivacyAuditEntry) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.audit = append(f.audit, entry)
+	return nil
+}
+
+func TestScrubber_URL(t *testing.T) {
+	s := NewScrubber([]string{"Order-Ref"})
+
+	cases := []struct{ in, want string }{
+		{"https://shop.example.com/cart?item=42&utm_source=mail", "https://shop.example.com/cart?item=42&utm_source=mail"},
+		{"/reset?token=abc123&step=2", "/reset?token=%5BREDACTED%5D&step=2"},
+		{"/login?user_email=jane%40example.com&next=%2Fhome", "/login?user_email=%5BREDACTED%5D&next=%2Fhome"},
+		{"/search?q=jane@example.com", "/search?q=%5BEMAIL%5D"},
+		{"/users/jane@example.com/profile", "/users/[EMAIL]/profile"},
+		{"/callback#access_token=eyJhbGciOiJIUzI1NiJ9.eyJzdWIiOiIxIn0.c2ln&state=xyz", "/callback#access_token=%5BREDACTED%5D&state=%5BREDACTED%5D"},
+		{"/orders?order_ref=A-1&CSRF_Token=x&flag", "/orders?order_ref=%5BREDACTED%5D&CSRF_Token=%5BREDACTED%5D&flag"},
+		{"/docs#section-2", "/docs#section-2"},
+		{"", ""},
+	}
+	for _, tc := range cases {
+		if got := s.URL(tc.in); got != tc.want {
+			t.Errorf("%s:\n got  %s\n want %s", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestScrubber_TextAndMetadata(t *testing.T) {
+	s := NewScrubber(nil)
+
+	cases := []struct{ in, want string }{
+		{"paid with 4111 1111 1111 1111 today", "paid with [CARD] today"},
+		{"order 4111111111111112 shipped", "order 4111111111111112 shipped"}, // fails Luhn
+		{"at 1700000000000 ms", "at 1700000000000 ms"},                       // not a card prefix
+		{"client 203.0.113.7 retried", "client [IP] retried"},
+		{"Authorization: Bearer abc.def-123", "Authorization: [TOKEN]"},
+		{"contact jane.doe+rum@example.co.uk", "contact [EMAIL]"},
+		{"version 1.2.3", "version 1.2.3"},
+	}
+	for _, tc := range cases {
+		if got := s.Text(tc.in); got != tc.want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+
+	metadata := map[string]any{
+		"password":  "hunter2",
+		"apiKey":    "k",
+		"plan":      "pro",
+		"count":     3.0,
+		"referrer":  "https://example.com/?session_id=s1",
+		"form":      map[string]any{"phone": "+44 20 7946 0000", "comment": "mail me at a@b.io"},
+		"recipient": []any{"x@example.com", 1.0},
+
+		"backend_trace_id": "4111111111111111110",
+	}
+	s.Metadata(metadata)
+	if metadata["password"] != redacted || metadata["apiKey"] != redacted || metadata["plan"] != "pro" || metadata["count"] != 3.0 {
+		t.Fatalf("unexpected top-level metadata %+v", metadata)
+	}
+	if metadata["referrer"] != "https://example.com/?session_id=%5BREDACTED%5D" {
+		t.Fatalf("unexpected referrer %v", metadata["referrer"])
+	}
+	form := metadata["form"].(map[string]any)
+	if form["phone"] != redacted || form["comment"] != "mail me at [EMAIL]" {
+		t.Fatalf("unexpected nested metadata %+v", form)
+	}
+	if list := metadata["recipient"].([]any); list[0] != redactedEmail || list[1] != 1.0 {
+		t.Fatalf("unexpected list %+v", list)
+	}
+	if metadata["backend_trace_id"] != "4111111111111111110" {
+		t.Fatalf("expected trace ids untouched, got %v", metadata["backend_trace_id"])
+	}
+}
+
+func TestPrivacy_ScrubError(t *testing.T) {
+	privacy := NewPrivacy(newFakePrivacyStore(nil), DefaultPrivacyConfig())
+	req := ErrorReport{
+		Message: "TypeError: no account for jane@example.com",
+		Stack: "TypeError: no account for jane@example.com\n" +
+			"    at loadAccount (https://app.example.com/static/app.js?session_id=s3cr3t:10:15)\n" +
+			"    at https://app.example.com/users/jane@example.com/profile.js:4:2\n" +
+			"loadProfile@https://app.example.com/static/app.js?token=abc:20:7",
+	}
+	privacy.ScrubError(&req)
+
+	for _, leak := range []string{"jane@example.com", "s3cr3t", "token=abc"} {
+		if strings.Contains(req.Message+req.Stack, leak) {
+			t.Errorf("expected %q scrubbed, got %q", leak, req.Stack)
+		}
+	}
+
+	// Frames still parse, with their positions, from the scrubbed stack
+	frames := parseStack(req.Stack)
+	want := []StackFrame{
+		{Function: "loadAccount", File: "https://app.example.com/static/app.js?session_id=%5BREDACTED%5D", Line: 10, Column: 15},
+		{File: "https://app.example.com/users/[EMAIL]/profile.js", Line: 4, Column: 2},
+		{Function: "loadProfile", File: "https://app.example.com/static/app.js?token=%5BREDACTED%5D", Line: 20, Column: 7},
+	}
+	if len(frames) != len(want) {
+		t.Fatalf("expected %d frames, got %+v", len(want), frames)
+	}
+	for i, f := range frames {
+		if f.Function != want[i].Function || f.File != want[i].File || f.Line != want[i].Line || f.Column != want[i].Column {
+			t.Errorf("frame %d: got %+v, want %+v", i, f, want[i])
+		}
+	}
+}
+
+func TestPrivacy_Pseudonymize(t *testing.T) {
+	store := newFakePrivacyStore(nil)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	p := NewPrivacy(store, PrivacyConfig{SaltRotation: time.Hour})
+	p.now = func() time.Time { return now }
+
+	a, _ := p.Pseudonymize("203.0.113.7")
+	b, _ := p.Pseudonymize("203.0.113.7")
+	c, _ := p.Pseudonymize("203.0.113.8")
+	if a != b || a == c || !strings.HasPrefix(a, "492324:") || len(a) != len("492324:")+32 {
+		t.Fatalf("unexpected pseudonyms %q %q %q", a, b, c)
+	}
+	if empty, _ := p.Pseudonymize(""); empty != "" {
+		t.Fatalf("expected no pseudonym without an IP, got %q", empty)
+	}
+
+	// Replicas agree on the first salt written for an epoch
+	replica := NewPrivacy(store, PrivacyConfig{SaltRotation: time.Hour})
+	replica.now = p.now
+	if r, _ := replica.Pseudonymize("203.0.113.7"); r != a {
+		t.Fatalf("expected replicas to agree, got %q and %q", a, r)
+	}
+
+	// Rotation changes the pseudonym and deletes the old salt
+	now = now.Add(time.Hour)
+	rotated, _ := p.Pseudonymize("203.0.113.7")
+	if rotated == a || !strings.HasPrefix(rotated, "492325:") {
+		t.Fatalf("expected a new pseudonym after rotation, got %q", rotated)
+	}
+	if _, ok := store.salts[492324]; ok || len(store.salts) != 1 {
+		t.Fatalf("expected only the current salt to remain, got %d", len(store.salts))
+	}
+}
+
+func TestPrivacy_Consent(t *testing.T) {
+	store := newFakePrivacyStore(map[string]string{"v-granted": ConsentGranted, "v-denied": ConsentDenied, "v-unknown": ConsentUnknown})
+
+	optOut := NewPrivacy(store, DefaultPrivacyConfig())
+	optIn := NewPrivacy(store, PrivacyConfig{RequireConsent: true})
+	for _, tc := range []struct {
+		visitor       string
+		optOut, optIn bool
+	}{
+		{"v-granted", true, true},
+		{"v-denied", false, false},
+		{"v-unknown", true, false},
+		{"v-missing", true, false},
+		{"", true, false},
+	} {
+		if got := optOut.Allowed(tc.visitor); got != tc.optOut {
+			t.Errorf("%q without required consent: got %v", tc.visitor, got)
+		}
+		if got := optIn.Allowed(tc.visitor); got != tc.optIn {
+			t.Errorf("%q with required consent: got %v", tc.visitor, got)
+		}
+	}
+
+	// Lookups are cached; changes made here update the cache
+	lookups := store.lookups
+	optIn.Allowed("v-unknown")
+	if store.lookups != lookups {
+		t.Fatal("expected a cached consent state")
+	}
+	if found, err := optIn.SetConsent("v-unknown", ConsentGranted, "visitor"); !found || err != nil {
+		t.Fatalf("expected consent to be set, got %v %v", found, err)
+	}
+	if !optIn.Allowed("v-unknown") || store.lookups != lookups {
+		t.Fatal("expected the new consent state from the cache")
+	}
+	if len(store.audit) != 1 || store.audit[0].Event != PrivacyAuditConsent || store.audit[0].Detail != ConsentGranted {
+		t.Fatalf("expected a consent audit entry, got %+v", store.audit)
+	}
+	if found, _ := optIn.SetConsent("v-missing", ConsentGranted, "visitor"); found || len(store.audit) != 1 {
+		t.Fatal("expected no consent or audit entry for an unknown visitor")
+	}
+
+	optIn.Forget("v-unknown")
+	optIn.Allowed("v-unknown")
+	if store.lookups != lookups+1 {
+		t.Fatal("expected a lookup after Forget")
+	}
+}
+
+func TestHandler_Privacy(t *testing.T) {
+	store := newFakePrivacyStore(map[string]string{"v-denied": ConsentDenied})
+	handler := NewHandler(&Storage{})
+	handler.SetPrivacy(NewPrivacy(store, DefaultPrivacyConfig()))
+
+	call := func(method, target, body string, h func(http.ResponseWriter, *http.Request) (int, any)) (int, any) {
+		return h(httptest.NewRecorder(), httptest.NewRequest(method, target, strings.NewReader(body)))
+	}
+	withUUID := func(h func(http.ResponseWriter, *http.Request, string) (int, any)) func(http.ResponseWriter, *http.Request) (int, any) {
+		return func(w http.ResponseWriter, r *http.Request) (int, any) { return h(w, r, "v-denied") }
+	}
+
+	// Export, erasure and the audit need an operator token, whatever the
+	// query claims; auth and validation happen before the database is touched
+	handler.SetOperators(operator.NewAuthenticator(map[string]string{"alice": "a-token"}))
+	asOperator := func(token string, h func(http.ResponseWriter, *http.Request) (int, any)) func(http.ResponseWriter, *http.Request) (int, any) {
+		return func(w http.ResponseWriter, r *http.Request) (int, any) {
+			r.Header.Set("Authorization", "Bearer "+token)
+			return h(w, r)
+		}
+	}
+	for name, tt := range map[string]struct {
+		status, want int
+	}{
+		"erase without token":   {first(call("DELETE", "/v1/rum/visitor/v-denied?actor=operator:alice", "", withUUID(handler.DeleteVisitor))), http.StatusUnauthorized},
+		"export without token":  {first(call("GET", "/v1/rum/visitor/v-denied/export?actor=operator:alice", "", withUUID(handler.ExportVisitor))), http.StatusUnauthorized},
+		"audit without token":   {first(call("GET", "/v1/rum/privacy/audit", "", handler.GetPrivacyAudit)), http.StatusUnauthorized},
+		"erase with bad token":  {first(call("DELETE", "/v1/rum/visitor/v-denied", "", asOperator("forged", withUUID(handler.DeleteVisitor)))), http.StatusUnauthorized},
+		"invalid audit limit":   {first(call("GET", "/v1/rum/privacy/audit?limit=5000", "", asOperator("a-token", handler.GetPrivacyAudit))), http.StatusBadRequest},
+		"invalid consent state": {first(call("POST", "/v1/rum/visitor/v-denied/consent", `{"state":"maybe"}`, withUUID(handler.UpdateConsent))), http.StatusBadRequest},
+		"invalid init consent":  {first(call("POST", "/v1/rum/init", `{"consent":"yes"}`, handler.InitVisitor)), http.StatusBadRequest},
+	} {
+		if tt.status != tt.want {
+			t.Errorf("%s: expected %d, got %d", name, tt.want, tt.status)
+		}
+	}
+
+	// Data from a visitor who denied consent is accepted but not stored
+	status, resp := call("POST", "/v1/rum/events", `{"visitor_uuid":"v-denied","session_id":"s1","event_type":"view"}`, handler.TrackEvent)
+	if status != http.StatusAccepted || !strings.Contains(resp.(TrackEventResponse).Status, "dropped") {
+		t.Fatalf("expected the event to be dropped, got %d %+v", status, resp)
+	}
+	status, resp = call("POST", "/v1/rum/batch", `{"visitor_uuid":"v-denied","session_id":"s1","events":[
+		{"event_type":"view"},{"event_type":"bogus"}]}`, handler.TrackBatch)
+	batch := resp.(BatchEventResponse)
+	if status != http.StatusAccepted || batch.Accepted != 0 || batch.Dropped != 1 || len(batch.Rejected) != 1 {
+		t.Fatalf("expected one dropped and one rejected event, got %d %+v", status, batch)
+	}
+	if status, _ := call("POST", "/v1/rum/vitals", `{"visitor_uuid":"v-denied","session_id":"s1","page_url":"/","lcp":1200}`, handler.TrackVitals); status != http.StatusAccepted {
+		t.Fatalf("expected vitals to be dropped with 202, got %d", status)
+	}
+}
+
+func first(status int, _ any) int {
+	return status
+}
+
+func TestRecorder_DeleteVisitor(t *testing.T) {
+	recorder, index, dir := newTestRecorder(t, DefaultReplayConfig())
+	for _, c := range []struct{ session, visitor string }{{"s1", "v1"}, {"s2", "v1"}, {"s3", "v2"}} {
+		if _, err := recorder.Record(c.session, c.visitor, rawEvents(snapshotEvent)); err != nil {
+			t.Fatalf("record: %v", err)
+		}
+	}
+
+	n, err := recorder.DeleteVisitor("v1")
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 chunks deleted, got %d (%v)", n, err)
+	}
+	if len(index.chunks) != 1 || index.chunks[0].VisitorUUID != "v2" {
+		t.Fatalf("expected only v2's chunk to remain, got %+v", index.chunks)
+	}
+	for _, session := range []string{"s1", "s2"} {
+		if _, err := os.Stat(filepath.Join(dir, session)); !os.IsNotExist(err) {
+			t.Errorf("expected %s's replay data to be deleted, got %v", session, err)
+		}
+	}
+	if n, err := recorder.DeleteVisitor("v1"); n != 0 || err != nil {
+		t.Fatalf("expected a repeated erasure to be a no-op, got %d %v", n, err)
+	}
+}
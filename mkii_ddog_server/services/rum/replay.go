@@ -0,0 +1,485 @@
+package rum
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Replay limits
+const (
+	maxReplayChunkBytes    = 5 << 20  // one upload; full snapshots of large pages are big
+	maxReplayChunkEvents   = 5000     // events per upload
+	maxReplayPlaybackBytes = 64 << 20 // decompressed events served per playback
+	maxMaskStates          = 10000    // sessions whose masked nodes are remembered
+	maskStateIdle          = time.Hour
+	replayPurgeBatch       = 500
+)
+
+// ErrInvalidReplay is returned by Record for malformed chunks
+var ErrInvalidReplay = errors.New("invalid replay chunk")
+
+// ErrReplayTooLarge is returned by Load when a session's replay exceeds
+// maxReplayPlaybackBytes
+var ErrReplayTooLarge = errors.New("replay too large to play back")
+
+// replaySessionID restricts session ids used in storage keys
+var replaySessionID = regexp.MustCompile(`^[A-Za-z0-9_-]{1,64}$`)
+
+// ReplayConfig holds configuration for session replay
+type ReplayConfig struct {
+	// Dir stores chunks on the filesystem; empty stores them in Postgres
+	// Default: "" (Postgres)
+	Dir string
+
+	// Retention is how long chunks are kept
+	// Default: 30 days
+	Retention time.Duration
+
+	// PurgeInterval is how often expired chunks are deleted
+	// Default: 1h
+	PurgeInterval time.Duration
+
+	// MaskAllInputs masks every input, textarea and select value; password
+	// inputs are always masked
+	// Default: true
+	MaskAllInputs bool
+
+	// MaskAllText masks all page text, not just MaskSelectors
+	// Default: false
+	MaskAllText bool
+
+	// MaskSelectors mask the text of matching elements and their children
+	// Default: .rr-mask, [data-rum-mask]
+	MaskSelectors []string
+
+	// BlockSelectors drop the content of matching elements entirely
+	// Default: .rr-block, [data-rum-block]
+	BlockSelectors []string
+}
+
+// DefaultReplayConfig returns sensible defaults
+func DefaultReplayConfig() ReplayConfig {
+	return ReplayConfig{
+		Retention:      30 * 24 * time.Hour,
+		PurgeInterval:  time.Hour,
+		MaskAllInputs:  true,
+		MaskSelectors:  []string{".rr-mask", "[data-rum-mask]"},
+		BlockSelectors: []string{".rr-block", "[data-rum-block]"},
+	}
+}
+
+// ReplayConfigFromEnv returns the defaults overridden by RUM_REPLAY_DIR,
+// RUM_REPLAY_RETENTION, RUM_REPLAY_PURGE_INTERVAL, RUM_REPLAY_MASK_ALL_INPUTS,
+// RUM_REPLAY_MASK_ALL_TEXT, RUM_REPLAY_MASK_SELECTORS and
+// RUM_REPLAY_BLOCK_SELECTORS (comma-separated, added to the defaults)
+func ReplayConfigFromEnv() ReplayConfig {
+	config := DefaultReplayConfig()
+	config.Dir = os.Getenv("RUM_REPLAY_DIR")
+	if v, err := time.ParseDuration(os.Getenv("RUM_REPLAY_RETENTION")); err == nil && v > 0 {
+		config.Retention = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RUM_REPLAY_PURGE_INTERVAL")); err == nil && v > 0 {
+		config.PurgeInterval = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("RUM_REPLAY_MASK_ALL_INPUTS")); err == nil {
+		config.MaskAllInputs = v
+	}
+	if v, err := strconv.ParseBool(os.Getenv("RUM_REPLAY_MASK_ALL_TEXT")); err == nil {
+		config.MaskAllText = v
+	}
+	if v := os.Getenv("RUM_REPLAY_MASK_SELECTORS"); v != "" {
+		config.MaskSelectors = append(config.MaskSelectors, strings.Split(v, ",")...)
+	}
+	if v := os.Getenv("RUM_REPLAY_BLOCK_SELECTORS"); v != "" {
+		config.BlockSelectors = append(config.BlockSelectors, strings.Split(v, ",")...)
+	}
+	return config
+}
+
+// ReplayStore holds compressed replay chunks by key; implemented by
+// *FileReplayStore and *PostgresReplayStore
+type ReplayStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+// ReplayIndex records chunk metadata; implemented by *Storage
+type ReplayIndex interface {
+	AddReplayChunk(chunk *ReplayChunk) error
+	GetReplayChunks(sessionID string) ([]ReplayChunk, error)
+	GetExpiredReplayChunks(before time.Time, limit int) ([]ReplayChunk, error)
+	GetVisitorReplayChunks(visitorUUID string) ([]ReplayChunk, error)
+	DeleteReplayChunks(ids []int64) error
+}
+
+// FileReplayStore keeps chunks as files under a directory
+type FileReplayStore struct {
+	dir string
+}
+
+// NewFileReplayStore creates a store rooted at dir, creating it if needed
+func NewFileReplayStore(dir string) (*FileReplayStore, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &FileReplayStore{dir: dir}, nil
+}
+
+// Put writes a chunk atomically
+func (s *FileReplayStore) Put(key string, data []byte) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Get reads a chunk
+func (s *FileReplayStore) Get(key string) ([]byte, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// Delete removes a chunk and, once empty, its session directory
+func (s *FileReplayStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	os.Remove(filepath.Dir(path)) // fails while other chunks remain
+	return nil
+}
+
+// path resolves a key inside the store directory
+func (s *FileReplayStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if !strings.HasPrefix(path, filepath.Clean(s.dir)+string(filepath.Separator)) {
+		return "", fmt.Errorf("invalid replay key %q", key)
+	}
+	return path, nil
+}
+
+// Recorder masks, compresses and stores session replay chunks, serves them
+// for playback and deletes them after the retention period
+type Recorder struct {
+	index  ReplayIndex
+	store  ReplayStore
+	config ReplayConfig
+	masker *masker
+	now    func() time.Time
+
+	mu     sync.Mutex
+	states map[string]*sessionMask
+}
+
+// sessionMask is a session's mask state and when it was last used; mu
+// serializes chunks of the same session
+type sessionMask struct {
+	mu       sync.Mutex
+	state    *maskState
+	lastUsed time.Time
+}
+
+// NewRecorder creates a replay recorder
+func NewRecorder(index ReplayIndex, store ReplayStore, config ReplayConfig) *Recorder {
+	defaults := DefaultReplayConfig()
+	if config.Retention <= 0 {
+		config.Retention = defaults.Retention
+	}
+	if config.PurgeInterval <= 0 {
+		config.PurgeInterval = defaults.PurgeInterval
+	}
+	return &Recorder{
+		index:  index,
+		store:  store,
+		config: config,
+		masker: newMasker(config),
+		now:    time.Now,
+		states: make(map[string]*sessionMask),
+	}
+}
+
+// Config returns the recorder's configuration
+func (r *Recorder) Config() ReplayConfig {
+	return r.config
+}
+
+// Record masks a chunk of rrweb events and stores it compressed. Events are
+// validated and masked before anything is written.
+func (r *Recorder) Record(sessionID, visitorUUID string, events []json.RawMessage) (*ReplayChunk, error) {
+	if !replaySessionID.MatchString(sessionID) || visitorUUID == "" {
+		return nil, fmt.Errorf("%w: valid session id and visitor_uuid are required", ErrInvalidReplay)
+	}
+	if len(events) == 0 || len(events) > maxReplayChunkEvents {
+		return nil, fmt.Errorf("%w: between 1 and %d events per chunk", ErrInvalidReplay, maxReplayChunkEvents)
+	}
+
+	masked, start, end, err := r.mask(sessionID, events)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := json.Marshal(masked)
+	if err != nil {
+		return nil, err
+	}
+	var compressed bytes.Buffer
+	zw := gzip.NewWriter(&compressed)
+	if _, err := zw.Write(raw); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+
+	chunk := &ReplayChunk{
+		SessionID:   sessionID,
+		VisitorUUID: visitorUUID,
+		StartTime:   start,
+		EndTime:     end,
+		EventCount:  len(masked),
+		RawBytes:    len(raw),
+		StoredBytes: compressed.Len(),
+		StorageKey:  sessionID + "/" + uuid.New().String() + ".json.gz",
+	}
+	if err := r.store.Put(chunk.StorageKey, compressed.Bytes()); err != nil {
+		return nil, fmt.Errorf("store chunk: %w", err)
+	}
+	if err := r.index.AddReplayChunk(chunk); err != nil {
+		r.store.Delete(chunk.StorageKey)
+		return nil, err
+	}
+	return chunk, nil
+}
+
+// mask decodes and masks events with the session's mask state, returning
+// them with their time range
+func (r *Recorder) mask(sessionID string, events []json.RawMessage) ([]map[string]any, time.Time, time.Time, error) {
+	decoded := make([]map[string]any, len(events))
+	var first, last int64
+	for i, raw := range events {
+		dec := json.NewDecoder(bytes.NewReader(raw))
+		dec.UseNumber()
+		if err := dec.Decode(&decoded[i]); err != nil || decoded[i] == nil {
+			return nil, time.Time{}, time.Time{}, fmt.Errorf("%w: event %d is not an object", ErrInvalidReplay, i)
+		}
+		ts, ok := jsonInt(decoded[i]["timestamp"])
+		if _, typed := jsonInt(decoded[i]["type"]); !ok || !typed || ts <= 0 {
+			return nil, time.Time{}, time.Time{}, fmt.Errorf("%w: event %d needs a numeric type and timestamp", ErrInvalidReplay, i)
+		}
+		if first == 0 || ts < first {
+			first = ts
+		}
+		if ts > last {
+			last = ts
+		}
+	}
+
+	session := r.sessionMask(sessionID)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, event := range decoded {
+		r.masker.maskEvent(event, session.state)
+	}
+	return decoded, time.UnixMilli(first), time.UnixMilli(last), nil
+}
+
+// sessionMask returns the session's mask state, evicting idle sessions when
+// the map is full
+func (r *Recorder) sessionMask(sessionID string) *sessionMask {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+	if s, ok := r.states[sessionID]; ok {
+		s.lastUsed = now
+		return s
+	}
+	if len(r.states) >= maxMaskStates {
+		for id, s := range r.states {
+			if now.Sub(s.lastUsed) > maskStateIdle {
+				delete(r.states, id)
+			}
+		}
+	}
+	s := &sessionMask{state: newMaskState(), lastUsed: now}
+	if len(r.states) < maxMaskStates {
+		r.states[sessionID] = s
+	}
+	return s
+}
+
+// Load returns a session's chunks and their events in playback order
+func (r *Recorder) Load(sessionID string) ([]ReplayChunk, []json.RawMessage, error) {
+	chunks, err := r.index.GetReplayChunks(sessionID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	total := 0
+	for _, chunk := range chunks {
+		total += chunk.RawBytes
+	}
+	if total > maxReplayPlaybackBytes {
+		return chunks, nil, ErrReplayTooLarge
+	}
+
+	events := []json.RawMessage{}
+	for _, chunk := range chunks {
+		data, err := r.store.Get(chunk.StorageKey)
+		if err != nil {
+			return nil, nil, fmt.Errorf("read chunk %d: %w", chunk.ID, err)
+		}
+		zr, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read chunk %d: %w", chunk.ID, err)
+		}
+		raw, err := io.ReadAll(io.LimitReader(zr, maxReplayPlaybackBytes))
+		if err != nil {
+			return nil, nil, fmt.Errorf("read chunk %d: %w", chunk.ID, err)
+		}
+		var chunkEvents []json.RawMessage
+		if err := json.Unmarshal(raw, &chunkEvents); err != nil {
+			return nil, nil, fmt.Errorf("read chunk %d: %w", chunk.ID, err)
+		}
+		events = append(events, chunkEvents...)
+	}
+	return chunks, events, nil
+}
+
+// Summarize totals a session's chunks; nil when the session has no replay
+func Summarize(chunks []ReplayChunk) *ReplaySummary {
+	if len(chunks) == 0 {
+		return nil
+	}
+	summary := &ReplaySummary{Chunks: len(chunks), StartTime: chunks[0].StartTime, EndTime: chunks[0].EndTime}
+	for _, chunk := range chunks {
+		summary.EventCount += chunk.EventCount
+		if chunk.StartTime.Before(summary.StartTime) {
+			summary.StartTime = chunk.StartTime
+		}
+		if chunk.EndTime.After(summary.EndTime) {
+			summary.EndTime = chunk.EndTime
+		}
+	}
+	summary.DurationMs = summary.EndTime.Sub(summary.StartTime).Milliseconds()
+	return summary
+}
+
+// Summary returns a session's replay summary, or nil without a replay
+func (r *Recorder) Summary(sessionID string) (*ReplaySummary, error) {
+	chunks, err := r.index.GetReplayChunks(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return Summarize(chunks), nil
+}
+
+// Run deletes expired chunks every PurgeInterval until ctx is cancelled
+func (r *Recorder) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.PurgeInterval)
+	defer ticker.Stop()
+	for {
+		if n, err := r.Purge(ctx); err != nil {
+			log.Printf("[RUM] Replay purge failed: %v", err)
+		} else if n > 0 {
+			log.Printf("[RUM] Purged %d replay chunks older than %s", n, r.config.Retention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Purge deletes chunks that ended before the retention period. Chunks whose
+// data can't be deleted stay indexed and are retried on the next purge.
+func (r *Recorder) Purge(ctx context.Context) (int, error) {
+	cutoff := r.now().Add(-r.config.Retention)
+	purged := 0
+	for ctx.Err() == nil {
+		chunks, err := r.index.GetExpiredReplayChunks(cutoff, replayPurgeBatch)
+		if err != nil || len(chunks) == 0 {
+			return purged, err
+		}
+
+		var ids []int64
+		for _, chunk := range chunks {
+			if err := r.store.Delete(chunk.StorageKey); err != nil {
+				log.Printf("[RUM] Failed to delete replay chunk %d: %v", chunk.ID, err)
+				continue
+			}
+			ids = append(ids, chunk.ID)
+		}
+		if len(ids) == 0 {
+			return purged, errors.New("no expired chunks could be deleted")
+		}
+		if err := r.index.DeleteReplayChunks(ids); err != nil {
+			return purged, err
+		}
+		purged += len(ids)
+		if len(chunks) < replayPurgeBatch {
+			break
+		}
+	}
+	return purged, ctx.Err()
+}
+
+// DeleteVisitor deletes all of a visitor's replay chunks, for erasure
+// requests. Unlike Purge it stops at the first chunk whose data can't be
+// deleted, leaving the index intact so the erasure can be retried.
+func (r *Recorder) DeleteVisitor(visitorUUID string) (int, error) {
+	chunks, err := r.index.GetVisitorReplayChunks(visitorUUID)
+	if err != nil || len(chunks) == 0 {
+		return 0, err
+	}
+
+	ids := make([]int64, 0, len(chunks))
+	for _, chunk := range chunks {
+		if err := r.store.Delete(chunk.StorageKey); err != nil {
+			return 0, fmt.Errorf("delete replay chunk %d: %w", chunk.ID, err)
+		}
+		ids = append(ids, chunk.ID)
+	}
+	if err := r.index.DeleteReplayChunks(ids); err != nil {
+		return 0, err
+	}
+
+	r.mu.Lock()
+	for _, chunk := range chunks {
+		delete(r.states, chunk.SessionID)
+	}
+	r.mu.Unlock()
+	return len(ids), nil
+}
@@ -0,0 +1,262 @@
+package rum
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeReplayIndex struct {
+	mu     sync.Mutex
+	chunks []ReplayChunk
+	nextID int64
+}
+
+func (f *fakeReplayIndex) AddReplayChunk(chunk *ReplayChunk) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextID++
+	chunk.ID, chunk.CreatedAt = f.nextID, time.Now()
+	f.chunks = append(f.chunks, *chunk)
+	return nil
+}
+
+func (f *fakeReplayIndex) GetReplayChunks(sessionID string) ([]ReplayChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var chunks []ReplayChunk
+	for _, c := range f.chunks {
+		if c.SessionID == sessionID {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+func (f *fakeReplayIndex) GetExpiredReplayChunks(before time.Time, limit int) ([]ReplayChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var chunks []ReplayChunk
+	for _, c := range f.chunks {
+		if c.EndTime.Before(before) && len(chunks) < limit {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+func (f *fakeReplayIndex) GetVisitorReplayChunks(visitorUUID string) ([]ReplayChunk, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var chunks []ReplayChunk
+	for _, c := range f.chunks {
+		if c.VisitorUUID == visitorUUID {
+			chunks = append(chunks, c)
+		}
+	}
+	return chunks, nil
+}
+
+func (f *fakeReplayIndex) DeleteReplayChunks(ids []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	kept := f.chunks[:0]
+	for _, c := range f.chunks {
+		if !containsID(ids, c.ID) {
+			kept = append(kept, c)
+		}
+	}
+	f.chunks = kept
+	return nil
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshotEvent is a full snapshot with a password input, a masked element,
+// a blocked element and plain text
+const snapshotEvent = `{"type":2,"timestamp":1700000000000,"data":{"node":{"type":0,"id":1,"childNodes":[
+	{"type":2,"id":2,"tagName":"body","attributes":{},"childNodes":[
+		{"type":2,"id":3,"tagName":"input","attributes":{"type":"password","value":"hunter2"},"childNodes":[]},
+		{"type":2,"id":4,"tagName":"div","attributes":{"class":"card rr-mask"},"childNodes":[{"type":3,"id":5,"textContent":"4111 1111"}]},
+		{"type":2,"id":6,"tagName":"section","attributes":{"data-rum-block":""},"childNodes":[{"type":3,"id":7,"textContent":"secret"}]},
+		{"type":3,"id":8,"textContent":"Welcome back"}
+	]}]}}}`
+
+func rawEvents(events ...string) []json.RawMessage {
+	raw := make([]json.RawMessage, len(events))
+	for i, e := range events {
+		raw[i] = json.RawMessage(e)
+	}
+	return raw
+}
+
+func newTestRecorder(t *testing.T, config ReplayConfig) (*Recorder, *fakeReplayIndex, string) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := NewFileReplayStore(dir)
+	if err != nil {
+		t.Fatalf("store: %v", err)
+	}
+	index := &fakeReplayIndex{}
+	return NewRecorder(index, store, config), index, dir
+}
+
+func TestRecorder_MasksAndRoundTrips(t *testing.T) {
+	recorder, _, _ := newTestRecorder(t, DefaultReplayConfig())
+
+	chunk, err := recorder.Record("s1", "v1", rawEvents(snapshotEvent,
+		`{"type":3,"timestamp":1700000001000,"data":{"source":5,"id":9,"text":"jane@example.com"}}`))
+	if err != nil {
+		t.Fatalf("record: %v", err)
+	}
+	if chunk.EventCount != 2 || chunk.StoredBytes == 0 || chunk.EndTime.Sub(chunk.StartTime) != time.Second {
+		t.Fatalf("unexpected chunk %+v", chunk)
+	}
+
+	// Mutations in a later chunk are masked using nodes from the snapshot
+	if _, err := recorder.Record("s1", "v1", rawEvents(
+		`{"type":3,"timestamp":1700000002000,"data":{"source":0,"texts":[{"id":5,"value":"4242 4242"},{"id":8,"value":"Hello"}],
+			"attributes":[{"id":3,"attributes":{"value":"letmein"}}],
+			"adds":[{"parentId":6,"node":{"type":3,"id":10,"textContent":"leak"}},{"parentId":4,"node":{"type":3,"id":11,"textContent":"Visa"}}],"removes":[]}}`)); err != nil {
+		t.Fatalf("record: %v", err)
+	}
+
+	chunks, events, err := recorder.Load("s1")
+	if err != nil {
+		t.Fatalf("load: %v", err)
+	}
+	if len(chunks) != 2 || len(events) != 3 {
+		t.Fatalf("expected 2 chunks and 3 events, got %d and %d", len(chunks), len(events))
+	}
+
+	replay := string(events[0]) + string(events[1]) + string(events[2])
+	for _, leaked := range []string{"hunter2", "4111", "secret", "jane@example.com", "4242", "letmein", "leak", "Visa"} {
+		if strings.Contains(replay, leaked) {
+			t.Errorf("expected %q masked, replay: %s", leaked, replay)
+		}
+	}
+	for _, kept := range []string{"Welcome back", "Hello", `"rr_block":"true"`, `"timestamp":1700000000000`} {
+		if !strings.Contains(replay, kept) {
+			t.Errorf("expected %s kept, replay: %s", kept, replay)
+		}
+	}
+	if !strings.Contains(replay, `"textContent":"**** ****"`) {
+		t.Errorf("expected masked text to keep its shape, replay: %s", replay)
+	}
+}
+
+func TestRecorder_MaskAllText(t *testing.T) {
+	config := DefaultReplayConfig()
+	config.MaskAllText = true
+	config.MaskAllInputs = false
+	recorder, _, _ := newTestRecorder(t, config)
+
+	recorder.Record("s1", "v1", rawEvents(snapshotEvent,
+		`{"type":3,"timestamp":1700000001000,"data":{"source":5,"id":9,"text":"search term"}}`))
+	_, events, _ := recorder.Load("s1")
+	replay := string(events[0]) + string(events[1])
+	if strings.Contains(replay, "Welcome") || strings.Contains(replay, "hunter2") {
+		t.Fatalf("expected all text and passwords masked, replay: %s", replay)
+	}
+	if !strings.Contains(replay, "search term") {
+		t.Fatalf("expected non-password input kept with MaskAllInputs off, replay: %s", replay)
+	}
+}
+
+func TestRecorder_Validation(t *testing.T) {
+	recorder, index, _ := newTestRecorder(t, DefaultReplayConfig())
+
+	tests := map[string]struct {
+		session string
+		events  []json.RawMessage
+	}{
+		"path traversal":  {"../etc", rawEvents(snapshotEvent)},
+		"no events":       {"s1", nil},
+		"not an object":   {"s1", rawEvents(`[1,2]`)},
+		"missing time":    {"s1", rawEvents(`{"type":2,"data":{}}`)},
+		"non-number type": {"s1", rawEvents(`{"type":"full","timestamp":1}`)},
+	}
+	for name, tt := range tests {
+		if _, err := recorder.Record(tt.session, "v1", tt.events); !errors.Is(err, ErrInvalidReplay) {
+			t.Errorf("%s: expected ErrInvalidReplay, got %v", name, err)
+		}
+	}
+	if len(index.chunks) != 0 {
+		t.Fatalf("expected nothing stored, got %+v", index.chunks)
+	}
+}
+
+func TestRecorder_Purge(t *testing.T) {
+	config := DefaultReplayConfig()
+	config.Retention = 24 * time.Hour
+	recorder, index, dir := newTestRecorder(t, config)
+	recorder.now = func() time.Time { return time.UnixMilli(1700000000000).Add(48 * time.Hour) }
+
+	recorder.Record("old", "v1", rawEvents(snapshotEvent))
+	recorder.Record("new", "v1", rawEvents(`{"type":4,"timestamp":1700100000000,"data":{"href":"/"}}`))
+
+	n, err := recorder.Purge(context.Background())
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 chunk purged, got %d (%v)", n, err)
+	}
+	if len(index.chunks) != 1 || index.chunks[0].SessionID != "new" {
+		t.Fatalf("expected only the recent chunk kept, got %+v", index.chunks)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "old")); !os.IsNotExist(err) {
+		t.Fatalf("expected the purged session's directory removed, got %v", err)
+	}
+}
+
+func TestFileReplayStore_RejectsEscapingKeys(t *testing.T) {
+	store, _ := NewFileReplayStore(t.TempDir())
+	if err := store.Put("../outside.json.gz", []byte("x")); err == nil {
+		t.Fatal("expected a key outside the directory rejected")
+	}
+}
+
+func TestHandler_Replay(t *testing.T) {
+	handler := NewHandler(&Storage{})
+	handler.SetPrivacy(NewPrivacy(newFakePrivacyStore(nil), DefaultPrivacyConfig()))
+	upload := func(body string) int {
+		status, _ := handler.UploadReplay(httptest.NewRecorder(),
+			httptest.NewRequest(http.MethodPost, "/v1/rum/session/s1/replay", strings.NewReader(body)), "s1")
+		return status
+	}
+
+	if status := upload(`{"visitor_uuid":"v1","events":[]}`); status != http.StatusNotFound {
+		t.Fatalf("expected 404 with replay disabled, got %d", status)
+	}
+
+	recorder, _, _ := newTestRecorder(t, DefaultReplayConfig())
+	handler.SetRecorder(recorder)
+	if status := upload(`{"visitor_uuid":"v1","events":[]}`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty chunk, got %d", status)
+	}
+	if status := upload(`{"visitor_uuid":"v1","events":[` + snapshotEvent + `]}`); status != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", status)
+	}
+
+	status, result := handler.GetReplay(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/rum/session/s1/replay", nil), "s1")
+	replay, ok := result.(ReplayResponse)
+	if status != http.StatusOK || !ok || len(replay.Events) != 1 || replay.Summary.Chunks != 1 {
+		t.Fatalf("unexpected replay %d %+v", status, result)
+	}
+	if status, _ := handler.GetReplay(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/rum/session/s2/replay", nil), "s2"); status != http.StatusNotFound {
+		t.Fatalf("expected 404 for a session without replay, got %d", status)
+	}
+}
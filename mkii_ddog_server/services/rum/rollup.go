@@ -0,0 +1,339 @@
+package rum
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Rollup grains
+const (
+	GrainHour = "hour"
+	GrainDay  = "day"
+)
+
+// Rollup limits
+const (
+	maxRollupHourBuckets = 7 * 24 // buckets rebuilt per statement
+	maxRollupDayBuckets  = 31
+	maxRollupSeriesHours = 31 * 24 // points served per hourly series
+	maxRollupSeriesDays  = 366
+	rollupPurgeBatch     = 5000
+)
+
+// Raw tables with a retention period, purged in this order
+const (
+	TableEvents           = "rum_events"
+	TableVitals           = "rum_vitals"
+	TableErrorOccurrences = "rum_error_occurrences"
+	TableSessions         = "rum_sessions"
+)
+
+// RollupConfig holds configuration for the rollup and retention job
+type RollupConfig struct {
+	// Interval is how often rollups are rebuilt and expired rows purged
+	// Default: 15m
+	Interval time.Duration
+
+	// Lookback is how far before the watermark buckets are rebuilt, so
+	// late events and sessions ended after the last run are counted
+	// Default: 2h
+	Lookback time.Duration
+
+	// AnalyticsRange is the shortest GetAnalytics range served from daily
+	// rollups instead of raw tables; 0 always reads raw tables
+	// Default: 7 days
+	AnalyticsRange time.Duration
+
+	// Retention of raw tables; 0 keeps rows forever. Sessions with replay
+	// chunks are kept until the replay expires.
+	// Default: events, vitals and error occurrences 90 days, sessions 395 days
+	EventsRetention           time.Duration
+	VitalsRetention           time.Duration
+	ErrorOccurrencesRetention time.Duration
+	SessionsRetention         time.Duration
+
+	// Retention of the rollups themselves; 0 keeps them forever
+	// Default: hourly 90 days, daily forever
+	HourlyRetention time.Duration
+	DailyRetention  time.Duration
+}
+
+// DefaultRollupConfig returns sensible defaults
+func DefaultRollupConfig() RollupConfig {
+	day := 24 * time.Hour
+	return RollupConfig{
+		Interval:                  15 * time.Minute,
+		Lookback:                  2 * time.Hour,
+		AnalyticsRange:            7 * day,
+		EventsRetention:           90 * day,
+		VitalsRetention:           90 * day,
+		ErrorOccurrencesRetention: 90 * day,
+		SessionsRetention:         395 * day,
+		HourlyRetention:           90 * day,
+	}
+}
+
+// RollupConfigFromEnv returns the defaults overridden by RUM_ROLLUP_INTERVAL,
+// RUM_ROLLUP_LOOKBACK and RUM_ROLLUP_ANALYTICS_RANGE (durations) and
+// RUM_RETENTION_EVENTS_DAYS, RUM_RETENTION_VITALS_DAYS,
+// RUM_RETENTION_ERRORS_DAYS, RUM_RETENTION_SESSIONS_DAYS,
+// RUM_RETENTION_HOURLY_DAYS and RUM_RETENTION_DAILY_DAYS (0 keeps forever)
+func RollupConfigFromEnv() RollupConfig {
+	config := DefaultRollupConfig()
+	if v, err := time.ParseDuration(os.Getenv("RUM_ROLLUP_INTERVAL")); err == nil && v > 0 {
+		config.Interval = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RUM_ROLLUP_LOOKBACK")); err == nil && v >= 0 {
+		config.Lookback = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RUM_ROLLUP_ANALYTICS_RANGE")); err == nil && v >= 0 {
+		config.AnalyticsRange = v
+	}
+
+	days := map[string]*time.Duration{
+		"RUM_RETENTION_EVENTS_DAYS":   &config.EventsRetention,
+		"RUM_RETENTION_VITALS_DAYS":   &config.VitalsRetention,
+		"RUM_RETENTION_ERRORS_DAYS":   &config.ErrorOccurrencesRetention,
+		"RUM_RETENTION_SESSIONS_DAYS": &config.SessionsRetention,
+		"RUM_RETENTION_HOURLY_DAYS":   &config.HourlyRetention,
+		"RUM_RETENTION_DAILY_DAYS":    &config.DailyRetention,
+	}
+	for env, field := range days {
+		if v, err := strconv.Atoi(os.Getenv(env)); err == nil && v >= 0 {
+			*field = time.Duration(v) * 24 * time.Hour
+		}
+	}
+	return config
+}
+
+// RollupStore builds and purges rollups; implemented by *Storage
+type RollupStore interface {
+	// RollupWatermark returns the end of the last complete bucket rolled up
+	// for a grain, or the zero time
+	RollupWatermark(grain string) (time.Time, error)
+	// EarliestRawData returns the oldest event or session time, or the zero time
+	EarliestRawData() (time.Time, error)
+	// BuildRollups replaces a grain's buckets in [from, to) and advances its
+	// watermark to rolledUntil
+	BuildRollups(grain string, from, to, rolledUntil time.Time) error
+	PurgeRaw(table string, before time.Time, limit int) (int64, error)
+	PurgeRollups(grain string, before time.Time, limit int) (int64, error)
+}
+
+// RollupJob periodically rolls raw events up into hourly and daily buckets
+// and enforces retention
+type RollupJob struct {
+	store  RollupStore
+	config RollupConfig
+	now    func() time.Time
+}
+
+// NewRollupJob creates a rollup job
+func NewRollupJob(store RollupStore, config RollupConfig) *RollupJob {
+	defaults := DefaultRollupConfig()
+	if config.Interval <= 0 {
+		config.Interval = defaults.Interval
+	}
+	return &RollupJob{store: store, config: config, now: time.Now}
+}
+
+// Config returns the job's configuration
+func (j *RollupJob) Config() RollupConfig {
+	return j.config
+}
+
+// Run rolls up and purges every Interval until ctx is cancelled
+func (j *RollupJob) Run(ctx context.Context) {
+	ticker := time.NewTicker(j.config.Interval)
+	defer ticker.Stop()
+	for {
+		if err := j.Rollup(ctx); err != nil {
+			log.Printf("[RUM] Rollup failed: %v", err)
+		}
+		if purged, err := j.Purge(ctx); err != nil {
+			log.Printf("[RUM] Retention purge failed: %v", err)
+		} else {
+			for table, n := range purged {
+				log.Printf("[RUM] Purged %d expired rows from %s", n, table)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Rollup rebuilds hourly then daily buckets from Lookback before each
+// grain's watermark up to and including the current, partial bucket. The
+// first run backfills from the oldest raw data.
+func (j *RollupJob) Rollup(ctx context.Context) error {
+	now := j.now().UTC()
+	for _, grain := range []string{GrainHour, GrainDay} {
+		start, err := j.store.RollupWatermark(grain)
+		if err != nil {
+			return err
+		}
+		if start.IsZero() {
+			if start, err = j.store.EarliestRawData(); err != nil {
+				return err
+			}
+		}
+		if start.IsZero() {
+			start = now
+		}
+
+		for _, w := range rollupWindows(grain, start.Add(-j.config.Lookback), now) {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// The current bucket is partial, so the watermark stops at its start
+			rolledUntil := w[1]
+			if current := truncateGrain(grain, now); rolledUntil.After(current) {
+				rolledUntil = current
+			}
+			if err := j.store.BuildRollups(grain, w[0], w[1], rolledUntil); err != nil {
+				return fmt.Errorf("%s rollup of %s: %w", grain, w[0].Format(time.RFC3339), err)
+			}
+		}
+	}
+	return nil
+}
+
+// Purge deletes raw rows and rollups older than their retention. Raw rows
+// are only purged once both grains have rolled them up.
+func (j *RollupJob) Purge(ctx context.Context) (map[string]int64, error) {
+	now := j.now().UTC()
+	purged := make(map[string]int64)
+
+	rolled := time.Time{}
+	for i, grain := range []string{GrainHour, GrainDay} {
+		watermark, err := j.store.RollupWatermark(grain)
+		if err != nil {
+			return purged, err
+		}
+		if i == 0 || watermark.Before(rolled) {
+			rolled = watermark
+		}
+	}
+
+	raw := []struct {
+		table     string
+		retention time.Duration
+	}{
+		{TableEvents, j.config.EventsRetention},
+		{TableVitals, j.config.VitalsRetention},
+		{TableErrorOccurrences, j.config.ErrorOccurrencesRetention},
+		{TableSessions, j.config.SessionsRetention},
+	}
+	for _, t := range raw {
+		cutoff := retentionCutoff(now, t.retention, rolled)
+		if cutoff.IsZero() {
+			continue
+		}
+		n, err := purgeBatches(ctx, func(limit int) (int64, error) { return j.store.PurgeRaw(t.table, cutoff, limit) })
+		if n > 0 {
+			purged[t.table] = n
+		}
+		if err != nil {
+			return purged, fmt.Errorf("%s: %w", t.table, err)
+		}
+	}
+
+	for grain, retention := range map[string]time.Duration{GrainHour: j.config.HourlyRetention, GrainDay: j.config.DailyRetention} {
+		if retention <= 0 {
+			continue
+		}
+		cutoff := now.Add(-retention)
+		n, err := purgeBatches(ctx, func(limit int) (int64, error) { return j.store.PurgeRollups(grain, cutoff, limit) })
+		if n > 0 {
+			purged[grain+" rollups"] = n
+		}
+		if err != nil {
+			return purged, fmt.Errorf("%s rollups: %w", grain, err)
+		}
+	}
+	return purged, nil
+}
+
+// purgeBatches deletes in batches until a batch comes back short
+func purgeBatches(ctx context.Context, purge func(limit int) (int64, error)) (int64, error) {
+	var total int64
+	for ctx.Err() == nil {
+		n, err := purge(rollupPurgeBatch)
+		total += n
+		if err != nil || n < rollupPurgeBatch {
+			return total, err
+		}
+	}
+	return total, ctx.Err()
+}
+
+// retentionCutoff is now minus retention, held back to the rollup watermark
+// so rows are never purged before they are rolled up. The zero time means
+// nothing may be purged.
+func retentionCutoff(now time.Time, retention time.Duration, rolled time.Time) time.Time {
+	if retention <= 0 || rolled.IsZero() {
+		return time.Time{}
+	}
+	cutoff := now.Add(-retention)
+	if rolled.Before(cutoff) {
+		return rolled
+	}
+	return cutoff
+}
+
+// truncateGrain truncates t to the start of its UTC hour or day
+func truncateGrain(grain string, t time.Time) time.Time {
+	t = t.UTC()
+	if grain == GrainDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// addGrain moves a bucket start by n hours or days
+func addGrain(grain string, t time.Time, n int) time.Time {
+	if grain == GrainDay {
+		return t.AddDate(0, 0, n)
+	}
+	return t.Add(time.Duration(n) * time.Hour)
+}
+
+// rollupWindows splits the buckets from the one containing start through
+// the one containing now into [from, to) windows of at most a week of
+// hours or a month of days
+func rollupWindows(grain string, start, now time.Time) [][2]time.Time {
+	size := maxRollupHourBuckets
+	if grain == GrainDay {
+		size = maxRollupDayBuckets
+	}
+	end := addGrain(grain, truncateGrain(grain, now), 1)
+
+	var windows [][2]time.Time
+	for from := truncateGrain(grain, start); from.Before(end); {
+		to := addGrain(grain, from, size)
+		if to.After(end) {
+			to = end
+		}
+		windows = append(windows, [2]time.Time{from, to})
+		from = to
+	}
+	return windows
+}
+
+// useRollups reports whether an analytics range should be read from daily
+// rollups: it must be at least minRange long, and every day before the one
+// containing to must be rolled up
+func useRollups(from, to time.Time, minRange time.Duration, rolled time.Time) bool {
+	if minRange <= 0 || to.Sub(from) < minRange || rolled.IsZero() {
+		return false
+	}
+	return !rolled.Before(truncateGrain(GrainDay, to))
+}
@@ -0,0 +1,266 @@
+package rum
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+)
+
+type fakeRollupStore struct {
+	watermarks map[string]time.Time
+	earliest   time.Time
+	builds     []string // grain from-to
+	raw        map[string]int64
+	cutoffs    map[string]time.Time
+}
+
+func (f *fakeRollupStore) RollupWatermark(grain string) (time.Time, error) {
+	return f.watermarks[grain], nil
+}
+
+func (f *fakeRollupStore) EarliestRawData() (time.Time, error) {
+	return f.earliest, nil
+}
+
+func (f *fakeRollupStore) BuildRollups(grain string, from, to, rolledUntil time.Time) error {
+	f.builds = append(f.builds, grain+" "+from.Format(time.RFC3339)+" "+to.Format(time.RFC3339))
+	if rolledUntil.After(f.watermarks[grain]) {
+		f.watermarks[grain] = rolledUntil
+	}
+	return nil
+}
+
+func (f *fakeRollupStore) PurgeRaw(table string, before time.Time, limit int) (int64, error) {
+	f.cutoffs[table] = before
+	n := min(f.raw[table], int64(limit))
+	f.raw[table] -= n
+	return n, nil
+}
+
+func (f *fakeRollupStore) PurgeRollups(grain string, before time.Time, limit int) (int64, error) {
+	f.cutoffs[grain] = before
+	return 0, nil
+}
+
+func newFakeRollupStore() *fakeRollupStore {
+	return &fakeRollupStore{watermarks: map[string]time.Time{}, raw: map[string]int64{}, cutoffs: map[string]time.Time{}}
+}
+
+func TestRollupWindows(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+
+	windows := rollupWindows(GrainHour, now.Add(-2*time.Hour), now)
+	if len(windows) != 1 || !windows[0][0].Equal(now.Add(-150*time.Minute)) || !windows[0][1].Equal(now.Add(30*time.Minute)) {
+		t.Fatalf("expected 13:00-16:00, got %v", windows)
+	}
+
+	// Ten days of hours are split into weekly windows
+	windows = rollupWindows(GrainHour, now.AddDate(0, 0, -10), now)
+	if len(windows) != 2 || windows[0][1].Sub(windows[0][0]) != maxRollupHourBuckets*time.Hour || !windows[1][1].Equal(windows[0][1].Add(73*time.Hour)) {
+		t.Fatalf("unexpected hourly windows %v", windows)
+	}
+
+	windows = rollupWindows(GrainDay, day("2026-10-13"), now)
+	if len(windows) != 1 || !windows[0][0].Equal(day("2026-10-13")) || !windows[0][1].Equal(day("2026-10-15")) {
+		t.Fatalf("expected yesterday and today, got %v", windows)
+	}
+}
+
+func TestRetentionCutoff(t *testing.T) {
+	now := day("2026-10-14")
+	rolled := day("2026-10-10")
+	if got := retentionCutoff(now, 24*time.Hour, rolled); !got.Equal(rolled) {
+		t.Fatalf("expected the cutoff held back to the watermark, got %s", got)
+	}
+	if got := retentionCutoff(now, 30*24*time.Hour, rolled); !got.Equal(day("2026-09-14")) {
+		t.Fatalf("expected now minus retention, got %s", got)
+	}
+	if !retentionCutoff(now, 0, rolled).IsZero() || !retentionCutoff(now, time.Hour, time.Time{}).IsZero() {
+		t.Fatal("expected no purge without retention or before the first rollup")
+	}
+}
+
+func TestUseRollups(t *testing.T) {
+	to := time.Date(2026, 10, 14, 15, 0, 0, 0, time.UTC)
+	week := 7 * 24 * time.Hour
+	if !useRollups(to.Add(-30*24*time.Hour), to, week, day("2026-10-14")) {
+		t.Fatal("expected a month rolled up to today served from rollups")
+	}
+	if useRollups(to.Add(-24*time.Hour), to, week, day("2026-10-14")) {
+		t.Fatal("expected a short range served from raw tables")
+	}
+	if useRollups(to.Add(-30*24*time.Hour), to, week, day("2026-10-12")) {
+		t.Fatal("expected raw tables while the rollups lag behind")
+	}
+	if useRollups(to.Add(-30*24*time.Hour), to, 0, day("2026-10-14")) {
+		t.Fatal("expected rollups disabled with a zero range")
+	}
+}
+
+func TestRollupJob_BackfillsThenAdvances(t *testing.T) {
+	store := newFakeRollupStore()
+	store.earliest = time.Date(2026, 10, 14, 9, 45, 0, 0, time.UTC)
+	config := DefaultRollupConfig()
+	config.Lookback = 0
+	job := NewRollupJob(store, config)
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	job.now = func() time.Time { return now }
+
+	if err := job.Rollup(context.Background()); err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+	if len(store.builds) != 2 || store.builds[0] != "hour 2026-10-14T09:00:00Z 2026-10-14T16:00:00Z" || store.builds[1] != "day 2026-10-14T00:00:00Z 2026-10-15T00:00:00Z" {
+		t.Fatalf("unexpected builds %v", store.builds)
+	}
+	// Watermarks stop at the start of the current, partial bucket
+	if !store.watermarks[GrainHour].Equal(now.Truncate(time.Hour)) || !store.watermarks[GrainDay].Equal(day("2026-10-14")) {
+		t.Fatalf("unexpected watermarks %v", store.watermarks)
+	}
+
+	store.builds = nil
+	now = now.Add(2 * time.Hour)
+	job.Rollup(context.Background())
+	if store.builds[0] != "hour 2026-10-14T15:00:00Z 2026-10-14T18:00:00Z" {
+		t.Fatalf("expected the next run to resume at the watermark, got %v", store.builds)
+	}
+}
+
+func TestRollupJob_Purge(t *testing.T) {
+	store := newFakeRollupStore()
+	store.watermarks[GrainHour] = day("2026-10-14")
+	store.raw[TableEvents] = rollupPurgeBatch + 10
+	config := DefaultRollupConfig()
+	config.VitalsRetention = 0
+	job := NewRollupJob(store, config)
+	job.now = func() time.Time { return day("2026-10-14") }
+
+	job.Purge(context.Background())
+	if _, ok := store.cutoffs[TableEvents]; ok || store.raw[TableEvents] != rollupPurgeBatch+10 {
+		t.Fatalf("expected no raw rows purged before the daily rollup, got %v", store.cutoffs)
+	}
+
+	store.watermarks[GrainDay] = day("2026-10-14")
+	purged, err := job.Purge(context.Background())
+	if err != nil || purged[TableEvents] != rollupPurgeBatch+10 {
+		t.Fatalf("expected every expired event purged in batches, got %v (%v)", purged, err)
+	}
+	if !store.cutoffs[TableEvents].Equal(day("2026-07-16")) || !store.cutoffs[TableSessions].Equal(day("2025-09-14")) {
+		t.Fatalf("unexpected cutoffs %v", store.cutoffs)
+	}
+	if _, ok := store.cutoffs[TableVitals]; ok {
+		t.Fatal("expected vitals kept with zero retention")
+	}
+	if _, ok := store.cutoffs[GrainDay]; ok {
+		t.Fatal("expected daily rollups kept forever by default")
+	}
+	if !store.cutoffs[GrainHour].Equal(day("2026-07-16")) {
+		t.Fatalf("unexpected hourly rollup cutoff %s", store.cutoffs[GrainHour])
+	}
+}
+
+func TestRollupConfigFromEnv(t *testing.T) {
+	t.Setenv("RUM_RETENTION_EVENTS_DAYS", "30")
+	t.Setenv("RUM_RETENTION_DAILY_DAYS", "730")
+	t.Setenv("RUM_RETENTION_SESSIONS_DAYS", "0")
+	t.Setenv("RUM_RETENTION_VITALS_DAYS", "-1")
+	t.Setenv("RUM_ROLLUP_ANALYTICS_RANGE", "0s")
+
+	config := RollupConfigFromEnv()
+	if config.EventsRetention != 30*24*time.Hour || config.DailyRetention != 730*24*time.Hour {
+		t.Fatalf("unexpected retention %+v", config)
+	}
+	if config.SessionsRetention != 0 || config.VitalsRetention != 90*24*time.Hour || config.AnalyticsRange != 0 {
+		t.Fatalf("expected 0 to keep forever and negatives ignored, got %+v", config)
+	}
+}
+
+// countingRollupStore builds buckets from raw event times the way Storage
+// does: BuildRollups replaces every bucket in [from, to)
+type countingRollupStore struct {
+	*fakeRollupStore
+	events  []time.Time
+	buckets map[string]map[time.Time]int
+}
+
+func (f *countingRollupStore) BuildRollups(grain string, from, to, rolledUntil time.Time) error {
+	for bucket := range f.buckets[grain] {
+		if !bucket.Before(from) && bucket.Before(to) {
+			delete(f.buckets[grain], bucket)
+		}
+	}
+	for _, at := range f.events {
+		if !at.Before(from) && at.Before(to) {
+			f.buckets[grain][truncateGrain(grain, at)]++
+		}
+	}
+	return f.fakeRollupStore.BuildRollups(grain, from, to, rolledUntil)
+}
+
+func TestRollupJob_RerunIsIdempotent(t *testing.T) {
+	now := time.Date(2026, 10, 14, 15, 30, 0, 0, time.UTC)
+	store := &countingRollupStore{
+		fakeRollupStore: newFakeRollupStore(),
+		buckets:         map[string]map[time.Time]int{GrainHour: {}, GrainDay: {}},
+	}
+	// Ten days of events, so hourly windows are split, including the current hour
+	for at := now.AddDate(0, 0, -10); !at.After(now); at = at.Add(20 * time.Minute) {
+		store.events = append(store.events, at)
+	}
+	store.earliest = store.events[0]
+
+	config := DefaultRollupConfig()
+	config.Lookback = 6 * time.Hour
+	job := NewRollupJob(store, config)
+	job.now = func() time.Time { return now }
+
+	total := func(grain string) int {
+		n := 0
+		for _, count := range store.buckets[grain] {
+			n += count
+		}
+		return n
+	}
+	snapshot := func() map[string]map[time.Time]int {
+		out := map[string]map[time.Time]int{}
+		for grain, buckets := range store.buckets {
+			out[grain] = maps.Clone(buckets)
+		}
+		return out
+	}
+
+	if err := job.Rollup(context.Background()); err != nil {
+		t.Fatalf("rollup: %v", err)
+	}
+	first, watermarks := snapshot(), maps.Clone(store.watermarks)
+	for _, grain := range []string{GrainHour, GrainDay} {
+		if total(grain) != len(store.events) {
+			t.Fatalf("%s: expected %d events rolled up, got %d", grain, len(store.events), total(grain))
+		}
+	}
+
+	// Re-running at the same time rebuilds the lookback without double counting
+	for i := 0; i < 3; i++ {
+		if err := job.Rollup(context.Background()); err != nil {
+			t.Fatalf("rerun %d: %v", i, err)
+		}
+	}
+	for grain, buckets := range first {
+		if !maps.Equal(store.buckets[grain], buckets) {
+			t.Fatalf("%s: buckets changed on rerun", grain)
+		}
+	}
+	if !maps.Equal(store.watermarks, watermarks) {
+		t.Fatalf("watermarks moved on rerun: %v -> %v", watermarks, store.watermarks)
+	}
+
+	// A late event inside the lookback is counted exactly once
+	store.events = append(store.events, now.Add(-2*time.Hour))
+	job.Rollup(context.Background())
+	job.Rollup(context.Background())
+	for _, grain := range []string{GrainHour, GrainDay} {
+		if total(grain) != len(store.events) {
+			t.Fatalf("%s: expected %d events after a late arrival, got %d", grain, len(store.events), total(grain))
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package rum
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Redaction markers
+const (
+	redacted      = "[REDACTED]"
+	redactedEmail = "[EMAIL]"
+	redactedCard  = "[CARD]"
+	redactedIP    = "[IP]"
+	redactedToken = "[TOKEN]"
+)
+
+// defaultScrubFields are metadata keys and query parameters whose values are
+// always dropped, compared lowercase without '-' and '_'
+var defaultScrubFields = []string{
+	"password", "passwd", "pwd", "secret", "token", "accesstoken", "refreshtoken", "idtoken",
+	"apikey", "key", "auth", "authorization", "session", "sessionid", "sid", "code", "state",
+	"email", "mail", "phone", "tel", "mobile", "ssn", "card", "cardnumber", "cc", "cvv", "cvc",
+	"iban", "address", "firstname", "lastname", "fullname", "dob", "birthdate",
+}
+
+// scrubSuffixes catch compound keys such as csrf_token or user_email
+var scrubSuffixes = []string{"token", "password", "secret", "apikey", "email", "phone"}
+
+// scrubPatterns replace personal data found inside otherwise harmless values
+var scrubPatterns = []struct {
+	re          *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\bbearer\s+[a-z0-9._~+/-]+=*`), redactedToken},
+	{regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`), redactedToken},
+	{regexp.MustCompile(`(?i)[a-z0-9._%+-]+@[a-z0-9.-]+\.[a-z]{2,}`), redactedEmail},
+	{regexp.MustCompile(`\b(?:25[0-5]|2[0-4]\d|1?\d?\d)(?:\.(?:25[0-5]|2[0-4]\d|1?\d?\d)){3}\b`), redactedIP},
+}
+
+// cardNumber finds 13-19 digit runs starting like a card network's numbers,
+// optionally grouped by spaces or dashes; only Luhn-valid runs are redacted
+var cardNumber = regexp.MustCompile(`\b[3-6](?:[ -]?\d){12,18}\b`)
+
+// stackURL finds frame URLs in a browser-formatted stack
+var stackURL = regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^\s()]+`)
+
+// stackPosition is the :line:column suffix browsers append to frame URLs
+var stackPosition = regexp.MustCompile(`(?::\d+){1,2}$`)
+
+// traceMetadata are the trace ids newEvent adds to metadata; long decimal
+// ids can pass the card check
+var traceMetadata = map[string]bool{
+	"frontend_trace_id": true,
+	"frontend_span_id":  true,
+	"backend_trace_id":  true,
+	"backend_span_id":   true,
+}
+
+// Scrubber removes personal data from URLs and event metadata before they
+// are stored
+type Scrubber struct {
+	fields map[string]bool
+}
+
+// NewScrubber returns a scrubber for the default fields plus extra ones
+func NewScrubber(extra []string) *Scrubber {
+	s := &Scrubber{fields: make(map[string]bool)}
+	for _, f := range append(defaultScrubFields, extra...) {
+		if f = normalizeField(f); f != "" {
+			s.fields[f] = true
+		}
+	}
+	return s
+}
+
+func normalizeField(name string) string {
+	return strings.NewReplacer("-", "", "_", "", " ", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+}
+
+// sensitive reports whether a key or parameter name holds personal data
+func (s *Scrubber) sensitive(name string) bool {
+	name = normalizeField(name)
+	if s.fields[name] {
+		return true
+	}
+	for _, suffix := range scrubSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Text replaces emails, tokens, IPv4 addresses and card numbers in a string
+func (s *Scrubber) Text(text string) string {
+	if s == nil {
+		return text
+	}
+	for _, p := range scrubPatterns {
+		text = p.re.ReplaceAllString(text, p.replacement)
+	}
+	return cardNumber.ReplaceAllStringFunc(text, func(m string) string {
+		if luhn(m) {
+			return redactedCard
+		}
+		return m
+	})
+}
+
+// URL redacts sensitive query and fragment parameters and scrubs the rest
+// of the URL as text. Parameters that need no change keep their encoding
+// and order, so page URLs still group the same way.
+func (s *Scrubber) URL(raw string) string {
+	if s == nil || raw == "" {
+		return raw
+	}
+	base, fragment, hasFragment := strings.Cut(raw, "#")
+	path, query, hasQuery := strings.Cut(base, "?")
+
+	out := s.Text(path)
+	if hasQuery {
+		out += "?" + s.params(query)
+	}
+	if hasFragment {
+		// OAuth implicit flows put tokens in the fragment
+		if strings.Contains(fragment, "=") {
+			fragment = s.params(fragment)
+		} else {
+			fragment = s.Text(fragment)
+		}
+		out += "#" + fragment
+	}
+	return out
+}
+
+// Stack scrubs a browser-formatted stack: each frame URL is scrubbed
+// without its :line:column suffix, so the frames still parse and
+// symbolicate, and the rest is scrubbed as text
+func (s *Scrubber) Stack(stack string) string {
+	if s == nil || stack == "" {
+		return stack
+	}
+	stack = stackURL.ReplaceAllStringFunc(stack, func(frameURL string) string {
+		position := stackPosition.FindString(frameURL)
+		return s.URL(strings.TrimSuffix(frameURL, position)) + position
+	})
+	return s.Text(stack)
+}
+
+// params scrubs an &-separated list of key=value pairs
+func (s *Scrubber) params(query string) string {
+	pairs := strings.Split(query, "&")
+	for i, pair := range pairs {
+		key, value, hasValue := strings.Cut(pair, "=")
+		name, err := url.QueryUnescape(key)
+		if err != nil {
+			name = key
+		}
+		if s.sensitive(name) && hasValue {
+			pairs[i] = key + "=" + url.QueryEscape(redacted)
+			continue
+		}
+		decoded, err := url.QueryUnescape(value)
+		if err != nil {
+			continue
+		}
+		if scrubbed := s.Text(decoded); scrubbed != decoded {
+			pairs[i] = key + "=" + url.QueryEscape(scrubbed)
+		}
+	}
+	return strings.Join(pairs, "&")
+}
+
+// Metadata scrubs a metadata map in place: values under sensitive keys are
+// replaced, strings are scrubbed as text (or as URLs when they look like
+// one) and nested maps and lists are walked. Trace ids are left alone.
+func (s *Scrubber) Metadata(metadata map[string]any) {
+	if s == nil {
+		return
+	}
+	for key, value := range metadata {
+		if traceMetadata[key] {
+			continue
+		}
+		if s.sensitive(key) {
+			metadata[key] = redacted
+			continue
+		}
+		metadata[key] = s.value(value)
+	}
+}
+
+func (s *Scrubber) value(v any) any {
+	switch v := v.(type) {
+	case string:
+		if strings.Contains(v, "://") || strings.HasPrefix(v, "/") {
+			return s.URL(v)
+		}
+		return s.Text(v)
+	case map[string]any:
+		s.Metadata(v)
+		return v
+	case []any:
+		for i := range v {
+			v[i] = s.value(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// luhn checks a card number's checksum, ignoring separators
+func luhn(number string) bool {
+	sum, double, digits := 0, false, 0
+	for i := len(number) - 1; i >= 0; i-- {
+		c := number[i]
+		if c < '0' || c > '9' {
+			continue
+		}
+		d := int(c - '0')
+		if double {
+			if d *= 2; d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+		digits++
+	}
+	return digits >= 13 && sum%10 == 0
+}
@@ -0,0 +1,246 @@
+package rum
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Source map limits
+const (
+	maxCachedSourceMaps = 32 // parsed maps kept in memory
+	maxSourceMapBytes   = 20 << 20
+)
+
+// SourceMapStore looks up uploaded source maps; implemented by *Storage.
+// SourceMap returns nil data when no map was uploaded for the file.
+type SourceMapStore interface {
+	SourceMap(release, file string) ([]byte, error)
+}
+
+// sourceMap is a decoded Source Map v3
+type sourceMap struct {
+	sources []string
+	names   []string
+	lines   [][]mapping // by generated line, sorted by column
+}
+
+// mapping is one decoded segment; src and name are -1 when absent
+type mapping struct {
+	genColumn int
+	src       int
+	srcLine   int
+	srcColumn int
+	name      int
+}
+
+// parseSourceMap decodes a Source Map v3 document. Index maps (sections)
+// are not supported.
+func parseSourceMap(data []byte) (*sourceMap, error) {
+	var raw struct {
+		Version    int               `json:"version"`
+		SourceRoot string            `json:"sourceRoot"`
+		Sources    []string          `json:"sources"`
+		Names      []string          `json:"names"`
+		Mappings   string            `json:"mappings"`
+		Sections   []json.RawMessage `json:"sections"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid source map: %w", err)
+	}
+	if raw.Version != 3 {
+		return nil, fmt.Errorf("unsupported source map version %d", raw.Version)
+	}
+	if len(raw.Sections) > 0 {
+		return nil, errors.New("index source maps are not supported")
+	}
+
+	sm := &sourceMap{names: raw.Names}
+	for _, src := range raw.Sources {
+		if raw.SourceRoot != "" && !strings.Contains(src, "://") {
+			src = strings.TrimSuffix(raw.SourceRoot, "/") + "/" + src
+		}
+		sm.sources = append(sm.sources, src)
+	}
+
+	// Source, line, column and name fields are relative across the whole
+	// mappings string; the generated column resets on every line
+	var src, srcLine, srcColumn, name int
+	for _, line := range strings.Split(raw.Mappings, ";") {
+		var segments []mapping
+		genColumn := 0
+		for _, segment := range strings.Split(line, ",") {
+			if segment == "" {
+				continue
+			}
+			fields, err := decodeVLQ(segment)
+			if err != nil {
+				return nil, err
+			}
+			genColumn += fields[0]
+			m := mapping{genColumn: genColumn, src: -1, name: -1}
+			if len(fields) >= 4 {
+				src += fields[1]
+				srcLine += fields[2]
+				srcColumn += fields[3]
+				m.src, m.srcLine, m.srcColumn = src, srcLine, srcColumn
+			}
+			if len(fields) >= 5 {
+				name += fields[4]
+				m.name = name
+			}
+			segments = append(segments, m)
+		}
+		sort.SliceStable(segments, func(i, j int) bool { return segments[i].genColumn < segments[j].genColumn })
+		sm.lines = append(sm.lines, segments)
+	}
+	return sm, nil
+}
+
+// decodeVLQ decodes a segment of base64 VLQ values
+func decodeVLQ(segment string) ([]int, error) {
+	const alphabet = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+	var values []int
+	value, shift := 0, 0
+	for i := 0; i < len(segment); i++ {
+		digit := strings.IndexByte(alphabet, segment[i])
+		if digit < 0 {
+			return nil, fmt.Errorf("invalid VLQ character %q", segment[i])
+		}
+		value += (digit & 31) << shift
+		if digit&32 != 0 {
+			shift += 5
+			continue
+		}
+		if value&1 != 0 {
+			values = append(values, -(value >> 1))
+		} else {
+			values = append(values, value>>1)
+		}
+		value, shift = 0, 0
+	}
+	if shift != 0 || len(values) == 0 {
+		return nil, fmt.Errorf("truncated VLQ segment %q", segment)
+	}
+	return values, nil
+}
+
+// lookup maps a 1-based generated line and column to the original position
+func (sm *sourceMap) lookup(line, column int) (source string, srcLine, srcColumn int, name string, ok bool) {
+	if line < 1 || line > len(sm.lines) {
+		return "", 0, 0, "", false
+	}
+	segments := sm.lines[line-1]
+	// Last segment starting at or before the column
+	i := sort.Search(len(segments), func(i int) bool { return segments[i].genColumn > column-1 }) - 1
+	if i < 0 || segments[i].src < 0 || segments[i].src >= len(sm.sources) {
+		return "", 0, 0, "", false
+	}
+	m := segments[i]
+	if m.name >= 0 && m.name < len(sm.names) {
+		name = sm.names[m.name]
+	}
+	return sm.sources[m.src], m.srcLine + 1, m.srcColumn + 1, name, true
+}
+
+// Symbolicator maps minified frames to original sources using the source
+// maps uploaded for a release, caching parsed maps
+type Symbolicator struct {
+	store SourceMapStore
+
+	mu    sync.Mutex
+	cache map[string]*sourceMap // release|file -> map; nil = no map uploaded
+	order []string
+}
+
+// NewSymbolicator creates a symbolicator reading maps from store
+func NewSymbolicator(store SourceMapStore) *Symbolicator {
+	return &Symbolicator{store: store, cache: make(map[string]*sourceMap)}
+}
+
+// Symbolicate rewrites frames that have a source map for the release. Frames
+// without one are returned unchanged; lookup errors leave the frame as is.
+func (s *Symbolicator) Symbolicate(release string, frames []StackFrame) []StackFrame {
+	if release == "" {
+		return frames
+	}
+	out := make([]StackFrame, len(frames))
+	for i, f := range frames {
+		out[i] = f
+		sm := s.mapFor(release, f.File)
+		if sm == nil {
+			continue
+		}
+		source, line, column, name, ok := sm.lookup(f.Line, f.Column)
+		if !ok {
+			continue
+		}
+		out[i].File, out[i].Line, out[i].Column = source, line, column
+		if name != "" {
+			out[i].Function = name
+		}
+		out[i].InApp = isInApp(source)
+		out[i].Symbolicated = true
+	}
+	return out
+}
+
+// Invalidate drops a cached map after a new upload
+func (s *Symbolicator) Invalidate(release, file string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := release + "|" + sourceMapFile(file)
+	delete(s.cache, key)
+	s.order = slices.DeleteFunc(s.order, func(k string) bool { return k == key })
+}
+
+// mapFor returns the parsed map for a frame's file, loading it on first use
+func (s *Symbolicator) mapFor(release, file string) *sourceMap {
+	key := release + "|" + sourceMapFile(file)
+
+	s.mu.Lock()
+	sm, cached := s.cache[key]
+	s.mu.Unlock()
+	if cached {
+		return sm
+	}
+
+	data, err := s.store.SourceMap(release, sourceMapFile(file))
+	if err != nil {
+		// Not cached, so a transient failure is retried on the next error
+		return nil
+	}
+	if data != nil {
+		if sm, err = parseSourceMap(data); err != nil {
+			sm = nil
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache[key]; !ok {
+		s.order = append(s.order, key)
+		if len(s.order) > maxCachedSourceMaps {
+			delete(s.cache, s.order[0])
+			s.order = s.order[1:]
+		}
+	}
+	s.cache[key] = sm
+	return sm
+}
+
+// sourceMapFile is the key a source map is uploaded and looked up under: the
+// script's path without scheme, host or query ("/static/js/app.3f2a.js")
+func sourceMapFile(file string) string {
+	if u, err := url.Parse(file); err == nil && u.Path != "" {
+		file = u.Path
+	}
+	return "/" + strings.TrimPrefix(path.Clean("/"+file), "/")
+}
@@ -3,12 +3,18 @@ package rum
 import (
 	"database/sql"
 	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 // Storage handles database operations for RUM
 type Storage struct {
-	db *sql.DB
+	db          *sql.DB
+	rollupRange time.Duration // shortest analytics range read from rollups; 0 never
 }
 
 // NewStorage creates a new RUM storage instance
@@ -16,6 +22,12 @@ func NewStorage(db *sql.DB) *Storage {
 	return &Storage{db: db}
 }
 
+// SetRollupRange makes GetAnalytics read ranges of at least minRange from
+// the daily rollups; 0 always reads raw tables
+func (s *Storage) SetRollupRange(minRange time.Duration) {
+	s.rollupRange = minRange
+}
+
 // InitTables creates the necessary database tables for RUM
 func (s *Storage) InitTables() error {
 	query := `
@@ -32,6 +44,16 @@ func (s *Storage) InitTables() error {
 		city VARCHAR(100)
 	);
 
+	ALTER TABLE rum_visitors ADD COLUMN IF NOT EXISTS region VARCHAR(100);
+	ALTER TABLE rum_visitors ADD COLUMN IF NOT EXISTS asn BIGINT;
+	ALTER TABLE rum_visitors ADD COLUMN IF NOT EXISTS asn_org TEXT;
+	ALTER TABLE rum_visitors ADD COLUMN IF NOT EXISTS consent VARCHAR(16) NOT NULL DEFAULT 'unknown';
+	ALTER TABLE rum_visitors ADD COLUMN IF NOT EXISTS consent_updated_at TIMESTAMP WITH TIME ZONE;
+
+	-- Unsalted SHA-256 IP hashes can be reversed by enumerating IPv4; drop
+	-- them in favour of salted "<epoch>:<hmac>" pseudonyms
+	UPDATE rum_visitors SET ip_hash = NULL WHERE ip_hash IS NOT NULL AND position(':' in ip_hash) = 0;
+
 	CREATE TABLE IF NOT EXISTS rum_sessions (
 		id SERIAL PRIMARY KEY,
 		visitor_uuid VARCHAR(36) REFERENCES rum_visitors(uuid) ON DELETE CASCADE,
@@ -49,6 +71,12 @@ func (s *Storage) InitTables() error {
 		os VARCHAR(100)
 	);
 
+	ALTER TABLE rum_sessions ADD COLUMN IF NOT EXISTS browser_version VARCHAR(50);
+	ALTER TABLE rum_sessions ADD COLUMN IF NOT EXISTS os_version VARCHAR(50);
+	ALTER TABLE rum_sessions ADD COLUMN IF NOT EXISTS device_model VARCHAR(100);
+	ALTER TABLE rum_sessions ADD COLUMN IF NOT EXISTS is_bot BOOLEAN NOT NULL DEFAULT FALSE;
+	ALTER TABLE rum_sessions ADD COLUMN IF NOT EXISTS bot_name VARCHAR(100);
+
 	CREATE TABLE IF NOT EXISTS rum_events (
 		id SERIAL PRIMARY KEY,
 		visitor_uuid VARCHAR(36) REFERENCES rum_visitors(uuid) ON DELETE CASCADE,
@@ -64,6 +92,146 @@ func (s *Storage) InitTables() error {
 		metadata JSONB
 	);
 
+	CREATE TABLE IF NOT EXISTS rum_vitals (
+		id BIGSERIAL PRIMARY KEY,
+		visitor_uuid VARCHAR(36) REFERENCES rum_visitors(uuid) ON DELETE CASCADE,
+		session_id VARCHAR(36) REFERENCES rum_sessions(session_id) ON DELETE CASCADE,
+		timestamp TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		page_url TEXT,
+		device_type VARCHAR(50),
+		browser VARCHAR(100),
+		metric VARCHAR(20) NOT NULL,
+		value DOUBLE PRECISION NOT NULL,
+		resource_name TEXT,
+		resource_type VARCHAR(50),
+		size_bytes BIGINT
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_error_issues (
+		fingerprint VARCHAR(16) PRIMARY KEY,
+		error_type TEXT NOT NULL,
+		message TEXT NOT NULL,
+		top_frames JSONB,
+		first_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+		last_seen TIMESTAMP WITH TIME ZONE NOT NULL,
+		count BIGINT NOT NULL DEFAULT 0,
+		affected_sessions INT NOT NULL DEFAULT 0,
+		last_release TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_error_occurrences (
+		id BIGSERIAL PRIMARY KEY,
+		fingerprint VARCHAR(16) REFERENCES rum_error_issues(fingerprint) ON DELETE CASCADE,
+		visitor_uuid VARCHAR(36) REFERENCES rum_visitors(uuid) ON DELETE CASCADE,
+		session_id VARCHAR(36) REFERENCES rum_sessions(session_id) ON DELETE CASCADE,
+		timestamp TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		page_url TEXT,
+		message TEXT,
+		release TEXT,
+		frames JSONB,
+		raw_stack TEXT
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_error_issue_sessions (
+		fingerprint VARCHAR(16) REFERENCES rum_error_issues(fingerprint) ON DELETE CASCADE,
+		session_id VARCHAR(36) REFERENCES rum_sessions(session_id) ON DELETE CASCADE,
+		PRIMARY KEY (fingerprint, session_id)
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_sourcemaps (
+		release TEXT NOT NULL,
+		file TEXT NOT NULL,
+		source_map TEXT NOT NULL,
+		uploaded_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		PRIMARY KEY (release, file)
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_replay_chunks (
+		id BIGSERIAL PRIMARY KEY,
+		session_id VARCHAR(36) REFERENCES rum_sessions(session_id) ON DELETE CASCADE,
+		visitor_uuid VARCHAR(36) NOT NULL,
+		start_time TIMESTAMP WITH TIME ZONE NOT NULL,
+		end_time TIMESTAMP WITH TIME ZONE NOT NULL,
+		event_count INT NOT NULL,
+		raw_bytes INT NOT NULL,
+		stored_bytes INT NOT NULL,
+		storage_key TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_replay_blobs (
+		storage_key TEXT PRIMARY KEY,
+		data BYTEA NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_funnels (
+		id BIGSERIAL PRIMARY KEY,
+		name TEXT NOT NULL,
+		description TEXT,
+		steps JSONB NOT NULL,
+		conversion_window TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_visitor_days (
+		visitor_uuid VARCHAR(36) REFERENCES rum_visitors(uuid) ON DELETE CASCADE,
+		day DATE NOT NULL,
+		sessions INT NOT NULL DEFAULT 1,
+		PRIMARY KEY (visitor_uuid, day)
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_rollups (
+		id BIGSERIAL PRIMARY KEY,
+		grain TEXT NOT NULL,
+		bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+		page_url TEXT NOT NULL,
+		device_type TEXT NOT NULL,
+		country TEXT NOT NULL,
+		views BIGINT NOT NULL DEFAULT 0,
+		sessions BIGINT NOT NULL DEFAULT 0,
+		errors BIGINT NOT NULL DEFAULT 0,
+		duration_ms BIGINT NOT NULL DEFAULT 0,
+		timed_sessions BIGINT NOT NULL DEFAULT 0,
+		UNIQUE (grain, bucket, page_url, device_type, country)
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_rollup_vitals (
+		id BIGSERIAL PRIMARY KEY,
+		grain TEXT NOT NULL,
+		bucket TIMESTAMP WITH TIME ZONE NOT NULL,
+		page_url TEXT NOT NULL,
+		device_type TEXT NOT NULL,
+		country TEXT NOT NULL,
+		metric VARCHAR(20) NOT NULL,
+		count BIGINT NOT NULL,
+		p50 DOUBLE PRECISION NOT NULL,
+		p75 DOUBLE PRECISION NOT NULL,
+		p95 DOUBLE PRECISION NOT NULL,
+		UNIQUE (grain, bucket, page_url, device_type, country, metric)
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_ip_salts (
+		epoch BIGINT PRIMARY KEY,
+		salt BYTEA NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_privacy_audit (
+		id BIGSERIAL PRIMARY KEY,
+		visitor_uuid VARCHAR(36) NOT NULL,
+		event VARCHAR(32) NOT NULL,
+		actor VARCHAR(255) NOT NULL,
+		detail TEXT,
+		data JSONB,
+		at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+
+	CREATE TABLE IF NOT EXISTS rum_rollup_state (
+		grain TEXT PRIMARY KEY,
+		rolled_until TIMESTAMP WITH TIME ZONE NOT NULL,
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	);
+
 	CREATE INDEX IF NOT EXISTS idx_rum_visitors_uuid ON rum_visitors(uuid);
 	CREATE INDEX IF NOT EXISTS idx_rum_visitors_last_seen ON rum_visitors(last_seen);
 	CREATE INDEX IF NOT EXISTS idx_rum_sessions_visitor ON rum_sessions(visitor_uuid);
@@ -71,9 +239,35 @@ func (s *Storage) InitTables() error {
 	CREATE INDEX IF NOT EXISTS idx_rum_events_session ON rum_events(session_id);
 	CREATE INDEX IF NOT EXISTS idx_rum_events_timestamp ON rum_events(timestamp);
 	CREATE INDEX IF NOT EXISTS idx_rum_events_type ON rum_events(event_type);
+	CREATE INDEX IF NOT EXISTS idx_rum_vitals_metric_timestamp ON rum_vitals(metric, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_rum_vitals_session ON rum_vitals(session_id);
+	CREATE INDEX IF NOT EXISTS idx_rum_visitors_first_seen ON rum_visitors(first_seen);
+	CREATE INDEX IF NOT EXISTS idx_rum_visitor_days_day ON rum_visitor_days(day);
+	CREATE INDEX IF NOT EXISTS idx_rum_replay_chunks_session ON rum_replay_chunks(session_id, start_time);
+	CREATE INDEX IF NOT EXISTS idx_rum_replay_chunks_end ON rum_replay_chunks(end_time);
+	CREATE INDEX IF NOT EXISTS idx_rum_error_issues_last_seen ON rum_error_issues(last_seen);
+	CREATE INDEX IF NOT EXISTS idx_rum_error_occurrences_fingerprint ON rum_error_occurrences(fingerprint, timestamp);
+	CREATE INDEX IF NOT EXISTS idx_rum_error_occurrences_timestamp ON rum_error_occurrences(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_rum_rollups_bucket ON rum_rollups(grain, bucket);
+	CREATE INDEX IF NOT EXISTS idx_rum_rollup_vitals_bucket ON rum_rollup_vitals(grain, bucket);
+	CREATE INDEX IF NOT EXISTS idx_rum_replay_chunks_visitor ON rum_replay_chunks(visitor_uuid);
+	CREATE INDEX IF NOT EXISTS idx_rum_events_visitor ON rum_events(visitor_uuid);
+	CREATE INDEX IF NOT EXISTS idx_rum_vitals_visitor ON rum_vitals(visitor_uuid);
+	CREATE INDEX IF NOT EXISTS idx_rum_error_occurrences_visitor ON rum_error_occurrences(visitor_uuid);
+	CREATE INDEX IF NOT EXISTS idx_rum_privacy_audit_visitor ON rum_privacy_audit(visitor_uuid, at);
 	`
 
-	_, err := s.db.Exec(query)
+	if _, err := s.db.Exec(query); err != nil {
+		return err
+	}
+
+	// Backfill the daily activity rollup once, from sessions recorded before it existed
+	_, err := s.db.Exec(`
+	INSERT INTO rum_visitor_days (visitor_uuid, day, sessions)
+	SELECT visitor_uuid, (start_time AT TIME ZONE 'UTC')::date, COUNT(*)
+	FROM rum_sessions
+	WHERE visitor_uuid IS NOT NULL AND NOT is_bot AND NOT EXISTS (SELECT 1 FROM rum_visitor_days)
+	GROUP BY 1, 2`)
 	return err
 }
 
@@ -92,14 +286,17 @@ func (s *Storage) GetVisitorByUUID(uuid string) (*Visitor, error) {
 	query := `
 	SELECT id, uuid, first_seen, last_seen, session_count, total_views,
 		COALESCE(user_agent, ''), COALESCE(ip_hash, ''),
-		COALESCE(country, ''), COALESCE(city, '')
+		COALESCE(country, ''), COALESCE(region, ''), COALESCE(city, ''),
+		COALESCE(asn, 0), COALESCE(asn_org, ''), consent, consent_updated_at
 	FROM rum_visitors WHERE uuid = $1`
 
 	visitor := &Visitor{}
+	var consentUpdatedAt sql.NullTime
 	err := s.db.QueryRow(query, uuid).Scan(
 		&visitor.ID, &visitor.UUID, &visitor.FirstSeen, &visitor.LastSeen,
 		&visitor.SessionCount, &visitor.TotalViews, &visitor.UserAgent,
-		&visitor.IPHash, &visitor.Country, &visitor.City,
+		&visitor.IPHash, &visitor.Country, &visitor.Region, &visitor.City,
+		&visitor.ASN, &visitor.ASNOrg, &visitor.Consent, &consentUpdatedAt,
 	)
 
 	if err == sql.ErrNoRows {
@@ -109,6 +306,10 @@ func (s *Storage) GetVisitorByUUID(uuid string) (*Visitor, error) {
 		return nil, err
 	}
 
+	if consentUpdatedAt.Valid {
+		visitor.ConsentUpdatedAt = &consentUpdatedAt.Time
+	}
+
 	return visitor, nil
 }
 
@@ -123,6 +324,18 @@ func (s *Storage) UpdateVisitorLastSeen(uuid string) error {
 	return err
 }
 
+// SetVisitorLocation records where a visitor was last seen from
+func (s *Storage) SetVisitorLocation(uuid string, loc Location) error {
+	query := `
+	UPDATE rum_visitors
+	SET country = NULLIF($2, ''), region = NULLIF($3, ''), city = NULLIF($4, ''),
+		asn = NULLIF($5, 0), asn_org = NULLIF($6, '')
+	WHERE uuid = $1`
+
+	_, err := s.db.Exec(query, uuid, loc.Country, loc.Region, loc.City, loc.ASN, loc.ASNOrg)
+	return err
+}
+
 // IncrementVisitorViews increments the total views for a visitor
 func (s *Storage) IncrementVisitorViews(uuid string) error {
 	query := `UPDATE rum_visitors SET total_views = total_views + 1 WHERE uuid = $1`
@@ -130,15 +343,33 @@ func (s *Storage) IncrementVisitorViews(uuid string) error {
 	return err
 }
 
-// CreateSession creates a new session record
+// CreateSession creates a new session record, parsing the user agent
+// without bot detection
 func (s *Storage) CreateSession(visitorUUID, sessionID, referrer, entryPage, userAgent string) error {
-	deviceType, browser, os := parseUserAgent(userAgent)
+	return s.CreateClientSession(visitorUUID, sessionID, referrer, entryPage, ParseUserAgent(userAgent))
+}
 
+// CreateClientSession creates a new session record for a parsed client. Bot
+// sessions are kept but stay out of the daily activity rollup, so retention
+// cohorts and active users only ever count humans.
+func (s *Storage) CreateClientSession(visitorUUID, sessionID, referrer, entryPage string, client Client) error {
 	query := `
-	INSERT INTO rum_sessions (visitor_uuid, session_id, referrer, entry_page, user_agent, device_type, browser, os)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	INSERT INTO rum_sessions (visitor_uuid, session_id, referrer, entry_page, user_agent, device_type, browser, os,
+		browser_version, os_version, device_model, is_bot, bot_name)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)`
+
+	if _, err := s.db.Exec(query, visitorUUID, sessionID, referrer, entryPage, client.UserAgent, client.DeviceType,
+		client.Browser, client.OS, client.BrowserVersion, client.OSVersion, client.DeviceModel, client.IsBot(), client.BotName); err != nil {
+		return err
+	}
+	if client.IsBot() {
+		return nil
+	}
 
-	_, err := s.db.Exec(query, visitorUUID, sessionID, referrer, entryPage, userAgent, deviceType, browser, os)
+	// Daily activity rollup for retention and DAU/WAU/MAU
+	_, err := s.db.Exec(`
+	INSERT INTO rum_visitor_days (visitor_uuid, day) VALUES ($1, (NOW() AT TIME ZONE 'UTC')::date)
+	ON CONFLICT (visitor_uuid, day) DO UPDATE SET sessions = rum_visitor_days.sessions + 1`, visitorUUID)
 	return err
 }
 
@@ -148,7 +379,9 @@ func (s *Storage) GetSessionByID(sessionID string) (*Session, error) {
 	SELECT id, visitor_uuid, session_id, start_time, end_time, page_views,
 		duration_ms, COALESCE(referrer, ''), COALESCE(entry_page, ''),
 		COALESCE(exit_page, ''), COALESCE(user_agent, ''),
-		COALESCE(device_type, ''), COALESCE(browser, ''), COALESCE(os, '')
+		COALESCE(device_type, ''), COALESCE(browser, ''), COALESCE(os, ''),
+		COALESCE(browser_version, ''), COALESCE(os_version, ''), COALESCE(device_model, ''),
+		is_bot, COALESCE(bot_name, '')
 	FROM rum_sessions WHERE session_id = $1`
 
 	session := &Session{}
@@ -158,6 +391,7 @@ func (s *Storage) GetSessionByID(sessionID string) (*Session, error) {
 		&session.ID, &session.VisitorUUID, &session.SessionID, &session.StartTime, &endTime,
 		&session.PageViews, &session.DurationMs, &session.Referrer, &session.EntryPage,
 		&session.ExitPage, &session.UserAgent, &session.DeviceType, &session.Browser, &session.OS,
+		&session.BrowserVersion, &session.OSVersion, &session.DeviceModel, &session.IsBot, &session.BotName,
 	)
 
 	if err == sql.ErrNoRows {
@@ -215,16 +449,17 @@ func (s *Storage) StoreEvent(event RUMEvent) error {
 		}
 	}
 
+	// A zero timestamp falls back to NOW(); batched events carry the client's
 	query := `
 	INSERT INTO rum_events (
 		visitor_uuid, session_id, event_type, page_url, page_title,
-		action_name, action_type, error_message, duration_ms, metadata
-	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`
+		action_name, action_type, error_message, duration_ms, metadata, timestamp
+	) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, COALESCE($11::timestamptz, NOW()))`
 
 	_, err := s.db.Exec(query,
 		event.VisitorUUID, event.SessionID, event.EventType, event.PageURL,
 		event.PageTitle, event.ActionName, event.ActionType, event.ErrorMsg,
-		event.Duration, metadataJSON,
+		event.Duration, metadataJSON, sql.NullTime{Time: event.Timestamp, Valid: !event.Timestamp.IsZero()},
 	)
 
 	// Increment page views if this is a view event
@@ -236,21 +471,101 @@ func (s *Storage) StoreEvent(event RUMEvent) error {
 	return err
 }
 
-// GetEventsBySession retrieves events for a session
-func (s *Storage) GetEventsBySession(sessionID string) ([]RUMEvent, error) {
-	query := `
+// StoreEvents saves a batch of RUM events in one transaction using COPY, then
+// applies the batch's page-view counts with one update per session and visitor
+func (s *Storage) StoreEvents(events []RUMEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.Prepare(pq.CopyIn("rum_events",
+		"visitor_uuid", "session_id", "event_type", "timestamp", "page_url", "page_title",
+		"action_name", "action_type", "error_message", "duration_ms", "metadata",
+	))
+	if err != nil {
+		return err
+	}
+
+	sessionViews := make(map[string]int)
+	visitorViews := make(map[string]int)
+	now := time.Now()
+	for _, event := range events {
+		var metadata interface{}
+		if event.Metadata != nil {
+			metadataJSON, err := json.Marshal(event.Metadata)
+			if err != nil {
+				stmt.Close()
+				return err
+			}
+			metadata = string(metadataJSON)
+		}
+		timestamp := event.Timestamp
+		if timestamp.IsZero() {
+			timestamp = now
+		}
+
+		if _, err := stmt.Exec(
+			event.VisitorUUID, event.SessionID, event.EventType, timestamp, event.PageURL,
+			event.PageTitle, event.ActionName, event.ActionType, event.ErrorMsg,
+			event.Duration, metadata,
+		); err != nil {
+			stmt.Close()
+			return err
+		}
+
+		if event.EventType == "view" || event.EventType == "page_view" {
+			sessionViews[event.SessionID]++
+			visitorViews[event.VisitorUUID]++
+		}
+	}
+	// The final Exec flushes the COPY buffer
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	if err := stmt.Close(); err != nil {
+		return err
+	}
+
+	for sessionID, views := range sessionViews {
+		if _, err := tx.Exec(`UPDATE rum_sessions SET page_views = page_views + $1 WHERE session_id = $2`, views, sessionID); err != nil {
+			return err
+		}
+	}
+	for visitorUUID, views := range visitorViews {
+		if _, err := tx.Exec(`UPDATE rum_visitors SET total_views = total_views + $1 WHERE uuid = $2`, views, visitorUUID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// eventSelect selects the columns scanEvents reads
+const eventSelect = `
 	SELECT id, visitor_uuid, session_id, event_type, timestamp,
 		COALESCE(page_url, ''), COALESCE(page_title, ''),
 		COALESCE(action_name, ''), COALESCE(action_type, ''),
 		COALESCE(error_message, ''), COALESCE(duration_ms, 0), metadata
-	FROM rum_events
-	WHERE session_id = $1
-	ORDER BY timestamp ASC`
+	FROM rum_events`
 
-	rows, err := s.db.Query(query, sessionID)
+// GetEventsBySession retrieves events for a session
+func (s *Storage) GetEventsBySession(sessionID string) ([]RUMEvent, error) {
+	rows, err := s.db.Query(eventSelect+` WHERE session_id = $1 ORDER BY timestamp ASC`, sessionID)
 	if err != nil {
 		return nil, err
 	}
+	return scanEvents(rows)
+}
+
+// scanEvents reads eventSelect rows
+func scanEvents(rows *sql.Rows) ([]RUMEvent, error) {
 	defer rows.Close()
 
 	var events []RUMEvent
@@ -277,66 +592,138 @@ func (s *Storage) GetEventsBySession(sessionID string) ([]RUMEvent, error) {
 	return events, nil
 }
 
-// CountUniqueVisitors counts unique visitors in a time range
-func (s *Storage) CountUniqueVisitors(from, to time.Time) (int, error) {
+// CountUniqueVisitors counts unique visitors first seen in a time range;
+// visitors with only bot sessions count when includeBots is set
+func (s *Storage) CountUniqueVisitors(from, to time.Time, includeBots bool) (int, error) {
 	query := `
 	SELECT COUNT(DISTINCT uuid) FROM rum_visitors
-	WHERE first_seen >= $1 AND first_seen <= $2`
+	WHERE first_seen >= $1 AND first_seen <= $2` + humanVisitors(includeBots)
 
 	var count int
 	err := s.db.QueryRow(query, from, to).Scan(&count)
 	return count, err
 }
 
-// GetAnalytics retrieves analytics data for a time range
-func (s *Storage) GetAnalytics(from, to time.Time) (*VisitorAnalytics, error) {
+// GetAnalytics retrieves analytics data for a time range. Bot sessions and
+// their events are left out unless includeBots is set, which always reads
+// the raw tables since rollups hold human traffic only.
+func (s *Storage) GetAnalytics(from, to time.Time, includeBots bool) (*VisitorAnalytics, error) {
 	analytics := &VisitorAnalytics{
 		Period:    from.Format("2006-01-02") + " to " + to.Format("2006-01-02"),
 		ByDevice:  make(map[string]int),
 		ByBrowser: make(map[string]int),
 		ByCountry: make(map[string]int),
+		ByRegion:  make(map[string]int),
+		Source:    "raw",
+	}
+
+	// Long ranges read whole days from the rollups once they are complete
+	rollups := false
+	if s.rollupRange > 0 && !includeBots {
+		if rolled, err := s.RollupWatermark(GrainDay); err == nil {
+			rollups = useRollups(from, to, s.rollupRange, rolled)
+		}
+	}
+
+	if rollups {
+		analytics.Source = "rollups"
+		s.rollupAnalytics(analytics, truncateGrain(GrainDay, from), to)
+	} else {
+		s.rawAnalytics(analytics, from, to, includeBots)
+	}
+
+	// Bot sessions, whether or not they are included above
+	s.db.QueryRow(`
+		SELECT COUNT(*) FROM rum_sessions
+		WHERE start_time >= $1 AND start_time <= $2 AND is_bot
+	`, from, to).Scan(&analytics.BotSessions)
+
+	// New vs returning visitors
+	s.db.QueryRow(`
+		SELECT COUNT(*) FROM rum_visitors
+		WHERE first_seen >= $1 AND first_seen <= $2`+humanVisitors(includeBots),
+		from, to).Scan(&analytics.NewVisitors)
+
+	analytics.ReturningVisitors = analytics.UniqueVisitors - analytics.NewVisitors
+	if analytics.ReturningVisitors < 0 {
+		analytics.ReturningVisitors = 0
+	}
+
+	// By browser
+	rows3, err := s.db.Query(`
+		SELECT COALESCE(browser, 'Unknown'), COUNT(*)
+		FROM rum_sessions
+		WHERE start_time >= $1 AND start_time <= $2`+humanSessions("is_bot", includeBots)+`
+		GROUP BY browser
+	`, from, to)
+	if err == nil {
+		defer rows3.Close()
+		for rows3.Next() {
+			var browser string
+			var count int
+			rows3.Scan(&browser, &count)
+			analytics.ByBrowser[browser] = count
+		}
+	}
+
+	// By region, for visitors GeoIP could place
+	rows4, err := s.db.Query(`
+		SELECT v.region, COUNT(*)
+		FROM rum_sessions s
+		JOIN rum_visitors v ON v.uuid = s.visitor_uuid
+		WHERE s.start_time >= $1 AND s.start_time <= $2 AND v.region <> ''`+humanSessions("s.is_bot", includeBots)+`
+		GROUP BY v.region
+		ORDER BY COUNT(*) DESC
+		LIMIT 50
+	`, from, to)
+	if err == nil {
+		defer rows4.Close()
+		for rows4.Next() {
+			var region string
+			var count int
+			rows4.Scan(&region, &count)
+			analytics.ByRegion[region] = count
+		}
 	}
 
+	return analytics, nil
+}
+
+// rawAnalytics fills the visitor, session, page and vitals figures from the
+// raw tables
+func (s *Storage) rawAnalytics(analytics *VisitorAnalytics, from, to time.Time, includeBots bool) {
+	sessions := humanSessions("is_bot", includeBots)
+
 	// Unique visitors
 	s.db.QueryRow(`
 		SELECT COUNT(DISTINCT visitor_uuid) FROM rum_sessions
-		WHERE start_time >= $1 AND start_time <= $2
-	`, from, to).Scan(&analytics.UniqueVisitors)
+		WHERE start_time >= $1 AND start_time <= $2`+sessions,
+		from, to).Scan(&analytics.UniqueVisitors)
 
 	// Total sessions
 	s.db.QueryRow(`
 		SELECT COUNT(*) FROM rum_sessions
-		WHERE start_time >= $1 AND start_time <= $2
-	`, from, to).Scan(&analytics.TotalSessions)
+		WHERE start_time >= $1 AND start_time <= $2`+sessions,
+		from, to).Scan(&analytics.TotalSessions)
 
 	// Total page views
 	s.db.QueryRow(`
 		SELECT COALESCE(SUM(page_views), 0) FROM rum_sessions
-		WHERE start_time >= $1 AND start_time <= $2
-	`, from, to).Scan(&analytics.TotalPageViews)
+		WHERE start_time >= $1 AND start_time <= $2`+sessions,
+		from, to).Scan(&analytics.TotalPageViews)
 
 	// Average session duration
 	s.db.QueryRow(`
 		SELECT COALESCE(AVG(duration_ms), 0) FROM rum_sessions
-		WHERE start_time >= $1 AND start_time <= $2 AND duration_ms > 0
-	`, from, to).Scan(&analytics.AvgSessionDuration)
-
-	// New vs returning visitors
-	s.db.QueryRow(`
-		SELECT COUNT(*) FROM rum_visitors
-		WHERE first_seen >= $1 AND first_seen <= $2
-	`, from, to).Scan(&analytics.NewVisitors)
-
-	analytics.ReturningVisitors = analytics.UniqueVisitors - analytics.NewVisitors
-	if analytics.ReturningVisitors < 0 {
-		analytics.ReturningVisitors = 0
-	}
+		WHERE start_time >= $1 AND start_time <= $2 AND duration_ms > 0`+sessions,
+		from, to).Scan(&analytics.AvgSessionDuration)
 
 	// Top pages
 	rows, err := s.db.Query(`
 		SELECT page_url, COALESCE(page_title, ''), COUNT(*) as views
 		FROM rum_events
-		WHERE event_type IN ('view', 'page_view') AND timestamp >= $1 AND timestamp <= $2
+		WHERE event_type IN ('view', 'page_view') AND timestamp >= $1 AND timestamp <= $2`+
+		humanEvents("rum_events.session_id", includeBots)+`
 		GROUP BY page_url, page_title
 		ORDER BY views DESC
 		LIMIT 10
@@ -354,7 +741,7 @@ func (s *Storage) GetAnalytics(from, to time.Time) (*VisitorAnalytics, error) {
 	rows2, err := s.db.Query(`
 		SELECT COALESCE(device_type, 'Unknown'), COUNT(*)
 		FROM rum_sessions
-		WHERE start_time >= $1 AND start_time <= $2
+		WHERE start_time >= $1 AND start_time <= $2`+sessions+`
 		GROUP BY device_type
 	`, from, to)
 	if err == nil {
@@ -367,116 +754,1354 @@ func (s *Storage) GetAnalytics(from, to time.Time) (*VisitorAnalytics, error) {
 		}
 	}
 
-	// By browser
+	// By country
 	rows3, err := s.db.Query(`
-		SELECT COALESCE(browser, 'Unknown'), COUNT(*)
-		FROM rum_sessions
-		WHERE start_time >= $1 AND start_time <= $2
-		GROUP BY browser
+		SELECT COALESCE(NULLIF(v.country, ''), 'Unknown'), COUNT(*)
+		FROM rum_sessions s
+		LEFT JOIN rum_visitors v ON v.uuid = s.visitor_uuid
+		WHERE s.start_time >= $1 AND s.start_time <= $2`+humanSessions("s.is_bot", includeBots)+`
+		GROUP BY 1
 	`, from, to)
 	if err == nil {
 		defer rows3.Close()
 		for rows3.Next() {
-			var browser string
+			var country string
 			var count int
-			rows3.Scan(&browser, &count)
-			analytics.ByBrowser[browser] = count
+			rows3.Scan(&country, &count)
+			analytics.ByCountry[country] = count
 		}
 	}
 
-	return analytics, nil
+	// Core Web Vitals percentiles
+	if vitals, err := s.GetVitalSummaries(from, to, coreMetrics, "", includeBots); err == nil {
+		analytics.Vitals = vitals
+	}
+}
+
+// rollupAnalytics fills the same figures from the daily rollups and the
+// visitor activity table. Vitals percentiles are the sample-weighted mean
+// of the daily percentiles, so they are approximate.
+func (s *Storage) rollupAnalytics(analytics *VisitorAnalytics, from, to time.Time) {
+	// Unique visitors
+	s.db.QueryRow(`
+		SELECT COUNT(DISTINCT visitor_uuid) FROM rum_visitor_days
+		WHERE day >= $1::date AND day <= $2::date
+	`, from.Format("2006-01-02"), to.UTC().Format("2006-01-02")).Scan(&analytics.UniqueVisitors)
+
+	// Sessions, page views and average session duration
+	s.db.QueryRow(`
+		SELECT COALESCE(SUM(sessions), 0), COALESCE(SUM(views), 0),
+			COALESCE(SUM(duration_ms)::float8 / NULLIF(SUM(timed_sessions), 0), 0)
+		FROM rum_rollups
+		WHERE grain = 'day' AND bucket >= $1 AND bucket <= $2
+	`, from, to).Scan(&analytics.TotalSessions, &analytics.TotalPageViews, &analytics.AvgSessionDuration)
+
+	// Top pages
+	rows, err := s.db.Query(`
+		SELECT page_url, SUM(views) AS views
+		FROM rum_rollups
+		WHERE grain = 'day' AND bucket >= $1 AND bucket <= $2 AND page_url <> ''
+		GROUP BY page_url
+		HAVING SUM(views) > 0
+		ORDER BY views DESC
+		LIMIT 10
+	`, from, to)
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			var stat PageStat
+			rows.Scan(&stat.PageURL, &stat.Views)
+			analytics.TopPages = append(analytics.TopPages, stat)
+		}
+	}
+
+	// Sessions by device type and country
+	rows2, err := s.db.Query(`
+		SELECT 'device', COALESCE(NULLIF(device_type, ''), 'Unknown'), SUM(sessions)
+		FROM rum_rollups
+		WHERE grain = 'day' AND bucket >= $1 AND bucket <= $2
+		GROUP BY 2
+		HAVING SUM(sessions) > 0
+		UNION ALL
+		SELECT 'country', COALESCE(NULLIF(country, ''), 'Unknown'), SUM(sessions)
+		FROM rum_rollups
+		WHERE grain = 'day' AND bucket >= $1 AND bucket <= $2
+		GROUP BY 2
+		HAVING SUM(sessions) > 0
+	`, from, to)
+	if err == nil {
+		defer rows2.Close()
+		for rows2.Next() {
+			var dimension, value string
+			var count int
+			rows2.Scan(&dimension, &value, &count)
+			if dimension == "device" {
+				analytics.ByDevice[value] = count
+			} else {
+				analytics.ByCountry[value] = count
+			}
+		}
+	}
+
+	// Core Web Vitals percentiles
+	rows3, err := s.db.Query(`
+		SELECT metric, SUM(count),
+			SUM(p50 * count) / SUM(count), SUM(p75 * count) / SUM(count), SUM(p95 * count) / SUM(count)
+		FROM rum_rollup_vitals
+		WHERE grain = 'day' AND bucket >= $1 AND bucket <= $2 AND count > 0
+		GROUP BY metric
+		ORDER BY metric
+	`, from, to)
+	if err == nil {
+		defer rows3.Close()
+		for rows3.Next() {
+			var v VitalSummary
+			if rows3.Scan(&v.Metric, &v.Count, &v.P50, &v.P75, &v.P95) != nil {
+				continue
+			}
+			v.P50, v.P75, v.P95 = roundVital(v.P50), roundVital(v.P75), roundVital(v.P95)
+			v.Rating = rateVital(v.Metric, v.P75)
+			analytics.Vitals = append(analytics.Vitals, v)
+		}
+	}
 }
 
-// GetRecentSessions retrieves recent sessions
-func (s *Storage) GetRecentSessions(limit, offset int) ([]Session, error) {
+// StoreVitals saves a vitals report in one statement, copying the session's
+// device type and browser onto each row so percentiles group without joins
+func (s *Storage) StoreVitals(req VitalsRequest, rows []vitalRow) error {
+	metrics := make([]string, len(rows))
+	values := make([]float64, len(rows))
+	names := make([]string, len(rows))
+	kinds := make([]string, len(rows))
+	sizes := make([]int64, len(rows))
+	for i, row := range rows {
+		metrics[i], values[i], names[i], kinds[i], sizes[i] = row.Metric, row.Value, row.ResourceName, row.ResourceType, row.SizeBytes
+	}
+
 	query := `
-	SELECT id, visitor_uuid, session_id, start_time, end_time, page_views,
-		duration_ms, COALESCE(referrer, ''), COALESCE(entry_page, ''),
-		COALESCE(exit_page, ''), COALESCE(user_agent, ''),
-		COALESCE(device_type, ''), COALESCE(browser, ''), COALESCE(os, '')
-	FROM rum_sessions
-	ORDER BY start_time DESC
-	LIMIT $1 OFFSET $2`
+	INSERT INTO rum_vitals (
+		visitor_uuid, session_id, timestamp, page_url, device_type, browser,
+		metric, value, resource_name, resource_type, size_bytes
+	)
+	SELECT $1, $2, COALESCE($3::timestamptz, NOW()), $4, s.device_type, s.browser,
+		m.metric, m.value, NULLIF(m.resource_name, ''), NULLIF(m.resource_type, ''), NULLIF(m.size_bytes, 0)
+	FROM unnest($5::text[], $6::float8[], $7::text[], $8::text[], $9::bigint[])
+		AS m(metric, value, resource_name, resource_type, size_bytes)
+	LEFT JOIN rum_sessions s ON s.session_id = $2`
+
+	_, err := s.db.Exec(query,
+		req.VisitorUUID, req.SessionID, sql.NullTime{Time: req.Timestamp, Valid: !req.Timestamp.IsZero()}, req.PageURL,
+		pq.Array(metrics), pq.Array(values), pq.Array(names), pq.Array(kinds), pq.Array(sizes),
+	)
+	return err
+}
+
+// GetVitalSummaries returns p50/p75/p95 per metric, grouped by a
+// vitalDimensions column (empty groupBy = one row per metric)
+func (s *Storage) GetVitalSummaries(from, to time.Time, metrics []string, groupBy string, includeBots bool) ([]VitalSummary, error) {
+	group := "''"
+	if groupBy != "" {
+		column, ok := vitalDimensions[groupBy]
+		if !ok {
+			return nil, fmt.Errorf("unknown dimension %q", groupBy)
+		}
+		group = fmt.Sprintf("COALESCE(%s, 'Unknown')", column)
+	}
 
-	rows, err := s.db.Query(query, limit, offset)
+	query := fmt.Sprintf(`
+	SELECT metric, %s AS grp, COUNT(*),
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY value),
+		percentile_cont(0.75) WITHIN GROUP (ORDER BY value),
+		percentile_cont(0.95) WITHIN GROUP (ORDER BY value)
+	FROM rum_vitals
+	WHERE timestamp >= $1 AND timestamp <= $2 AND metric = ANY($3)%s
+	GROUP BY metric, grp
+	ORDER BY metric, COUNT(*) DESC
+	LIMIT 500`, group, humanEvents("rum_vitals.session_id", includeBots))
+
+	rows, err := s.db.Query(query, from, to, pq.Array(metrics))
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var sessions []Session
+	summaries := []VitalSummary{}
 	for rows.Next() {
-		session := Session{}
-		var endTime sql.NullTime
-
-		err := rows.Scan(
-			&session.ID, &session.VisitorUUID, &session.SessionID, &session.StartTime, &endTime,
-			&session.PageViews, &session.DurationMs, &session.Referrer, &session.EntryPage,
-			&session.ExitPage, &session.UserAgent, &session.DeviceType, &session.Browser, &session.OS,
-		)
-		if err != nil {
+		var v VitalSummary
+		if err := rows.Scan(&v.Metric, &v.Group, &v.Count, &v.P50, &v.P75, &v.P95); err != nil {
 			return nil, err
 		}
+		v.P50, v.P75, v.P95 = roundVital(v.P50), roundVital(v.P75), roundVital(v.P95)
+		v.Rating = rateVital(v.Metric, v.P75)
+		summaries = append(summaries, v)
+	}
+	return summaries, rows.Err()
+}
 
-		if endTime.Valid {
-			session.EndTime = &endTime.Time
-		}
+// GetVitalTrend returns p50/p75/p95 per metric per time bucket
+func (s *Storage) GetVitalTrend(from, to time.Time, metrics []string, bucket time.Duration, includeBots bool) ([]VitalBucket, error) {
+	query := `
+	SELECT metric, to_timestamp(floor(extract(epoch FROM timestamp) / $4) * $4) AS bucket, COUNT(*),
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY value),
+		percentile_cont(0.75) WITHIN GROUP (ORDER BY value),
+		percentile_cont(0.95) WITHIN GROUP (ORDER BY value)
+	FROM rum_vitals
+	WHERE timestamp >= $1 AND timestamp <= $2 AND metric = ANY($3)` + humanEvents("rum_vitals.session_id", includeBots) + `
+	GROUP BY metric, bucket
+	ORDER BY metric, bucket`
+
+	rows, err := s.db.Query(query, from, to, pq.Array(metrics), bucket.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-		sessions = append(sessions, session)
+	trend := []VitalBucket{}
+	for rows.Next() {
+		var b VitalBucket
+		if err := rows.Scan(&b.Metric, &b.Bucket, &b.Count, &b.P50, &b.P75, &b.P95); err != nil {
+			return nil, err
+		}
+		b.P50, b.P75, b.P95 = roundVital(b.P50), roundVital(b.P75), roundVital(b.P95)
+		trend = append(trend, b)
 	}
+	return trend, rows.Err()
+}
 
-	return sessions, nil
+// roundVital keeps three decimals, enough for CLS
+func roundVital(v float64) float64 {
+	return math.Round(v*1000) / 1000
 }
 
-// parseUserAgent is a simple user agent parser (can be enhanced with a proper library)
-func parseUserAgent(ua string) (deviceType, browser, os string) {
-	deviceType = "desktop"
-	browser = "Unknown"
-	os = "Unknown"
+// StoreSourceMap saves (or replaces) the source map for a script in a release
+func (s *Storage) StoreSourceMap(release, file string, data []byte) error {
+	query := `
+	INSERT INTO rum_sourcemaps (release, file, source_map)
+	VALUES ($1, $2, $3)
+	ON CONFLICT (release, file) DO UPDATE SET source_map = EXCLUDED.source_map, uploaded_at = NOW()`
 
-	// Simple device detection
-	if contains(ua, "Mobile") || contains(ua, "Android") || contains(ua, "iPhone") {
-		deviceType = "mobile"
-	} else if contains(ua, "Tablet") || contains(ua, "iPad") {
-		deviceType = "tablet"
-	}
+	_, err := s.db.Exec(query, release, file, string(data))
+	return err
+}
 
-	// Simple browser detection
-	if contains(ua, "Chrome") && !contains(ua, "Edge") {
-		browser = "Chrome"
-	} else if contains(ua, "Firefox") {
-		browser = "Firefox"
-	} else if contains(ua, "Safari") && !contains(ua, "Chrome") {
-		browser = "Safari"
-	} else if contains(ua, "Edge") {
-		browser = "Edge"
+// SourceMap returns the source map uploaded for a script in a release, or
+// nil when there is none (SourceMapStore)
+func (s *Storage) SourceMap(release, file string) ([]byte, error) {
+	var data string
+	err := s.db.QueryRow(`SELECT source_map FROM rum_sourcemaps WHERE release = $1 AND file = $2`, release, file).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
 	}
-
-	// Simple OS detection
-	if contains(ua, "Windows") {
-		os = "Windows"
-	} else if contains(ua, "Mac OS") || contains(ua, "Macintosh") {
-		os = "macOS"
-	} else if contains(ua, "Linux") && !contains(ua, "Android") {
-		os = "Linux"
-	} else if contains(ua, "Android") {
-		os = "Android"
-	} else if contains(ua, "iOS") || contains(ua, "iPhone") || contains(ua, "iPad") {
-		os = "iOS"
+	if err != nil {
+		return nil, err
 	}
-
-	return
+	return []byte(data), nil
 }
 
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
-}
+// RecordError stores an occurrence and updates its issue in one transaction:
+// count, first/last seen, the latest release and top frames, and the number
+// of distinct sessions affected
+func (s *Storage) RecordError(occ ErrorOccurrence, issue ErrorIssue) (*ErrorIssue, error) {
+	framesJSON, err := json.Marshal(occ.Frames)
+	if err != nil {
+		return nil, err
+	}
+	topJSON, err := json.Marshal(issue.TopFrames)
+	if err != nil {
+		return nil, err
+	}
 
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+	INSERT INTO rum_error_issues (fingerprint, error_type, message, top_frames, first_seen, last_seen, count, last_release)
+	VALUES ($1, $2, $3, $4, $5, $5, 1, NULLIF($6, ''))
+	ON CONFLICT (fingerprint) DO UPDATE SET
+		count = rum_error_issues.count + 1,
+		first_seen = LEAST(rum_error_issues.first_seen, EXCLUDED.first_seen),
+		last_seen = GREATEST(rum_error_issues.last_seen, EXCLUDED.last_seen),
+		top_frames = EXCLUDED.top_frames,
+		last_release = COALESCE(EXCLUDED.last_release, rum_error_issues.last_release)`,
+		issue.Fingerprint, issue.Type, issue.Message, topJSON, occ.Timestamp, occ.Release)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO rum_error_occurrences (fingerprint, visitor_uuid, session_id, timestamp, page_url, message, release, frames, raw_stack)
+	VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), $8, $9)`,
+		occ.Fingerprint, occ.VisitorUUID, occ.SessionID, occ.Timestamp, occ.PageURL, occ.Message, occ.Release, framesJSON, occ.RawStack)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(`
+	INSERT INTO rum_error_issue_sessions (fingerprint, session_id) VALUES ($1, $2)
+	ON CONFLICT DO NOTHING`, occ.Fingerprint, occ.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		if _, err := tx.Exec(`UPDATE rum_error_issues SET affected_sessions = affected_sessions + 1 WHERE fingerprint = $1`, occ.Fingerprint); err != nil {
+			return nil, err
 		}
 	}
-	return false
+
+	stored, err := scanErrorIssue(tx.QueryRow(errorIssueSelect+` WHERE fingerprint = $1`, occ.Fingerprint))
+	if err != nil {
+		return nil, err
+	}
+	return stored, tx.Commit()
+}
+
+// errorIssueSelect selects the columns scanErrorIssue reads
+const errorIssueSelect = `
+	SELECT fingerprint, error_type, message, top_frames, first_seen, last_seen,
+		count, affected_sessions, COALESCE(last_release, '')
+	FROM rum_error_issues`
+
+// scanErrorIssue scans one errorIssueSelect row
+func scanErrorIssue(row interface{ Scan(...any) error }) (*ErrorIssue, error) {
+	issue := &ErrorIssue{}
+	var topJSON []byte
+	if err := row.Scan(&issue.Fingerprint, &issue.Type, &issue.Message, &topJSON, &issue.FirstSeen,
+		&issue.LastSeen, &issue.Count, &issue.AffectedSessions, &issue.LastRelease); err != nil {
+		return nil, err
+	}
+	if len(topJSON) > 0 {
+		json.Unmarshal(topJSON, &issue.TopFrames)
+	}
+	return issue, nil
+}
+
+// ListErrorIssues returns issues seen in a time range, optionally for one
+// release, ordered by "count", "sessions" or most recent (default)
+func (s *Storage) ListErrorIssues(from, to time.Time, release, sortBy string, limit int) ([]ErrorIssue, error) {
+	order := "last_seen DESC"
+	switch sortBy {
+	case "count":
+		order = "count DESC"
+	case "sessions":
+		order = "affected_sessions DESC"
+	}
+
+	query := errorIssueSelect + `
+	WHERE last_seen >= $1 AND last_seen <= $2 AND ($3 = '' OR last_release = $3)
+	ORDER BY ` + order + `
+	LIMIT $4`
+
+	rows, err := s.db.Query(query, from, to, release, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	issues := []ErrorIssue{}
+	for rows.Next() {
+		issue, err := scanErrorIssue(rows)
+		if err != nil {
+			return nil, err
+		}
+		issues = append(issues, *issue)
+	}
+	return issues, rows.Err()
+}
+
+// GetErrorIssue returns an issue, or nil if the fingerprint is unknown
+func (s *Storage) GetErrorIssue(fingerprint string) (*ErrorIssue, error) {
+	issue, err := scanErrorIssue(s.db.QueryRow(errorIssueSelect+` WHERE fingerprint = $1`, fingerprint))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return issue, err
+}
+
+// errorOccurrenceSelect selects the columns scanErrorOccurrences reads
+const errorOccurrenceSelect = `
+	SELECT id, fingerprint, visitor_uuid, session_id, timestamp, COALESCE(page_url, ''),
+		COALESCE(message, ''), COALESCE(release, ''), frames, COALESCE(raw_stack, '')
+	FROM rum_error_occurrences`
+
+// GetErrorOccurrences returns an issue's most recent occurrences
+func (s *Storage) GetErrorOccurrences(fingerprint string, limit int) ([]ErrorOccurrence, error) {
+	rows, err := s.db.Query(errorOccurrenceSelect+` WHERE fingerprint = $1 ORDER BY timestamp DESC LIMIT $2`, fingerprint, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanErrorOccurrences(rows)
+}
+
+// scanErrorOccurrences reads errorOccurrenceSelect rows
+func scanErrorOccurrences(rows *sql.Rows) ([]ErrorOccurrence, error) {
+	defer rows.Close()
+
+	occurrences := []ErrorOccurrence{}
+	for rows.Next() {
+		var occ ErrorOccurrence
+		var framesJSON []byte
+		if err := rows.Scan(&occ.ID, &occ.Fingerprint, &occ.VisitorUUID, &occ.SessionID, &occ.Timestamp,
+			&occ.PageURL, &occ.Message, &occ.Release, &framesJSON, &occ.RawStack); err != nil {
+			return nil, err
+		}
+		if len(framesJSON) > 0 {
+			json.Unmarshal(framesJSON, &occ.Frames)
+		}
+		occurrences = append(occurrences, occ)
+	}
+	return occurrences, rows.Err()
+}
+
+// AddReplayChunk indexes a stored replay chunk, setting its ID and CreatedAt
+// (ReplayIndex)
+func (s *Storage) AddReplayChunk(chunk *ReplayChunk) error {
+	query := `
+	INSERT INTO rum_replay_chunks (session_id, visitor_uuid, start_time, end_time, event_count, raw_bytes, stored_bytes, storage_key)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	RETURNING id, created_at`
+
+	return s.db.QueryRow(query, chunk.SessionID, chunk.VisitorUUID, chunk.StartTime, chunk.EndTime,
+		chunk.EventCount, chunk.RawBytes, chunk.StoredBytes, chunk.StorageKey).Scan(&chunk.ID, &chunk.CreatedAt)
+}
+
+// replayChunkSelect selects the columns scanReplayChunks reads
+const replayChunkSelect = `
+	SELECT id, session_id, visitor_uuid, start_time, end_time, event_count,
+		raw_bytes, stored_bytes, storage_key, created_at
+	FROM rum_replay_chunks`
+
+// scanReplayChunks reads replayChunkSelect rows
+func scanReplayChunks(rows *sql.Rows) ([]ReplayChunk, error) {
+	defer rows.Close()
+
+	chunks := []ReplayChunk{}
+	for rows.Next() {
+		var c ReplayChunk
+		if err := rows.Scan(&c.ID, &c.SessionID, &c.VisitorUUID, &c.StartTime, &c.EndTime, &c.EventCount,
+			&c.RawBytes, &c.StoredBytes, &c.StorageKey, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, c)
+	}
+	return chunks, rows.Err()
+}
+
+// GetReplayChunks returns a session's replay chunks in playback order
+func (s *Storage) GetReplayChunks(sessionID string) ([]ReplayChunk, error) {
+	rows, err := s.db.Query(replayChunkSelect+` WHERE session_id = $1 ORDER BY start_time, id`, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReplayChunks(rows)
+}
+
+// GetExpiredReplayChunks returns up to limit chunks that ended before a time
+func (s *Storage) GetExpiredReplayChunks(before time.Time, limit int) ([]ReplayChunk, error) {
+	rows, err := s.db.Query(replayChunkSelect+` WHERE end_time < $1 ORDER BY end_time LIMIT $2`, before, limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanReplayChunks(rows)
+}
+
+// DeleteReplayChunks removes chunk metadata by ID
+func (s *Storage) DeleteReplayChunks(ids []int64) error {
+	_, err := s.db.Exec(`DELETE FROM rum_replay_chunks WHERE id = ANY($1)`, pq.Array(ids))
+	return err
+}
+
+// GetVisitorReplayChunks returns all of a visitor's replay chunks (ReplayIndex)
+func (s *Storage) GetVisitorReplayChunks(visitorUUID string) ([]ReplayChunk, error) {
+	rows, err := s.db.Query(replayChunkSelect+` WHERE visitor_uuid = $1 ORDER BY start_time, id`, visitorUUID)
+	if err != nil {
+		return nil, err
+	}
+	return scanReplayChunks(rows)
+}
+
+// PostgresReplayStore keeps replay chunks in the rum_replay_blobs table
+type PostgresReplayStore struct {
+	db *sql.DB
+}
+
+// NewPostgresReplayStore creates a store using the RUM database; the table
+// is created by Storage.InitTables
+func NewPostgresReplayStore(db *sql.DB) *PostgresReplayStore {
+	return &PostgresReplayStore{db: db}
+}
+
+// Put saves a chunk
+func (s *PostgresReplayStore) Put(key string, data []byte) error {
+	_, err := s.db.Exec(`
+	INSERT INTO rum_replay_blobs (storage_key, data) VALUES ($1, $2)
+	ON CONFLICT (storage_key) DO UPDATE SET data = EXCLUDED.data`, key, data)
+	return err
+}
+
+// Get reads a chunk
+func (s *PostgresReplayStore) Get(key string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRow(`SELECT data FROM rum_replay_blobs WHERE storage_key = $1`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("replay chunk %q not found", key)
+	}
+	return data, err
+}
+
+// Delete removes a chunk
+func (s *PostgresReplayStore) Delete(key string) error {
+	_, err := s.db.Exec(`DELETE FROM rum_replay_blobs WHERE storage_key = $1`, key)
+	return err
+}
+
+// CreateFunnel saves a funnel definition, setting its ID and CreatedAt
+func (s *Storage) CreateFunnel(f *Funnel) error {
+	steps, err := json.Marshal(f.Steps)
+	if err != nil {
+		return err
+	}
+	query := `
+	INSERT INTO rum_funnels (name, description, steps, conversion_window)
+	VALUES ($1, NULLIF($2, ''), $3, NULLIF($4, ''))
+	RETURNING id, created_at`
+
+	return s.db.QueryRow(query, f.Name, f.Description, steps, f.Window).Scan(&f.ID, &f.CreatedAt)
+}
+
+// funnelSelect selects the columns scanFunnel reads
+const funnelSelect = `
+	SELECT id, name, COALESCE(description, ''), steps, COALESCE(conversion_window, ''), created_at
+	FROM rum_funnels`
+
+// scanFunnel scans one funnelSelect row
+func scanFunnel(row interface{ Scan(...any) error }) (*Funnel, error) {
+	f := &Funnel{}
+	var steps []byte
+	if err := row.Scan(&f.ID, &f.Name, &f.Description, &steps, &f.Window, &f.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(steps, &f.Steps); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// ListFunnels returns all funnel definitions, newest first
+func (s *Storage) ListFunnels() ([]Funnel, error) {
+	rows, err := s.db.Query(funnelSelect + ` ORDER BY created_at DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	funnels := []Funnel{}
+	for rows.Next() {
+		f, err := scanFunnel(rows)
+		if err != nil {
+			return nil, err
+		}
+		funnels = append(funnels, *f)
+	}
+	return funnels, rows.Err()
+}
+
+// GetFunnel returns a funnel definition, or nil if it doesn't exist
+func (s *Storage) GetFunnel(id int64) (*Funnel, error) {
+	f, err := scanFunnel(s.db.QueryRow(funnelSelect+` WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return f, err
+}
+
+// DeleteFunnel removes a funnel definition, reporting whether it existed
+func (s *Storage) DeleteFunnel(id int64) (bool, error) {
+	res, err := s.db.Exec(`DELETE FROM rum_funnels WHERE id = $1`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+// EvaluateFunnel computes per-step conversion for a funnel over a segment
+func (s *Storage) EvaluateFunnel(f Funnel, filter SegmentFilter) (*FunnelReport, error) {
+	query, args, err := funnelQuery(f, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make([]int64, len(f.Steps))
+	medians := make([]sql.NullFloat64, len(f.Steps)-1)
+	dest := make([]any, 0, len(counts)+len(medians))
+	for i := range counts {
+		dest = append(dest, &counts[i])
+	}
+	for i := range medians {
+		dest = append(dest, &medians[i])
+	}
+	if err := s.db.QueryRow(query, args...).Scan(dest...); err != nil {
+		return nil, err
+	}
+
+	medianMs := make([]*float64, len(medians))
+	for i, m := range medians {
+		if m.Valid {
+			v := math.Round(m.Float64)
+			medianMs[i] = &v
+		}
+	}
+	return funnelReport(f, filter, counts, medianMs), nil
+}
+
+// pathSteps is the page views of a segment with reloads of the same page
+// collapsed, numbered per session; $1-$4 as in segmentEvents
+func pathSteps(filter SegmentFilter) string {
+	return `
+	WITH views AS (
+		SELECT ev.session_id, ev.timestamp, ev.page_url,
+			LAG(ev.page_url) OVER (PARTITION BY ev.session_id ORDER BY ev.timestamp, ev.id) AS prev
+		FROM (` + segmentEvents + humanSessions("s.is_bot", filter.IncludeBots) + `
+			AND e.event_type IN ('page_view', 'view') AND e.page_url IS NOT NULL
+		) ev
+	),
+	steps AS (
+		SELECT session_id, timestamp, page_url,
+			LAG(page_url) OVER w AS prev_page,
+			LEAD(page_url) OVER w AS next_page
+		FROM views
+		WHERE prev IS DISTINCT FROM page_url
+		WINDOW w AS (PARTITION BY session_id ORDER BY timestamp)
+	)`
+}
+
+// GetPaths analyses page paths over a segment: the pages before and after
+// page (when set), the most common transitions, entry-to-exit pairs and the
+// most common first depth pages of a session
+func (s *Storage) GetPaths(filter SegmentFilter, page string, depth, limit int) (*PathsReport, error) {
+	report := &PathsReport{Filter: filter, Page: page, Depth: depth}
+	args := []any{filter.From, filter.To, filter.Device, filter.Browser}
+	steps := pathSteps(filter)
+
+	if page != "" {
+		var err error
+		for _, dir := range []struct {
+			column, boundary string
+			dest             *[]PathCount
+		}{
+			{"next_page", "(exit)", &report.Next},
+			{"prev_page", "(entry)", &report.Previous},
+		} {
+			*dir.dest, err = s.pathCounts(steps+`
+	SELECT COALESCE(`+dir.column+`, '`+dir.boundary+`'), COUNT(*),
+		COUNT(*)::float / SUM(COUNT(*)) OVER ()
+	FROM steps WHERE page_url = $5
+	GROUP BY 1 ORDER BY 2 DESC, 1 LIMIT $6`, append(args, page, limit)...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	var err error
+	report.Transitions, err = s.pathTransitions(steps+`
+	SELECT page_url, next_page, COUNT(*) FROM steps
+	WHERE next_page IS NOT NULL
+	GROUP BY 1, 2 ORDER BY 3 DESC, 1, 2 LIMIT $5`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	report.EntryExit, err = s.pathTransitions(steps+`
+	SELECT entry_page, exit_page, COUNT(*) FROM (
+		SELECT (array_agg(page_url ORDER BY timestamp))[1] AS entry_page,
+			(array_agg(page_url ORDER BY timestamp DESC))[1] AS exit_page
+		FROM steps GROUP BY session_id
+	) sessions
+	GROUP BY 1, 2 ORDER BY 3 DESC, 1, 2 LIMIT $5`, append(args, limit)...)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(steps+`
+	SELECT path, COUNT(*), COUNT(*)::float / SUM(COUNT(*)) OVER () FROM (
+		SELECT (array_agg(page_url ORDER BY timestamp))[1:$5] AS path
+		FROM steps GROUP BY session_id
+	) sessions
+	GROUP BY path ORDER BY 2 DESC LIMIT $6`, append(args, depth, limit)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	report.Flows = []PathFlow{}
+	for rows.Next() {
+		var flow PathFlow
+		var pages pq.StringArray
+		if err := rows.Scan(&pages, &flow.Sessions, &flow.Share); err != nil {
+			return nil, err
+		}
+		flow.Pages, flow.Share = pages, roundRate(flow.Share)
+		report.Flows = append(report.Flows, flow)
+	}
+	return report, rows.Err()
+}
+
+// pathCounts reads (page, count, share) rows
+func (s *Storage) pathCounts(query string, args ...any) ([]PathCount, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := []PathCount{}
+	for rows.Next() {
+		var c PathCount
+		if err := rows.Scan(&c.Page, &c.Count, &c.Share); err != nil {
+			return nil, err
+		}
+		c.Share = roundRate(c.Share)
+		counts = append(counts, c)
+	}
+	return counts, rows.Err()
+}
+
+// pathTransitions reads (from, to, count) rows
+func (s *Storage) pathTransitions(query string, args ...any) ([]PathTransition, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transitions := []PathTransition{}
+	for rows.Next() {
+		var t PathTransition
+		if err := rows.Scan(&t.From, &t.To, &t.Count); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, rows.Err()
+}
+
+// GetCohorts builds the retention matrix of visitors first seen in [from, to),
+// grouped by week or month, from the rum_visitor_days rollup
+func (s *Storage) GetCohorts(grain string, from, to, now time.Time) (*CohortReport, error) {
+	offset, ok := cohortOffset[grain]
+	if !ok {
+		return nil, fmt.Errorf("unknown cohort grain %q", grain)
+	}
+	periods := 0
+	for c := from; c.Before(to); c = addGrains(grain, c, 1) {
+		periods++
+	}
+
+	cohorts := `
+	WITH cohorts AS (
+		SELECT uuid, date_trunc($1, first_seen AT TIME ZONE 'UTC') AS cohort
+		FROM rum_visitors
+		WHERE first_seen >= $2 AND first_seen < $3
+	)`
+
+	rows, err := s.db.Query(cohorts+`
+	SELECT cohort, COUNT(*) FROM cohorts GROUP BY cohort`, grain, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sizes := make(map[time.Time]int64)
+	for rows.Next() {
+		var cohort time.Time
+		var size int64
+		if err := rows.Scan(&cohort, &size); err != nil {
+			return nil, err
+		}
+		sizes[time.Date(cohort.Year(), cohort.Month(), cohort.Day(), 0, 0, 0, 0, time.UTC)] = size
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	cellRows, err := s.db.Query(cohorts+`,
+	active AS (
+		SELECT DISTINCT d.visitor_uuid, date_trunc($1, d.day::timestamp) AS period
+		FROM rum_visitor_days d
+		JOIN cohorts c ON c.uuid = d.visitor_uuid
+	)
+	SELECT c.cohort, `+offset+` AS period_offset, COUNT(*)
+	FROM cohorts c
+	JOIN active a ON a.visitor_uuid = c.uuid
+	WHERE a.period >= c.cohort AND `+offset+` < $4
+	GROUP BY 1, 2
+	ORDER BY 1, 2`, grain, from, to, periods)
+	if err != nil {
+		return nil, err
+	}
+	defer cellRows.Close()
+
+	var cells []cohortCell
+	for cellRows.Next() {
+		var cell cohortCell
+		if err := cellRows.Scan(&cell.Cohort, &cell.Offset, &cell.Active); err != nil {
+			return nil, err
+		}
+		cell.Cohort = time.Date(cell.Cohort.Year(), cell.Cohort.Month(), cell.Cohort.Day(), 0, 0, 0, 0, time.UTC)
+		cells = append(cells, cell)
+	}
+	if err := cellRows.Err(); err != nil {
+		return nil, err
+	}
+
+	return buildCohortReport(grain, from, to, now, sizes, cells), nil
+}
+
+// GetActiveUsers returns daily DAU and trailing 7- and 30-day active visitors
+// for each UTC day in [from, to], from the rum_visitor_days rollup
+func (s *Storage) GetActiveUsers(from, to time.Time) (*ActiveUsersReport, error) {
+	query := `
+	SELECT days.day::date,
+		(SELECT COUNT(*) FROM rum_visitor_days v WHERE v.day = days.day::date),
+		(SELECT COUNT(DISTINCT visitor_uuid) FROM rum_visitor_days v WHERE v.day > days.day::date - 7 AND v.day <= days.day::date),
+		(SELECT COUNT(DISTINCT visitor_uuid) FROM rum_visitor_days v WHERE v.day > days.day::date - 30 AND v.day <= days.day::date)
+	FROM generate_series($1::date, $2::date, INTERVAL '1 day') AS days(day)
+	ORDER BY 1`
+
+	rows, err := s.db.Query(query, from.UTC().Format("2006-01-02"), to.UTC().Format("2006-01-02"))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	series := []ActiveUsersPoint{}
+	for rows.Next() {
+		var p ActiveUsersPoint
+		var day time.Time
+		if err := rows.Scan(&day, &p.DAU, &p.WAU, &p.MAU); err != nil {
+			return nil, err
+		}
+		p.Date = day.Format("2006-01-02")
+		series = append(series, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buildActiveUsersReport(from, to, series), nil
+}
+
+// rollupBucket is the SQL truncating a timestamp column to the UTC start of
+// the grain bound to $3
+func rollupBucket(column string) string {
+	return fmt.Sprintf("date_trunc($3::text, %s AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'", column)
+}
+
+// RollupWatermark returns the end of the last complete bucket rolled up for
+// a grain, or the zero time before the first rollup
+func (s *Storage) RollupWatermark(grain string) (time.Time, error) {
+	var rolled time.Time
+	err := s.db.QueryRow(`SELECT rolled_until FROM rum_rollup_state WHERE grain = $1`, grain).Scan(&rolled)
+	if err == sql.ErrNoRows {
+		return time.Time{}, nil
+	}
+	return rolled, err
+}
+
+// EarliestRawData returns the time of the oldest session or event, or the
+// zero time when there are none
+func (s *Storage) EarliestRawData() (time.Time, error) {
+	var earliest sql.NullTime
+	err := s.db.QueryRow(`
+		SELECT LEAST((SELECT MIN(start_time) FROM rum_sessions), (SELECT MIN(timestamp) FROM rum_events))
+	`).Scan(&earliest)
+	return earliest.Time, err
+}
+
+// BuildRollups replaces a grain's buckets in [from, to) with aggregates of
+// the raw tables and advances the grain's watermark to rolledUntil, in one
+// transaction. Sessions count toward their entry page and the bucket they
+// started in, so they sum across buckets and pages without double counting.
+func (s *Storage) BuildRollups(grain string, from, to, rolledUntil time.Time) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"rum_rollups", "rum_rollup_vitals"} {
+		if _, err := tx.Exec(`DELETE FROM `+table+` WHERE grain = $1 AND bucket >= $2 AND bucket < $3`, grain, from, to); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`
+	INSERT INTO rum_rollups (grain, bucket, page_url, device_type, country, views, sessions, errors, duration_ms, timed_sessions)
+	SELECT $3::text, bucket, page_url, device_type, country, SUM(views), SUM(sessions), SUM(errors), SUM(duration_ms), SUM(timed_sessions)
+	FROM (
+		SELECT %s AS bucket, COALESCE(e.page_url, '') AS page_url, COALESCE(s.device_type, '') AS device_type, COALESCE(v.country, '') AS country,
+			COUNT(*) FILTER (WHERE e.event_type IN ('view', 'page_view')) AS views, 0 AS sessions,
+			COUNT(*) FILTER (WHERE e.event_type = 'error') AS errors, 0 AS duration_ms, 0 AS timed_sessions
+		FROM rum_events e
+		LEFT JOIN rum_sessions s ON s.session_id = e.session_id
+		LEFT JOIN rum_visitors v ON v.uuid = e.visitor_uuid
+		WHERE e.timestamp >= $1 AND e.timestamp < $2 AND e.event_type IN ('view', 'page_view', 'error') AND s.is_bot IS NOT TRUE
+		GROUP BY 1, 2, 3, 4
+
+		UNION ALL
+
+		SELECT %s, COALESCE(o.page_url, ''), COALESCE(s.device_type, ''), COALESCE(v.country, ''), 0, 0, COUNT(*), 0, 0
+		FROM rum_error_occurrences o
+		LEFT JOIN rum_sessions s ON s.session_id = o.session_id
+		LEFT JOIN rum_visitors v ON v.uuid = o.visitor_uuid
+		WHERE o.timestamp >= $1 AND o.timestamp < $2 AND s.is_bot IS NOT TRUE
+		GROUP BY 1, 2, 3, 4
+
+		UNION ALL
+
+		SELECT %s, COALESCE(s.entry_page, ''), COALESCE(s.device_type, ''), COALESCE(v.country, ''), 0, COUNT(*), 0,
+			COALESCE(SUM(s.duration_ms) FILTER (WHERE s.duration_ms > 0), 0), COUNT(*) FILTER (WHERE s.duration_ms > 0)
+		FROM rum_sessions s
+		LEFT JOIN rum_visitors v ON v.uuid = s.visitor_uuid
+		WHERE s.start_time >= $1 AND s.start_time < $2 AND NOT s.is_bot
+		GROUP BY 1, 2, 3, 4
+	) facts
+	GROUP BY bucket, page_url, device_type, country`,
+		rollupBucket("e.timestamp"), rollupBucket("o.timestamp"), rollupBucket("s.start_time")), from, to, grain)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(fmt.Sprintf(`
+	INSERT INTO rum_rollup_vitals (grain, bucket, page_url, device_type, country, metric, count, p50, p75, p95)
+	SELECT $3::text, %s, COALESCE(t.page_url, ''), COALESCE(t.device_type, ''), COALESCE(v.country, ''), t.metric, COUNT(*),
+		percentile_cont(0.5) WITHIN GROUP (ORDER BY t.value),
+		percentile_cont(0.75) WITHIN GROUP (ORDER BY t.value),
+		percentile_cont(0.95) WITHIN GROUP (ORDER BY t.value)
+	FROM rum_vitals t
+	LEFT JOIN rum_sessions s ON s.session_id = t.session_id
+	LEFT JOIN rum_visitors v ON v.uuid = t.visitor_uuid
+	WHERE t.timestamp >= $1 AND t.timestamp < $2 AND t.metric = ANY($4) AND s.is_bot IS NOT TRUE
+	GROUP BY 2, 3, 4, 5, 6`, rollupBucket("t.timestamp")), from, to, grain, pq.Array(coreMetrics))
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+	INSERT INTO rum_rollup_state (grain, rolled_until) VALUES ($1, $2)
+	ON CONFLICT (grain) DO UPDATE SET
+		rolled_until = GREATEST(rum_rollup_state.rolled_until, EXCLUDED.rolled_until),
+		updated_at = NOW()`, grain, rolledUntil)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// purgeQueries delete up to $2 rows of a raw table older than $1. Sessions
+// with replay chunks are left for the replay purge, which also deletes the
+// chunk data; deleting them here would cascade to the index and orphan it.
+var purgeQueries = map[string]string{
+	TableEvents:           `DELETE FROM rum_events WHERE id IN (SELECT id FROM rum_events WHERE timestamp < $1 LIMIT $2)`,
+	TableVitals:           `DELETE FROM rum_vitals WHERE id IN (SELECT id FROM rum_vitals WHERE timestamp < $1 LIMIT $2)`,
+	TableErrorOccurrences: `DELETE FROM rum_error_occurrences WHERE id IN (SELECT id FROM rum_error_occurrences WHERE timestamp < $1 LIMIT $2)`,
+	TableSessions: `DELETE FROM rum_sessions WHERE id IN (
+		SELECT s.id FROM rum_sessions s
+		WHERE s.start_time < $1 AND NOT EXISTS (SELECT 1 FROM rum_replay_chunks r WHERE r.session_id = s.session_id)
+		LIMIT $2)`,
+}
+
+// PurgeRaw deletes up to limit rows of a raw table older than before
+func (s *Storage) PurgeRaw(table string, before time.Time, limit int) (int64, error) {
+	query, ok := purgeQueries[table]
+	if !ok {
+		return 0, fmt.Errorf("no retention for table %q", table)
+	}
+	result, err := s.db.Exec(query, before, limit)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// PurgeRollups deletes up to limit rows of a grain's rollups, counting both
+// rollup tables, with buckets before before
+func (s *Storage) PurgeRollups(grain string, before time.Time, limit int) (int64, error) {
+	var total int64
+	for _, table := range []string{"rum_rollups", "rum_rollup_vitals"} {
+		result, err := s.db.Exec(`DELETE FROM `+table+` WHERE id IN (
+			SELECT id FROM `+table+` WHERE grain = $1 AND bucket < $2 LIMIT $3)`, grain, before, limit)
+		if err != nil {
+			return total, err
+		}
+		n, _ := result.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+// GetRollupSeries returns a grain's buckets in [from, to), optionally for one
+// page, device type or country
+func (s *Storage) GetRollupSeries(grain string, from, to time.Time, filter RollupFilter) (*RollupSeries, error) {
+	series := &RollupSeries{Grain: grain, From: from, To: to, Filter: filter, Points: []RollupPoint{}}
+	if rolled, err := s.RollupWatermark(grain); err != nil {
+		return nil, err
+	} else if !rolled.IsZero() {
+		series.RolledUntil = &rolled
+	}
+
+	const where = `WHERE grain = $1 AND bucket >= $2 AND bucket < $3
+		AND ($4 = '' OR page_url = $4) AND ($5 = '' OR device_type = $5) AND ($6 = '' OR country = $6)`
+	args := []any{grain, from, to, filter.Page, filter.Device, filter.Country}
+
+	rows, err := s.db.Query(`
+	SELECT bucket, SUM(views), SUM(sessions), SUM(errors),
+		COALESCE(SUM(duration_ms)::float8 / NULLIF(SUM(timed_sessions), 0), 0)
+	FROM rum_rollups `+where+`
+	GROUP BY bucket`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := make(map[time.Time]*RollupPoint)
+	for rows.Next() {
+		p := &RollupPoint{}
+		if err := rows.Scan(&p.Bucket, &p.Views, &p.Sessions, &p.Errors, &p.AvgSessionDuration); err != nil {
+			return nil, err
+		}
+		p.Bucket = p.Bucket.UTC()
+		p.AvgSessionDuration = math.Round(p.AvgSessionDuration)
+		points[p.Bucket] = p
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	vitals, err := s.db.Query(`
+	SELECT bucket, metric, SUM(p75 * count) / SUM(count)
+	FROM rum_rollup_vitals `+where+` AND count > 0
+	GROUP BY bucket, metric`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer vitals.Close()
+
+	for vitals.Next() {
+		var bucket time.Time
+		var metric string
+		var p75 float64
+		if err := vitals.Scan(&bucket, &metric, &p75); err != nil {
+			return nil, err
+		}
+		bucket = bucket.UTC()
+		p, ok := points[bucket]
+		if !ok {
+			p = &RollupPoint{Bucket: bucket}
+			points[bucket] = p
+		}
+		if p.VitalsP75 == nil {
+			p.VitalsP75 = make(map[string]float64)
+		}
+		p.VitalsP75[metric] = roundVital(p75)
+	}
+	if err := vitals.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, p := range points {
+		series.Points = append(series.Points, *p)
+	}
+	sort.Slice(series.Points, func(i, j int) bool { return series.Points[i].Bucket.Before(series.Points[j].Bucket) })
+	return series, nil
+}
+
+// GetRecentSessions retrieves recent sessions, leaving out bots unless
+// includeBots is set
+func (s *Storage) GetRecentSessions(limit, offset int, includeBots bool) ([]Session, error) {
+	query := sessionSelect + `
+	WHERE $3 OR NOT is_bot
+	ORDER BY start_time DESC
+	LIMIT $1 OFFSET $2`
+
+	rows, err := s.db.Query(query, limit, offset, includeBots)
+	if err != nil {
+		return nil, err
+	}
+	return scanSessions(rows)
+}
+
+// sessionSelect selects the columns scanSessions reads
+const sessionSelect = `
+	SELECT id, visitor_uuid, session_id, start_time, end_time, page_views,
+		duration_ms, COALESCE(referrer, ''), COALESCE(entry_page, ''),
+		COALESCE(exit_page, ''), COALESCE(user_agent, ''),
+		COALESCE(device_type, ''), COALESCE(browser, ''), COALESCE(os, ''),
+		COALESCE(browser_version, ''), COALESCE(os_version, ''), COALESCE(device_model, ''),
+		is_bot, COALESCE(bot_name, '')
+	FROM rum_sessions`
+
+// scanSessions reads sessionSelect rows
+func scanSessions(rows *sql.Rows) ([]Session, error) {
+	defer rows.Close()
+
+	var sessions []Session
+	for rows.Next() {
+		session := Session{}
+		var endTime sql.NullTime
+
+		err := rows.Scan(
+			&session.ID, &session.VisitorUUID, &session.SessionID, &session.StartTime, &endTime,
+			&session.PageViews, &session.DurationMs, &session.Referrer, &session.EntryPage,
+			&session.ExitPage, &session.UserAgent, &session.DeviceType, &session.Browser, &session.OS,
+			&session.BrowserVersion, &session.OSVersion, &session.DeviceModel, &session.IsBot, &session.BotName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		if endTime.Valid {
+			session.EndTime = &endTime.Time
+		}
+
+		sessions = append(sessions, session)
+	}
+
+	return sessions, nil
+}
+
+// VisitorConsent returns a visitor's consent state (PrivacyStore)
+func (s *Storage) VisitorConsent(uuid string) (string, bool, error) {
+	var state string
+	err := s.db.QueryRow(`SELECT consent FROM rum_visitors WHERE uuid = $1`, uuid).Scan(&state)
+	if err == sql.ErrNoRows {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return state, true, nil
+}
+
+// SetVisitorConsent records a visitor's consent state, reporting whether the
+// visitor exists (PrivacyStore)
+func (s *Storage) SetVisitorConsent(uuid, state string) (bool, error) {
+	result, err := s.db.Exec(`
+	UPDATE rum_visitors SET consent = $2, consent_updated_at = NOW()
+	WHERE uuid = $1`, uuid, state)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
+
+// IPSalt returns the IP salt of an epoch, saving candidate if there is none
+// yet (PrivacyStore)
+func (s *Storage) IPSalt(epoch int64, candidate []byte) ([]byte, error) {
+	if _, err := s.db.Exec(`
+	INSERT INTO rum_ip_salts (epoch, salt) VALUES ($1, $2)
+	ON CONFLICT (epoch) DO NOTHING`, epoch, candidate); err != nil {
+		return nil, err
+	}
+	var salt []byte
+	err := s.db.QueryRow(`SELECT salt FROM rum_ip_salts WHERE epoch = $1`, epoch).Scan(&salt)
+	return salt, err
+}
+
+// PurgeIPSalts deletes the salts of epochs before beforeEpoch (PrivacyStore)
+func (s *Storage) PurgeIPSalts(beforeEpoch int64) (int64, error) {
+	result, err := s.db.Exec(`DELETE FROM rum_ip_salts WHERE epoch < $1`, beforeEpoch)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// AppendPrivacyAudit records a consent change, export or erasure (PrivacyStore)
+func (s *Storage) AppendPrivacyAudit(entry PrivacyAuditEntry) error {
+	var data []byte
+	if len(entry.Data) > 0 {
+		var err error
+		if data, err = json.Marshal(entry.Data); err != nil {
+			return fmt.Errorf("marshal privacy audit data: %w", err)
+		}
+	}
+	if entry.At.IsZero() {
+		entry.At = time.Now()
+	}
+
+	_, err := s.db.Exec(`
+	INSERT INTO rum_privacy_audit (visitor_uuid, event, actor, detail, data, at)
+	VALUES ($1, $2, $3, $4, $5, $6)`,
+		entry.VisitorUUID, entry.Event, entry.Actor, entry.Detail, data, entry.At)
+	if err != nil {
+		return fmt.Errorf("append privacy audit: %w", err)
+	}
+	return nil
+}
+
+// ListPrivacyAudit returns the most recent privacy audit entries, filtered
+// to one visitor and/or one actor; empty filters match everything
+func (s *Storage) ListPrivacyAudit(visitorUUID, actor string, limit int) ([]PrivacyAuditEntry, error) {
+	rows, err := s.db.Query(`
+	SELECT id, visitor_uuid, event, actor, COALESCE(detail, ''), data, at
+	FROM rum_privacy_audit
+	WHERE ($1 = '' OR visitor_uuid = $1) AND ($2 = '' OR actor = $2)
+	ORDER BY at DESC, id DESC
+	LIMIT $3`, visitorUUID, actor, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []PrivacyAuditEntry{}
+	for rows.Next() {
+		var e PrivacyAuditEntry
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.VisitorUUID, &e.Event, &e.Actor, &e.Detail, &data, &e.At); err != nil {
+			return nil, err
+		}
+		if len(data) > 0 {
+			json.Unmarshal(data, &e.Data)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// visitorTables hold rows that cascade from rum_visitors, counted before an
+// erasure
+var visitorTables = []string{TableEvents, TableVitals, TableErrorOccurrences, TableSessions, "rum_visitor_days"}
+
+// EraseVisitor deletes a visitor and everything recorded about them in one
+// transaction, returning the rows deleted per table. Replay chunk rows and
+// blobs kept in Postgres are deleted explicitly since no foreign key ties
+// them to the visitor; replays on the filesystem must be deleted first with
+// Recorder.DeleteVisitor.
+func (s *Storage) EraseVisitor(uuid string) (*VisitorErasure, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	erasure := &VisitorErasure{VisitorUUID: uuid, Deleted: make(map[string]int64)}
+	for _, table := range visitorTables {
+		var n int64
+		if err := tx.QueryRow(`SELECT COUNT(*) FROM `+table+` WHERE visitor_uuid = $1`, uuid).Scan(&n); err != nil {
+			return nil, fmt.Errorf("count %s: %w", table, err)
+		}
+		erasure.Deleted[table] = n
+	}
+
+	if _, err := tx.Exec(`
+	DELETE FROM rum_replay_blobs
+	WHERE storage_key IN (SELECT storage_key FROM rum_replay_chunks WHERE visitor_uuid = $1)`, uuid); err != nil {
+		return nil, fmt.Errorf("delete replay blobs: %w", err)
+	}
+	result, err := tx.Exec(`DELETE FROM rum_replay_chunks WHERE visitor_uuid = $1`, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("delete replay chunks: %w", err)
+	}
+	erasure.Deleted["rum_replay_chunks"], _ = result.RowsAffected()
+
+	// Sessions, events, vitals, error occurrences and daily activity cascade
+	result, err = tx.Exec(`DELETE FROM rum_visitors WHERE uuid = $1`, uuid)
+	if err != nil {
+		return nil, fmt.Errorf("delete visitor: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	erasure.Deleted["rum_visitors"] = n
+	erasure.Found = n > 0
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	erasure.ErasedAt = time.Now()
+	return erasure, nil
+}
+
+// ExportVisitor returns everything stored about a visitor, or nil if the
+// visitor doesn't exist
+func (s *Storage) ExportVisitor(uuid string) (*VisitorExport, error) {
+	visitor, err := s.GetVisitorByUUID(uuid)
+	if err != nil || visitor == nil {
+		return nil, err
+	}
+	export := &VisitorExport{Visitor: visitor, ExportedAt: time.Now()}
+
+	rows, err := s.db.Query(sessionSelect+` WHERE visitor_uuid = $1 ORDER BY start_time LIMIT $2`, uuid, maxExportRows+1)
+	if err != nil {
+		return nil, err
+	}
+	if export.Sessions, err = scanSessions(rows); err != nil {
+		return nil, err
+	}
+	export.Sessions = capExport(export, export.Sessions)
+
+	if rows, err = s.db.Query(eventSelect+` WHERE visitor_uuid = $1 ORDER BY timestamp LIMIT $2`, uuid, maxExportRows+1); err != nil {
+		return nil, err
+	}
+	if export.Events, err = scanEvents(rows); err != nil {
+		return nil, err
+	}
+	export.Events = capExport(export, export.Events)
+
+	if export.Vitals, err = s.visitorVitals(uuid); err != nil {
+		return nil, err
+	}
+	export.Vitals = capExport(export, export.Vitals)
+
+	if rows, err = s.db.Query(errorOccurrenceSelect+` WHERE visitor_uuid = $1 ORDER BY timestamp LIMIT $2`, uuid, maxExportRows+1); err != nil {
+		return nil, err
+	}
+	if export.Errors, err = scanErrorOccurrences(rows); err != nil {
+		return nil, err
+	}
+	export.Errors = capExport(export, export.Errors)
+
+	if export.ReplayChunks, err = s.GetVisitorReplayChunks(uuid); err != nil {
+		return nil, err
+	}
+	return export, nil
+}
+
+// visitorVitals returns up to maxExportRows+1 of a visitor's vitals rows
+func (s *Storage) visitorVitals(uuid string) ([]VitalMeasurement, error) {
+	rows, err := s.db.Query(`
+	SELECT COALESCE(session_id, ''), timestamp, COALESCE(page_url, ''), metric, value,
+		COALESCE(resource_name, ''), COALESCE(resource_type, ''), COALESCE(size_bytes, 0)
+	FROM rum_vitals
+	WHERE visitor_uuid = $1
+	ORDER BY timestamp
+	LIMIT $2`, uuid, maxExportRows+1)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	vitals := []VitalMeasurement{}
+	for rows.Next() {
+		var v VitalMeasurement
+		if err := rows.Scan(&v.SessionID, &v.Timestamp, &v.PageURL, &v.Metric, &v.Value,
+			&v.ResourceName, &v.ResourceType, &v.SizeBytes); err != nil {
+			return nil, err
+		}
+		vitals = append(vitals, v)
+	}
+	return vitals, rows.Err()
+}
+
+// capExport trims rows read with a maxExportRows+1 limit, flagging the
+// export as truncated
+func capExport[T any](export *VisitorExport, rows []T) []T {
+	if len(rows) > maxExportRows {
+		export.Truncated = true
+		return rows[:maxExportRows]
+	}
+	return rows
 }
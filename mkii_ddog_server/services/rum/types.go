@@ -1,6 +1,9 @@
 package rum
 
-import "time"
+import (
+	"encoding/json"
+	"time"
+)
 
 // Visitor represents a unique visitor tracked by RUM
 type Visitor struct {
@@ -12,8 +15,28 @@ type Visitor struct {
 	TotalViews   int       `json:"total_views"`
 	UserAgent    string    `json:"user_agent,omitempty"`
 	IPHash       string    `json:"ip_hash,omitempty"`
-	Country      string    `json:"country,omitempty"`
+	Country      string    `json:"country,omitempty"` // ISO 3166-1 alpha-2
+	Region       string    `json:"region,omitempty"`  // ISO 3166-2 ("US-CA") or name
 	City         string    `json:"city,omitempty"`
+	ASN          int64     `json:"asn,omitempty"`
+	ASNOrg       string    `json:"asn_org,omitempty"`
+
+	Consent          string     `json:"consent"` // granted, denied or unknown
+	ConsentUpdatedAt *time.Time `json:"consent_updated_at,omitempty"`
+}
+
+// Location is what GeoIP resolves a client IP to
+type Location struct {
+	Country string
+	Region  string
+	City    string
+	ASN     int64
+	ASNOrg  string
+}
+
+// IsZero reports whether nothing was resolved
+func (l Location) IsZero() bool {
+	return l == Location{}
 }
 
 // Session represents a visitor session
@@ -32,6 +55,31 @@ type Session struct {
 	DeviceType  string     `json:"device_type,omitempty"`
 	Browser     string     `json:"browser,omitempty"`
 	OS          string     `json:"os,omitempty"`
+
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OSVersion      string `json:"os_version,omitempty"`
+	DeviceModel    string `json:"device_model,omitempty"`
+	IsBot          bool   `json:"is_bot"`
+	BotName        string `json:"bot_name,omitempty"`
+}
+
+// Client is the parsed user agent of a session, plus the bot rule that
+// matched it, if any
+type Client struct {
+	UserAgent      string `json:"user_agent"`
+	DeviceType     string `json:"device_type"`
+	DeviceModel    string `json:"device_model,omitempty"`
+	Browser        string `json:"browser"`
+	BrowserVersion string `json:"browser_version,omitempty"`
+	OS             string `json:"os"`
+	OSVersion      string `json:"os_version,omitempty"`
+	BotName        string `json:"bot_name,omitempty"`
+	BotCategory    string `json:"bot_category,omitempty"`
+}
+
+// IsBot reports whether a bot rule matched the client
+func (c Client) IsBot() bool {
+	return c.BotName != ""
 }
 
 // RUMEvent represents a Real User Monitoring event
@@ -58,6 +106,7 @@ type VisitorInitRequest struct {
 	Referrer     string `json:"referrer,omitempty"`
 	EntryPage    string `json:"entry_page,omitempty"`
 	PageURL      string `json:"page_url,omitempty"` // Alias for entry_page
+	Consent      string `json:"consent,omitempty"`  // granted or denied; recorded before anything else
 }
 
 // VisitorInitResponse is returned when initializing a visitor
@@ -66,6 +115,7 @@ type VisitorInitResponse struct {
 	SessionID   string `json:"session_id"`
 	IsNew       bool   `json:"is_new"`
 	Message     string `json:"message,omitempty"`
+	Consent     string `json:"consent"`
 	// APM trace context - allows RUM to be tied to backend traces
 	TraceID string `json:"trace_id,omitempty"`
 	SpanID  string `json:"span_id,omitempty"`
@@ -86,6 +136,33 @@ type TrackEventRequest struct {
 	// Optional: pass trace context from frontend to tie to backend traces
 	TraceID string `json:"trace_id,omitempty"`
 	SpanID  string `json:"span_id,omitempty"`
+	// Optional: when the event happened (RFC3339); batched events are sent
+	// after the fact. Ignored unless within the last day.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
+
+// BatchEventRequest is sent when tracking several RUM events at once.
+// VisitorUUID and SessionID apply to events that don't set their own.
+type BatchEventRequest struct {
+	VisitorUUID string              `json:"visitor_uuid,omitempty"`
+	SessionID   string              `json:"session_id,omitempty"`
+	Events      []TrackEventRequest `json:"events"`
+}
+
+// BatchEventResponse is returned when tracking a batch of RUM events
+type BatchEventResponse struct {
+	Status   string       `json:"status"`
+	Accepted int          `json:"accepted"`
+	Dropped  int          `json:"dropped,omitempty"` // valid, but the visitor hasn't consented
+	Rejected []BatchError `json:"rejected,omitempty"`
+	TraceID  string       `json:"trace_id,omitempty"`
+	SpanID   string       `json:"span_id,omitempty"`
+}
+
+// BatchError describes an event rejected from a batch
+type BatchError struct {
+	Index int    `json:"index"`
+	Error string `json:"error"`
 }
 
 // TrackEventResponse is returned when tracking a RUM event
@@ -114,7 +191,11 @@ type VisitorAnalytics struct {
 	ByDevice          map[string]int         `json:"by_device,omitempty"`
 	ByBrowser         map[string]int         `json:"by_browser,omitempty"`
 	ByCountry         map[string]int         `json:"by_country,omitempty"`
+	ByRegion          map[string]int         `json:"by_region,omitempty"`
+	Vitals            []VitalSummary         `json:"vitals,omitempty"` // p50/p75/p95 of the Core Web Vitals
 	Period            string                 `json:"period"`
+	Source            string                 `json:"source"` // "raw" or "rollups" (daily buckets)
+	BotSessions       int                    `json:"bot_sessions"` // counted whether or not bots are included
 }
 
 // PageStat represents page view statistics
@@ -129,3 +210,351 @@ type TimeRange struct {
 	From time.Time `json:"from"`
 	To   time.Time `json:"to"`
 }
+
+// VitalsRequest reports a page's Core Web Vitals and performance timings.
+// Vitals are optional so the SDK can report each one as it becomes final.
+type VitalsRequest struct {
+	VisitorUUID string           `json:"visitor_uuid"`
+	SessionID   string           `json:"session_id"`
+	PageURL     string           `json:"page_url"`
+	Timestamp   time.Time        `json:"timestamp,omitempty"`
+	LCP         *float64         `json:"lcp,omitempty"`  // ms
+	INP         *float64         `json:"inp,omitempty"`  // ms
+	CLS         *float64         `json:"cls,omitempty"`  // unitless
+	FCP         *float64         `json:"fcp,omitempty"`  // ms
+	TTFB        *float64         `json:"ttfb,omitempty"` // ms
+	Resources   []ResourceTiming `json:"resources,omitempty"`
+	LongTasks   []LongTask       `json:"long_tasks,omitempty"`
+}
+
+// ResourceTiming is a PerformanceResourceTiming entry
+type ResourceTiming struct {
+	Name          string  `json:"name"`
+	InitiatorType string  `json:"initiator_type,omitempty"` // script, img, fetch, xmlhttprequest, css...
+	DurationMs    float64 `json:"duration_ms"`
+	TransferSize  int64   `json:"transfer_size,omitempty"`
+}
+
+// LongTask is a main-thread task over 50ms
+type LongTask struct {
+	DurationMs  float64 `json:"duration_ms"`
+	Attribution string  `json:"attribution,omitempty"`
+}
+
+// VitalSummary holds percentiles of one metric for one group
+type VitalSummary struct {
+	Metric string  `json:"metric"`
+	Group  string  `json:"group,omitempty"` // page, device type, browser or resource type
+	Count  int     `json:"count"`
+	P50    float64 `json:"p50"`
+	P75    float64 `json:"p75"`
+	P95    float64 `json:"p95"`
+	Rating string  `json:"rating,omitempty"` // p75 against the web.dev thresholds
+}
+
+// VitalBucket holds percentiles of one metric for one time bucket
+type VitalBucket struct {
+	Metric string    `json:"metric"`
+	Bucket time.Time `json:"bucket"`
+	Count  int       `json:"count"`
+	P50    float64   `json:"p50"`
+	P75    float64   `json:"p75"`
+	P95    float64   `json:"p95"`
+}
+
+// VitalsReport is returned by the vitals analytics endpoint
+type VitalsReport struct {
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	GroupBy string         `json:"group_by,omitempty"`
+	Bucket  string         `json:"bucket"`
+	Metrics []VitalSummary `json:"metrics"`
+	Trend   []VitalBucket  `json:"trend"`
+}
+
+// ErrorReport is sent when a JavaScript error is caught
+type ErrorReport struct {
+	VisitorUUID string    `json:"visitor_uuid"`
+	SessionID   string    `json:"session_id"`
+	PageURL     string    `json:"page_url,omitempty"`
+	Message     string    `json:"message"`           // error.message, or "TypeError: ..." when type is empty
+	Type        string    `json:"type,omitempty"`    // error.name
+	Stack       string    `json:"stack,omitempty"`   // error.stack as the browser formats it
+	Release     string    `json:"release,omitempty"` // build the page was served from; selects source maps
+	Timestamp   time.Time `json:"timestamp,omitempty"`
+}
+
+// StackFrame is one frame of a parsed (and possibly symbolicated) stack
+type StackFrame struct {
+	Function     string `json:"function,omitempty"`
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+	Column       int    `json:"column"`
+	InApp        bool   `json:"in_app"`
+	Symbolicated bool   `json:"symbolicated,omitempty"`
+}
+
+// ErrorIssue groups error occurrences sharing a fingerprint
+type ErrorIssue struct {
+	Fingerprint      string       `json:"fingerprint"`
+	Type             string       `json:"type"`
+	Message          string       `json:"message"` // normalized
+	TopFrames        []StackFrame `json:"top_frames,omitempty"`
+	FirstSeen        time.Time    `json:"first_seen"`
+	LastSeen         time.Time    `json:"last_seen"`
+	Count            int64        `json:"count"`
+	AffectedSessions int          `json:"affected_sessions"`
+	LastRelease      string       `json:"last_release,omitempty"`
+}
+
+// ErrorOccurrence is a single reported error
+type ErrorOccurrence struct {
+	ID          int64        `json:"id"`
+	Fingerprint string       `json:"fingerprint"`
+	VisitorUUID string       `json:"visitor_uuid"`
+	SessionID   string       `json:"session_id"`
+	Timestamp   time.Time    `json:"timestamp"`
+	PageURL     string       `json:"page_url,omitempty"`
+	Message     string       `json:"message"`
+	Release     string       `json:"release,omitempty"`
+	Frames      []StackFrame `json:"frames,omitempty"`
+	RawStack    string       `json:"raw_stack,omitempty"`
+}
+
+// ReplayUploadRequest is one chunk of rrweb events recorded for a session
+type ReplayUploadRequest struct {
+	VisitorUUID string            `json:"visitor_uuid"`
+	Events      []json.RawMessage `json:"events"` // rrweb events: {type, data, timestamp}
+}
+
+// ReplayChunk describes a stored, compressed chunk of replay events
+type ReplayChunk struct {
+	ID          int64     `json:"id"`
+	SessionID   string    `json:"session_id"`
+	VisitorUUID string    `json:"visitor_uuid"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	EventCount  int       `json:"event_count"`
+	RawBytes    int       `json:"raw_bytes"`
+	StoredBytes int       `json:"stored_bytes"` // compressed
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ReplaySummary is shown next to a session's events
+type ReplaySummary struct {
+	Chunks     int       `json:"chunks"`
+	EventCount int       `json:"event_count"`
+	StartTime  time.Time `json:"start_time"`
+	EndTime    time.Time `json:"end_time"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// ReplayResponse is a session's replay in playback order
+type ReplayResponse struct {
+	SessionID string            `json:"session_id"`
+	Summary   ReplaySummary     `json:"summary"`
+	Chunks    []ReplayChunk     `json:"chunks"`
+	Events    []json.RawMessage `json:"events"`
+}
+
+// FunnelStep is one ordered step of a funnel. Match is a page URL or action
+// name; * matches any characters.
+type FunnelStep struct {
+	Type  string `json:"type"` // "page" or "action"
+	Match string `json:"match"`
+	Label string `json:"label,omitempty"`
+}
+
+// Funnel is a saved funnel definition
+type Funnel struct {
+	ID          int64        `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Steps       []FunnelStep `json:"steps"`
+	Window      string       `json:"window,omitempty"` // max time from the first step, e.g. "30m"; empty = same session
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// SegmentFilter narrows journey analytics to a time range and segment
+type SegmentFilter struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Device  string    `json:"device,omitempty"`
+	Browser string    `json:"browser,omitempty"`
+
+	IncludeBots bool `json:"include_bots,omitempty"`
+}
+
+// FunnelStepResult is the conversion at one funnel step
+type FunnelStepResult struct {
+	Step             int      `json:"step"`
+	Label            string   `json:"label"`
+	Sessions         int64    `json:"sessions"`
+	ConversionRate   float64  `json:"conversion_rate"` // of sessions entering the funnel
+	StepConversion   float64  `json:"step_conversion"` // of sessions reaching the previous step
+	DropOff          int64    `json:"drop_off"`        // sessions lost since the previous step
+	DropOffRate      float64  `json:"drop_off_rate"`
+	MedianTimeFromMs *float64 `json:"median_time_from_previous_ms,omitempty"`
+}
+
+// FunnelReport is a funnel evaluated over a segment
+type FunnelReport struct {
+	Funnel         Funnel             `json:"funnel"`
+	Filter         SegmentFilter      `json:"filter"`
+	Entered        int64              `json:"entered"`
+	Converted      int64              `json:"converted"`
+	ConversionRate float64            `json:"conversion_rate"`
+	Steps          []FunnelStepResult `json:"steps"`
+}
+
+// PathCount is a page reached from (or leading to) another page
+type PathCount struct {
+	Page  string  `json:"page"` // "(entry)" or "(exit)" at session boundaries
+	Count int64   `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// PathTransition is a page-to-page move within sessions
+type PathTransition struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Count int64  `json:"count"`
+}
+
+// PathFlow is a sequence of pages shared by sessions
+type PathFlow struct {
+	Pages    []string `json:"pages"`
+	Sessions int64    `json:"sessions"`
+	Share    float64  `json:"share"`
+}
+
+// PathsReport is the page-path analysis for a segment
+type PathsReport struct {
+	Filter      SegmentFilter    `json:"filter"`
+	Page        string           `json:"page,omitempty"`
+	Next        []PathCount      `json:"next,omitempty"`
+	Previous    []PathCount      `json:"previous,omitempty"`
+	Transitions []PathTransition `json:"transitions"`
+	EntryExit   []PathTransition `json:"entry_exit"` // first and last page of each session
+	Flows       []PathFlow       `json:"flows"`      // first Depth pages of each session
+	Depth       int              `json:"depth"`
+}
+
+// CohortRow is the retention of visitors first seen in one period
+type CohortRow struct {
+	Cohort    time.Time `json:"cohort"` // start of the week or month
+	Size      int64     `json:"size"`
+	Retained  []int64   `json:"retained"`  // active visitors per period offset, from 0; elapsed periods only
+	Retention []float64 `json:"retention"` // Retained / Size
+}
+
+// CohortReport is a retention matrix
+type CohortReport struct {
+	Grain   string      `json:"grain"` // "week" or "month"
+	From    time.Time   `json:"from"`
+	To      time.Time   `json:"to"`
+	Periods int         `json:"periods"`
+	Cohorts []CohortRow `json:"cohorts"`
+	Average []float64   `json:"average"` // size-weighted retention per offset
+}
+
+// ActiveUsersPoint is one day of active-visitor counts
+type ActiveUsersPoint struct {
+	Date             string  `json:"date"`
+	DAU              int64   `json:"dau"`
+	WAU              int64   `json:"wau"`               // trailing 7 days
+	MAU              int64   `json:"mau"`               // trailing 30 days
+	Stickiness       float64 `json:"stickiness"`        // DAU / MAU
+	WeeklyStickiness float64 `json:"weekly_stickiness"` // DAU / WAU
+}
+
+// ActiveUsersReport is DAU/WAU/MAU over a range
+type ActiveUsersReport struct {
+	From       string             `json:"from"`
+	To         string             `json:"to"`
+	Series     []ActiveUsersPoint `json:"series"`
+	AvgDAU     float64            `json:"avg_dau"`
+	WAU        int64              `json:"wau"`        // as of the last day
+	MAU        int64              `json:"mau"`        // as of the last day
+	Stickiness float64            `json:"stickiness"` // AvgDAU / MAU
+}
+
+// RollupPoint is one hourly or daily bucket of the rollup tables
+type RollupPoint struct {
+	Bucket             time.Time          `json:"bucket"`
+	Views              int64              `json:"views"`
+	Sessions           int64              `json:"sessions"` // sessions started in the bucket
+	Errors             int64              `json:"errors"`
+	AvgSessionDuration float64            `json:"avg_session_duration_ms"`
+	VitalsP75          map[string]float64 `json:"vitals_p75,omitempty"` // sample-weighted across pages
+}
+
+// RollupSeries is a time series read from the rollup tables
+type RollupSeries struct {
+	Grain       string        `json:"grain"` // "hour" or "day"
+	From        time.Time     `json:"from"`
+	To          time.Time     `json:"to"`
+	Filter      RollupFilter  `json:"filter"`
+	RolledUntil *time.Time    `json:"rolled_until,omitempty"` // buckets before this are complete
+	Points      []RollupPoint `json:"points"`
+}
+
+// RollupFilter narrows a rollup series to one page, device type or country
+type RollupFilter struct {
+	Page    string `json:"page,omitempty"`
+	Device  string `json:"device,omitempty"`
+	Country string `json:"country,omitempty"`
+}
+
+// ConsentRequest sets a visitor's consent state
+type ConsentRequest struct {
+	State string `json:"state"`           // granted, denied or unknown
+	Actor string `json:"actor,omitempty"` // defaults to "visitor"
+}
+
+// PrivacyAuditEntry records a consent change, export or erasure. Entries
+// hold counts, never the personal data itself, and outlive the visitor.
+type PrivacyAuditEntry struct {
+	ID          int64          `json:"id"`
+	VisitorUUID string         `json:"visitor_uuid"`
+	Event       string         `json:"event"` // consent, export or erase
+	Actor       string         `json:"actor"`
+	Detail      string         `json:"detail,omitempty"`
+	Data        map[string]any `json:"data,omitempty"`
+	At          time.Time      `json:"at"`
+}
+
+// VitalMeasurement is one stored vitals row
+type VitalMeasurement struct {
+	SessionID    string    `json:"session_id"`
+	Timestamp    time.Time `json:"timestamp"`
+	PageURL      string    `json:"page_url,omitempty"`
+	Metric       string    `json:"metric"`
+	Value        float64   `json:"value"`
+	ResourceName string    `json:"resource_name,omitempty"`
+	ResourceType string    `json:"resource_type,omitempty"`
+	SizeBytes    int64     `json:"size_bytes,omitempty"`
+}
+
+// VisitorExport is everything stored about a visitor, for data-subject
+// access requests. Each list holds at most maxExportRows rows; Truncated is
+// set when one was cut short.
+type VisitorExport struct {
+	Visitor      *Visitor           `json:"visitor"`
+	Sessions     []Session          `json:"sessions"`
+	Events       []RUMEvent         `json:"events"`
+	Vitals       []VitalMeasurement `json:"vitals"`
+	Errors       []ErrorOccurrence  `json:"errors"`
+	ReplayChunks []ReplayChunk      `json:"replay_chunks"`
+	Truncated    bool               `json:"truncated"`
+	ExportedAt   time.Time          `json:"exported_at"`
+}
+
+// VisitorErasure reports what was deleted for a visitor
+type VisitorErasure struct {
+	VisitorUUID string           `json:"visitor_uuid"`
+	Found       bool             `json:"found"`   // whether a visitor record existed
+	Deleted     map[string]int64 `json:"deleted"` // rows per table
+	ErasedAt    time.Time        `json:"erased_at"`
+}
@@ -0,0 +1,114 @@
+package rum
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Device types
+const (
+	DeviceDesktop = "desktop"
+	DeviceMobile  = "mobile"
+	DeviceTablet  = "tablet"
+	DeviceBot     = "bot"
+)
+
+// uaRule names a browser or OS when re matches; the first submatch, if
+// any, is its version
+type uaRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// browserRules are checked in order, so browsers built on Chromium or
+// WebKit come before Chrome and Safari, whose tokens they also send
+var browserRules = []uaRule{
+	{"Edge", regexp.MustCompile(`\bEdg(?:e|A|iOS)?/(\d+)`)},
+	{"Opera", regexp.MustCompile(`\b(?:OPR|OPiOS|Opera)/(\d+)`)},
+	{"Samsung Internet", regexp.MustCompile(`\bSamsungBrowser/(\d+)`)},
+	{"Yandex", regexp.MustCompile(`\bYaBrowser/(\d+)`)},
+	{"Vivaldi", regexp.MustCompile(`\bVivaldi/(\d+)`)},
+	{"Firefox", regexp.MustCompile(`\b(?:Firefox|FxiOS)/(\d+)`)},
+	{"Chrome", regexp.MustCompile(`(?:Chrome|CriOS)/(\d+)`)},
+	{"Safari", regexp.MustCompile(`\bVersion/(\d+(?:\.\d+)?).*\bSafari/`)},
+	{"Internet Explorer", regexp.MustCompile(`(?:\bMSIE |\bTrident/.*\brv:)(\d+)`)},
+}
+
+// osRules are checked in order; iOS sends "like Mac OS X" and Android
+// sends "Linux", so both come before macOS and Linux
+var osRules = []uaRule{
+	{"iOS", regexp.MustCompile(`\b(?:iPhone|iPod|iPad)\b.*?\bOS (\d+(?:_\d+)*)`)},
+	{"Android", regexp.MustCompile(`\bAndroid(?: (\d+(?:\.\d+)*))?`)},
+	{"Chrome OS", regexp.MustCompile(`\bCrOS \w+ (\d+(?:\.\d+)*)`)},
+	{"Windows", regexp.MustCompile(`\bWindows NT (\d+\.\d+)`)},
+	{"macOS", regexp.MustCompile(`\bMac OS X(?: (\d+(?:[_.]\d+)*))?`)},
+	{"Linux", regexp.MustCompile(`\b(?:Linux|X11)\b`)},
+}
+
+// windowsVersions maps NT versions to marketing names; Windows 11 still
+// reports NT 10.0
+var windowsVersions = map[string]string{
+	"10.0": "10",
+	"6.3":  "8.1",
+	"6.2":  "8",
+	"6.1":  "7",
+	"6.0":  "Vista",
+	"5.1":  "XP",
+}
+
+// androidModel is the device model of "Android 14; Pixel 7 Build/..."
+var androidModel = regexp.MustCompile(`\bAndroid[^;)]*;\s*([^;)]+?)(?:\s+Build/[^;)]*)?\s*\)`)
+
+// ParseUserAgent extracts browser, OS and device from a user agent. Browser
+// versions are major only; OS versions keep their minor and patch parts.
+// Bot detection needs request headers too, see BotDetector.
+func ParseUserAgent(ua string) Client {
+	client := Client{UserAgent: ua, DeviceType: DeviceDesktop, Browser: "Unknown", OS: "Unknown"}
+
+	for _, rule := range browserRules {
+		if m := rule.re.FindStringSubmatch(ua); m != nil {
+			client.Browser, client.BrowserVersion = rule.name, m[1]
+			break
+		}
+	}
+
+	for _, rule := range osRules {
+		m := rule.re.FindStringSubmatch(ua)
+		if m == nil {
+			continue
+		}
+		client.OS = rule.name
+		if len(m) > 1 {
+			client.OSVersion = strings.ReplaceAll(m[1], "_", ".")
+		}
+		if rule.name == "Windows" {
+			client.OSVersion = windowsVersions[client.OSVersion]
+		}
+		break
+	}
+
+	switch {
+	case strings.Contains(ua, "iPad"):
+		client.DeviceType, client.DeviceModel = DeviceTablet, "iPad"
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPod"):
+		client.DeviceType, client.DeviceModel = DeviceMobile, "iPhone"
+		if strings.Contains(ua, "iPod") {
+			client.DeviceModel = "iPod"
+		}
+	case client.OS == "Android":
+		// Android tablets leave "Mobile" out
+		client.DeviceType = DeviceTablet
+		if strings.Contains(ua, "Mobile") {
+			client.DeviceType = DeviceMobile
+		}
+		// Reduced user agents replace the model with "K"
+		if m := androidModel.FindStringSubmatch(ua); m != nil && m[1] != "K" {
+			client.DeviceModel = m[1]
+		}
+	case strings.Contains(ua, "Mobi"):
+		client.DeviceType = DeviceMobile
+	case strings.Contains(ua, "Tablet"):
+		client.DeviceType = DeviceTablet
+	}
+	return client
+}
@@ -0,0 +1,63 @@
+package rum
+
+import "testing"
+
+func TestParseUserAgent(t *testing.T) {
+	cases := []struct {
+		ua   string
+		want Client
+	}{
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36",
+			Client{DeviceType: DeviceDesktop, Browser: "Chrome", BrowserVersion: "122", OS: "Windows", OSVersion: "10"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.0.0 Safari/537.36 Edg/121.0.2277.128",
+			Client{DeviceType: DeviceDesktop, Browser: "Edge", BrowserVersion: "121", OS: "Windows", OSVersion: "10"},
+		},
+		{
+			"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.3 Safari/605.1.15",
+			Client{DeviceType: DeviceDesktop, Browser: "Safari", BrowserVersion: "17.3", OS: "macOS", OSVersion: "10.15.7"},
+		},
+		{
+			"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:123.0) Gecko/20100101 Firefox/123.0",
+			Client{DeviceType: DeviceDesktop, Browser: "Firefox", BrowserVersion: "123", OS: "Linux"},
+		},
+		{
+			"Mozilla/5.0 (iPhone; CPU iPhone OS 17_3_1 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.3 Mobile/15E148 Safari/604.1",
+			Client{DeviceType: DeviceMobile, DeviceModel: "iPhone", Browser: "Safari", BrowserVersion: "17.3", OS: "iOS", OSVersion: "17.3.1"},
+		},
+		{
+			"Mozilla/5.0 (iPad; CPU OS 16_6 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) CriOS/120.0.6099.119 Mobile/15E148 Safari/604.1",
+			Client{DeviceType: DeviceTablet, DeviceModel: "iPad", Browser: "Chrome", BrowserVersion: "120", OS: "iOS", OSVersion: "16.6"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 14; Pixel 7 Build/UQ1A.240205.002) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.6167.178 Mobile Safari/537.36",
+			Client{DeviceType: DeviceMobile, DeviceModel: "Pixel 7", Browser: "Chrome", BrowserVersion: "121", OS: "Android", OSVersion: "14"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 13; SM-X700) AppleWebKit/537.36 (KHTML, like Gecko) SamsungBrowser/23.0 Chrome/115.0.0.0 Safari/537.36",
+			Client{DeviceType: DeviceTablet, DeviceModel: "SM-X700", Browser: "Samsung Internet", BrowserVersion: "23", OS: "Android", OSVersion: "13"},
+		},
+		{
+			"Mozilla/5.0 (Linux; Android 10; K) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Mobile Safari/537.36",
+			Client{DeviceType: DeviceMobile, Browser: "Chrome", BrowserVersion: "122", OS: "Android", OSVersion: "10"},
+		},
+		{
+			"Mozilla/5.0 (X11; CrOS x86_64 14541.0.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36",
+			Client{DeviceType: DeviceDesktop, Browser: "Chrome", BrowserVersion: "120", OS: "Chrome OS", OSVersion: "14541.0.0"},
+		},
+		{
+			"Mozilla/5.0 (Windows NT 6.1; Trident/7.0; rv:11.0) like Gecko",
+			Client{DeviceType: DeviceDesktop, Browser: "Internet Explorer", BrowserVersion: "11", OS: "Windows", OSVersion: "7"},
+		},
+		{"", Client{DeviceType: DeviceDesktop, Browser: "Unknown", OS: "Unknown"}},
+	}
+
+	for _, tc := range cases {
+		tc.want.UserAgent = tc.ua
+		if got := ParseUserAgent(tc.ua); got != tc.want {
+			t.Errorf("%s:\n got  %+v\n want %+v", tc.ua, got, tc.want)
+		}
+	}
+}
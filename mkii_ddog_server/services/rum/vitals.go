@@ -0,0 +1,149 @@
+package rum
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Performance metrics stored in rum_vitals
+const (
+	MetricLCP      = "lcp"  // Largest Contentful Paint (ms)
+	MetricINP      = "inp"  // Interaction to Next Paint (ms)
+	MetricCLS      = "cls"  // Cumulative Layout Shift (unitless)
+	MetricFCP      = "fcp"  // First Contentful Paint (ms)
+	MetricTTFB     = "ttfb" // Time to First Byte (ms)
+	MetricResource = "resource"
+	MetricLongTask = "long_task"
+)
+
+// coreMetrics are reported when a query names no metrics
+var coreMetrics = []string{MetricLCP, MetricINP, MetricCLS, MetricFCP, MetricTTFB}
+
+// Limits on a single vitals report
+const (
+	maxResourceTimings = 200
+	maxLongTasks       = 100
+	maxTimingMs        = 10 * 60 * 1000 // anything longer is a broken measurement
+	maxCLS             = 100
+)
+
+// vitalThresholds are the good / poor boundaries for each Core Web Vital,
+// applied to p75 as recommended by web.dev
+var vitalThresholds = map[string][2]float64{
+	MetricLCP:  {2500, 4000},
+	MetricINP:  {200, 500},
+	MetricCLS:  {0.1, 0.25},
+	MetricFCP:  {1800, 3000},
+	MetricTTFB: {800, 1800},
+}
+
+// vitalDimensions maps the ?by= values to rum_vitals columns
+var vitalDimensions = map[string]string{
+	"page":          "page_url",
+	"device":        "device_type",
+	"browser":       "browser",
+	"resource_type": "resource_type",
+}
+
+// vitalRow is one measurement as stored in rum_vitals
+type vitalRow struct {
+	Metric       string
+	Value        float64
+	ResourceName string
+	ResourceType string
+	SizeBytes    int64
+}
+
+// rows validates the report and flattens it into one row per measurement
+func (req VitalsRequest) rows() ([]vitalRow, error) {
+	if req.VisitorUUID == "" || req.SessionID == "" {
+		return nil, errors.New("visitor_uuid and session_id are required")
+	}
+	if len(req.Resources) > maxResourceTimings {
+		return nil, fmt.Errorf("at most %d resource timings per report", maxResourceTimings)
+	}
+	if len(req.LongTasks) > maxLongTasks {
+		return nil, fmt.Errorf("at most %d long tasks per report", maxLongTasks)
+	}
+
+	var rows []vitalRow
+	for _, v := range []struct {
+		metric string
+		value  *float64
+	}{
+		{MetricLCP, req.LCP}, {MetricINP, req.INP}, {MetricCLS, req.CLS},
+		{MetricFCP, req.FCP}, {MetricTTFB, req.TTFB},
+	} {
+		if v.value == nil {
+			continue
+		}
+		limit := float64(maxTimingMs)
+		if v.metric == MetricCLS {
+			limit = maxCLS
+		}
+		if *v.value < 0 || *v.value > limit {
+			return nil, fmt.Errorf("%s out of range: %v", v.metric, *v.value)
+		}
+		rows = append(rows, vitalRow{Metric: v.metric, Value: *v.value})
+	}
+
+	for i, res := range req.Resources {
+		if res.Name == "" || res.DurationMs < 0 || res.DurationMs > maxTimingMs {
+			return nil, fmt.Errorf("invalid resource timing at index %d", i)
+		}
+		rows = append(rows, vitalRow{
+			Metric:       MetricResource,
+			Value:        res.DurationMs,
+			ResourceName: res.Name,
+			ResourceType: strings.ToLower(res.InitiatorType),
+			SizeBytes:    res.TransferSize,
+		})
+	}
+	for i, task := range req.LongTasks {
+		if task.DurationMs <= 0 || task.DurationMs > maxTimingMs {
+			return nil, fmt.Errorf("invalid long task at index %d", i)
+		}
+		rows = append(rows, vitalRow{Metric: MetricLongTask, Value: task.DurationMs, ResourceName: task.Attribution})
+	}
+
+	if len(rows) == 0 {
+		return nil, errors.New("no measurements in report")
+	}
+	return rows, nil
+}
+
+// rateVital classifies a p75 value as good, needs_improvement or poor;
+// metrics without thresholds are not rated
+func rateVital(metric string, p75 float64) string {
+	t, ok := vitalThresholds[metric]
+	if !ok {
+		return ""
+	}
+	switch {
+	case p75 <= t[0]:
+		return "good"
+	case p75 <= t[1]:
+		return "needs_improvement"
+	}
+	return "poor"
+}
+
+// parseMetrics validates a comma-separated metric list, defaulting to the
+// Core Web Vitals
+func parseMetrics(value string) ([]string, error) {
+	if value == "" {
+		return coreMetrics, nil
+	}
+	var metrics []string
+	for _, m := range strings.Split(value, ",") {
+		m = strings.ToLower(strings.TrimSpace(m))
+		switch m {
+		case MetricLCP, MetricINP, MetricCLS, MetricFCP, MetricTTFB, MetricResource, MetricLongTask:
+			metrics = append(metrics, m)
+		default:
+			return nil, fmt.Errorf("unknown metric %q", m)
+		}
+	}
+	return metrics, nil
+}
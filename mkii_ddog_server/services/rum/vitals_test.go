@@ -0,0 +1,115 @@
+package rum
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func ptr(v float64) *float64 { return &v }
+
+func TestVitalsRequest_Rows(t *testing.T) {
+	req := VitalsRequest{
+		VisitorUUID: "v1",
+		SessionID:   "s1",
+		PageURL:     "/checkout",
+		LCP:         ptr(2300),
+		CLS:         ptr(0.05),
+		Resources:   []ResourceTiming{{Name: "https://cdn/app.js", InitiatorType: "Script", DurationMs: 120, TransferSize: 5120}},
+		LongTasks:   []LongTask{{DurationMs: 80, Attribution: "self"}},
+	}
+
+	rows, err := req.rows()
+	if err != nil {
+		t.Fatalf("rows: %v", err)
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 measurements, got %+v", rows)
+	}
+	if rows[0] != (vitalRow{Metric: MetricLCP, Value: 2300}) || rows[1].Metric != MetricCLS {
+		t.Fatalf("expected vitals first, got %+v", rows[:2])
+	}
+	if rows[2] != (vitalRow{Metric: MetricResource, Value: 120, ResourceName: "https://cdn/app.js", ResourceType: "script", SizeBytes: 5120}) {
+		t.Fatalf("unexpected resource row %+v", rows[2])
+	}
+	if rows[3] != (vitalRow{Metric: MetricLongTask, Value: 80, ResourceName: "self"}) {
+		t.Fatalf("unexpected long task row %+v", rows[3])
+	}
+}
+
+func TestVitalsRequest_RowsValidation(t *testing.T) {
+	base := VitalsRequest{VisitorUUID: "v1", SessionID: "s1"}
+	tests := map[string]func(r *VitalsRequest){
+		"missing session": func(r *VitalsRequest) { r.SessionID = ""; r.LCP = ptr(1) },
+		"empty report":    func(r *VitalsRequest) {},
+		"negative lcp":    func(r *VitalsRequest) { r.LCP = ptr(-1) },
+		"huge cls":        func(r *VitalsRequest) { r.CLS = ptr(500) },
+		"unnamed resource": func(r *VitalsRequest) {
+			r.Resources = []ResourceTiming{{DurationMs: 10}}
+		},
+		"zero long task": func(r *VitalsRequest) { r.LongTasks = []LongTask{{}} },
+		"too many resources": func(r *VitalsRequest) {
+			r.Resources = make([]ResourceTiming, maxResourceTimings+1)
+		},
+	}
+	for name, mutate := range tests {
+		req := base
+		mutate(&req)
+		if _, err := req.rows(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestRateVital(t *testing.T) {
+	tests := []struct {
+		metric string
+		p75    float64
+		want   string
+	}{
+		{MetricLCP, 2500, "good"},
+		{MetricLCP, 3000, "needs_improvement"},
+		{MetricLCP, 4001, "poor"},
+		{MetricCLS, 0.05, "good"},
+		{MetricCLS, 0.3, "poor"},
+		{MetricINP, 350, "needs_improvement"},
+		{MetricResource, 10000, ""},
+	}
+	for _, tt := range tests {
+		if got := rateVital(tt.metric, tt.p75); got != tt.want {
+			t.Errorf("%s at %v: expected %q, got %q", tt.metric, tt.p75, tt.want, got)
+		}
+	}
+}
+
+func TestParseMetrics(t *testing.T) {
+	if metrics, _ := parseMetrics(""); strings.Join(metrics, ",") != "lcp,inp,cls,fcp,ttfb" {
+		t.Fatalf("expected the Core Web Vitals by default, got %v", metrics)
+	}
+	if metrics, err := parseMetrics("LCP, long_task"); err != nil || strings.Join(metrics, ",") != "lcp,long_task" {
+		t.Fatalf("unexpected metrics %v (%v)", metrics, err)
+	}
+	if _, err := parseMetrics("lcp,fid"); err == nil {
+		t.Fatal("expected an unknown metric rejected")
+	}
+}
+
+func TestVitalBucket(t *testing.T) {
+	if b, _ := vitalBucket("", 24*time.Hour); b != time.Hour {
+		t.Fatalf("expected 1h buckets for a day, got %s", b)
+	}
+	if b, _ := vitalBucket("", 7*24*time.Hour); b != 24*time.Hour {
+		t.Fatalf("expected daily buckets for a week, got %s", b)
+	}
+	if b, err := vitalBucket("15m", 24*time.Hour); err != nil || b != 15*time.Minute {
+		t.Fatalf("expected 15m, got %s (%v)", b, err)
+	}
+	for _, bad := range []string{"10s", "soon"} {
+		if _, err := vitalBucket(bad, time.Hour); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+	if _, err := vitalBucket("1m", 30*24*time.Hour); err == nil {
+		t.Fatal("expected too many buckets rejected")
+	}
+}
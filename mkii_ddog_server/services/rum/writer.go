@@ -0,0 +1,277 @@
+package rum
+
+import (
+	"errors"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueFull is returned by Enqueue when the write queue has no room for
+// the whole batch; callers should retry later
+var ErrQueueFull = errors.New("rum write queue full")
+
+// ErrWriterClosed is returned by Enqueue after Shutdown
+var ErrWriterClosed = errors.New("rum writer is shut down")
+
+// EventSink persists RUM events; implemented by *Storage
+type EventSink interface {
+	StoreEvents(events []RUMEvent) error
+	StoreEvent(event RUMEvent) error
+}
+
+// WriterConfig holds configuration for the batch writer
+type WriterConfig struct {
+	// BatchSize is the number of events written per COPY
+	// Default: 500
+	BatchSize int
+
+	// FlushInterval is the longest an event waits in the queue
+	// Default: 2s
+	FlushInterval time.Duration
+
+	// QueueSize is the number of events buffered before Enqueue rejects
+	// Default: 10000
+	QueueSize int
+}
+
+// DefaultWriterConfig returns sensible defaults
+func DefaultWriterConfig() WriterConfig {
+	return WriterConfig{
+		BatchSize:     500,
+		FlushInterval: 2 * time.Second,
+		QueueSize:     10000,
+	}
+}
+
+// WriterConfigFromEnv returns the defaults overridden by RUM_BATCH_SIZE,
+// RUM_FLUSH_INTERVAL and RUM_QUEUE_SIZE
+func WriterConfigFromEnv() WriterConfig {
+	config := DefaultWriterConfig()
+	if v, err := strconv.Atoi(os.Getenv("RUM_BATCH_SIZE")); err == nil && v > 0 {
+		config.BatchSize = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("RUM_FLUSH_INTERVAL")); err == nil && v > 0 {
+		config.FlushInterval = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("RUM_QUEUE_SIZE")); err == nil && v > 0 {
+		config.QueueSize = v
+	}
+	return config
+}
+
+// BatchWriter buffers RUM events and writes them in batches, flushing when a
+// batch fills or FlushInterval passes, whichever comes first
+type BatchWriter struct {
+	sink   EventSink
+	config WriterConfig
+	queue  chan RUMEvent
+	done   chan struct{}
+
+	mu      sync.Mutex
+	started bool
+	closed  bool
+
+	// Metrics
+	enqueued  int64
+	written   int64
+	failed    int64
+	dropped   int64
+	flushes   int64
+	fallbacks int64
+
+	lastMu    sync.Mutex
+	lastFlush time.Time
+	lastTook  time.Duration
+	lastError string
+}
+
+// NewBatchWriter creates a batch writer; call Start to begin flushing
+func NewBatchWriter(sink EventSink, config WriterConfig) *BatchWriter {
+	defaults := DefaultWriterConfig()
+	if config.BatchSize <= 0 {
+		config.BatchSize = defaults.BatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+	if config.QueueSize < config.BatchSize {
+		config.QueueSize = config.BatchSize
+	}
+
+	return &BatchWriter{
+		sink:   sink,
+		config: config,
+		queue:  make(chan RUMEvent, config.QueueSize),
+		done:   make(chan struct{}),
+	}
+}
+
+// Start launches the flush loop
+func (w *BatchWriter) Start() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.started || w.closed {
+		return
+	}
+	w.started = true
+	log.Printf("[RUM] Batch writer started (batch %d, flush every %s, queue %d)",
+		w.config.BatchSize, w.config.FlushInterval, cap(w.queue))
+	go w.run()
+}
+
+// Enqueue queues events for writing. The batch is accepted whole or not at
+// all: when the queue lacks room it returns ErrQueueFull (backpressure).
+func (w *BatchWriter) Enqueue(events []RUMEvent) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+	if cap(w.queue)-len(w.queue) < len(events) {
+		atomic.AddInt64(&w.dropped, int64(len(events)))
+		return ErrQueueFull
+	}
+	// Only Enqueue sends, under mu, so the room checked above is still there
+	for _, event := range events {
+		w.queue <- event
+	}
+	atomic.AddInt64(&w.enqueued, int64(len(events)))
+	return nil
+}
+
+// Shutdown stops accepting events and flushes everything queued, waiting up
+// to timeout for the final write
+func (w *BatchWriter) Shutdown(timeout time.Duration) {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	started := w.started
+	close(w.queue)
+	w.mu.Unlock()
+
+	if !started {
+		// Never started: flush inline so queued events are not lost
+		go w.run()
+	}
+
+	log.Printf("[RUM] Flushing %d queued events...", len(w.queue))
+	select {
+	case <-w.done:
+		log.Println("[RUM] Batch writer stopped")
+	case <-time.After(timeout):
+		log.Printf("[RUM] Batch writer shutdown timeout, %d events may be lost", len(w.queue))
+	}
+}
+
+// Stats returns current writer statistics
+func (w *BatchWriter) Stats() WriterStats {
+	w.lastMu.Lock()
+	defer w.lastMu.Unlock()
+
+	stats := WriterStats{
+		QueueSize:      len(w.queue),
+		QueueCapacity:  cap(w.queue),
+		EnqueuedCount:  atomic.LoadInt64(&w.enqueued),
+		WrittenCount:   atomic.LoadInt64(&w.written),
+		FailedCount:    atomic.LoadInt64(&w.failed),
+		DroppedCount:   atomic.LoadInt64(&w.dropped),
+		FlushCount:     atomic.LoadInt64(&w.flushes),
+		FallbackCount:  atomic.LoadInt64(&w.fallbacks),
+		LastFlushMs:    w.lastTook.Milliseconds(),
+		LastFlushError: w.lastError,
+	}
+	if !w.lastFlush.IsZero() {
+		last := w.lastFlush
+		stats.LastFlushAt = &last
+	}
+	return stats
+}
+
+// WriterStats holds batch writer metrics
+type WriterStats struct {
+	QueueSize      int        `json:"queue_size"`
+	QueueCapacity  int        `json:"queue_capacity"`
+	EnqueuedCount  int64      `json:"enqueued_count"`
+	WrittenCount   int64      `json:"written_count"`
+	FailedCount    int64      `json:"failed_count"`
+	DroppedCount   int64      `json:"dropped_count"` // rejected because the queue was full
+	FlushCount     int64      `json:"flush_count"`
+	FallbackCount  int64      `json:"fallback_count"` // batches retried event by event
+	LastFlushAt    *time.Time `json:"last_flush_at,omitempty"`
+	LastFlushMs    int64      `json:"last_flush_ms"`
+	LastFlushError string     `json:"last_flush_error,omitempty"`
+}
+
+// run collects queued events into batches until the queue is closed and drained
+func (w *BatchWriter) run() {
+	defer close(w.done)
+
+	ticker := time.NewTicker(w.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]RUMEvent, 0, w.config.BatchSize)
+	for {
+		select {
+		case event, ok := <-w.queue:
+			if !ok {
+				w.flush(batch)
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= w.config.BatchSize {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				w.flush(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+// flush writes a batch. When the batch write fails (e.g. one event names an
+// unknown session) the events are retried one by one so only bad ones are lost.
+func (w *BatchWriter) flush(batch []RUMEvent) {
+	if len(batch) == 0 {
+		return
+	}
+	start := time.Now()
+	atomic.AddInt64(&w.flushes, 1)
+
+	var lastErr error
+	if err := w.sink.StoreEvents(batch); err != nil {
+		atomic.AddInt64(&w.fallbacks, 1)
+		log.Printf("[RUM] Batch write of %d events failed, retrying individually: %v", len(batch), err)
+		for _, event := range batch {
+			if err := w.sink.StoreEvent(event); err != nil {
+				atomic.AddInt64(&w.failed, 1)
+				lastErr = err
+				continue
+			}
+			atomic.AddInt64(&w.written, 1)
+		}
+	} else {
+		atomic.AddInt64(&w.written, int64(len(batch)))
+	}
+
+	w.lastMu.Lock()
+	w.lastFlush = time.Now()
+	w.lastTook = time.Since(start)
+	w.lastError = ""
+	if lastErr != nil {
+		w.lastError = lastErr.Error()
+		log.Printf("[RUM] Dropped events after individual retry: %v", lastErr)
+	}
+	w.lastMu.Unlock()
+}
@@ -0,0 +1,218 @@
+package rum
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	batches [][]RUMEvent
+	singles []RUMEvent
+	failOn  string // session ID that fails the batch and its single write
+}
+
+func (f *fakeSink) StoreEvents(events []RUMEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, e := range events {
+		if f.failOn != "" && e.SessionID == f.failOn {
+			return errors.New("violates foreign key constraint")
+		}
+	}
+	f.batches = append(f.batches, append([]RUMEvent(nil), events...))
+	return nil
+}
+
+func (f *fakeSink) StoreEvent(event RUMEvent) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if event.SessionID == f.failOn {
+		return errors.New("violates foreign key constraint")
+	}
+	f.singles = append(f.singles, event)
+	return nil
+}
+
+func (f *fakeSink) counts() (batches, written int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, b := range f.batches {
+		written += len(b)
+	}
+	return len(f.batches), written + len(f.singles)
+}
+
+func testEvents(n int, session string) []RUMEvent {
+	events := make([]RUMEvent, n)
+	for i := range events {
+		events[i] = RUMEvent{VisitorUUID: "v1", SessionID: session, EventType: "action"}
+	}
+	return events
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for flush")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatchWriter_FlushesOnSize(t *testing.T) {
+	sink := &fakeSink{}
+	writer := NewBatchWriter(sink, WriterConfig{BatchSize: 3, FlushInterval: time.Hour, QueueSize: 10})
+	writer.Start()
+	defer writer.Shutdown(time.Second)
+
+	if err := writer.Enqueue(testEvents(7, "s1")); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	waitFor(t, func() bool { batches, _ := sink.counts(); return batches == 2 })
+
+	if _, written := sink.counts(); written != 6 {
+		t.Fatalf("expected two full batches of 3 before the interval, got %d events", written)
+	}
+}
+
+func TestBatchWriter_FlushesOnInterval(t *testing.T) {
+	sink := &fakeSink{}
+	writer := NewBatchWriter(sink, WriterConfig{BatchSize: 100, FlushInterval: 20 * time.Millisecond, QueueSize: 100})
+	writer.Start()
+	defer writer.Shutdown(time.Second)
+
+	writer.Enqueue(testEvents(2, "s1"))
+	waitFor(t, func() bool { _, written := sink.counts(); return written == 2 })
+
+	stats := writer.Stats()
+	if stats.WrittenCount != 2 || stats.FlushCount != 1 || stats.LastFlushAt == nil {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestBatchWriter_BackpressureRejectsWholeBatch(t *testing.T) {
+	sink := &fakeSink{}
+	// Not started, so nothing drains the queue
+	writer := NewBatchWriter(sink, WriterConfig{BatchSize: 2, FlushInterval: time.Hour, QueueSize: 4})
+
+	if err := writer.Enqueue(testEvents(3, "s1")); err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	if err := writer.Enqueue(testEvents(2, "s1")); !errors.Is(err, ErrQueueFull) {
+		t.Fatalf("expected ErrQueueFull, got %v", err)
+	}
+	stats := writer.Stats()
+	if stats.QueueSize != 3 || stats.DroppedCount != 2 || stats.EnqueuedCount != 3 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	// Shutdown flushes what was queued, even though the writer never started
+	writer.Shutdown(time.Second)
+	if _, written := sink.counts(); written != 3 {
+		t.Fatalf("expected queued events flushed on shutdown, got %d", written)
+	}
+	if err := writer.Enqueue(testEvents(1, "s1")); !errors.Is(err, ErrWriterClosed) {
+		t.Fatalf("expected ErrWriterClosed after shutdown, got %v", err)
+	}
+}
+
+func TestBatchWriter_FallsBackToSingleWrites(t *testing.T) {
+	sink := &fakeSink{failOn: "gone"}
+	writer := NewBatchWriter(sink, WriterConfig{BatchSize: 10, FlushInterval: time.Hour, QueueSize: 10})
+	writer.Start()
+
+	writer.Enqueue(append(testEvents(2, "s1"), testEvents(1, "gone")...))
+	writer.Shutdown(time.Second)
+
+	stats := writer.Stats()
+	if stats.WrittenCount != 2 || stats.FailedCount != 1 || stats.FallbackCount != 1 || stats.LastFlushError == "" {
+		t.Fatalf("expected the bad event dropped and the rest written, got %+v", stats)
+	}
+}
+
+func TestHandler_TrackBatch(t *testing.T) {
+	sink := &fakeSink{}
+	writer := NewBatchWriter(sink, WriterConfig{BatchSize: 10, FlushInterval: time.Hour, QueueSize: 10})
+	handler := NewHandler(&Storage{})
+	handler.SetBatchWriter(writer)
+	handler.SetPrivacy(NewPrivacy(newFakePrivacyStore(nil), DefaultPrivacyConfig()))
+
+	clientTime := time.Now().Add(-time.Minute).UTC().Truncate(time.Second)
+	body, _ := json.Marshal(BatchEventRequest{
+		VisitorUUID: "v1",
+		SessionID:   "s1",
+		Events: []TrackEventRequest{
+			{EventType: "page_view", PageURL: "/", Timestamp: clientTime},
+			{EventType: "bogus"},
+			{EventType: "action", SessionID: "s2", ActionName: "click"},
+		},
+	})
+	// sendBeacon posts text/plain
+	req := httptest.NewRequest(http.MethodPost, "/v1/rum/batch", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "text/plain;charset=UTF-8")
+
+	status, result := handler.TrackBatch(httptest.NewRecorder(), req)
+	resp, ok := result.(BatchEventResponse)
+	if status != http.StatusAccepted || !ok {
+		t.Fatalf("expected 202, got %d %+v", status, result)
+	}
+	if resp.Accepted != 2 || len(resp.Rejected) != 1 || resp.Rejected[0].Index != 1 {
+		t.Fatalf("unexpected response %+v", resp)
+	}
+
+	writer.Shutdown(time.Second)
+	if len(sink.batches) != 1 || len(sink.batches[0]) != 2 {
+		t.Fatalf("expected one batch of 2 events, got %+v", sink.batches)
+	}
+	first, second := sink.batches[0][0], sink.batches[0][1]
+	if !first.Timestamp.Equal(clientTime) || first.SessionID != "s1" {
+		t.Fatalf("expected client timestamp and envelope session, got %+v", first)
+	}
+	if second.SessionID != "s2" || second.VisitorUUID != "v1" {
+		t.Fatalf("expected per-event session to win over the envelope, got %+v", second)
+	}
+}
+
+func TestHandler_TrackBatchArrayAndErrors(t *testing.T) {
+	sink := &fakeSink{}
+	writer := NewBatchWriter(sink, WriterConfig{BatchSize: 1, FlushInterval: time.Hour, QueueSize: 1})
+	handler := NewHandler(&Storage{})
+	handler.SetBatchWriter(writer)
+	handler.SetPrivacy(NewPrivacy(newFakePrivacyStore(nil), DefaultPrivacyConfig()))
+
+	post := func(body string) (int, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		status, _ := handler.TrackBatch(w, httptest.NewRequest(http.MethodPost, "/v1/rum/batch", strings.NewReader(body)))
+		return status, w
+	}
+
+	if status, _ := post(`[{"visitor_uuid":"v1","session_id":"s1","event_type":"view","timestamp":"2001-01-01T00:00:00Z"}]`); status != http.StatusAccepted {
+		t.Fatalf("expected a bare array accepted, got %d", status)
+	}
+	status, w := post(`[{"visitor_uuid":"v1","session_id":"s1","event_type":"view"}]`)
+	if status != http.StatusServiceUnavailable || w.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 503 with Retry-After when the queue is full, got %d", status)
+	}
+	if status, _ := post(`{"events":[]}`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an empty batch, got %d", status)
+	}
+	if status, _ := post(`not json`); status != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid body, got %d", status)
+	}
+
+	// The stale client timestamp is replaced with the receive time
+	writer.Shutdown(time.Second)
+	if len(sink.batches) != 1 || time.Since(sink.batches[0][0].Timestamp) > time.Minute {
+		t.Fatalf("expected the stale timestamp replaced, got %+v", sink.batches)
+	}
+}
@@ -0,0 +1,373 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+	"github.com/Nokodoko/mkii_ddog_server/services/changes"
+)
+
+// Finding categories produced by the Watchdog agent
+const (
+	FindingCategoryStory   = "watchdog_story"
+	FindingCategoryRepeat  = "watchdog_repeat"
+	FindingCategoryCatalog = "service_catalog"
+	FindingCategoryAPM     = "apm"
+)
+
+// Config controls Watchdog story handling
+type Config struct {
+	// GroupWindow is how long a story stays open for repeats to join it
+	// Default: 6h
+	GroupWindow time.Duration
+
+	// EnrichTimeout bounds the Service Catalog and service map lookups
+	// Default: 10s
+	EnrichTimeout time.Duration
+
+	// UseSidecar routes Watchdog alerts to the sidecar's /watchdog endpoint
+	// instead of this agent
+	// Default: false
+	UseSidecar bool
+}
+
+// DefaultConfig returns sensible defaults
+func DefaultConfig() Config {
+	return Config{
+		GroupWindow:   6 * time.Hour,
+		EnrichTimeout: 10 * time.Second,
+	}
+}
+
+// ConfigFromEnv returns the defaults overridden by WATCHDOG_GROUP_WINDOW,
+// WATCHDOG_ENRICH_TIMEOUT and WATCHDOG_USE_SIDECAR
+func ConfigFromEnv() Config {
+	config := DefaultConfig()
+	if v, err := time.ParseDuration(os.Getenv("WATCHDOG_GROUP_WINDOW")); err == nil && v > 0 {
+		config.GroupWindow = v
+	}
+	if v, err := time.ParseDuration(os.Getenv("WATCHDOG_ENRICH_TIMEOUT")); err == nil && v > 0 {
+		config.EnrichTimeout = v
+	}
+	config.UseSidecar = os.Getenv("WATCHDOG_USE_SIDECAR") == "true"
+	return config
+}
+
+// Agent analyzes Watchdog stories in-process: it parses the story, enriches
+// it with Service Catalog and APM service map data and groups repeats. It
+// needs no sidecar, so Watchdog alerts are analyzed while the circuit is open.
+type Agent struct {
+	enricher Enricher
+	groups   *Grouper
+	config   Config
+}
+
+// NewAgent creates the Watchdog agent; enricher and groups may be nil
+func NewAgent(enricher Enricher, groups *Grouper, config Config) *Agent {
+	if config.EnrichTimeout <= 0 {
+		config.EnrichTimeout = DefaultConfig().EnrichTimeout
+	}
+	return &Agent{enricher: enricher, groups: groups, config: config}
+}
+
+// Name returns the agent's unique identifier
+func (a *Agent) Name() string {
+	return "watchdog-story"
+}
+
+// Role returns the agent's specialist role
+func (a *Agent) Role() agents.AgentRole {
+	return agents.RoleWatchdog
+}
+
+// Local marks the agent as independent of the sidecar (agents.LocalAgent)
+func (a *Agent) Local() bool {
+	return true
+}
+
+// Plan completes immediately; the story and enrichment are handled in Conclude
+func (a *Agent) Plan(ctx context.Context, event *types.AlertEvent, agentCtx agents.AgentContext) agents.AgentPlan {
+	return agents.AgentPlan{
+		Complete:  true,
+		Reasoning: "Parsing Watchdog story and enriching it with Service Catalog and APM data",
+	}
+}
+
+// Analyze is a no-op because Plan always completes
+func (a *Agent) Analyze(ctx context.Context, results []agents.QueryResult, agentCtx agents.AgentContext) agents.AgentContext {
+	return agentCtx
+}
+
+// Conclude builds the analysis from the parsed story, its group and enrichment
+func (a *Agent) Conclude(ctx context.Context, agentCtx agents.AgentContext) *agents.AnalysisResult {
+	event := agentCtx.Event
+	p := event.Payload
+	story := ParseStory(event)
+
+	var group StoryGroup
+	if a.groups != nil {
+		group = a.groups.Observe(story, event.ID, p.MonitorID)
+	}
+
+	enrichCtx, cancel := context.WithTimeout(ctx, a.config.EnrichTimeout)
+	enrichment := enrich(enrichCtx, a.enricher, story, event.AccountName)
+	cancel()
+	for _, e := range enrichment.Errors {
+		log.Printf("[WATCHDOG] Enrichment for monitor %d incomplete: %s", p.MonitorID, e)
+	}
+
+	description := story.Describe()
+	findings := append(slices.Clone(agentCtx.Findings), storyFinding(a.Name(), story, description))
+	if group.Count > 1 {
+		findings = append(findings, agents.Finding{
+			Source:    a.Name(),
+			Category:  FindingCategoryRepeat,
+			Summary:   repeatSummary(group),
+			Severity:  "info",
+			Timestamp: time.Now(),
+			Metadata: map[string]interface{}{
+				"fingerprint": group.Fingerprint,
+				"count":       group.Count,
+				"first_seen":  group.FirstSeen,
+				"event_ids":   group.EventIDs,
+				"monitor_ids": group.MonitorIDs,
+			},
+		})
+	}
+	if f := catalogFinding(a.Name(), enrichment.Catalog); f != nil {
+		findings = append(findings, *f)
+	}
+	if f := dependencyFinding(a.Name(), enrichment.Dependencies); f != nil {
+		findings = append(findings, *f)
+	}
+
+	rootCause := description + "."
+	if change := recentChange(agentCtx.Findings); change != "" {
+		rootCause += " Possible trigger: " + change
+	}
+
+	details := rootCause
+	if p.DetailedDescription != "" {
+		details += "\n\n" + p.DetailedDescription
+	}
+	if len(enrichment.Errors) > 0 {
+		details += "\n\nEnrichment incomplete: " + strings.Join(enrichment.Errors, "; ")
+	}
+
+	summary := description
+	if group.Count > 1 {
+		summary += " (" + repeatSummary(group) + ")"
+	}
+	if agentCtx.Question != "" {
+		// No model to reason with: answer follow-ups with everything known
+		// about the story, which Ask returns when no Answer is set
+		lines := []string{rootCause}
+		for _, f := range findings[len(agentCtx.Findings)+1:] {
+			lines = append(lines, f.Summary+".")
+		}
+		summary = strings.Join(lines, " ")
+	}
+
+	return &agents.AnalysisResult{
+		MonitorID:       p.MonitorID,
+		MonitorName:     p.MonitorName,
+		AlertStatus:     p.AlertStatus,
+		Success:         true,
+		AgentRole:       agents.RoleWatchdog,
+		RootCause:       rootCause,
+		Summary:         summary,
+		Details:         details,
+		Findings:        findings,
+		Recommendations: recommendations(story, group, enrichment),
+	}
+}
+
+// storyFinding records the parsed story
+func storyFinding(source string, story *Story, description string) agents.Finding {
+	metadata := map[string]interface{}{
+		"title":     story.Title,
+		"category":  story.Category,
+		"signal":    story.Signal,
+		"direction": story.Direction,
+		"entities":  story.Entities,
+	}
+	for key, value := range map[string]string{
+		"story_key": story.Key,
+		"service":   story.Service,
+		"env":       story.Env,
+		"resource":  story.Resource,
+		"metric":    story.Metric,
+		"value":     story.Value,
+		"url":       story.URL,
+	} {
+		if value != "" {
+			metadata[key] = value
+		}
+	}
+
+	var entities []string
+	for _, e := range story.Entities {
+		entities = append(entities, e.Type+":"+e.Name)
+	}
+	details := description
+	if len(entities) > 0 {
+		details += "\nRelated entities: " + strings.Join(entities, ", ")
+	}
+
+	return agents.Finding{
+		Source:    source,
+		Category:  FindingCategoryStory,
+		Summary:   description,
+		Details:   details,
+		Severity:  "warning",
+		Timestamp: story.DetectedAt,
+		Metadata:  metadata,
+	}
+}
+
+// catalogFinding records the owning team and contacts from the Service Catalog
+func catalogFinding(source string, info *ServiceInfo) *agents.Finding {
+	if info == nil {
+		return nil
+	}
+	summary := fmt.Sprintf("Service %s", info.Name)
+	if info.Team != "" {
+		summary += " is owned by team " + info.Team
+	}
+	if info.Tier != "" {
+		summary += " (tier " + info.Tier + ")"
+	}
+
+	var details []string
+	for _, c := range info.Contacts {
+		details = append(details, fmt.Sprintf("%s contact: %s", c.Type, c.Contact))
+	}
+	for _, l := range info.Links {
+		details = append(details, fmt.Sprintf("%s: %s", utils.FirstNonEmpty(l.Name, l.Type), l.URL))
+	}
+
+	return &agents.Finding{
+		Source:    source,
+		Category:  FindingCategoryCatalog,
+		Summary:   summary,
+		Details:   strings.Join(details, "\n"),
+		Severity:  "info",
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"service": info},
+	}
+}
+
+// dependencyFinding records the service's neighbours in the APM service map
+func dependencyFinding(source string, deps *Dependencies) *agents.Finding {
+	if deps == nil || len(deps.Calls)+len(deps.CalledBy) == 0 {
+		return nil
+	}
+	var parts []string
+	if len(deps.Calls) > 0 {
+		parts = append(parts, "calls "+strings.Join(deps.Calls, ", "))
+	}
+	if len(deps.CalledBy) > 0 {
+		parts = append(parts, "is called by "+strings.Join(deps.CalledBy, ", "))
+	}
+
+	return &agents.Finding{
+		Source:    source,
+		Category:  FindingCategoryAPM,
+		Summary:   fmt.Sprintf("In env:%s, %s %s", deps.Env, deps.Service, strings.Join(parts, " and ")),
+		Severity:  "info",
+		Timestamp: time.Now(),
+		Metadata:  map[string]interface{}{"dependencies": deps},
+	}
+}
+
+// recentChange returns the first change finding gathered before the agent ran
+func recentChange(findings []agents.Finding) string {
+	for _, f := range findings {
+		if f.Category == changes.FindingCategory {
+			return f.Summary
+		}
+	}
+	return ""
+}
+
+// repeatSummary describes how often a story has repeated
+func repeatSummary(group StoryGroup) string {
+	summary := fmt.Sprintf("seen %d times since %s", group.Count, group.FirstSeen.UTC().Format(time.RFC3339))
+	if len(group.MonitorIDs) > 1 {
+		summary += fmt.Sprintf(" across %d monitors", len(group.MonitorIDs))
+	}
+	return summary
+}
+
+// recommendations suggests next steps for the story's signal, pointing at
+// the related services and owners found during enrichment
+func recommendations(story *Story, group StoryGroup, enrichment Enrichment) []string {
+	var recs []string
+	if story.URL != "" {
+		recs = append(recs, "Open the Watchdog story: "+story.URL)
+	}
+
+	target := utils.FirstNonEmpty(story.Resource, story.Service, "the affected service")
+	var calls, calledBy []string
+	if deps := enrichment.Dependencies; deps != nil {
+		calls, calledBy = deps.Calls, deps.CalledBy
+	}
+	if len(calls) == 0 {
+		calls = story.RelatedServices()
+	}
+
+	switch story.Signal {
+	case SignalLatency:
+		recs = append(recs, fmt.Sprintf("Compare the latency breakdown of %s in APM with the previous week", target))
+		if len(calls) > 0 {
+			recs = append(recs, "Check the downstream services for matching latency: "+strings.Join(calls, ", "))
+		}
+	case SignalErrorRate:
+		recs = append(recs, fmt.Sprintf("Inspect error traces for %s and group them by error type", target))
+		if len(calls) > 0 {
+			recs = append(recs, "Check whether the errors originate in downstream services: "+strings.Join(calls, ", "))
+		}
+	case SignalThroughput:
+		if story.Direction == DirectionDecrease {
+			recs = append(recs, "Check load balancer health and upstream callers for dropped traffic")
+		} else {
+			recs = append(recs, "Look for retries or a traffic surge from upstream callers")
+		}
+		if len(calledBy) > 0 {
+			recs = append(recs, "Upstream callers: "+strings.Join(calledBy, ", "))
+		}
+	case SignalSaturation:
+		recs = append(recs, "Check resource usage on the related hosts and containers")
+	default:
+		recs = append(recs, fmt.Sprintf("Review %s around %s", utils.FirstNonEmpty(story.Metric, "the anomalous metric"),
+			story.DetectedAt.UTC().Format(time.RFC3339)))
+	}
+
+	if info := enrichment.Catalog; info != nil && info.Team != "" {
+		rec := "Notify the owning team " + info.Team
+		if len(info.Contacts) > 0 {
+			rec += fmt.Sprintf(" (%s: %s)", info.Contacts[0].Type, info.Contacts[0].Contact)
+		}
+		recs = append(recs, rec)
+	}
+	if info := enrichment.Catalog; info != nil {
+		for _, l := range info.Links {
+			if strings.EqualFold(l.Type, "runbook") {
+				recs = append(recs, "Follow the runbook: "+l.URL)
+			}
+		}
+	}
+	if group.Count > 2 {
+		recs = append(recs, fmt.Sprintf("This story has repeated %d times; if the behaviour is expected, mute it for %s",
+			group.Count, utils.FirstNonEmpty(story.Service, "the service")))
+	}
+	return recs
+}
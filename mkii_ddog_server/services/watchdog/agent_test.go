@@ -0,0 +1,157 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+	"github.com/Nokodoko/mkii_ddog_server/services/changes"
+)
+
+type fakeEnricher struct {
+	info    *ServiceInfo
+	deps    *Dependencies
+	infoErr error
+	calls   int
+}
+
+func (f *fakeEnricher) ServiceDefinition(ctx context.Context, service, accountName string) (*ServiceInfo, error) {
+	f.calls++
+	return f.info, f.infoErr
+}
+
+func (f *fakeEnricher) ServiceDependencies(ctx context.Context, service, env, accountName string) (*Dependencies, error) {
+	f.calls++
+	return f.deps, nil
+}
+
+func newTestAgent(enricher Enricher) (*Agent, *Grouper) {
+	groups := NewGrouper(time.Hour)
+	groups.now = func() time.Time { return t0 }
+	return NewAgent(enricher, groups, DefaultConfig()), groups
+}
+
+func findingsOf(result *agents.AnalysisResult, category string) []agents.Finding {
+	var found []agents.Finding
+	for _, f := range result.Findings {
+		if f.Category == category {
+			found = append(found, f)
+		}
+	}
+	return found
+}
+
+func TestAgent_ConcludeEnrichesStory(t *testing.T) {
+	enricher := &fakeEnricher{
+		info: &ServiceInfo{
+			Name:     "checkout",
+			Team:     "shop-core",
+			Contacts: []Contact{{Type: "slack", Contact: "#shop-core"}},
+			Links:    []Link{{Name: "Checkout runbook", Type: "runbook", URL: "https://runbooks/checkout"}},
+		},
+		deps: &Dependencies{Service: "checkout", Env: "prod", Calls: []string{"payments"}, CalledBy: []string{"web"}},
+	}
+	agent, _ := newTestAgent(enricher)
+
+	event := storyEvent(1, latencyPayload())
+	change := agents.Finding{Category: changes.FindingCategory, Summary: "deploy checkout v1.4.0 10m before the alert"}
+	result := agent.Conclude(context.Background(), agents.AgentContext{Event: event, Findings: []agents.Finding{change}})
+
+	if !result.Success || result.AgentRole != agents.RoleWatchdog || result.MonitorID != 7 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	if !strings.Contains(result.RootCause, "latency increase on service checkout") ||
+		!strings.Contains(result.RootCause, "Possible trigger: deploy checkout v1.4.0") {
+		t.Fatalf("unexpected root cause %q", result.RootCause)
+	}
+	if result.Findings[0].Category != changes.FindingCategory {
+		t.Fatalf("expected seed findings kept first, got %+v", result.Findings[0])
+	}
+	for _, category := range []string{FindingCategoryStory, FindingCategoryCatalog, FindingCategoryAPM} {
+		if len(findingsOf(result, category)) != 1 {
+			t.Fatalf("expected one %s finding in %+v", category, result.Findings)
+		}
+	}
+	if len(findingsOf(result, FindingCategoryRepeat)) != 0 {
+		t.Fatal("expected no repeat finding for the first notification")
+	}
+
+	recs := strings.Join(result.Recommendations, "\n")
+	for _, want := range []string{
+		"https://app.datadoghq.com/watchdog?storyKey=abc123",
+		"downstream services for matching latency: payments",
+		"owning team shop-core (slack: #shop-core)",
+		"runbook: https://runbooks/checkout",
+	} {
+		if !strings.Contains(recs, want) {
+			t.Errorf("expected recommendation containing %q in\n%s", want, recs)
+		}
+	}
+}
+
+func TestAgent_ConcludeGroupsRepeats(t *testing.T) {
+	agent, groups := newTestAgent(nil)
+
+	for id := int64(1); id <= 3; id++ {
+		event := storyEvent(id, latencyPayload())
+		event.ReceivedAt = t0.Add(time.Duration(id) * 10 * time.Minute)
+		agent.Conclude(context.Background(), agents.AgentContext{Event: event})
+	}
+	// A follow-up re-runs the analysis for an event already counted
+	result := agent.Conclude(context.Background(), agents.AgentContext{Event: storyEvent(3, latencyPayload()), Question: "why?"})
+
+	listed := groups.List()
+	if len(listed) != 1 || listed[0].Count != 3 || len(listed[0].EventIDs) != 3 {
+		t.Fatalf("expected one group of 3 notifications, got %+v", listed)
+	}
+	repeats := findingsOf(result, FindingCategoryRepeat)
+	if len(repeats) != 1 || !strings.Contains(repeats[0].Summary, "seen 3 times") {
+		t.Fatalf("expected a repeat finding, got %+v", repeats)
+	}
+	if !strings.Contains(result.Summary, "seen 3 times") || !strings.Contains(result.Summary, "Watchdog detected") {
+		t.Fatalf("expected the follow-up answer to cover the story and repeats, got %q", result.Summary)
+	}
+}
+
+func TestAgent_EnrichmentErrorsAreNotFatal(t *testing.T) {
+	enricher := &fakeEnricher{infoErr: errors.New("service definition API returned 404")}
+	agent, _ := newTestAgent(enricher)
+
+	payload := latencyPayload()
+	payload.AlertTitle = "Latency increased on service:checkout"
+	result := agent.Conclude(context.Background(), agents.AgentContext{Event: storyEvent(1, payload)})
+
+	if !result.Success {
+		t.Fatal("expected enrichment failures not to fail the analysis")
+	}
+	if enricher.calls != 1 {
+		t.Fatalf("expected the service map lookup skipped without an env, got %d calls", enricher.calls)
+	}
+	if !strings.Contains(result.Details, "service catalog: service definition API returned 404") ||
+		!strings.Contains(result.Details, "story has no env") {
+		t.Fatalf("expected enrichment errors in details, got %q", result.Details)
+	}
+}
+
+func TestDependenciesOf(t *testing.T) {
+	serviceMap := map[string]serviceDependency{
+		"web":      {Calls: []string{"checkout"}},
+		"checkout": {Calls: []string{"payments", "inventory", "checkout"}},
+		"admin":    {Calls: []string{"checkout", "users"}},
+	}
+	deps := dependenciesOf(serviceMap, "checkout", "prod")
+	if strings.Join(deps.Calls, ",") != "checkout,inventory,payments" || strings.Join(deps.CalledBy, ",") != "admin,web" {
+		t.Fatalf("unexpected dependencies %+v", deps)
+	}
+}
+
+func TestAgent_IsLocal(t *testing.T) {
+	var agent agents.Agent = NewAgent(nil, nil, Config{})
+	local, ok := agent.(agents.LocalAgent)
+	if !ok || !local.Local() {
+		t.Fatal("expected the Watchdog agent to run without the sidecar")
+	}
+}
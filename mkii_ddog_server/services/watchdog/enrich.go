@@ -0,0 +1,211 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/keys"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/requests"
+	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+)
+
+// ServiceInfo is a service's Service Catalog definition
+type ServiceInfo struct {
+	Name      string    `json:"name"`
+	Team      string    `json:"team,omitempty"`
+	Type      string    `json:"type,omitempty"`
+	Tier      string    `json:"tier,omitempty"`
+	Languages []string  `json:"languages,omitempty"`
+	Contacts  []Contact `json:"contacts,omitempty"`
+	Links     []Link    `json:"links,omitempty"`
+	Tags      []string  `json:"tags,omitempty"`
+}
+
+// Contact is an owner contact from the Service Catalog
+type Contact struct {
+	Name    string `json:"name,omitempty"`
+	Type    string `json:"type"`
+	Contact string `json:"contact"`
+}
+
+// Link is a runbook, dashboard or repo link from the Service Catalog
+type Link struct {
+	Name string `json:"name"`
+	Type string `json:"type,omitempty"`
+	URL  string `json:"url"`
+}
+
+// Dependencies are a service's neighbours in the APM service map
+type Dependencies struct {
+	Service  string   `json:"service"`
+	Env      string   `json:"env"`
+	Calls    []string `json:"calls,omitempty"`     // downstream services
+	CalledBy []string `json:"called_by,omitempty"` // upstream services
+}
+
+// Enrichment is the APM and Service Catalog data gathered for a story
+type Enrichment struct {
+	Catalog      *ServiceInfo  `json:"catalog,omitempty"`
+	Dependencies *Dependencies `json:"dependencies,omitempty"`
+	Errors       []string      `json:"errors,omitempty"`
+}
+
+// Enricher looks up Service Catalog and APM data; implemented by *DatadogEnricher
+type Enricher interface {
+	ServiceDefinition(ctx context.Context, service, accountName string) (*ServiceInfo, error)
+	ServiceDependencies(ctx context.Context, service, env, accountName string) (*Dependencies, error)
+}
+
+// enrich gathers what the enricher knows about the story's service. Lookup
+// failures are recorded on the enrichment; they never fail the analysis.
+func enrich(ctx context.Context, enricher Enricher, story *Story, accountName string) Enrichment {
+	var enrichment Enrichment
+	if enricher == nil || story.Service == "" {
+		return enrichment
+	}
+
+	info, err := enricher.ServiceDefinition(ctx, story.Service, accountName)
+	if err != nil {
+		enrichment.Errors = append(enrichment.Errors, fmt.Sprintf("service catalog: %v", err))
+	} else {
+		enrichment.Catalog = info
+	}
+
+	if story.Env == "" {
+		enrichment.Errors = append(enrichment.Errors, "service map: story has no env")
+		return enrichment
+	}
+	deps, err := enricher.ServiceDependencies(ctx, story.Service, story.Env, accountName)
+	if err != nil {
+		enrichment.Errors = append(enrichment.Errors, fmt.Sprintf("service map: %v", err))
+	} else {
+		enrichment.Dependencies = deps
+	}
+	return enrichment
+}
+
+// CredentialProvider interface at consumer side (interface ownership)
+type CredentialProvider interface {
+	GetByName(name string) (*accounts.Account, error)
+	GetDefault() *accounts.Account
+}
+
+// DatadogEnricher reads the Service Catalog and APM service map using the
+// credentials of the account the story arrived on
+type DatadogEnricher struct {
+	accounts CredentialProvider
+}
+
+// NewDatadogEnricher creates an enricher; accounts may be nil to use env credentials
+func NewDatadogEnricher(accounts CredentialProvider) *DatadogEnricher {
+	return &DatadogEnricher{accounts: accounts}
+}
+
+// ServiceDefinition fetches a service definition (GET /api/v2/services/definitions/{service})
+func (e *DatadogEnricher) ServiceDefinition(ctx context.Context, service, accountName string) (*ServiceInfo, error) {
+	creds := e.credentials(accountName)
+	u := creds.BuildURL(accounts.PathServiceDefinitions + "/" + url.PathEscape(service))
+
+	resp, status, err := requests.GetWithCreds[serviceDefinitionResponse](ctx, u, creds)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, fmt.Errorf("service definition API returned %d", status)
+	}
+
+	schema := resp.Data.Attributes.Schema
+	info := &ServiceInfo{
+		Name:      utils.FirstNonEmpty(schema.DDService, service),
+		Team:      schema.Team,
+		Type:      schema.Type,
+		Tier:      schema.Tier,
+		Languages: schema.Languages,
+		Contacts:  schema.Contacts,
+		Links:     schema.Links,
+		Tags:      schema.Tags,
+	}
+	return info, nil
+}
+
+// ServiceDependencies reads the service map for env (GET /api/v1/service_dependencies?env=)
+// and returns the service's downstream and upstream neighbours
+func (e *DatadogEnricher) ServiceDependencies(ctx context.Context, service, env, accountName string) (*Dependencies, error) {
+	creds := e.credentials(accountName)
+	u := creds.BuildURL(accounts.PathServiceDependencies + "?env=" + url.QueryEscape(env))
+
+	serviceMap, status, err := requests.GetWithCreds[map[string]serviceDependency](ctx, u, creds)
+	if err != nil {
+		return nil, err
+	}
+	if status >= 400 {
+		return nil, fmt.Errorf("service dependencies API returned %d", status)
+	}
+	return dependenciesOf(serviceMap, service, env), nil
+}
+
+// dependenciesOf picks a service's callees and callers out of the service map
+func dependenciesOf(serviceMap map[string]serviceDependency, service, env string) *Dependencies {
+	deps := &Dependencies{Service: service, Env: env}
+	deps.Calls = append(deps.Calls, serviceMap[service].Calls...)
+	for caller, dep := range serviceMap {
+		for _, callee := range dep.Calls {
+			if callee == service && caller != service {
+				deps.CalledBy = append(deps.CalledBy, caller)
+			}
+		}
+	}
+	sort.Strings(deps.Calls)
+	sort.Strings(deps.CalledBy)
+	return deps
+}
+
+// credentials returns the named account's credentials, the default account's, or env keys
+func (e *DatadogEnricher) credentials(accountName string) keys.Credentials {
+	if e.accounts == nil {
+		return keys.Default()
+	}
+
+	var account *accounts.Account
+	if accountName != "" {
+		account, _ = e.accounts.GetByName(accountName)
+	}
+	if account == nil {
+		account = e.accounts.GetDefault()
+	}
+	if account == nil {
+		return keys.Default()
+	}
+
+	return keys.Credentials{
+		APIKey:  account.APIKey,
+		AppKey:  account.AppKey,
+		BaseURL: account.BaseURL,
+	}
+}
+
+// serviceDefinitionResponse is the Service Catalog API response for one service
+type serviceDefinitionResponse struct {
+	Data struct {
+		Attributes struct {
+			Schema struct {
+				DDService string    `json:"dd-service"`
+				Team      string    `json:"team"`
+				Type      string    `json:"type"`
+				Tier      string    `json:"tier"`
+				Languages []string  `json:"languages"`
+				Contacts  []Contact `json:"contacts"`
+				Links     []Link    `json:"links"`
+				Tags      []string  `json:"tags"`
+			} `json:"schema"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// serviceDependency is one service's entry in the APM service map
+type serviceDependency struct {
+	Calls []string `json:"calls"`
+}
@@ -0,0 +1,134 @@
+package watchdog
+
+import (
+	"slices"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Bounds on what a Grouper keeps in memory
+const (
+	maxGroups          = 500
+	maxGroupEventIDs   = 50
+	unknownFingerprint = "unknown"
+)
+
+// StoryGroup collects repeated notifications of the same story
+type StoryGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Story       Story     `json:"story"` // latest notification
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+	EventIDs    []int64   `json:"event_ids"`   // oldest first, capped at maxGroupEventIDs
+	MonitorIDs  []int64   `json:"monitor_ids"` // every monitor that reported the story
+}
+
+// Grouper groups stories by fingerprint. A story seen again within the
+// window joins its group; after a quiet window it starts a new one.
+type Grouper struct {
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	groups map[string]*StoryGroup
+}
+
+// NewGrouper creates a grouper with the given repeat window
+func NewGrouper(window time.Duration) *Grouper {
+	if window <= 0 {
+		window = DefaultConfig().GroupWindow
+	}
+	return &Grouper{
+		window: window,
+		now:    time.Now,
+		groups: make(map[string]*StoryGroup),
+	}
+}
+
+// Observe records a notification of story from an event and returns a copy
+// of its group. Observing the same event twice (e.g. a follow-up re-running
+// the analysis) does not count it again.
+func (g *Grouper) Observe(story *Story, eventID, monitorID int64) StoryGroup {
+	at := story.DetectedAt
+	if at.IsZero() {
+		at = g.now()
+	}
+	fingerprint := story.Fingerprint()
+	if fingerprint == "" {
+		fingerprint = unknownFingerprint
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	group, ok := g.groups[fingerprint]
+	if !ok || at.Sub(group.LastSeen) > g.window {
+		group = &StoryGroup{Fingerprint: fingerprint, FirstSeen: at}
+		g.groups[fingerprint] = group
+	}
+
+	if eventID == 0 || !slices.Contains(group.EventIDs, eventID) {
+		group.Count++
+		if eventID != 0 {
+			group.EventIDs = append(group.EventIDs, eventID)
+			if len(group.EventIDs) > maxGroupEventIDs {
+				group.EventIDs = group.EventIDs[len(group.EventIDs)-maxGroupEventIDs:]
+			}
+		}
+	}
+	if monitorID != 0 && !slices.Contains(group.MonitorIDs, monitorID) {
+		group.MonitorIDs = append(group.MonitorIDs, monitorID)
+	}
+	group.Story = *story
+	if at.After(group.LastSeen) {
+		group.LastSeen = at
+	}
+
+	g.prune(at)
+	return copyGroup(group)
+}
+
+// List returns the groups seen within the window before now, most recent first
+func (g *Grouper) List() []StoryGroup {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	cutoff := g.now().Add(-g.window)
+	groups := []StoryGroup{}
+	for _, group := range g.groups {
+		if group.LastSeen.After(cutoff) {
+			groups = append(groups, copyGroup(group))
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].LastSeen.After(groups[j].LastSeen) })
+	return groups
+}
+
+// prune drops groups idle longer than the window, then the oldest beyond maxGroups
+func (g *Grouper) prune(now time.Time) {
+	for key, group := range g.groups {
+		if now.Sub(group.LastSeen) > g.window {
+			delete(g.groups, key)
+		}
+	}
+	for len(g.groups) > maxGroups {
+		var oldest string
+		for key, group := range g.groups {
+			if oldest == "" || group.LastSeen.Before(g.groups[oldest].LastSeen) {
+				oldest = key
+			}
+		}
+		delete(g.groups, oldest)
+	}
+}
+
+// copyGroup copies a group so callers can't race with later observations
+func copyGroup(group *StoryGroup) StoryGroup {
+	c := *group
+	c.EventIDs = slices.Clone(group.EventIDs)
+	c.MonitorIDs = slices.Clone(group.MonitorIDs)
+	c.Story.Entities = slices.Clone(group.Story.Entities)
+	return c
+}
@@ -0,0 +1,37 @@
+package watchdog
+
+import (
+	"net/http"
+)
+
+// Handler handles Watchdog story requests
+type Handler struct {
+	groups *Grouper
+}
+
+// NewHandler creates a new watchdog handler
+func NewHandler(groups *Grouper) *Handler {
+	return &Handler{groups: groups}
+}
+
+// ListStories returns the story groups seen within the group window, most
+// recent first (GET /v1/watchdog/stories?service=checkout)
+func (h *Handler) ListStories(w http.ResponseWriter, r *http.Request) (int, any) {
+	service := r.URL.Query().Get("service")
+
+	groups := h.groups.List()
+	if service != "" {
+		filtered := []StoryGroup{}
+		for _, group := range groups {
+			if group.Story.Service == service {
+				filtered = append(filtered, group)
+			}
+		}
+		groups = filtered
+	}
+
+	return http.StatusOK, map[string]any{
+		"stories": groups,
+		"count":   len(groups),
+	}
+}
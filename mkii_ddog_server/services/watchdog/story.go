@@ -0,0 +1,322 @@
+package watchdog
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+	"github.com/Nokodoko/mkii_ddog_server/cmd/utils"
+	"github.com/Nokodoko/mkii_ddog_server/services/agents"
+)
+
+// Direction is which way the anomalous signal moved
+type Direction string
+
+const (
+	DirectionIncrease Direction = "increase"
+	DirectionDecrease Direction = "decrease"
+)
+
+// Signal kinds Watchdog reports on
+const (
+	SignalLatency    = "latency"
+	SignalErrorRate  = "error_rate"
+	SignalThroughput = "throughput"
+	SignalSaturation = "saturation"
+)
+
+// Entity is a service, host or other resource related to a story
+type Entity struct {
+	Type string `json:"type"`
+	Name string `json:"name"`
+}
+
+// Story is a Watchdog story parsed from a monitor notification
+type Story struct {
+	Key        string    `json:"key,omitempty"`
+	Title      string    `json:"title"`
+	Category   string    `json:"category,omitempty"` // apm, infrastructure, logs
+	Service    string    `json:"service,omitempty"`
+	Env        string    `json:"env,omitempty"`
+	Resource   string    `json:"resource,omitempty"`
+	Metric     string    `json:"metric,omitempty"`
+	Signal     string    `json:"signal,omitempty"`
+	Direction  Direction `json:"direction,omitempty"`
+	Value      string    `json:"value,omitempty"`
+	Entities   []Entity  `json:"entities,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	DetectedAt time.Time `json:"detected_at"`
+}
+
+// entityTags maps tag keys to related entity types
+var entityTags = map[string]string{
+	"service":           "service",
+	"host":              "host",
+	"resource_name":     "resource",
+	"container_name":    "container",
+	"kube_deployment":   "kube_deployment",
+	"kube_namespace":    "kube_namespace",
+	"pod_name":          "pod",
+	"db.instance":       "database",
+	"availability-zone": "availability_zone",
+}
+
+var (
+	// storyURLPattern matches links to the story in the notification body
+	storyURLPattern = regexp.MustCompile(`https?://[^\s)\]>"]*watchdog[^\s)\]>"]*`)
+
+	// fieldPattern matches "Service: checkout" style lines (optionally bold)
+	fieldPattern = regexp.MustCompile(`(?im)^[\s*_-]*(service|env|environment|resource|metric|impacted services|related services)[*_]*\s*:\s*[*_]*(.+?)[*_]*\s*$`)
+
+	// titleServicePattern and titleEnvPattern find tags inlined in story titles
+	titleServicePattern = regexp.MustCompile(`(?i)\bservice[: ]+([\w.\-/]+)`)
+	titleEnvPattern     = regexp.MustCompile(`(?i)\benv[: ]+([\w.\-]+)`)
+
+	// titlePrefixPattern strips "[Triggered]" / "[Watchdog]" prefixes
+	titlePrefixPattern = regexp.MustCompile(`^(\s*\[[^\]]*\]\s*)+`)
+)
+
+// Direction keywords, checked in the title before the message
+var (
+	increasePattern = regexp.MustCompile(`\b(increas(e|es|ed|ing)|spik(e|es|ed|ing)|higher|ris(e|es|ing)|rose|surg(e|es|ed|ing)|elevated|jump(s|ed)?)\b`)
+	decreasePattern = regexp.MustCompile(`\b(decreas(e|es|ed|ing)|drop(s|ped|ping)?|lower|fell|fall(s|ing)?|dips?|dipped|declin(e|es|ed|ing))\b`)
+)
+
+// IsStory reports whether an alert is a Watchdog notification
+func IsStory(p types.AlertPayload) bool {
+	return agents.IsWatchdog(p.MonitorType, p.MonitorName, utils.FirstNonEmpty(p.AlertTitleCustom, p.AlertTitle), p.Tags)
+}
+
+// ParseStory extracts the Watchdog story from an alert. Fields come from the
+// payload, its tags, "Field: value" lines and the story link in the message,
+// in that order of preference; anything not found is left empty.
+func ParseStory(event *types.AlertEvent) *Story {
+	p := event.Payload
+	story := &Story{
+		Title:      cleanTitle(utils.FirstNonEmpty(p.AlertTitleCustom, p.AlertTitle, p.MonitorName)),
+		Service:    p.Service,
+		Metric:     p.Metric,
+		Value:      p.Value,
+		DetectedAt: event.ReceivedAt,
+	}
+	body := strings.Join([]string{p.AlertMessage, p.DetailedDescription, p.Impact}, "\n")
+
+	seen := make(map[Entity]bool)
+	addEntity := func(kind, name string) {
+		name = strings.TrimSpace(name)
+		entity := Entity{Type: kind, Name: name}
+		if name == "" || seen[entity] {
+			return
+		}
+		seen[entity] = true
+		story.Entities = append(story.Entities, entity)
+	}
+
+	for _, tag := range p.Tags {
+		key, value, ok := strings.Cut(tag, ":")
+		if !ok || value == "" {
+			continue
+		}
+		key = strings.ToLower(key)
+		switch key {
+		case "story_key", "story_id", "watchdog_story_key":
+			story.Key = utils.FirstNonEmpty(story.Key, value)
+		case "story_category", "category":
+			story.Category = utils.FirstNonEmpty(story.Category, strings.ToLower(value))
+		case "env":
+			story.Env = utils.FirstNonEmpty(story.Env, value)
+		case "metric":
+			story.Metric = utils.FirstNonEmpty(story.Metric, value)
+		case "service":
+			story.Service = utils.FirstNonEmpty(story.Service, value)
+		case "resource_name":
+			story.Resource = utils.FirstNonEmpty(story.Resource, value)
+		}
+		if kind, ok := entityTags[key]; ok {
+			addEntity(kind, value)
+		}
+	}
+
+	for _, m := range fieldPattern.FindAllStringSubmatch(body, -1) {
+		value := strings.TrimSpace(m[2])
+		switch strings.ToLower(m[1]) {
+		case "service":
+			story.Service = utils.FirstNonEmpty(story.Service, value)
+		case "env", "environment":
+			story.Env = utils.FirstNonEmpty(story.Env, value)
+		case "resource":
+			story.Resource = utils.FirstNonEmpty(story.Resource, value)
+		case "metric":
+			story.Metric = utils.FirstNonEmpty(story.Metric, value)
+		default: // impacted / related services
+			for _, name := range strings.Split(value, ",") {
+				addEntity("service", strings.Trim(name, " `"))
+			}
+		}
+	}
+
+	if m := titleServicePattern.FindStringSubmatch(story.Title); m != nil {
+		story.Service = utils.FirstNonEmpty(story.Service, m[1])
+	}
+	if m := titleEnvPattern.FindStringSubmatch(story.Title); m != nil {
+		story.Env = utils.FirstNonEmpty(story.Env, m[1])
+	}
+	if story.Service != "" {
+		addEntity("service", story.Service)
+	}
+	if p.Hostname != "" {
+		addEntity("host", p.Hostname)
+	}
+
+	if link := storyURLPattern.FindString(body + "\n" + p.Link); link != "" {
+		story.URL = link
+		story.Key = utils.FirstNonEmpty(story.Key, storyKeyFromURL(link))
+	}
+
+	text := strings.ToLower(story.Title + "\n" + story.Metric)
+	story.Signal = utils.FirstNonEmpty(signalOf(text), signalOf(strings.ToLower(body)))
+	story.Direction = directionOf(strings.ToLower(story.Title))
+	if story.Direction == "" {
+		story.Direction = directionOf(strings.ToLower(body))
+	}
+	if story.Category == "" {
+		story.Category = categoryOf(story)
+	}
+	return story
+}
+
+// Fingerprint identifies repeats of the same story: the story key when
+// Datadog sent one, otherwise what moved where
+func (s *Story) Fingerprint() string {
+	if s.Key != "" {
+		return s.Key
+	}
+	parts := []string{s.Service, s.Env, s.Resource, s.Signal, string(s.Direction), s.Metric}
+	if strings.Join(parts, "") == "" {
+		return strings.ToLower(s.Title)
+	}
+	return strings.ToLower(strings.Join(parts, "|"))
+}
+
+// Describe renders the story as one sentence, e.g. "Watchdog detected a
+// latency increase on service checkout (env:prod, resource GET /cart)"
+func (s *Story) Describe() string {
+	what := "an anomaly"
+	switch {
+	case s.Signal != "" && s.Direction != "":
+		what = fmt.Sprintf("a %s %s", strings.ReplaceAll(s.Signal, "_", " "), s.Direction)
+	case s.Signal != "":
+		what = "anomalous " + strings.ReplaceAll(s.Signal, "_", " ")
+	case s.Direction != "":
+		what = "an anomalous " + string(s.Direction)
+	}
+
+	where := ""
+	if s.Service != "" {
+		where = " on service " + s.Service
+	}
+	var scope []string
+	if s.Env != "" {
+		scope = append(scope, "env:"+s.Env)
+	}
+	if s.Resource != "" {
+		scope = append(scope, "resource "+s.Resource)
+	}
+	if len(scope) > 0 {
+		where += " (" + strings.Join(scope, ", ") + ")"
+	}
+
+	sentence := fmt.Sprintf("Watchdog detected %s%s", what, where)
+	if s.Metric != "" {
+		sentence += "; metric " + s.Metric
+		if s.Value != "" {
+			sentence += " = " + s.Value
+		}
+	}
+	return sentence
+}
+
+// RelatedServices returns the story's services other than the primary one
+func (s *Story) RelatedServices() []string {
+	var related []string
+	for _, e := range s.Entities {
+		if e.Type == "service" && !strings.EqualFold(e.Name, s.Service) {
+			related = append(related, e.Name)
+		}
+	}
+	return related
+}
+
+// cleanTitle strips bracketed prefixes such as "[Triggered] [Watchdog]"
+func cleanTitle(title string) string {
+	return strings.TrimSpace(titlePrefixPattern.ReplaceAllString(title, ""))
+}
+
+// storyKeyFromURL extracts the story key from a Watchdog link
+// (?storyKey=..., ?story_key=... or .../story/<key>)
+func storyKeyFromURL(link string) string {
+	u, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	for _, param := range []string{"storyKey", "story_key", "story"} {
+		if v := u.Query().Get(param); v != "" {
+			return v
+		}
+	}
+	if _, key, ok := strings.Cut(u.Path, "/story/"); ok {
+		key, _, _ = strings.Cut(key, "/")
+		return key
+	}
+	return ""
+}
+
+// signalOf classifies the anomalous signal from lowercase text
+func signalOf(text string) string {
+	switch {
+	case strings.Contains(text, "latency") || strings.Contains(text, "duration") || strings.Contains(text, "slow"):
+		return SignalLatency
+	case strings.Contains(text, "error"):
+		return SignalErrorRate
+	case strings.Contains(text, "hits") || strings.Contains(text, "throughput") ||
+		strings.Contains(text, "requests") || strings.Contains(text, "traffic"):
+		return SignalThroughput
+	case strings.Contains(text, "cpu") || strings.Contains(text, "memory") || strings.Contains(text, "disk"):
+		return SignalSaturation
+	}
+	return ""
+}
+
+// directionOf finds the first direction keyword in lowercase text
+func directionOf(text string) Direction {
+	up := increasePattern.FindStringIndex(text)
+	down := decreasePattern.FindStringIndex(text)
+	switch {
+	case up != nil && (down == nil || up[0] < down[0]):
+		return DirectionIncrease
+	case down != nil:
+		return DirectionDecrease
+	}
+	return ""
+}
+
+// categoryOf infers the story category when no tag names it
+func categoryOf(s *Story) string {
+	switch {
+	case strings.Contains(strings.ToLower(s.Title), "log"):
+		return "logs"
+	case s.Service != "" && s.Signal != SignalSaturation:
+		return "apm"
+	case s.Signal == SignalSaturation:
+		return "infrastructure"
+	}
+	for _, e := range s.Entities {
+		if e.Type == "host" || e.Type == "container" {
+			return "infrastructure"
+		}
+	}
+	return ""
+}
@@ -0,0 +1,121 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
+)
+
+var t0 = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func storyEvent(id int64, payload types.AlertPayload) *types.AlertEvent {
+	return &types.AlertEvent{ID: id, Payload: payload, ReceivedAt: t0}
+}
+
+func latencyPayload() types.AlertPayload {
+	return types.AlertPayload{
+		MonitorID:   7,
+		MonitorName: "Watchdog",
+		MonitorType: "watchdog",
+		AlertStatus: "Alert",
+		AlertTitle:  "[Triggered] [Watchdog] Latency increased on service:checkout env:prod",
+		AlertMessage: "**Resource:** GET /cart\n" +
+			"Metric: trace.http.request.duration\n" +
+			"Impacted services: payments, inventory\n" +
+			"See https://app.datadoghq.com/watchdog?storyKey=abc123 for details",
+		Tags: []string{"story_category:apm", "kube_namespace:shop", "host:web-1"},
+	}
+}
+
+func TestParseStory_APMStory(t *testing.T) {
+	story := ParseStory(storyEvent(1, latencyPayload()))
+
+	if story.Title != "Latency increased on service:checkout env:prod" {
+		t.Fatalf("expected prefixes stripped from title, got %q", story.Title)
+	}
+	if story.Service != "checkout" || story.Env != "prod" || story.Resource != "GET /cart" {
+		t.Fatalf("unexpected scope service=%q env=%q resource=%q", story.Service, story.Env, story.Resource)
+	}
+	if story.Metric != "trace.http.request.duration" || story.Signal != SignalLatency || story.Direction != DirectionIncrease {
+		t.Fatalf("unexpected metric=%q signal=%q direction=%q", story.Metric, story.Signal, story.Direction)
+	}
+	if story.Key != "abc123" || story.Category != "apm" || !story.DetectedAt.Equal(t0) {
+		t.Fatalf("unexpected key=%q category=%q detected=%v", story.Key, story.Category, story.DetectedAt)
+	}
+
+	related := story.RelatedServices()
+	if len(related) != 2 || related[0] != "payments" || related[1] != "inventory" {
+		t.Fatalf("expected payments and inventory as related services, got %v", related)
+	}
+	want := map[Entity]bool{
+		{Type: "kube_namespace", Name: "shop"}: true,
+		{Type: "host", Name: "web-1"}:          true,
+		{Type: "service", Name: "checkout"}:    true,
+	}
+	for _, e := range story.Entities {
+		delete(want, e)
+	}
+	if len(want) > 0 {
+		t.Fatalf("missing entities %v in %v", want, story.Entities)
+	}
+
+	if got := story.Describe(); got != "Watchdog detected a latency increase on service checkout (env:prod, resource GET /cart); metric trace.http.request.duration" {
+		t.Fatalf("unexpected description %q", got)
+	}
+}
+
+func TestParseStory_DirectionAndSignal(t *testing.T) {
+	tests := []struct {
+		title     string
+		signal    string
+		direction Direction
+	}{
+		{"Error rate spiked on service:billing", SignalErrorRate, DirectionIncrease},
+		{"Hits dropped on service:frontend", SignalThroughput, DirectionDecrease},
+		{"Enterprise traffic fell on service:api", SignalThroughput, DirectionDecrease},
+		{"CPU usage is elevated on host web-2", SignalSaturation, DirectionIncrease},
+		{"Something odd happened", "", ""},
+	}
+	for _, tt := range tests {
+		story := ParseStory(storyEvent(1, types.AlertPayload{AlertTitle: tt.title}))
+		if story.Signal != tt.signal || story.Direction != tt.direction {
+			t.Errorf("%q: expected %q/%q, got %q/%q", tt.title, tt.signal, tt.direction, story.Signal, story.Direction)
+		}
+	}
+}
+
+func TestStory_Fingerprint(t *testing.T) {
+	keyed := ParseStory(storyEvent(1, latencyPayload()))
+	if keyed.Fingerprint() != "abc123" {
+		t.Fatalf("expected the story key as fingerprint, got %q", keyed.Fingerprint())
+	}
+
+	unkeyed := latencyPayload()
+	unkeyed.AlertMessage = "Metric: trace.http.request.duration"
+	a := ParseStory(storyEvent(1, unkeyed))
+	b := ParseStory(storyEvent(2, unkeyed))
+	if a.Fingerprint() == "" || a.Fingerprint() != b.Fingerprint() {
+		t.Fatalf("expected repeats to share a fingerprint, got %q and %q", a.Fingerprint(), b.Fingerprint())
+	}
+
+	other := unkeyed
+	other.AlertTitle = "Latency increased on service:checkout env:staging"
+	if ParseStory(storyEvent(3, other)).Fingerprint() == a.Fingerprint() {
+		t.Fatal("expected a different env to change the fingerprint")
+	}
+}
+
+func TestStoryKeyFromURL(t *testing.T) {
+	tests := map[string]string{
+		"https://app.datadoghq.com/watchdog?storyKey=k1":      "k1",
+		"https://app.datadoghq.com/watchdog?story_key=k2&x=1": "k2",
+		"https://app.datadoghq.eu/watchdog/story/k3/overview": "k3",
+		"https://app.datadoghq.com/watchdog?tab=alerts":       "",
+	}
+	for link, want := range tests {
+		if got := storyKeyFromURL(link); got != want {
+			t.Errorf("%s: expected %q, got %q", link, want, got)
+		}
+	}
+}
@@ -12,9 +12,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/requests"
 	"github.com/Nokodoko/mkii_ddog_server/cmd/utils/urls"
 	"github.com/Nokodoko/mkii_ddog_server/services/accounts"
+	"github.com/Nokodoko/mkii_ddog_server/services/changes"
 )
 
 // AccountResolver interface defined at consumer side (Go best practice)
@@ -24,12 +26,25 @@ type AccountResolver interface {
 	GetDefault() *accounts.Account
 }
 
+// ChangeLookup interface at consumer side; implemented by *changes.Tracker
+type ChangeLookup interface {
+	ForAlert(ctx context.Context, event *types.AlertEvent) ([]changes.Change, error)
+}
+
+// WebhookEventDetail is a stored event with the changes made to its service
+// or host shortly before it was received
+type WebhookEventDetail struct {
+	*WebhookEvent
+	Changes []changes.Change `json:"changes"`
+}
+
 // Handler handles webhook HTTP requests
 type Handler struct {
 	storage    *Storage
 	dispatcher *Dispatcher
 	processor  *Processor        // Legacy processor for backwards compatibility
 	accounts   AccountResolver   // Optional: for multi-account support
+	changes    ChangeLookup      // Optional: related changes on event detail
 }
 
 // NewHandler creates a new webhook handler with dispatcher
@@ -57,6 +72,11 @@ func NewHandlerLegacy(storage *Storage, processor *Processor) *Handler {
 	}
 }
 
+// SetChangeLookup adds related change events to the event detail response
+func (h *Handler) SetChangeLookup(lookup ChangeLookup) {
+	h.changes = lookup
+}
+
 // ReceiveWebhook handles incoming webhooks from Datadog
 func (h *Handler) ReceiveWebhook(w http.ResponseWriter, r *http.Request) (int, any) {
 	return h.receiveWebhookInternal(w, r, "")
@@ -178,8 +198,18 @@ func (h *Handler) GetWebhookEvent(w http.ResponseWriter, r *http.Request, idStr
 	if err != nil {
 		return http.StatusNotFound, map[string]string{"error": "event not found"}
 	}
+	if h.changes == nil {
+		return http.StatusOK, event
+	}
 
-	return http.StatusOK, event
+	related, err := h.changes.ForAlert(r.Context(), toAlertEvent(event))
+	if err != nil {
+		log.Printf("[WEBHOOK] Failed to load changes for event %d: %v", id, err)
+	}
+	if related == nil {
+		related = []changes.Change{}
+	}
+	return http.StatusOK, WebhookEventDetail{WebhookEvent: event, Changes: related}
 }
 
 // GetEventsByMonitor retrieves events for a specific monitor
@@ -3,9 +3,11 @@ package webhooks
 import (
 	"context"
 	"log"
+	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/cmd/types"
 	"github.com/Nokodoko/mkii_ddog_server/services/agents"
@@ -13,19 +15,39 @@ import (
 
 // ProcessorOrchestrator manages webhook processing with tiered execution:
 // - Tier 1 (fast): Parallel execution of quick processors
-// - Tier 2 (slow): Bounded execution of agent analysis
+// - Tier 2 (slow): Bounded execution of agent analysis (background when async)
 type ProcessorOrchestrator struct {
 	fastProcessors []WebhookProcessor
 	agentOrch      *agents.AgentOrchestrator
 	storage        *Storage
 	notifier       *Notifier
+	anomalies      AnomalyDetector // Optional: baseline deviations for notifications
 	mu             sync.RWMutex
+
+	// asyncCtx is set when tier 2 runs in the background; each analysis gets
+	// asyncTimeout and pending tracks them
+	asyncCtx     context.Context
+	asyncTimeout time.Duration
+	pending      sync.WaitGroup
+}
+
+// DefaultAsyncAnalysisTimeout bounds one background analysis so a stuck
+// agent call can't hold a goroutine and its scheduler slot until shutdown
+const DefaultAsyncAnalysisTimeout = 10 * time.Minute
+
+// AsyncAnalysisTimeoutFromEnv reads AGENT_ANALYSIS_TIMEOUT (default 10m)
+func AsyncAnalysisTimeoutFromEnv() time.Duration {
+	if v, err := time.ParseDuration(os.Getenv("AGENT_ANALYSIS_TIMEOUT")); err == nil && v > 0 {
+		return v
+	}
+	return DefaultAsyncAnalysisTimeout
 }
 
 // OrchestratorResult contains the results of processing a webhook
 type OrchestratorResult struct {
 	ProcessedBy []string
 	Errors      []string
+	DowntimeID  string // auto-downtime created by a fast processor
 	AgentResult *agents.AnalysisResult
 }
 
@@ -39,6 +61,32 @@ func NewProcessorOrchestrator(storage *Storage, agentOrch *agents.AgentOrchestra
 	}
 }
 
+// NewAsyncProcessorOrchestrator creates an orchestrator that runs tier 2
+// (agent analysis and recovery) in the background, so dispatcher workers
+// return as soon as the fast processors finish. Background work uses ctx
+// instead of the job context, bounded by timeout per analysis.
+func NewAsyncProcessorOrchestrator(ctx context.Context, storage *Storage, agentOrch *agents.AgentOrchestrator, timeout time.Duration) *ProcessorOrchestrator {
+	if timeout <= 0 {
+		timeout = DefaultAsyncAnalysisTimeout
+	}
+	o := NewProcessorOrchestrator(storage, agentOrch)
+	o.asyncCtx = ctx
+	o.asyncTimeout = timeout
+	log.Printf("[ORCHESTRATOR] Asynchronous agent analysis enabled (timeout %s)", timeout)
+	return o
+}
+
+// AnomalyDetector interface at consumer side; implemented by *baselines.Engine
+type AnomalyDetector interface {
+	Anomalies(ctx context.Context, event *types.AlertEvent) []string
+}
+
+// SetAnomalyDetector flags alerts that deviate from their monitor's baseline
+// before fast processors (notifications) run
+func (o *ProcessorOrchestrator) SetAnomalyDetector(detector AnomalyDetector) {
+	o.anomalies = detector
+}
+
 // RegisterFastProcessor adds a fast processor (desktop notify, forwarding, downtime)
 func (o *ProcessorOrchestrator) RegisterFastProcessor(processor WebhookProcessor) {
 	o.mu.Lock()
@@ -88,6 +136,12 @@ func (o *ProcessorOrchestrator) Process(ctx context.Context, event *WebhookEvent
 		configs = []WebhookConfig{{}}
 	}
 
+	// Convert to AlertEvent for anomaly detection and agent processing
+	alertEvent := toAlertEvent(event)
+	if o.anomalies != nil {
+		event.Anomalies = o.anomalies.Anomalies(ctx, alertEvent)
+	}
+
 	// --- TIER 1: Fast processors in parallel ---
 	o.mu.RLock()
 	processors := make([]WebhookProcessor, len(o.fastProcessors))
@@ -109,13 +163,48 @@ func (o *ProcessorOrchestrator) Process(ctx context.Context, event *WebhookEvent
 				result.Errors = append(result.Errors, r.ProcessorName+": "+r.Error)
 			}
 			forwardedTo = append(forwardedTo, r.ForwardedTo...)
+			if r.DowntimeID != "" {
+				result.DowntimeID = r.DowntimeID
+			}
 		}
 	}
 
-	// --- TIER 2: Agent analysis (bounded by semaphore) ---
-	// Convert to AlertEvent for agent processing
-	alertEvent := toAlertEvent(event)
-	if o.agentOrch != nil && o.agentOrch.ShouldAnalyze(alertEvent) {
+	// --- TIER 2: Agent analysis (priority-scheduled, bounded concurrency) ---
+	needsAgent := o.agentOrch != nil &&
+		(o.agentOrch.ShouldAnalyze(alertEvent) || o.agentOrch.ShouldRecover(alertEvent))
+
+	if needsAgent && o.asyncCtx != nil {
+		// Hand off so the dispatcher worker returns to fast processing; the
+		// event status is written again once the agent finishes
+		result.ProcessedBy = append(result.ProcessedBy, "agent_queued")
+		o.finish(event, &result, forwardedTo)
+
+		background := OrchestratorResult{
+			ProcessedBy: append([]string(nil), result.ProcessedBy[:len(result.ProcessedBy)-1]...),
+			Errors:      append([]string(nil), result.Errors...),
+		}
+		o.pending.Add(1)
+		go func() {
+			defer o.pending.Done()
+			analysisCtx, cancel := context.WithTimeout(o.asyncCtx, o.asyncTimeout)
+			defer cancel()
+			o.runAgents(analysisCtx, event, alertEvent, &background)
+			o.finish(event, &background, forwardedTo)
+		}()
+		return result
+	}
+
+	if needsAgent {
+		o.runAgents(ctx, event, alertEvent, &result)
+	}
+
+	o.finish(event, &result, forwardedTo)
+	return result
+}
+
+// runAgents performs tier 2: agent analysis for alerts, recovery for OK events
+func (o *ProcessorOrchestrator) runAgents(ctx context.Context, event *WebhookEvent, alertEvent *types.AlertEvent, result *OrchestratorResult) {
+	if o.agentOrch.ShouldAnalyze(alertEvent) {
 		log.Printf("[ORCHESTRATOR] Triggering agent analysis for event %d", event.ID)
 
 		agentResult, err := o.agentOrch.Analyze(ctx, alertEvent)
@@ -124,6 +213,10 @@ func (o *ProcessorOrchestrator) Process(ctx context.Context, event *WebhookEvent
 		if err != nil {
 			log.Printf("[ORCHESTRATOR] Agent analysis failed for event %d: %v", event.ID, err)
 			result.Errors = append(result.Errors, "agent_analysis: "+err.Error())
+		} else if agentResult != nil && agentResult.Skipped {
+			// Budget, cooldown, open circuit or shed from the queue: not an error, just recorded
+			log.Printf("[ORCHESTRATOR] Agent analysis skipped for event %d: %s", event.ID, agentResult.SkipReason)
+			result.ProcessedBy = append(result.ProcessedBy, "agent_skipped")
 		} else if agentResult != nil {
 			result.ProcessedBy = append(result.ProcessedBy, "agent_"+string(agentResult.AgentRole))
 			if !agentResult.Success && agentResult.Error != "" {
@@ -139,7 +232,7 @@ func (o *ProcessorOrchestrator) Process(ctx context.Context, event *WebhookEvent
 				)
 			}
 		}
-	} else if o.agentOrch != nil && o.agentOrch.ShouldRecover(alertEvent) {
+	} else if o.agentOrch.ShouldRecover(alertEvent) {
 		// Recovery event: notify the agent sidecar to resolve the existing notebook
 		log.Printf("[ORCHESTRATOR] Triggering recovery for event %d (monitor %d, status: %s)",
 			event.ID, alertEvent.Payload.MonitorID, alertEvent.Payload.AlertStatus)
@@ -150,13 +243,19 @@ func (o *ProcessorOrchestrator) Process(ctx context.Context, event *WebhookEvent
 			result.Errors = append(result.Errors, "agent_recovery: "+err.Error())
 		} else if recoverResult != nil {
 			result.ProcessedBy = append(result.ProcessedBy, "agent_recovery")
+			if res := recoverResult.Resolution; res != nil && recoverResult.AnalysisID != "" {
+				log.Printf("[ORCHESTRATOR] Event %d resolved analysis %s (time to resolve: %v)",
+					event.ID, recoverResult.AnalysisID, res.TimeToResolve)
+			}
 			if !recoverResult.Success && recoverResult.Error != "" {
 				result.Errors = append(result.Errors, "agent_recovery: "+recoverResult.Error)
 			}
 		}
 	}
+}
 
-	// Determine final status
+// finish determines the event's final status and persists it
+func (o *ProcessorOrchestrator) finish(event *WebhookEvent, result *OrchestratorResult, forwardedTo []string) {
 	status := "processed"
 	var errorMsg string
 
@@ -175,12 +274,30 @@ func (o *ProcessorOrchestrator) Process(ctx context.Context, event *WebhookEvent
 
 	if o.storage != nil && o.storage.db != nil {
 		o.storage.UpdateEventStatus(event.ID, status, forwardedTo, errorMsg)
+		if result.DowntimeID != "" {
+			o.storage.SetEventDowntime(event.ID, result.DowntimeID)
+		}
 	}
 
 	log.Printf("[ORCHESTRATOR] Event %d processed: status=%s, processors=%v, errors=%d",
 		event.ID, status, result.ProcessedBy, len(result.Errors))
+}
 
-	return result
+// WaitForAnalyses blocks until background analyses finish or the timeout elapses.
+// Returns false on timeout.
+func (o *ProcessorOrchestrator) WaitForAnalyses(timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		o.pending.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
 }
 
 // executeFastProcessors runs fast processors in parallel using fan-out
@@ -343,7 +460,8 @@ func toAlertEvent(event *WebhookEvent) *types.AlertEvent {
 	}
 
 	return &types.AlertEvent{
-		ID: event.ID,
+		ID:          event.ID,
+		AccountName: event.AccountName,
 		Payload: types.AlertPayload{
 			AlertID:             p.AlertID,
 			AlertTitle:          alertTitle,
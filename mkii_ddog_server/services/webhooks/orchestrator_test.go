@@ -243,6 +243,44 @@ func TestOrchestrator_ProcessorFiltering(t *testing.T) {
 	}
 }
 
+// stubAnomalyDetector flags every alert with fixed messages
+type stubAnomalyDetector struct {
+	anomalies []string
+}
+
+func (s *stubAnomalyDetector) Anomalies(ctx context.Context, event *types.AlertEvent) []string {
+	return s.anomalies
+}
+
+// anomalyRecorder is a fast processor that records the anomalies it sees
+type anomalyRecorder struct {
+	seen []string
+}
+
+func (a *anomalyRecorder) Name() string { return "recorder" }
+
+func (a *anomalyRecorder) CanProcess(event *WebhookEvent, config *WebhookConfig) bool { return true }
+
+func (a *anomalyRecorder) Process(event *WebhookEvent, config *WebhookConfig) ProcessorResult {
+	a.seen = event.Anomalies
+	return ProcessorResult{ProcessorName: a.Name(), Success: true}
+}
+
+func TestOrchestrator_AnomaliesReachFastProcessors(t *testing.T) {
+	orch := NewProcessorOrchestrator(&Storage{}, nil)
+	orch.SetAnomalyDetector(&stubAnomalyDetector{anomalies: []string{"3.0x usual rate"}})
+
+	recorder := &anomalyRecorder{}
+	orch.RegisterFastProcessor(recorder)
+
+	event := &WebhookEvent{ID: 1, Payload: WebhookPayload{MonitorID: 7, AlertStatus: "Alert"}}
+	orch.Process(context.Background(), event)
+
+	if len(recorder.seen) != 1 || recorder.seen[0] != "3.0x usual rate" {
+		t.Errorf("expected the anomaly on the event, got %v", recorder.seen)
+	}
+}
+
 func TestOrchestrator_ProcessorFailure(t *testing.T) {
 	storage := &Storage{}
 	orch := NewProcessorOrchestrator(storage, nil)
@@ -468,3 +506,64 @@ func TestNewProcessorOrchestrator(t *testing.T) {
 		t.Error("Fast processors should be empty initially")
 	}
 }
+
+func TestOrchestrator_AsyncAnalysis(t *testing.T) {
+	agentOrch := agents.NewAgentOrchestrator(agents.DefaultOrchestratorConfig())
+	agentOrch.SetDefaultAgent(agents.NewHeuristicAgent(agentOrch.Classifier()))
+	orch := NewAsyncProcessorOrchestrator(context.Background(), nil, agentOrch, time.Minute)
+	orch.RegisterFastProcessor(newMockProcessor("fast", true))
+
+	event := &WebhookEvent{
+		ID: 1,
+		Payload: WebhookPayload{
+			MonitorID:   10,
+			AlertStatus: "Alert",
+		},
+	}
+
+	result := orch.Process(context.Background(), event)
+
+	// Tier 1 completes and the analysis is handed off
+	if len(result.ProcessedBy) != 2 || result.ProcessedBy[1] != "agent_queued" {
+		t.Errorf("expected fast processor and agent_queued, got %v", result.ProcessedBy)
+	}
+	if result.AgentResult != nil {
+		t.Error("async processing should not wait for the agent result")
+	}
+
+	if !orch.WaitForAnalyses(5 * time.Second) {
+		t.Fatal("background analysis did not finish")
+	}
+	if processed := agentOrch.Stats().TotalProcessed; processed != 1 {
+		t.Errorf("expected 1 background analysis, got %d", processed)
+	}
+}
+
+// blockingAgent plans until its context is cancelled
+type blockingAgent struct{}
+
+func (blockingAgent) Name() string           { return "blocking" }
+func (blockingAgent) Role() agents.AgentRole { return agents.RoleGeneral }
+func (blockingAgent) Plan(ctx context.Context, _ *types.AlertEvent, _ agents.AgentContext) agents.AgentPlan {
+	<-ctx.Done()
+	return agents.AgentPlan{Complete: true}
+}
+func (blockingAgent) Analyze(_ context.Context, _ []agents.QueryResult, agentCtx agents.AgentContext) agents.AgentContext {
+	return agentCtx
+}
+func (blockingAgent) Conclude(ctx context.Context, _ agents.AgentContext) *agents.AnalysisResult {
+	return &agents.AnalysisResult{Error: ctx.Err().Error()}
+}
+
+func TestOrchestrator_AsyncAnalysisTimeout(t *testing.T) {
+	agentOrch := agents.NewAgentOrchestrator(agents.DefaultOrchestratorConfig())
+	agentOrch.SetDefaultAgent(blockingAgent{})
+	orch := NewAsyncProcessorOrchestrator(context.Background(), nil, agentOrch, 50*time.Millisecond)
+
+	orch.Process(context.Background(), &WebhookEvent{ID: 1, Payload: WebhookPayload{MonitorID: 10, AlertStatus: "Alert"}})
+
+	// The analysis is cut off by its timeout, not left running until shutdown
+	if !orch.WaitForAnalyses(5 * time.Second) {
+		t.Fatal("background analysis outlived its timeout")
+	}
+}
@@ -83,6 +83,7 @@ func (p *Processor) Process(event *WebhookEvent) {
 
 	var allResults []ProcessorResult
 	var allForwardedTo []string
+	var downtimeID string
 
 	p.mu.RLock()
 	processors := make([]WebhookProcessor, len(p.processors))
@@ -113,6 +114,9 @@ func (p *Processor) Process(event *WebhookEvent) {
 
 			// Collect forwarded URLs
 			allForwardedTo = append(allForwardedTo, result.ForwardedTo...)
+			if result.DowntimeID != "" {
+				downtimeID = result.DowntimeID
+			}
 		}
 	}
 
@@ -146,6 +150,9 @@ func (p *Processor) Process(event *WebhookEvent) {
 	}
 
 	p.storage.UpdateEventStatus(event.ID, status, allForwardedTo, errorMsg)
+	if downtimeID != "" {
+		p.storage.SetEventDowntime(event.ID, downtimeID)
+	}
 }
 
 // ProcessPending reprocesses all pending webhook events
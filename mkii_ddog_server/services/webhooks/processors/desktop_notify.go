@@ -95,7 +95,7 @@ func (p *DesktopNotifyProcessor) Process(event *webhooks.WebhookEvent, config *w
 	monitorType := classifyForNotification(&event.Payload)
 
 	// Forward the full custom payload to notify-server
-	err := p.sendNotification(event.Payload, monitorType)
+	err := p.sendNotification(event.Payload, monitorType, event.Anomalies)
 	if err != nil {
 		log.Printf("[NOTIFY-PROC] Error sending notification: %v", err)
 		result.Success = false
@@ -149,15 +149,19 @@ func resolveTitle(p webhooks.WebhookPayload) string {
 }
 
 // sendNotification sends the notification to all configured servers
-func (p *DesktopNotifyProcessor) sendNotification(webhookPayload webhooks.WebhookPayload, monitorType string) error {
+func (p *DesktopNotifyProcessor) sendNotification(webhookPayload webhooks.WebhookPayload, monitorType string, anomalies []string) error {
 	log.Printf("[NOTIFY] Sending to %d servers: %v", len(p.serverURLs), p.serverURLs)
 
 	title := resolveTitle(webhookPayload)
 
 	// Include monitor type in the notification title so users can distinguish webhook types
+	message := fmt.Sprintf("[%s] %s", monitorType, title)
+	for _, anomaly := range anomalies {
+		message += "\n⚠ " + anomaly
+	}
 	payload := map[string]string{
 		"title":   fmt.Sprintf("💣 [%s] %s", monitorType, title),
-		"message": fmt.Sprintf("[%s] %s", monitorType, title),
+		"message": message,
 		"urgency": "critical",
 	}
 
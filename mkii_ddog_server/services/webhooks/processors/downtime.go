@@ -68,7 +68,7 @@ func (p *DowntimeProcessor) Process(event *webhooks.WebhookEvent, config *webhoo
 	// Get credentials for this event's account
 	creds := p.getCredentials(event)
 
-	err := p.createDowntime(event.Payload.MonitorID, event.Payload.Scope, duration, creds)
+	downtimeID, err := p.createDowntime(event.Payload.MonitorID, event.Payload.Scope, duration, creds)
 	if err != nil {
 		result.Success = false
 		result.Error = err.Error()
@@ -76,7 +76,9 @@ func (p *DowntimeProcessor) Process(event *webhooks.WebhookEvent, config *webhoo
 	}
 
 	result.Success = true
-	result.Message = fmt.Sprintf("created %d minute downtime for monitor %d", duration, event.Payload.MonitorID)
+	result.Message = fmt.Sprintf("created %d minute downtime %s for monitor %d", duration, downtimeID, event.Payload.MonitorID)
+	// Stored on the event so reports can tell auto-downtimed recoveries apart
+	result.DowntimeID = downtimeID
 	return result
 }
 
@@ -104,8 +106,8 @@ func (p *DowntimeProcessor) getCredentials(event *webhooks.WebhookEvent) keys.Cr
 	}
 }
 
-// createDowntime creates a downtime via Datadog API
-func (p *DowntimeProcessor) createDowntime(monitorID int64, scope string, durationMinutes int, creds keys.Credentials) error {
+// createDowntime creates a downtime via Datadog API and returns its ID
+func (p *DowntimeProcessor) createDowntime(monitorID int64, scope string, durationMinutes int, creds keys.Credentials) (string, error) {
 	now := time.Now().UTC()
 	end := now.Add(time.Duration(durationMinutes) * time.Minute)
 
@@ -128,7 +130,7 @@ func (p *DowntimeProcessor) createDowntime(monitorID int64, scope string, durati
 
 	jsonBody, err := json.Marshal(request)
 	if err != nil {
-		return fmt.Errorf("marshal request: %w", err)
+		return "", fmt.Errorf("marshal request: %w", err)
 	}
 
 	// Build API URL from account's base URL
@@ -136,7 +138,7 @@ func (p *DowntimeProcessor) createDowntime(monitorID int64, scope string, durati
 
 	req, err := http.NewRequest("POST", apiURL, bytes.NewBuffer(jsonBody))
 	if err != nil {
-		return fmt.Errorf("create request: %w", err)
+		return "", fmt.Errorf("create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
@@ -146,16 +148,23 @@ func (p *DowntimeProcessor) createDowntime(monitorID int64, scope string, durati
 
 	resp, err := p.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("execute request: %w", err)
+		return "", fmt.Errorf("execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode >= 400 {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API returned %d: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("API returned %d: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	var created downtimeResponse
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		return "", fmt.Errorf("decode response: %w", err)
+	}
+	if created.Data.ID == "" {
+		return "", fmt.Errorf("API response has no downtime ID")
+	}
+	return created.Data.ID, nil
 }
 
 // formatScope converts comma-separated scope to Datadog format
@@ -185,6 +194,12 @@ type downtimeRequest struct {
 	Data downtimeData `json:"data"`
 }
 
+type downtimeResponse struct {
+	Data struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
 type downtimeData struct {
 	Type       string             `json:"type"`
 	Attributes downtimeAttributes `json:"attributes"`
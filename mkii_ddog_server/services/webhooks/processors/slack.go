@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/Nokodoko/mkii_ddog_server/services/webhooks"
@@ -126,6 +127,15 @@ func (p *SlackProcessor) buildSlackMessage(event *webhooks.WebhookEvent) slackMe
 		})
 	}
 
+	// Deviations from the monitor's baseline ("3.0x usual rate", ...)
+	if len(event.Anomalies) > 0 {
+		attachment.Fields = append(attachment.Fields, slackField{
+			Title: "Anomalies",
+			Value: "• " + strings.Join(event.Anomalies, "\n• "),
+			Short: false,
+		})
+	}
+
 	msg := slackMessage{
 		Attachments: []slackAttachment{attachment},
 	}
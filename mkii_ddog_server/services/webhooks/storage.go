@@ -47,6 +47,7 @@ func (s *Storage) InitTables() error {
 		processed_at TIMESTAMP WITH TIME ZONE,
 		status VARCHAR(50) DEFAULT 'pending',
 		forwarded_to TEXT[],
+		downtime_id VARCHAR(64),
 		error_message TEXT,
 		account_id BIGINT,
 		account_name VARCHAR(255)
@@ -79,7 +80,7 @@ func (s *Storage) InitTables() error {
 		return err
 	}
 
-	// Add account columns if they don't exist (for existing tables)
+	// Add account and downtime columns if they don't exist (for existing tables)
 	alterQuery := `
 	DO $$
 	BEGIN
@@ -91,6 +92,10 @@ func (s *Storage) InitTables() error {
 					   WHERE table_name = 'webhook_events' AND column_name = 'account_name') THEN
 			ALTER TABLE webhook_events ADD COLUMN account_name VARCHAR(255);
 		END IF;
+		IF NOT EXISTS (SELECT 1 FROM information_schema.columns
+					   WHERE table_name = 'webhook_events' AND column_name = 'downtime_id') THEN
+			ALTER TABLE webhook_events ADD COLUMN downtime_id VARCHAR(64);
+		END IF;
 	END $$;
 	`
 	_, err = s.db.Exec(alterQuery)
@@ -151,7 +156,7 @@ func (s *Storage) GetEventByID(id int64) (*WebhookEvent, error) {
 		service, scope, transition_id, last_updated,
 		snapshot_url, link, org_id, org_name,
 		received_at, processed_at, status, forwarded_to, error_message,
-		account_id, account_name
+		account_id, account_name, downtime_id
 	FROM webhook_events WHERE id = $1`
 
 	event := &WebhookEvent{}
@@ -161,6 +166,7 @@ func (s *Storage) GetEventByID(id int64) (*WebhookEvent, error) {
 	var processedAt sql.NullTime
 	var accountID sql.NullInt64
 	var accountName sql.NullString
+	var downtimeID sql.NullString
 
 	err := s.db.QueryRow(query, id).Scan(
 		&event.ID,
@@ -170,7 +176,7 @@ func (s *Storage) GetEventByID(id int64) (*WebhookEvent, error) {
 		&event.Payload.Service, &event.Payload.Scope, &event.Payload.TransitionID, &event.Payload.LastUpdated,
 		&event.Payload.SnapshotURL, &event.Payload.Link, &event.Payload.OrgID, &event.Payload.OrgName,
 		&event.ReceivedAt, &processedAt, &event.Status, &forwardedTo, &errorMsg,
-		&accountID, &accountName,
+		&accountID, &accountName, &downtimeID,
 	)
 
 	if err != nil {
@@ -179,6 +185,7 @@ func (s *Storage) GetEventByID(id int64) (*WebhookEvent, error) {
 
 	event.Payload.Tags = tags
 	event.ForwardedTo = forwardedTo
+	event.DowntimeID = downtimeID.String
 	if errorMsg.Valid {
 		event.Error = errorMsg.String
 	}
@@ -210,7 +217,7 @@ func (s *Storage) GetRecentEvents(limit int, offset int) ([]WebhookEvent, int, e
 		service, scope, transition_id, last_updated,
 		snapshot_url, link, org_id, org_name,
 		received_at, processed_at, status, forwarded_to, error_message,
-		account_id, account_name
+		account_id, account_name, downtime_id
 	FROM webhook_events
 	ORDER BY received_at DESC
 	LIMIT $1 OFFSET $2`
@@ -230,6 +237,7 @@ func (s *Storage) GetRecentEvents(limit int, offset int) ([]WebhookEvent, int, e
 		var processedAt sql.NullTime
 		var accountID sql.NullInt64
 		var accountName sql.NullString
+		var downtimeID sql.NullString
 
 		err := rows.Scan(
 			&event.ID,
@@ -239,7 +247,7 @@ func (s *Storage) GetRecentEvents(limit int, offset int) ([]WebhookEvent, int, e
 			&event.Payload.Service, &event.Payload.Scope, &event.Payload.TransitionID, &event.Payload.LastUpdated,
 			&event.Payload.SnapshotURL, &event.Payload.Link, &event.Payload.OrgID, &event.Payload.OrgName,
 			&event.ReceivedAt, &processedAt, &event.Status, &forwardedTo, &errorMsg,
-			&accountID, &accountName,
+			&accountID, &accountName, &downtimeID,
 		)
 		if err != nil {
 			return nil, 0, err
@@ -247,6 +255,7 @@ func (s *Storage) GetRecentEvents(limit int, offset int) ([]WebhookEvent, int, e
 
 		event.Payload.Tags = tags
 		event.ForwardedTo = forwardedTo
+		event.DowntimeID = downtimeID.String
 		if errorMsg.Valid {
 			event.Error = errorMsg.String
 		}
@@ -283,6 +292,12 @@ func (s *Storage) UpdateEventStatus(id int64, status string, forwardedTo []strin
 	return err
 }
 
+// SetEventDowntime records the auto-downtime created after an event
+func (s *Storage) SetEventDowntime(id int64, downtimeID string) error {
+	_, err := s.db.Exec(`UPDATE webhook_events SET downtime_id = $1 WHERE id = $2`, downtimeID, id)
+	return err
+}
+
 // GetEventsByMonitorID retrieves events for a specific monitor
 func (s *Storage) GetEventsByMonitorID(monitorID int64, limit int) ([]WebhookEvent, error) {
 	query := `
@@ -317,6 +332,100 @@ func (s *Storage) GetEventsByMonitorID(monitorID int64, limit int) ([]WebhookEve
 	return events, nil
 }
 
+// GetMonitorEventsBetween retrieves a monitor's events received in [from, to], oldest first
+func (s *Storage) GetMonitorEventsBetween(monitorID int64, from, to time.Time) ([]WebhookEvent, error) {
+	query := `
+	SELECT id, alert_id, alert_title, alert_message, alert_status,
+		monitor_id, monitor_name, monitor_type, tags,
+		event_timestamp, event_type, priority, hostname,
+		service, scope, transition_id, last_updated,
+		snapshot_url, link, org_id, org_name,
+		received_at, processed_at, status, forwarded_to, error_message,
+		account_id, account_name, downtime_id
+	FROM webhook_events
+	WHERE monitor_id = $1 AND received_at BETWEEN $2 AND $3
+	ORDER BY received_at ASC, id ASC`
+
+	rows, err := s.db.Query(query, monitorID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []WebhookEvent
+	for rows.Next() {
+		event := WebhookEvent{}
+		var tags pq.StringArray
+		var forwardedTo pq.StringArray
+		var scope, errorMsg sql.NullString
+		var processedAt sql.NullTime
+		var accountID sql.NullInt64
+		var accountName sql.NullString
+		var downtimeID sql.NullString
+
+		err := rows.Scan(
+			&event.ID,
+			&event.Payload.AlertID, &event.Payload.AlertTitle, &event.Payload.AlertMessage, &event.Payload.AlertStatus,
+			&event.Payload.MonitorID, &event.Payload.MonitorName, &event.Payload.MonitorType, &tags,
+			&event.Payload.Timestamp, &event.Payload.EventType, &event.Payload.Priority, &event.Payload.Hostname,
+			&event.Payload.Service, &scope, &event.Payload.TransitionID, &event.Payload.LastUpdated,
+			&event.Payload.SnapshotURL, &event.Payload.Link, &event.Payload.OrgID, &event.Payload.OrgName,
+			&event.ReceivedAt, &processedAt, &event.Status, &forwardedTo, &errorMsg,
+			&accountID, &accountName, &downtimeID,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		event.Payload.Tags = tags
+		event.Payload.Scope = scope.String
+		event.ForwardedTo = forwardedTo
+		event.DowntimeID = downtimeID.String
+		if errorMsg.Valid {
+			event.Error = errorMsg.String
+		}
+		if processedAt.Valid {
+			event.ProcessedAt = &processedAt.Time
+		}
+		if accountID.Valid {
+			event.AccountID = &accountID.Int64
+		}
+		if accountName.Valid {
+			event.AccountName = accountName.String
+		}
+
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
+}
+
+// GetMonitorIDsSince returns the monitors with events received since the given time
+func (s *Storage) GetMonitorIDsSince(since time.Time) ([]int64, error) {
+	query := `
+	SELECT DISTINCT monitor_id
+	FROM webhook_events
+	WHERE monitor_id IS NOT NULL AND monitor_id <> 0 AND received_at >= $1
+	ORDER BY monitor_id`
+
+	rows, err := s.db.Query(query, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
+
 // SaveConfig saves a webhook configuration
 func (s *Storage) SaveConfig(config WebhookConfig) (*WebhookConfig, error) {
 	query := `
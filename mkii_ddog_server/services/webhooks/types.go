@@ -27,6 +27,7 @@ type ProcessorResult struct {
 	Message       string   `json:"message,omitempty"`
 	Error         string   `json:"error,omitempty"`
 	ForwardedTo   []string `json:"forwarded_to,omitempty"` // URLs that received the webhook
+	DowntimeID    string   `json:"downtime_id,omitempty"`  // Datadog downtime the processor created
 }
 
 // WebhookPayload represents the incoming webhook data from Datadog
@@ -75,9 +76,14 @@ type WebhookEvent struct {
 	ProcessedAt *time.Time     `json:"processed_at,omitempty"`
 	Status      string         `json:"status"` // "pending", "processing", "processed", "failed"
 	ForwardedTo []string       `json:"forwarded_to,omitempty"`
+	DowntimeID  string         `json:"downtime_id,omitempty"` // auto-downtime created after this event
 	Error       string         `json:"error,omitempty"`
 	AccountID   *int64         `json:"account_id,omitempty"`
 	AccountName string         `json:"account_name,omitempty"`
+
+	// Anomalies describe how the alert deviates from its monitor's history;
+	// set before fast processors run so notifications can include them (not stored)
+	Anomalies []string `json:"anomalies,omitempty"`
 }
 
 // WebhookConfig represents configuration for a webhook endpoint
@@ -297,6 +297,7 @@ async function initRumSession(visitorUuid, userAgent, referrer) {
         'Content-Type': 'application/json',
         'Content-Length': Buffer.byteLength(payload),
         'User-Agent': userAgent,
+        'X-RUM-Synthetic': 'headless-traffic-generator',
       },
     };
 
@@ -341,6 +342,7 @@ async function trackRumEvent(sessionId, eventType, eventData, userAgent) {
         'Content-Type': 'application/json',
         'Content-Length': Buffer.byteLength(payload),
         'User-Agent': userAgent,
+        'X-RUM-Synthetic': 'headless-traffic-generator',
       },
     };
 
@@ -393,6 +395,18 @@ async function simulateSession(browser, sessionNum) {
     }
   }
 
+  // Tag the page's own calls to the backend as synthetic so they stay out of
+  // RUM analytics; Datadog intake requests are left untouched
+  await page.setRequestInterception(true);
+  page.on('request', request => {
+    if (request.isInterceptResolutionHandled()) return;
+    if (new URL(request.url()).pathname.startsWith('/v1/rum/')) {
+      request.continue({ headers: { ...request.headers(), 'x-rum-synthetic': 'headless-traffic-generator' } });
+    } else {
+      request.continue();
+    }
+  });
+
   // Listen for Datadog SDK network requests
   if (config.verbose) {
     page.on('request', request => {